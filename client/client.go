@@ -0,0 +1,558 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrClosed is returned for operations on a closed client
+var ErrClosed = errors.New("client closed")
+
+// ErrTooManyConnections is returned by Dial, and reported on reconnect
+// attempts, when the user already holds as many connections as the gateway
+// allows
+var ErrTooManyConnections = errors.New("too many connections")
+
+// Client is a WebSocket client for the PubSub gateway
+type Client struct {
+	conn          *websocket.Conn // guarded by writeMu
+	endpoint      string
+	header        http.Header
+	opts          *Options
+	nextID        atomic.Uint64
+	writeMu       sync.Mutex
+	mu            sync.RWMutex
+	pending       map[string]chan *response    // request_id -> waiting caller
+	handlers      map[string]Handler           // topic -> event handler
+	subscriptions map[string]*SubscribeOptions // topic -> options, restored on reconnect
+	ordering      map[string]*orderingState    // topic -> received so far, for ValidateOrdering subscriptions
+	lastSeq       map[string]uint64            // topic -> seq of the newest event received, for Resume subscriptions
+	expiresAt     map[string]time.Time         // topic -> end of the subscription, for TTL subscriptions
+	done          chan struct{}
+	closed        chan struct{} // closed by Close, stops reconnecting
+	closeOnce     sync.Once
+	clockOffset   atomic.Int64 // nanoseconds, set by SyncTime
+	err           error
+}
+
+// Dial connects to the gateway WebSocket endpoint (e.g. ws://host:8000/ws)
+// authenticating with the given JWT token
+func Dial(ctx context.Context, endpoint, token string, opts *Options) (*Client, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = DefaultRequestTimeout
+	}
+
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+
+	conn, resp, err := opts.dialer().DialContext(ctx, endpoint, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", dialError(resp, err))
+	}
+
+	c := &Client{
+		conn:          conn,
+		endpoint:      endpoint,
+		header:        header,
+		opts:          opts,
+		pending:       make(map[string]chan *response),
+		handlers:      make(map[string]Handler),
+		subscriptions: make(map[string]*SubscribeOptions),
+		ordering:      make(map[string]*orderingState),
+		lastSeq:       make(map[string]uint64),
+		expiresAt:     make(map[string]time.Time),
+		done:          make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+
+	go c.readLoop(conn)
+
+	return c, nil
+}
+
+// Subscribe subscribes to a topic, calling handler for every delivered event
+func (c *Client) Subscribe(ctx context.Context, topic string, opts *SubscribeOptions, handler Handler) error {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+
+	c.mu.Lock()
+	c.handlers[topic] = handler
+	c.subscriptions[topic] = opts
+	if opts.ValidateOrdering {
+		c.ordering[topic] = &orderingState{}
+	}
+	delete(c.lastSeq, topic)
+	if opts.AfterSeq != nil {
+		c.lastSeq[topic] = *opts.AfterSeq
+	}
+	delete(c.expiresAt, topic)
+	if opts.TTL > 0 {
+		c.expiresAt[topic] = time.Now().Add(opts.TTL)
+	}
+	c.mu.Unlock()
+
+	if err := c.subscribe(ctx, topic, opts); err != nil {
+		c.mu.Lock()
+		delete(c.handlers, topic)
+		delete(c.subscriptions, topic)
+		delete(c.ordering, topic)
+		delete(c.lastSeq, topic)
+		delete(c.expiresAt, topic)
+		c.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// subscribe sends a subscribe request
+func (c *Client) subscribe(ctx context.Context, topic string, opts *SubscribeOptions) error {
+	var since, ttl string
+	if opts.Since > 0 {
+		since = opts.Since.String()
+	}
+	if opts.TTL > 0 {
+		ttl = opts.TTL.String()
+	}
+	_, err := c.roundTrip(ctx, &request{
+		Type:             "subscribe",
+		Topic:            topic,
+		LastN:            opts.LastN,
+		Since:            since,
+		AfterSeq:         opts.AfterSeq,
+		Sampling:         opts.Sampling,
+		TagFilter:        opts.TagFilter,
+		Durable:          opts.Durable,
+		DeadLetter:       opts.DeadLetter,
+		ValidateOrdering: opts.ValidateOrdering,
+		MaxDeliveries:    opts.MaxDeliveries,
+		Backpressure:     opts.Backpressure,
+		Preferences:      opts.Preferences,
+		Partition:        opts.Partition,
+		TTL:              ttl,
+	})
+	return err
+}
+
+// Unsubscribe removes the subscription to a topic
+func (c *Client) Unsubscribe(ctx context.Context, topic string) error {
+	_, err := c.roundTrip(ctx, &request{
+		Type:  "unsubscribe",
+		Topic: topic,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.handlers, topic)
+	delete(c.subscriptions, topic)
+	delete(c.ordering, topic)
+	delete(c.lastSeq, topic)
+	delete(c.expiresAt, topic)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Publish publishes a message to a topic and waits for the gateway ack.
+// OnPublish interceptors see a copy of msg.
+func (c *Client) Publish(ctx context.Context, topic string, msg *Message) error {
+	if msg != nil && len(c.opts.Interceptors) > 0 {
+		intercepted := *msg
+		if err := c.interceptPublish(ctx, topic, &intercepted); err != nil {
+			c.reportError("publish", err)
+			return err
+		}
+		msg = &intercepted
+	}
+
+	_, err := c.roundTrip(ctx, &request{
+		Type:    "publish",
+		Topic:   topic,
+		Message: msg,
+	})
+	return err
+}
+
+// Ack acknowledges all messages up to seq on a durable subscription
+func (c *Client) Ack(ctx context.Context, topic string, seq uint64) error {
+	_, err := c.roundTrip(ctx, &request{
+		Type:  "ack",
+		Topic: topic,
+		Seq:   seq,
+	})
+	return err
+}
+
+// Nack rejects a message received on a subscription made with
+// MaxDeliveries. With requeue it is delivered again, unless that would
+// exceed MaxDeliveries; otherwise, or then, it goes to the subscription's
+// dead-letter topic, or is discarded without one.
+func (c *Client) Nack(ctx context.Context, topic string, seq uint64, requeue bool) (*NackResult, error) {
+	resp, err := c.roundTrip(ctx, &request{
+		Type:    "nack",
+		Topic:   topic,
+		Seq:     seq,
+		Requeue: &requeue,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Nack, nil
+}
+
+// MarkRead records that messages up to seq on topic have been read and
+// returns the updated marker with the remaining unread count
+func (c *Client) MarkRead(ctx context.Context, topic string, seq uint64) (*ReadMarker, error) {
+	resp, err := c.roundTrip(ctx, &request{
+		Type:  "mark_read",
+		Topic: topic,
+		Seq:   seq,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ReadMarker, nil
+}
+
+// Ping sends a ping and waits for the pong
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.roundTrip(ctx, &request{Type: "ping"})
+	return err
+}
+
+// Done is closed when the connection terminates
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that terminated the connection, if any
+func (c *Client) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	return c.conn.Close()
+}
+
+// roundTrip sends a request and waits for the response with the same
+// request ID, reporting failures to the OnError interceptors
+func (c *Client) roundTrip(ctx context.Context, req *request) (*response, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		c.reportError(req.Type, err)
+	}
+	return resp, err
+}
+
+// send sends a request and waits for its response
+func (c *Client) send(ctx context.Context, req *request) (*response, error) {
+	req.RequestID = strconv.FormatUint(c.nextID.Add(1), 10)
+	wait := make(chan *response, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c.pending[req.RequestID] = wait
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, req.RequestID)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(req)
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s request: %w", req.Type, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.RequestTimeout)
+	defer cancel()
+
+	select {
+	case resp := <-wait:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp, nil
+	case <-c.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// readLoop reads from conn until it fails, reconnecting when enabled, and
+// closes Done once the client gives up
+func (c *Client) readLoop(conn *websocket.Conn) {
+	defer close(c.done)
+
+	for {
+		err := c.read(conn)
+
+		select {
+		case <-c.closed:
+		default:
+			if c.opts.Reconnect {
+				c.reportError("connection", err)
+				c.failPending(err)
+				if conn, err = c.reconnect(); err == nil {
+					continue
+				}
+			}
+		}
+
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		return
+	}
+}
+
+// read dispatches responses to waiting callers and events to handlers
+// until reading fails
+func (c *Client) read(conn *websocket.Conn) error {
+	for {
+		var resp response
+		if err := c.readJSON(conn, &resp); err != nil {
+			return err
+		}
+
+		if resp.Type == "event" && resp.Message != nil {
+			c.checkOrdering(resp.Topic, resp.Message, resp.Ordering)
+
+			if err := c.interceptEvent(resp.Topic, resp.Message); err != nil {
+				c.reportError("event", err)
+				continue
+			}
+
+			c.mu.Lock()
+			handler := c.handlers[resp.Topic]
+			if opts := c.subscriptions[resp.Topic]; opts != nil && opts.Resume {
+				c.lastSeq[resp.Topic] = max(c.lastSeq[resp.Topic], resp.Message.Seq)
+			}
+			c.mu.Unlock()
+
+			if handler != nil {
+				handler(resp.Message)
+			}
+			continue
+		}
+
+		if resp.Type == "subscription_closed" {
+			c.closeSubscription(resp.Topic, resp.Reason)
+			continue
+		}
+
+		if resp.Type == "dropped" && resp.Dropped != nil {
+			c.mu.RLock()
+			opts := c.subscriptions[resp.Topic]
+			c.mu.RUnlock()
+
+			if opts != nil && opts.OnDropped != nil {
+				opts.OnDropped(*resp.Dropped)
+			}
+			continue
+		}
+
+		c.mu.RLock()
+		wait, ok := c.pending[resp.RequestID]
+		c.mu.RUnlock()
+
+		if ok {
+			wait <- &resp
+		}
+	}
+}
+
+// closeSubscription forgets a subscription the server ended and notifies
+// its OnClosed callback
+func (c *Client) closeSubscription(topic, reason string) {
+	c.mu.Lock()
+	opts := c.subscriptions[topic]
+	delete(c.handlers, topic)
+	delete(c.subscriptions, topic)
+	delete(c.ordering, topic)
+	delete(c.lastSeq, topic)
+	delete(c.expiresAt, topic)
+	c.mu.Unlock()
+
+	if opts != nil && opts.OnClosed != nil {
+		opts.OnClosed(reason)
+	}
+}
+
+// readJSON reads the next frame into v, honouring Options.UseNumber
+func (c *Client) readJSON(conn *websocket.Conn, v interface{}) error {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if c.opts.UseNumber {
+		decoder.UseNumber()
+	}
+	return decoder.Decode(v)
+}
+
+// failPending fails the requests waiting for a response on a dropped
+// connection
+func (c *Client) failPending(cause error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, wait := range c.pending {
+		select {
+		case wait <- &response{Error: &Error{Code: ErrorCodeDisconnected, Message: cause.Error()}}:
+		default:
+		}
+	}
+}
+
+// reconnect redials with exponential backoff until it succeeds, the client
+// is closed or MaxReconnectAttempts is reached. Subscriptions are restored
+// in the background once the new read loop is running.
+func (c *Client) reconnect() (*websocket.Conn, error) {
+	backoff := c.opts.ReconnectMinBackoff
+	if backoff <= 0 {
+		backoff = DefaultReconnectMinBackoff
+	}
+	maxBackoff := c.opts.ReconnectMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultReconnectMaxBackoff
+	}
+
+	var lastErr error
+	for attempt := 1; c.opts.MaxReconnectAttempts <= 0 || attempt <= c.opts.MaxReconnectAttempts; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-c.closed:
+			return nil, ErrClosed
+		}
+
+		conn, resp, err := c.opts.dialer().Dial(c.endpoint, c.header)
+		if err != nil {
+			err = dialError(resp, err)
+			lastErr = err
+			c.reportReconnect(attempt, err)
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+
+		c.writeMu.Lock()
+		c.conn = conn
+		c.writeMu.Unlock()
+
+		// Close may have run against the old connection meanwhile
+		select {
+		case <-c.closed:
+			conn.Close()
+			return nil, ErrClosed
+		default:
+		}
+
+		go c.restore(attempt)
+		return conn, nil
+	}
+
+	return nil, fmt.Errorf("reconnect failed after %d attempts: %w", c.opts.MaxReconnectAttempts, lastErr)
+}
+
+// dialError maps a refused upgrade to ErrTooManyConnections when the
+// gateway refused it for the connection cap
+func dialError(resp *http.Response, err error) error {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error == ErrTooManyConnections.Error() {
+			return ErrTooManyConnections
+		}
+	}
+	return err
+}
+
+// restore resubscribes to every topic after a reconnect. Non-durable
+// subscriptions do not replay last_n again, but Resume ones replay from
+// after the last event received; durable ones resume from their last ack.
+// Ordering validation starts over, as delivery sequences restart on the new
+// connection. TTL subscriptions that ran out while disconnected are closed
+// instead.
+func (c *Client) restore(attempt int) {
+	c.mu.Lock()
+	subscriptions := make(map[string]*SubscribeOptions, len(c.subscriptions))
+	var expired []string
+	for topic, opts := range c.subscriptions {
+		resumed := *opts
+		if end, ok := c.expiresAt[topic]; ok {
+			resumed.TTL = time.Until(end)
+			if resumed.TTL <= 0 {
+				expired = append(expired, topic)
+				continue
+			}
+		}
+		resumed.AfterSeq = nil
+		if !resumed.Durable {
+			resumed.LastN = 0
+			resumed.Since = 0
+			if seq, received := c.lastSeq[topic]; received && resumed.Resume {
+				resumed.AfterSeq = &seq
+			}
+		}
+		subscriptions[topic] = &resumed
+		if opts.ValidateOrdering {
+			c.ordering[topic] = &orderingState{}
+		}
+	}
+	c.mu.Unlock()
+
+	for _, topic := range expired {
+		c.closeSubscription(topic, CloseReasonSubscriptionExpired)
+	}
+
+	var firstErr error
+	for topic, resumed := range subscriptions {
+		if err := c.subscribe(context.Background(), topic, resumed); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("resubscribing to %s: %w", topic, err)
+		}
+	}
+
+	c.reportReconnect(attempt, firstErr)
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DeadLetter is the payload of messages on a dead-letter topic: a message
+// dropped or nacked on a subscription, and where
+type DeadLetter struct {
+	Topic    string   `json:"topic"`     // topic the message was published to
+	ClientID string   `json:"client_id"` // subscriber that did not receive it
+	Reason   string   `json:"reason"`    // one of the DeadLetterReason values
+	Message  *Message `json:"message"`
+}
+
+// Reasons the gateway dead-letters a message
+const (
+	DeadLetterReasonBacklog       = "backlog"        // the subscription's queue was full
+	DeadLetterReasonNacked        = "nacked"         // nacked without requeue
+	DeadLetterReasonMaxDeliveries = "max_deliveries" // nacked after its last allowed delivery
+)
+
+// AsDeadLetter decodes a message received from a dead-letter topic
+func AsDeadLetter(msg *Message) (*DeadLetter, error) {
+	data, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var deadLetter DeadLetter
+	if err := json.Unmarshal(data, &deadLetter); err != nil {
+		return nil, fmt.Errorf("not a dead letter: %w", err)
+	}
+	if deadLetter.Message == nil {
+		return nil, fmt.Errorf("not a dead letter: message is missing")
+	}
+	return &deadLetter, nil
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// EncryptionHeader marks an encrypted message; its value is the ID of the
+// key the payload was encrypted with
+const EncryptionHeader = "enc"
+
+// Keyring encrypts and decrypts message payloads end to end with AES-GCM,
+// so the gateway only ever sees ciphertext. Payloads are encrypted with the
+// current key and carry its ID in the EncryptionHeader; any key still on
+// the keyring decrypts them, so keys can be rotated without losing messages
+// already published. Topic routes, header indexes and schemas see only the
+// encrypted payload, but headers and tags stay in the clear.
+//
+// Install it with Interceptor, or call Encrypt and Decrypt directly.
+type Keyring struct {
+	// UseNumber decodes decrypted payload numbers as json.Number, as
+	// Options.UseNumber does for plaintext events
+	UseNumber bool
+
+	mu      sync.RWMutex
+	keys    map[string]cipher.AEAD
+	current string
+}
+
+// NewKeyring returns a keyring encrypting with key, an AES-128, AES-192 or
+// AES-256 key, under the given ID
+func NewKeyring(id string, key []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	if err := k.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Add adds a key that decrypts messages but does not encrypt them, such as
+// one a producer has not rotated to yet, or a retired one
+func (k *Keyring) Add(id string, key []byte) error {
+	if id == "" {
+		return fmt.Errorf("invalid key: ID is required")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("invalid key %s: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("invalid key %s: %w", id, err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, exists := k.keys[id]; exists {
+		return fmt.Errorf("invalid key %s: ID already in use", id)
+	}
+	k.keys[id] = aead
+	return nil
+}
+
+// Rotate adds a key and encrypts with it from now on. The previous key
+// stays on the keyring to decrypt older messages until it is removed.
+func (k *Keyring) Rotate(id string, key []byte) error {
+	if err := k.Add(id, key); err != nil {
+		return err
+	}
+	return k.Use(id)
+}
+
+// Use encrypts with a key already on the keyring from now on
+func (k *Keyring) Use(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, exists := k.keys[id]; !exists {
+		return fmt.Errorf("unknown key %s", id)
+	}
+	k.current = id
+	return nil
+}
+
+// Remove retires a key once no message encrypted with it is left to
+// decrypt. The current key cannot be removed.
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if id == k.current {
+		return fmt.Errorf("cannot remove key %s: it is the current key", id)
+	}
+	if _, exists := k.keys[id]; !exists {
+		return fmt.Errorf("unknown key %s", id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// Current returns the ID of the key messages are encrypted with
+func (k *Keyring) Current() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// Encrypt replaces a message's payload with its JSON encoding, encrypted
+// with the current key and base64-encoded, and sets the EncryptionHeader.
+// The headers are copied, not modified in place. Tombstones and messages
+// already encrypted are left alone.
+func (k *Keyring) Encrypt(msg *Message) error {
+	if msg.Tombstone != "" || msg.Headers[EncryptionHeader] != "" {
+		return nil
+	}
+
+	plaintext, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload for encryption: %w", err)
+	}
+
+	k.mu.RLock()
+	id, aead := k.current, k.keys[k.current]
+	k.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	// The key ID is authenticated, so the header cannot be swapped
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(id))
+
+	msg.Payload = base64.StdEncoding.EncodeToString(sealed)
+	msg.Headers = maps.Clone(msg.Headers)
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string, 1)
+	}
+	msg.Headers[EncryptionHeader] = id
+	return nil
+}
+
+// Decrypt restores the payload of a message carrying the EncryptionHeader
+// and removes the header. Messages without it are left alone.
+func (k *Keyring) Decrypt(msg *Message) error {
+	id, encrypted := msg.Headers[EncryptionHeader]
+	if !encrypted {
+		return nil
+	}
+
+	k.mu.RLock()
+	aead := k.keys[id]
+	k.mu.RUnlock()
+	if aead == nil {
+		return fmt.Errorf("cannot decrypt message %s: unknown key %s", msg.ID, id)
+	}
+
+	encoded, ok := msg.Payload.(string)
+	if !ok {
+		return fmt.Errorf("cannot decrypt message %s: payload is not a string", msg.ID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return fmt.Errorf("cannot decrypt message %s: malformed payload", msg.ID)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return fmt.Errorf("cannot decrypt message %s: %w", msg.ID, err)
+	}
+
+	var payload interface{}
+	decoder := json.NewDecoder(bytes.NewReader(plaintext))
+	if k.UseNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(&payload); err != nil {
+		return fmt.Errorf("cannot decrypt message %s: %w", msg.ID, err)
+	}
+
+	msg.Payload = payload
+	msg.Headers = maps.Clone(msg.Headers)
+	delete(msg.Headers, EncryptionHeader)
+	if len(msg.Headers) == 0 {
+		msg.Headers = nil
+	}
+	return nil
+}
+
+// Interceptor encrypts published payloads and decrypts events. Put it last
+// in Options.Interceptors, so the others see plaintext both ways. Events
+// that cannot be decrypted are dropped and reported to OnError.
+func (k *Keyring) Interceptor() Interceptor {
+	return Interceptor{
+		OnPublish: func(ctx context.Context, topic string, msg *Message) error {
+			return k.Encrypt(msg)
+		},
+		OnEvent: func(topic string, msg *Message) error {
+			return k.Decrypt(msg)
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"log"
+)
+
+// Error codes the client reports for failures that happen locally rather
+// than on the gateway
+const (
+	// ErrorCodeDisconnected fails requests in flight when the connection
+	// drops and the client reconnects
+	ErrorCodeDisconnected = "DISCONNECTED"
+)
+
+// Interceptor hooks into client operations, e.g. for metrics, logging or
+// payload encryption. Every field is optional. Interceptors run in the
+// order given in Options.Interceptors, except OnEvent, which runs in
+// reverse so an interceptor that transforms outgoing messages can undo
+// that on incoming ones after the others.
+type Interceptor struct {
+	// OnPublish runs before a message is sent and may modify it. The
+	// caller's Message is not changed. Returning an error aborts the
+	// publish with that error.
+	OnPublish func(ctx context.Context, topic string, msg *Message) error
+
+	// OnEvent runs before an event reaches its handler and may modify the
+	// message. Returning an error drops the event; the error is passed to
+	// OnError with op "event".
+	OnEvent func(topic string, msg *Message) error
+
+	// OnError receives every failed request, with op set to the request
+	// type ("publish", "subscribe", ...), dropped events ("event") and
+	// connection errors ("connection")
+	OnError func(op string, err error)
+
+	// OnReconnect runs after each reconnect attempt when Options.Reconnect
+	// is set: with a nil error once the client is connected again and its
+	// subscriptions are restored, or with the error of a failed attempt
+	OnReconnect func(attempt int, err error)
+}
+
+// interceptPublish runs the OnPublish hooks
+func (c *Client) interceptPublish(ctx context.Context, topic string, msg *Message) error {
+	for _, interceptor := range c.opts.Interceptors {
+		if interceptor.OnPublish == nil {
+			continue
+		}
+		if err := interceptor.OnPublish(ctx, topic, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interceptEvent runs the OnEvent hooks, last interceptor first
+func (c *Client) interceptEvent(topic string, msg *Message) error {
+	for i := len(c.opts.Interceptors) - 1; i >= 0; i-- {
+		if hook := c.opts.Interceptors[i].OnEvent; hook != nil {
+			if err := hook(topic, msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reportError passes an error to the OnError hooks
+func (c *Client) reportError(op string, err error) {
+	for _, interceptor := range c.opts.Interceptors {
+		if interceptor.OnError != nil {
+			interceptor.OnError(op, err)
+		}
+	}
+}
+
+// reportReconnect passes a reconnect attempt to the OnReconnect hooks, or
+// logs failed attempts when none is set
+func (c *Client) reportReconnect(attempt int, err error) {
+	hooked := false
+	for _, interceptor := range c.opts.Interceptors {
+		if interceptor.OnReconnect != nil {
+			interceptor.OnReconnect(attempt, err)
+			hooked = true
+		}
+	}
+	if !hooked && err != nil {
+		log.Printf("pubsub client: reconnect attempt %d failed: %v", attempt, err)
+	}
+}
@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Configuration constants
+const (
+	DefaultHandshakeTimeout = 10 * time.Second
+	DefaultRequestTimeout   = 10 * time.Second
+
+	DefaultReconnectMinBackoff = 500 * time.Millisecond
+	DefaultReconnectMaxBackoff = 30 * time.Second
+)
+
+// Options holds configurable parameters for a client connection
+type Options struct {
+	// Dialer is used as-is when set; Proxy, TLSConfig, NetDialContext and
+	// HandshakeTimeout are ignored in that case
+	Dialer *websocket.Dialer
+
+	// Proxy selects the proxy for the upgrade request. http, https and
+	// socks5 proxy URLs are supported. Defaults to http.ProxyFromEnvironment.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// TLSConfig is used for wss:// connections
+	TLSConfig *tls.Config
+
+	// NetDialContext overrides how TCP connections are established, e.g. to
+	// pin DNS resolution or route through a custom transport
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	HandshakeTimeout time.Duration
+	RequestTimeout   time.Duration
+
+	// UseNumber decodes event payload numbers as json.Number instead of
+	// float64, so integers beyond 2^53 (e.g. int64 IDs) arrive unchanged
+	UseNumber bool
+
+	// OnOrderingViolation receives ordering violations detected on
+	// subscriptions with ValidateOrdering set. Violations are logged with
+	// the standard logger when nil.
+	OnOrderingViolation func(OrderingViolation)
+
+	// Interceptors hook into publishes, events, errors and reconnects
+	Interceptors []Interceptor
+
+	// Reconnect redials when the connection drops, waiting between
+	// ReconnectMinBackoff and ReconnectMaxBackoff (doubling per failed
+	// attempt), and restores the client's subscriptions. Requests in flight
+	// fail with a DISCONNECTED error. After MaxReconnectAttempts failed
+	// attempts (0 means no limit) the client gives up and Done is closed.
+	Reconnect            bool
+	ReconnectMinBackoff  time.Duration
+	ReconnectMaxBackoff  time.Duration
+	MaxReconnectAttempts int
+}
+
+// DefaultOptions returns default options
+func DefaultOptions() *Options {
+	return &Options{
+		Proxy:               http.ProxyFromEnvironment,
+		HandshakeTimeout:    DefaultHandshakeTimeout,
+		RequestTimeout:      DefaultRequestTimeout,
+		ReconnectMinBackoff: DefaultReconnectMinBackoff,
+		ReconnectMaxBackoff: DefaultReconnectMaxBackoff,
+	}
+}
+
+// dialer returns the websocket dialer described by the options
+func (o *Options) dialer() *websocket.Dialer {
+	if o.Dialer != nil {
+		return o.Dialer
+	}
+
+	return &websocket.Dialer{
+		Proxy:            o.Proxy,
+		TLSClientConfig:  o.TLSConfig,
+		NetDialContext:   o.NetDialContext,
+		HandshakeTimeout: o.HandshakeTimeout,
+	}
+}
+
+// Message represents a published message
+type Message struct {
+	ID        string      `json:"id"`
+	Seq       uint64      `json:"seq,omitempty"`
+	Payload   interface{} `json:"payload"`
+	Topic     string      `json:"topic,omitempty"`
+	Timestamp time.Time   `json:"timestamp,omitempty"`
+	Tombstone string      `json:"tombstone,omitempty"` // set on tombstones: ID of a deleted message to purge
+	Tags      []string    `json:"tags,omitempty"`
+	Origin    string      `json:"origin,omitempty"`     // region the message was published in
+	OriginSeq uint64      `json:"origin_seq,omitempty"` // sequence number in the origin region
+
+	// Headers are free-form metadata; keys the topic indexes can be looked
+	// up with GET /topics/{name}/messages?header.<key>=<value>
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// SchemaVersion is the topic schema version the payload conforms to. On
+	// publish it picks the version to validate against, 0 meaning the
+	// latest; delivered events carry the version used.
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	// Priority "high" delivers the message ahead of the normal ones queued
+	// for each subscriber; empty means "normal"
+	Priority string `json:"priority,omitempty"`
+
+	// Key picks the partition of a partitioned topic: messages with the
+	// same key are delivered in the order they were published
+	Key string `json:"key,omitempty"`
+}
+
+// Sampling restricts a subscription to a representative subset of a topic
+type Sampling struct {
+	EveryN int     `json:"every_n,omitempty"`
+	Rate   float64 `json:"rate,omitempty"`
+}
+
+// Tag match modes
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// TagFilter restricts a subscription to messages carrying any (the
+// default) or all of Tags
+type TagFilter struct {
+	Tags  []string `json:"tags"`
+	Match string   `json:"match,omitempty"`
+}
+
+// SubscribeOptions holds per-subscription settings
+type SubscribeOptions struct {
+	LastN     int
+	Sampling  *Sampling
+	TagFilter *TagFilter
+	Durable   bool // resume from the last acked seq on resubscribe; see Client.Ack
+
+	// Since replays the messages published within this long before
+	// subscribing; with LastN too, only the newest LastN of them
+	Since time.Duration
+
+	// AfterSeq replays every buffered message with a greater Seq. It cannot
+	// be combined with LastN or Since.
+	AfterSeq *uint64
+
+	// Resume makes a subscription restored after a reconnect replay what
+	// was published while disconnected, from after the last event received,
+	// as far back as the topic's buffer reaches. Durable subscriptions
+	// resume from their last ack regardless.
+	Resume bool
+
+	// DeadLetter names a topic that receives messages the gateway drops for
+	// this subscription because it fell behind, so they can be recovered
+	// later; see AsDeadLetter. Not allowed with Durable.
+	DeadLetter string
+
+	// MaxDeliveries allows the subscription to nack messages; see
+	// Client.Nack. A nacked message is redelivered until it has been
+	// delivered this many times, then sent to DeadLetter. Not allowed with
+	// Durable.
+	MaxDeliveries int
+
+	// Backpressure overrides the topic's policy for when this subscription
+	// falls behind. Not allowed with Durable.
+	Backpressure *Backpressure
+
+	// Preferences picks the variants delivered when producers publish one
+	// message per variant, marked with a header such as "locale": a message
+	// carrying a preferred header is delivered only if its value matches.
+	// Messages without the header are always delivered.
+	Preferences map[string]string
+
+	// Partition subscribes to one partition of a partitioned topic, so
+	// consumers can split its keys between them. LastN replays from the
+	// partition's own buffer. Not allowed with Durable.
+	Partition *int
+
+	// ValidateOrdering asks the server to attach delivery sequence numbers
+	// to events and checks them for loss, duplication and reordering; see
+	// Options.OnOrderingViolation. Meant for debugging.
+	ValidateOrdering bool
+
+	// TTL ends the subscription this long after subscribing, with
+	// CloseReasonSubscriptionExpired, for consumers that must not stay
+	// subscribed forever. A subscription restored on reconnect keeps its
+	// original end.
+	TTL time.Duration
+
+	// OnClosed is called when the server ends the subscription, with one of
+	// the CloseReason values. The subscription is not restored on reconnect.
+	OnClosed func(reason string)
+
+	// OnDropped is called when the server reports messages dropped for the
+	// subscription under its backpressure policy
+	OnDropped func(Dropped)
+}
+
+// Backpressure is what the server does when a subscription's queue is full
+type Backpressure struct {
+	Policy    string `json:"policy,omitempty"`     // one of the Backpressure policies
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // BackpressureBlock wait; 0 uses the server default
+}
+
+// Backpressure policies
+const (
+	BackpressureDropNewest = "drop_newest"
+	BackpressureDropOldest = "drop_oldest"
+	BackpressureBlock      = "block_with_timeout"
+	BackpressureDisconnect = "disconnect"
+)
+
+// Dropped reports messages the server dropped for a subscription
+type Dropped struct {
+	Count  uint64 `json:"count"` // since the previous report
+	Total  uint64 `json:"total"` // since subscribing
+	Policy string `json:"policy"`
+}
+
+// Reasons the server gives for ending a subscription
+const (
+	CloseReasonTopicDeleted = "topic_deleted"
+	CloseReasonTopicExpired = "topic_expired"
+	CloseReasonAdminKick    = "admin_kick"
+	CloseReasonShutdown     = "shutdown"
+	CloseReasonBackpressure = "backpressure"
+
+	// CloseReasonRepartitioned ends a subscription to one partition when the
+	// topic's partition count changes
+	CloseReasonRepartitioned = "repartitioned"
+
+	// CloseReasonSubscriptionExpired ends a subscription when its
+	// SubscribeOptions.TTL runs out
+	CloseReasonSubscriptionExpired = "subscription_expired"
+)
+
+// Handler is called for every event delivered on a subscription
+type Handler func(msg *Message)
+
+// request is a frame sent to the gateway
+type request struct {
+	Type      string     `json:"type"`
+	Topic     string     `json:"topic,omitempty"`
+	Message   *Message   `json:"message,omitempty"`
+	LastN     int        `json:"last_n,omitempty"`
+	Since     string     `json:"since,omitempty"`
+	AfterSeq  *uint64    `json:"after_seq,omitempty"`
+	Sampling  *Sampling  `json:"sampling,omitempty"`
+	TagFilter *TagFilter `json:"tag_filter,omitempty"`
+	Durable   bool       `json:"durable,omitempty"`
+	Seq       uint64     `json:"seq,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+
+	ValidateOrdering bool       `json:"validate_ordering,omitempty"`
+	DeadLetter       string     `json:"dead_letter,omitempty"`
+	ClientTime       *time.Time `json:"client_ts,omitempty"`
+	MaxDeliveries    int        `json:"max_deliveries,omitempty"`
+	Requeue          *bool      `json:"requeue,omitempty"`
+
+	Backpressure *Backpressure     `json:"backpressure,omitempty"`
+	Preferences  map[string]string `json:"preferences,omitempty"`
+
+	Partition *int   `json:"partition,omitempty"`
+	TTL       string `json:"ttl,omitempty"`
+}
+
+// response is a frame received from the gateway
+type response struct {
+	Type       string        `json:"type"`
+	RequestID  string        `json:"request_id,omitempty"`
+	Topic      string        `json:"topic,omitempty"`
+	Message    *Message      `json:"message,omitempty"`
+	Error      *Error        `json:"error,omitempty"`
+	Status     string        `json:"status,omitempty"`
+	Msg        string        `json:"msg,omitempty"`
+	ReadMarker *ReadMarker   `json:"read_marker,omitempty"`
+	Ordering   *orderingInfo `json:"ordering,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	Time       *timeInfo     `json:"time,omitempty"`
+	Nack       *NackResult   `json:"nack,omitempty"`
+	Timestamp  time.Time     `json:"ts"`
+
+	Dropped *Dropped `json:"dropped,omitempty"`
+}
+
+// ReadMarker is how far the client has read a topic
+type ReadMarker struct {
+	Read   uint64 `json:"read"`
+	Head   uint64 `json:"head"`
+	Unread uint64 `json:"unread"`
+}
+
+// NackResult is what the gateway did with a nacked message
+type NackResult struct {
+	Seq        uint64 `json:"seq"`
+	Outcome    string `json:"outcome"`          // NackRedelivered, NackDeadLettered or NackDiscarded
+	Deliveries int    `json:"deliveries"`       // times the message has been delivered
+	Reason     string `json:"reason,omitempty"` // dead-letter reason, unless redelivered
+}
+
+// Outcomes of a nack
+const (
+	NackRedelivered  = "redelivered"
+	NackDeadLettered = "dead_lettered"
+	NackDiscarded    = "discarded"
+)
+
+// Error is an error frame returned by the gateway
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
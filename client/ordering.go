@@ -0,0 +1,95 @@
+package client
+
+import (
+	"fmt"
+	"log"
+)
+
+// Kinds of ordering violation reported for validate_ordering subscriptions
+const (
+	// ViolationLost: events the server sent never reached the handler
+	ViolationLost = "lost"
+	// ViolationDuplicate: an event the server sent once arrived again
+	ViolationDuplicate = "duplicate"
+	// ViolationOutOfOrder: the server sent an older message after a newer one
+	ViolationOutOfOrder = "out_of_order"
+	// ViolationServerGap: the server skipped topic seqs for this subscriber.
+	// Expected with sampling; otherwise the subscriber was too slow and
+	// messages were dropped, or they fell out of the replay buffer.
+	ViolationServerGap = "server_gap"
+)
+
+// OrderingViolation describes one broken ordering invariant
+type OrderingViolation struct {
+	Topic    string
+	Kind     string
+	Expected uint64 // expected delivery_seq (lost, duplicate) or topic seq (out_of_order, server_gap)
+	Got      uint64
+	Message  *Message
+}
+
+func (v OrderingViolation) String() string {
+	return fmt.Sprintf("%s on topic %s: expected %d, got %d (message %s)",
+		v.Kind, v.Topic, v.Expected, v.Got, v.Message.ID)
+}
+
+// orderingInfo is attached by the server to events on validate_ordering
+// subscriptions
+type orderingInfo struct {
+	DeliverySeq uint64 `json:"delivery_seq"`
+	PrevSeq     uint64 `json:"prev_seq"`
+}
+
+// orderingState is what the client has received on one subscription
+type orderingState struct {
+	deliverySeq uint64
+	seq         uint64
+}
+
+// checkOrdering validates an event against what was previously received on
+// its subscription and reports every violation
+func (c *Client) checkOrdering(topic string, msg *Message, info *orderingInfo) {
+	c.mu.Lock()
+	state, tracked := c.ordering[topic]
+	if !tracked || info == nil {
+		c.mu.Unlock()
+		return
+	}
+	last := *state
+	state.deliverySeq = info.DeliverySeq
+	state.seq = msg.Seq
+	c.mu.Unlock()
+
+	violation := OrderingViolation{Topic: topic, Message: msg}
+
+	switch {
+	case info.DeliverySeq > last.deliverySeq+1:
+		violation.Kind, violation.Expected, violation.Got = ViolationLost, last.deliverySeq+1, info.DeliverySeq
+		c.reportViolation(violation)
+	case info.DeliverySeq <= last.deliverySeq:
+		violation.Kind, violation.Expected, violation.Got = ViolationDuplicate, last.deliverySeq+1, info.DeliverySeq
+		c.reportViolation(violation)
+	}
+
+	if info.PrevSeq == 0 {
+		return // first event on the subscription
+	}
+	switch {
+	case msg.Seq <= info.PrevSeq:
+		violation.Kind, violation.Expected, violation.Got = ViolationOutOfOrder, info.PrevSeq+1, msg.Seq
+		c.reportViolation(violation)
+	case msg.Seq > info.PrevSeq+1:
+		violation.Kind, violation.Expected, violation.Got = ViolationServerGap, info.PrevSeq+1, msg.Seq
+		c.reportViolation(violation)
+	}
+}
+
+// reportViolation passes a violation to Options.OnOrderingViolation, or
+// logs it when no callback is set
+func (c *Client) reportViolation(violation OrderingViolation) {
+	if c.opts.OnOrderingViolation != nil {
+		c.opts.OnOrderingViolation(violation)
+		return
+	}
+	log.Printf("pubsub client: ordering violation: %s", violation)
+}
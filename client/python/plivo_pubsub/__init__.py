@@ -0,0 +1,62 @@
+"""Python client for the PubSub gateway WebSocket API.
+
+``AsyncClient`` is the asyncio API and ``Client`` the blocking one; both
+mirror the Go SDK in the ``client`` module.
+"""
+
+from ._websocket import ConnectionClosed, HandshakeError
+from .aio import AsyncClient
+from .client import Client
+from .models import (
+    BACKPRESSURE_BLOCK,
+    BACKPRESSURE_DISCONNECT,
+    BACKPRESSURE_DROP_NEWEST,
+    BACKPRESSURE_DROP_OLDEST,
+    CLOSE_REASON_ADMIN_KICK,
+    CLOSE_REASON_BACKPRESSURE,
+    CLOSE_REASON_REPARTITIONED,
+    CLOSE_REASON_SUBSCRIPTION_EXPIRED,
+    CLOSE_REASON_SHUTDOWN,
+    CLOSE_REASON_TOPIC_DELETED,
+    CLOSE_REASON_TOPIC_EXPIRED,
+    ERROR_CODE_DISCONNECTED,
+    ClientClosedError,
+    DeadLetter,
+    Message,
+    PubSubError,
+    ReadMarker,
+    Sampling,
+    SubscribeOptions,
+    TagFilter,
+    TooManyConnectionsError,
+    as_dead_letter,
+)
+
+__all__ = [
+    "AsyncClient",
+    "Client",
+    "BACKPRESSURE_BLOCK",
+    "BACKPRESSURE_DISCONNECT",
+    "BACKPRESSURE_DROP_NEWEST",
+    "BACKPRESSURE_DROP_OLDEST",
+    "CLOSE_REASON_ADMIN_KICK",
+    "CLOSE_REASON_BACKPRESSURE",
+    "CLOSE_REASON_REPARTITIONED",
+    "CLOSE_REASON_SUBSCRIPTION_EXPIRED",
+    "CLOSE_REASON_SHUTDOWN",
+    "CLOSE_REASON_TOPIC_DELETED",
+    "CLOSE_REASON_TOPIC_EXPIRED",
+    "ERROR_CODE_DISCONNECTED",
+    "ClientClosedError",
+    "ConnectionClosed",
+    "HandshakeError",
+    "DeadLetter",
+    "Message",
+    "PubSubError",
+    "ReadMarker",
+    "Sampling",
+    "SubscribeOptions",
+    "TagFilter",
+    "TooManyConnectionsError",
+    "as_dead_letter",
+]
@@ -0,0 +1,203 @@
+"""A minimal RFC 6455 WebSocket client on asyncio streams, so the SDK has
+no dependencies. It sends masked text frames and answers pings; extensions
+and subprotocols are not supported."""
+
+import asyncio
+import base64
+import hashlib
+import os
+import ssl as ssl_module
+import struct
+from typing import Dict, Optional, Tuple
+from urllib.parse import urlsplit
+
+_GUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+OP_CONTINUATION = 0x0
+OP_TEXT = 0x1
+OP_BINARY = 0x2
+OP_CLOSE = 0x8
+OP_PING = 0x9
+OP_PONG = 0xA
+
+CLOSE_NORMAL = 1000
+
+
+class HandshakeError(Exception):
+    """The server refused the WebSocket upgrade."""
+
+    def __init__(self, status: int, body: bytes):
+        super().__init__(f"websocket upgrade failed with status {status}: {body.decode(errors='replace')}")
+        self.status = status
+        self.body = body
+
+
+class ConnectionClosed(Exception):
+    """The connection was closed, cleanly or not."""
+
+    def __init__(self, code: int = 1006, reason: str = ""):
+        super().__init__(f"connection closed ({code}){': ' + reason if reason else ''}")
+        self.code = code
+        self.reason = reason
+
+
+async def connect(
+    url: str,
+    headers: Dict[str, str],
+    ssl: Optional[ssl_module.SSLContext] = None,
+    timeout: float = 10.0,
+) -> "Connection":
+    """Opens a WebSocket connection to a ws:// or wss:// URL."""
+    parts = urlsplit(url)
+    if parts.scheme not in ("ws", "wss"):
+        raise ValueError(f"invalid endpoint {url!r}: scheme must be ws or wss")
+    secure = parts.scheme == "wss"
+    host = parts.hostname or ""
+    port = parts.port or (443 if secure else 80)
+    path = (parts.path or "/") + ("?" + parts.query if parts.query else "")
+
+    if secure and ssl is None:
+        ssl = ssl_module.create_default_context()
+
+    async def handshake() -> Connection:
+        reader, writer = await asyncio.open_connection(
+            host, port, ssl=ssl if secure else None, server_hostname=host if secure else None
+        )
+        key = base64.b64encode(os.urandom(16)).decode()
+        lines = [
+            f"GET {path} HTTP/1.1",
+            f"Host: {parts.netloc}",
+            "Upgrade: websocket",
+            "Connection: Upgrade",
+            f"Sec-WebSocket-Key: {key}",
+            "Sec-WebSocket-Version: 13",
+        ]
+        lines += [f"{name}: {value}" for name, value in headers.items()]
+        writer.write(("\r\n".join(lines) + "\r\n\r\n").encode())
+        await writer.drain()
+
+        status, response_headers = await _read_response_head(reader)
+        if status != 101:
+            length = int(response_headers.get("content-length", "0") or 0)
+            body = await reader.readexactly(length) if length else b""
+            writer.close()
+            raise HandshakeError(status, body)
+
+        accept = base64.b64encode(hashlib.sha1((key + _GUID).encode()).digest()).decode()
+        if response_headers.get("sec-websocket-accept") != accept:
+            writer.close()
+            raise HandshakeError(status, b"invalid Sec-WebSocket-Accept")
+        return Connection(reader, writer)
+
+    return await asyncio.wait_for(handshake(), timeout)
+
+
+async def _read_response_head(reader: asyncio.StreamReader) -> Tuple[int, Dict[str, str]]:
+    head = await reader.readuntil(b"\r\n\r\n")
+    lines = head.decode("latin-1").split("\r\n")
+    status = int(lines[0].split(" ", 2)[1])
+    headers = {}
+    for line in lines[1:]:
+        if ":" in line:
+            name, value = line.split(":", 1)
+            headers[name.strip().lower()] = value.strip()
+    return status, headers
+
+
+class Connection:
+    """An open WebSocket connection. recv must only be called from one
+    task; send may be called from any."""
+
+    def __init__(self, reader: asyncio.StreamReader, writer: asyncio.StreamWriter):
+        self._reader = reader
+        self._writer = writer
+        self._write_lock = asyncio.Lock()
+        self._close_sent = False
+
+    async def send_text(self, text: str) -> None:
+        await self._send(OP_TEXT, text.encode())
+
+    async def recv(self) -> str:
+        """Returns the next text or binary message, answering pings on the
+        way. Raises ConnectionClosed once the connection ends."""
+        fragments = []
+        while True:
+            try:
+                opcode, payload, fin = await self._read_frame()
+            except (asyncio.IncompleteReadError, ConnectionError) as exc:
+                self.abort()
+                if self._close_sent:
+                    raise ConnectionClosed(CLOSE_NORMAL) from exc
+                raise ConnectionClosed(1006, str(exc)) from exc
+
+            if opcode == OP_PING:
+                await self._send(OP_PONG, payload)
+                continue
+            if opcode == OP_PONG:
+                continue
+            if opcode == OP_CLOSE:
+                code = struct.unpack("!H", payload[:2])[0] if len(payload) >= 2 else 1005
+                reason = payload[2:].decode(errors="replace")
+                await self.close(code)
+                self.abort()
+                raise ConnectionClosed(code, reason)
+
+            fragments.append(payload)
+            if fin:
+                return b"".join(fragments).decode()
+
+    async def close(self, code: int = CLOSE_NORMAL) -> None:
+        """Starts the closing handshake; recv raises ConnectionClosed once
+        the server answers."""
+        if not self._close_sent:
+            self._close_sent = True
+            try:
+                await self._send(OP_CLOSE, struct.pack("!H", code))
+            except (ConnectionError, RuntimeError):
+                self.abort()
+
+    def abort(self) -> None:
+        """Closes the underlying transport."""
+        self._writer.close()
+
+    async def _send(self, opcode: int, payload: bytes) -> None:
+        header = bytearray([0x80 | opcode])
+        length = len(payload)
+        if length < 126:
+            header.append(0x80 | length)
+        elif length < 1 << 16:
+            header.append(0x80 | 126)
+            header += struct.pack("!H", length)
+        else:
+            header.append(0x80 | 127)
+            header += struct.pack("!Q", length)
+        mask = os.urandom(4)
+        header += mask
+        masked = _apply_mask(payload, mask)
+
+        async with self._write_lock:
+            self._writer.write(bytes(header) + masked)
+            await self._writer.drain()
+
+    async def _read_frame(self) -> Tuple[int, bytes, bool]:
+        first, second = await self._reader.readexactly(2)
+        fin = bool(first & 0x80)
+        opcode = first & 0x0F
+        length = second & 0x7F
+        if length == 126:
+            (length,) = struct.unpack("!H", await self._reader.readexactly(2))
+        elif length == 127:
+            (length,) = struct.unpack("!Q", await self._reader.readexactly(8))
+        mask = await self._reader.readexactly(4) if second & 0x80 else None
+        payload = await self._reader.readexactly(length)
+        if mask:
+            payload = _apply_mask(payload, mask)
+        return opcode, payload, fin
+
+
+def _apply_mask(data: bytes, mask: bytes) -> bytes:
+    """XORs data with the repeated 4-byte mask."""
+    if not data:
+        return data
+    key = (mask * (len(data) // 4 + 1))[: len(data)]
+    return (int.from_bytes(data, "big") ^ int.from_bytes(key, "big")).to_bytes(len(data), "big")
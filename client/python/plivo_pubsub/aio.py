@@ -0,0 +1,225 @@
+"""The asyncio client."""
+
+import asyncio
+import inspect
+import itertools
+import json
+import logging
+import ssl as ssl_module
+from typing import Any, Awaitable, Callable, Dict, Optional, Union
+
+from . import _websocket
+from .models import (
+    ERROR_CODE_DISCONNECTED,
+    ClientClosedError,
+    Message,
+    PubSubError,
+    ReadMarker,
+    SubscribeOptions,
+    TooManyConnectionsError,
+)
+
+DEFAULT_HANDSHAKE_TIMEOUT = 10.0
+DEFAULT_REQUEST_TIMEOUT = 10.0
+
+logger = logging.getLogger("plivo_pubsub")
+
+# Handler is called for every event delivered on a subscription; it may be
+# a coroutine function, which is awaited before the next frame is read
+Handler = Callable[[Message], Union[None, Awaitable[None]]]
+
+
+class AsyncClient:
+    """A WebSocket client for the PubSub gateway. Create one with
+    ``await AsyncClient.connect(...)``."""
+
+    def __init__(self, conn: _websocket.Connection, request_timeout: float):
+        self._conn = conn
+        self._request_timeout = request_timeout
+        self._ids = itertools.count(1)
+        self._pending: Dict[str, asyncio.Future] = {}
+        self._handlers: Dict[str, Handler] = {}
+        self._subscriptions: Dict[str, SubscribeOptions] = {}
+        self._error: Optional[BaseException] = None
+        self._reader = asyncio.get_running_loop().create_task(self._read_loop())
+
+    @classmethod
+    async def connect(
+        cls,
+        endpoint: str,
+        token: str,
+        *,
+        ssl: Optional[ssl_module.SSLContext] = None,
+        headers: Optional[Dict[str, str]] = None,
+        handshake_timeout: float = DEFAULT_HANDSHAKE_TIMEOUT,
+        request_timeout: float = DEFAULT_REQUEST_TIMEOUT,
+    ) -> "AsyncClient":
+        """Connects to the gateway WebSocket endpoint (e.g.
+        ws://host:8000/ws) authenticating with the given JWT token."""
+        all_headers = dict(headers or {})
+        all_headers["Authorization"] = "Bearer " + token
+        try:
+            conn = await _websocket.connect(endpoint, all_headers, ssl=ssl, timeout=handshake_timeout)
+        except _websocket.HandshakeError as exc:
+            if exc.status == 429 and _error_body(exc.body) == "too many connections":
+                raise TooManyConnectionsError() from exc
+            raise
+        return cls(conn, request_timeout)
+
+    async def subscribe(self, topic: str, handler: Handler, options: Optional[SubscribeOptions] = None) -> None:
+        """Subscribes to a topic, calling handler for every delivered event."""
+        options = options or SubscribeOptions()
+        self._handlers[topic] = handler
+        self._subscriptions[topic] = options
+        try:
+            await self._round_trip({"type": "subscribe", "topic": topic, **options.to_request()})
+        except BaseException:
+            self._handlers.pop(topic, None)
+            self._subscriptions.pop(topic, None)
+            raise
+
+    async def unsubscribe(self, topic: str) -> None:
+        """Removes the subscription to a topic."""
+        await self._round_trip({"type": "unsubscribe", "topic": topic})
+        self._handlers.pop(topic, None)
+        self._subscriptions.pop(topic, None)
+
+    async def publish(self, topic: str, message: Union[Message, Dict[str, Any]]) -> None:
+        """Publishes a message to a topic and waits for the gateway ack. A
+        dict is sent as the message as-is."""
+        if isinstance(message, Message):
+            message = message.to_dict()
+        await self._round_trip({"type": "publish", "topic": topic, "message": message})
+
+    async def ack(self, topic: str, seq: int) -> None:
+        """Acknowledges all messages up to seq on a durable subscription."""
+        await self._round_trip({"type": "ack", "topic": topic, "seq": seq})
+
+    async def mark_read(self, topic: str, seq: int) -> ReadMarker:
+        """Records that messages up to seq on topic have been read and
+        returns the updated marker with the remaining unread count."""
+        response = await self._round_trip({"type": "mark_read", "topic": topic, "seq": seq})
+        marker = response.get("read_marker") or {}
+        return ReadMarker(read=marker.get("read", 0), head=marker.get("head", 0), unread=marker.get("unread", 0))
+
+    async def ping(self) -> None:
+        """Sends a ping and waits for the pong."""
+        await self._round_trip({"type": "ping"})
+
+    @property
+    def closed(self) -> bool:
+        """Whether the connection has terminated."""
+        return self._reader.done()
+
+    @property
+    def error(self) -> Optional[BaseException]:
+        """The error that terminated the connection, if any."""
+        return self._error
+
+    async def wait_closed(self) -> None:
+        """Waits until the connection terminates."""
+        await asyncio.shield(self._reader)
+
+    async def close(self) -> None:
+        """Closes the connection, waiting for the server to acknowledge."""
+        await self._conn.close()
+        try:
+            await asyncio.wait_for(asyncio.shield(self._reader), self._request_timeout)
+        except asyncio.TimeoutError:
+            self._reader.cancel()
+        finally:
+            self._conn.abort()
+
+    async def __aenter__(self) -> "AsyncClient":
+        return self
+
+    async def __aexit__(self, *exc_info) -> None:
+        await self.close()
+
+    async def _round_trip(self, request: Dict[str, Any]) -> Dict[str, Any]:
+        """Sends a request and waits for the response with the same request
+        ID, raising PubSubError for error frames."""
+        if self.closed:
+            raise ClientClosedError()
+
+        request_id = str(next(self._ids))
+        request["request_id"] = request_id
+        future = asyncio.get_running_loop().create_future()
+        self._pending[request_id] = future
+        try:
+            try:
+                await self._conn.send_text(json.dumps(request))
+            except (ConnectionError, RuntimeError) as exc:
+                raise ClientClosedError(f"failed to send {request['type']} request: {exc}") from exc
+            response = await asyncio.wait_for(future, self._request_timeout)
+        finally:
+            self._pending.pop(request_id, None)
+
+        error = response.get("error")
+        if error:
+            raise PubSubError(error.get("code", ""), error.get("message", ""))
+        return response
+
+    async def _read_loop(self) -> None:
+        """Dispatches responses to waiting callers and events to handlers
+        until the connection ends, then fails the requests in flight."""
+        try:
+            while True:
+                response = json.loads(await self._conn.recv())
+                await self._dispatch(response)
+        except _websocket.ConnectionClosed as exc:
+            if exc.code != _websocket.CLOSE_NORMAL:
+                self._error = exc
+        except ValueError as exc:
+            self._error = exc
+            self._conn.abort()
+        finally:
+            cause = self._error or ClientClosedError()
+            for future in self._pending.values():
+                if not future.done():
+                    future.set_result({"error": {"code": ERROR_CODE_DISCONNECTED, "message": str(cause)}})
+
+    async def _dispatch(self, response: Dict[str, Any]) -> None:
+        kind = response.get("type")
+        topic = response.get("topic", "")
+
+        if kind == "event" and response.get("message") is not None:
+            handler = self._handlers.get(topic)
+            if handler is not None:
+                try:
+                    result = handler(Message.from_dict(response["message"]))
+                    if inspect.isawaitable(result):
+                        await result
+                except Exception:
+                    logger.exception("Handler for topic %s failed", topic)
+            return
+
+        if kind == "subscription_closed":
+            options = self._subscriptions.pop(topic, None)
+            self._handlers.pop(topic, None)
+            if options is not None and options.on_closed is not None:
+                try:
+                    options.on_closed(response.get("reason", ""))
+                except Exception:
+                    logger.exception("on_closed for topic %s failed", topic)
+            return
+
+        if kind == "dropped" and response.get("dropped") is not None:
+            options = self._subscriptions.get(topic)
+            if options is not None and options.on_dropped is not None:
+                try:
+                    options.on_dropped(response["dropped"])
+                except Exception:
+                    logger.exception("on_dropped for topic %s failed", topic)
+            return
+
+        future = self._pending.get(response.get("request_id", ""))
+        if future is not None and not future.done():
+            future.set_result(response)
+
+
+def _error_body(body: bytes) -> str:
+    try:
+        return json.loads(body).get("error", "")
+    except (ValueError, AttributeError):
+        return ""
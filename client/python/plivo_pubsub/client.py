@@ -0,0 +1,139 @@
+"""The blocking client, running an AsyncClient on a background event loop."""
+
+import asyncio
+import concurrent.futures
+import threading
+from typing import Any, Callable, Coroutine, Dict, Optional, TypeVar, Union
+
+from .aio import DEFAULT_HANDSHAKE_TIMEOUT, DEFAULT_REQUEST_TIMEOUT, AsyncClient
+from .models import Message, ReadMarker, SubscribeOptions
+
+T = TypeVar("T")
+
+
+class Client:
+    """A blocking WebSocket client for the PubSub gateway. Create one with
+    ``Client.connect(...)``.
+
+    Handlers run on the client's event loop thread, one event at a time, so
+    they must not call the client's blocking methods; hand the work to
+    another thread instead.
+    """
+
+    def __init__(self, loop: asyncio.AbstractEventLoop, thread: threading.Thread, client: AsyncClient):
+        self._loop = loop
+        self._thread = thread
+        self._client = client
+
+    @classmethod
+    def connect(
+        cls,
+        endpoint: str,
+        token: str,
+        *,
+        ssl=None,
+        headers: Optional[Dict[str, str]] = None,
+        handshake_timeout: float = DEFAULT_HANDSHAKE_TIMEOUT,
+        request_timeout: float = DEFAULT_REQUEST_TIMEOUT,
+    ) -> "Client":
+        """Connects to the gateway WebSocket endpoint (e.g.
+        ws://host:8000/ws) authenticating with the given JWT token."""
+        loop = asyncio.new_event_loop()
+        thread = threading.Thread(target=loop.run_forever, name="plivo-pubsub", daemon=True)
+        thread.start()
+
+        future = asyncio.run_coroutine_threadsafe(
+            AsyncClient.connect(
+                endpoint,
+                token,
+                ssl=ssl,
+                headers=headers,
+                handshake_timeout=handshake_timeout,
+                request_timeout=request_timeout,
+            ),
+            loop,
+        )
+        try:
+            client = future.result()
+        except BaseException:
+            _stop_loop(loop, thread)
+            raise
+        return cls(loop, thread, client)
+
+    def subscribe(
+        self,
+        topic: str,
+        handler: Callable[[Message], Any],
+        options: Optional[SubscribeOptions] = None,
+    ) -> None:
+        """Subscribes to a topic, calling handler for every delivered event."""
+        self._run(self._client.subscribe(topic, handler, options))
+
+    def unsubscribe(self, topic: str) -> None:
+        """Removes the subscription to a topic."""
+        self._run(self._client.unsubscribe(topic))
+
+    def publish(self, topic: str, message: Union[Message, Dict[str, Any]]) -> None:
+        """Publishes a message to a topic and waits for the gateway ack."""
+        self._run(self._client.publish(topic, message))
+
+    def ack(self, topic: str, seq: int) -> None:
+        """Acknowledges all messages up to seq on a durable subscription."""
+        self._run(self._client.ack(topic, seq))
+
+    def mark_read(self, topic: str, seq: int) -> ReadMarker:
+        """Records that messages up to seq on topic have been read."""
+        return self._run(self._client.mark_read(topic, seq))
+
+    def ping(self) -> None:
+        """Sends a ping and waits for the pong."""
+        self._run(self._client.ping())
+
+    @property
+    def closed(self) -> bool:
+        """Whether the connection has terminated."""
+        return self._client.closed
+
+    @property
+    def error(self) -> Optional[BaseException]:
+        """The error that terminated the connection, if any."""
+        return self._client.error
+
+    def wait(self, timeout: Optional[float] = None) -> bool:
+        """Blocks until the connection terminates or timeout seconds pass,
+        returning whether it terminated."""
+        future = asyncio.run_coroutine_threadsafe(self._client.wait_closed(), self._loop)
+        try:
+            future.result(timeout)
+        except concurrent.futures.TimeoutError:
+            future.cancel()
+        return self._client.closed
+
+    def close(self) -> None:
+        """Closes the connection and stops the event loop thread."""
+        if not self._loop.is_running():
+            return
+        try:
+            self._run(self._client.close())
+        finally:
+            _stop_loop(self._loop, self._thread)
+
+    def __enter__(self) -> "Client":
+        return self
+
+    def __exit__(self, *exc_info) -> None:
+        self.close()
+
+    def _run(self, coro: Coroutine[Any, Any, T]) -> T:
+        if threading.current_thread() is self._thread:
+            coro.close()
+            raise RuntimeError("blocking Client methods cannot be called from a handler")
+        return asyncio.run_coroutine_threadsafe(coro, self._loop).result()
+
+
+def _stop_loop(loop: asyncio.AbstractEventLoop, thread: threading.Thread) -> None:
+    """Stops a loop running on thread and releases its resolver threads."""
+    loop.call_soon_threadsafe(loop.stop)
+    thread.join()
+    loop.run_until_complete(loop.shutdown_default_executor())
+    loop.close()
@@ -0,0 +1,259 @@
+"""Messages, options and errors of the gateway protocol."""
+
+import re
+from dataclasses import dataclass, field
+from datetime import datetime, timedelta, timezone
+from typing import Any, Callable, Dict, List, Optional
+
+# Reasons the server gives for ending a subscription
+CLOSE_REASON_TOPIC_DELETED = "topic_deleted"
+CLOSE_REASON_TOPIC_EXPIRED = "topic_expired"
+CLOSE_REASON_ADMIN_KICK = "admin_kick"
+CLOSE_REASON_SHUTDOWN = "shutdown"
+CLOSE_REASON_BACKPRESSURE = "backpressure"
+CLOSE_REASON_REPARTITIONED = "repartitioned"
+CLOSE_REASON_SUBSCRIPTION_EXPIRED = "subscription_expired"
+
+# Backpressure policies
+BACKPRESSURE_DROP_NEWEST = "drop_newest"
+BACKPRESSURE_DROP_OLDEST = "drop_oldest"
+BACKPRESSURE_BLOCK = "block_with_timeout"
+BACKPRESSURE_DISCONNECT = "disconnect"
+
+# ERROR_CODE_DISCONNECTED fails requests in flight when the connection drops
+ERROR_CODE_DISCONNECTED = "DISCONNECTED"
+
+# Tag match modes
+TAG_MATCH_ANY = "any"
+TAG_MATCH_ALL = "all"
+
+
+class PubSubError(Exception):
+    """An error frame returned by the gateway."""
+
+    def __init__(self, code: str, message: str):
+        super().__init__(f"{code}: {message}")
+        self.code = code
+        self.message = message
+
+
+class ClientClosedError(Exception):
+    """Raised for operations on a closed client."""
+
+    def __init__(self, message: str = "client closed"):
+        super().__init__(message)
+
+
+class TooManyConnectionsError(Exception):
+    """Raised by connect when the user already holds as many connections as
+    the gateway allows."""
+
+    def __init__(self):
+        super().__init__("too many connections")
+
+
+@dataclass
+class Message:
+    """A published message. ``seq``, ``topic`` and ``timestamp`` are set by
+    the gateway on delivered events."""
+
+    id: str
+    payload: Any = None
+    seq: int = 0
+    topic: str = ""
+    timestamp: Optional[datetime] = None
+    tombstone: str = ""  # set on tombstones: ID of a deleted message to purge
+    tags: List[str] = field(default_factory=list)
+    headers: Dict[str, str] = field(default_factory=dict)
+    origin: str = ""  # region the message was published in
+    origin_seq: int = 0  # sequence number in the origin region
+    # schema_version picks the topic schema version a publish is validated
+    # against, 0 meaning the latest; events carry the version used
+    schema_version: int = 0
+    # priority "high" delivers the message ahead of the normal ones queued
+    # for each subscriber; empty means "normal"
+    priority: str = ""
+    # key picks the partition of a partitioned topic: messages with the
+    # same key are delivered in the order they were published
+    key: str = ""
+
+    def to_dict(self) -> Dict[str, Any]:
+        data: Dict[str, Any] = {"id": self.id, "payload": self.payload}
+        if self.tags:
+            data["tags"] = self.tags
+        if self.headers:
+            data["headers"] = self.headers
+        if self.schema_version:
+            data["schema_version"] = self.schema_version
+        if self.priority:
+            data["priority"] = self.priority
+        if self.key:
+            data["key"] = self.key
+        return data
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Message":
+        return cls(
+            id=data.get("id", ""),
+            payload=data.get("payload"),
+            seq=data.get("seq", 0),
+            topic=data.get("topic", ""),
+            timestamp=parse_time(data.get("timestamp")),
+            tombstone=data.get("tombstone", ""),
+            tags=data.get("tags") or [],
+            headers=data.get("headers") or {},
+            origin=data.get("origin", ""),
+            origin_seq=data.get("origin_seq", 0),
+            schema_version=data.get("schema_version", 0),
+            priority=data.get("priority", ""),
+            key=data.get("key", ""),
+        )
+
+
+@dataclass
+class Sampling:
+    """Restricts a subscription to every nth message, or a random rate."""
+
+    every_n: int = 0
+    rate: float = 0.0
+
+    def to_dict(self) -> Dict[str, Any]:
+        data: Dict[str, Any] = {}
+        if self.every_n:
+            data["every_n"] = self.every_n
+        if self.rate:
+            data["rate"] = self.rate
+        return data
+
+
+@dataclass
+class TagFilter:
+    """Restricts a subscription to messages carrying any (the default) or
+    all of ``tags``."""
+
+    tags: List[str]
+    match: str = ""
+
+    def to_dict(self) -> Dict[str, Any]:
+        data: Dict[str, Any] = {"tags": self.tags}
+        if self.match:
+            data["match"] = self.match
+        return data
+
+
+@dataclass
+class SubscribeOptions:
+    """Per-subscription settings, as in the Go SDK's SubscribeOptions."""
+
+    last_n: int = 0
+    sampling: Optional[Sampling] = None
+    tag_filter: Optional[TagFilter] = None
+    durable: bool = False  # resume from the last acked seq on resubscribe
+    # since replays the messages published within this long before
+    # subscribing; with last_n too, only the newest last_n of them
+    since: Optional[timedelta] = None
+    # after_seq replays every buffered message with a greater seq; it cannot
+    # be combined with last_n or since
+    after_seq: Optional[int] = None
+    # dead_letter names a topic receiving the messages dropped for this
+    # subscription because it fell behind; not allowed with durable
+    dead_letter: str = ""
+    # backpressure overrides the topic's policy for when this subscription
+    # falls behind, with backpressure_timeout_ms bounding the wait of
+    # BACKPRESSURE_BLOCK; not allowed with durable
+    backpressure: str = ""
+    backpressure_timeout_ms: int = 0
+    # preferences picks the variants delivered when producers publish one
+    # message per variant marked with a header such as "locale": a message
+    # carrying a preferred header is delivered only if its value matches
+    preferences: Dict[str, str] = field(default_factory=dict)
+    # partition subscribes to one partition of a partitioned topic, with
+    # last_n replaying from the partition's own buffer; not allowed with
+    # durable
+    partition: Optional[int] = None
+    # ttl ends the subscription this long after subscribing, with
+    # CLOSE_REASON_SUBSCRIPTION_EXPIRED
+    ttl: Optional[timedelta] = None
+    # on_closed is called with one of the CLOSE_REASON values when the
+    # server ends the subscription
+    on_closed: Optional[Callable[[str], Any]] = None
+    # on_dropped is called with the server's dropped report, a dict with
+    # count, total and policy, when messages are dropped for the subscription
+    on_dropped: Optional[Callable[[Dict[str, Any]], Any]] = None
+
+    def to_request(self) -> Dict[str, Any]:
+        data: Dict[str, Any] = {}
+        if self.last_n:
+            data["last_n"] = self.last_n
+        if self.since:
+            data["since"] = f"{self.since.total_seconds():g}s"
+        if self.after_seq is not None:
+            data["after_seq"] = self.after_seq
+        if self.sampling:
+            data["sampling"] = self.sampling.to_dict()
+        if self.tag_filter:
+            data["tag_filter"] = self.tag_filter.to_dict()
+        if self.durable:
+            data["durable"] = True
+        if self.dead_letter:
+            data["dead_letter"] = self.dead_letter
+        if self.backpressure:
+            data["backpressure"] = {"policy": self.backpressure}
+            if self.backpressure_timeout_ms:
+                data["backpressure"]["timeout_ms"] = self.backpressure_timeout_ms
+        if self.preferences:
+            data["preferences"] = self.preferences
+        if self.partition is not None:
+            data["partition"] = self.partition
+        if self.ttl:
+            data["ttl"] = f"{self.ttl.total_seconds():g}s"
+        return data
+
+
+@dataclass
+class ReadMarker:
+    """How far the client has read a topic."""
+
+    read: int = 0
+    head: int = 0
+    unread: int = 0
+
+
+@dataclass
+class DeadLetter:
+    """The payload of messages on a dead-letter topic: a message dropped for
+    a subscription, and where it was dropped."""
+
+    topic: str
+    client_id: str
+    reason: str
+    message: Message
+
+
+def as_dead_letter(msg: Message) -> DeadLetter:
+    """Decodes a message received from a dead-letter topic."""
+    payload = msg.payload
+    if not isinstance(payload, dict) or not isinstance(payload.get("message"), dict):
+        raise ValueError("not a dead letter: message is missing")
+    return DeadLetter(
+        topic=payload.get("topic", ""),
+        client_id=payload.get("client_id", ""),
+        reason=payload.get("reason", ""),
+        message=Message.from_dict(payload["message"]),
+    )
+
+
+_FRACTION = re.compile(r"\.(\d+)")
+
+
+def parse_time(value: Optional[str]) -> Optional[datetime]:
+    """Parses an RFC 3339 timestamp, truncating nanoseconds to what
+    datetime holds."""
+    if not value:
+        return None
+    value = value.replace("Z", "+00:00")
+    value = _FRACTION.sub(lambda m: "." + m.group(1)[:6].ljust(6, "0"), value, count=1)
+    parsed = datetime.fromisoformat(value)
+    if parsed.tzinfo is None:
+        parsed = parsed.replace(tzinfo=timezone.utc)
+    return parsed
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeSyncSamples is how many time requests SyncTime sends
+const TimeSyncSamples = 5
+
+// TimeSync is the outcome of SyncTime
+type TimeSync struct {
+	Offset     time.Duration // server clock minus local clock
+	RTT        time.Duration // round trip of the sample the offset was taken from
+	ServerRTT  time.Duration // round trip measured by the server, 0 if not yet known
+	ServerTime time.Time     // server clock when it answered that sample
+}
+
+// timeInfo is the server's answer to a time request
+type timeInfo struct {
+	ClientTime *time.Time `json:"client_ts,omitempty"`
+	ServerTime time.Time  `json:"server_ts"`
+	RTTMillis  *float64   `json:"rtt_ms,omitempty"`
+}
+
+// SyncTime estimates the offset between the local and server clocks. It
+// sends TimeSyncSamples time requests and keeps the one with the shortest
+// round trip, assuming the server answered halfway through it. The offset
+// is then used by ServerNow and Since.
+func (c *Client) SyncTime(ctx context.Context) (TimeSync, error) {
+	var best TimeSync
+	for i := 0; i < TimeSyncSamples; i++ {
+		sent := time.Now()
+		resp, err := c.roundTrip(ctx, &request{Type: "time", ClientTime: &sent})
+		if err != nil {
+			return TimeSync{}, err
+		}
+		rtt := time.Since(sent)
+		if resp.Time == nil {
+			return TimeSync{}, fmt.Errorf("time response without server time")
+		}
+
+		if i > 0 && rtt >= best.RTT {
+			continue
+		}
+		best = TimeSync{
+			Offset:     resp.Time.ServerTime.Sub(sent.Add(rtt / 2)),
+			RTT:        rtt,
+			ServerTime: resp.Time.ServerTime,
+		}
+		if resp.Time.RTTMillis != nil {
+			best.ServerRTT = time.Duration(*resp.Time.RTTMillis * float64(time.Millisecond))
+		}
+	}
+
+	c.clockOffset.Store(int64(best.Offset))
+	return best, nil
+}
+
+// ClockOffset returns the offset found by the last SyncTime, or 0
+func (c *Client) ClockOffset() time.Duration {
+	return time.Duration(c.clockOffset.Load())
+}
+
+// ServerNow returns the current time by the server's clock
+func (c *Client) ServerNow() time.Time {
+	return time.Now().Add(c.ClockOffset())
+}
+
+// Since returns how long ago a server timestamp, such as Message.Timestamp,
+// was by the server's clock
+func (c *Client) Since(t time.Time) time.Duration {
+	return c.ServerNow().Sub(t)
+}
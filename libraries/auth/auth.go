@@ -14,57 +14,119 @@ import (
 
 var (
 	instance AuthInterface
+	clock    Clock = systemClock{} // the clock instance tells the time by
 	once     sync.Once
 	mu       sync.RWMutex
 )
 
-// InitAuth initializes the auth module with configuration using singleton pattern
+// InitAuth initializes the auth module with configuration using singleton pattern.
+// When JWT_LEGACY_AUTH_TYPE names another auth type, tokens are signed with
+// authType and verified with either, so the auth type can be switched
+// without invalidating outstanding tokens.
 func InitAuth(authType AuthType) {
 	log := logging.Default()
 
+	mu.Lock()
+	defer mu.Unlock()
+
 	once.Do(func() {
-		var err error
-		factory := NewAuthFactory()
-
-		switch authType {
-		case AuthTypeECDSA:
-			config := LoadECDSAConfig()
-			instance, err = factory.CreateAuth(AuthTypeECDSA, &Config{
-				PrivateKey:        config.PrivateKey,
-				PublicKey:         config.PublicKey,
-				JWTExpirationTime: 1440, // Default 24 hours
-			})
-			if err != nil {
-				log.Errorw("failed to create ECDSA auth instance", "error", err)
-				panic(fmt.Sprintf("failed to initialize ECDSA auth: %v", err))
-			}
-			log.Infow("ECDSA auth initialized successfully")
-
-		case AuthTypeHMAC:
-			config := LoadHMACConfig()
-			instance, err = factory.CreateAuth(AuthTypeHMAC, &Config{
-				SecretKey:         config.SecretKey,
-				JWTExpirationTime: 1440, // Default 24 hours
-			})
-			if err != nil {
-				log.Errorw("failed to create HMAC auth instance", "error", err)
-				panic(fmt.Sprintf("failed to initialize HMAC auth: %v", err))
-			}
-			log.Infow("HMAC auth initialized successfully")
-
-		default:
-			panic(fmt.Sprintf("unsupported auth type: %s", authType))
+		instance = newAuth(authType)
+
+		legacyType := LoadMigrationConfig().LegacyType
+		if legacyType != "" && legacyType != authType {
+			instance = NewDualAuth(instance, newAuth(legacyType))
+			log.Infow("Auth migration enabled", "signing", authType, "also_verifying", legacyType)
 		}
 	})
 }
 
+// InitAuthWithConfig initializes the auth module with keys passed directly
+// rather than read from the environment, for embedding and tests. Unlike
+// InitAuth it returns an error instead of panicking when the configuration
+// is missing or invalid, or when the module is already initialized. A zero
+// JWTExpirationTime uses DefaultJWTExpirationMinutes.
+func InitAuthWithConfig(authType AuthType, config *Config) error {
+	if config == nil {
+		return errors.New("auth config is required")
+	}
+
+	cfg := *config
+	if cfg.JWTExpirationTime == 0 {
+		cfg.JWTExpirationTime = DefaultJWTExpirationMinutes
+	}
+	if cfg.JWTExpirationTime < 0 {
+		return fmt.Errorf("invalid JWT expiration time: %d minutes", cfg.JWTExpirationTime)
+	}
+
+	impl, err := NewAuthFactory().CreateAuth(authType, &cfg)
+	if err != nil {
+		return err
+	}
+
+	initialized := false
+	mu.Lock()
+	once.Do(func() {
+		instance = impl
+		clock = cfg.clock()
+		initialized = true
+	})
+	mu.Unlock()
+	if !initialized {
+		return errors.New("auth already initialized")
+	}
+
+	logging.Default().Infow("Auth initialized from config", "type", getAuthType(impl))
+	return nil
+}
+
+// newAuth creates an auth instance of the given type from environment
+// configuration, panicking if it is missing or invalid
+func newAuth(authType AuthType) AuthInterface {
+	log := logging.Default()
+	factory := NewAuthFactory()
+
+	switch authType {
+	case AuthTypeECDSA:
+		config := LoadECDSAConfig()
+		impl, err := factory.CreateAuth(AuthTypeECDSA, &Config{
+			PrivateKey:        config.PrivateKey,
+			PublicKey:         config.PublicKey,
+			JWTExpirationTime: DefaultJWTExpirationMinutes,
+		})
+		if err != nil {
+			log.Errorw("failed to create ECDSA auth instance", "error", err)
+			panic(fmt.Sprintf("failed to initialize ECDSA auth: %v", err))
+		}
+		log.Infow("ECDSA auth initialized successfully")
+		return impl
+
+	case AuthTypeHMAC:
+		config := LoadHMACConfig()
+		impl, err := factory.CreateAuth(AuthTypeHMAC, &Config{
+			SecretKey:         config.SecretKey,
+			JWTExpirationTime: DefaultJWTExpirationMinutes,
+		})
+		if err != nil {
+			log.Errorw("failed to create HMAC auth instance", "error", err)
+			panic(fmt.Sprintf("failed to initialize HMAC auth: %v", err))
+		}
+		log.Infow("HMAC auth initialized successfully")
+		return impl
+
+	default:
+		panic(fmt.Sprintf("unsupported auth type: %s", authType))
+	}
+}
+
 // getAuthType returns the type of auth instance for logging
 func getAuthType(auth AuthInterface) string {
-	switch auth.(type) {
+	switch a := auth.(type) {
 	case *ECDSAAuth:
 		return "ECDSA"
 	case *HMACAuth:
 		return "HMAC"
+	case *DualAuth:
+		return getAuthType(a.signer) + "+" + getAuthType(a.legacy)
 	default:
 		return "Unknown"
 	}
@@ -93,7 +155,17 @@ func GenerateJWTWithExpiry(
 	return instance.GenerateJWTWithExpiry(sub, expiryDuration)
 }
 
-func Verify(token string) (*jwt.RegisteredClaims, error) {
+func GenerateJWTWithClaims(claims *Claims) (string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if instance == nil {
+		return "", errors.New("auth not initialized")
+	}
+	return instance.GenerateJWTWithClaims(claims)
+}
+
+func Verify(token string) (*Claims, error) {
 	mu.RLock()
 	defer mu.RUnlock()
 
@@ -103,11 +175,21 @@ func Verify(token string) (*jwt.RegisteredClaims, error) {
 	return instance.Verify(token)
 }
 
+// Now returns the time on the clock the module was initialized with: the
+// Config.Clock passed to InitAuthWithConfig, or the system clock
+func Now() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return clock.Now()
+}
+
+// VerifyWithPublicKey verifies a token signed with the private key of
+// publicKey, checking its expiry by Now
 func VerifyWithPublicKey(
 	token string, publicKey *ecdsa.PublicKey,
-) (*jwt.RegisteredClaims, error) {
+) (*Claims, error) {
 	log := logging.Default()
-	claims := &jwt.RegisteredClaims{}
+	claims := &Claims{}
 
 	tkn, err := jwt.ParseWithClaims(
 		token,
@@ -115,6 +197,7 @@ func VerifyWithPublicKey(
 		func(token *jwt.Token) (interface{}, error) {
 			return publicKey, nil
 		},
+		jwt.WithTimeFunc(Now),
 	)
 	if err != nil {
 		log.Errorf("token: %s Parsing failed with %s\n", token, err)
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// manualClock is a Clock that stands still until advanced
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// ecdsaConfig returns a Config holding a new ECDSA key pair as PEM
+func ecdsaConfig(t *testing.T) (*Config, *ecdsa.PublicKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Config{
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+	}, &key.PublicKey
+}
+
+// TestVerifyWithPublicKeyExpiry checks expiry by the configured clock. The
+// clock starts years ago, so the token is only valid by that clock.
+func TestVerifyWithPublicKeyExpiry(t *testing.T) {
+	t.Cleanup(ResetForTest)
+
+	clock := &manualClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	config, publicKey := ecdsaConfig(t)
+	config.Clock = clock
+	if err := InitAuthWithConfig(AuthTypeECDSA, config); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := GenerateJWTWithExpiry("client", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := VerifyWithPublicKey(token, publicKey)
+	if err != nil {
+		t.Fatalf("token rejected before expiry: %v", err)
+	}
+	if claims.Subject != "client" {
+		t.Errorf("subject = %q, want client", claims.Subject)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, err := VerifyWithPublicKey(token, publicKey); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("token after expiry: err = %v, want %v", err, jwt.ErrTokenExpired)
+	}
+}
+
+// TestInitAuthReset checks the module keeps its first configuration until
+// ResetForTest, after which tokens of the discarded one are not accepted
+func TestInitAuthReset(t *testing.T) {
+	t.Cleanup(ResetForTest)
+
+	if err := InitAuthWithConfig(AuthTypeHMAC, &Config{SecretKey: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateJWT("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InitAuthWithConfig(AuthTypeHMAC, &Config{SecretKey: "second"}); err == nil {
+		t.Fatal("second InitAuthWithConfig succeeded without a reset")
+	}
+
+	ResetForTest()
+	if _, err := Verify(token); err == nil {
+		t.Error("Verify succeeded after ResetForTest, with no configuration")
+	}
+
+	if err := InitAuthWithConfig(AuthTypeHMAC, &Config{SecretKey: "second"}); err != nil {
+		t.Fatalf("InitAuthWithConfig after ResetForTest: %v", err)
+	}
+	if _, err := Verify(token); err == nil {
+		t.Error("token signed before the reset verified with the new key")
+	}
+	fresh, err := GenerateJWT("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(fresh); err != nil {
+		t.Errorf("token of the new configuration rejected: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the verified claims of a JWT: the registered claims, plus the
+// tenant, scopes and roles an issuer may add. Tokens generated by this
+// library carry only the registered claims, unless generated with
+// GenerateJWTWithClaims.
+type Claims struct {
+	jwt.RegisteredClaims
+	Tenant string   `json:"tenant,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+}
+
+// HasScope reports whether the token was granted scope
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// HasRole reports whether the token holds role
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of ctx carrying verified claims, so handlers
+// further down can make decisions on them without verifying the token again
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims attached by WithClaims, or nil
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims
+}
@@ -0,0 +1,17 @@
+package auth
+
+import "time"
+
+// Clock tells the auth module the time when it issues tokens and checks
+// their expiry. Config.Clock replaces the system clock, so tests can expire
+// tokens without waiting; a pubsub.ManualClock satisfies it.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock of the host
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -16,12 +18,35 @@ type HMACConfig struct {
 	SecretKey string `env:"JWT_SECRET_KEY" env-default:""`
 }
 
-// Config holds the configuration for the auth module (used by factory)
+// MigrationConfig configures a switch between auth types. While LegacyType
+// is set, tokens it signed are still verified, but new tokens are signed
+// with the configured type only.
+type MigrationConfig struct {
+	LegacyType AuthType `env:"JWT_LEGACY_AUTH_TYPE" env-default:""`
+}
+
+// DefaultJWTExpirationMinutes is how long tokens are valid by default: 24 hours
+const DefaultJWTExpirationMinutes = 1440
+
+// Config holds the configuration for the auth module (used by factory and
+// InitAuthWithConfig). Keys may be PEM or base64-encoded PEM.
 type Config struct {
 	PrivateKey        string `env:"PRIVATE_KEY" env-default:""`
 	PublicKey         string `env:"PUBLIC_KEY" env-default:""`
 	SecretKey         string `env:"JWT_SECRET_KEY" env-default:""`
 	JWTExpirationTime int    `env:"JWT_EXPIRATION_TIME" env-default:"1440"` // in minutes
+
+	// Clock tells the time tokens are issued and checked at; nil uses the
+	// system clock
+	Clock Clock
+}
+
+// clock returns the configured Clock, or the system clock
+func (c *Config) clock() Clock {
+	if c.Clock == nil {
+		return systemClock{}
+	}
+	return c.Clock
 }
 
 // LoadECDSAConfig loads the configuration from environment variables
@@ -53,7 +78,50 @@ func LoadHMACConfig() *HMACConfig {
 	return &cfg
 }
 
+// LoadMigrationConfig loads the migration configuration from environment
+// variables
+func LoadMigrationConfig() *MigrationConfig {
+	var cfg MigrationConfig
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		panic(fmt.Sprintf("error reading auth config: %v", err))
+	}
+	return &cfg
+}
+
+// AuthTypeFromEnv returns the auth type named by AUTH_TYPE, HMAC by default
+func AuthTypeFromEnv() AuthType {
+	if value := os.Getenv("AUTH_TYPE"); value != "" {
+		return AuthType(strings.ToLower(value))
+	}
+	return AuthTypeHMAC
+}
+
+// ConfigFromEnv reads the configuration for an auth type from environment
+// variables, for InitAuthWithConfig. Unlike LoadECDSAConfig and
+// LoadHMACConfig it returns an error when variables are missing.
+func ConfigFromEnv(authType AuthType) (*Config, error) {
+	var cfg Config
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("error reading auth config: %w", err)
+	}
+
+	switch authType {
+	case AuthTypeECDSA:
+		if cfg.PrivateKey == "" || cfg.PublicKey == "" {
+			return nil, fmt.Errorf("PRIVATE_KEY and PUBLIC_KEY environment variables are required")
+		}
+	case AuthTypeHMAC:
+		if cfg.SecretKey == "" {
+			return nil, fmt.Errorf("JWT_SECRET_KEY environment variable is required")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported auth type: %s", authType)
+	}
+
+	return &cfg, nil
+}
+
 // GetExpirationTime returns the JWT expiration time as a Duration
 func GetExpirationTime() time.Duration {
-	return time.Duration(1440) * time.Minute
+	return time.Duration(DefaultJWTExpirationMinutes) * time.Minute
 }
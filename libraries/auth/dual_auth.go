@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DualAuth implements AuthInterface for a migration between auth types: it
+// signs with the new implementation and verifies tokens signed by either,
+// so tokens issued before the switch stay valid until they expire.
+type DualAuth struct {
+	signer AuthInterface // issues and verifies new tokens
+	legacy AuthInterface // only verifies tokens issued before the switch
+}
+
+// NewDualAuth creates an auth instance that signs with signer and verifies
+// with signer or legacy
+func NewDualAuth(signer, legacy AuthInterface) AuthInterface {
+	return &DualAuth{
+		signer: signer,
+		legacy: legacy,
+	}
+}
+
+// verifierFor returns the implementation that signed a token, judged by its
+// alg header, so a legacy token is never logged as a failure of the signer
+func (d *DualAuth) verifierFor(tokenString string) AuthInterface {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &jwt.RegisteredClaims{})
+	if err != nil {
+		return d.signer
+	}
+
+	if signs(d.legacy, token.Method) && !signs(d.signer, token.Method) {
+		return d.legacy
+	}
+	return d.signer
+}
+
+// signs reports whether an implementation uses the given signing method
+func signs(impl AuthInterface, method jwt.SigningMethod) bool {
+	switch impl.(type) {
+	case *HMACAuth:
+		_, ok := method.(*jwt.SigningMethodHMAC)
+		return ok
+	case *ECDSAAuth:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok
+	default:
+		return false
+	}
+}
+
+// GenerateJWT creates a JWT token with the new implementation
+func (d *DualAuth) GenerateJWT(sub string) (string, error) {
+	return d.signer.GenerateJWT(sub)
+}
+
+// GenerateJWTWithExpiry creates a JWT token with custom expiry with the new
+// implementation
+func (d *DualAuth) GenerateJWTWithExpiry(sub string, expiryDuration time.Duration) (string, error) {
+	return d.signer.GenerateJWTWithExpiry(sub, expiryDuration)
+}
+
+// GenerateJWTWithClaims creates a JWT token carrying claims with the new
+// implementation
+func (d *DualAuth) GenerateJWTWithClaims(claims *Claims) (string, error) {
+	return d.signer.GenerateJWTWithClaims(claims)
+}
+
+// Verify verifies a JWT token with the implementation that signed it
+func (d *DualAuth) Verify(token string) (*Claims, error) {
+	return d.verifierFor(token).Verify(token)
+}
+
+// HashPassword creates a bcrypt hash of the password with salt
+func (d *DualAuth) HashPassword(password, salt string) (string, error) {
+	return d.signer.HashPassword(password, salt)
+}
+
+// VerifyPassword verifies a password against its hash and salt
+func (d *DualAuth) VerifyPassword(password, hashedPassword, salt string) error {
+	return d.signer.VerifyPassword(password, hashedPassword, salt)
+}
+
+// VerifyPasswordBool is a convenience function that returns a boolean instead of an error
+func (d *DualAuth) VerifyPasswordBool(password, hashedPassword, salt string) bool {
+	return d.signer.VerifyPasswordBool(password, hashedPassword, salt)
+}
+
+// SignMessage signs a message with whichever implementation supports it
+func (d *DualAuth) SignMessage(msg []byte) (string, error) {
+	if signature, err := d.signer.SignMessage(msg); err == nil {
+		return signature, nil
+	}
+	if signature, err := d.legacy.SignMessage(msg); err == nil {
+		return signature, nil
+	}
+	return "", errors.New("message signing not supported by configured auth types")
+}
+
+// VerifySignature verifies a message signature with either implementation
+func (d *DualAuth) VerifySignature(msg []byte, signature string) bool {
+	return d.signer.VerifySignature(msg, signature) || d.legacy.VerifySignature(msg, signature)
+}
+
+// ClientIDFromJWT extracts client ID from JWT token as the implementation
+// that signed it encodes it
+func (d *DualAuth) ClientIDFromJWT(token string) (clientID string, err error) {
+	return d.verifierFor(token).ClientIDFromJWT(token)
+}
@@ -115,12 +115,12 @@ func (e *ECDSAAuth) GenerateJWT(sub string) (string, error) {
 func (e *ECDSAAuth) GenerateJWTWithExpiry(sub string, expiryDuration time.Duration) (string, error) {
 	log := logging.Default()
 	aud := jwt.ClaimStrings{"aud"}
-	expirationTime := time.Now().Add(expiryDuration)
+	now := e.authConfig.clock().Now()
 
 	claims := &jwt.RegisteredClaims{
 		Audience:  aud,
-		ExpiresAt: jwt.NewNumericDate(expirationTime),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiryDuration)),
+		IssuedAt:  jwt.NewNumericDate(now),
 		Issuer:    "quickly.com",
 		Subject:   sub,
 	}
@@ -139,15 +139,40 @@ func (e *ECDSAAuth) GenerateJWTWithExpiry(sub string, expiryDuration time.Durati
 	return signedToken, err
 }
 
+// GenerateJWTWithClaims creates a JWT token carrying claims using ECDSA. The
+// issuer and issue time are set when claims leave them empty.
+func (e *ECDSAAuth) GenerateJWTWithClaims(claims *Claims) (string, error) {
+	log := logging.Default()
+
+	signed := *claims
+	if signed.Issuer == "" {
+		signed.Issuer = "quickly.com"
+	}
+	if signed.IssuedAt == nil {
+		signed.IssuedAt = jwt.NewNumericDate(e.authConfig.clock().Now())
+	}
+
+	if e.config.PrivateKey == nil {
+		return "", errors.New("private key is not configured")
+	}
+
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodES256, &signed).SignedString(e.config.PrivateKey)
+	if err != nil {
+		log.Errorw("signing private key throws error", "error", err)
+	}
+
+	return signedToken, err
+}
+
 // Verify verifies a JWT token using ECDSA public key
-func (e *ECDSAAuth) Verify(token string) (*jwt.RegisteredClaims, error) {
+func (e *ECDSAAuth) Verify(token string) (*Claims, error) {
 	return e.VerifyWithPublicKey(token, e.config.PublicKey)
 }
 
 // VerifyWithPublicKey verifies a JWT token with a specific public key
-func (e *ECDSAAuth) VerifyWithPublicKey(token string, publicKey *ecdsa.PublicKey) (*jwt.RegisteredClaims, error) {
+func (e *ECDSAAuth) VerifyWithPublicKey(token string, publicKey *ecdsa.PublicKey) (*Claims, error) {
 	log := logging.Default()
-	claims := &jwt.RegisteredClaims{}
+	claims := &Claims{}
 
 	tkn, err := jwt.ParseWithClaims(
 		token,
@@ -155,6 +180,7 @@ func (e *ECDSAAuth) VerifyWithPublicKey(token string, publicKey *ecdsa.PublicKey
 		func(token *jwt.Token) (interface{}, error) {
 			return publicKey, nil
 		},
+		jwt.WithTimeFunc(e.authConfig.clock().Now),
 	)
 	if err != nil {
 		log.Errorf("token: %s Parsing failed with %s\n", token, err)
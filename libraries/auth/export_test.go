@@ -0,0 +1,14 @@
+package auth
+
+import "sync"
+
+// ResetForTest discards the singleton so the next InitAuth call can select a
+// different auth type or configuration
+func ResetForTest() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	instance = nil
+	clock = systemClock{}
+	once = sync.Once{}
+}
@@ -29,10 +29,10 @@ func (f *AuthFactory) CreateAuth(authType AuthType, config *Config) (AuthInterfa
 func (f *AuthFactory) createECDSAAuth(config *Config) (AuthInterface, error) {
 	// Validate required fields for ECDSA
 	if config.PrivateKey == "" {
-		return nil, errors.New("PRIVATE_KEY environment variable is required for ECDSA auth")
+		return nil, errors.New("private key (PRIVATE_KEY) is required for ECDSA auth")
 	}
 	if config.PublicKey == "" {
-		return nil, errors.New("PUBLIC_KEY environment variable is required for ECDSA auth")
+		return nil, errors.New("public key (PUBLIC_KEY) is required for ECDSA auth")
 	}
 
 	return NewECDSAAuth(config)
@@ -42,10 +42,10 @@ func (f *AuthFactory) createECDSAAuth(config *Config) (AuthInterface, error) {
 func (f *AuthFactory) createHMACAuth(config *Config) (AuthInterface, error) {
 	// Validate required fields for HMAC
 	if config.SecretKey == "" {
-		return nil, errors.New("JWT_SECRET_KEY environment variable is required for HMAC auth")
+		return nil, errors.New("secret key (JWT_SECRET_KEY) is required for HMAC auth")
 	}
 
-	return NewHMACAuth(config.SecretKey, config.JWTExpirationTime), nil
+	return newHMACAuth(config.SecretKey, config.JWTExpirationTime, config.clock()), nil
 }
 
 // CreateAuthFromConfig creates an auth instance based on config detection
@@ -13,13 +13,20 @@ import (
 type HMACAuth struct {
 	secretKey      string
 	expirationTime time.Duration
+	clock          Clock
 }
 
 // NewHMACAuth creates a new HMAC auth instance
 func NewHMACAuth(secretKey string, expirationMinutes int) AuthInterface {
+	return newHMACAuth(secretKey, expirationMinutes, systemClock{})
+}
+
+// newHMACAuth creates an HMAC auth instance telling the time by clock
+func newHMACAuth(secretKey string, expirationMinutes int, clock Clock) *HMACAuth {
 	return &HMACAuth{
 		secretKey:      secretKey,
 		expirationTime: time.Duration(expirationMinutes) * time.Minute,
+		clock:          clock,
 	}
 }
 
@@ -32,10 +39,11 @@ func (h *HMACAuth) GenerateJWT(sub string) (string, error) {
 func (h *HMACAuth) GenerateJWTWithExpiry(sub string, expiryDuration time.Duration) (string, error) {
 	log := logging.Default()
 
+	now := h.clock.Now()
 	claims := &jwt.RegisteredClaims{
 		Audience:  jwt.ClaimStrings{"aud"},
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiryDuration)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiryDuration)),
+		IssuedAt:  jwt.NewNumericDate(now),
 		Issuer:    "shopping-gateway",
 		Subject:   sub,
 	}
@@ -55,10 +63,36 @@ func (h *HMACAuth) GenerateJWTWithExpiry(sub string, expiryDuration time.Duratio
 	return signedToken, nil
 }
 
+// GenerateJWTWithClaims creates a JWT token carrying claims using HMAC. The
+// issuer and issue time are set when claims leave them empty.
+func (h *HMACAuth) GenerateJWTWithClaims(claims *Claims) (string, error) {
+	log := logging.Default()
+
+	signed := *claims
+	if signed.Issuer == "" {
+		signed.Issuer = "shopping-gateway"
+	}
+	if signed.IssuedAt == nil {
+		signed.IssuedAt = jwt.NewNumericDate(h.clock.Now())
+	}
+
+	if h.secretKey == "" {
+		return "", errors.New("secret key is not configured")
+	}
+
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &signed).SignedString([]byte(h.secretKey))
+	if err != nil {
+		log.Errorw("signing token failed", "error", err)
+		return "", err
+	}
+
+	return signedToken, nil
+}
+
 // Verify verifies a JWT token using HMAC
-func (h *HMACAuth) Verify(tokenString string) (*jwt.RegisteredClaims, error) {
+func (h *HMACAuth) Verify(tokenString string) (*Claims, error) {
 	log := logging.Default()
-	claims := &jwt.RegisteredClaims{}
+	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		// Validate signing method
@@ -66,7 +100,7 @@ func (h *HMACAuth) Verify(tokenString string) (*jwt.RegisteredClaims, error) {
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(h.secretKey), nil
-	})
+	}, jwt.WithTimeFunc(h.clock.Now))
 
 	if err != nil {
 		log.Errorf("token parsing failed: %s", err)
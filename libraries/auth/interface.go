@@ -1,17 +1,14 @@
 package auth
 
-import (
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-)
+import "time"
 
 // AuthInterface defines the contract for authentication implementations
 type AuthInterface interface {
 	// JWT Operations
 	GenerateJWT(sub string) (string, error)
 	GenerateJWTWithExpiry(sub string, expiryDuration time.Duration) (string, error)
-	Verify(token string) (*jwt.RegisteredClaims, error)
+	GenerateJWTWithClaims(claims *Claims) (string, error)
+	Verify(token string) (*Claims, error)
 
 	// Password Operations (with salt support)
 	HashPassword(password, salt string) (string, error)
@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Action is a data-plane operation a Grant can permit
+type Action string
+
+const (
+	ActionPublish   Action = "publish"
+	ActionSubscribe Action = "subscribe" // also covers fetching, acking and marking read
+)
+
+// Grant restricts a caller to one action on a set of topics, for
+// credentials issued for a single direction. Calls whose context carries no
+// grant are unrestricted.
+type Grant struct {
+	Action    Action
+	Topics    []string // topic names; "*" matches every topic
+	Namespace string   // every topic under this namespace, as well as Topics
+}
+
+// Allows reports whether the grant permits action on topic
+func (g *Grant) Allows(action Action, topic string) bool {
+	if g.Action != action {
+		return false
+	}
+	if g.Namespace != "" && InNamespace(topic, g.Namespace) {
+		return true
+	}
+	return slices.Contains(g.Topics, topic) || slices.Contains(g.Topics, "*")
+}
+
+type grantContextKey struct{}
+
+// WithGrant returns a copy of ctx whose pubsub calls are restricted to grant
+func WithGrant(ctx context.Context, grant *Grant) context.Context {
+	return context.WithValue(ctx, grantContextKey{}, grant)
+}
+
+// GrantFromContext returns the grant restricting ctx, or nil when it is
+// unrestricted
+func GrantFromContext(ctx context.Context) *Grant {
+	grant, _ := ctx.Value(grantContextKey{}).(*Grant)
+	return grant
+}
+
+// authorize fails unless ctx may perform action on topic
+func authorize(ctx context.Context, action Action, topic string) error {
+	if grant := GrantFromContext(ctx); grant != nil && !grant.Allows(action, topic) {
+		return fmt.Errorf("forbidden: %s is not permitted on topic %s", action, topic)
+	}
+	return nil
+}
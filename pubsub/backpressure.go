@@ -0,0 +1,178 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// BackpressurePolicy decides what happens to a live message when a
+// subscriber's queue is full
+type BackpressurePolicy string
+
+// Backpressure policies
+const (
+	// BackpressureDropNewest drops the incoming message, the default
+	BackpressureDropNewest BackpressurePolicy = "drop_newest"
+	// BackpressureDropOldest drops the oldest queued message to make room
+	BackpressureDropOldest BackpressurePolicy = "drop_oldest"
+	// BackpressureBlock makes the subscriber's writer wait for room up to a
+	// timeout, then drops the incoming message. Publishes wait once the
+	// writer's pending messages fill up too.
+	BackpressureBlock BackpressurePolicy = "block_with_timeout"
+	// BackpressureDisconnect closes the subscription with
+	// CloseReasonBackpressure
+	BackpressureDisconnect BackpressurePolicy = "disconnect"
+)
+
+// Block timeout bounds
+const (
+	DefaultBackpressureTimeout = 100 * time.Millisecond
+	MaxBackpressureTimeout     = 10 * time.Second
+)
+
+// Backpressure configures a topic's or a subscription's policy. A
+// subscription without a policy uses its topic's, and a topic without one
+// drops the newest message.
+type Backpressure struct {
+	Policy    BackpressurePolicy `json:"policy,omitempty"`
+	TimeoutMs int                `json:"timeout_ms,omitempty"` // block_with_timeout wait; 0 uses DefaultBackpressureTimeout
+}
+
+// Validate checks the policy is known and the timeout within bounds
+func (b *Backpressure) Validate() error {
+	switch b.Policy {
+	case "", BackpressureDropNewest, BackpressureDropOldest, BackpressureBlock, BackpressureDisconnect:
+	default:
+		return fmt.Errorf("invalid backpressure: policy must be %s, %s, %s or %s",
+			BackpressureDropNewest, BackpressureDropOldest, BackpressureBlock, BackpressureDisconnect)
+	}
+	if b.TimeoutMs < 0 || b.TimeoutMs > int(MaxBackpressureTimeout.Milliseconds()) {
+		return fmt.Errorf("invalid backpressure: timeout_ms must be between 0 and %d", MaxBackpressureTimeout.Milliseconds())
+	}
+	if b.TimeoutMs > 0 && b.Policy != BackpressureBlock {
+		return fmt.Errorf("invalid backpressure: timeout_ms only applies to %s", BackpressureBlock)
+	}
+	return nil
+}
+
+// or returns b, or inherited when b has no policy, or drop_newest when
+// neither has one
+func (b Backpressure) or(inherited Backpressure) Backpressure {
+	switch {
+	case b.Policy != "":
+		return b
+	case inherited.Policy != "":
+		return inherited
+	}
+	return Backpressure{Policy: BackpressureDropNewest}
+}
+
+// timeout returns how long block_with_timeout waits for room
+func (b Backpressure) timeout() time.Duration {
+	if b.TimeoutMs == 0 {
+		return DefaultBackpressureTimeout
+	}
+	return time.Duration(b.TimeoutMs) * time.Millisecond
+}
+
+// SetBackpressure sets the policy of a topic's subscriptions that have none
+// of their own. It applies to their next full queue.
+func (s *service) SetBackpressure(ctx context.Context, topicName string, backpressure Backpressure) error {
+	if err := backpressure.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.Backpressure = backpressure
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic backpressure", "topic", topicName,
+		"policy", backpressure.Policy, "timeout_ms", backpressure.TimeoutMs)
+	return nil
+}
+
+// enqueueFor offers a message to a subscriber's queue, its priority queue
+// when high, applying policy when the queue is full. It returns the messages dropped for the
+// subscriber and whether the subscriber must be disconnected.
+func (s *service) enqueueFor(ctx context.Context, topic *Topic, sub *Subscriber, message *Message, policy Backpressure, high bool) ([]*Message, bool) {
+	switch s.tryEnqueue(sub, message, 0, high) {
+	case sendOK, sendClosed:
+		return nil, false
+	}
+
+	var dropped []*Message
+
+	switch policy.Policy {
+	case BackpressureDropOldest:
+		// The client may drain the queue meanwhile, leaving nothing to drop
+		if oldest := sub.evictOldest(high); oldest != nil {
+			dropped = append(dropped, oldest)
+		}
+		if s.tryEnqueue(sub, message, 0, high) != sendFull {
+			break
+		}
+		// Refilled by a concurrent publish: the newest is dropped after all
+		dropped = append(dropped, message)
+	case BackpressureBlock:
+		if s.tryEnqueue(sub, message, policy.timeout(), high) == sendFull {
+			dropped = append(dropped, message)
+		}
+	default:
+		dropped = append(dropped, message)
+	}
+
+	now := s.clock.Now()
+	for _, msg := range dropped {
+		topic.dropped.Add(1)
+		topic.drops.record(now)
+		sub.dropped.Add(1)
+		logging.WithContext(ctx).Warn("Dropped message due to full subscriber channel",
+			"client_id", sub.ClientID, "topic", topic.Name, "message_id", msg.ID, "policy", policy.Policy)
+	}
+	if len(dropped) > 0 {
+		sub.dropPolicy.Store(policy.Policy)
+		s.emitDropped(topic, len(dropped))
+	}
+	return dropped, policy.Policy == BackpressureDisconnect
+}
+
+// tryEnqueue adds a message to a subscriber's queue, waiting up to wait for
+// room, and counts the delivery. A subscription closed meanwhile, or a
+// service shutting down, takes nothing and drops nothing.
+func (s *service) tryEnqueue(sub *Subscriber, message *Message, wait time.Duration, high bool) sendResult {
+	result := sub.send(message, wait, high, s.shutdown)
+	if result == sendOK {
+		sub.recordDelivery(1)
+		sub.trackInFlight(message)
+	}
+	return result
+}
+
+// disconnectSlow closes a subscription whose queue filled up under the
+// disconnect policy, unless it was closed meanwhile
+func (s *service) disconnectSlow(ctx context.Context, topic *Topic, sub *Subscriber) {
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+
+	if topic.Subscribers[sub.ClientID] != sub {
+		return
+	}
+	sub.close(CloseReasonBackpressure)
+	delete(topic.Subscribers, sub.ClientID)
+	s.emitSubscribers(topic)
+
+	logging.WithContext(ctx).Warnw("Disconnected subscriber with a full queue",
+		"client_id", sub.ClientID, "topic", topic.Name, "reason", CloseReasonBackpressure)
+}
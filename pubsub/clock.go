@@ -0,0 +1,131 @@
+package pubsub
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the service the time: message timestamps, subscription TTLs,
+// topic expiry and retention are measured by it, and the janitor sweeps and
+// the graceful shutdown timeout run on its tickers. Config.Clock replaces the system clock, so tests can move
+// time forward with a ManualClock instead of sleeping. Waits on the network
+// and backpressure timeouts still use real time.
+type Clock interface {
+	Now() time.Time
+	// NewTicker returns a ticker sending the clock's time every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks of a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the Clock of the host, used when Config.Clock is nil
+type SystemClock struct{}
+
+// Now returns the current local time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker returns a time.Ticker
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// ManualClock is a Clock that stands still until advanced, for tests. Its
+// tickers tick as Advance passes their deadlines; like a time.Ticker, a
+// ticker whose tick has not been received yet drops the next ones.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*manualTicker
+}
+
+// NewManualClock returns a ManualClock reading now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the clock's time
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a ticker whose first tick is d after the clock's time.
+// It panics if d is not positive, as time.NewTicker does.
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for ManualClock.NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ticker := &manualTicker{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		interval: d,
+		next:     c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// Advance moves the clock forward by d. Each ticker with a deadline passed
+// ticks once, with the time of the latest deadline passed.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, ticker := range c.tickers {
+		if ticker.next.After(c.now) {
+			continue
+		}
+		missed := c.now.Sub(ticker.next) / ticker.interval
+		tick := ticker.next.Add(missed * ticker.interval)
+		ticker.next = tick.Add(ticker.interval)
+
+		select {
+		case ticker.c <- tick:
+		default:
+		}
+	}
+}
+
+type manualTicker struct {
+	clock    *ManualClock
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time // guarded by clock.mu
+}
+
+func (t *manualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop stops the ticker; a tick already sent stays in the channel
+func (t *manualTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.clock.tickers = slices.DeleteFunc(t.clock.tickers, func(ticker *manualTicker) bool { return ticker == t })
+}
@@ -0,0 +1,240 @@
+package pubsub
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// MaxCompactionHeaderLength bounds the header a compacted topic is keyed by
+const MaxCompactionHeaderLength = 256
+
+// Compaction configures a compacted topic, which keeps the latest message
+// of each key beyond its buffer, such as the presence of each user or the
+// value of each setting. Subscriptions without after_seq or since receive
+// that state, oldest first, before live messages. A message is keyed by its
+// Key or, without one, by the value of Header; unkeyed messages are
+// delivered but not kept. A message with a null payload removes its key.
+type Compaction struct {
+	Enabled bool   `json:"enabled"`
+	Header  string `json:"header,omitempty"` // header keying messages without a key
+}
+
+// Validate checks the header is within bounds
+func (c *Compaction) Validate() error {
+	if c.Header != "" && !c.Enabled {
+		return fmt.Errorf("invalid compaction: header requires compaction to be enabled")
+	}
+	if len(c.Header) > MaxCompactionHeaderLength {
+		return fmt.Errorf("invalid compaction: header must be at most %d characters", MaxCompactionHeaderLength)
+	}
+	return nil
+}
+
+// keyOf returns the key a message is compacted by, or "" for none
+func (c Compaction) keyOf(msg *Message) string {
+	if msg.Key != "" || c.Header == "" {
+		return msg.Key
+	}
+	return msg.Headers[c.Header]
+}
+
+// compactedState is the latest message of each key on a compacted topic
+type compactedState struct {
+	mu       sync.Mutex
+	messages map[string]*Message // key -> latest message
+}
+
+func newCompactedState() *compactedState {
+	return &compactedState{messages: make(map[string]*Message)}
+}
+
+// add makes msg the latest of key unless a newer message already is,
+// removing key for a null payload. It reports whether the state changed.
+func (c *compactedState) add(key string, msg *Message) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if latest, exists := c.messages[key]; exists && latest.Seq >= msg.Seq {
+		return false
+	}
+	if msg.Payload == nil {
+		_, existed := c.messages[key]
+		delete(c.messages, key)
+		return existed
+	}
+	c.messages[key] = msg
+	return true
+}
+
+// remove drops key if msg is its latest message, reporting whether it was
+func (c *compactedState) remove(key string, msg *Message) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if latest, exists := c.messages[key]; !exists || latest.ID != msg.ID {
+		return false
+	}
+	delete(c.messages, key)
+	return true
+}
+
+// latest returns the latest message of every key, oldest first, limited to
+// the newest lastN when lastN > 0
+func (c *compactedState) latest(lastN int) []*Message {
+	c.mu.Lock()
+	messages := make([]*Message, 0, len(c.messages))
+	for _, msg := range c.messages {
+		messages = append(messages, msg)
+	}
+	c.mu.Unlock()
+
+	slices.SortFunc(messages, func(a, b *Message) int { return cmp.Compare(a.Seq, b.Seq) })
+	if lastN > 0 && len(messages) > lastN {
+		messages = messages[len(messages)-lastN:]
+	}
+	return messages
+}
+
+// find returns the latest message with the given ID, or nil
+func (c *compactedState) find(id string) *Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, msg := range c.messages {
+		if msg.ID == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+// keys returns how many keys the state holds
+func (c *compactedState) keys() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.messages)
+}
+
+// SetCompaction makes a topic compacted, keeping the latest message of each
+// key, or not. Enabling it builds the state from the messages buffered.
+func (s *service) SetCompaction(ctx context.Context, topicName string, compaction Compaction) error {
+	if err := compaction.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	setCompaction(topic, compaction)
+	topic.mu.Unlock()
+	topic.unsaved.Store(true)
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic compaction", "topic", topicName,
+		"enabled", compaction.Enabled, "header", compaction.Header)
+	return nil
+}
+
+// setCompaction sets a topic's compaction, rebuilding its state from the
+// topic's buffer when it changes. Caller must hold topic.mu or own topic
+// exclusively.
+func setCompaction(topic *Topic, compaction Compaction) {
+	if topic.Compaction == compaction {
+		return
+	}
+	topic.Compaction = compaction
+	if !compaction.Enabled {
+		topic.compacted = nil
+		return
+	}
+
+	compacted := newCompactedState()
+	for _, msg := range topic.Messages.GetMessages() {
+		if key := compaction.keyOf(msg); key != "" && msg.Tombstone == "" {
+			compacted.add(key, msg)
+		}
+	}
+	topic.compacted = compacted
+}
+
+// loadCompacted restores a compacted topic's saved state, then applies the
+// buffered messages again, since they may be newer than the save. Caller
+// must hold topic.mu or own topic exclusively.
+func loadCompacted(topic *Topic, saved []*Message) {
+	if topic.compacted == nil || len(saved) == 0 {
+		return
+	}
+	for _, msg := range saved {
+		if key := topic.Compaction.keyOf(msg); key != "" {
+			topic.compacted.add(key, msg)
+		}
+	}
+	for _, msg := range topic.Messages.GetMessages() {
+		if key := topic.Compaction.keyOf(msg); key != "" && msg.Tombstone == "" {
+			topic.compacted.add(key, msg)
+		}
+	}
+}
+
+// compact makes a stored message the latest of its key, if the topic is
+// compacted and the message is keyed
+func (t *Topic) compact(msg *Message) {
+	t.mu.RLock()
+	compacted, compaction := t.compacted, t.Compaction
+	t.mu.RUnlock()
+
+	if compacted == nil || msg.Tombstone != "" {
+		return
+	}
+	if key := compaction.keyOf(msg); key != "" && compacted.add(key, msg) {
+		t.unsaved.Store(true)
+	}
+}
+
+// uncompact drops a deleted message's key from the topic's state, if the
+// message is still the latest of it
+func (t *Topic) uncompact(msg *Message) {
+	t.mu.RLock()
+	compacted, compaction := t.compacted, t.Compaction
+	t.mu.RUnlock()
+
+	if compacted == nil {
+		return
+	}
+	if key := compaction.keyOf(msg); key != "" && compacted.remove(key, msg) {
+		t.unsaved.Store(true)
+	}
+}
+
+// findCompacted returns a message still held as the latest of its key, or
+// nil
+func (t *Topic) findCompacted(id string) *Message {
+	t.mu.RLock()
+	compacted := t.compacted
+	t.mu.RUnlock()
+
+	if compacted == nil {
+		return nil
+	}
+	return compacted.find(id)
+}
+
+// savedCompacted returns a topic's state to save, oldest first. Caller
+// must hold topic.mu.
+func savedCompacted(topic *Topic) []*Message {
+	if topic.compacted == nil {
+		return nil
+	}
+	return topic.compacted.latest(0)
+}
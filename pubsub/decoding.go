@@ -0,0 +1,149 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Number modes for decoding payloads
+const (
+	NumbersExact = "exact" // json.Number, so integers beyond 2^53 round-trip unchanged (default)
+	NumbersFloat = "float" // float64, as encoding/json decodes into interface{}
+)
+
+// Decoding controls how messages published to a topic are decoded
+type Decoding struct {
+	Numbers string `json:"numbers,omitempty"` // NumbersExact (default) or NumbersFloat
+	Strict  bool   `json:"strict,omitempty"`  // reject publish frames with unknown fields
+}
+
+// Validate checks the number mode
+func (d *Decoding) Validate() error {
+	switch d.Numbers {
+	case "", NumbersExact, NumbersFloat:
+		return nil
+	default:
+		return fmt.Errorf("invalid decoding: numbers must be %q or %q", NumbersExact, NumbersFloat)
+	}
+}
+
+// Unmarshal decodes data into v according to the decoding settings. Payload
+// numbers become json.Number unless Numbers is NumbersFloat; Strict rejects
+// fields v does not declare.
+func (d Decoding) Unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if d.Numbers != NumbersFloat {
+		decoder.UseNumber()
+	}
+	if d.Strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// SetDecoding sets how messages published to a topic are decoded
+func (s *service) SetDecoding(ctx context.Context, topicName string, decoding Decoding) error {
+	if err := decoding.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.Decoding = decoding
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic decoding", "topic", topicName, "numbers", decoding.Numbers, "strict", decoding.Strict)
+	return nil
+}
+
+// GetDecoding returns how messages published to a topic are decoded
+func (s *service) GetDecoding(ctx context.Context, topicName string) (Decoding, error) {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return Decoding{}, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+	return topic.Decoding, nil
+}
+
+// jsonEqual reports whether two decoded JSON values are equal, comparing
+// numbers by value so json.Number and float64 forms of the same number match
+func jsonEqual(a, b interface{}) bool {
+	if x, ok := jsonNumber(a); ok {
+		y, ok := jsonNumber(b)
+		return ok && x.Cmp(y) == 0
+	}
+
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, exists := b[key]
+			if !exists || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// jsonNumber returns the exact value of a decoded JSON number
+func jsonNumber(v interface{}) (*big.Rat, bool) {
+	var literal string
+	switch n := v.(type) {
+	case json.Number:
+		literal = n.String()
+	case float64:
+		literal = strconv.FormatFloat(n, 'g', -1, 64)
+	case int:
+		literal = strconv.Itoa(n)
+	case int64:
+		literal = strconv.FormatInt(n, 10)
+	default:
+		return nil, false
+	}
+	return new(big.Rat).SetString(literal)
+}
@@ -0,0 +1,173 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Dedup window bounds
+const (
+	DefaultDedupSize = 10000
+	MaxDedupSize     = 1000000
+	MaxDedupWindow   = 24 * time.Hour
+)
+
+// Dedup configures a topic's deduplication window. A publish whose message
+// ID was published to the topic within the window is acknowledged without
+// being stored or delivered again, so clients can retry publishes safely.
+// A zero Dedup disables it.
+type Dedup struct {
+	Size      int   `json:"size,omitempty"`       // message IDs remembered; 0 uses DefaultDedupSize when WindowSec is set
+	WindowSec int64 `json:"window_sec,omitempty"` // message IDs are forgotten after this many seconds; 0 keeps them until Size evicts
+}
+
+// Enabled reports whether any limit is set
+func (d Dedup) Enabled() bool {
+	return d.Size > 0 || d.WindowSec > 0
+}
+
+// Validate checks the limits are within bounds
+func (d *Dedup) Validate() error {
+	if d.Size < 0 || d.Size > MaxDedupSize {
+		return fmt.Errorf("invalid dedup: size must be between 0 and %d", MaxDedupSize)
+	}
+	if d.WindowSec < 0 || d.WindowSec > int64(MaxDedupWindow.Seconds()) {
+		return fmt.Errorf("invalid dedup: window_sec must be between 0 and %d", int64(MaxDedupWindow.Seconds()))
+	}
+	return nil
+}
+
+// size returns how many message IDs are remembered
+func (d Dedup) size() int {
+	if d.Size == 0 {
+		return DefaultDedupSize
+	}
+	return d.Size
+}
+
+// seenID is a remembered message ID and when it was published
+type seenID struct {
+	id string
+	at time.Time
+}
+
+// dedupWindow remembers the message IDs recently published to a topic,
+// oldest first
+type dedupWindow struct {
+	mu    sync.Mutex
+	ids   map[string]time.Time
+	order []seenID
+}
+
+// claim records id as published at now and reports true, or reports false
+// when it was already published within the window
+func (w *dedupWindow) claim(id string, now time.Time, dedup Dedup) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ids == nil {
+		w.ids = make(map[string]time.Time)
+	}
+	w.expire(now, dedup)
+
+	if _, seen := w.ids[id]; seen {
+		return false
+	}
+	w.ids[id] = now
+	w.order = append(w.order, seenID{id: id, at: now})
+	for len(w.ids) > dedup.size() {
+		w.evictOldest()
+	}
+	return true
+}
+
+// release forgets id, so a publish that failed after claiming it can be
+// retried
+func (w *dedupWindow) release(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Its entry in order is skipped when it reaches the front
+	delete(w.ids, id)
+}
+
+// reset forgets every message ID
+func (w *dedupWindow) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.ids = nil
+	w.order = nil
+}
+
+// expire forgets the message IDs published before the window. Caller must
+// hold w.mu.
+func (w *dedupWindow) expire(now time.Time, dedup Dedup) {
+	if dedup.WindowSec == 0 {
+		return
+	}
+	cutoff := now.Add(-time.Duration(dedup.WindowSec) * time.Second)
+	for len(w.order) > 0 && w.order[0].at.Before(cutoff) {
+		w.evictOldest()
+	}
+}
+
+// evictOldest forgets the oldest message ID. Caller must hold w.mu.
+func (w *dedupWindow) evictOldest() {
+	oldest := w.order[0]
+	w.order = w.order[1:]
+	// A released and claimed again ID has a newer entry
+	if at, exists := w.ids[oldest.id]; exists && at.Equal(oldest.at) {
+		delete(w.ids, oldest.id)
+	}
+}
+
+// SetDedup sets a topic's deduplication window. Message IDs published
+// before the change are forgotten when it is disabled.
+func (s *service) SetDedup(ctx context.Context, topicName string, dedup Dedup) error {
+	if err := dedup.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.Dedup = dedup
+	topic.mu.Unlock()
+	if !dedup.Enabled() {
+		topic.seen.reset()
+	}
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic dedup", "topic", topicName,
+		"size", dedup.Size, "window_sec", dedup.WindowSec)
+	return nil
+}
+
+// claimMessageID reports whether a message is new to a topic with a dedup
+// window, recording its ID, or false for a duplicate. Topics without one
+// accept every message.
+func (s *service) claimMessageID(topic *Topic, message *Message) bool {
+	topic.mu.RLock()
+	dedup := topic.Dedup
+	topic.mu.RUnlock()
+
+	if !dedup.Enabled() {
+		return true
+	}
+	if topic.seen.claim(message.ID, s.clock.Now(), dedup) {
+		return true
+	}
+	topic.duplicates.Add(1)
+	return false
+}
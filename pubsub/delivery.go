@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// sendResult is the outcome of offering a message to a subscriber's queue
+type sendResult int
+
+const (
+	sendOK     sendResult = iota // queued
+	sendFull                     // the queue had no room in time
+	sendClosed                   // the subscription closed, or the service is stopping
+)
+
+// deliveryGuard makes closing a subscriber's queue safe against concurrent
+// sends without relying on the topic lock. Senders hold mu for reading and
+// check closed; close first closes done to wake senders waiting for room,
+// then takes mu for writing, so no send is in progress when the queues are
+// closed.
+type deliveryGuard struct {
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+	doneOnce  sync.Once
+	closeOnce sync.Once
+}
+
+// doneChan returns the channel closed when the guard closes
+func (g *deliveryGuard) doneChan() chan struct{} {
+	g.doneOnce.Do(func() { g.done = make(chan struct{}) })
+	return g.done
+}
+
+// close wakes waiting senders, waits for every send in progress and then
+// runs closeQueue, once
+func (g *deliveryGuard) close(closeQueue func()) {
+	// Waiting senders hold mu, so they are woken before taking it
+	g.closeOnce.Do(func() { close(g.doneChan()) })
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return
+	}
+	g.closed = true
+	closeQueue()
+}
+
+// queue returns the subscriber's priority queue when high, else its
+// normal queue. Subscribers made without a priority queue use the normal
+// one for both.
+func (sub *Subscriber) queue(high bool) chan *Message {
+	if high && sub.PriorityChan != nil {
+		return sub.PriorityChan
+	}
+	return sub.MessageChan
+}
+
+// send offers a message to the subscriber's queue, its priority queue when
+// high, waiting up to wait for room when it is full. It never sends on a
+// closed queue, and gives up when the subscription closes or shutdown is
+// closed.
+func (sub *Subscriber) send(message *Message, wait time.Duration, high bool, shutdown <-chan struct{}) sendResult {
+	sub.guard.mu.RLock()
+	defer sub.guard.mu.RUnlock()
+
+	if sub.guard.closed {
+		return sendClosed
+	}
+	queue := sub.queue(high)
+
+	select {
+	case queue <- message:
+		return sendOK
+	case <-shutdown:
+		return sendClosed
+	default:
+	}
+	if wait <= 0 {
+		return sendFull
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case queue <- message:
+		return sendOK
+	case <-sub.guard.doneChan():
+		return sendClosed
+	case <-shutdown:
+		return sendClosed
+	case <-timer.C:
+		return sendFull
+	}
+}
+
+// evictOldest removes the oldest message from the queue high selects to
+// make room, returning nil when the queue is empty or closed
+func (sub *Subscriber) evictOldest(high bool) *Message {
+	sub.guard.mu.RLock()
+	defer sub.guard.mu.RUnlock()
+
+	if sub.guard.closed {
+		return nil
+	}
+	select {
+	case oldest := <-sub.queue(high):
+		return oldest
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestSubscriber returns a subscriber with queues of the given size
+func newTestSubscriber(size int) *Subscriber {
+	return &Subscriber{
+		MessageChan:  make(chan *Message, size),
+		PriorityChan: make(chan *Message, size),
+	}
+}
+
+// TestDeliveryGuardCloseDuringSends closes a subscriber while senders and
+// a consumer are busy. A send on a closed queue would panic; every message
+// reported sent must reach the consumer.
+func TestDeliveryGuardCloseDuringSends(t *testing.T) {
+	for range 20 {
+		sub := newTestSubscriber(4)
+		shutdown := make(chan struct{})
+
+		var received atomic.Int64
+		consumed := make(chan struct{})
+		go func() {
+			defer close(consumed)
+			for {
+				select {
+				case _, ok := <-sub.MessageChan:
+					if !ok {
+						for range sub.PriorityChan {
+							received.Add(1)
+						}
+						return
+					}
+					received.Add(1)
+				case _, ok := <-sub.PriorityChan:
+					if ok {
+						received.Add(1)
+					}
+				}
+			}
+		}()
+
+		var sent atomic.Int64
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 200 {
+					if sub.send(&Message{}, time.Millisecond, (i+j)%3 == 0, shutdown) == sendOK {
+						sent.Add(1)
+					}
+				}
+			}()
+		}
+
+		time.Sleep(time.Millisecond)
+		sub.close(CloseReasonShutdown)
+		wg.Wait()
+		<-consumed
+
+		if got, want := received.Load(), sent.Load(); got != want {
+			t.Fatalf("consumer received %d messages, senders sent %d", got, want)
+		}
+		if result := sub.send(&Message{}, 0, false, shutdown); result != sendClosed {
+			t.Fatalf("send after close = %v, want sendClosed", result)
+		}
+	}
+}
+
+// TestDeliveryGuardCloseWakesWaitingSenders checks that senders blocked on
+// a full queue return as soon as the subscriber closes
+func TestDeliveryGuardCloseWakesWaitingSenders(t *testing.T) {
+	sub := newTestSubscriber(1)
+	sub.MessageChan <- &Message{}
+
+	results := make(chan sendResult, 16)
+	for range cap(results) {
+		go func() {
+			results <- sub.send(&Message{}, time.Minute, false, nil)
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		sub.close(CloseReasonShutdown)
+		close(closed)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for range cap(results) {
+		select {
+		case result := <-results:
+			if result != sendClosed {
+				t.Fatalf("waiting send = %v, want sendClosed", result)
+			}
+		case <-timeout:
+			t.Fatal("close did not wake waiting senders")
+		}
+	}
+	select {
+	case <-closed:
+	case <-timeout:
+		t.Fatal("close did not return")
+	}
+}
+
+// TestDeliveryGuardConcurrentClose closes a subscriber from many goroutines
+// at once; the queues must be closed exactly once
+func TestDeliveryGuardConcurrentClose(t *testing.T) {
+	var guard deliveryGuard
+	var runs atomic.Int32
+
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			guard.close(func() { runs.Add(1) })
+		}()
+	}
+	wg.Wait()
+
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("closeQueue ran %d times, want 1", got)
+	}
+	select {
+	case <-guard.doneChan():
+	default:
+		t.Fatal("done channel not closed")
+	}
+}
+
+// TestDeliveryGuardEvictDuringClose evicts from both queues while senders
+// fill them and the subscriber closes
+func TestDeliveryGuardEvictDuringClose(t *testing.T) {
+	sub := newTestSubscriber(2)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				sub.send(&Message{}, 0, i%2 == 0, nil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				sub.evictOldest(i%2 == 0)
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	sub.close(CloseReasonShutdown)
+	wg.Wait()
+
+	if message := sub.evictOldest(false); message != nil {
+		t.Fatal("evictOldest returned a message after close")
+	}
+}
+
+// TestDeliveryGuardShutdown checks that closing shutdown releases senders
+// waiting on a full queue without closing the subscriber
+func TestDeliveryGuardShutdown(t *testing.T) {
+	sub := newTestSubscriber(1)
+	sub.MessageChan <- &Message{}
+	shutdown := make(chan struct{})
+
+	results := make(chan sendResult, 8)
+	for range cap(results) {
+		go func() {
+			results <- sub.send(&Message{}, time.Minute, false, shutdown)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(shutdown)
+
+	for range cap(results) {
+		select {
+		case result := <-results:
+			if result != sendClosed {
+				t.Fatalf("send during shutdown = %v, want sendClosed", result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("shutdown did not wake waiting senders")
+		}
+	}
+	if len(sub.MessageChan) != 1 {
+		t.Fatalf("queue holds %d messages, want 1", len(sub.MessageChan))
+	}
+}
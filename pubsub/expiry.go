@@ -0,0 +1,119 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// ExpirySweepInterval is how often topics are checked for expiry, so a topic
+// is deleted at most this long after its expires_at
+const ExpirySweepInterval = time.Second
+
+// SetExpiry schedules a topic to be deleted at expiresAt. Its subscribers
+// are then closed with CloseReasonTopicExpired. A zero time cancels the
+// expiry.
+func (s *service) SetExpiry(ctx context.Context, topicName string, expiresAt time.Time) error {
+	if !expiresAt.IsZero() && !expiresAt.After(s.clock.Now()) {
+		return fmt.Errorf("invalid expiry: expires_at %s is not in the future", expiresAt.Format(time.RFC3339))
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.ExpiresAt = expiresAt
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic expiry", "topic", topicName, "expires_at", expiresAt)
+	return nil
+}
+
+// expireTopics deletes expired topics and ends expired subscriptions every
+// ExpirySweepInterval until the service shuts down
+func (s *service) expireTopics(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(ExpirySweepInterval)
+	defer ticker.Stop()
+
+	var sweeping atomic.Bool
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case now := <-ticker.C():
+			s.sweep(ctx, &sweeping, "expiry", func() {
+				for _, name := range s.expiredTopics(now) {
+					if err := s.deleteTopic(ctx, name, CloseReasonTopicExpired); err == nil {
+						logging.WithContext(ctx).Infow("Expired topic", "topic", name)
+					}
+				}
+				s.expireSubscriptions(ctx, now)
+			})
+		}
+	}
+}
+
+// expiredTopics returns the names of topics whose expiry has passed
+func (s *service) expiredTopics(now time.Time) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var expired []string
+	for name, topic := range s.topics {
+		topic.mu.RLock()
+		if !topic.ExpiresAt.IsZero() && !now.Before(topic.ExpiresAt) {
+			expired = append(expired, name)
+		}
+		topic.mu.RUnlock()
+	}
+	return expired
+}
+
+// expireSubscriptions closes the subscriptions whose TTL has passed with
+// CloseReasonSubscriptionExpired. Durable subscribers keep their cursors.
+func (s *service) expireSubscriptions(ctx context.Context, now time.Time) {
+	s.mu.RLock()
+	topics := make([]*Topic, 0, len(s.topics))
+	for _, topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	s.mu.RUnlock()
+
+	for _, topic := range topics {
+		topic.mu.RLock()
+		var expired []*Subscriber
+		for _, subscriber := range topic.Subscribers {
+			if subscriber.ExpiresAt != nil && !now.Before(*subscriber.ExpiresAt) {
+				expired = append(expired, subscriber)
+			}
+		}
+		topic.mu.RUnlock()
+		if len(expired) == 0 {
+			continue
+		}
+
+		// A client may have resubscribed meanwhile
+		topic.mu.Lock()
+		for _, subscriber := range expired {
+			if topic.Subscribers[subscriber.ClientID] != subscriber {
+				continue
+			}
+			subscriber.close(CloseReasonSubscriptionExpired)
+			delete(topic.Subscribers, subscriber.ClientID)
+			logging.WithContext(ctx).Infow("Expired subscription", "topic", topic.Name, "client_id", subscriber.ClientID)
+		}
+		s.emitSubscribers(topic)
+		topic.mu.Unlock()
+	}
+}
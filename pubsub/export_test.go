@@ -0,0 +1,21 @@
+package pubsub
+
+import "sync"
+
+// ResetForTest discards the singleton so the next InitService call builds a
+// fresh instance with a new config
+func ResetForTest() {
+	instanceMu.Lock()
+	defer instanceMu.Unlock()
+
+	if instance != nil {
+		select {
+		case <-instance.shutdown:
+		default:
+			close(instance.shutdown)
+		}
+	}
+
+	instance = nil
+	once = sync.Once{}
+}
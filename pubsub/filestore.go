@@ -0,0 +1,219 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Layout of Config.DataDir: every topic has a directory under topicsDir
+// holding its settings and its message database, beside the namespace
+// defaults
+const (
+	topicsDir      = "topics"
+	topicFile      = "topic.json"
+	messagesFile   = "messages.db"
+	sessionFile    = "session.json"
+	namespacesFile = "namespaces.json"
+)
+
+// Buckets of a FileStore database
+var (
+	messagesBucket = []byte("messages") // big-endian seq -> message JSON
+	metaBucket     = []byte("meta")     // lastSeqKey -> big-endian seq
+	lastSeqKey     = []byte("last_seq")
+)
+
+// FileStoreOpenTimeout bounds the wait for another process holding a
+// FileStore database open
+const FileStoreOpenTimeout = 5 * time.Second
+
+// FileStore is a MessageStore persisting a topic's messages to a bbolt
+// database, so they survive gateway restarts. Reads are served from a
+// RingBuffer kept in memory; the database keeps the same window of
+// messages, keyed by sequence number. Every append and removal is a
+// transaction synced to disk before it returns, so a crash loses nothing
+// already acknowledged.
+type FileStore struct {
+	*RingBuffer
+	path string
+	db   *bolt.DB
+	mu   sync.Mutex
+}
+
+// OpenFileStore opens the message database at path, creating it if needed,
+// and loads the newest size messages it holds
+func OpenFileStore(path string, size int) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: FileStoreOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	fs := &FileStore{
+		RingBuffer: NewRingBuffer(size),
+		path:       path,
+		db:         db,
+	}
+	if err := fs.load(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	return fs, nil
+}
+
+// load creates the buckets of a new database and fills the ring buffer with
+// the messages it holds
+func (fs *FileStore) load() error {
+	var messages []*Message
+	var lastSeq uint64
+
+	err := fs.db.Update(func(tx *bolt.Tx) error {
+		stored, err := tx.CreateBucketIfNotExists(messagesBucket)
+		if err != nil {
+			return err
+		}
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		if data := meta.Get(lastSeqKey); len(data) == 8 {
+			lastSeq = binary.BigEndian.Uint64(data)
+		}
+
+		return stored.ForEach(func(key, data []byte) error {
+			msg, err := decodeStoredMessage(data)
+			if err != nil {
+				return fmt.Errorf("message %d: %w", binary.BigEndian.Uint64(key), err)
+			}
+			messages = append(messages, msg)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	fs.RingBuffer.load(messages, lastSeq)
+	return nil
+}
+
+// Append stores a message under the next sequence number, dropping the
+// message it evicts, then stamps and buffers it. A message that cannot be
+// stored leaves the store and msg unchanged.
+func (fs *FileStore) Append(msg *Message) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	// Stamp a copy as RingBuffer.Append will stamp msg: appends all go
+	// through fs.mu, so the buffer's next sequence number is this one
+	stamped := *msg
+	stamped.Seq = fs.LastSeq() + 1
+	if stamped.Origin != "" && stamped.OriginSeq == 0 {
+		stamped.OriginSeq = stamped.Seq
+	}
+	data, err := json.Marshal(&stamped)
+	if err != nil {
+		return err
+	}
+
+	err = fs.db.Update(func(tx *bolt.Tx) error {
+		stored := tx.Bucket(messagesBucket)
+		if err := stored.Put(seqKey(stamped.Seq), data); err != nil {
+			return err
+		}
+		if err := tx.Bucket(metaBucket).Put(lastSeqKey, seqKey(stamped.Seq)); err != nil {
+			return err
+		}
+
+		// Keep the buffer's window: sequence numbers after Seq-size
+		cursor := stored.Cursor()
+		for key, _ := cursor.First(); key != nil && binary.BigEndian.Uint64(key)+uint64(fs.size) <= stamped.Seq; key, _ = cursor.First() {
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return fs.RingBuffer.Append(msg)
+}
+
+// Remove drops a message from the buffer and the database, so it stays
+// removed after a restart
+func (fs *FileStore) Remove(id string) *Message {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	removed := fs.RingBuffer.Remove(id)
+	if removed == nil {
+		return nil
+	}
+	err := fs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(messagesBucket).Delete(seqKey(removed.Seq))
+	})
+	if err != nil {
+		logging.WithContext(context.Background()).Errorw("Failed to persist message removal",
+			"path", fs.path, "message_id", id, "error", err)
+	}
+
+	return removed
+}
+
+// Close closes the database. The store must not be used afterwards.
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.db.Close()
+}
+
+// seqKey encodes a sequence number as a database key, ordered as numbers
+func seqKey(seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, seq)
+}
+
+// writeFileAtomic replaces path with data, so readers and crashes never see
+// a partly written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+	return os.Rename(tmp, path)
+}
+
+// topicDir returns the directory holding a topic's files under dataDir.
+// Names are escaped, so "/" in topic names such as $SYS/alerts and the
+// names "." and ".." cannot leave the topics directory.
+func topicDir(dataDir, name string) string {
+	escaped := strings.ReplaceAll(url.PathEscape(name), ".", "%2E")
+	return filepath.Join(dataDir, topicsDir, escaped)
+}
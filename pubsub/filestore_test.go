@@ -0,0 +1,187 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders", messagesFile)
+
+	store, err := OpenFileStore(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := store.Append(&Message{ID: fmt.Sprint("m", i), Payload: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if removed := store.Remove("m4"); removed == nil {
+		t.Fatal("Remove(m4) found nothing")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A larger buffer shows the evicted messages were dropped from disk too
+	store, err = OpenFileStore(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	assertSeqs(t, "GetMessages after restart", store.GetMessages(), 3, 5)
+	if seq := store.LastSeq(); seq != 5 {
+		t.Errorf("LastSeq after restart = %d, want 5", seq)
+	}
+	if payload := store.GetLastN(1)[0].Payload; payload != json.Number("5") {
+		t.Errorf("payload after restart = %#v, want 5", payload)
+	}
+
+	msg := &Message{ID: "m6"}
+	if err := store.Append(msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Seq != 6 {
+		t.Errorf("append after restart stamped seq %d, want 6", msg.Seq)
+	}
+}
+
+// TestFileStoreCrash opens a copy of the database taken while the store is
+// still open, as a crash would leave it
+func TestFileStoreCrash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, messagesFile)
+
+	store, err := OpenFileStore(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for i := 1; i <= 3; i++ {
+		if err := store.Append(&Message{ID: fmt.Sprint("m", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Remove("m2")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crashed := filepath.Join(dir, "crashed.db")
+	if err := os.WriteFile(crashed, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := OpenFileStore(crashed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recovered.Close()
+	assertSeqs(t, "GetMessages after crash", recovered.GetMessages(), 1, 3)
+	if seq := recovered.LastSeq(); seq != 3 {
+		t.Errorf("LastSeq after crash = %d, want 3", seq)
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), messagesFile)
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path, 10); err == nil {
+		t.Error("corrupt database opened")
+	}
+}
+
+// TestDataDirRestart restarts a service on the same DataDir: topics and
+// their messages are restored in Start
+func TestDataDirRestart(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+
+	s := newService(config)
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateTopic(ctx, "orders", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := s.Publish(ctx, "orders", Message{ID: fmt.Sprint("m", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newService(config)
+	if err := restarted.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Stop(ctx)
+
+	topic, exists := restarted.topics["orders"]
+	if !exists {
+		t.Fatal("topic not restored")
+	}
+	assertSeqs(t, "restored messages", topic.Messages.GetMessages(), 1, 2, 3)
+	if err := restarted.Publish(ctx, "orders", Message{ID: "m4"}); err != nil {
+		t.Fatal(err)
+	}
+	if seq := topic.Messages.LastSeq(); seq != 4 {
+		t.Errorf("LastSeq after publishing on restart = %d, want 4", seq)
+	}
+}
+
+// TestFileStoreAppendError checks a failed write leaves the buffer as the
+// database has it
+func TestFileStoreAppendError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), messagesFile)
+
+	store, err := OpenFileStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := store.Append(&Message{ID: fmt.Sprint("m", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &Message{ID: "m4"}
+	if err := store.Append(msg); err == nil {
+		t.Fatal("Append to a closed database succeeded")
+	}
+	if msg.Seq != 0 {
+		t.Errorf("failed Append stamped seq %d", msg.Seq)
+	}
+	assertSeqs(t, "GetMessages after failed Append", store.GetMessages(), 2, 3)
+	if seq := store.LastSeq(); seq != 3 {
+		t.Errorf("LastSeq after failed Append = %d, want 3", seq)
+	}
+	if count := store.Count(); count != 2 {
+		t.Errorf("Count after failed Append = %d, want 2", count)
+	}
+
+	reopened, err := OpenFileStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	assertSeqs(t, "GetMessages after reopening", reopened.GetMessages(), 2, 3)
+	if seq := reopened.LastSeq(); seq != 3 {
+		t.Errorf("LastSeq after reopening = %d, want 3", seq)
+	}
+}
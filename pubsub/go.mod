@@ -3,13 +3,22 @@ module github.com/ammysap/plivo-pub-sub/pubsub
 go 1.24.6
 
 require (
+	github.com/alicebob/miniredis/v2 v2.39.0
 	github.com/ammysap/plivo-pub-sub/logging v0.0.0
 	github.com/google/uuid v1.6.0
+	github.com/redis/go-redis/v9 v9.22.0
+	github.com/robfig/cron/v3 v3.0.1
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/time v0.14.0
 )
 
 require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	go.uber.org/atomic v1.11.0 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
 	go.uber.org/zap v1.27.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
 )
 
 replace github.com/ammysap/plivo-pub-sub/logging => ../logging
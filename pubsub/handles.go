@@ -0,0 +1,137 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrSubscriptionClosed is returned by SubscriberHandle.Receive once the
+// subscription has ended and its queued messages are drained. It is wrapped
+// with the CloseReason.
+var ErrSubscriptionClosed = errors.New("subscription closed")
+
+// Publisher publishes to one topic. Embedders hand it to components that
+// only produce, instead of the whole Service.
+type Publisher struct {
+	service *service
+	topic   string
+}
+
+// OpenPublisher returns a handle publishing to a topic, checking that the
+// topic exists and that the context's principal may publish to it
+func (s *service) OpenPublisher(ctx context.Context, topicName string) (*Publisher, error) {
+	if err := authorize(ctx, ActionPublish, topicName); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	_, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+	return &Publisher{service: s, topic: topicName}, nil
+}
+
+// Topic returns the topic the handle publishes to
+func (p *Publisher) Topic() string {
+	return p.topic
+}
+
+// Publish publishes a message to the handle's topic, as Service.Publish
+func (p *Publisher) Publish(ctx context.Context, message Message) error {
+	return p.service.Publish(ctx, p.topic, message)
+}
+
+// SubscriberHandle receives from one subscription. Embedders hand it to
+// components that only consume, instead of the whole Service and the
+// subscriber's raw channels.
+type SubscriberHandle struct {
+	service    *service
+	subscriber *Subscriber
+}
+
+// OpenSubscriber subscribes to a topic, as Service.Subscribe, and returns a
+// handle to the subscription
+func (s *service) OpenSubscriber(ctx context.Context, topicName, clientID string, opts *SubscribeOptions) (*SubscriberHandle, error) {
+	subscriber, err := s.Subscribe(ctx, topicName, clientID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SubscriberHandle{service: s, subscriber: subscriber}, nil
+}
+
+// Topic returns the subscribed topic
+func (h *SubscriberHandle) Topic() string {
+	return h.subscriber.TopicName
+}
+
+// ClientID returns the ID the subscription was made under
+func (h *SubscriberHandle) ClientID() string {
+	return h.subscriber.ClientID
+}
+
+// Receive waits for the next message, high priority ones first. Once the
+// subscription has ended and every queued message was received, it returns
+// ErrSubscriptionClosed wrapped with the reason. Durable subscriptions pull
+// with Fetch instead.
+func (h *SubscriberHandle) Receive(ctx context.Context) (*Message, error) {
+	sub := h.subscriber
+	if sub.Durable {
+		return nil, fmt.Errorf("durable subscriptions pull with Fetch")
+	}
+
+	for {
+		message, ok, closed := sub.TryReceive()
+		if ok {
+			return message, nil
+		}
+		if closed {
+			return nil, fmt.Errorf("%w: %s", ErrSubscriptionClosed, sub.CloseReason())
+		}
+
+		// Wait for either queue, then take from them in priority order
+		select {
+		case message, ok := <-sub.PriorityChan:
+			if ok {
+				return message, nil
+			}
+		case message, ok := <-sub.MessageChan:
+			if ok {
+				return message, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Fetch pulls up to max messages from a durable subscription, as
+// Service.Fetch
+func (h *SubscriberHandle) Fetch(ctx context.Context, max int) ([]*Message, error) {
+	return h.service.Fetch(ctx, h.subscriber.TopicName, h.subscriber.ClientID, max)
+}
+
+// Ack acknowledges a durable subscription's messages up to seq, as
+// Service.Ack
+func (h *SubscriberHandle) Ack(ctx context.Context, seq uint64) (*CursorInfo, error) {
+	return h.service.Ack(ctx, h.subscriber.TopicName, h.subscriber.ClientID, seq)
+}
+
+// Nack rejects a received message on a subscription made with
+// MaxDeliveries, as Service.Nack
+func (h *SubscriberHandle) Nack(ctx context.Context, seq uint64, requeue bool) (*NackResult, error) {
+	return h.service.Nack(ctx, h.subscriber.TopicName, h.subscriber.ClientID, seq, requeue)
+}
+
+// Depth returns how many messages wait in the subscription's queues
+func (h *SubscriberHandle) Depth() int {
+	return h.subscriber.Depth()
+}
+
+// Close unsubscribes. Messages already queued can still be received.
+func (h *SubscriberHandle) Close(ctx context.Context) error {
+	return h.service.Unsubscribe(ctx, h.subscriber.TopicName, h.subscriber.ClientID)
+}
@@ -0,0 +1,187 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// MaxHeaderIndexes is the most header keys a single topic may index
+const MaxHeaderIndexes = 8
+
+// SetIndexes replaces the header keys the buffer indexes and rebuilds the
+// index over the messages already buffered. No keys removes the index.
+func (rb *RingBuffer) SetIndexes(keys []string) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.indexes = newHeaderIndexes(keys)
+	if rb.indexes == nil {
+		return
+	}
+	for i := 0; i < rb.count; i++ {
+		if msg := rb.buffer[(rb.head+i)%rb.size]; msg != nil {
+			rb.indexes.add(msg)
+		}
+	}
+}
+
+// Indexes returns the indexed header keys, sorted
+func (rb *RingBuffer) Indexes() []string {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.indexes.keys()
+}
+
+// Lookup returns up to max of the newest buffered messages carrying every
+// one of the headers, in chronological order. It fails if any of the
+// header keys is not indexed.
+func (rb *RingBuffer) Lookup(headers map[string]string, max int) ([]*Message, error) {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.indexes.lookup(headers, max)
+}
+
+// headerIndexes maps each indexed header key to its values, and each value
+// to the buffered messages carrying it, oldest first. Stores guard it with
+// their own lock.
+type headerIndexes map[string]map[string][]*Message
+
+// newHeaderIndexes returns empty indexes over keys, or nil for no keys
+func newHeaderIndexes(keys []string) headerIndexes {
+	if len(keys) == 0 {
+		return nil
+	}
+	indexes := make(headerIndexes, len(keys))
+	for _, key := range keys {
+		indexes[key] = make(map[string][]*Message)
+	}
+	return indexes
+}
+
+// keys returns the indexed header keys, sorted
+func (indexes headerIndexes) keys() []string {
+	keys := make([]string, 0, len(indexes))
+	for key := range indexes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// lookup returns up to max of the newest indexed messages carrying every
+// one of the headers, in chronological order
+func (indexes headerIndexes) lookup(headers map[string]string, max int) ([]*Message, error) {
+	// Walk the shortest posting list and check the other headers directly
+	var candidates []*Message
+	first := true
+	for key, value := range headers {
+		index, indexed := indexes[key]
+		if !indexed {
+			return nil, fmt.Errorf("invalid header lookup: header %s is not indexed", key)
+		}
+		if posting := index[value]; first || len(posting) < len(candidates) {
+			candidates = posting
+			first = false
+		}
+	}
+
+	messages := make([]*Message, 0, min(len(candidates), max))
+	for i := len(candidates) - 1; i >= 0 && len(messages) < max; i-- {
+		if carriesHeaders(candidates[i], headers) {
+			messages = append(messages, candidates[i])
+		}
+	}
+	slices.Reverse(messages)
+
+	return messages, nil
+}
+
+// add adds a message to the posting lists of its indexed headers
+func (indexes headerIndexes) add(msg *Message) {
+	for key, value := range msg.Headers {
+		if index, indexed := indexes[key]; indexed {
+			index[value] = append(index[value], msg)
+		}
+	}
+}
+
+// remove removes a message from the posting lists of its indexed headers
+func (indexes headerIndexes) remove(msg *Message) {
+	for key, value := range msg.Headers {
+		index, indexed := indexes[key]
+		if !indexed {
+			continue
+		}
+		posting := slices.DeleteFunc(index[value], func(m *Message) bool { return m == msg })
+		if len(posting) == 0 {
+			delete(index, value)
+		} else {
+			index[value] = posting
+		}
+	}
+}
+
+// carriesHeaders reports whether the message has every one of the headers
+func carriesHeaders(msg *Message, headers map[string]string) bool {
+	for key, value := range headers {
+		if got, ok := msg.Headers[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SetHeaderIndexes sets the header keys a topic indexes for FindMessages,
+// indexing the messages it already buffers. No keys drops the index.
+func (s *service) SetHeaderIndexes(ctx context.Context, topicName string, keys []string) error {
+	if len(keys) > MaxHeaderIndexes {
+		return fmt.Errorf("invalid header index: at most %d keys may be indexed", MaxHeaderIndexes)
+	}
+	for i, key := range keys {
+		if key == "" {
+			return fmt.Errorf("invalid header index: keys must not be empty")
+		}
+		if slices.Contains(keys[:i], key) {
+			return fmt.Errorf("invalid header index: duplicate key %s", key)
+		}
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.Messages.SetIndexes(keys)
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic header indexes", "topic", topicName, "keys", keys)
+	return nil
+}
+
+// FindMessages returns up to max of the newest buffered messages on a topic
+// carrying every one of the headers, oldest first. Each header key must be
+// indexed on the topic; see SetHeaderIndexes.
+func (s *service) FindMessages(ctx context.Context, topicName string, headers map[string]string, max int) ([]*Message, error) {
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("invalid header lookup: at least one header is required")
+	}
+	if max <= 0 {
+		return nil, fmt.Errorf("invalid header lookup: max must be positive")
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	return topic.Messages.Lookup(headers, max)
+}
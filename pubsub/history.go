@@ -0,0 +1,305 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Topic lifecycle actions recorded in a topic's history
+const (
+	HistoryCreated    = "created"
+	HistoryRecreated  = "recreated" // created again after being deleted or expiring
+	HistoryConfigured = "configured"
+	HistoryDeleted    = "deleted"
+	HistoryExpired    = "expired"
+)
+
+// History limits. History outlives its topic, so who deleted a topic can be
+// answered after the fact; once MaxHistoryTopics names have history, the
+// deleted topic changed least recently is forgotten.
+const (
+	MaxTopicHistory  = 100
+	MaxHistoryTopics = 10000
+)
+
+// ActorSystem is recorded for changes made without an actor in the context,
+// such as expiry or demo seeding
+const ActorSystem = "system"
+
+// TopicSettings is the configuration of a topic, enough to recreate it
+type TopicSettings struct {
+	Owner         string            `json:"owner,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	ReplayRate    float64           `json:"replay_rate,omitempty"`
+	Decoding      Decoding          `json:"decoding"`
+	Ordering      string            `json:"ordering,omitempty"`
+	Delivery      string            `json:"delivery,omitempty"`
+	HeaderIndexes []string          `json:"header_indexes,omitempty"`
+	ExpiresAt     *time.Time        `json:"expires_at,omitempty"`
+	Retention     Retention         `json:"retention"`
+	Routes        []Route           `json:"routes,omitempty"`
+	Schedules     []Schedule        `json:"schedules,omitempty"` // without run counters
+
+	Options TopicOptions `json:"options"` // fixed at creation
+
+	Backpressure Backpressure `json:"backpressure"`
+	Schemas      Schemas      `json:"schemas"`
+	Dedup        Dedup        `json:"dedup"`
+	LegalHold    *LegalHold   `json:"legal_hold,omitempty"`
+
+	Partitions int `json:"partitions,omitempty"`
+	TextIndex  int `json:"text_index,omitempty"`
+
+	Compaction Compaction `json:"compaction"`
+	Ingress    Ingress    `json:"ingress"`
+}
+
+// ConfigChange is one setting that differs from the previous settings
+type ConfigChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// HistoryEvent is one change in a topic's lifecycle. Settings are the
+// topic's configuration after the change or, for deletions, as it was
+// deleted, so the topic can be restored from them.
+type HistoryEvent struct {
+	Action   string         `json:"action"`
+	Actor    string         `json:"actor"` // user ID, replication:<region> or system
+	At       time.Time      `json:"at"`
+	Source   string         `json:"source,omitempty"` // topic cloned from
+	Settings TopicSettings  `json:"settings"`
+	Changes  []ConfigChange `json:"changes,omitempty"` // against the previous event's settings
+}
+
+type actorContextKey struct{}
+
+// WithActor returns a copy of ctx attributing the topic changes made with it
+// to actor in topic history
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// actorFromContext returns the actor set by WithActor, or ActorSystem
+func actorFromContext(ctx context.Context) string {
+	if actor, _ := ctx.Value(actorContextKey{}).(string); actor != "" {
+		return actor
+	}
+	return ActorSystem
+}
+
+// TopicHistory returns a topic's lifecycle events, oldest first. It is
+// available after the topic is deleted.
+func (s *service) TopicHistory(ctx context.Context, topicName string) ([]HistoryEvent, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	events, exists := s.history[topicName]
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+	return slices.Clone(events), nil
+}
+
+// topicSettings snapshots a topic's configuration. Caller must hold
+// topic.mu.
+func topicSettings(topic *Topic) TopicSettings {
+	settings := TopicSettings{
+		Owner:         topic.Owner,
+		Description:   topic.Description,
+		Labels:        maps.Clone(topic.Labels),
+		ReplayRate:    topic.ReplayRate,
+		Decoding:      topic.Decoding,
+		Ordering:      topic.Ordering,
+		Delivery:      topic.Delivery,
+		Retention:     topic.Retention,
+		Backpressure:  topic.Backpressure,
+		Schemas:       topic.Schemas,
+		Dedup:         topic.Dedup,
+		LegalHold:     topic.LegalHold,
+		Options:       topic.Options,
+		HeaderIndexes: topic.Messages.Indexes(),
+		Partitions:    topic.Partitions,
+		TextIndex:     topic.TextIndex,
+		Compaction:    topic.Compaction,
+		Ingress:       topic.Ingress,
+	}
+	if !topic.ExpiresAt.IsZero() {
+		expiresAt := topic.ExpiresAt
+		settings.ExpiresAt = &expiresAt
+	}
+	for _, route := range topic.Routes {
+		settings.Routes = append(settings.Routes, *route)
+	}
+	for _, schedule := range topic.Schedules {
+		settings.Schedules = append(settings.Schedules, Schedule{
+			ID:              schedule.ID,
+			Cron:            schedule.Cron,
+			PayloadTemplate: schedule.PayloadTemplate,
+			CreatedAt:       schedule.CreatedAt,
+		})
+	}
+	if len(settings.HeaderIndexes) == 0 {
+		settings.HeaderIndexes = nil
+	}
+	return settings
+}
+
+// diffSettings lists the settings that differ between old and new
+func diffSettings(old, new TopicSettings) []ConfigChange {
+	fields := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"owner", old.Owner, new.Owner},
+		{"description", old.Description, new.Description},
+		{"labels", old.Labels, new.Labels},
+		{"replay_rate", old.ReplayRate, new.ReplayRate},
+		{"decoding", old.Decoding, new.Decoding},
+		{"ordering", old.Ordering, new.Ordering},
+		{"delivery", old.Delivery, new.Delivery},
+		{"partitions", old.Partitions, new.Partitions},
+		{"header_indexes", old.HeaderIndexes, new.HeaderIndexes},
+		{"expires_at", old.ExpiresAt, new.ExpiresAt},
+		{"retention", old.Retention, new.Retention},
+		{"backpressure", old.Backpressure, new.Backpressure},
+		{"schemas", old.Schemas, new.Schemas},
+		{"dedup", old.Dedup, new.Dedup},
+		{"legal_hold", old.LegalHold, new.LegalHold},
+		{"text_index", old.TextIndex, new.TextIndex},
+		{"compaction", old.Compaction, new.Compaction},
+		{"ingress", old.Ingress, new.Ingress},
+		{"routes", old.Routes, new.Routes},
+		{"schedules", old.Schedules, new.Schedules},
+	}
+
+	var changes []ConfigChange
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.old, field.new) {
+			changes = append(changes, ConfigChange{Field: field.name, Old: field.old, New: field.new})
+		}
+	}
+	return changes
+}
+
+// recordCreated records a topic's creation, as a recreation with the
+// changes since it was deleted when it has history. Caller must hold s.mu
+// and not have added topic to s.topics yet.
+func (s *service) recordCreated(ctx context.Context, topic *Topic, source string) {
+	event := HistoryEvent{
+		Action:   HistoryCreated,
+		Actor:    actorFromContext(ctx),
+		At:       s.clock.Now(),
+		Source:   source,
+		Settings: topicSettings(topic),
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	if previous := s.history[topic.Name]; len(previous) > 0 {
+		event.Action = HistoryRecreated
+		event.Changes = diffSettings(previous[len(previous)-1].Settings, event.Settings)
+	}
+	s.appendHistory(topic.Name, event)
+}
+
+// recordDeleted records a topic's deletion with the settings it had. Caller
+// must hold s.mu and topic.mu.
+func (s *service) recordDeleted(ctx context.Context, topic *Topic, reason string) {
+	event := HistoryEvent{
+		Action:   HistoryDeleted,
+		Actor:    actorFromContext(ctx),
+		At:       s.clock.Now(),
+		Settings: topicSettings(topic),
+	}
+	if reason == CloseReasonTopicExpired {
+		event.Action = HistoryExpired
+		event.Actor = ActorSystem
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	s.appendHistory(topic.Name, event)
+}
+
+// recordConfigured records a change to a topic's configuration, and persists
+// it in the topic catalog, unless the topic was deleted meanwhile or nothing
+// changed
+func (s *service) recordConfigured(ctx context.Context, topic *Topic) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.topics[topic.Name] != topic {
+		return
+	}
+
+	topic.mu.RLock()
+	settings := topicSettings(topic)
+	topic.mu.RUnlock()
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	var changes []ConfigChange
+	if previous := s.history[topic.Name]; len(previous) > 0 {
+		changes = diffSettings(previous[len(previous)-1].Settings, settings)
+		if len(changes) == 0 {
+			return
+		}
+	}
+
+	if err := s.saveTopic(ctx, topic.Name, topic.CreatedAt, settings); err != nil {
+		logging.WithContext(ctx).Errorw("Failed to persist topic configuration", "topic", topic.Name, "error", err)
+	}
+
+	s.appendHistory(topic.Name, HistoryEvent{
+		Action:   HistoryConfigured,
+		Actor:    actorFromContext(ctx),
+		At:       s.clock.Now(),
+		Settings: settings,
+		Changes:  changes,
+	})
+	logging.WithContext(ctx).Debugw("Recorded topic configuration change", "topic", topic.Name, "changes", len(changes))
+}
+
+// appendHistory adds an event to a topic's history, keeping the newest
+// MaxTopicHistory events. Caller must hold s.historyMu.
+func (s *service) appendHistory(name string, event HistoryEvent) {
+	events := s.history[name]
+	if len(events) >= MaxTopicHistory {
+		events = slices.Delete(events, 0, len(events)-MaxTopicHistory+1)
+	}
+	s.history[name] = append(events, event)
+
+	if len(s.history) > MaxHistoryTopics {
+		s.forgetOldestHistory()
+	}
+}
+
+// forgetOldestHistory drops the history of the deleted topic whose last
+// event is oldest. Caller must hold s.historyMu.
+func (s *service) forgetOldestHistory() {
+	oldest := ""
+	var oldestAt time.Time
+	for name, events := range s.history {
+		last := events[len(events)-1]
+		if last.Action != HistoryDeleted && last.Action != HistoryExpired {
+			continue
+		}
+		if oldest == "" || last.At.Before(oldestAt) {
+			oldest, oldestAt = name, last.At
+		}
+	}
+	if oldest != "" {
+		delete(s.history, oldest)
+	}
+}
@@ -0,0 +1,204 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"golang.org/x/time/rate"
+)
+
+// Ingress queue bounds
+const (
+	MaxIngressSize = 100000
+	MaxIngressRate = 1000000
+)
+
+// Ingress configures a topic's ingress queue, which absorbs bursts of
+// publishes and fans them out in publish order, at most Rate per second.
+// A publish is acknowledged once queued, and refused as busy only while
+// the queue is full. A zero Ingress publishes directly.
+type Ingress struct {
+	Size int     `json:"size,omitempty"` // publishes queued; 0 disables the queue
+	Rate float64 `json:"rate,omitempty"` // publishes fanned out per second; 0 is as fast as fan-out allows
+}
+
+// Enabled reports whether publishes are queued
+func (i Ingress) Enabled() bool {
+	return i.Size > 0
+}
+
+// Validate checks the size and rate are within bounds
+func (i *Ingress) Validate() error {
+	if i.Size < 0 || i.Size > MaxIngressSize {
+		return fmt.Errorf("invalid ingress: size must be between 0 and %d", MaxIngressSize)
+	}
+	if i.Rate < 0 || i.Rate > MaxIngressRate {
+		return fmt.Errorf("invalid ingress: rate must be between 0 and %d", MaxIngressRate)
+	}
+	if i.Rate > 0 && i.Size == 0 {
+		return fmt.Errorf("invalid ingress: rate requires a size")
+	}
+	return nil
+}
+
+// limit returns the pace of fan-out from the queue
+func (i Ingress) limit() rate.Limit {
+	if i.Rate == 0 {
+		return rate.Inf
+	}
+	return rate.Limit(i.Rate)
+}
+
+// ingressQueue holds a topic's queued publishes for its drainer
+type ingressQueue struct {
+	queue   chan ingressPublish
+	stop    chan struct{}
+	limiter *rate.Limiter
+}
+
+// ingressPublish is a publish waiting in a topic's ingress queue
+type ingressPublish struct {
+	ctx     context.Context
+	message *Message
+	visited map[string]bool
+}
+
+// errBusy reports a publish refused because the topic's ingress queue is
+// full
+func errBusy(topicName string, size int) error {
+	return fmt.Errorf("busy: ingress queue of topic %s is full (%d publishes), retry later", topicName, size)
+}
+
+// SetIngress sets a topic's ingress queue. Publishes queued before its size
+// changes or it is disabled are fanned out at once.
+func (s *service) SetIngress(ctx context.Context, topicName string, ingress Ingress) error {
+	if err := ingress.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	s.setIngress(topic, ingress)
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic ingress", "topic", topicName, "size", ingress.Size, "rate", ingress.Rate)
+	return nil
+}
+
+// setIngress sets a topic's ingress queue, starting or replacing its
+// drainer when the size changes. Caller must hold topic.mu or own topic
+// exclusively.
+func (s *service) setIngress(topic *Topic, ingress Ingress) {
+	topic.Ingress = ingress
+
+	if topic.ingress != nil && cap(topic.ingress.queue) == ingress.Size {
+		topic.ingress.limiter.SetLimit(ingress.limit())
+		return
+	}
+	stopIngress(topic)
+	if !ingress.Enabled() {
+		return
+	}
+
+	topic.ingress = &ingressQueue{
+		queue:   make(chan ingressPublish, ingress.Size),
+		stop:    make(chan struct{}),
+		limiter: rate.NewLimiter(ingress.limit(), 1),
+	}
+	s.wg.Add(1)
+	go s.drainIngress(topic, topic.ingress)
+}
+
+// stopIngress stops a topic's drainer, if it has one, which fans out what
+// is still queued unless the topic was deleted. Caller must hold topic.mu.
+func stopIngress(topic *Topic) {
+	if topic.ingress != nil {
+		close(topic.ingress.stop)
+		topic.ingress = nil
+	}
+}
+
+// queueIngress queues a publish on a topic with an ingress queue,
+// reporting whether it did. The publish outlives its caller's context, and
+// follows routes with its own copy of visited.
+func (s *service) queueIngress(ctx context.Context, topic *Topic, message *Message, visited map[string]bool) (bool, error) {
+	// Holding topic.mu keeps setIngress from stopping the queue mid-send
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+
+	queue := topic.ingress
+	if queue == nil {
+		return false, nil
+	}
+
+	publish := ingressPublish{ctx: context.WithoutCancel(ctx), message: message, visited: maps.Clone(visited)}
+	select {
+	case queue.queue <- publish:
+		return true, nil
+	default:
+		topic.busy.Add(1)
+		return false, errBusy(topic.Name, cap(queue.queue))
+	}
+}
+
+// drainIngress fans out a topic's queued publishes at the queue's rate,
+// until the queue is stopped or the service stops
+func (s *service) drainIngress(topic *Topic, queue *ingressQueue) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case publish := <-queue.queue:
+			if !s.sleep(queue.limiter.Reserve().Delay()) {
+				s.deliverQueued(topic, publish)
+				s.flushIngress(topic, queue)
+				return
+			}
+			s.deliverQueued(topic, publish)
+		case <-queue.stop:
+			s.flushIngress(topic, queue)
+			return
+		case <-s.shutdown:
+			s.flushIngress(topic, queue)
+			return
+		}
+	}
+}
+
+// flushIngress fans out every publish left in a stopped queue, without
+// pacing, or discards them when the topic was deleted
+func (s *service) flushIngress(topic *Topic, queue *ingressQueue) {
+	s.mu.RLock()
+	deleted := s.topics[topic.Name] != topic
+	s.mu.RUnlock()
+
+	for {
+		select {
+		case publish := <-queue.queue:
+			if !deleted {
+				s.deliverQueued(topic, publish)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// deliverQueued fans out a queued publish, logging a failure its publisher
+// can no longer be told about
+func (s *service) deliverQueued(topic *Topic, publish ingressPublish) {
+	if err := s.publishNow(publish.ctx, topic, publish.message, publish.visited); err != nil {
+		logging.WithContext(publish.ctx).Errorw("Failed to publish queued message",
+			"topic", topic.Name, "message_id", publish.message.ID, "error", err)
+	}
+}
@@ -0,0 +1,546 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON Schema bounds
+const (
+	MaxJSONSchemaSize   = 64 * 1024 // bytes of the encoded document
+	MaxJSONSchemaDepth  = 32        // nested subschemas
+	MaxJSONSchemaErrors = 10        // failing fields reported for one payload
+)
+
+// JSON Schema types
+const (
+	jsonTypeObject  = "object"
+	jsonTypeArray   = "array"
+	jsonTypeString  = "string"
+	jsonTypeNumber  = "number"
+	jsonTypeInteger = "integer"
+	jsonTypeBoolean = "boolean"
+	jsonTypeNull    = "null"
+)
+
+var jsonTypes = []string{jsonTypeObject, jsonTypeArray, jsonTypeString, jsonTypeNumber, jsonTypeInteger, jsonTypeBoolean, jsonTypeNull}
+
+// jsonSchemaAnnotations are keywords accepted but not validated
+var jsonSchemaAnnotations = []string{"$schema", "$id", "$comment", "title", "description", "default", "examples", "format", "deprecated", "readOnly", "writeOnly"}
+
+// JSONSchema is a JSON Schema document that payloads published to a topic
+// must match, attached when the topic is created. It supports the
+// validation keywords type, enum, const, properties, required,
+// additionalProperties, minProperties, maxProperties, items, minItems,
+// maxItems, uniqueItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf, oneOf and
+// not; annotations such as title and format are kept but not checked, and
+// other keywords, $ref included, are refused.
+type JSONSchema struct {
+	document map[string]interface{}
+	root     *schemaNode
+}
+
+// NewJSONSchema compiles a JSON Schema document
+func NewJSONSchema(document map[string]interface{}) (*JSONSchema, error) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json schema: %v", err)
+	}
+	schema := &JSONSchema{}
+	if err := schema.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// MarshalJSON encodes the schema as its document
+func (j *JSONSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.document)
+}
+
+// UnmarshalJSON decodes and compiles a schema document, which must be an
+// object
+func (j *JSONSchema) UnmarshalJSON(data []byte) error {
+	if len(data) > MaxJSONSchemaSize {
+		return fmt.Errorf("invalid json schema: larger than %d bytes", MaxJSONSchemaSize)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil || document == nil {
+		return fmt.Errorf("invalid json schema: expected an object")
+	}
+
+	root, err := compileSchema(document, "#", 0)
+	if err != nil {
+		return err
+	}
+	j.document, j.root = document, root
+	return nil
+}
+
+// Validate checks a payload matches the schema, reporting up to
+// MaxJSONSchemaErrors failing fields
+func (j *JSONSchema) Validate(payload interface{}) error {
+	if j.root == nil {
+		return nil
+	}
+
+	var failures []string
+	j.root.validate(payload, "", &failures)
+	if len(failures) == 0 {
+		return nil
+	}
+	if len(failures) > MaxJSONSchemaErrors {
+		failures = append(failures[:MaxJSONSchemaErrors], fmt.Sprintf("and %d more", len(failures)-MaxJSONSchemaErrors))
+	}
+	return fmt.Errorf("invalid payload: does not match the topic's json schema: %s", strings.Join(failures, "; "))
+}
+
+// schemaNode is a compiled schema or subschema. Unset bounds are nil.
+type schemaNode struct {
+	never bool // the false schema, which nothing matches
+
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+
+	properties    map[string]*schemaNode
+	required      []string
+	additional    *schemaNode // nil allows any other property
+	minProperties *int
+	maxProperties *int
+
+	items       *schemaNode
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *big.Rat
+	maximum          *big.Rat
+	exclusiveMinimum *big.Rat
+	exclusiveMaximum *big.Rat
+	multipleOf       *big.Rat
+
+	allOf []*schemaNode
+	anyOf []*schemaNode
+	oneOf []*schemaNode
+	not   *schemaNode
+}
+
+// compileSchema compiles the subschema at path, which is true, false or an
+// object
+func compileSchema(v interface{}, path string, depth int) (*schemaNode, error) {
+	if depth > MaxJSONSchemaDepth {
+		return nil, fmt.Errorf("invalid json schema: nested deeper than %d at %s", MaxJSONSchemaDepth, path)
+	}
+
+	if allowed, ok := v.(bool); ok {
+		return &schemaNode{never: !allowed}, nil
+	}
+	document, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid json schema: %s must be an object or a boolean", path)
+	}
+
+	node := &schemaNode{}
+	keywords := make([]string, 0, len(document))
+	for keyword := range document {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+
+	for _, keyword := range keywords {
+		value := document[keyword]
+		at := path + "/" + keyword
+		var err error
+
+		switch keyword {
+		case "type":
+			node.types, err = compileTypes(value, at)
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok || len(values) == 0 {
+				err = fmt.Errorf("invalid json schema: %s must be a non-empty array", at)
+			}
+			node.enum = values
+		case "const":
+			node.constant, node.hasConst = value, true
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("invalid json schema: %s must be an object", at)
+				break
+			}
+			node.properties = make(map[string]*schemaNode, len(properties))
+			for name, property := range properties {
+				if node.properties[name], err = compileSchema(property, at+"/"+pointerEscaper.Replace(name), depth+1); err != nil {
+					break
+				}
+			}
+		case "required":
+			node.required, err = compileStrings(value, at)
+		case "additionalProperties":
+			node.additional, err = compileSchema(value, at, depth+1)
+		case "minProperties":
+			node.minProperties, err = compileCount(value, at)
+		case "maxProperties":
+			node.maxProperties, err = compileCount(value, at)
+		case "items":
+			node.items, err = compileSchema(value, at, depth+1)
+		case "minItems":
+			node.minItems, err = compileCount(value, at)
+		case "maxItems":
+			node.maxItems, err = compileCount(value, at)
+		case "uniqueItems":
+			unique, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("invalid json schema: %s must be a boolean", at)
+			}
+			node.uniqueItems = unique
+		case "minLength":
+			node.minLength, err = compileCount(value, at)
+		case "maxLength":
+			node.maxLength, err = compileCount(value, at)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("invalid json schema: %s must be a string", at)
+				break
+			}
+			if node.pattern, err = regexp.Compile(pattern); err != nil {
+				err = fmt.Errorf("invalid json schema: %s is not a valid pattern: %v", at, err)
+			}
+		case "minimum":
+			node.minimum, err = compileNumber(value, at)
+		case "maximum":
+			node.maximum, err = compileNumber(value, at)
+		case "exclusiveMinimum":
+			node.exclusiveMinimum, err = compileNumber(value, at)
+		case "exclusiveMaximum":
+			node.exclusiveMaximum, err = compileNumber(value, at)
+		case "multipleOf":
+			if node.multipleOf, err = compileNumber(value, at); err == nil && node.multipleOf.Sign() <= 0 {
+				err = fmt.Errorf("invalid json schema: %s must be greater than 0", at)
+			}
+		case "allOf":
+			node.allOf, err = compileSchemas(value, at, depth)
+		case "anyOf":
+			node.anyOf, err = compileSchemas(value, at, depth)
+		case "oneOf":
+			node.oneOf, err = compileSchemas(value, at, depth)
+		case "not":
+			node.not, err = compileSchema(value, at, depth+1)
+		default:
+			if !slices.Contains(jsonSchemaAnnotations, keyword) {
+				err = fmt.Errorf("invalid json schema: unsupported keyword %s at %s", keyword, path)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// compileTypes returns the type or types a schema allows
+func compileTypes(v interface{}, path string) ([]string, error) {
+	var types []string
+	switch value := v.(type) {
+	case string:
+		types = []string{value}
+	case []interface{}:
+		var err error
+		if types, err = compileStrings(value, path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid json schema: %s must be a string or an array of strings", path)
+	}
+	for _, t := range types {
+		if !slices.Contains(jsonTypes, t) {
+			return nil, fmt.Errorf("invalid json schema: %s has unknown type %q", path, t)
+		}
+	}
+	return types, nil
+}
+
+// compileStrings returns an array of strings
+func compileStrings(v interface{}, path string) ([]string, error) {
+	values, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid json schema: %s must be an array of strings", path)
+	}
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid json schema: %s must be an array of strings", path)
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+// compileCount returns a non-negative integer bound
+func compileCount(v interface{}, path string) (*int, error) {
+	n, ok := jsonNumber(v)
+	if !ok || !n.IsInt() || n.Sign() < 0 || !n.Num().IsInt64() || n.Num().Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf("invalid json schema: %s must be a non-negative integer", path)
+	}
+	count := int(n.Num().Int64())
+	return &count, nil
+}
+
+// compileNumber returns a numeric bound
+func compileNumber(v interface{}, path string) (*big.Rat, error) {
+	n, ok := jsonNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("invalid json schema: %s must be a number", path)
+	}
+	return n, nil
+}
+
+// compileSchemas returns a non-empty array of subschemas
+func compileSchemas(v interface{}, path string, depth int) ([]*schemaNode, error) {
+	values, ok := v.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, fmt.Errorf("invalid json schema: %s must be a non-empty array of schemas", path)
+	}
+	nodes := make([]*schemaNode, 0, len(values))
+	for i, value := range values {
+		node, err := compileSchema(value, path+"/"+strconv.Itoa(i), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// validate appends to failures why value, at the payload path, does not
+// match the schema
+func (n *schemaNode) validate(value interface{}, path string, failures *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*failures = append(*failures, fieldName(path)+" "+fmt.Sprintf(format, args...))
+	}
+
+	if n.never {
+		fail("is not allowed")
+		return
+	}
+	if len(n.types) > 0 && !slices.ContainsFunc(n.types, func(t string) bool { return jsonHasType(value, t) }) {
+		fail("must be of type %s", strings.Join(n.types, " or "))
+		return
+	}
+	if n.enum != nil && !slices.ContainsFunc(n.enum, func(allowed interface{}) bool { return jsonEqual(value, allowed) }) {
+		fail("must be one of %s", jsonList(n.enum))
+	}
+	if n.hasConst && !jsonEqual(value, n.constant) {
+		fail("must be %s", jsonList([]interface{}{n.constant}))
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		n.validateObject(value, path, failures, fail)
+	case []interface{}:
+		n.validateArray(value, path, failures, fail)
+	case string:
+		length := utf8.RuneCountInString(value)
+		if n.minLength != nil && length < *n.minLength {
+			fail("must be at least %d characters", *n.minLength)
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			fail("must be at most %d characters", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(value) {
+			fail("must match pattern %s", n.pattern)
+		}
+	default:
+		if number, ok := jsonNumber(value); ok {
+			n.validateNumber(number, fail)
+		}
+	}
+
+	for _, sub := range n.allOf {
+		sub.validate(value, path, failures)
+	}
+	if n.anyOf != nil && !slices.ContainsFunc(n.anyOf, func(sub *schemaNode) bool { return sub.matches(value) }) {
+		fail("must match at least one schema of anyOf")
+	}
+	if n.oneOf != nil {
+		matched := 0
+		for _, sub := range n.oneOf {
+			if sub.matches(value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("must match exactly one schema of oneOf, matched %d", matched)
+		}
+	}
+	if n.not != nil && n.not.matches(value) {
+		fail("must not match the schema of not")
+	}
+}
+
+// validateObject checks an object's properties
+func (n *schemaNode) validateObject(object map[string]interface{}, path string, failures *[]string, fail func(string, ...interface{})) {
+	for _, name := range n.required {
+		if _, present := object[name]; !present {
+			*failures = append(*failures, fieldName(path+"/"+pointerEscaper.Replace(name))+" is required")
+		}
+	}
+	if n.minProperties != nil && len(object) < *n.minProperties {
+		fail("must have at least %d properties", *n.minProperties)
+	}
+	if n.maxProperties != nil && len(object) > *n.maxProperties {
+		fail("must have at most %d properties", *n.maxProperties)
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, declared := n.properties[name]
+		if !declared {
+			property = n.additional
+		}
+		if property != nil {
+			property.validate(object[name], path+"/"+pointerEscaper.Replace(name), failures)
+		}
+	}
+}
+
+// validateArray checks an array's items
+func (n *schemaNode) validateArray(items []interface{}, path string, failures *[]string, fail func(string, ...interface{})) {
+	if n.minItems != nil && len(items) < *n.minItems {
+		fail("must have at least %d items", *n.minItems)
+	}
+	if n.maxItems != nil && len(items) > *n.maxItems {
+		fail("must have at most %d items", *n.maxItems)
+	}
+	if n.uniqueItems {
+		for i := range items {
+			if slices.ContainsFunc(items[:i], func(other interface{}) bool { return jsonEqual(items[i], other) }) {
+				fail("must have unique items, item %d is repeated", i)
+				break
+			}
+		}
+	}
+	if n.items != nil {
+		for i, item := range items {
+			n.items.validate(item, path+"/"+strconv.Itoa(i), failures)
+		}
+	}
+}
+
+// validateNumber checks a number's bounds
+func (n *schemaNode) validateNumber(number *big.Rat, fail func(string, ...interface{})) {
+	if n.minimum != nil && number.Cmp(n.minimum) < 0 {
+		fail("must be at least %s", decimal(n.minimum))
+	}
+	if n.maximum != nil && number.Cmp(n.maximum) > 0 {
+		fail("must be at most %s", decimal(n.maximum))
+	}
+	if n.exclusiveMinimum != nil && number.Cmp(n.exclusiveMinimum) <= 0 {
+		fail("must be greater than %s", decimal(n.exclusiveMinimum))
+	}
+	if n.exclusiveMaximum != nil && number.Cmp(n.exclusiveMaximum) >= 0 {
+		fail("must be less than %s", decimal(n.exclusiveMaximum))
+	}
+	if n.multipleOf != nil && !new(big.Rat).Quo(number, n.multipleOf).IsInt() {
+		fail("must be a multiple of %s", decimal(n.multipleOf))
+	}
+}
+
+// decimal formats a bound taken from a JSON number, for errors
+func decimal(n *big.Rat) string {
+	if n.IsInt() {
+		return n.RatString()
+	}
+	return strings.TrimRight(n.FloatString(20), "0")
+}
+
+// matches reports whether value matches the schema
+func (n *schemaNode) matches(value interface{}) bool {
+	var failures []string
+	n.validate(value, "", &failures)
+	return len(failures) == 0
+}
+
+// jsonHasType reports whether a decoded JSON value has a JSON Schema type
+func jsonHasType(value interface{}, t string) bool {
+	switch value.(type) {
+	case nil:
+		return t == jsonTypeNull
+	case bool:
+		return t == jsonTypeBoolean
+	case string:
+		return t == jsonTypeString
+	case map[string]interface{}:
+		return t == jsonTypeObject
+	case []interface{}:
+		return t == jsonTypeArray
+	}
+	number, ok := jsonNumber(value)
+	if !ok {
+		return false
+	}
+	return t == jsonTypeNumber || (t == jsonTypeInteger && number.IsInt())
+}
+
+// pointerEscaper escapes a property name as a JSON Pointer segment
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// fieldName names the payload field at a JSON Pointer path, for errors
+func fieldName(path string) string {
+	if path == "" {
+		return "payload"
+	}
+	return "field " + path
+}
+
+// jsonList formats values as JSON, for errors
+func jsonList(values []interface{}) string {
+	data, _ := json.Marshal(values)
+	return strings.TrimSuffix(strings.TrimPrefix(string(data), "["), "]")
+}
+
+// applyJSONSchema validates a message published to a topic created with a
+// JSON Schema
+func (s *service) applyJSONSchema(topicName string, message *Message) error {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil // reported by publish
+	}
+
+	topic.mu.RLock()
+	schema := topic.Options.JSONSchema
+	topic.mu.RUnlock()
+
+	if schema == nil {
+		return nil
+	}
+	return schema.Validate(message.Payload)
+}
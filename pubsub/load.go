@@ -0,0 +1,62 @@
+package pubsub
+
+import (
+	"context"
+	"sort"
+)
+
+// TopicLoad is a topic's recent traffic and current footprint, for finding
+// the topics behind an incident
+type TopicLoad struct {
+	Topic       string `json:"topic"`
+	Subscribers int    `json:"subscribers"`
+	Published1m int    `json:"published_1m"` // publishes within ThroughputWindow
+	Dropped1m   int    `json:"dropped_1m"`   // messages dropped for full subscriber queues within ThroughputWindow
+	Backlog     int    `json:"backlog"`      // messages queued, or not yet fetched if durable, over all subscribers
+	MaxBacklog  int    `json:"max_backlog"`  // backlog of the furthest behind subscriber
+	Messages    int    `json:"messages"`     // messages held for replay
+	Bytes       int64  `json:"bytes"`        // payload bytes held for replay, as JSON
+
+	RingBufferSize    int `json:"ring_buffer_size"`    // messages held for replay at most
+	ChannelBufferSize int `json:"channel_buffer_size"` // messages queued per subscriber at most
+}
+
+// TopicLoads returns the load of every topic, sorted by name. Queued
+// messages share their payloads with the replay buffer, so Bytes
+// approximates a topic's memory footprint.
+func (s *service) TopicLoads(ctx context.Context) ([]TopicLoad, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.clock.Now()
+	loads := make([]TopicLoad, 0, len(s.topics))
+	for name, topic := range s.topics {
+		load := TopicLoad{
+			Topic:             name,
+			RingBufferSize:    s.ringBufferSize(topic.Options),
+			ChannelBufferSize: s.channelBufferSize(topic.Options),
+		}
+
+		topic.mu.RLock()
+		load.Subscribers = len(topic.Subscribers)
+		head := topic.Messages.LastSeq()
+		for clientID, subscriber := range topic.Subscribers {
+			backlog := subscriber.Depth()
+			if cursor, tracked := topic.Cursors[clientID]; tracked && subscriber.Durable && head > cursor.Delivered {
+				backlog = int(head - cursor.Delivered)
+			}
+			load.Backlog += backlog
+			load.MaxBacklog = max(load.MaxBacklog, backlog)
+		}
+		topic.mu.RUnlock()
+
+		load.Messages = topic.Messages.Count()
+		load.Bytes = storePayloadStats(topic.Messages).TotalBytes
+		load.Published1m = topic.publishes.recent(now)
+		load.Dropped1m = topic.drops.recent(now)
+		loads = append(loads, load)
+	}
+	sort.Slice(loads, func(i, j int) bool { return loads[i].Topic < loads[j].Topic })
+
+	return loads, nil
+}
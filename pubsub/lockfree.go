@@ -0,0 +1,236 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockFreeBuffer is an in-memory MessageStore with the behaviour of
+// RingBuffer whose reads take no lock, so replays, durable fetches and
+// stats never hold up publishes to the topic, nor publishes them.
+//
+// Each message lives in the slot its sequence number maps to. Appends
+// still serialize among themselves: they store the slot, then advance
+// lastSeq atomically, which publishes the message to readers. Readers load
+// lastSeq and walk the slots of the sequence numbers still in the window,
+// skipping slots emptied by Remove or already reused by a concurrent
+// Append, whose messages were about to be dropped anyway.
+//
+// Header indexes are kept under the appenders' lock, so SetIndexes and
+// Lookup wait for appends, as with RingBuffer.
+type LockFreeBuffer struct {
+	slots   []atomic.Pointer[Message] // the message of seq at seq % size
+	size    uint64
+	lastSeq atomic.Uint64 // sequence number of the newest message
+	stored  atomic.Int64  // non-empty slots
+
+	mu       sync.Mutex    // serializes Append, Remove, the header indexes and payload counts
+	indexes  headerIndexes // by header, for Lookup
+	payloads payloadCounts // over the stored messages, for stats
+	sizes    []int         // payload size of the message in each slot
+}
+
+// NewLockFreeBuffer creates a lock-free buffer holding size messages
+func NewLockFreeBuffer(size int) *LockFreeBuffer {
+	return &LockFreeBuffer{
+		slots: make([]atomic.Pointer[Message], size),
+		size:  uint64(size),
+		sizes: make([]int, size),
+	}
+}
+
+// Append adds a message, dropping the oldest when full, and stamps it with
+// the next sequence number. It never fails.
+func (b *LockFreeBuffer) Append(msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	seq := b.lastSeq.Load() + 1
+	msg.Seq = seq
+	if msg.Origin != "" && msg.OriginSeq == 0 {
+		msg.OriginSeq = msg.Seq
+	}
+
+	slot := &b.slots[seq%b.size]
+	if evicted := slot.Load(); evicted != nil {
+		b.indexes.remove(evicted)
+		b.payloads.remove(evicted, b.sizes[seq%b.size])
+	} else {
+		b.stored.Add(1)
+	}
+	b.indexes.add(msg)
+	b.sizes[seq%b.size] = b.payloads.add(msg)
+
+	slot.Store(msg)
+	b.lastSeq.Store(seq)
+	return nil
+}
+
+// load fills an empty buffer with messages restored from a snapshot,
+// keeping their sequence numbers. lastSeq is the newest sequence number
+// ever stamped. Header indexes are built by a later SetIndexes.
+func (b *LockFreeBuffer) load(messages []*Message, lastSeq uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastSeq.Store(lastSeq)
+	oldest, newest := b.window()
+	for _, msg := range messages {
+		if msg.Seq >= oldest && msg.Seq <= newest {
+			b.slots[msg.Seq%b.size].Store(msg)
+			b.sizes[msg.Seq%b.size] = b.payloads.add(msg)
+			b.stored.Add(1)
+		}
+	}
+}
+
+// window returns the sequence numbers of the oldest and newest messages
+// the buffer can hold right now; oldest > newest when it is empty
+func (b *LockFreeBuffer) window() (uint64, uint64) {
+	newest := b.lastSeq.Load()
+	if newest < b.size {
+		return 1, newest
+	}
+	return newest - b.size + 1, newest
+}
+
+// at returns the message with sequence number seq, or nil if it was
+// removed or its slot has been reused
+func (b *LockFreeBuffer) at(seq uint64) *Message {
+	msg := b.slots[seq%b.size].Load()
+	if msg == nil || msg.Seq != seq {
+		return nil
+	}
+	return msg
+}
+
+// collect returns the messages from seq first to last, oldest first
+func (b *LockFreeBuffer) collect(first, last uint64) []*Message {
+	if first > last {
+		return []*Message{}
+	}
+	messages := make([]*Message, 0, last-first+1)
+	for seq := first; seq <= last; seq++ {
+		if msg := b.at(seq); msg != nil {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// GetLastN returns the last n messages in chronological order
+func (b *LockFreeBuffer) GetLastN(n int) []*Message {
+	oldest, newest := b.window()
+	if n <= 0 || oldest > newest {
+		return []*Message{}
+	}
+	if uint64(n) <= newest-oldest {
+		oldest = newest - uint64(n) + 1
+	}
+	return b.collect(oldest, newest)
+}
+
+// GetSince returns up to max messages with a sequence number greater than
+// seq, in chronological order. Messages already dropped are skipped.
+func (b *LockFreeBuffer) GetSince(seq uint64, max int) []*Message {
+	oldest, newest := b.window()
+	if max <= 0 || seq >= newest {
+		return []*Message{}
+	}
+	first := oldest
+	if seq >= oldest {
+		first = seq + 1
+	}
+	if last := first + uint64(max) - 1; last < newest {
+		newest = last
+	}
+	return b.collect(first, newest)
+}
+
+// GetSinceTime returns the buffered messages stamped at or after since, in
+// chronological order
+func (b *LockFreeBuffer) GetSinceTime(since time.Time) []*Message {
+	messages := b.GetMessages()
+	kept := messages[:0]
+	for _, msg := range messages {
+		if !msg.Timestamp.Before(since) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
+
+// Remove drops the message with the given ID, returning it, or nil if it
+// is not (or no longer) buffered. The slot is left empty so sequence
+// positions are unchanged.
+func (b *LockFreeBuffer) Remove(id string) *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	oldest, newest := b.window()
+	for seq := oldest; seq <= newest; seq++ {
+		if msg := b.at(seq); msg != nil && msg.ID == id {
+			b.slots[seq%b.size].Store(nil)
+			b.stored.Add(-1)
+			b.indexes.remove(msg)
+			b.payloads.remove(msg, b.sizes[seq%b.size])
+			return msg
+		}
+	}
+	return nil
+}
+
+// payloadStats returns PayloadStats over the stored messages
+func (b *LockFreeBuffer) payloadStats() *PayloadStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.payloads.stats()
+}
+
+// LastSeq returns the sequence number of the newest message
+func (b *LockFreeBuffer) LastSeq() uint64 {
+	return b.lastSeq.Load()
+}
+
+// Count returns the number of messages in the buffer, not counting removed
+// ones
+func (b *LockFreeBuffer) Count() int {
+	return int(b.stored.Load())
+}
+
+// GetMessages returns all buffered messages in chronological order
+func (b *LockFreeBuffer) GetMessages() []*Message {
+	return b.collect(b.window())
+}
+
+// SetIndexes replaces the header keys the buffer indexes and rebuilds the
+// index over the messages already buffered. No keys removes the index.
+func (b *LockFreeBuffer) SetIndexes(keys []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.indexes = newHeaderIndexes(keys)
+	if b.indexes == nil {
+		return
+	}
+	for _, msg := range b.collect(b.window()) {
+		b.indexes.add(msg)
+	}
+}
+
+// Indexes returns the indexed header keys, sorted
+func (b *LockFreeBuffer) Indexes() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.indexes.keys()
+}
+
+// Lookup returns up to max of the newest buffered messages carrying every
+// one of the headers, in chronological order. It fails if any of the
+// header keys is not indexed.
+func (b *LockFreeBuffer) Lookup(headers map[string]string, max int) ([]*Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.indexes.lookup(headers, max)
+}
@@ -0,0 +1,208 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestLockFreeBufferMatchesRingBuffer applies the same appends and removals
+// to a LockFreeBuffer and a RingBuffer, then compares every read
+func TestLockFreeBufferMatchesRingBuffer(t *testing.T) {
+	type step struct {
+		append int      // messages to append, named m1, m2, ... in order
+		remove []string // IDs to remove afterwards
+	}
+	tests := []struct {
+		name  string
+		size  int
+		steps []step
+	}{
+		{name: "empty", size: 4},
+		{name: "partly filled", size: 4, steps: []step{{append: 3}}},
+		{name: "full", size: 4, steps: []step{{append: 4}}},
+		{name: "wrapped", size: 4, steps: []step{{append: 10}}},
+		{name: "wrapped many times", size: 3, steps: []step{{append: 3}, {append: 3}, {append: 7}}},
+		{name: "size one", size: 1, steps: []step{{append: 5}}},
+		{name: "removed", size: 5, steps: []step{{append: 5, remove: []string{"m2", "m4"}}}},
+		{name: "removed oldest and newest", size: 5, steps: []step{{append: 5, remove: []string{"m1", "m5"}}}},
+		{name: "removed then wrapped", size: 4, steps: []step{{append: 4, remove: []string{"m3"}}, {append: 2}}},
+		{name: "hole wrapped away", size: 4, steps: []step{{append: 4, remove: []string{"m1"}}, {append: 1}}},
+		{name: "removed evicted and unknown", size: 3, steps: []step{{append: 6, remove: []string{"m1", "m3", "missing", "m4"}}}},
+		{name: "removed twice", size: 3, steps: []step{{append: 3, remove: []string{"m2", "m2"}}}},
+		{name: "removed everything", size: 3, steps: []step{{append: 3, remove: []string{"m1", "m2", "m3"}}, {append: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, lockFree := NewRingBuffer(tt.size), NewLockFreeBuffer(tt.size)
+
+			appended := 0
+			for _, step := range tt.steps {
+				for range step.append {
+					appended++
+					id := fmt.Sprint("m", appended)
+					if err := ring.Append(&Message{ID: id}); err != nil {
+						t.Fatal(err)
+					}
+					if err := lockFree.Append(&Message{ID: id}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for _, id := range step.remove {
+					want, got := ring.Remove(id), lockFree.Remove(id)
+					if (want == nil) != (got == nil) || (want != nil && want.Seq != got.Seq) {
+						t.Errorf("Remove(%s) = %v, want %v", id, got, want)
+					}
+				}
+			}
+
+			if got, want := lockFree.LastSeq(), ring.LastSeq(); got != want {
+				t.Errorf("LastSeq = %d, want %d", got, want)
+			}
+			if got, want := lockFree.Count(), ring.Count(); got != want {
+				t.Errorf("Count = %d, want %d", got, want)
+			}
+			compareReads(t, "GetMessages", lockFree.GetMessages(), ring.GetMessages())
+			for n := -1; n <= tt.size+1; n++ {
+				compareReads(t, fmt.Sprintf("GetLastN(%d)", n), lockFree.GetLastN(n), ring.GetLastN(n))
+			}
+			for seq := uint64(0); seq <= ring.LastSeq()+1; seq++ {
+				for _, max := range []int{0, 1, 2, tt.size, tt.size + 1} {
+					compareReads(t, fmt.Sprintf("GetSince(%d, %d)", seq, max), lockFree.GetSince(seq, max), ring.GetSince(seq, max))
+				}
+			}
+		})
+	}
+}
+
+// compareReads checks that two reads returned the same messages in order
+func compareReads(t *testing.T, name string, got, want []*Message) {
+	t.Helper()
+
+	ids := func(messages []*Message) string {
+		out := make([]string, len(messages))
+		for i, msg := range messages {
+			out[i] = fmt.Sprintf("%s@%d", msg.ID, msg.Seq)
+		}
+		return fmt.Sprint(out)
+	}
+	if ids(got) != ids(want) {
+		t.Errorf("%s = %s, want %s", name, ids(got), ids(want))
+	}
+}
+
+// TestLockFreeBufferConcurrent reads while appending and removing, checking
+// every read is ordered and within the buffer; run it with -race
+func TestLockFreeBufferConcurrent(t *testing.T) {
+	const (
+		size    = 16
+		writers = 4
+		appends = 2000
+	)
+	buffer := NewLockFreeBuffer(size)
+
+	var wg sync.WaitGroup
+	var writing sync.WaitGroup
+	done := make(chan struct{})
+	for w := range writers {
+		wg.Add(1)
+		writing.Add(1)
+		go func() {
+			defer wg.Done()
+			defer writing.Done()
+			for i := range appends {
+				id := fmt.Sprint(w, "-", i)
+				buffer.Append(&Message{ID: id, Payload: id})
+				if i%7 == 0 {
+					buffer.Remove(id)
+				}
+			}
+		}()
+	}
+
+	check := func(name string, messages []*Message, limit int) {
+		if len(messages) > limit {
+			t.Errorf("%s returned %d messages, more than %d", name, len(messages), limit)
+		}
+		for i, msg := range messages {
+			if msg == nil {
+				t.Errorf("%s returned a nil message", name)
+				return
+			}
+			if msg.Payload != msg.ID {
+				t.Errorf("%s returned message %s with the payload of another", name, msg.ID)
+			}
+			if i > 0 && msg.Seq <= messages[i-1].Seq {
+				t.Errorf("%s returned seq %d after %d", name, msg.Seq, messages[i-1].Seq)
+				return
+			}
+		}
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				check("GetLastN", buffer.GetLastN(size/2), size/2)
+				since := buffer.LastSeq()
+				since -= min(since, size/2)
+				check("GetSince", buffer.GetSince(since, size), size)
+				check("GetMessages", buffer.GetMessages(), size)
+				buffer.Count()
+			}
+		}()
+	}
+
+	writing.Wait()
+	close(done)
+	wg.Wait()
+
+	if seq := buffer.LastSeq(); seq != writers*appends {
+		t.Errorf("LastSeq = %d, want %d", seq, writers*appends)
+	}
+	if count := buffer.Count(); count < 0 || count > size {
+		t.Errorf("Count = %d, outside [0, %d]", count, size)
+	}
+	check("GetMessages", buffer.GetMessages(), size)
+}
+
+// benchmarkStore runs four goroutines per CPU against a store, one in
+// workersPerWriter appending and the others reading the newest messages,
+// as publishers and replaying subscribers do
+func benchmarkStore(b *testing.B, store MessageStore, workersPerWriter int) {
+	for range 1000 {
+		store.Append(&Message{Payload: "seed"})
+	}
+
+	var workers atomic.Int64
+	b.SetParallelism(4)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		writer := workers.Add(1)%int64(workersPerWriter) == 0
+		for pb.Next() {
+			if writer {
+				store.Append(&Message{Payload: "payload"})
+			} else {
+				store.GetLastN(10)
+			}
+		}
+	})
+}
+
+func BenchmarkRingBuffer(b *testing.B) {
+	b.Run("append", func(b *testing.B) { benchmarkStore(b, NewRingBuffer(1000), 1) })
+	b.Run("mixed", func(b *testing.B) { benchmarkStore(b, NewRingBuffer(1000), 4) })
+}
+
+func BenchmarkLockFreeBuffer(b *testing.B) {
+	b.Run("append", func(b *testing.B) { benchmarkStore(b, NewLockFreeBuffer(1000), 1) })
+	b.Run("mixed", func(b *testing.B) { benchmarkStore(b, NewLockFreeBuffer(1000), 4) })
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import "time"
+
+// MessageStore holds a topic's message history, for replay, durable
+// subscriptions and header lookups. RingBuffer is the default, in-memory
+// store, and LockFreeBuffer one whose reads never block publishes; durable
+// backends implement MessageStore and are plugged in through
+// Config.MessageStore, without changes to publishing or replay.
+//
+// Implementations must be safe for concurrent use. Returned messages are
+// shared and must not be modified by the caller.
+type MessageStore interface {
+	// Append stores a message, stamping it with the topic's next sequence
+	// number (and its OriginSeq, for local messages without one). Stores
+	// may drop their oldest messages to make room.
+	Append(msg *Message) error
+	// GetLastN returns the newest n messages, oldest first
+	GetLastN(n int) []*Message
+	// GetSince returns up to max messages with a sequence number greater
+	// than seq, oldest first
+	GetSince(seq uint64, max int) []*Message
+	// GetSinceTime returns the messages stamped at or after since, oldest
+	// first
+	GetSinceTime(since time.Time) []*Message
+
+	// Remove drops a message by ID, returning it, or nil if it is not
+	// stored. Sequence numbers of the other messages are unchanged.
+	Remove(id string) *Message
+	// LastSeq returns the sequence number of the newest message ever
+	// appended, including ones since dropped
+	LastSeq() uint64
+	// Count returns the number of messages stored
+	Count() int
+	// GetMessages returns every stored message, oldest first
+	GetMessages() []*Message
+
+	// SetIndexes replaces the header keys Lookup can search by
+	SetIndexes(keys []string)
+	// Indexes returns the indexed header keys, sorted
+	Indexes() []string
+	// Lookup returns up to max of the newest messages carrying every one of
+	// the headers, oldest first. It fails if a header key is not indexed.
+	Lookup(headers map[string]string, max int) ([]*Message, error)
+}
+
+// MessageStoreFactory opens the store for a topic. size is the topic's
+// TopicOptions.RingBufferSize or Config.RingBufferSize, which stores may use
+// as their retention limit.
+type MessageStoreFactory func(topic string, size int) (MessageStore, error)
@@ -0,0 +1,87 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Metadata bounds
+const (
+	MaxDescriptionLength = 1024
+	MaxLabels            = 32
+	MaxLabelKeyLength    = 63
+	MaxLabelValueLength  = 255
+)
+
+// Metadata describes a topic to the people browsing a catalog of them, such
+// as its purpose and the team and environment it belongs to. Pubsub does
+// not interpret it.
+type Metadata struct {
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"` // e.g. "env": "prod"
+}
+
+// Validate checks the description and labels are within bounds. Label keys
+// cannot contain ':', which separates them from values in selectors.
+func (m *Metadata) Validate() error {
+	if utf8.RuneCountInString(m.Description) > MaxDescriptionLength {
+		return fmt.Errorf("invalid metadata: description must be at most %d characters", MaxDescriptionLength)
+	}
+	if len(m.Labels) > MaxLabels {
+		return fmt.Errorf("invalid metadata: at most %d labels are allowed", MaxLabels)
+	}
+	for key, value := range m.Labels {
+		if key == "" || len(key) > MaxLabelKeyLength || strings.ContainsAny(key, ": \t\n") {
+			return fmt.Errorf("invalid metadata: label key %q must be 1 to %d characters without ':' or spaces", key, MaxLabelKeyLength)
+		}
+		if len(value) > MaxLabelValueLength {
+			return fmt.Errorf("invalid metadata: label %s must be at most %d characters", key, MaxLabelValueLength)
+		}
+	}
+	return nil
+}
+
+// MatchLabels reports whether labels satisfy every selector: "key:value"
+// requires the label to have that value, "key" only requires the label
+func MatchLabels(labels map[string]string, selectors []string) bool {
+	for _, selector := range selectors {
+		key, value, hasValue := strings.Cut(selector, ":")
+		actual, exists := labels[key]
+		if !exists || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetMetadata replaces a topic's description and labels
+func (s *service) SetMetadata(ctx context.Context, topicName string, metadata Metadata) error {
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+	if len(metadata.Labels) == 0 {
+		metadata.Labels = nil
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.Description = metadata.Description
+	topic.Labels = maps.Clone(metadata.Labels)
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic metadata", "topic", topicName, "labels", len(metadata.Labels))
+	return nil
+}
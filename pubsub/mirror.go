@@ -0,0 +1,213 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Mirror queue bounds
+const (
+	DefaultMirrorBufferSize = 1000
+	MaxMirrorBufferSize     = 100000
+)
+
+// MirrorOptions configure a mirror
+type MirrorOptions struct {
+	Namespace  string     // topics mirrored: this one and those under it; "" mirrors every topic
+	Sampling   *Sampling  // nil mirrors every message
+	TagFilter  *TagFilter // nil mirrors messages regardless of tags
+	BufferSize int        // messages queued before they are dropped; 0 uses DefaultMirrorBufferSize
+}
+
+// Validate checks the namespace, filters and buffer size
+func (o *MirrorOptions) Validate() error {
+	if o.Namespace != "" {
+		if err := ValidateTopicName(o.Namespace); err != nil {
+			return fmt.Errorf("invalid mirror: namespace %s", strings.TrimPrefix(err.Error(), "invalid topic name: "))
+		}
+	}
+	if o.Sampling != nil {
+		if err := o.Sampling.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.TagFilter != nil {
+		if err := o.TagFilter.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.BufferSize < 0 || o.BufferSize > MaxMirrorBufferSize {
+		return fmt.Errorf("invalid mirror: buffer_size must be between 0 and %d", MaxMirrorBufferSize)
+	}
+	return nil
+}
+
+// Mirror is a read-only copy of every message published to the topics of
+// a namespace, for analytics consumers. It is fed apart from subscribers:
+// a message that finds the mirror's queue full is dropped for the mirror
+// alone, so a slow mirror never holds back publishers or subscribers.
+type Mirror struct {
+	ClientID  string
+	Namespace string
+	Messages  chan *Message // read-only messages of every mirrored topic
+
+	sampling  *Sampling
+	tagFilter *TagFilter
+	seen      atomic.Uint64 // messages offered, for sampling
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	taken     atomic.Uint64 // dropped reported by TakeDrops
+}
+
+// TryReceive returns the next mirrored message without blocking
+func (m *Mirror) TryReceive() (*Message, bool) {
+	select {
+	case msg := <-m.Messages:
+		return msg, true
+	default:
+		return nil, false
+	}
+}
+
+// TakeDrops returns how many messages were dropped for the mirror since
+// the last call, and in total
+func (m *Mirror) TakeDrops() (count, total uint64) {
+	total = m.dropped.Load()
+	return total - m.taken.Swap(total), total
+}
+
+// Delivered returns how many messages were queued for the mirror
+func (m *Mirror) Delivered() uint64 {
+	return m.delivered.Load()
+}
+
+// Dropped returns how many messages were dropped because the mirror's
+// queue was full
+func (m *Mirror) Dropped() uint64 {
+	return m.dropped.Load()
+}
+
+// covers reports whether the mirror copies messages of topic
+func (m *Mirror) covers(topic string) bool {
+	return InNamespace(topic, m.Namespace)
+}
+
+// offer queues a message for the mirror if its filters admit it, dropping
+// it if the queue is full
+func (m *Mirror) offer(msg *Message) {
+	if m.tagFilter != nil && !m.tagFilter.Matches(msg) {
+		return
+	}
+	// Tombstones bypass sampling, as for subscribers
+	if msg.Tombstone == "" && m.sampling != nil {
+		n := m.seen.Add(1)
+		if m.sampling.EveryN > 0 && (n-1)%uint64(m.sampling.EveryN) != 0 {
+			return
+		}
+		if m.sampling.Rate > 0 && rand.Float64() >= m.sampling.Rate {
+			return
+		}
+	}
+
+	select {
+	case m.Messages <- msg:
+		m.delivered.Add(1)
+	default:
+		m.dropped.Add(1)
+	}
+}
+
+// mirrors are the active mirrors. Publishing reads active without a lock;
+// changes replace the slice under mu.
+type mirrors struct {
+	mu     sync.Mutex
+	active atomic.Pointer[[]*Mirror]
+}
+
+// Mirror starts mirroring every message published to the topics of a
+// namespace, including topics created later, until Unmirror. Replicated
+// messages and those of other instances are mirrored too; replay is not
+// offered. Callers restricted by a grant cannot mirror.
+func (s *service) Mirror(ctx context.Context, clientID string, opts *MirrorOptions) (*Mirror, error) {
+	if GrantFromContext(ctx) != nil {
+		return nil, fmt.Errorf("forbidden: mirrors are not permitted to restricted credentials")
+	}
+	if opts == nil {
+		opts = &MirrorOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	bufferSize := opts.BufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultMirrorBufferSize
+	}
+	mirror := &Mirror{
+		ClientID:  clientID,
+		Namespace: opts.Namespace,
+		Messages:  make(chan *Message, bufferSize),
+		sampling:  opts.Sampling,
+	}
+	if opts.TagFilter != nil {
+		mirror.tagFilter = opts.TagFilter.indexed()
+	}
+
+	s.mirrors.mu.Lock()
+	defer s.mirrors.mu.Unlock()
+
+	var active []*Mirror
+	if current := s.mirrors.active.Load(); current != nil {
+		active = *current
+	}
+	if slices.ContainsFunc(active, func(m *Mirror) bool { return m.ClientID == clientID && m.Namespace == opts.Namespace }) {
+		return nil, fmt.Errorf("client %s already mirrors namespace %q", clientID, opts.Namespace)
+	}
+	active = append(slices.Clone(active), mirror)
+	s.mirrors.active.Store(&active)
+
+	logging.WithContext(ctx).Infow("Started mirror", "client_id", clientID, "namespace", opts.Namespace, "buffer_size", bufferSize)
+	return mirror, nil
+}
+
+// Unmirror stops a client's mirror of a namespace
+func (s *service) Unmirror(ctx context.Context, clientID, namespace string) error {
+	s.mirrors.mu.Lock()
+	defer s.mirrors.mu.Unlock()
+
+	var active []*Mirror
+	if current := s.mirrors.active.Load(); current != nil {
+		active = *current
+	}
+	i := slices.IndexFunc(active, func(m *Mirror) bool { return m.ClientID == clientID && m.Namespace == namespace })
+	if i < 0 {
+		return fmt.Errorf("client %s does not mirror namespace %q", clientID, namespace)
+	}
+	mirror := active[i]
+	active = slices.Delete(slices.Clone(active), i, i+1)
+	s.mirrors.active.Store(&active)
+
+	logging.WithContext(ctx).Infow("Stopped mirror", "client_id", clientID, "namespace", namespace,
+		"delivered", mirror.Delivered(), "dropped", mirror.Dropped())
+	return nil
+}
+
+// mirror offers a message of topic to the mirrors covering it
+func (s *service) mirror(topic *Topic, message *Message) {
+	active := s.mirrors.active.Load()
+	if active == nil {
+		return
+	}
+	for _, mirror := range *active {
+		if mirror.covers(topic.Name) {
+			mirror.offer(message)
+		}
+	}
+}
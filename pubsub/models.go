@@ -1,21 +1,123 @@
 package pubsub
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
+
+	"github.com/robfig/cron/v3"
 )
 
 // Configuration constants
 const (
 	DefaultRingBufferSize    = 100
 	DefaultChannelBufferSize = 100
+	DefaultMaxReplayRate     = 1000 // historical messages per second per subscriber
 	GracefulShutdownTimeout  = 30 * time.Second
 )
 
+// Upper bounds of TopicOptions
+const (
+	MaxTopicRingBufferSize    = 100000
+	MaxTopicChannelBufferSize = 10000
+)
+
+// Storage backends for Config.Backend
+const (
+	// BackendMemory keeps topics in this process, persisted to DataDir if set
+	BackendMemory = "memory"
+	// BackendRedis shares topics and their messages between every instance
+	// using the same Redis, which fan messages out to their own subscribers
+	BackendRedis = "redis"
+)
+
+// Reasons a subscription is closed by the service rather than the client
+const (
+	CloseReasonUnsubscribed = "unsubscribed"  // the client unsubscribed
+	CloseReasonTopicDeleted = "topic_deleted" // the topic was deleted
+	CloseReasonTopicExpired = "topic_expired" // the topic reached its expires_at and was deleted
+	CloseReasonAdminKick    = "admin_kick"    // an admin removed the subscriber
+	CloseReasonShutdown     = "shutdown"      // the service is stopping
+	CloseReasonBackpressure = "backpressure"  // the queue filled up under BackpressureDisconnect
+
+	// CloseReasonRepartitioned ends subscriptions to one partition when the
+	// topic's partition count changes, as the partition holds other keys
+	CloseReasonRepartitioned = "repartitioned"
+
+	// CloseReasonSubscriptionExpired ends a subscription that reached the
+	// end of its SubscribeOptions.TTL
+	CloseReasonSubscriptionExpired = "subscription_expired"
+)
+
 // Config holds configurable parameters
 type Config struct {
 	RingBufferSize    int
 	ChannelBufferSize int
+	MaxReplayRate     float64 // default replay pace for topics without their own; 0 means unpaced
+	Region            string  // stamped as Message.Origin on local publishes; empty disables stamping
+
+	// MessageStore opens the store holding each topic's messages; nil keeps
+	// them in a RingBuffer of RingBufferSize messages, or a FileStore with
+	// DataDir
+	MessageStore MessageStoreFactory
+
+	// DataDir persists topics and their messages under this directory, to
+	// be restored by Start; empty keeps everything in memory
+	DataDir string
+
+	// Backend selects where topics live, BackendMemory when empty.
+	// RedisURL locates the server for BackendRedis, as
+	// redis://[[user]:password@]host[:port][/db].
+	Backend  string
+	RedisURL string
+
+	// MaxGoroutines bounds the goroutines the engine starts for subscriber
+	// writers, replay, dead-lettering and janitor sweeps, and
+	// TaskQueueSize the tasks waiting for one; 0 uses DefaultMaxGoroutines
+	// and DefaultTaskQueueSize
+	MaxGoroutines int
+	TaskQueueSize int
+
+	// LockFreeBuffer keeps in-memory topics in a LockFreeBuffer instead of
+	// a RingBuffer, so reading a topic's history never blocks publishing
+	// to it
+	LockFreeBuffer bool
+
+	// SnapshotFile receives every topic with its buffered messages, cursors
+	// and read markers when the service stops, and every SnapshotInterval
+	// when set, to be restored by Start. It is for in-memory topics and
+	// cannot be combined with DataDir or BackendRedis.
+	SnapshotFile     string
+	SnapshotInterval time.Duration
+
+	// WALDir keeps a write-ahead log of every publish and topic change
+	// since the last snapshot, replayed by Start on top of it, so a crash
+	// between snapshots loses nothing. It requires SnapshotFile. Segments
+	// are rotated at WALSegmentSize bytes, DefaultWALSegmentSize when 0;
+	// WALSync syncs each record to disk before the publish returns, so
+	// records survive a host crash as well as a process crash.
+	WALDir         string
+	WALSegmentSize int64
+	WALSync        bool
+
+	// MaxTopics bounds the topics the service holds, and MaxTopicsPerOwner
+	// those owned by any one user, so creating topics without end cannot
+	// exhaust memory; 0 leaves them unbounded. Topics restored at startup
+	// or mirrored by BackendRedis are not refused.
+	MaxTopics         int
+	MaxTopicsPerOwner int
+
+	// Clock tells the time for message timestamps, TTLs, expiry and
+	// retention, and paces the janitor sweeps; nil uses SystemClock
+	Clock Clock
 }
 
 // DefaultConfig returns default configuration
@@ -23,16 +125,175 @@ func DefaultConfig() *Config {
 	return &Config{
 		RingBufferSize:    DefaultRingBufferSize,
 		ChannelBufferSize: DefaultChannelBufferSize,
+		MaxReplayRate:     DefaultMaxReplayRate,
 	}
 }
 
 // Topic represents a pub/sub topic
 type Topic struct {
 	Name        string                 `json:"name"`
-	Subscribers map[string]*Subscriber `json:"-"` // client_id -> subscriber
-	Messages    *RingBuffer            `json:"-"` // Ring buffer for message replay
+	Subscribers map[string]*Subscriber `json:"-"`                     // client_id -> subscriber
+	Messages    MessageStore           `json:"-"`                     // Message history for replay
+	Routes      []*Route               `json:"-"`                     // Content-based routes to other topics
+	Cursors     map[string]*Cursor     `json:"-"`                     // client_id -> durable subscriber cursor
+	ReadMarkers map[string]uint64      `json:"-"`                     // client_id -> seq read up to
+	Schedules   []*Schedule            `json:"-"`                     // Cron-driven publishers
+	Owner       string                 `json:"owner,omitempty"`       // user ID of the creator
+	Description string                 `json:"description,omitempty"` // see Metadata
+	Labels      map[string]string      `json:"labels,omitempty"`      // see Metadata
+	ReplayRate  float64                `json:"replay_rate,omitempty"` // max replay messages per second; 0 uses Config.MaxReplayRate
+	Decoding    Decoding               `json:"decoding"`              // how publish frames are decoded
 	CreatedAt   time.Time              `json:"created_at"`
-	mu          sync.RWMutex           `json:"-"`
+	ExpiresAt   time.Time              `json:"expires_at"`         // deleted automatically at this time; zero never expires
+	Ordering    string                 `json:"ordering,omitempty"` // OrderingDefault or OrderingStrict
+	Delivery    string                 `json:"delivery,omitempty"` // DeliveryAll or DeliveryOncePerUser
+	Options     TopicOptions           `json:"options"`            // fixed at creation
+	Retention   Retention              `json:"retention"`          // evicts old messages before the buffer wraps
+	sequencer   *sequencer             // single writer in strict ordering
+	publishes   throughput
+	dropped     atomic.Uint64     // messages dropped for full subscriber queues
+	evicted     atomic.Uint64     // messages evicted by Retention
+	unsaved     atomic.Bool       // cursors, read markers or compacted state changed since saveSessions
+	origins     map[string]uint64 // origin region -> highest OriginSeq replicated in
+	mu          sync.RWMutex      `json:"-"`
+
+	// Backpressure applies to subscriptions without a policy of their own
+	Backpressure Backpressure `json:"backpressure"`
+
+	drops throughput // dropped, as counted in dropped, within ThroughputWindow
+
+	// Schemas are the versioned shapes of payloads published to the topic
+	Schemas Schemas `json:"schemas"`
+
+	// Dedup skips publishes of message IDs seen recently
+	Dedup      Dedup         `json:"dedup"`
+	seen       dedupWindow   // message IDs within Dedup
+	duplicates atomic.Uint64 // publishes skipped by Dedup
+
+	// LegalHold, when placed, preserves the topic and its messages
+	LegalHold *LegalHold `json:"legal_hold,omitempty"`
+
+	// Partitions splits publishes by key across this many sequencers; 0
+	// leaves the topic unpartitioned
+	Partitions int          `json:"partitions,omitempty"`
+	partitions []*partition // one per partition, while Partitions > 0
+
+	// TextIndex is the number of newest messages whose payload words are
+	// indexed for SearchMessages; 0 indexes none
+	TextIndex int        `json:"text_index,omitempty"`
+	text      *textIndex // while TextIndex > 0
+
+	// Compaction keeps the latest message of each key beyond the buffer
+	Compaction Compaction      `json:"compaction"`
+	compacted  *compactedState // while Compaction is enabled
+
+	// Ingress queues publishes to smooth bursts
+	Ingress Ingress       `json:"ingress"`
+	ingress *ingressQueue // while Ingress is enabled
+	busy    atomic.Uint64 // publishes refused for a full ingress queue
+}
+
+// TopicOptions override Config buffer sizes for one topic and hold the JSON
+// Schema its payloads must match. They are fixed when the topic is created.
+type TopicOptions struct {
+	RingBufferSize    int         `json:"ring_buffer_size,omitempty"`    // messages held for replay; 0 uses Config.RingBufferSize
+	ChannelBufferSize int         `json:"channel_buffer_size,omitempty"` // messages queued per subscriber; 0 uses Config.ChannelBufferSize
+	JSONSchema        *JSONSchema `json:"json_schema,omitempty"`         // payloads must match it; nil accepts any
+}
+
+// Validate checks the sizes are within bounds
+func (o *TopicOptions) Validate() error {
+	if o.RingBufferSize < 0 || o.RingBufferSize > MaxTopicRingBufferSize {
+		return fmt.Errorf("invalid topic options: ring_buffer_size must be between 0 and %d", MaxTopicRingBufferSize)
+	}
+	if o.ChannelBufferSize < 0 || o.ChannelBufferSize > MaxTopicChannelBufferSize {
+		return fmt.Errorf("invalid topic options: channel_buffer_size must be between 0 and %d", MaxTopicChannelBufferSize)
+	}
+	return nil
+}
+
+// Retention evicts a topic's messages by age or count before its buffer
+// wraps. A zero limit disables it.
+type Retention struct {
+	MaxAgeSec int64 `json:"max_age_sec,omitempty"` // messages older than this many seconds are evicted
+	MaxCount  int   `json:"max_count,omitempty"`   // only the newest this many messages are kept
+}
+
+// Validate checks the limits are not negative
+func (r *Retention) Validate() error {
+	if r.MaxAgeSec < 0 {
+		return fmt.Errorf("invalid retention: max_age_sec must not be negative")
+	}
+	if r.MaxCount < 0 {
+		return fmt.Errorf("invalid retention: max_count must not be negative")
+	}
+	return nil
+}
+
+// Enabled reports whether any limit is set
+func (r *Retention) Enabled() bool {
+	return r.MaxAgeSec > 0 || r.MaxCount > 0
+}
+
+// Route republishes messages matching a predicate to another topic
+type Route struct {
+	ID        string     `json:"id"`
+	Target    string     `json:"target"`
+	Predicate *Predicate `json:"predicate,omitempty"` // nil matches every message
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Schedule publishes a message rendered from PayloadTemplate to a topic
+// whenever its cron expression fires
+type Schedule struct {
+	ID              string    `json:"id"`
+	Cron            string    `json:"cron"`             // standard 5-field expression or descriptor like "@every 30s"
+	PayloadTemplate string    `json:"payload_template"` // text/template producing a JSON payload
+	CreatedAt       time.Time `json:"created_at"`
+	LastRun         time.Time `json:"last_run,omitempty"`
+	Runs            uint64    `json:"runs"`
+
+	entryID  cron.EntryID
+	template *template.Template
+}
+
+// ScheduleData is the data available to a schedule's payload template
+type ScheduleData struct {
+	Topic      string
+	ScheduleID string
+	Run        uint64 // 1 for the first run
+	Now        time.Time
+}
+
+// Predicate matches a message when the payload field at Field equals Equals.
+// Field is a dot-separated path into an object payload, e.g. "order.region".
+type Predicate struct {
+	Field  string      `json:"field"`
+	Equals interface{} `json:"equals"`
+}
+
+// Validate checks that the predicate is usable
+func (p *Predicate) Validate() error {
+	if p.Field == "" {
+		return fmt.Errorf("invalid route: predicate field is required")
+	}
+	return nil
+}
+
+// Matches reports whether the message satisfies the predicate
+func (p *Predicate) Matches(msg *Message) bool {
+	value := msg.Payload
+	for _, key := range strings.Split(p.Field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if value, ok = object[key]; !ok {
+			return false
+		}
+	}
+
+	return jsonEqual(value, p.Equals)
 }
 
 // Subscriber represents a WebSocket connection subscribed to a topic
@@ -41,20 +302,373 @@ type Subscriber struct {
 	TopicName   string        `json:"topic_name"`
 	MessageChan chan *Message `json:"-"` // Channel for sending messages
 	LastSeen    time.Time     `json:"last_seen"`
+	Sampling    *Sampling     `json:"sampling,omitempty"`
+	TagFilter   *TagFilter    `json:"tag_filter,omitempty"`
+	Durable     bool          `json:"durable"` // pulls via Fetch/Ack, MessageChan is nil
+	// DeadLetter is the topic receiving messages dropped for this subscriber
+	DeadLetter  string        `json:"dead_letter,omitempty"`
+	seen        atomic.Uint64 // live messages offered to this subscriber
+	closeReason atomic.Value  // string, set before MessageChan is closed
+
+	delivered    atomic.Uint64 // messages enqueued or fetched, replay included
+	dropped      atomic.Uint64 // live messages dropped because the queue was full
+	lastDelivery atomic.Int64  // unix nanoseconds of the newest delivery, 0 before the first
+	clock        Clock         // the service's, timing deliveries and activity
+
+	// MaxDeliveries enables nack: a nacked message is redelivered until it
+	// has been delivered this many times. 0 disables nack.
+	MaxDeliveries int `json:"max_deliveries,omitempty"`
+	inFlight      inFlight
+
+	// Backpressure overrides the topic's policy when set
+	Backpressure Backpressure  `json:"backpressure"`
+	dropPolicy   atomic.Value  // BackpressurePolicy of the newest drop
+	dropsTaken   atomic.Uint64 // drops already returned by TakeDrops
+
+	// Preferences picks the variants delivered by their headers
+	Preferences HeaderPreferences `json:"preferences,omitempty"`
+
+	guard deliveryGuard // makes sends safe against close
+
+	// PriorityChan queues live PriorityHigh messages apart from
+	// MessageChan; TryReceive drains it first. It is closed with
+	// MessageChan.
+	PriorityChan chan *Message `json:"-"`
+
+	writer     *orderedWriter // feeds the queues from fan-out
+	writerOnce sync.Once
+
+	// Partition, when set, is the only partition of the topic delivered
+	Partition  *int `json:"partition,omitempty"`
+	partitions int  // the topic's partition count when subscribing
+
+	// UserID groups the subscriber with its user's other connections for
+	// DeliveryOncePerUser topics
+	UserID string       `json:"user_id,omitempty"`
+	active atomic.Int64 // unix nanoseconds of the newest Touch
+
+	// ExpiresAt is when the subscription ends with
+	// CloseReasonSubscriptionExpired; nil lasts until unsubscribed
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// SubscribeOptions holds per-subscription settings
+type SubscribeOptions struct {
+	LastN     int        // number of historical messages to replay
+	Sampling  *Sampling  // nil delivers every message
+	TagFilter *TagFilter // nil delivers messages regardless of tags
+	Durable   bool       // track a cursor in the topic buffer instead of pushing to a channel
+	// DeadLetter names a topic that receives, wrapped in a DeadLetter, every
+	// message dropped because this subscriber's queue was full
+	DeadLetter string
+	// MaxDeliveries lets the subscriber nack delivered messages, which are
+	// redelivered until delivered this many times and then dead-lettered
+	MaxDeliveries int
+	// Backpressure overrides the topic's policy for a full queue
+	Backpressure Backpressure
+	// Preferences picks the variants delivered by their headers; nil
+	// delivers every variant
+	Preferences HeaderPreferences
+
+	// Since replays the messages stamped at or after it; with LastN too,
+	// only the newest LastN of them
+	Since time.Time
+
+	// AfterSeq replays every buffered message with a greater sequence
+	// number, so a reconnecting client resumes after the last message it
+	// received. It cannot be combined with LastN or Since.
+	AfterSeq *uint64
+
+	// Partition subscribes to one partition of a partitioned topic; LastN
+	// replays from the partition's buffer
+	Partition *int
+
+	// UserID is the user the subscribing connection belongs to; topics
+	// with DeliveryOncePerUser offer each message to one connection per
+	// user. Empty subscribers are never grouped.
+	UserID string
+
+	// TTL ends the subscription this long after it is made, closing it
+	// with CloseReasonSubscriptionExpired; 0 lasts until unsubscribed
+	TTL time.Duration
+}
+
+// expiresAt returns when a subscription made now with the options ends, or
+// nil if it does not
+func (o *SubscribeOptions) expiresAt(now time.Time) *time.Time {
+	if o.TTL <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(o.TTL)
+	return &expiresAt
+}
+
+// Reasons a message is dead-lettered
+const (
+	DeadLetterReasonBacklog       = "backlog"        // the subscriber's queue was full
+	DeadLetterReasonNacked        = "nacked"         // the subscriber nacked it without requeue
+	DeadLetterReasonMaxDeliveries = "max_deliveries" // nacked after its last allowed delivery
+)
+
+// DeadLetter is the payload of a message redirected to a subscription's
+// dead-letter topic
+type DeadLetter struct {
+	Topic    string   `json:"topic"`     // topic the message was published to
+	ClientID string   `json:"client_id"` // subscriber that did not receive it
+	Reason   string   `json:"reason"`
+	Message  *Message `json:"message"`
+}
+
+// Cursor tracks a durable subscriber's position in a topic's buffer.
+// Messages up to Acked are done; messages after Delivered have not been
+// fetched yet. Resubscribing rewinds Delivered to Acked so unacknowledged
+// messages are delivered again (at-least-once).
+type Cursor struct {
+	Acked     uint64 `json:"acked"`
+	Delivered uint64 `json:"delivered"`
+}
+
+// CursorInfo represents a durable subscriber's position and lag
+type CursorInfo struct {
+	ClientID  string `json:"client_id"`
+	Acked     uint64 `json:"acked"`
+	Delivered uint64 `json:"delivered"`
+	Head      uint64 `json:"head"`
+	Lag       uint64 `json:"lag"` // messages published but not yet acked
+}
+
+// ReadMarker records how far a client has read a topic, independently of
+// delivery, so applications can show unread counts
+type ReadMarker struct {
+	ClientID string `json:"client_id"`
+	Read     uint64 `json:"read"` // seq the client has read up to
+	Head     uint64 `json:"head"`
+	Unread   uint64 `json:"unread"` // messages published after Read
+}
+
+// Sampling restricts a subscription to a representative subset of a topic.
+// Exactly one of EveryN or Rate may be set.
+type Sampling struct {
+	EveryN int     `json:"every_n,omitempty"` // deliver every Nth message
+	Rate   float64 `json:"rate,omitempty"`    // deliver each message with this probability
+}
+
+// Validate checks that the sampling settings are usable
+func (sm *Sampling) Validate() error {
+	if sm.EveryN < 0 {
+		return fmt.Errorf("invalid sampling: every_n must be positive")
+	}
+	if sm.Rate < 0 || sm.Rate > 1 {
+		return fmt.Errorf("invalid sampling: rate must be between 0 and 1")
+	}
+	if sm.EveryN > 0 && sm.Rate > 0 {
+		return fmt.Errorf("invalid sampling: set either every_n or rate, not both")
+	}
+	if sm.EveryN == 0 && sm.Rate == 0 {
+		return fmt.Errorf("invalid sampling: every_n or rate is required")
+	}
+	return nil
+}
+
+// CloseReason returns why the subscription was closed, or "" while it is
+// open. For live subscribers it is set before MessageChan is closed, so it
+// is always available once a receive reports the channel closed.
+func (sub *Subscriber) CloseReason() string {
+	reason, _ := sub.closeReason.Load().(string)
+	return reason
+}
+
+// close records the reason and closes the message channels once no send is
+// in progress. Callers hold the topic write lock and remove the subscriber
+// from the topic.
+func (sub *Subscriber) close(reason string) {
+	sub.closeReason.Store(reason)
+	sub.guard.close(func() {
+		if sub.PriorityChan != nil {
+			close(sub.PriorityChan)
+		}
+		if sub.MessageChan != nil {
+			close(sub.MessageChan)
+		}
+	})
+}
+
+// TakeDrops returns how many live messages were dropped for the subscriber
+// since the previous call, the total dropped, and the policy that dropped
+// the newest of them. It is meant for the one transport delivering the
+// subscription, to tell its client.
+func (sub *Subscriber) TakeDrops() (count, total uint64, policy BackpressurePolicy) {
+	total = sub.dropped.Load()
+	policy, _ = sub.dropPolicy.Load().(BackpressurePolicy)
+	return total - sub.dropsTaken.Swap(total), total, policy
 }
 
-// Message represents a published message
+// Delivered returns how many messages were enqueued for the subscriber, or
+// fetched if durable, replay included
+func (sub *Subscriber) Delivered() uint64 {
+	return sub.delivered.Load()
+}
+
+// Dropped returns how many live messages were dropped because the
+// subscriber's queue was full
+func (sub *Subscriber) Dropped() uint64 {
+	return sub.dropped.Load()
+}
+
+// Depth returns how many messages are queued for the subscriber. Durable
+// subscribers have no queue.
+func (sub *Subscriber) Depth() int {
+	return len(sub.MessageChan) + len(sub.PriorityChan)
+}
+
+// recordDelivery counts n messages handed to the subscriber
+func (sub *Subscriber) recordDelivery(n int) {
+	sub.delivered.Add(uint64(n))
+	sub.lastDelivery.Store(sub.clock.Now().UnixNano())
+}
+
+// accepts reports whether the next live message should be delivered,
+// applying the subscriber's sampling settings
+func (sub *Subscriber) accepts() bool {
+	if sub.Sampling == nil {
+		return true
+	}
+
+	n := sub.seen.Add(1)
+	if sub.Sampling.EveryN > 0 {
+		return (n-1)%uint64(sub.Sampling.EveryN) == 0
+	}
+	return rand.Float64() < sub.Sampling.Rate
+}
+
+// Message represents a published message.
+//
+// Ownership: Publish stores and delivers its own deep copy of the message, so
+// a publisher may reuse or mutate its value afterwards. Messages handed out by
+// the service (subscriber channels, ring buffer reads) are shared between all
+// readers and must be treated as read-only.
 type Message struct {
 	ID        string      `json:"id"`
+	Seq       uint64      `json:"seq"` // per-topic sequence number, assigned on publish
 	Payload   interface{} `json:"payload"`
 	Topic     string      `json:"topic"`
 	Timestamp time.Time   `json:"timestamp"`
+	Tombstone string      `json:"tombstone,omitempty"`  // set on tombstones: ID of the deleted message
+	Tags      []string    `json:"tags,omitempty"`       // matched by subscription tag filters
+	Origin    string      `json:"origin,omitempty"`     // region the message was published in, when regions are configured
+	OriginSeq uint64      `json:"origin_seq,omitempty"` // Seq in the origin region; with Origin, identifies the message across regions
+
+	// Headers are free-form key/value metadata; keys a topic indexes can be
+	// looked up with FindMessages
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// SchemaVersion is the topic schema version the payload was validated
+	// against, on topics with schemas
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	// Priority is PriorityHigh for messages subscribers should receive
+	// ahead of the normal ones already queued for them
+	Priority MessagePriority `json:"priority,omitempty"`
+
+	// Key picks the partition of a partitioned topic; messages with the
+	// same key are delivered in publish order
+	Key string `json:"key,omitempty"`
+
+	deadLetter bool // wraps a dropped message, so is never dead-lettered itself
+}
+
+// Clone returns a deep copy of the message
+func (m Message) Clone() *Message {
+	m.Payload = clonePayload(m.Payload)
+	m.Tags = slices.Clone(m.Tags)
+	m.Headers = maps.Clone(m.Headers)
+	return &m
+}
+
+// clonePayload deep-copies a payload. JSON-decoded values are copied
+// directly; any other reference type is copied through a JSON round trip.
+func clonePayload(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil, string, bool, float64, json.Number:
+		return value
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			copied[k] = clonePayload(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = clonePayload(item)
+		}
+		return copied
+	case json.RawMessage:
+		return append(json.RawMessage(nil), value...)
+	case []byte:
+		return append([]byte(nil), value...)
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Interface, reflect.Struct, reflect.Array:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var copied interface{}
+		if err := (Decoding{}).Unmarshal(data, &copied); err != nil {
+			return v
+		}
+		return copied
+	default:
+		return v
+	}
 }
 
 // TopicInfo represents topic information for external APIs
 type TopicInfo struct {
-	Name        string `json:"name"`
-	Subscribers int    `json:"subscribers"`
+	Name        string    `json:"name"`
+	Subscribers int       `json:"subscribers"`
+	Owner       string    `json:"owner,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Messages    int       `json:"messages"` // messages held for replay
+	Routes      int       `json:"routes"`
+	Schedules   int       `json:"schedules"`
+	ReplayRate  float64   `json:"replay_rate"`  // effective max replay messages per second
+	Decoding    Decoding  `json:"decoding"`     // number mode and strictness of publish frames
+	Published1m int       `json:"published_1m"` // publishes within ThroughputWindow
+
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+
+	HeaderIndexes []string   `json:"header_indexes,omitempty"` // header keys indexed for FindMessages
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`     // when the topic is deleted automatically
+	Ordering      string     `json:"ordering,omitempty"`       // "strict" when publishes are sequenced by one writer
+	Delivery      string     `json:"delivery,omitempty"`       // "once_per_user" when each user gets one copy
+	Partitions    int        `json:"partitions,omitempty"`     // sequencers publishes are split across by key
+	Retention     *Retention `json:"retention,omitempty"`      // message eviction by age and count
+	Evicted       uint64     `json:"evicted,omitempty"`        // messages evicted by retention
+
+	Backpressure *Backpressure `json:"backpressure,omitempty"` // policy of subscriptions without their own
+
+	Dedup      *Dedup `json:"dedup,omitempty"`      // window of message IDs published at most once
+	Duplicates uint64 `json:"duplicates,omitempty"` // publishes skipped as duplicates
+
+	LegalHold *LegalHold `json:"legal_hold,omitempty"` // set while the topic is preserved for an investigation
+
+	TextIndex int `json:"text_index,omitempty"` // newest messages indexed for SearchMessages
+
+	Compaction    *Compaction `json:"compaction,omitempty"`     // latest message kept per key
+	CompactedKeys int         `json:"compacted_keys,omitempty"` // keys whose latest message is kept
+
+	Ingress       *Ingress `json:"ingress,omitempty"`        // queue smoothing publish bursts
+	IngressQueued int      `json:"ingress_queued,omitempty"` // publishes waiting in it
+	Busy          uint64   `json:"busy,omitempty"`           // publishes refused while it was full
+
+	RingBufferSize    int `json:"ring_buffer_size"`    // messages held for replay
+	ChannelBufferSize int `json:"channel_buffer_size"` // messages queued per subscriber
+
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"` // payloads must match it
 }
 
 // HealthResponse represents health information
@@ -64,25 +678,88 @@ type HealthResponse struct {
 	Subscribers int   `json:"subscribers"`
 }
 
+// Lifecycle phases reported by GetReadiness
+const (
+	PhaseStarting   = "starting"
+	PhaseRecovering = "recovering"
+	PhaseReady      = "ready"
+	PhaseStopping   = "stopping"
+)
+
+// RecoveryProgress reports how much persisted state has been restored
+// into memory while the service is recovering
+type RecoveryProgress struct {
+	TopicsTotal       int   `json:"topics_total"`
+	TopicsRecovered   int   `json:"topics_recovered"`
+	MessagesRecovered int64 `json:"messages_recovered"`
+}
+
+// ReadinessResponse represents whether the service can serve traffic. A
+// node is only ready once recovery has finished, so replays are never
+// served from a partially restored catalog.
+type ReadinessResponse struct {
+	Ready    bool              `json:"ready"`
+	Phase    string            `json:"phase"`
+	Recovery *RecoveryProgress `json:"recovery,omitempty"` // nil when persistence is disabled
+}
+
 // TopicStats represents statistics for a topic
 type TopicStats struct {
-	Messages    int `json:"messages"`
-	Subscribers int `json:"subscribers"`
+	Messages    int           `json:"messages"`
+	Subscribers int           `json:"subscribers"`
+	Cursors     []CursorInfo  `json:"cursors,omitempty"`
+	Payloads    *PayloadStats `json:"payloads"` // over buffered messages
+	Dropped     uint64        `json:"dropped"`  // messages dropped for full subscriber queues since the topic was created
+
+	// Clients break the topic's delivery down per subscriber, sorted by
+	// client ID, so the one falling behind can be found
+	Clients []SubscriberStats `json:"clients,omitempty"`
+}
+
+// SubscriberStats are the delivery counters of one subscriber of a topic
+type SubscriberStats struct {
+	ClientID  string `json:"client_id"`
+	Durable   bool   `json:"durable"`
+	Delivered uint64 `json:"delivered"` // messages enqueued, or fetched if durable
+	Dropped   uint64 `json:"dropped"`   // live messages dropped because the queue was full
+	Depth     int    `json:"depth"`     // messages queued, or not yet fetched if durable
+	Capacity  int    `json:"capacity"`  // messages the queue holds; 0 if durable
+}
+
+// SubscriptionStats describes one of a client's subscriptions, so the
+// client can tell whether messages were dropped or are still queued
+type SubscriptionStats struct {
+	Topic        string     `json:"topic"`
+	Durable      bool       `json:"durable"`
+	Delivered    uint64     `json:"delivered"` // messages enqueued for the client, or fetched if durable
+	Dropped      uint64     `json:"dropped"`   // live messages dropped because the queue was full
+	Backlog      int        `json:"backlog"`   // messages queued, or not yet fetched if durable
+	LastDelivery *time.Time `json:"last_delivery,omitempty"`
+	LastSeen     time.Time  `json:"last_seen"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // when the subscription's TTL ends it
 }
 
 // StatsResponse represents overall statistics
 type StatsResponse struct {
 	Topics map[string]TopicStats `json:"topics"`
+	Tasks  TaskStats             `json:"tasks"`
 }
 
 // RingBuffer for message replay with drop-oldest backpressure policy
 type RingBuffer struct {
-	buffer []*Message
-	size   int
-	head   int
-	tail   int
-	count  int
-	mu     sync.RWMutex
+	buffer  []*Message
+	size    int
+	head    int
+	tail    int
+	count   int
+	lastSeq uint64 // sequence number of the newest message
+	mu      sync.RWMutex
+
+	holes int // slots among count emptied by Remove
+
+	indexes  headerIndexes // by header, for Lookup
+	payloads payloadCounts // over the buffered messages, for stats
+	sizes    []int         // payload size of the message in each slot
 }
 
 // NewRingBuffer creates a new ring buffer with specified size
@@ -90,16 +767,32 @@ func NewRingBuffer(size int) *RingBuffer {
 	return &RingBuffer{
 		buffer: make([]*Message, size),
 		size:   size,
+		sizes:  make([]int, size),
 	}
 }
 
-// Add adds a message to the ring buffer (drop-oldest policy)
-func (rb *RingBuffer) Add(msg *Message) {
+// Append adds a message to the ring buffer (drop-oldest policy), stamping it
+// with the next sequence number. It never fails.
+func (rb *RingBuffer) Append(msg *Message) error {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
+	rb.lastSeq++
+	msg.Seq = rb.lastSeq
+	if msg.Origin != "" && msg.OriginSeq == 0 {
+		msg.OriginSeq = msg.Seq
+	}
+
+	if evicted := rb.buffer[rb.tail]; rb.count == rb.size && evicted != nil {
+		rb.indexes.remove(evicted)
+		rb.payloads.remove(evicted, rb.sizes[rb.tail])
+	} else if rb.count == rb.size {
+		rb.holes--
+	}
 	rb.buffer[rb.tail] = msg
+	rb.sizes[rb.tail] = rb.payloads.add(msg)
 	rb.tail = (rb.tail + 1) % rb.size
+	rb.indexes.add(msg)
 
 	if rb.count < rb.size {
 		rb.count++
@@ -107,6 +800,37 @@ func (rb *RingBuffer) Add(msg *Message) {
 		// Drop oldest message (advance head)
 		rb.head = (rb.head + 1) % rb.size
 	}
+
+	return nil
+}
+
+// load fills an empty buffer with messages restored from storage, keeping
+// their sequence numbers. lastSeq is the newest sequence number ever
+// stamped; slots of messages missing from messages are left empty, as
+// Remove leaves them. Header indexes are built by a later SetIndexes.
+func (rb *RingBuffer) load(messages []*Message, lastSeq uint64) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	count := rb.size
+	if lastSeq < uint64(count) {
+		count = int(lastSeq)
+	}
+	oldest := lastSeq - uint64(count) + 1
+
+	placed := 0
+	for _, msg := range messages {
+		if msg.Seq >= oldest && msg.Seq <= lastSeq {
+			rb.buffer[msg.Seq-oldest] = msg
+			rb.sizes[msg.Seq-oldest] = rb.payloads.add(msg)
+			placed++
+		}
+	}
+	rb.holes = count - placed
+	rb.head = 0
+	rb.tail = count % rb.size
+	rb.count = count
+	rb.lastSeq = lastSeq
 }
 
 // GetLastN returns the last n messages in chronological order
@@ -142,11 +866,98 @@ func (rb *RingBuffer) GetLastN(n int) []*Message {
 	return messages
 }
 
-// Count returns the number of messages in the buffer
+// GetSince returns up to max messages with a sequence number greater than
+// seq, in chronological order. Messages already dropped from the buffer are
+// skipped.
+func (rb *RingBuffer) GetSince(seq uint64, max int) []*Message {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	if max <= 0 || rb.count == 0 || seq >= rb.lastSeq {
+		return []*Message{}
+	}
+
+	oldest := rb.lastSeq - uint64(rb.count) + 1
+	offset := 0
+	if seq >= oldest {
+		offset = int(seq - oldest + 1)
+	}
+
+	n := rb.count - offset
+	if n > max {
+		n = max
+	}
+
+	messages := make([]*Message, 0, n)
+	for i := 0; i < n; i++ {
+		idx := (rb.head + offset + i) % rb.size
+		if rb.buffer[idx] != nil {
+			messages = append(messages, rb.buffer[idx])
+		}
+	}
+
+	return messages
+}
+
+// GetSinceTime returns the buffered messages stamped at or after since, in
+// chronological order. Replicated messages keep their origin timestamps, so
+// the buffer is scanned in full rather than searched.
+func (rb *RingBuffer) GetSinceTime(since time.Time) []*Message {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	messages := make([]*Message, 0)
+	for i := 0; i < rb.count; i++ {
+		msg := rb.buffer[(rb.head+i)%rb.size]
+		if msg != nil && !msg.Timestamp.Before(since) {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+// Remove drops the message with the given ID from the buffer, returning it,
+// or nil if it is not (or no longer) buffered. The slot is left empty so
+// sequence positions are unchanged.
+func (rb *RingBuffer) Remove(id string) *Message {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	for i := 0; i < rb.count; i++ {
+		idx := (rb.head + i) % rb.size
+		if msg := rb.buffer[idx]; msg != nil && msg.ID == id {
+			rb.buffer[idx] = nil
+			rb.holes++
+			rb.indexes.remove(msg)
+			rb.payloads.remove(msg, rb.sizes[idx])
+			return msg
+		}
+	}
+
+	return nil
+}
+
+// LastSeq returns the sequence number of the newest message
+func (rb *RingBuffer) LastSeq() uint64 {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.lastSeq
+}
+
+// Count returns the number of messages in the buffer, not counting removed
+// ones
 func (rb *RingBuffer) Count() int {
 	rb.mu.RLock()
 	defer rb.mu.RUnlock()
-	return rb.count
+	return rb.count - rb.holes
+}
+
+// payloadStats returns PayloadStats over the buffered messages
+func (rb *RingBuffer) payloadStats() *PayloadStats {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.payloads.stats()
 }
 
 // GetMessages returns all messages in the buffer (for stats)
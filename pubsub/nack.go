@@ -0,0 +1,175 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// InFlightWindow is how many delivered messages a subscriber with
+// MaxDeliveries can nack. Older deliveries are forgotten, as messages are
+// never acked on push subscriptions.
+const InFlightWindow = 1000
+
+// Outcomes of a nack
+const (
+	NackRedelivered  = "redelivered"   // queued to the subscriber again
+	NackDeadLettered = "dead_lettered" // published to the subscription's dead-letter topic
+	NackDiscarded    = "discarded"     // dropped, as the subscription has no dead-letter topic
+)
+
+// NackResult reports what happened to a nacked message
+type NackResult struct {
+	Seq        uint64 `json:"seq"`
+	Outcome    string `json:"outcome"`
+	Deliveries int    `json:"deliveries"`       // times the message has been delivered, the redelivery included
+	Reason     string `json:"reason,omitempty"` // dead-letter reason, unless redelivered
+}
+
+// inFlightEntry is a delivered message that can still be nacked
+type inFlightEntry struct {
+	message    *Message
+	deliveries int
+}
+
+// inFlight tracks the messages delivered to a subscriber by seq, keeping
+// the newest InFlightWindow of them
+type inFlight struct {
+	mu      sync.Mutex
+	entries map[uint64]*inFlightEntry
+	order   []uint64 // seqs in first delivery order; may hold removed seqs
+}
+
+// record counts a delivery of message, evicting the oldest entry when the
+// window is full
+func (f *inFlight) record(message *Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if entry, exists := f.entries[message.Seq]; exists {
+		entry.deliveries++
+		return
+	}
+
+	if f.entries == nil {
+		f.entries = make(map[uint64]*inFlightEntry)
+	}
+	f.entries[message.Seq] = &inFlightEntry{message: message, deliveries: 1}
+	f.order = append(f.order, message.Seq)
+
+	for len(f.entries) > InFlightWindow {
+		delete(f.entries, f.order[0])
+		f.order = f.order[1:]
+	}
+	// Drop seqs removed by nacks once they make up half the order
+	if len(f.order) > 2*InFlightWindow {
+		order := make([]uint64, 0, len(f.entries))
+		for _, seq := range f.order {
+			if _, exists := f.entries[seq]; exists {
+				order = append(order, seq)
+			}
+		}
+		f.order = order
+	}
+}
+
+// get returns the in-flight message with seq and its delivery count
+func (f *inFlight) get(seq uint64) (*Message, int, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, exists := f.entries[seq]
+	if !exists {
+		return nil, 0, false
+	}
+	return entry.message, entry.deliveries, true
+}
+
+// remove forgets the in-flight message with seq
+func (f *inFlight) remove(seq uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.entries, seq)
+}
+
+// trackInFlight records a delivery for subscribers that can nack. Messages
+// without a seq, such as tombstones, cannot be nacked.
+func (sub *Subscriber) trackInFlight(message *Message) {
+	if sub.MaxDeliveries > 0 && message.Seq != 0 {
+		sub.inFlight.record(message)
+	}
+}
+
+// Nack rejects a message delivered to a subscriber. With requeue, the
+// message is queued to the subscriber again unless it has already been
+// delivered MaxDeliveries times. Otherwise it is published to the
+// subscription's dead-letter topic, or discarded without one.
+func (s *service) Nack(ctx context.Context, topicName, clientID string, seq uint64, requeue bool) (*NackResult, error) {
+	log := logging.WithContext(ctx)
+
+	if err := authorize(ctx, ActionSubscribe, topicName); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	sub, subscribed := topic.Subscribers[clientID]
+	topic.mu.RUnlock()
+
+	switch {
+	case !subscribed:
+		return nil, fmt.Errorf("client %s not subscribed to topic %s", clientID, topicName)
+	case sub.Durable:
+		return nil, fmt.Errorf("invalid nack: durable subscriptions redeliver unacked messages on resubscribe")
+	case sub.MaxDeliveries == 0:
+		return nil, fmt.Errorf("invalid nack: subscription has no max_deliveries")
+	}
+
+	message, deliveries, tracked := sub.inFlight.get(seq)
+	if !tracked {
+		return nil, fmt.Errorf("invalid nack: seq %d is not in flight for client %s", seq, clientID)
+	}
+	result := &NackResult{Seq: seq, Deliveries: deliveries}
+
+	reason := DeadLetterReasonNacked
+	if requeue {
+		if deliveries < sub.MaxDeliveries {
+			// A redelivery dropped by a full queue is dead-lettered like any
+			// other dropped message
+			dropped := s.deliver(ctx, topic, sub, message)
+			if !slices.Contains(dropped, message) {
+				s.deadLetterDropped(ctx, topicName, sub, dropped)
+				result.Outcome = NackRedelivered
+				result.Deliveries++
+				return result, nil
+			}
+			s.deadLetterDropped(ctx, topicName, sub, slices.DeleteFunc(dropped, func(m *Message) bool { return m == message }))
+			reason = DeadLetterReasonBacklog
+		} else {
+			reason = DeadLetterReasonMaxDeliveries
+		}
+	}
+
+	sub.inFlight.remove(seq)
+	result.Reason = reason
+	if sub.DeadLetter == "" {
+		result.Outcome = NackDiscarded
+		log.Infow("Discarded nacked message", "client_id", clientID, "topic", topicName, "seq", seq, "reason", reason)
+		return result, nil
+	}
+
+	s.deadLetter(ctx, topicName, sub, message, reason)
+	result.Outcome = NackDeadLettered
+	return result, nil
+}
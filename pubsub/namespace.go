@@ -0,0 +1,208 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Topic names are segmented by NamespaceSeparator, as in tenant/app/events,
+// where every leading segment path is a namespace
+const (
+	NamespaceSeparator = "/"
+	MaxTopicNameLength = 255
+	MaxNamespaceDepth  = 8
+)
+
+// Namespace holds defaults for the topics created under its prefix. A
+// topic inherits each setting it leaves unset from its nearest parent
+// namespace setting it, when it is created; later changes to a namespace
+// do not affect existing topics.
+type Namespace struct {
+	Prefix    string       `json:"prefix"`
+	Options   TopicOptions `json:"options"`
+	Retention Retention    `json:"retention"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// ValidateTopicName checks a topic name or namespace prefix: at most
+// MaxNamespaceDepth non-empty segments, other than "." and "..", without
+// spaces or control characters
+func ValidateTopicName(name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid topic name: name is required")
+	}
+	if len(name) > MaxTopicNameLength {
+		return fmt.Errorf("invalid topic name: %s is longer than %d bytes", name, MaxTopicNameLength)
+	}
+	if strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("invalid topic name: %q contains spaces or control characters", name)
+	}
+
+	segments := strings.Split(name, NamespaceSeparator)
+	if len(segments) > MaxNamespaceDepth {
+		return fmt.Errorf("invalid topic name: %s has more than %d segments", name, MaxNamespaceDepth)
+	}
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid topic name: %s has an empty, \".\" or \"..\" segment", name)
+		}
+	}
+	return nil
+}
+
+// InNamespace reports whether a topic is prefix itself or lies under it.
+// An empty prefix holds every topic.
+func InNamespace(name, prefix string) bool {
+	return prefix == "" || name == prefix || strings.HasPrefix(name, prefix+NamespaceSeparator)
+}
+
+// SetNamespace creates or replaces the defaults of a namespace, returning
+// the stored namespace
+func (s *service) SetNamespace(ctx context.Context, namespace Namespace) (*Namespace, error) {
+	if err := ValidateTopicName(namespace.Prefix); err != nil {
+		return nil, fmt.Errorf("invalid namespace: %s", strings.TrimPrefix(err.Error(), "invalid topic name: "))
+	}
+	if err := namespace.Options.Validate(); err != nil {
+		return nil, err
+	}
+	if err := namespace.Retention.Validate(); err != nil {
+		return nil, err
+	}
+	namespace.UpdatedAt = s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.namespaces[namespace.Prefix]
+	s.namespaces[namespace.Prefix] = &namespace
+	if err := s.saveNamespaces(); err != nil {
+		if existed {
+			s.namespaces[namespace.Prefix] = previous
+		} else {
+			delete(s.namespaces, namespace.Prefix)
+		}
+		return nil, fmt.Errorf("failed to save namespaces: %w", err)
+	}
+
+	logging.WithContext(ctx).Infow("Set namespace", "namespace", namespace.Prefix, "actor", actorFromContext(ctx),
+		"ring_buffer_size", namespace.Options.RingBufferSize, "channel_buffer_size", namespace.Options.ChannelBufferSize)
+	stored := namespace
+	return &stored, nil
+}
+
+// DeleteNamespace removes the defaults of a namespace. Its topics keep the
+// settings they inherited.
+func (s *service) DeleteNamespace(ctx context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	namespace, exists := s.namespaces[prefix]
+	if !exists {
+		return fmt.Errorf("namespace %s not found", prefix)
+	}
+	delete(s.namespaces, prefix)
+	if err := s.saveNamespaces(); err != nil {
+		s.namespaces[prefix] = namespace
+		return fmt.Errorf("failed to save namespaces: %w", err)
+	}
+
+	logging.WithContext(ctx).Infow("Deleted namespace", "namespace", prefix, "actor", actorFromContext(ctx))
+	return nil
+}
+
+// ListNamespaces returns the namespaces with defaults under prefix, sorted
+// by prefix
+func (s *service) ListNamespaces(ctx context.Context, prefix string) ([]Namespace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	namespaces := make([]Namespace, 0, len(s.namespaces))
+	for _, namespace := range s.namespaces {
+		if InNamespace(namespace.Prefix, prefix) {
+			namespaces = append(namespaces, *namespace)
+		}
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Prefix < namespaces[j].Prefix })
+
+	return namespaces, nil
+}
+
+// inheritNamespaces fills the options a new topic leaves unset from its
+// parent namespaces, nearest first, and returns the retention it inherits.
+// Caller must hold s.mu.
+func (s *service) inheritNamespaces(name string, opts *TopicOptions) Retention {
+	var retention Retention
+	for i := strings.LastIndex(name, NamespaceSeparator); i > 0; i = strings.LastIndex(name[:i], NamespaceSeparator) {
+		namespace, exists := s.namespaces[name[:i]]
+		if !exists {
+			continue
+		}
+		if opts.RingBufferSize == 0 {
+			opts.RingBufferSize = namespace.Options.RingBufferSize
+		}
+		if opts.ChannelBufferSize == 0 {
+			opts.ChannelBufferSize = namespace.Options.ChannelBufferSize
+		}
+		if opts.JSONSchema == nil {
+			opts.JSONSchema = namespace.Options.JSONSchema
+		}
+		if !retention.Enabled() {
+			retention = namespace.Retention
+		}
+	}
+	return retention
+}
+
+// saveNamespaces writes the namespaces to Config.DataDir, if set. Caller
+// must hold s.mu.
+func (s *service) saveNamespaces() error {
+	if s.config.DataDir == "" {
+		return nil
+	}
+
+	namespaces := make([]*Namespace, 0, len(s.namespaces))
+	for _, namespace := range s.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	data, err := json.Marshal(namespaces)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.config.DataDir, 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.config.DataDir, namespacesFile), data)
+}
+
+// loadNamespaces restores the namespaces saved in Config.DataDir
+func (s *service) loadNamespaces() error {
+	data, err := os.ReadFile(filepath.Join(s.config.DataDir, namespacesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read namespaces: %w", err)
+	}
+
+	var namespaces []*Namespace
+	if err := json.Unmarshal(data, &namespaces); err != nil {
+		return fmt.Errorf("failed to read namespaces: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, namespace := range namespaces {
+		s.namespaces[namespace.Prefix] = namespace
+	}
+	return nil
+}
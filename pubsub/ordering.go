@@ -0,0 +1,145 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Ordering modes for topics
+const (
+	// OrderingDefault publishes concurrently: each publish stores its message
+	// and pushes it to every subscriber's writer on its own goroutine, so
+	// concurrent publishes may reach different subscribers in different
+	// orders
+	OrderingDefault = ""
+	// OrderingStrict funnels every publish through one goroutine per topic,
+	// which assigns sequence numbers, stores the message and hands it to
+	// every subscriber's writer before taking the next publish. Each
+	// subscriber's writer is the only goroutine feeding its queue, so every
+	// subscriber sees messages in sequence order, at the cost of publish
+	// throughput.
+	OrderingStrict = "strict"
+)
+
+// sequencer is the single writer of a strictly ordered topic
+type sequencer struct {
+	queue chan orderedPublish // unbuffered, so an accepted publish is always answered
+	stop  chan struct{}
+}
+
+// orderedPublish is a publish waiting for a topic's sequencer
+type orderedPublish struct {
+	ctx     context.Context
+	message *Message
+	done    chan orderedResult
+}
+
+type orderedResult struct {
+	subscribers int
+	err         error
+}
+
+// SetOrdering switches a topic between OrderingDefault and OrderingStrict.
+// Publishes in flight while the mode changes complete in the mode they
+// started in.
+func (s *service) SetOrdering(ctx context.Context, topicName, ordering string) error {
+	if ordering != OrderingDefault && ordering != OrderingStrict {
+		return fmt.Errorf("invalid ordering: mode must be %q or empty", OrderingStrict)
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	if ordering == OrderingStrict && topic.Partitions > 0 {
+		topic.mu.Unlock()
+		return fmt.Errorf("invalid ordering: topic %s is partitioned, and ordered within each partition", topicName)
+	}
+	s.setOrdering(topic, ordering)
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic ordering", "topic", topicName, "ordering", ordering)
+	return nil
+}
+
+// setOrdering sets a topic's mode, starting or stopping its sequencer.
+// Caller must hold topic.mu or own topic exclusively.
+func (s *service) setOrdering(topic *Topic, ordering string) {
+	topic.Ordering = ordering
+
+	switch {
+	case ordering == OrderingStrict && topic.sequencer == nil:
+		topic.sequencer = &sequencer{
+			queue: make(chan orderedPublish),
+			stop:  make(chan struct{}),
+		}
+		s.wg.Add(1)
+		go s.sequence(topic, topic.sequencer)
+	case ordering != OrderingStrict:
+		stopSequencer(topic)
+	}
+}
+
+// stopSequencer stops a topic's sequencer, if it has one. Caller must hold
+// topic.mu.
+func stopSequencer(topic *Topic) {
+	if topic.sequencer != nil {
+		close(topic.sequencer.stop)
+		topic.sequencer = nil
+	}
+}
+
+// sequence stores and enqueues a strictly ordered topic's publishes one at a
+// time, until the topic leaves strict mode or the service stops
+func (s *service) sequence(topic *Topic, seq *sequencer) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case publish := <-seq.queue:
+			subscribers, err := s.fanOut(publish.ctx, topic, publish.message)
+			publish.done <- orderedResult{subscribers: subscribers, err: err}
+		case <-seq.stop:
+			return
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// enqueue stores a message and offers it to the topic's subscribers,
+// through the topic's sequencer in strict mode or its partition's on a
+// partitioned topic, and returns how many subscribers it was offered to
+func (s *service) enqueue(ctx context.Context, topic *Topic, message *Message) (int, error) {
+	topic.mu.RLock()
+	seq := topic.sequencer
+	if len(topic.partitions) > 0 {
+		seq = topic.partitions[partitionOf(message, len(topic.partitions))].seq
+	}
+	topic.mu.RUnlock()
+
+	if seq == nil {
+		return s.fanOut(ctx, topic, message)
+	}
+
+	done := make(chan orderedResult, 1)
+	select {
+	case seq.queue <- orderedPublish{ctx: ctx, message: message, done: done}:
+		result := <-done
+		return result.subscribers, result.err
+	case <-seq.stop:
+		// Strict mode ended, the topic was repartitioned or deleted while
+		// waiting
+		return s.enqueue(ctx, topic, message)
+	case <-s.shutdown:
+		return 0, fmt.Errorf("pubsub service is stopping")
+	}
+}
@@ -0,0 +1,309 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// MaxPartitions bounds how many partitions a topic may be split into
+const MaxPartitions = 64
+
+// partition is one lane of a partitioned topic: a sequencer that stores
+// and enqueues its messages one at a time, so messages with the same key
+// reach every subscriber in publish order, and a buffer of its newest
+// messages for replay to subscriptions to the partition
+type partition struct {
+	seq    *sequencer
+	buffer *partitionBuffer
+}
+
+// partitionKey is what a message is partitioned by: its key, or for
+// messages without one, the ID, which spreads them evenly. Tombstones go
+// by the deleted message's ID, to the deleted message's partition.
+func (m *Message) partitionKey() string {
+	switch {
+	case m.Key != "":
+		return m.Key
+	case m.Tombstone != "":
+		return m.Tombstone
+	default:
+		return m.ID
+	}
+}
+
+// partitionOf returns the partition of count a message belongs to
+func partitionOf(msg *Message, count int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(msg.partitionKey()))
+	return int(hash.Sum32() % uint32(count))
+}
+
+// SetPartitions splits a topic into count partitions, each with a
+// sequencer of its own, or merges it back into one with 0. Changing the
+// count rebuilds the partition buffers from the topic's buffer and closes
+// the subscriptions to a single partition.
+func (s *service) SetPartitions(ctx context.Context, topicName string, count int) error {
+	if count < 0 || count > MaxPartitions {
+		return fmt.Errorf("invalid partitions: count must be between 0 and %d", MaxPartitions)
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	if count > 0 && topic.Ordering == OrderingStrict {
+		topic.mu.Unlock()
+		return fmt.Errorf("invalid partitions: topic %s is strictly ordered as a whole", topicName)
+	}
+	s.setPartitions(topic, count)
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic partitions", "topic", topicName, "partitions", count)
+	return nil
+}
+
+// setPartitions sets a topic's partition count, replacing its partitions
+// when the count changes. Caller must hold topic.mu or own topic
+// exclusively.
+func (s *service) setPartitions(topic *Topic, count int) {
+	if count == len(topic.partitions) {
+		topic.Partitions = count
+		return
+	}
+
+	stopPartitions(topic)
+	closed := 0
+	for clientID, subscriber := range topic.Subscribers {
+		if subscriber.Partition != nil {
+			subscriber.close(CloseReasonRepartitioned)
+			delete(topic.Subscribers, clientID)
+			closed++
+		}
+	}
+	if closed > 0 {
+		s.emitSubscribers(topic)
+	}
+	topic.Partitions = count
+	if count == 0 {
+		return
+	}
+
+	// Each partition holds its share of the topic's buffer, at least one
+	size := max((s.ringBufferSize(topic.Options)+count-1)/count, 1)
+	topic.partitions = make([]*partition, count)
+	for i := range topic.partitions {
+		part := &partition{
+			seq: &sequencer{
+				queue: make(chan orderedPublish),
+				stop:  make(chan struct{}),
+			},
+			buffer: newPartitionBuffer(size),
+		}
+		topic.partitions[i] = part
+		s.wg.Add(1)
+		go s.sequence(topic, part.seq)
+	}
+	for _, msg := range topic.Messages.GetMessages() {
+		topic.partitions[partitionOf(msg, count)].buffer.append(msg)
+	}
+}
+
+// stopPartitions stops a topic's partition sequencers and drops their
+// buffers. Caller must hold topic.mu.
+func stopPartitions(topic *Topic) {
+	for _, part := range topic.partitions {
+		close(part.seq.stop)
+	}
+	topic.partitions = nil
+}
+
+// partitionFor returns the partition a message is published through, or
+// nil when the topic is not partitioned
+func (t *Topic) partitionFor(msg *Message) *partition {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.partitions) == 0 {
+		return nil
+	}
+	return t.partitions[partitionOf(msg, len(t.partitions))]
+}
+
+// bufferPartition adds a stored message to its partition's buffer, if
+// the topic is partitioned
+func (t *Topic) bufferPartition(msg *Message) {
+	if part := t.partitionFor(msg); part != nil {
+		part.buffer.append(msg)
+	}
+}
+
+// unbufferPartition removes a message from its partition's buffer,
+// returning it, or nil if it is not buffered there
+func (t *Topic) unbufferPartition(msg *Message) *Message {
+	if part := t.partitionFor(msg); part != nil {
+		return part.buffer.remove(msg.ID)
+	}
+	return nil
+}
+
+// evictPartitions drops the messages stamped before cutoff from every
+// partition buffer, including those the topic's buffer already wrapped past
+func (t *Topic) evictPartitions(cutoff time.Time) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, part := range t.partitions {
+		part.buffer.evictBefore(cutoff)
+	}
+}
+
+// findPartitioned returns a message still held by a partition buffer,
+// searching all of them, or nil
+func (t *Topic) findPartitioned(id string) *Message {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, part := range t.partitions {
+		if msg := part.buffer.find(id); msg != nil {
+			return msg
+		}
+	}
+	return nil
+}
+
+// partitionLastN returns the newest n messages of one partition, or nil
+// when the topic no longer has it. Caller must hold topic.mu.
+func (t *Topic) partitionLastN(index, n int) []*Message {
+	if index >= len(t.partitions) {
+		return nil
+	}
+	return t.partitions[index].buffer.lastN(n)
+}
+
+// PartitionInfo reports one partition of a topic
+type PartitionInfo struct {
+	Partition int    `json:"partition"`
+	Messages  int    `json:"messages"`           // messages held for replay
+	LastSeq   uint64 `json:"last_seq,omitempty"` // sequence number of its newest message
+}
+
+// GetPartitions reports a partitioned topic's partitions, so hot keys
+// show up as a partition holding more than its share
+func (s *service) GetPartitions(ctx context.Context, topicName string) ([]PartitionInfo, error) {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+
+	infos := make([]PartitionInfo, 0, len(topic.partitions))
+	for i, part := range topic.partitions {
+		messages, lastSeq := part.buffer.stats()
+		infos = append(infos, PartitionInfo{Partition: i, Messages: messages, LastSeq: lastSeq})
+	}
+	return infos, nil
+}
+
+// partitionBuffer holds a partition's newest messages in publish order,
+// dropping the oldest when full. It holds the topic's messages, not
+// copies, and leaves their sequence numbers alone.
+type partitionBuffer struct {
+	mu       sync.RWMutex
+	messages []*Message
+	size     int
+}
+
+func newPartitionBuffer(size int) *partitionBuffer {
+	return &partitionBuffer{size: size}
+}
+
+// append adds a message, unless a rebuild from the topic's buffer already
+// added it while it was on its way
+func (b *partitionBuffer) append(msg *Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n := len(b.messages); n > 0 && b.messages[n-1].Seq >= msg.Seq && slices.Contains(b.messages, msg) {
+		return
+	}
+	if len(b.messages) == b.size {
+		b.messages[0] = nil
+		b.messages = b.messages[1:]
+	}
+	b.messages = append(b.messages, msg)
+}
+
+// lastN returns the newest n messages, oldest first
+func (b *partitionBuffer) lastN(n int) []*Message {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	start := max(len(b.messages)-n, 0)
+	return append([]*Message(nil), b.messages[start:]...)
+}
+
+func (b *partitionBuffer) find(id string) *Message {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, msg := range b.messages {
+		if msg.ID == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (b *partitionBuffer) remove(id string) *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, msg := range b.messages {
+		if msg.ID == id {
+			b.messages = append(b.messages[:i:i], b.messages[i+1:]...)
+			return msg
+		}
+	}
+	return nil
+}
+
+// evictBefore drops the messages stamped before cutoff
+func (b *partitionBuffer) evictBefore(cutoff time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	kept := b.messages[:0:0]
+	for _, msg := range b.messages {
+		if !msg.Timestamp.Before(cutoff) {
+			kept = append(kept, msg)
+		}
+	}
+	b.messages = kept
+}
+
+func (b *partitionBuffer) stats() (int, uint64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.messages) == 0 {
+		return 0, 0
+	}
+	return len(b.messages), b.messages[len(b.messages)-1].Seq
+}
@@ -0,0 +1,177 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// PayloadSizeBuckets are the upper bounds, in bytes, of the payload size
+// histogram buckets. Larger payloads fall into a final "+Inf" bucket.
+var PayloadSizeBuckets = []int{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// Payload types reported in PayloadStats.Types
+const (
+	PayloadTypeObject  = "object"
+	PayloadTypeArray   = "array"
+	PayloadTypeString  = "string"
+	PayloadTypeNumber  = "number"
+	PayloadTypeBoolean = "boolean"
+	PayloadTypeNull    = "null"
+)
+
+// SizeBucket counts payloads no larger than LE bytes and larger than the
+// previous bucket's bound
+type SizeBucket struct {
+	LE    string `json:"le"` // upper bound in bytes, or "+Inf"
+	Count int    `json:"count"`
+}
+
+// PayloadStats describes the payloads of the messages a topic currently
+// buffers, so small control messages can be told apart from large blobs
+// that dominate memory
+type PayloadStats struct {
+	TotalBytes int64          `json:"total_bytes"`
+	MaxBytes   int            `json:"max_bytes"`
+	Sizes      []SizeBucket   `json:"sizes"`
+	Types      map[string]int `json:"types"` // payload JSON type -> message count
+}
+
+// payloadCounter is implemented by stores that keep payloadCounts, so their
+// stats are read without encoding every buffered message
+type payloadCounter interface {
+	payloadStats() *PayloadStats
+}
+
+// storePayloadStats returns PayloadStats over the messages of store, from
+// its counts when it keeps them
+func storePayloadStats(store MessageStore) *PayloadStats {
+	if counter, ok := store.(payloadCounter); ok {
+		return counter.payloadStats()
+	}
+	return payloadStats(store.GetMessages())
+}
+
+// payloadStats builds PayloadStats over messages, measuring each payload by
+// its JSON encoding
+func payloadStats(messages []*Message) *PayloadStats {
+	var counts payloadCounts
+	for _, message := range messages {
+		counts.add(message)
+	}
+	return counts.stats()
+}
+
+// payloadCounts keeps PayloadStats over a changing set of messages: add and
+// remove update the totals as messages are stored and dropped, so reading
+// them costs nothing per message. Its owner serializes access.
+type payloadCounts struct {
+	totalBytes int64
+	buckets    []int          // messages per PayloadSizeBuckets bucket, then +Inf
+	types      map[string]int // payload JSON type -> messages
+	bySize     map[int]int    // payload size -> messages, to find the largest after removals
+	maxBytes   int
+}
+
+// payloadSize measures a payload by its JSON encoding
+func payloadSize(payload interface{}) int {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// sizeBucket returns the index of the PayloadSizeBuckets bucket of size
+func sizeBucket(size int) int {
+	for i, bound := range PayloadSizeBuckets {
+		if size <= bound {
+			return i
+		}
+	}
+	return len(PayloadSizeBuckets)
+}
+
+// add counts a stored message and returns its payload size, which the
+// store passes back to remove. Tombstones are not counted.
+func (c *payloadCounts) add(message *Message) int {
+	if message.Tombstone != "" {
+		return 0
+	}
+	if c.buckets == nil {
+		c.buckets = make([]int, len(PayloadSizeBuckets)+1)
+		c.types = make(map[string]int)
+		c.bySize = make(map[int]int)
+	}
+
+	size := payloadSize(message.Payload)
+	c.buckets[sizeBucket(size)]++
+	c.types[payloadType(message.Payload)]++
+	c.bySize[size]++
+	c.totalBytes += int64(size)
+	c.maxBytes = max(c.maxBytes, size)
+	return size
+}
+
+// remove uncounts a dropped message of the size add returned for it
+func (c *payloadCounts) remove(message *Message, size int) {
+	if message.Tombstone != "" || c.buckets == nil {
+		return
+	}
+
+	c.buckets[sizeBucket(size)]--
+	kind := payloadType(message.Payload)
+	if c.types[kind]--; c.types[kind] <= 0 {
+		delete(c.types, kind)
+	}
+	if c.bySize[size]--; c.bySize[size] <= 0 {
+		delete(c.bySize, size)
+		if size == c.maxBytes {
+			c.maxBytes = 0
+			for other := range c.bySize {
+				c.maxBytes = max(c.maxBytes, other)
+			}
+		}
+	}
+	c.totalBytes -= int64(size)
+}
+
+// stats returns a copy of the counts as PayloadStats
+func (c *payloadCounts) stats() *PayloadStats {
+	stats := &PayloadStats{
+		TotalBytes: c.totalBytes,
+		MaxBytes:   c.maxBytes,
+		Sizes:      make([]SizeBucket, len(PayloadSizeBuckets)+1),
+		Types:      make(map[string]int, len(c.types)),
+	}
+	for i, bound := range PayloadSizeBuckets {
+		stats.Sizes[i].LE = strconv.Itoa(bound)
+	}
+	stats.Sizes[len(PayloadSizeBuckets)].LE = "+Inf"
+	for i, count := range c.buckets {
+		stats.Sizes[i].Count = count
+	}
+	for kind, count := range c.types {
+		stats.Types[kind] = count
+	}
+	return stats
+}
+
+// payloadType names the JSON type of a decoded payload
+func payloadType(payload interface{}) string {
+	switch payload.(type) {
+	case nil:
+		return PayloadTypeNull
+	case map[string]interface{}:
+		return PayloadTypeObject
+	case []interface{}:
+		return PayloadTypeArray
+	case string:
+		return PayloadTypeString
+	case bool:
+		return PayloadTypeBoolean
+	case float64, json.Number, int, int64, uint64:
+		return PayloadTypeNumber
+	default:
+		return PayloadTypeObject
+	}
+}
@@ -0,0 +1,309 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// persistedTopic is a topic as a catalog stores it
+type persistedTopic struct {
+	Name      string        `json:"name"`
+	CreatedAt time.Time     `json:"created_at"`
+	Settings  TopicSettings `json:"settings"`
+}
+
+// topicCatalog keeps the settings of every topic outside this process, so
+// topics survive restarts or are shared between instances
+type topicCatalog interface {
+	// create adds a topic, reporting false if the catalog already has one
+	// of its name
+	create(topic persistedTopic) (bool, error)
+	// save replaces a topic's settings
+	save(topic persistedTopic) error
+	// remove deletes a topic with its messages
+	remove(name string) error
+	// load returns every topic in the catalog
+	load() ([]persistedTopic, error)
+}
+
+// fileCatalog keeps each topic's settings in topic.json in its directory
+// under Config.DataDir, beside its FileStore database
+type fileCatalog struct {
+	dir string
+}
+
+func (c *fileCatalog) create(topic persistedTopic) (bool, error) {
+	return true, c.save(topic)
+}
+
+func (c *fileCatalog) save(topic persistedTopic) error {
+	data, err := json.MarshalIndent(topic, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := topicDir(c.dir, topic.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, topicFile), data)
+}
+
+func (c *fileCatalog) remove(name string) error {
+	return os.RemoveAll(topicDir(c.dir, name))
+}
+
+// load reads every topic directory, skipping and logging unreadable ones,
+// which are left on disk
+func (c *fileCatalog) load() ([]persistedTopic, error) {
+	root := filepath.Join(c.dir, topicsDir)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	var topics []persistedTopic
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(root, entry.Name(), topicFile))
+		if err == nil {
+			var topic persistedTopic
+			if err = json.Unmarshal(data, &topic); err == nil {
+				topics = append(topics, topic)
+				continue
+			}
+		}
+		logging.WithContext(context.Background()).Errorw("Failed to read persisted topic", "dir", entry.Name(), "error", err)
+	}
+	return topics, nil
+}
+
+// openMessageStore opens a topic's store holding size messages: the
+// configured factory's, a redisStore or FileStore for the configured
+// backend, or a RingBuffer or LockFreeBuffer.
+// Unless restoring, files a previous topic of the same name left under
+// Config.DataDir are removed first, so a new topic never inherits old
+// messages.
+func (s *service) openMessageStore(name string, size int, restore bool) (MessageStore, error) {
+	if s.config.DataDir != "" && !restore {
+		if err := os.RemoveAll(topicDir(s.config.DataDir, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case s.config.MessageStore != nil:
+		return s.config.MessageStore(name, size)
+	case s.redis != nil:
+		return newRedisStore(s.redis, name, size), nil
+	case s.config.DataDir != "":
+		return OpenFileStore(filepath.Join(topicDir(s.config.DataDir, name), messagesFile), size)
+	case s.config.LockFreeBuffer:
+		return NewLockFreeBuffer(size), nil
+	default:
+		return NewRingBuffer(size), nil
+	}
+}
+
+// createTopicRecord adds a new topic to the catalog, if there is one, and
+// announces it to other instances. It fails with "topic X already exists"
+// if another instance created the topic first.
+func (s *service) createTopicRecord(ctx context.Context, topic *Topic) error {
+	record := persistedTopic{Name: topic.Name, CreatedAt: topic.CreatedAt, Settings: topicSettings(topic)}
+	if err := s.logWAL(walRecord{Created: &record}); err != nil {
+		return fmt.Errorf("failed to log topic %s: %w", topic.Name, err)
+	}
+	if s.catalog == nil {
+		return nil
+	}
+
+	created, err := s.catalog.create(record)
+	if err != nil {
+		return fmt.Errorf("failed to persist topic %s: %w", topic.Name, err)
+	}
+	if !created {
+		return fmt.Errorf("topic %s already exists", topic.Name)
+	}
+	s.announce(ctx, &backendEvent{Kind: eventTopic, Topic: &record})
+
+	return nil
+}
+
+// saveTopic replaces a topic's settings in the catalog, if there is one, and
+// announces them to other instances
+func (s *service) saveTopic(ctx context.Context, name string, createdAt time.Time, settings TopicSettings) error {
+	record := persistedTopic{Name: name, CreatedAt: createdAt, Settings: settings}
+	if err := s.logWAL(walRecord{Saved: &record}); err != nil {
+		return err
+	}
+	if s.catalog == nil {
+		return nil
+	}
+
+	if err := s.catalog.save(record); err != nil {
+		return err
+	}
+	s.announce(ctx, &backendEvent{Kind: eventTopic, Topic: &record})
+
+	return nil
+}
+
+// closeStore closes a topic's message store, if it needs closing
+func closeStore(ctx context.Context, topic *Topic) {
+	if closer, ok := topic.Messages.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logging.WithContext(ctx).Warnw("Failed to close message store", "topic", topic.Name, "error", err)
+		}
+	}
+}
+
+// dropTopic closes a deleted topic's store, removes it from the catalog and
+// announces the deletion to other instances. Topics deleted by another
+// instance are only closed.
+func (s *service) dropTopic(ctx context.Context, topic *Topic, reason string) {
+	closeStore(ctx, topic)
+	if err := s.logWAL(walRecord{Deleted: topic.Name}); err != nil {
+		logging.WithContext(ctx).Errorw("Failed to log topic deletion", "topic", topic.Name, "error", err)
+	}
+	if s.catalog == nil || isRemote(ctx) {
+		return
+	}
+
+	if err := s.catalog.remove(topic.Name); err != nil {
+		logging.WithContext(ctx).Errorw("Failed to remove persisted topic", "topic", topic.Name, "error", err)
+		return
+	}
+	s.announce(ctx, &backendEvent{Kind: eventDeleted, Name: topic.Name, Reason: reason})
+}
+
+// recoverTopics restores the topics in the catalog with their messages and,
+// from DATA_DIR, their cursors and read markers, reporting progress through
+// GetReadiness. Subscriptions are not persisted: durable subscribers
+// resubscribe to resume from their cursors.
+func (s *service) recoverTopics(ctx context.Context) error {
+	log := logging.WithContext(ctx)
+
+	persisted, err := s.catalog.load()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.recovery = &RecoveryProgress{TopicsTotal: len(persisted)}
+	s.mu.Unlock()
+
+	for _, record := range persisted {
+		topic, err := s.restoreTopic(record, nil)
+		if err != nil {
+			log.Errorw("Failed to recover topic", "topic", record.Name, "error", err)
+			continue
+		}
+		s.addRecovered(topic)
+	}
+
+	s.mu.RLock()
+	progress := *s.recovery
+	s.mu.RUnlock()
+	log.Infow("Recovered persisted topics", "topics", progress.TopicsRecovered, "messages", progress.MessagesRecovered)
+
+	return nil
+}
+
+// addRecovered adds a topic restored at startup and counts it in the
+// recovery progress
+func (s *service) addRecovered(topic *Topic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.topics[topic.Name] = topic
+	s.emitStats(StatsDelta{Kind: StatsTopicAdded, Topic: topic.Name})
+	s.recovery.TopicsRecovered++
+	s.recovery.MessagesRecovered += int64(topic.Messages.Count())
+}
+
+// restoreTopic rebuilds a topic from its catalog record, or from a snapshot
+// when saved is set. Schedules are restored from DATA_DIR and snapshots
+// only: with Redis, each runs on the instance it was added on, so instances
+// do not all publish it.
+func (s *service) restoreTopic(record persistedTopic, saved *topicSnapshot) (*Topic, error) {
+	messages, err := s.openMessageStore(record.Name, s.ringBufferSize(record.Settings.Options), true)
+	if err != nil {
+		return nil, err
+	}
+
+	topic := &Topic{
+		Name:        record.Name,
+		Subscribers: make(map[string]*Subscriber),
+		Messages:    messages,
+		Cursors:     make(map[string]*Cursor),
+		ReadMarkers: make(map[string]uint64),
+		Options:     record.Settings.Options,
+		CreatedAt:   record.CreatedAt,
+	}
+	if s.redis == nil {
+		for _, schedule := range record.Settings.Schedules {
+			if err := s.restoreSchedule(topic, schedule); err != nil {
+				return nil, fmt.Errorf("schedule %s: %w", schedule.ID, err)
+			}
+		}
+	}
+	// Messages go in first, for the header and text indexes to cover them
+	if saved != nil {
+		loadSnapshot(topic, saved)
+	}
+	s.applySettings(topic, record.Settings)
+	if saved != nil {
+		loadCompacted(topic, saved.Session.Latest)
+	}
+	if s.config.DataDir != "" {
+		if err := s.restoreSession(topic); err != nil {
+			logging.WithContext(context.Background()).Warnw("Failed to restore topic session, cursors start over",
+				"topic", record.Name, "error", err)
+		}
+	}
+
+	return topic, nil
+}
+
+// applySettings sets a topic's configuration, other than schedules, from
+// settings. Caller must hold topic.mu or own topic exclusively.
+func (s *service) applySettings(topic *Topic, settings TopicSettings) {
+	topic.Owner = settings.Owner
+	topic.Description = settings.Description
+	topic.Labels = maps.Clone(settings.Labels)
+	topic.ReplayRate = settings.ReplayRate
+	topic.Decoding = settings.Decoding
+	topic.Delivery = settings.Delivery
+	topic.Retention = settings.Retention
+	topic.Backpressure = settings.Backpressure
+	topic.Schemas = settings.Schemas
+	topic.Dedup = settings.Dedup
+	topic.LegalHold = settings.LegalHold
+	topic.ExpiresAt = time.Time{}
+	if settings.ExpiresAt != nil {
+		topic.ExpiresAt = *settings.ExpiresAt
+	}
+	topic.Messages.SetIndexes(settings.HeaderIndexes)
+	topic.Routes = nil
+	for _, route := range settings.Routes {
+		topic.Routes = append(topic.Routes, &route)
+	}
+	s.setOrdering(topic, settings.Ordering)
+	s.setPartitions(topic, settings.Partitions)
+	setTextIndex(topic, settings.TextIndex)
+	setCompaction(topic, settings.Compaction)
+	s.setIngress(topic, settings.Ingress)
+}
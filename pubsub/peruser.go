@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Delivery modes for topics
+const (
+	// DeliveryAll offers every live message to every subscriber
+	DeliveryAll = ""
+	// DeliveryOncePerUser offers each live message to only one of the
+	// subscribers sharing a SubscribeOptions.UserID, the most recently
+	// active, so a user connected from a phone and a laptop is alerted
+	// once. Subscribers without a user ID, replays and tombstones are
+	// delivered as usual.
+	DeliveryOncePerUser = "once_per_user"
+)
+
+// SetDelivery switches a topic between DeliveryAll and DeliveryOncePerUser
+func (s *service) SetDelivery(ctx context.Context, topicName, delivery string) error {
+	if delivery != DeliveryAll && delivery != DeliveryOncePerUser {
+		return fmt.Errorf("invalid delivery: mode must be %q or empty", DeliveryOncePerUser)
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.Delivery = delivery
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic delivery", "topic", topicName, "delivery", delivery)
+	return nil
+}
+
+// Touch records activity on the subscriber's connection, making it the
+// one DeliveryOncePerUser topics prefer among its user's subscribers
+func (sub *Subscriber) Touch() {
+	sub.active.Store(sub.clock.Now().UnixNano())
+}
+
+// oncePerUser returns the most recently active subscriber of each user
+// among the subscribers wanting the message
+func oncePerUser(subscribers []*Subscriber, message *Message) map[string]*Subscriber {
+	chosen := make(map[string]*Subscriber)
+	for _, sub := range subscribers {
+		if sub.UserID == "" || !sub.wants(message) {
+			continue
+		}
+		if current, ok := chosen[sub.UserID]; !ok || sub.active.Load() > current.active.Load() {
+			chosen[sub.UserID] = sub
+		}
+	}
+	return chosen
+}
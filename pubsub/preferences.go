@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"fmt"
+	"maps"
+)
+
+// MaxHeaderPreferences is the most header keys a subscription may prefer
+const MaxHeaderPreferences = 8
+
+// HeaderPreferences picks the variants a subscription receives when
+// producers publish one message per variant, each marked with a header
+// such as locale. A message carrying a preferred key is delivered only
+// when its value is the preferred one; messages without the key are not
+// variants and are always delivered.
+type HeaderPreferences map[string]string
+
+// Validate checks that the preferences are usable
+func (p HeaderPreferences) Validate() error {
+	if len(p) > MaxHeaderPreferences {
+		return fmt.Errorf("invalid header preferences: at most %d keys may be preferred", MaxHeaderPreferences)
+	}
+	for key, value := range p {
+		if key == "" {
+			return fmt.Errorf("invalid header preferences: keys must not be empty")
+		}
+		if value == "" {
+			return fmt.Errorf("invalid header preferences: value for %s must not be empty", key)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the message is a variant the preferences admit.
+// Tombstones always match so every subscriber can purge deleted messages.
+func (p HeaderPreferences) Matches(msg *Message) bool {
+	if msg.Tombstone != "" {
+		return true
+	}
+	for key, preferred := range p {
+		if value, ok := msg.Headers[key]; ok && value != preferred {
+			return false
+		}
+	}
+	return true
+}
+
+// clone returns a copy of the preferences, or nil when there are none
+func (p HeaderPreferences) clone() HeaderPreferences {
+	if len(p) == 0 {
+		return nil
+	}
+	return maps.Clone(p)
+}
@@ -0,0 +1,58 @@
+package pubsub
+
+import "fmt"
+
+// MessagePriority decides which of a subscriber's queues a live message
+// waits in
+type MessagePriority string
+
+// Message priorities
+const (
+	// PriorityNormal is the default, used when a message has none
+	PriorityNormal MessagePriority = "normal"
+	// PriorityHigh messages are queued apart from normal ones and handed to
+	// the subscriber first, so alerts are not stuck behind bulk traffic
+	PriorityHigh MessagePriority = "high"
+)
+
+// Validate checks the priority is known
+func (p MessagePriority) Validate() error {
+	switch p {
+	case "", PriorityNormal, PriorityHigh:
+		return nil
+	}
+	return fmt.Errorf("invalid priority: must be %s or %s", PriorityNormal, PriorityHigh)
+}
+
+// TryReceive takes the next queued message without waiting, high priority
+// first. ok is false when nothing is queued; closed is true once the
+// subscription is closed and both queues are drained.
+func (sub *Subscriber) TryReceive() (message *Message, ok bool, closed bool) {
+	select {
+	case msg, open := <-sub.PriorityChan:
+		if open {
+			return msg, true, false
+		}
+	default:
+	}
+
+	select {
+	case msg, open := <-sub.MessageChan:
+		if open {
+			return msg, true, false
+		}
+	default:
+		return nil, false, false
+	}
+
+	// MessageChan is closed after PriorityChan, so a message still queued
+	// in PriorityChan was queued before the close
+	select {
+	case msg, open := <-sub.PriorityChan:
+		if open {
+			return msg, true, false
+		}
+	default:
+	}
+	return nil, false, true
+}
@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+)
+
+// MarkRead moves a client's read marker forward to seq. Markers never move
+// backwards; marking an older seq returns the current marker unchanged.
+func (s *service) MarkRead(ctx context.Context, topicName, clientID string, seq uint64) (*ReadMarker, error) {
+	if err := authorize(ctx, ActionSubscribe, topicName); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+
+	head := topic.Messages.LastSeq()
+	if seq > head {
+		return nil, fmt.Errorf("invalid read marker: seq %d is beyond head seq %d", seq, head)
+	}
+	if seq > topic.ReadMarkers[clientID] {
+		topic.ReadMarkers[clientID] = seq
+		topic.unsaved.Store(true)
+	}
+
+	return readMarker(clientID, topic.ReadMarkers[clientID], head), nil
+}
+
+// GetReadMarker returns a client's read marker; a client that never marked
+// anything read has read nothing
+func (s *service) GetReadMarker(ctx context.Context, topicName, clientID string) (*ReadMarker, error) {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+
+	return readMarker(clientID, topic.ReadMarkers[clientID], topic.Messages.LastSeq()), nil
+}
+
+// readMarker builds a ReadMarker against the topic head
+func readMarker(clientID string, read, head uint64) *ReadMarker {
+	marker := &ReadMarker{
+		ClientID: clientID,
+		Read:     read,
+		Head:     head,
+	}
+	if head > read {
+		marker.Unread = head - read
+	}
+	return marker
+}
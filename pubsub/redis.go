@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis connection parameters
+const (
+	RedisDialTimeout    = 5 * time.Second
+	RedisCommandTimeout = 5 * time.Second
+	// RedisReconnectDelay is the wait before resubscribing after the
+	// subscription connection drops
+	RedisReconnectDelay = time.Second
+)
+
+// newRedisClient connects to the server at rawURL, a
+// redis://[[user]:password@]host[:port][/db] or rediss:// URL, and checks
+// that it answers
+func newRedisClient(rawURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: expected redis://[:password@]host[:port][/db]")
+	}
+	opts.DialTimeout = RedisDialTimeout
+	opts.ReadTimeout = RedisCommandTimeout
+	opts.WriteTimeout = RedisCommandTimeout
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), RedisDialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return client, nil
+}
+
+// subscribeRedis delivers every message published to channel to handler,
+// in order, until ctx is done. When the subscription drops, onError is told
+// and go-redis resubscribes on a new connection; messages published while
+// it is down are lost.
+func subscribeRedis(ctx context.Context, client *redis.Client, channel string, handler func(payload string), onError func(error)) {
+	subscription := client.Subscribe(ctx, channel)
+	defer subscription.Close()
+
+	// Closing the subscription ends a receive waiting on the network
+	stop := context.AfterFunc(ctx, func() { subscription.Close() })
+	defer stop()
+
+	for {
+		message, err := subscription.ReceiveMessage(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			onError(err)
+
+			select {
+			case <-time.After(RedisReconnectDelay):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		handler(message.Payload)
+	}
+}
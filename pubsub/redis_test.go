@@ -0,0 +1,240 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis starts an in-process Redis and returns a client of it
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client, err := newRedisClient("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestNewRedisClientErrors(t *testing.T) {
+	if _, err := newRedisClient("http://localhost"); err == nil {
+		t.Error("non-redis url accepted")
+	}
+
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+	if _, err := newRedisClient("redis://" + addr); err == nil {
+		t.Error("unreachable server accepted")
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	_, client := newTestRedis(t)
+	store := newRedisStore(client, "orders", 3)
+
+	if seq := store.LastSeq(); seq != 0 {
+		t.Fatalf("LastSeq of an empty store = %d, want 0", seq)
+	}
+
+	for i := 1; i <= 5; i++ {
+		msg := &Message{ID: fmt.Sprint("m", i), Payload: i}
+		if err := store.Append(msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Seq != uint64(i) {
+			t.Fatalf("message %d stamped with seq %d", i, msg.Seq)
+		}
+	}
+
+	if seq := store.LastSeq(); seq != 5 {
+		t.Errorf("LastSeq = %d, want 5", seq)
+	}
+	if count := store.Count(); count != 3 {
+		t.Errorf("Count = %d, want 3 after trimming", count)
+	}
+	assertSeqs(t, "GetMessages", store.GetMessages(), 3, 4, 5)
+	assertSeqs(t, "GetLastN", store.GetLastN(2), 4, 5)
+	assertSeqs(t, "GetSince", store.GetSince(3, 1), 4)
+	assertSeqs(t, "GetSince past the end", store.GetSince(5, 10))
+
+	if removed := store.Remove("m4"); removed == nil || removed.Seq != 4 {
+		t.Fatalf("Remove(m4) = %v", removed)
+	}
+	if removed := store.Remove("m4"); removed != nil {
+		t.Error("Remove of a removed message returned it")
+	}
+	assertSeqs(t, "GetMessages after Remove", store.GetMessages(), 3, 5)
+}
+
+// assertSeqs checks the sequence numbers of messages
+func assertSeqs(t *testing.T, name string, messages []*Message, seqs ...uint64) {
+	t.Helper()
+
+	got := make([]uint64, len(messages))
+	for i, msg := range messages {
+		got[i] = msg.Seq
+	}
+	if fmt.Sprint(got) != fmt.Sprint(seqs) {
+		t.Errorf("%s returned seqs %v, want %v", name, got, seqs)
+	}
+}
+
+func TestRedisCatalog(t *testing.T) {
+	server, client := newTestRedis(t)
+	catalog := &redisCatalog{client: client}
+
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	created, err := catalog.create(persistedTopic{Name: "orders", CreatedAt: createdAt})
+	if err != nil || !created {
+		t.Fatalf("create = %v, %v", created, err)
+	}
+	if created, err := catalog.create(persistedTopic{Name: "orders"}); err != nil || created {
+		t.Fatalf("second create = %v, %v", created, err)
+	}
+	if err := catalog.save(persistedTopic{Name: "audit"}); err != nil {
+		t.Fatal(err)
+	}
+
+	topics, err := catalog.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 || topics[0].Name != "audit" || topics[1].Name != "orders" || !topics[1].CreatedAt.Equal(createdAt) {
+		t.Fatalf("load = %+v", topics)
+	}
+
+	store := newRedisStore(client, "orders", 10)
+	if err := store.Append(&Message{ID: "m1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.remove("orders"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{redisMessagesKey + "orders", redisSeqKey + "orders"} {
+		if server.Exists(key) {
+			t.Errorf("%s kept after remove", key)
+		}
+	}
+	if topics, _ := catalog.load(); len(topics) != 1 {
+		t.Errorf("load after remove = %+v", topics)
+	}
+}
+
+func TestSubscribeRedis(t *testing.T) {
+	_, client := newTestRedis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var mu sync.Mutex
+	var received []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		subscribeRedis(ctx, client, "events",
+			func(payload string) {
+				mu.Lock()
+				received = append(received, payload)
+				mu.Unlock()
+			},
+			func(err error) { t.Errorf("subscription failed: %v", err) })
+	}()
+
+	// Wait for the subscription, then publish in order
+	waitFor(t, func() bool {
+		subscribers, _ := client.PubSubNumSub(ctx, "events").Result()
+		return subscribers["events"] == 1
+	})
+	for i := range 3 {
+		if err := client.Publish(ctx, "events", fmt.Sprint(i)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	})
+	if fmt.Sprint(received) != "[0 1 2]" {
+		t.Errorf("received %v", received)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscribeRedis did not return after cancel")
+	}
+}
+
+// TestRedisBackendInstances runs two services on one Redis: topics created
+// and messages published on one reach the other
+func TestRedisBackendInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	start := func() *service {
+		config := DefaultConfig()
+		config.Backend = BackendRedis
+		config.RedisURL = "redis://" + server.Addr()
+		s := newService(config)
+		if err := s.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Stop(ctx) })
+		return s
+	}
+	a, b := start(), start()
+	waitFor(t, func() bool {
+		subscribers, _ := a.redis.PubSubNumSub(ctx, redisEventsChannel).Result()
+		return subscribers[redisEventsChannel] == 2
+	})
+
+	if err := a.CreateTopic(ctx, "orders", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		_, err := b.GetTopic(ctx, "orders")
+		return err == nil
+	})
+
+	sub, err := b.Subscribe(ctx, "orders", "client", &SubscribeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Publish(ctx, "orders", Message{ID: "m1", Payload: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-sub.MessageChan:
+		if msg.ID != "m1" || msg.Seq != 1 {
+			t.Errorf("received %s with seq %d", msg.ID, msg.Seq)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message published on one instance not delivered on the other")
+	}
+	if seq := b.topics["orders"].Messages.LastSeq(); seq != 1 {
+		t.Errorf("other instance sees LastSeq %d, want 1", seq)
+	}
+}
+
+// waitFor polls condition until it holds, failing the test after 5s
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
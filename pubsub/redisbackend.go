@@ -0,0 +1,273 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisURL is used by BackendRedis when Config.RedisURL is empty
+const DefaultRedisURL = "redis://localhost:6379"
+
+// Kinds of backend events instances announce to each other
+const (
+	eventTopic   = "topic"   // a topic was created or configured
+	eventDeleted = "deleted" // a topic was deleted
+	eventMessage = "message" // a message was stored, to offer to local subscribers
+)
+
+// backendEvent is published on redisEventsChannel by the instance making a
+// change, so every other instance can mirror it
+type backendEvent struct {
+	Kind     string          `json:"kind"`
+	Instance string          `json:"instance"`
+	Actor    string          `json:"actor,omitempty"`
+	Topic    *persistedTopic `json:"topic,omitempty"` // eventTopic
+	Name     string          `json:"name,omitempty"`  // eventDeleted and eventMessage
+	Reason   string          `json:"reason,omitempty"`
+	Message  json.RawMessage `json:"message,omitempty"`
+}
+
+type remoteContextKey struct{}
+
+// withRemote marks ctx as applying a change another instance already made
+// to the backend
+func withRemote(ctx context.Context) context.Context {
+	return context.WithValue(ctx, remoteContextKey{}, true)
+}
+
+// isRemote reports whether ctx was marked by withRemote
+func isRemote(ctx context.Context) bool {
+	remote, _ := ctx.Value(remoteContextKey{}).(bool)
+	return remote
+}
+
+// openBackend sets up the topic catalog for Config.Backend, connecting to
+// Redis for BackendRedis
+func (s *service) openBackend() error {
+	switch s.config.Backend {
+	case "", BackendMemory:
+		if s.config.SnapshotFile != "" && s.config.DataDir != "" {
+			return fmt.Errorf("invalid snapshot: SnapshotFile cannot be used with DataDir, which persists topics already")
+		}
+		if s.config.DataDir != "" {
+			s.catalog = &fileCatalog{dir: s.config.DataDir}
+		}
+		if s.config.WALDir != "" {
+			if s.config.SnapshotFile == "" {
+				return fmt.Errorf("invalid wal: WALDir requires SnapshotFile, which checkpoints it")
+			}
+			wal, err := openWAL(s.config.WALDir, s.config.WALSegmentSize, s.config.WALSync)
+			if err != nil {
+				return err
+			}
+			s.wal = wal
+		}
+		return nil
+	case BackendRedis:
+		if s.config.DataDir != "" {
+			return fmt.Errorf("invalid backend: DataDir cannot be used with the %s backend", BackendRedis)
+		}
+		if s.config.SnapshotFile != "" {
+			return fmt.Errorf("invalid snapshot: SnapshotFile cannot be used with the %s backend", BackendRedis)
+		}
+		if s.config.WALDir != "" {
+			return fmt.Errorf("invalid wal: WALDir cannot be used with the %s backend", BackendRedis)
+		}
+		url := s.config.RedisURL
+		if url == "" {
+			url = DefaultRedisURL
+		}
+		client, err := newRedisClient(url)
+		if err != nil {
+			return err
+		}
+		s.redis = client
+		s.catalog = &redisCatalog{client: client}
+		return nil
+	default:
+		return fmt.Errorf("invalid backend %q: must be %q or %q", s.config.Backend, BackendMemory, BackendRedis)
+	}
+}
+
+// redisCatalog keeps every topic's settings in one Redis hash shared by all
+// instances
+type redisCatalog struct {
+	client *redis.Client
+}
+
+func (c *redisCatalog) create(topic persistedTopic) (bool, error) {
+	data, err := json.Marshal(topic)
+	if err != nil {
+		return false, err
+	}
+	return c.client.HSetNX(context.Background(), redisTopicsKey, topic.Name, data).Result()
+}
+
+func (c *redisCatalog) save(topic persistedTopic) error {
+	data, err := json.Marshal(topic)
+	if err != nil {
+		return err
+	}
+	return c.client.HSet(context.Background(), redisTopicsKey, topic.Name, data).Err()
+}
+
+// remove deletes a topic with its messages and sequence counter at once, so
+// a topic created later under the same name starts empty
+func (c *redisCatalog) remove(name string) error {
+	ctx := context.Background()
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, redisTopicsKey, name)
+		pipe.Del(ctx, redisMessagesKey+name, redisSeqKey+name)
+		return nil
+	})
+	return err
+}
+
+func (c *redisCatalog) load() ([]persistedTopic, error) {
+	fields, err := c.client.HGetAll(context.Background(), redisTopicsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topics from redis: %w", err)
+	}
+
+	var topics []persistedTopic
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		var topic persistedTopic
+		if err := json.Unmarshal([]byte(fields[name]), &topic); err != nil {
+			logging.WithContext(context.Background()).Errorw("Failed to read topic from redis", "topic", name, "error", err)
+			continue
+		}
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
+
+// announce publishes an event to the other instances, if topics are shared
+// through Redis
+func (s *service) announce(ctx context.Context, event *backendEvent) {
+	if s.redis == nil {
+		return
+	}
+
+	event.Instance = s.instanceID
+	event.Actor = actorFromContext(ctx)
+	data, err := json.Marshal(event)
+	if err == nil {
+		err = s.redis.Publish(ctx, redisEventsChannel, data).Err()
+	}
+	if err != nil {
+		logging.WithContext(ctx).Errorw("Failed to announce topic event", "kind", event.Kind, "error", err)
+	}
+}
+
+// broadcast announces a stored message, so other instances offer it to
+// their subscribers
+func (s *service) broadcast(ctx context.Context, topic *Topic, message *Message) {
+	if s.redis == nil {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		logging.WithContext(ctx).Errorw("Failed to encode message for other instances", "topic", topic.Name, "error", err)
+		return
+	}
+	s.announce(ctx, &backendEvent{Kind: eventMessage, Name: topic.Name, Message: data})
+}
+
+// watchEvents mirrors the changes other instances announce until the
+// service stops
+func (s *service) watchEvents(ctx context.Context) {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	go func() {
+		select {
+		case <-s.shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	log := logging.WithContext(ctx)
+	subscribeRedis(ctx, s.redis, redisEventsChannel,
+		func(payload string) {
+			var event backendEvent
+			if err := json.Unmarshal([]byte(payload), &event); err != nil {
+				log.Warnw("Ignoring malformed topic event", "error", err)
+				return
+			}
+			if event.Instance != s.instanceID {
+				s.applyEvent(ctx, &event)
+			}
+		},
+		func(err error) {
+			log.Warnw("Lost topic event subscription, resubscribing", "error", err)
+		})
+}
+
+// applyEvent mirrors another instance's change. Nothing is written back to
+// Redis, which already holds the change.
+func (s *service) applyEvent(ctx context.Context, event *backendEvent) {
+	log := logging.WithContext(ctx)
+	ctx = withRemote(WithActor(ctx, event.Actor))
+
+	switch event.Kind {
+	case eventTopic:
+		if event.Topic == nil {
+			return
+		}
+		if err := s.mirrorTopic(*event.Topic); err != nil {
+			log.Errorw("Failed to mirror topic", "topic", event.Topic.Name, "error", err)
+		}
+	case eventDeleted:
+		if err := s.deleteTopic(ctx, event.Name, event.Reason); err == nil {
+			log.Infow("Mirrored topic deletion", "topic", event.Name, "actor", event.Actor)
+		}
+	case eventMessage:
+		message, err := decodeStoredMessage(event.Message)
+		if err != nil {
+			log.Warnw("Ignoring malformed message event", "topic", event.Name, "error", err)
+			return
+		}
+
+		s.mu.RLock()
+		topic, exists := s.topics[event.Name]
+		s.mu.RUnlock()
+		if exists {
+			topic.bufferPartition(message)
+			topic.indexText(message)
+			topic.compact(message)
+			s.offer(ctx, topic, message)
+		}
+	}
+}
+
+// mirrorTopic adds a topic another instance created, or applies the
+// settings another instance gave it
+func (s *service) mirrorTopic(record persistedTopic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic, exists := s.topics[record.Name]
+	if !exists {
+		topic, err := s.restoreTopic(record, nil)
+		if err != nil {
+			return err
+		}
+		s.topics[record.Name] = topic
+		s.emitStats(StatsDelta{Kind: StatsTopicAdded, Topic: record.Name})
+		return nil
+	}
+
+	topic.mu.Lock()
+	s.applySettings(topic, record.Settings)
+	topic.mu.Unlock()
+	return nil
+}
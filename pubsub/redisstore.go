@@ -0,0 +1,227 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis key layout. Topic names end the keys, so names containing ":"
+// cannot collide.
+const (
+	redisTopicsKey     = "pubsub:topics"    // hash: topic name -> persistedTopic JSON
+	redisMessagesKey   = "pubsub:messages:" // + topic: sorted set of message JSON scored by seq
+	redisSeqKey        = "pubsub:seq:"      // + topic: newest sequence number stamped
+	redisEventsChannel = "pubsub:events"    // topic and message events between instances
+)
+
+// redisStore is a MessageStore keeping a topic's messages in a Redis sorted
+// set scored by sequence number, shared by every gateway instance using the
+// same Redis. Sequence numbers come from INCR, so instances publishing to
+// the same topic never reuse one. Header indexes are kept per instance and
+// looked up by scanning the stored messages.
+type redisStore struct {
+	client  *redis.Client
+	topic   string
+	size    int
+	indexes []string
+	mu      sync.RWMutex // guards indexes
+}
+
+// newRedisStore returns the store of a topic; it does not touch Redis
+func newRedisStore(client *redis.Client, topic string, size int) *redisStore {
+	return &redisStore{client: client, topic: topic, size: size}
+}
+
+// Append stamps a message with the next sequence number, stores it and
+// trims the set to the newest size messages
+func (rs *redisStore) Append(msg *Message) error {
+	ctx := context.Background()
+
+	seq, err := rs.client.Incr(ctx, redisSeqKey+rs.topic).Result()
+	if err != nil {
+		return err
+	}
+	msg.Seq = uint64(seq)
+	if msg.Origin != "" && msg.OriginSeq == 0 {
+		msg.OriginSeq = msg.Seq
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := rs.client.ZAdd(ctx, redisMessagesKey+rs.topic, redis.Z{Score: float64(msg.Seq), Member: data}).Err(); err != nil {
+		return err
+	}
+	if err := rs.client.ZRemRangeByRank(ctx, redisMessagesKey+rs.topic, 0, int64(-rs.size-1)).Err(); err != nil {
+		rs.logError("trim", err)
+	}
+
+	return nil
+}
+
+// GetLastN returns the newest n messages, oldest first
+func (rs *redisStore) GetLastN(n int) []*Message {
+	if n <= 0 {
+		return []*Message{}
+	}
+	return rs.decodeMessages(rs.client.ZRange(context.Background(), redisMessagesKey+rs.topic, int64(-n), -1).Result())
+}
+
+// GetSince returns up to max messages with a sequence number greater than
+// seq, oldest first
+func (rs *redisStore) GetSince(seq uint64, max int) []*Message {
+	if max <= 0 {
+		return []*Message{}
+	}
+	return rs.decodeMessages(rs.client.ZRangeByScore(context.Background(), redisMessagesKey+rs.topic, &redis.ZRangeBy{
+		Min:   "(" + strconv.FormatUint(seq, 10),
+		Max:   "+inf",
+		Count: int64(max),
+	}).Result())
+}
+
+// GetSinceTime returns the messages stamped at or after since, oldest first.
+// Messages are scored by sequence number, so every message is read.
+func (rs *redisStore) GetSinceTime(since time.Time) []*Message {
+	stored := rs.GetMessages()
+	messages := make([]*Message, 0, len(stored))
+	for _, msg := range stored {
+		if !msg.Timestamp.Before(since) {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// Remove drops the message with the given ID, returning it, or nil if it is
+// not stored
+func (rs *redisStore) Remove(id string) *Message {
+	ctx := context.Background()
+
+	members, err := rs.client.ZRange(ctx, redisMessagesKey+rs.topic, 0, -1).Result()
+	if err != nil {
+		rs.logError("remove", err)
+		return nil
+	}
+
+	for _, data := range members {
+		msg, err := decodeStoredMessage([]byte(data))
+		if err != nil || msg.ID != id {
+			continue
+		}
+		if err := rs.client.ZRem(ctx, redisMessagesKey+rs.topic, data).Err(); err != nil {
+			rs.logError("remove", err)
+			return nil
+		}
+		return msg
+	}
+
+	return nil
+}
+
+// LastSeq returns the newest sequence number stamped by any instance
+func (rs *redisStore) LastSeq() uint64 {
+	seq, err := rs.client.Get(context.Background(), redisSeqKey+rs.topic).Uint64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		rs.logError("read sequence", err)
+	}
+	return seq
+}
+
+// Count returns the number of messages stored
+func (rs *redisStore) Count() int {
+	count, err := rs.client.ZCard(context.Background(), redisMessagesKey+rs.topic).Result()
+	if err != nil {
+		rs.logError("count", err)
+		return 0
+	}
+	return int(count)
+}
+
+// GetMessages returns every stored message, oldest first
+func (rs *redisStore) GetMessages() []*Message {
+	return rs.decodeMessages(rs.client.ZRange(context.Background(), redisMessagesKey+rs.topic, 0, -1).Result())
+}
+
+// SetIndexes replaces the header keys Lookup can search by
+func (rs *redisStore) SetIndexes(keys []string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.indexes = slices.Sorted(slices.Values(keys))
+}
+
+// Indexes returns the indexed header keys, sorted
+func (rs *redisStore) Indexes() []string {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return slices.Clone(rs.indexes)
+}
+
+// Lookup returns up to max of the newest messages carrying every one of the
+// headers, oldest first. It fails if any of the header keys is not indexed.
+func (rs *redisStore) Lookup(headers map[string]string, max int) ([]*Message, error) {
+	indexes := rs.Indexes()
+	for key := range headers {
+		if !slices.Contains(indexes, key) {
+			return nil, fmt.Errorf("invalid header lookup: header %s is not indexed", key)
+		}
+	}
+
+	stored := rs.GetMessages()
+	messages := make([]*Message, 0, min(len(stored), max))
+	for i := len(stored) - 1; i >= 0 && len(messages) < max; i-- {
+		if carriesHeaders(stored[i], headers) {
+			messages = append(messages, stored[i])
+		}
+	}
+	slices.Reverse(messages)
+
+	return messages, nil
+}
+
+// decodeMessages decodes the members a range command returned
+func (rs *redisStore) decodeMessages(members []string, err error) []*Message {
+	if err != nil {
+		rs.logError("read", err)
+		return []*Message{}
+	}
+
+	messages := make([]*Message, 0, len(members))
+	for _, data := range members {
+		msg, err := decodeStoredMessage([]byte(data))
+		if err != nil {
+			rs.logError("decode", err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func (rs *redisStore) logError(op string, err error) {
+	logging.WithContext(context.Background()).Errorw("Redis message store "+op+" failed", "topic", rs.topic, "error", err)
+}
+
+// decodeStoredMessage decodes a message written by a store, keeping payload
+// numbers exact as publishes do by default
+func decodeStoredMessage(data []byte) (*Message, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var msg Message
+	if err := decoder.Decode(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
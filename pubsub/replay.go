@@ -0,0 +1,124 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"golang.org/x/time/rate"
+)
+
+// replayPollInterval is how often a paused replay checks whether the
+// subscriber has drained its queue
+const replayPollInterval = 10 * time.Millisecond
+
+// SetReplayRate sets the maximum rate, in messages per second, at which
+// historical messages are replayed to each subscriber of a topic. Zero
+// restores the service default.
+func (s *service) SetReplayRate(ctx context.Context, topicName string, replayRate float64) error {
+	if replayRate < 0 {
+		return fmt.Errorf("invalid replay rate: %v must not be negative", replayRate)
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.ReplayRate = replayRate
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic replay rate", "topic", topicName, "rate", replayRate)
+	return nil
+}
+
+// replayRate returns the topic's replay rate, falling back to the service
+// default. Caller must hold topic.mu.
+func (s *service) replayRate(topic *Topic) float64 {
+	if topic.ReplayRate > 0 {
+		return topic.ReplayRate
+	}
+	return s.config.MaxReplayRate
+}
+
+// replay feeds historical messages into a subscriber's queue. Credits are
+// the free half of the queue: the replay only enqueues while the queue is
+// less than half full, so it advances as fast as the client's writes
+// drain the queue and leaves the other half for live messages. It is
+// further capped at maxRate messages per second (0 means uncapped). The
+// replay stops when the subscriber unsubscribes or the service shuts down.
+func (s *service) replay(ctx context.Context, topic *Topic, subscriber *Subscriber, messages []*Message, maxRate float64) {
+	log := logging.WithContext(ctx)
+
+	credits := max(cap(subscriber.MessageChan)/2, 1)
+
+	var limiter *rate.Limiter
+	if maxRate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(maxRate), 1)
+	}
+
+	for i, msg := range messages {
+		if limiter != nil {
+			if !s.sleep(limiter.Reserve().Delay()) {
+				return
+			}
+		}
+
+		for {
+			sent, subscribed := s.enqueueReplay(topic, subscriber, msg, credits)
+			if !subscribed {
+				log.Infow("Replay stopped, subscriber left",
+					"client_id", subscriber.ClientID, "topic", topic.Name, "replayed", i)
+				return
+			}
+			if sent {
+				break
+			}
+			if !s.sleep(replayPollInterval) {
+				return
+			}
+		}
+	}
+
+	log.Infow("Replay finished", "client_id", subscriber.ClientID, "topic", topic.Name, "replayed", len(messages))
+}
+
+// enqueueReplay enqueues one replayed message if the subscriber has
+// credit. The subscriber's delivery guard keeps the send safe from
+// Unsubscribe or DeleteTopic closing the channel.
+func (s *service) enqueueReplay(topic *Topic, subscriber *Subscriber, msg *Message, credits int) (sent, subscribed bool) {
+	if len(subscriber.MessageChan) >= credits {
+		return false, subscriber.CloseReason() == ""
+	}
+
+	switch s.tryEnqueue(subscriber, msg, 0, false) {
+	case sendOK:
+		return true, true
+	case sendClosed:
+		return false, false
+	}
+	return false, true
+}
+
+// sleep waits for d, returning false if the service shuts down first
+func (s *service) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.shutdown:
+		return false
+	}
+}
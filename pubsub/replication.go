@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Replicate publishes a message received from another region. The message
+// keeps its Origin and OriginSeq and gets a local Seq. Messages at or below
+// the highest OriginSeq already replicated from the same origin are
+// duplicates and are skipped, so replaying a batch is harmless. Replicated
+// tombstones remove the deleted message from the local buffer too.
+// Replicated messages are not routed: the origin region routes them, and
+// its routed copies are replicated in turn.
+//
+// It reports whether the message was published.
+func (s *service) Replicate(ctx context.Context, topicName string, message Message) (bool, error) {
+	if message.Origin == "" || message.OriginSeq == 0 {
+		return false, fmt.Errorf("invalid replicated message: origin and origin_seq are required")
+	}
+	if message.Origin == s.config.Region {
+		return false, fmt.Errorf("invalid replicated message: origin %s is this region", message.Origin)
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return false, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	if topic.origins == nil {
+		topic.origins = make(map[string]uint64)
+	}
+	if message.OriginSeq <= topic.origins[message.Origin] {
+		topic.mu.Unlock()
+		return false, nil
+	}
+	topic.origins[message.Origin] = message.OriginSeq
+	topic.mu.Unlock()
+
+	if message.Tombstone != "" {
+		topic.Messages.Remove(message.Tombstone)
+	}
+
+	if err := s.publish(ctx, topicName, message.Clone(), map[string]bool{}); err != nil {
+		return false, err
+	}
+
+	logging.WithContext(ctx).Debugw("Replicated message", "topic", topicName, "message_id", message.ID,
+		"origin", message.Origin, "origin_seq", message.OriginSeq)
+	return true, nil
+}
@@ -0,0 +1,119 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// RetentionSweepInterval is how often messages are checked against their
+// topic's retention, so a message outlives its max age by at most this long
+const RetentionSweepInterval = time.Second
+
+// SetRetention sets how long and how many messages a topic keeps. Messages
+// beyond either limit are evicted by a background sweep; a zero Retention
+// keeps messages until the buffer wraps.
+func (s *service) SetRetention(ctx context.Context, topicName string, retention Retention) error {
+	if err := retention.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	topic.Retention = retention
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic retention", "topic", topicName,
+		"max_age_sec", retention.MaxAgeSec, "max_count", retention.MaxCount)
+	return nil
+}
+
+// enforceRetention evicts messages beyond their topic's retention every
+// RetentionSweepInterval until the service shuts down
+func (s *service) enforceRetention(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(RetentionSweepInterval)
+	defer ticker.Stop()
+
+	var sweeping atomic.Bool
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case now := <-ticker.C():
+			s.sweep(ctx, &sweeping, "retention", func() {
+				s.mu.RLock()
+				topics := make([]*Topic, 0, len(s.topics))
+				for _, topic := range s.topics {
+					topics = append(topics, topic)
+				}
+				s.mu.RUnlock()
+
+				for _, topic := range topics {
+					if evicted := s.evictMessages(ctx, topic, now); evicted > 0 {
+						logging.WithContext(ctx).Debugw("Evicted messages past retention", "topic", topic.Name, "evicted", evicted)
+					}
+				}
+			})
+		}
+	}
+}
+
+// evictMessages removes a topic's messages older than its max age or
+// beyond its max count, returning how many were removed. Evicted messages
+// are gone like those dropped when the buffer wraps: no tombstones are
+// published, but removals are logged to the WAL so a replay does not bring
+// them back. Topics under legal hold are skipped.
+func (s *service) evictMessages(ctx context.Context, topic *Topic, now time.Time) int {
+	topic.mu.RLock()
+	retention := topic.Retention
+	held := topic.LegalHold != nil
+	topic.mu.RUnlock()
+
+	if !retention.Enabled() || held {
+		return 0
+	}
+
+	messages := topic.Messages.GetMessages()
+	excess := 0
+	if retention.MaxCount > 0 && len(messages) > retention.MaxCount {
+		excess = len(messages) - retention.MaxCount
+	}
+	cutoff := now.Add(-time.Duration(retention.MaxAgeSec) * time.Second)
+
+	// Replicated messages keep their origin timestamps, so every message is
+	// checked for age rather than stopping at the first recent one
+	evicted := 0
+	for i, msg := range messages {
+		if i >= excess && (retention.MaxAgeSec == 0 || !msg.Timestamp.Before(cutoff)) {
+			continue
+		}
+		if removed := topic.Messages.Remove(msg.ID); removed != nil {
+			topic.unbufferPartition(removed)
+			topic.unindexText(removed)
+			if err := s.logWAL(walRecord{Removed: removed.Seq, Topic: topic.Name}); err != nil {
+				logging.WithContext(ctx).Errorw("Failed to log message eviction", "topic", topic.Name, "message_id", removed.ID, "error", err)
+			}
+			evicted++
+		}
+	}
+	if retention.MaxAgeSec > 0 {
+		topic.evictPartitions(cutoff)
+		topic.evictText(cutoff)
+	}
+	topic.evicted.Add(uint64(evicted))
+
+	return evicted
+}
@@ -0,0 +1,56 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestEvictionReplayedFromWAL restarts a service without stopping it, as
+// after a crash, so its messages come back from the WAL alone: those
+// retention evicted must stay evicted
+func TestEvictionReplayedFromWAL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clock := NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	config := DefaultConfig()
+	config.Clock = clock
+	config.SnapshotFile = filepath.Join(dir, "snapshot.json")
+	config.WALDir = filepath.Join(dir, "wal")
+
+	s := newService(config)
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Stop(ctx) })
+
+	if err := s.CreateTopic(ctx, "orders", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		if err := s.Publish(ctx, "orders", Message{ID: fmt.Sprint("m", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SetRetention(ctx, "orders", Retention{MaxCount: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if evicted := s.evictMessages(ctx, s.topics["orders"], clock.Now()); evicted != 2 {
+		t.Fatalf("evicted %d messages, want 2", evicted)
+	}
+
+	restarted := newService(config)
+	if err := restarted.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { restarted.Stop(ctx) })
+
+	topic, exists := restarted.topics["orders"]
+	if !exists {
+		t.Fatal("topic not replayed from the wal")
+	}
+	assertSeqs(t, "replayed messages", topic.Messages.GetMessages(), 3, 4)
+}
@@ -0,0 +1,190 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// AddSchedule registers a cron-driven publisher on a topic
+func (s *service) AddSchedule(ctx context.Context, topicName string, schedule *Schedule) (*Schedule, error) {
+	log := logging.WithContext(ctx)
+
+	if schedule.Cron == "" {
+		return nil, fmt.Errorf("invalid schedule: cron is required")
+	}
+	cronSchedule, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: cron: %w", err)
+	}
+
+	tmpl, err := template.New("payload").Parse(schedule.PayloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: payload_template: %w", err)
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	created := &Schedule{
+		ID:              uuid.New().String(),
+		Cron:            schedule.Cron,
+		PayloadTemplate: schedule.PayloadTemplate,
+		CreatedAt:       s.clock.Now(),
+		template:        tmpl,
+	}
+
+	// Render once up front so a template that never yields JSON is rejected
+	if _, err := renderSchedulePayload(created, ScheduleData{Topic: topicName, ScheduleID: created.ID, Run: 1, Now: s.clock.Now()}, Decoding{}); err != nil {
+		return nil, err
+	}
+
+	topic.mu.Lock()
+	created.entryID = s.scheduler.Schedule(cronSchedule, cron.FuncJob(func() {
+		s.runSchedule(topic, created)
+	}))
+	topic.Schedules = append(topic.Schedules, created)
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	log.Info("Added schedule", "topic", topicName, "schedule_id", created.ID, "cron", created.Cron)
+	return created, nil
+}
+
+// ListSchedules returns the schedules of a topic
+func (s *service) ListSchedules(ctx context.Context, topicName string) ([]Schedule, error) {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+
+	schedules := make([]Schedule, 0, len(topic.Schedules))
+	for _, schedule := range topic.Schedules {
+		schedules = append(schedules, *schedule)
+	}
+
+	return schedules, nil
+}
+
+// DeleteSchedule removes a schedule from a topic
+func (s *service) DeleteSchedule(ctx context.Context, topicName, scheduleID string) error {
+	log := logging.WithContext(ctx)
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	for i, schedule := range topic.Schedules {
+		if schedule.ID == scheduleID {
+			s.scheduler.Remove(schedule.entryID)
+			topic.Schedules = append(topic.Schedules[:i], topic.Schedules[i+1:]...)
+			topic.mu.Unlock()
+			s.recordConfigured(ctx, topic)
+			log.Info("Deleted schedule", "topic", topicName, "schedule_id", scheduleID)
+			return nil
+		}
+	}
+	topic.mu.Unlock()
+
+	return fmt.Errorf("schedule %s not found", scheduleID)
+}
+
+// removeSchedules unregisters all schedules of a topic. Caller must hold topic.mu.
+func (s *service) removeSchedules(topic *Topic) {
+	for _, schedule := range topic.Schedules {
+		s.scheduler.Remove(schedule.entryID)
+	}
+	topic.Schedules = nil
+}
+
+// restoreSchedule registers a persisted schedule on a topic being restored.
+// Run counters start again from zero.
+func (s *service) restoreSchedule(topic *Topic, schedule Schedule) error {
+	cronSchedule, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return fmt.Errorf("invalid schedule: cron: %w", err)
+	}
+	tmpl, err := template.New("payload").Parse(schedule.PayloadTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid schedule: payload_template: %w", err)
+	}
+
+	restored := &Schedule{
+		ID:              schedule.ID,
+		Cron:            schedule.Cron,
+		PayloadTemplate: schedule.PayloadTemplate,
+		CreatedAt:       schedule.CreatedAt,
+		template:        tmpl,
+	}
+	restored.entryID = s.scheduler.Schedule(cronSchedule, cron.FuncJob(func() {
+		s.runSchedule(topic, restored)
+	}))
+	topic.Schedules = append(topic.Schedules, restored)
+
+	return nil
+}
+
+// runSchedule renders and publishes one scheduled message
+func (s *service) runSchedule(topic *Topic, schedule *Schedule) {
+	ctx := context.Background()
+	log := logging.WithContext(ctx)
+
+	topic.mu.Lock()
+	schedule.Runs++
+	schedule.LastRun = s.clock.Now()
+	data := ScheduleData{
+		Topic:      topic.Name,
+		ScheduleID: schedule.ID,
+		Run:        schedule.Runs,
+		Now:        schedule.LastRun,
+	}
+	decoding := topic.Decoding
+	topic.mu.Unlock()
+
+	payload, err := renderSchedulePayload(schedule, data, decoding)
+	if err != nil {
+		log.Errorw("Failed to render scheduled payload", "error", err, "topic", topic.Name, "schedule_id", schedule.ID)
+		return
+	}
+
+	if err := s.Publish(ctx, topic.Name, Message{Payload: payload}); err != nil {
+		log.Errorw("Failed to publish scheduled message", "error", err, "topic", topic.Name, "schedule_id", schedule.ID)
+	}
+}
+
+// renderSchedulePayload executes the payload template and decodes the JSON
+// result with the topic's number mode
+func renderSchedulePayload(schedule *Schedule, data ScheduleData, decoding Decoding) (interface{}, error) {
+	var buf bytes.Buffer
+	if err := schedule.template.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("invalid schedule: payload_template: %w", err)
+	}
+
+	var payload interface{}
+	if err := (Decoding{Numbers: decoding.Numbers}).Unmarshal(buf.Bytes(), &payload); err != nil {
+		return nil, fmt.Errorf("invalid schedule: payload_template must render valid JSON: %w", err)
+	}
+
+	return payload, nil
+}
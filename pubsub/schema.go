@@ -0,0 +1,287 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Types of schema fields
+const (
+	FieldString  = "string"
+	FieldNumber  = "number"
+	FieldBoolean = "boolean"
+	FieldObject  = "object"
+	FieldArray   = "array"
+	FieldAny     = "any"
+)
+
+// Compatibility modes checked when a topic's schema changes
+const (
+	// CompatibilityBackward lets consumers on the new version read messages
+	// published with the previous one: new fields must be optional
+	CompatibilityBackward = "backward"
+	// CompatibilityForward lets consumers still on the previous version read
+	// messages published with the new one: required fields must stay
+	CompatibilityForward = "forward"
+	// CompatibilityFull requires both
+	CompatibilityFull = "full"
+	// CompatibilityNone accepts any change
+	CompatibilityNone = "none"
+)
+
+// MaxSchemaFields bounds the top-level fields a schema can declare
+const MaxSchemaFields = 256
+
+// SchemaField is a top-level payload field
+type SchemaField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // one of the Field types; FieldAny accepts any value
+	Required bool   `json:"required,omitempty"`
+}
+
+// Schema is one version of the shape of a topic's payloads, which must be
+// JSON objects
+type Schema struct {
+	Version   int           `json:"version"`
+	Fields    []SchemaField `json:"fields"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// Schemas are a topic's schema versions, oldest first, and the
+// compatibility each new version is checked for against the latest
+type Schemas struct {
+	Compatibility string   `json:"compatibility,omitempty"` // empty is CompatibilityBackward
+	Versions      []Schema `json:"versions,omitempty"`
+}
+
+// Latest returns the newest schema, or nil when there is none
+func (s *Schemas) Latest() *Schema {
+	if len(s.Versions) == 0 {
+		return nil
+	}
+	return &s.Versions[len(s.Versions)-1]
+}
+
+// compatibilityOrDefault returns the mode in effect for a topic's
+// Compatibility
+func compatibilityOrDefault(mode string) string {
+	if mode == "" {
+		return CompatibilityBackward
+	}
+	return mode
+}
+
+// validateFields checks field names are unique and types known
+func validateFields(fields []SchemaField) error {
+	if len(fields) > MaxSchemaFields {
+		return fmt.Errorf("invalid schema: at most %d fields", MaxSchemaFields)
+	}
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if field.Name == "" {
+			return fmt.Errorf("invalid schema: field name is required")
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("invalid schema: duplicate field %s", field.Name)
+		}
+		seen[field.Name] = true
+
+		switch field.Type {
+		case FieldString, FieldNumber, FieldBoolean, FieldObject, FieldArray, FieldAny:
+		default:
+			return fmt.Errorf("invalid schema: field %s has unknown type %q", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// readable reports why messages written with writer cannot be read by a
+// consumer expecting reader, or nil when they can
+func readable(reader, writer []SchemaField) error {
+	written := make(map[string]SchemaField, len(writer))
+	for _, field := range writer {
+		written[field.Name] = field
+	}
+	for _, field := range reader {
+		other, exists := written[field.Name]
+		switch {
+		case exists && field.Type != other.Type && field.Type != FieldAny && other.Type != FieldAny:
+			return fmt.Errorf("field %s changes type from %s to %s", field.Name, other.Type, field.Type)
+		case field.Required && (!exists || !other.Required):
+			return fmt.Errorf("field %s is required but may be missing", field.Name)
+		}
+	}
+	return nil
+}
+
+// checkCompatibility checks next against previous under mode
+func checkCompatibility(mode string, previous, next *Schema) error {
+	if mode == CompatibilityBackward || mode == CompatibilityFull {
+		if err := readable(next.Fields, previous.Fields); err != nil {
+			return fmt.Errorf("invalid schema: not backward compatible with version %d: %v", previous.Version, err)
+		}
+	}
+	if mode == CompatibilityForward || mode == CompatibilityFull {
+		if err := readable(previous.Fields, next.Fields); err != nil {
+			return fmt.Errorf("invalid schema: not forward compatible with version %d: %v", previous.Version, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks a payload has the schema's required fields and that its
+// declared fields have their types. Other fields are allowed, and null
+// counts as missing.
+func (s *Schema) Validate(payload interface{}) error {
+	object, ok := payload.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid payload: schema version %d expects an object", s.Version)
+	}
+	for _, field := range s.Fields {
+		value, present := object[field.Name]
+		if !present || value == nil {
+			if field.Required {
+				return fmt.Errorf("invalid payload: schema version %d requires field %s", s.Version, field.Name)
+			}
+			continue
+		}
+		if !fieldHasType(value, field.Type) {
+			return fmt.Errorf("invalid payload: schema version %d expects field %s to be %s", s.Version, field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// fieldHasType reports whether a JSON-decoded value has a field type
+func fieldHasType(value interface{}, fieldType string) bool {
+	switch value.(type) {
+	case string:
+		return fieldType == FieldString || fieldType == FieldAny
+	case float64, json.Number:
+		return fieldType == FieldNumber || fieldType == FieldAny
+	case bool:
+		return fieldType == FieldBoolean || fieldType == FieldAny
+	case map[string]interface{}:
+		return fieldType == FieldObject || fieldType == FieldAny
+	case []interface{}:
+		return fieldType == FieldArray || fieldType == FieldAny
+	}
+	return fieldType == FieldAny
+}
+
+// RegisterSchema adds a schema version to a topic after checking it is
+// compatible with the latest under the topic's compatibility mode, which a
+// non-empty compatibility replaces first. Registering the latest fields
+// again returns the latest version unchanged.
+func (s *service) RegisterSchema(ctx context.Context, topicName string, fields []SchemaField, compatibility string) (*Schema, error) {
+	switch compatibility {
+	case "", CompatibilityBackward, CompatibilityForward, CompatibilityFull, CompatibilityNone:
+	default:
+		return nil, fmt.Errorf("invalid schema: compatibility must be %s, %s, %s or %s",
+			CompatibilityBackward, CompatibilityForward, CompatibilityFull, CompatibilityNone)
+	}
+	if err := validateFields(fields); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	mode := topic.Schemas.Compatibility
+	if compatibility != "" {
+		mode = compatibility
+	}
+
+	latest := topic.Schemas.Latest()
+	if latest != nil && reflect.DeepEqual(latest.Fields, fields) {
+		// Only the compatibility may change
+		schema := *latest
+		changed := mode != topic.Schemas.Compatibility
+		topic.Schemas.Compatibility = mode
+		topic.mu.Unlock()
+		if changed {
+			s.recordConfigured(ctx, topic)
+		}
+		return &schema, nil
+	}
+
+	next := Schema{Version: len(topic.Schemas.Versions) + 1, Fields: slices.Clone(fields), CreatedAt: s.clock.Now()}
+	if latest != nil {
+		if err := checkCompatibility(compatibilityOrDefault(mode), latest, &next); err != nil {
+			topic.mu.Unlock()
+			return nil, err
+		}
+	}
+	// Versions is replaced rather than appended to, so publishers can read
+	// it without the lock
+	topic.Schemas = Schemas{
+		Compatibility: mode,
+		Versions:      append(slices.Clone(topic.Schemas.Versions), next),
+	}
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Registered topic schema", "topic", topicName,
+		"version", next.Version, "fields", len(next.Fields), "compatibility", compatibilityOrDefault(mode))
+	return &next, nil
+}
+
+// ListSchemas returns a topic's schema versions
+func (s *service) ListSchemas(ctx context.Context, topicName string) (*Schemas, error) {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+
+	return &Schemas{
+		Compatibility: compatibilityOrDefault(topic.Schemas.Compatibility),
+		Versions:      slices.Clone(topic.Schemas.Versions),
+	}, nil
+}
+
+// applySchema validates a message published to a topic with schemas
+// against the version it names, or the latest, and stamps that version on
+// it. Messages to topics without schemas cannot name a version.
+func (s *service) applySchema(topicName string, message *Message) error {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil // reported by publish
+	}
+
+	topic.mu.RLock()
+	versions := topic.Schemas.Versions
+	topic.mu.RUnlock()
+
+	switch {
+	case len(versions) == 0 && message.SchemaVersion != 0:
+		return fmt.Errorf("invalid schema_version: topic %s has no schemas", topicName)
+	case len(versions) == 0:
+		return nil
+	case message.SchemaVersion == 0:
+		message.SchemaVersion = len(versions)
+	case message.SchemaVersion < 0 || message.SchemaVersion > len(versions):
+		return fmt.Errorf("invalid schema_version: topic %s has no version %d", topicName, message.SchemaVersion)
+	}
+	return versions[message.SchemaVersion-1].Validate(message.Payload)
+}
@@ -3,62 +3,172 @@ package pubsub
 import (
 	"context"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ammysap/plivo-pub-sub/logging"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
 )
 
 // Service interface for external access
 type Service interface {
-	CreateTopic(ctx context.Context, name string) error
+	CreateTopic(ctx context.Context, name, owner string, opts *TopicOptions) error
+	CloneTopic(ctx context.Context, source, target, owner string, withHistory bool) (int, error)
 	DeleteTopic(ctx context.Context, name string) error
 	GetTopic(ctx context.Context, name string) (*Topic, error)
-	ListTopics(ctx context.Context) ([]TopicInfo, error)
-	Subscribe(ctx context.Context, topicName, clientID string, lastN int) (*Subscriber, error)
+	ListTopics(ctx context.Context, prefix string) ([]TopicInfo, error)
+	SetNamespace(ctx context.Context, namespace Namespace) (*Namespace, error)
+	DeleteNamespace(ctx context.Context, prefix string) error
+	ListNamespaces(ctx context.Context, prefix string) ([]Namespace, error)
+	Topology(ctx context.Context) (*Topology, error)
+	TopicLoads(ctx context.Context) ([]TopicLoad, error)
+	RegisterSchema(ctx context.Context, topicName string, fields []SchemaField, compatibility string) (*Schema, error)
+	ListSchemas(ctx context.Context, topicName string) (*Schemas, error)
+	TopicHistory(ctx context.Context, topicName string) ([]HistoryEvent, error)
+	ListClientTopics(ctx context.Context, clientID string) ([]string, error)
+	ListSubscriptionStats(ctx context.Context, clientID string) ([]SubscriptionStats, error)
+	Subscribe(ctx context.Context, topicName, clientID string, opts *SubscribeOptions) (*Subscriber, error)
 	Unsubscribe(ctx context.Context, topicName, clientID string) error
-	Publish(ctx context.Context, topicName string, message *Message) error
+	KickSubscriber(ctx context.Context, topicName, clientID string) error
+	Replicate(ctx context.Context, topicName string, message Message) (bool, error)
+	Publish(ctx context.Context, topicName string, message Message) error
+	OpenPublisher(ctx context.Context, topicName string) (*Publisher, error)
+	OpenSubscriber(ctx context.Context, topicName, clientID string, opts *SubscribeOptions) (*SubscriberHandle, error)
+	DeleteMessage(ctx context.Context, topicName, messageID string) (*Message, error)
+	AddRoute(ctx context.Context, topicName string, route *Route) (*Route, error)
+	ListRoutes(ctx context.Context, topicName string) ([]Route, error)
+	DeleteRoute(ctx context.Context, topicName, routeID string) error
+	Fetch(ctx context.Context, topicName, clientID string, max int) ([]*Message, error)
+	Ack(ctx context.Context, topicName, clientID string, seq uint64) (*CursorInfo, error)
+	SetBackpressure(ctx context.Context, topicName string, backpressure Backpressure) error
+	SetDedup(ctx context.Context, topicName string, dedup Dedup) error
+	PlaceLegalHold(ctx context.Context, topicName, reason string) (*LegalHold, error)
+	ReleaseLegalHold(ctx context.Context, topicName string) error
+	Nack(ctx context.Context, topicName, clientID string, seq uint64, requeue bool) (*NackResult, error)
+	GetCursor(ctx context.Context, topicName, clientID string) (*CursorInfo, error)
+	MarkRead(ctx context.Context, topicName, clientID string, seq uint64) (*ReadMarker, error)
+	GetReadMarker(ctx context.Context, topicName, clientID string) (*ReadMarker, error)
+	SetReplayRate(ctx context.Context, topicName string, rate float64) error
+	SetExpiry(ctx context.Context, topicName string, expiresAt time.Time) error
+	SetRetention(ctx context.Context, topicName string, retention Retention) error
+	SetDecoding(ctx context.Context, topicName string, decoding Decoding) error
+	SetOrdering(ctx context.Context, topicName, ordering string) error
+	SetDelivery(ctx context.Context, topicName, delivery string) error
+	SetMetadata(ctx context.Context, topicName string, metadata Metadata) error
+	SetPartitions(ctx context.Context, topicName string, count int) error
+	GetPartitions(ctx context.Context, topicName string) ([]PartitionInfo, error)
+	SetTextIndex(ctx context.Context, topicName string, size int) error
+	SetCompaction(ctx context.Context, topicName string, compaction Compaction) error
+	SetIngress(ctx context.Context, topicName string, ingress Ingress) error
+	Mirror(ctx context.Context, clientID string, opts *MirrorOptions) (*Mirror, error)
+	Unmirror(ctx context.Context, clientID, namespace string) error
+	SearchMessages(ctx context.Context, query string, topicNames []string, max int) ([]SearchResult, error)
+	GetDecoding(ctx context.Context, topicName string) (Decoding, error)
+	SetHeaderIndexes(ctx context.Context, topicName string, keys []string) error
+	FindMessages(ctx context.Context, topicName string, headers map[string]string, max int) ([]*Message, error)
+	AddSchedule(ctx context.Context, topicName string, schedule *Schedule) (*Schedule, error)
+	ListSchedules(ctx context.Context, topicName string) ([]Schedule, error)
+	DeleteSchedule(ctx context.Context, topicName, scheduleID string) error
 	GetStats(ctx context.Context) (*StatsResponse, error)
+	TaskStats(ctx context.Context) TaskStats
+	WatchStats(ctx context.Context) <-chan StatsDelta
 	GetHealth(ctx context.Context) (*HealthResponse, error)
+	GetReadiness(ctx context.Context) (*ReadinessResponse, error)
 	Start(ctx context.Context) error
 	Stop(ctx context.Context) error
 }
 
 // Singleton instance
 var (
-	instance *service
-	once     sync.Once
+	instance   *service
+	once       sync.Once
+	instanceMu sync.RWMutex // guards instance and once
 )
 
 // service implements the PubSub service with singleton pattern
 type service struct {
 	topics    map[string]*Topic
 	config    *Config
+	clock     Clock
 	startTime time.Time
 	mu        sync.RWMutex
 	shutdown  chan struct{}
 	wg        sync.WaitGroup
+	scheduler *cron.Cron
+	phase     string
+	recovery  *RecoveryProgress
+
+	history   map[string][]HistoryEvent // topic name -> lifecycle events, kept after deletion
+	historyMu sync.Mutex
+
+	namespaces map[string]*Namespace // prefix -> defaults for new topics under it
+
+	catalog    topicCatalog  // nil keeps topics in memory only
+	redis      *redis.Client // set with BackendRedis
+	instanceID string        // tells this instance's backend events from others'
+
+	wal *writeAheadLog // set with Config.WALDir
+
+	statsWatchers statsWatchers
+
+	mirrors mirrors // analytics copies of every message, apart from subscribers
+
+	tasks *taskPool // goroutine budget for writers, replay and sweeps
 }
 
 // InitService initializes the singleton PubSub service
 func InitService(config *Config) *service {
-	once.Do(func() {
-		if config == nil {
-			config = DefaultConfig()
-		}
+	instanceMu.Lock()
+	defer instanceMu.Unlock()
 
-		instance = &service{
-			topics:   make(map[string]*Topic),
-			config:   config,
-			shutdown: make(chan struct{}),
-		}
+	once.Do(func() {
+		instance = newService(config)
 	})
 	return instance
 }
 
+// NewService creates a standalone PubSub service that is not tied to the
+// package singleton, for callers that inject their own instance
+func NewService(config *Config) Service {
+	return newService(config)
+}
+
+func newService(config *Config) *service {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+
+	return &service{
+		topics:    make(map[string]*Topic),
+		config:    config,
+		clock:     clock,
+		shutdown:  make(chan struct{}),
+		scheduler: cron.New(),
+		phase:     PhaseStarting,
+		history:   make(map[string][]HistoryEvent),
+
+		namespaces: make(map[string]*Namespace),
+		instanceID: uuid.New().String(),
+
+		tasks: newTaskPool(config.MaxGoroutines, config.TaskQueueSize),
+	}
+}
+
 // GetService returns the singleton instance
 func GetService() *service {
+	instanceMu.RLock()
+	defer instanceMu.RUnlock()
+
 	if instance == nil {
 		panic("PubSub service not initialized. Call InitService() first.")
 	}
@@ -67,7 +177,51 @@ func GetService() *service {
 
 // Start initializes the service
 func (s *service) Start(ctx context.Context) error {
-	s.startTime = time.Now()
+	s.startTime = s.clock.Now()
+	if err := s.openBackend(); err != nil {
+		return err
+	}
+	if s.config.DataDir != "" {
+		if err := s.loadNamespaces(); err != nil {
+			return err
+		}
+	}
+	if s.catalog != nil {
+		s.setPhase(PhaseRecovering)
+		if err := s.recoverTopics(ctx); err != nil {
+			return err
+		}
+	}
+	if s.config.SnapshotFile != "" {
+		s.setPhase(PhaseRecovering)
+		if err := s.restoreSnapshot(ctx); err != nil {
+			return err
+		}
+	}
+	if s.wal != nil {
+		// Checkpoint the replayed log, so it only covers this run
+		if err := s.writeSnapshot(ctx); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+	if s.redis != nil {
+		s.wg.Add(1)
+		go s.watchEvents(ctx)
+	}
+	s.scheduler.Start()
+	s.wg.Add(1)
+	go s.expireTopics(ctx)
+	s.wg.Add(1)
+	go s.enforceRetention(ctx)
+	if s.config.DataDir != "" {
+		s.wg.Add(1)
+		go s.saveSessions(ctx)
+	}
+	if s.config.SnapshotFile != "" && s.config.SnapshotInterval > 0 {
+		s.wg.Add(1)
+		go s.saveSnapshots(ctx)
+	}
+	s.setPhase(PhaseReady)
 	log := logging.WithContext(ctx)
 	log.Info("PubSub service started")
 	return nil
@@ -77,9 +231,24 @@ func (s *service) Start(ctx context.Context) error {
 func (s *service) Stop(ctx context.Context) error {
 	log := logging.WithContext(ctx)
 	log.Info("Stopping PubSub service...")
+	s.setPhase(PhaseStopping)
 
-	// Signal shutdown
+	// Signal shutdown and let running scheduled publishes finish
 	close(s.shutdown)
+	<-s.scheduler.Stop().Done()
+
+	// Close every subscription so consumers see a shutdown rather than a
+	// dropped connection
+	s.mu.RLock()
+	for _, topic := range s.topics {
+		topic.mu.Lock()
+		for clientID, subscriber := range topic.Subscribers {
+			subscriber.close(CloseReasonShutdown)
+			delete(topic.Subscribers, clientID)
+		}
+		topic.mu.Unlock()
+	}
+	s.mu.RUnlock()
 
 	// Wait for graceful shutdown with timeout
 	done := make(chan struct{})
@@ -88,42 +257,261 @@ func (s *service) Stop(ctx context.Context) error {
 		close(done)
 	}()
 
+	timeout := s.clock.NewTicker(GracefulShutdownTimeout)
+	defer timeout.Stop()
 	select {
 	case <-done:
 		log.Info("PubSub service stopped gracefully")
-	case <-time.After(GracefulShutdownTimeout):
+	case <-timeout.C():
 		log.Warn("PubSub service shutdown timeout exceeded")
 	}
 
+	if s.config.SnapshotFile != "" {
+		if err := s.writeSnapshot(ctx); err != nil {
+			log.Errorw("Failed to write snapshot", "file", s.config.SnapshotFile, "error", err)
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+	// Release the topics' databases, so a restarted service can open them
+	s.mu.RLock()
+	for _, topic := range s.topics {
+		closeStore(ctx, topic)
+	}
+	s.mu.RUnlock()
+	if s.wal != nil {
+		if err := s.wal.close(); err != nil {
+			log.Warnw("Failed to close write-ahead log", "error", err)
+		}
+	}
+
+	if s.redis != nil {
+		if err := s.redis.Close(); err != nil {
+			log.Warnw("Failed to close redis connection", "error", err)
+		}
+	}
+
 	return nil
 }
 
-// CreateTopic creates a new topic
-func (s *service) CreateTopic(ctx context.Context, name string) error {
+// CreateTopic creates a new topic owned by the given user ID, which may be
+// empty for topics created by the system. Options and retention it leaves
+// unset are inherited from its parent namespaces.
+func (s *service) CreateTopic(ctx context.Context, name, owner string, opts *TopicOptions) error {
 	log := logging.WithContext(ctx)
 
+	if err := ValidateTopicName(name); err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &TopicOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, exists := s.topics[name]; exists {
 		return fmt.Errorf("topic %s already exists", name)
 	}
+	if err := s.checkTopicQuota(owner); err != nil {
+		return err
+	}
+	options := *opts
+	retention := s.inheritNamespaces(name, &options)
+
+	messages, err := s.openMessageStore(name, s.ringBufferSize(options), false)
+	if err != nil {
+		return fmt.Errorf("failed to open message store for topic %s: %w", name, err)
+	}
 
 	topic := &Topic{
 		Name:        name,
 		Subscribers: make(map[string]*Subscriber),
-		Messages:    NewRingBuffer(s.config.RingBufferSize),
-		CreatedAt:   time.Now(),
+		Messages:    messages,
+		Cursors:     make(map[string]*Cursor),
+		ReadMarkers: make(map[string]uint64),
+		Owner:       owner,
+		Retention:   retention,
+		Options:     options,
+		CreatedAt:   s.clock.Now(),
 	}
 
+	if err := s.createTopicRecord(ctx, topic); err != nil {
+		closeStore(ctx, topic)
+		return err
+	}
+	s.recordCreated(ctx, topic, "")
 	s.topics[name] = topic
-	log.Info("Created topic", "topic", name)
+	s.emitStats(StatsDelta{Kind: StatsTopicAdded, Topic: name})
+	log.Info("Created topic", "topic", name, "owner", owner)
 
 	return nil
 }
 
+// checkTopicQuota refuses another topic once the service holds
+// Config.MaxTopics, or owner owns Config.MaxTopicsPerOwner. Topics created
+// by the system have no owner and only count towards MaxTopics. Caller
+// must hold s.mu.
+func (s *service) checkTopicQuota(owner string) error {
+	if s.config.MaxTopics > 0 && len(s.topics) >= s.config.MaxTopics {
+		return fmt.Errorf("topic quota exceeded: at most %d topics are allowed", s.config.MaxTopics)
+	}
+	if s.config.MaxTopicsPerOwner == 0 || owner == "" {
+		return nil
+	}
+
+	owned := 0
+	for _, topic := range s.topics {
+		if topic.Owner == owner {
+			owned++
+		}
+	}
+	if owned >= s.config.MaxTopicsPerOwner {
+		return fmt.Errorf("topic quota exceeded: at most %d topics per user are allowed", s.config.MaxTopicsPerOwner)
+	}
+	return nil
+}
+
+// ringBufferSize returns the number of messages a topic with opts holds
+// for replay
+func (s *service) ringBufferSize(opts TopicOptions) int {
+	if opts.RingBufferSize > 0 {
+		return opts.RingBufferSize
+	}
+	return s.config.RingBufferSize
+}
+
+// channelBufferSize returns the number of messages queued for each
+// subscriber of a topic with opts
+func (s *service) channelBufferSize(opts TopicOptions) int {
+	if opts.ChannelBufferSize > 0 {
+		return opts.ChannelBufferSize
+	}
+	return s.config.ChannelBufferSize
+}
+
+// CloneTopic creates target with source's replay rate, decoding, ordering and
+// header indexes and, when withHistory is set, copies of source's buffered
+// messages (tombstones excluded), and returns the number of messages copied. Routes and schedules
+// are not copied, so a clone never publishes into other topics on its own.
+func (s *service) CloneTopic(ctx context.Context, source, target, owner string, withHistory bool) (int, error) {
+	log := logging.WithContext(ctx)
+
+	if err := ValidateTopicName(target); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sourceTopic, exists := s.topics[source]
+	if !exists {
+		return 0, fmt.Errorf("topic %s not found", source)
+	}
+	if _, exists := s.topics[target]; exists {
+		return 0, fmt.Errorf("topic %s already exists", target)
+	}
+	if err := s.checkTopicQuota(owner); err != nil {
+		return 0, err
+	}
+
+	sourceTopic.mu.RLock()
+	description := sourceTopic.Description
+	labels := maps.Clone(sourceTopic.Labels)
+	replayRate := sourceTopic.ReplayRate
+	decoding := sourceTopic.Decoding
+	ordering := sourceTopic.Ordering
+	delivery := sourceTopic.Delivery
+	partitions := sourceTopic.Partitions
+	backpressure := sourceTopic.Backpressure
+	schemas := sourceTopic.Schemas
+	dedup := sourceTopic.Dedup
+	compaction := sourceTopic.Compaction
+	ingress := sourceTopic.Ingress
+	retention := sourceTopic.Retention
+	options := sourceTopic.Options
+	sourceTopic.mu.RUnlock()
+
+	messages, err := s.openMessageStore(target, s.ringBufferSize(options), false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open message store for topic %s: %w", target, err)
+	}
+
+	topic := &Topic{
+		Name:        target,
+		Subscribers: make(map[string]*Subscriber),
+		Messages:    messages,
+		Cursors:     make(map[string]*Cursor),
+		ReadMarkers: make(map[string]uint64),
+		Owner:       owner,
+		Description: description,
+		Labels:      labels,
+		ReplayRate:  replayRate,
+		Decoding:    decoding,
+		Ordering:    ordering,
+		Delivery:    delivery,
+		Partitions:  partitions,
+		Retention:   retention,
+		Options:     options,
+		CreatedAt:   s.clock.Now(),
+
+		Backpressure: backpressure,
+		Schemas:      schemas,
+		Dedup:        dedup,
+	}
+	topic.Messages.SetIndexes(sourceTopic.Messages.Indexes())
+
+	if err := s.createTopicRecord(ctx, topic); err != nil {
+		closeStore(ctx, topic)
+		return 0, err
+	}
+
+	copied := 0
+	if withHistory {
+		for _, msg := range sourceTopic.Messages.GetMessages() {
+			if msg.Tombstone != "" {
+				continue
+			}
+			clone := msg.Clone()
+			clone.Topic = target
+			clone.Origin, clone.OriginSeq = s.config.Region, 0
+			if err := topic.Messages.Append(clone); err != nil {
+				s.dropTopic(ctx, topic, CloseReasonTopicDeleted)
+				return 0, fmt.Errorf("failed to copy message %s: %w", msg.ID, err)
+			}
+			if err := s.logWAL(walRecord{Message: clone}); err != nil {
+				s.dropTopic(ctx, topic, CloseReasonTopicDeleted)
+				return 0, fmt.Errorf("failed to log message %s: %w", msg.ID, err)
+			}
+			copied++
+		}
+	}
+
+	s.setOrdering(topic, ordering)
+	s.setPartitions(topic, partitions)
+	setCompaction(topic, compaction)
+	s.setIngress(topic, ingress)
+	s.recordCreated(ctx, topic, source)
+	s.topics[target] = topic
+	s.emitStats(StatsDelta{Kind: StatsTopicAdded, Topic: target})
+	log.Info("Cloned topic", "source", source, "topic", target, "owner", owner, "messages", copied)
+
+	return copied, nil
+}
+
 // DeleteTopic deletes a topic and disconnects all subscribers
 func (s *service) DeleteTopic(ctx context.Context, name string) error {
+	return s.deleteTopic(ctx, name, CloseReasonTopicDeleted)
+}
+
+// deleteTopic deletes a topic, closing its subscriptions with reason. With
+// CloseReasonTopicExpired the topic is only deleted if its expiry has
+// passed, so a topic re-created or extended since the sweep is kept.
+// Topics under legal hold are kept, unless the deletion is mirrored from
+// the instance that already checked.
+func (s *service) deleteTopic(ctx context.Context, name, reason string) error {
 	log := logging.WithContext(ctx)
 
 	s.mu.Lock()
@@ -136,14 +524,30 @@ func (s *service) DeleteTopic(ctx context.Context, name string) error {
 
 	// Disconnect all subscribers
 	topic.mu.Lock()
+	if reason == CloseReasonTopicExpired && (topic.ExpiresAt.IsZero() || s.clock.Now().Before(topic.ExpiresAt)) {
+		topic.mu.Unlock()
+		return fmt.Errorf("topic %s has not expired", name)
+	}
+	if topic.LegalHold != nil && !isRemote(ctx) {
+		topic.mu.Unlock()
+		return errLegalHold(name)
+	}
 	for clientID, subscriber := range topic.Subscribers {
-		close(subscriber.MessageChan)
+		subscriber.close(reason)
+		delete(topic.Subscribers, clientID)
 		log.Info("Disconnected subscriber", "topic", name, "client_id", clientID)
 	}
+	s.recordDeleted(ctx, topic, reason)
+	s.removeSchedules(topic)
+	stopSequencer(topic)
+	stopPartitions(topic)
+	stopIngress(topic)
 	topic.mu.Unlock()
+	s.dropTopic(ctx, topic, reason)
 
 	delete(s.topics, name)
-	log.Info("Deleted topic", "topic", name)
+	s.emitStats(StatsDelta{Kind: StatsTopicRemoved, Topic: name})
+	log.Info("Deleted topic", "topic", name, "reason", reason)
 
 	return nil
 }
@@ -161,37 +565,215 @@ func (s *service) GetTopic(ctx context.Context, name string) (*Topic, error) {
 	return topic, nil
 }
 
-// ListTopics returns all topics with subscriber counts
-func (s *service) ListTopics(ctx context.Context) ([]TopicInfo, error) {
+// ListTopics returns the topics in the namespace prefix, or all topics when
+// it is empty, with subscriber counts
+func (s *service) ListTopics(ctx context.Context, prefix string) ([]TopicInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := s.clock.Now()
 	topics := make([]TopicInfo, 0, len(s.topics))
 	for name, topic := range s.topics {
+		if !InNamespace(name, prefix) {
+			continue
+		}
 		topic.mu.RLock()
-		subscriberCount := len(topic.Subscribers)
+		info := TopicInfo{
+			Name:        name,
+			Subscribers: len(topic.Subscribers),
+			Owner:       topic.Owner,
+			CreatedAt:   topic.CreatedAt,
+			Description: topic.Description,
+			Labels:      maps.Clone(topic.Labels),
+			Routes:      len(topic.Routes),
+			Schedules:   len(topic.Schedules),
+			ReplayRate:  s.replayRate(topic),
+			Decoding:    topic.Decoding,
+			Ordering:    topic.Ordering,
+			Delivery:    topic.Delivery,
+			Partitions:  topic.Partitions,
+			TextIndex:   topic.TextIndex,
+
+			RingBufferSize:    s.ringBufferSize(topic.Options),
+			ChannelBufferSize: s.channelBufferSize(topic.Options),
+
+			JSONSchema: topic.Options.JSONSchema,
+		}
+		if !topic.ExpiresAt.IsZero() {
+			expiresAt := topic.ExpiresAt
+			info.ExpiresAt = &expiresAt
+		}
+		if topic.Retention.Enabled() {
+			retention := topic.Retention
+			info.Retention = &retention
+		}
+		if topic.Backpressure.Policy != "" {
+			backpressure := topic.Backpressure
+			info.Backpressure = &backpressure
+		}
+		if topic.Dedup.Enabled() {
+			dedup := topic.Dedup
+			info.Dedup = &dedup
+		}
+		if topic.LegalHold != nil {
+			hold := *topic.LegalHold
+			info.LegalHold = &hold
+		}
+		if topic.compacted != nil {
+			compaction := topic.Compaction
+			info.Compaction = &compaction
+			info.CompactedKeys = topic.compacted.keys()
+		}
+		if topic.ingress != nil {
+			ingress := topic.Ingress
+			info.Ingress = &ingress
+			info.IngressQueued = len(topic.ingress.queue)
+		}
 		topic.mu.RUnlock()
 
-		topics = append(topics, TopicInfo{
-			Name:        name,
-			Subscribers: subscriberCount,
-		})
+		info.Duplicates = topic.duplicates.Load()
+		info.Busy = topic.busy.Load()
+		info.Evicted = topic.evicted.Load()
+		info.Messages = topic.Messages.Count()
+		info.HeaderIndexes = topic.Messages.Indexes()
+		info.Published1m = topic.publishes.recent(now)
+		topics = append(topics, info)
 	}
 
 	return topics, nil
 }
 
+// ListClientTopics returns the topics a client has a live subscription or
+// a durable cursor on
+func (s *service) ListClientTopics(ctx context.Context, clientID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for name, topic := range s.topics {
+		topic.mu.RLock()
+		_, subscribed := topic.Subscribers[clientID]
+		_, durable := topic.Cursors[clientID]
+		topic.mu.RUnlock()
+
+		if subscribed || durable {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// ListSubscriptionStats returns delivery statistics for each of a client's
+// subscriptions, sorted by topic. Counters start over when the client
+// resubscribes.
+func (s *service) ListSubscriptionStats(ctx context.Context, clientID string) ([]SubscriptionStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := []SubscriptionStats{}
+	for name, topic := range s.topics {
+		topic.mu.RLock()
+		subscriber, subscribed := topic.Subscribers[clientID]
+		if !subscribed {
+			topic.mu.RUnlock()
+			continue
+		}
+
+		entry := SubscriptionStats{
+			Topic:     name,
+			Durable:   subscriber.Durable,
+			Delivered: subscriber.delivered.Load(),
+			Dropped:   subscriber.dropped.Load(),
+			Backlog:   subscriber.Depth(),
+			LastSeen:  subscriber.LastSeen,
+			ExpiresAt: subscriber.ExpiresAt,
+		}
+		if cursor, tracked := topic.Cursors[clientID]; tracked && subscriber.Durable {
+			if head := topic.Messages.LastSeq(); head > cursor.Delivered {
+				entry.Backlog = int(head - cursor.Delivered)
+			}
+		}
+		topic.mu.RUnlock()
+
+		if at := subscriber.lastDelivery.Load(); at != 0 {
+			lastDelivery := time.Unix(0, at)
+			entry.LastDelivery = &lastDelivery
+		}
+		stats = append(stats, entry)
+	}
+	slices.SortFunc(stats, func(a, b SubscriptionStats) int { return strings.Compare(a.Topic, b.Topic) })
+
+	return stats, nil
+}
+
 // Subscribe adds a client to a topic
-func (s *service) Subscribe(ctx context.Context, topicName, clientID string, lastN int) (*Subscriber, error) {
+func (s *service) Subscribe(ctx context.Context, topicName, clientID string, opts *SubscribeOptions) (*Subscriber, error) {
 	log := logging.WithContext(ctx)
 
+	if err := authorize(ctx, ActionSubscribe, topicName); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	if opts.Sampling != nil {
+		if err := opts.Sampling.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	var tagFilter *TagFilter
+	if opts.TagFilter != nil {
+		if err := opts.TagFilter.Validate(); err != nil {
+			return nil, err
+		}
+		tagFilter = opts.TagFilter.indexed()
+	}
+	if err := opts.Preferences.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.DeadLetter != "" {
+		if opts.Durable {
+			return nil, fmt.Errorf("invalid dead_letter: durable subscriptions do not drop messages")
+		}
+		if opts.DeadLetter == topicName {
+			return nil, fmt.Errorf("invalid dead_letter: must differ from the subscribed topic")
+		}
+	}
+	if opts.MaxDeliveries < 0 {
+		return nil, fmt.Errorf("invalid max_deliveries: must not be negative")
+	}
+	if opts.MaxDeliveries > 0 && opts.Durable {
+		return nil, fmt.Errorf("invalid max_deliveries: durable subscriptions redeliver unacked messages on resubscribe")
+	}
+	if err := opts.Backpressure.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Backpressure.Policy != "" && opts.Durable {
+		return nil, fmt.Errorf("invalid backpressure: durable subscriptions pull at their own pace")
+	}
+	if opts.AfterSeq != nil && (opts.LastN > 0 || !opts.Since.IsZero()) {
+		return nil, fmt.Errorf("invalid after_seq: cannot be combined with last_n or since")
+	}
+	if opts.Partition != nil && opts.Durable {
+		return nil, fmt.Errorf("invalid partition: durable subscriptions read the whole topic")
+	}
+	if opts.TTL < 0 {
+		return nil, fmt.Errorf("invalid ttl: must not be negative")
+	}
+	lastN := opts.LastN
+
 	s.mu.RLock()
 	topic, exists := s.topics[topicName]
+	_, deadLetterExists := s.topics[opts.DeadLetter]
 	s.mu.RUnlock()
 
 	if !exists {
 		return nil, fmt.Errorf("topic %s not found", topicName)
 	}
+	if opts.DeadLetter != "" && !deadLetterExists {
+		return nil, fmt.Errorf("invalid dead_letter: topic %s not found", opts.DeadLetter)
+	}
 
 	topic.mu.Lock()
 	defer topic.mu.Unlock()
@@ -201,40 +783,272 @@ func (s *service) Subscribe(ctx context.Context, topicName, clientID string, las
 		return nil, fmt.Errorf("client %s already subscribed to topic %s", clientID, topicName)
 	}
 
-	// Create subscriber with buffered channel
+	if opts.Durable {
+		return s.subscribeDurable(ctx, topic, clientID, opts, tagFilter), nil
+	}
+	if opts.Partition != nil && (*opts.Partition < 0 || *opts.Partition >= len(topic.partitions)) {
+		return nil, fmt.Errorf("invalid partition: topic %s has %d partitions", topicName, len(topic.partitions))
+	}
+
+	// Create subscriber with buffered channels, one per priority
+	bufferSize := s.channelBufferSize(topic.Options)
 	subscriber := &Subscriber{
 		ClientID:    clientID,
 		TopicName:   topicName,
-		MessageChan: make(chan *Message, s.config.ChannelBufferSize),
-		LastSeen:    time.Now(),
+		MessageChan: make(chan *Message, bufferSize),
+		LastSeen:    s.clock.Now(),
+		Sampling:    opts.Sampling,
+		TagFilter:   tagFilter,
+		DeadLetter:  opts.DeadLetter,
+		Preferences: opts.Preferences.clone(),
+
+		MaxDeliveries: opts.MaxDeliveries,
+		Backpressure:  opts.Backpressure,
+		PriorityChan:  make(chan *Message, bufferSize),
+		UserID:        opts.UserID,
+		ExpiresAt:     opts.expiresAt(s.clock.Now()),
+		clock:         s.clock,
+	}
+	subscriber.Touch()
+	if opts.Partition != nil {
+		index := *opts.Partition
+		subscriber.Partition = &index
+		subscriber.partitions = len(topic.partitions)
 	}
 
 	topic.Subscribers[clientID] = subscriber
+	s.emitSubscribers(topic)
+
+	// Send historical messages if requested, paced to the client's
+	// consumption so the replay does not crowd out live messages
+	var historicalMessages []*Message
+	switch {
+	case opts.AfterSeq != nil:
+		historicalMessages = topic.Messages.GetSince(*opts.AfterSeq, math.MaxInt)
+	case !opts.Since.IsZero():
+		historicalMessages = topic.Messages.GetSinceTime(opts.Since)
+		if lastN > 0 && len(historicalMessages) > lastN {
+			historicalMessages = historicalMessages[len(historicalMessages)-lastN:]
+		}
+	case topic.compacted != nil:
+		// A compacted topic's state may reach further back than its buffer
+		historicalMessages = topic.compacted.latest(lastN)
+	case lastN > 0 && subscriber.Partition != nil:
+		// The partition's own buffer may reach further back than the topic's
+		historicalMessages = topic.partitionLastN(*subscriber.Partition, lastN)
+	case lastN > 0:
+		historicalMessages = topic.Messages.GetLastN(lastN)
+	}
+	if len(historicalMessages) > 0 {
+		historicalMessages = subscriber.filterWanted(historicalMessages)
+		s.wg.Add(1)
+		started := s.tasks.goOrQueue(TaskReplay, func() {
+			defer s.wg.Done()
+			s.replay(ctx, topic, subscriber, historicalMessages, s.replayRate(topic))
+		})
+		if !started {
+			s.wg.Done()
+			delete(topic.Subscribers, clientID)
+			s.emitSubscribers(topic)
+			return nil, errOverloaded("cannot replay history")
+		}
+	}
 
-	// Send historical messages if requested
-	if lastN > 0 {
-		historicalMessages := topic.Messages.GetLastN(lastN)
-		go func() {
-			for _, msg := range historicalMessages {
-				select {
-				case subscriber.MessageChan <- msg:
-				case <-s.shutdown:
-					return
-				default:
-					// Channel is full, drop message (backpressure)
-					log.Warn("Dropped historical message due to full channel",
-						"client_id", clientID, "topic", topicName)
-				}
+	log.Info("Subscribed client to topic", "client_id", clientID, "topic", topicName, "last_n", lastN, "since", opts.Since, "dead_letter", opts.DeadLetter, "max_deliveries", opts.MaxDeliveries)
+	return subscriber, nil
+}
+
+// subscribeDurable registers a pull-based subscriber backed by a cursor.
+// AfterSeq moves the cursor there, new or not, capped at the head. Otherwise
+// an existing cursor is resumed from its last ack; a new one starts LastN
+// messages behind the head, or before the first message stamped at or after
+// Since (the later of the two when both are set). Caller must hold topic.mu.
+func (s *service) subscribeDurable(ctx context.Context, topic *Topic, clientID string, opts *SubscribeOptions, tagFilter *TagFilter) *Subscriber {
+	log := logging.WithContext(ctx)
+
+	cursor, exists := topic.Cursors[clientID]
+	switch {
+	case opts.AfterSeq != nil:
+		start := min(*opts.AfterSeq, topic.Messages.LastSeq())
+		cursor = &Cursor{Acked: start, Delivered: start}
+		topic.Cursors[clientID] = cursor
+		topic.unsaved.Store(true)
+	case exists:
+		cursor.Delivered = cursor.Acked
+	default:
+		head := topic.Messages.LastSeq()
+		start := uint64(0)
+		if uint64(opts.LastN) < head {
+			start = head - uint64(opts.LastN)
+		}
+		if !opts.Since.IsZero() {
+			sinceStart := head
+			if since := topic.Messages.GetSinceTime(opts.Since); len(since) > 0 {
+				sinceStart = since[0].Seq - 1
 			}
-		}()
+			if opts.LastN > 0 {
+				start = max(start, sinceStart)
+			} else {
+				start = sinceStart
+			}
+		}
+		cursor = &Cursor{Acked: start, Delivered: start}
+		topic.Cursors[clientID] = cursor
+		topic.unsaved.Store(true)
 	}
 
-	log.Info("Subscribed client to topic", "client_id", clientID, "topic", topicName, "last_n", lastN)
-	return subscriber, nil
+	subscriber := &Subscriber{
+		ClientID:  clientID,
+		TopicName: topic.Name,
+		LastSeen:  s.clock.Now(),
+		Sampling:  opts.Sampling,
+		TagFilter: tagFilter,
+		Durable:   true,
+		ExpiresAt: opts.expiresAt(s.clock.Now()),
+		clock:     s.clock,
+
+		Preferences: opts.Preferences.clone(),
+	}
+	topic.Subscribers[clientID] = subscriber
+	s.emitSubscribers(topic)
+
+	log.Info("Subscribed durable client to topic",
+		"client_id", clientID, "topic", topic.Name, "cursor", cursor.Acked, "resumed", exists)
+	return subscriber
+}
+
+// Fetch returns up to max messages after a durable subscriber's delivered
+// position and advances that position. Messages stay pending until acked.
+func (s *service) Fetch(ctx context.Context, topicName, clientID string, max int) ([]*Message, error) {
+	if err := authorize(ctx, ActionSubscribe, topicName); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+
+	subscriber, subscribed := topic.Subscribers[clientID]
+	cursor, tracked := topic.Cursors[clientID]
+	if !subscribed || !subscriber.Durable || !tracked {
+		return nil, fmt.Errorf("client %s has no durable subscription to topic %s", clientID, topicName)
+	}
+
+	messages := topic.Messages.GetSince(cursor.Delivered, max)
+	if len(messages) == 0 {
+		return messages, nil
+	}
+	cursor.Delivered = messages[len(messages)-1].Seq
+
+	// Tag filters and sampling still apply to pulled messages; skipped ones
+	// count as delivered
+	messages = subscriber.filterWanted(messages)
+	if subscriber.Sampling != nil {
+		sampled := messages[:0:0]
+		for _, msg := range messages {
+			if subscriber.accepts() {
+				sampled = append(sampled, msg)
+			}
+		}
+		messages = sampled
+	}
+
+	if len(messages) > 0 {
+		subscriber.recordDelivery(len(messages))
+	}
+	subscriber.LastSeen = s.clock.Now()
+	return messages, nil
+}
+
+// Ack advances a durable subscriber's cursor to seq. Acks never move the
+// cursor backwards or past the last delivered message.
+func (s *service) Ack(ctx context.Context, topicName, clientID string, seq uint64) (*CursorInfo, error) {
+	if err := authorize(ctx, ActionSubscribe, topicName); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+
+	cursor, tracked := topic.Cursors[clientID]
+	if !tracked {
+		return nil, fmt.Errorf("client %s has no durable subscription to topic %s", clientID, topicName)
+	}
+
+	if seq > cursor.Delivered {
+		return nil, fmt.Errorf("cannot ack seq %d beyond delivered seq %d", seq, cursor.Delivered)
+	}
+	if seq > cursor.Acked {
+		cursor.Acked = seq
+		topic.unsaved.Store(true)
+	}
+
+	return cursorInfo(clientID, cursor, topic.Messages.LastSeq()), nil
+}
+
+// GetCursor returns a durable subscriber's position and lag
+func (s *service) GetCursor(ctx context.Context, topicName, clientID string) (*CursorInfo, error) {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+
+	cursor, tracked := topic.Cursors[clientID]
+	if !tracked {
+		return nil, fmt.Errorf("client %s has no durable subscription to topic %s", clientID, topicName)
+	}
+
+	return cursorInfo(clientID, cursor, topic.Messages.LastSeq()), nil
+}
+
+// cursorInfo builds a CursorInfo for a cursor against the topic head
+func cursorInfo(clientID string, cursor *Cursor, head uint64) *CursorInfo {
+	info := &CursorInfo{
+		ClientID:  clientID,
+		Acked:     cursor.Acked,
+		Delivered: cursor.Delivered,
+		Head:      head,
+	}
+	if head > cursor.Acked {
+		info.Lag = head - cursor.Acked
+	}
+	return info
 }
 
 // Unsubscribe removes a client from a topic
 func (s *service) Unsubscribe(ctx context.Context, topicName, clientID string) error {
+	return s.removeSubscriber(ctx, topicName, clientID, CloseReasonUnsubscribed)
+}
+
+// KickSubscriber removes a client from a topic on an admin's behalf. The
+// subscription is closed with CloseReasonAdminKick.
+func (s *service) KickSubscriber(ctx context.Context, topicName, clientID string) error {
+	return s.removeSubscriber(ctx, topicName, clientID, CloseReasonAdminKick)
+}
+
+// removeSubscriber closes a client's subscription with the given reason
+func (s *service) removeSubscriber(ctx context.Context, topicName, clientID, reason string) error {
 	log := logging.WithContext(ctx)
 
 	s.mu.RLock()
@@ -253,18 +1067,46 @@ func (s *service) Unsubscribe(ctx context.Context, topicName, clientID string) e
 		return fmt.Errorf("client %s not subscribed to topic %s", clientID, topicName)
 	}
 
-	// Close the message channel
-	close(subscriber.MessageChan)
+	// Close the message channel; durable subscribers keep their cursor
+	subscriber.close(reason)
 	delete(topic.Subscribers, clientID)
+	s.emitSubscribers(topic)
 
-	log.Info("Unsubscribed client from topic", "client_id", clientID, "topic", topicName)
+	log.Info("Unsubscribed client from topic", "client_id", clientID, "topic", topicName, "reason", reason)
 	return nil
 }
 
-// Publish sends a message to all subscribers of a topic
-func (s *service) Publish(ctx context.Context, topicName string, message *Message) error {
+// Publish sends a message to all subscribers of a topic. The message is
+// deep-copied before it is stamped, buffered and fanned out, so later changes
+// to the caller's payload cannot corrupt delivered or buffered history.
+func (s *service) Publish(ctx context.Context, topicName string, message Message) error {
+	if err := authorize(ctx, ActionPublish, topicName); err != nil {
+		return err
+	}
+
+	// Only DeleteMessage issues tombstones, and only Replicate keeps origins
+	message.Tombstone = ""
+	message.Origin, message.OriginSeq = "", 0
+	if err := message.Priority.Validate(); err != nil {
+		return err
+	}
+	if err := s.applySchema(topicName, &message); err != nil {
+		return err
+	}
+	if err := s.applyJSONSchema(topicName, &message); err != nil {
+		return err
+	}
+	return s.publish(ctx, topicName, message.Clone(), map[string]bool{})
+}
+
+// publish delivers a message to a topic and follows its routes. visited holds
+// the topics this message has already been published to, so routing cycles
+// terminate instead of republishing forever.
+func (s *service) publish(ctx context.Context, topicName string, message *Message, visited map[string]bool) error {
 	log := logging.WithContext(ctx)
 
+	visited[topicName] = true
+
 	s.mu.RLock()
 	topic, exists := s.topics[topicName]
 	s.mu.RUnlock()
@@ -273,43 +1115,380 @@ func (s *service) Publish(ctx context.Context, topicName string, message *Messag
 		return fmt.Errorf("topic %s not found", topicName)
 	}
 
-	// Set message metadata
+	// Set message metadata; replicated messages keep their origin timestamp
 	message.Topic = topicName
-	message.Timestamp = time.Now()
+	if message.Origin == "" {
+		message.Origin = s.config.Region
+	}
+	if message.Origin == s.config.Region || message.Timestamp.IsZero() {
+		message.Timestamp = s.clock.Now()
+	}
 	if message.ID == "" {
 		message.ID = uuid.New().String()
 	}
 
-	// Add to ring buffer for replay
-	topic.Messages.Add(message)
+	if !s.claimMessageID(topic, message) {
+		log.Info("Skipped duplicate message", "topic", topicName, "message_id", message.ID)
+		return nil
+	}
+
+	// Local publishes wait in the topic's ingress queue, if it has one;
+	// tombstones and replicated messages are not bursts to smooth
+	if message.Tombstone == "" && message.Origin == s.config.Region {
+		queued, err := s.queueIngress(ctx, topic, message, visited)
+		if err != nil {
+			topic.seen.release(message.ID) // the retry is not a duplicate
+			return err
+		}
+		if queued {
+			return nil
+		}
+	}
+	return s.publishNow(ctx, topic, message, visited)
+}
+
+// publishNow stores a message, offers it to the topic's subscribers and
+// follows the topic's routes
+func (s *service) publishNow(ctx context.Context, topic *Topic, message *Message, visited map[string]bool) error {
+	subscribers, err := s.enqueue(ctx, topic, message)
+	if err != nil {
+		topic.seen.release(message.ID) // the retry is not a duplicate
+		return err
+	}
+
+	logging.WithContext(ctx).Info("Published message to topic", "topic", topic.Name, "message_id", message.ID, "subscribers", subscribers)
+
+	// Replicated messages were routed in their origin region
+	if message.Tombstone == "" && message.Origin == s.config.Region {
+		s.route(ctx, topic, message, visited)
+	}
+	return nil
+}
+
+// fanOut stores a message and offers it to the topic's subscribers,
+// returning how many it was offered to
+func (s *service) fanOut(ctx context.Context, topic *Topic, message *Message) (int, error) {
+	// Store for replay before any subscriber can see it
+	if err := topic.Messages.Append(message); err != nil {
+		return 0, fmt.Errorf("failed to store message %s in topic %s: %w", message.ID, topic.Name, err)
+	}
+	if err := s.logWAL(walRecord{Message: message}); err != nil {
+		topic.Messages.Remove(message.ID)
+		return 0, fmt.Errorf("failed to log message %s in topic %s: %w", message.ID, topic.Name, err)
+	}
+	topic.bufferPartition(message)
+	topic.indexText(message)
+	topic.compact(message)
+	topic.publishes.record(message.Timestamp)
+	s.broadcast(ctx, topic, message)
+
+	return s.offer(ctx, topic, message), nil
+}
+
+// offer offers a stored message to the topic's subscribers on this instance,
+// returning how many it was offered to. The message is pushed to each
+// subscriber's writer before offer returns, so successive calls reach every
+// subscriber in call order, while the writers deliver it concurrently: a
+// publish costs at most one goroutine per subscriber with messages pending,
+// not one per subscriber per message.
+func (s *service) offer(ctx context.Context, topic *Topic, message *Message) int {
+	snapshot := subscriberSnapshots.Get().(*[]*Subscriber)
+	defer releaseSubscriberSnapshot(snapshot)
 
-	// Fan-out to all subscribers
 	topic.mu.RLock()
-	subscribers := make([]*Subscriber, 0, len(topic.Subscribers))
+	subscribers := (*snapshot)[:0]
 	for _, subscriber := range topic.Subscribers {
+		if subscriber.Durable {
+			continue // durable subscribers pull from the buffer
+		}
 		subscribers = append(subscribers, subscriber)
 	}
+	delivery := topic.Delivery
 	topic.mu.RUnlock()
+	*snapshot = subscribers
+
+	// Every connection of a user purges tombstones
+	var chosen map[string]*Subscriber
+	if delivery == DeliveryOncePerUser && message.Tombstone == "" {
+		chosen = oncePerUser(subscribers, message)
+	}
 
-	// Send message to all subscribers concurrently
 	for _, subscriber := range subscribers {
-		go func(sub *Subscriber) {
-			select {
-			case sub.MessageChan <- message:
-				// Message sent successfully
-			case <-s.shutdown:
-				// Service is shutting down
-				return
-			default:
-				// Channel is full, drop message (backpressure policy)
-				log.Warn("Dropped message due to full subscriber channel",
-					"client_id", sub.ClientID, "topic", topicName)
-			}
-		}(subscriber)
+		if !subscriber.wants(message) {
+			continue
+		}
+		if chosen != nil && subscriber.UserID != "" && chosen[subscriber.UserID] != subscriber {
+			continue
+		}
+		// Tombstones bypass sampling so every cache can purge
+		if message.Tombstone == "" && !subscriber.accepts() {
+			continue
+		}
+
+		s.pushOrdered(ctx, topic, subscriber, message)
 	}
+	s.mirror(topic, message)
 
-	log.Info("Published message to topic", "topic", topicName, "message_id", message.ID, "subscribers", len(subscribers))
-	return nil
+	return len(subscribers)
+}
+
+// maxPooledSnapshot is the most subscribers a pooled snapshot may hold;
+// larger ones are left to the garbage collector
+const maxPooledSnapshot = 4096
+
+// subscriberSnapshots holds the slices offer copies a topic's subscribers
+// into, so publishing does not allocate one per message
+var subscriberSnapshots = sync.Pool{
+	New: func() interface{} { return new([]*Subscriber) },
+}
+
+// releaseSubscriberSnapshot returns a snapshot to the pool, dropping its
+// references to the subscribers
+func releaseSubscriberSnapshot(snapshot *[]*Subscriber) {
+	if cap(*snapshot) > maxPooledSnapshot {
+		return
+	}
+	clear(*snapshot)
+	*snapshot = (*snapshot)[:0]
+	subscriberSnapshots.Put(snapshot)
+}
+
+// deliver offers a message to a subscriber's queue under its backpressure
+// policy, returning the messages dropped for the subscriber
+func (s *service) deliver(ctx context.Context, topic *Topic, sub *Subscriber, message *Message) []*Message {
+	topic.mu.RLock()
+	policy := sub.Backpressure.or(topic.Backpressure)
+	// Strictly ordered and partitioned topics keep one queue, so priority
+	// cannot reorder them
+	high := message.Priority == PriorityHigh && topic.Ordering != OrderingStrict && topic.Partitions == 0
+	topic.mu.RUnlock()
+
+	// The subscriber's delivery guard, not the topic lock, keeps the send
+	// safe from a concurrent close, so a blocking policy cannot stall
+	// Subscribe and Unsubscribe on the topic
+	dropped, disconnect := s.enqueueFor(ctx, topic, sub, message, policy, high)
+
+	if disconnect {
+		s.disconnectSlow(ctx, topic, sub)
+	}
+	return dropped
+}
+
+// deadLetterDropped redirects messages dropped for a subscriber to its
+// dead-letter topic, if it has one. Messages dropped from a dead-letter
+// topic are not redirected again.
+func (s *service) deadLetterDropped(ctx context.Context, topicName string, sub *Subscriber, dropped []*Message) {
+	if sub.DeadLetter == "" {
+		return
+	}
+	for _, message := range dropped {
+		if !message.deadLetter {
+			s.deadLetter(ctx, topicName, sub, message, DeadLetterReasonBacklog)
+		}
+	}
+}
+
+// deadLetterLater redirects dropped messages on a goroutine of its own, for
+// callers that must not wait on the dead-letter topic. Past the goroutine
+// budget and task queue the messages are discarded.
+func (s *service) deadLetterLater(ctx context.Context, topicName string, sub *Subscriber, dropped []*Message) {
+	if sub.DeadLetter == "" {
+		return
+	}
+	if !s.tasks.goOrQueue(TaskDeadLetter, func() { s.deadLetterDropped(ctx, topicName, sub, dropped) }) {
+		logging.WithContext(ctx).Warnw("Discarded dropped messages, goroutine budget spent",
+			"client_id", sub.ClientID, "topic", topicName, "dead_letter", sub.DeadLetter, "messages", len(dropped))
+	}
+}
+
+// deadLetter publishes a message dropped or nacked for a subscriber to its
+// dead-letter topic, wrapped in a DeadLetter naming where it came from and
+// why. The wrapper keeps the message's tags so tag-filtered consumers can
+// recover their share.
+func (s *service) deadLetter(ctx context.Context, topicName string, sub *Subscriber, message *Message, reason string) {
+	wrapped := &Message{
+		ID: uuid.New().String(),
+		Payload: clonePayload(DeadLetter{
+			Topic:    topicName,
+			ClientID: sub.ClientID,
+			Reason:   reason,
+			Message:  message,
+		}),
+		Tags:       slices.Clone(message.Tags),
+		deadLetter: true,
+	}
+
+	if err := s.publish(ctx, sub.DeadLetter, wrapped, map[string]bool{}); err != nil {
+		logging.WithContext(ctx).Warnw("Failed to dead-letter message",
+			"client_id", sub.ClientID, "topic", topicName, "dead_letter", sub.DeadLetter, "message_id", message.ID, "reason", reason, "error", err)
+	}
+}
+
+// DeleteMessage removes a message from the topic's buffer and publishes a
+// tombstone carrying its ID, so subscribers and downstream caches can purge
+// their copies. Copies already routed to other topics are not removed.
+func (s *service) DeleteMessage(ctx context.Context, topicName, messageID string) (*Message, error) {
+	log := logging.WithContext(ctx)
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+	if topic.held() {
+		return nil, errLegalHold(topicName)
+	}
+
+	// A partition or the compacted state may still hold a message the
+	// topic's buffer wrapped past
+	removed := topic.Messages.Remove(messageID)
+	if removed == nil {
+		removed = topic.findPartitioned(messageID)
+	}
+	if removed == nil {
+		removed = topic.findCompacted(messageID)
+	}
+	if removed == nil {
+		return nil, fmt.Errorf("message %s not found in topic %s", messageID, topicName)
+	}
+	topic.unbufferPartition(removed)
+	topic.unindexText(removed)
+	topic.uncompact(removed)
+	if err := s.logWAL(walRecord{Removed: removed.Seq, Topic: topicName}); err != nil {
+		log.Errorw("Failed to log message removal", "topic", topicName, "message_id", messageID, "error", err)
+	}
+
+	// The tombstone carries the key to follow the message through its partition
+	tombstone := &Message{Tombstone: messageID, Key: removed.Key}
+	if err := s.publish(ctx, topicName, tombstone, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Deleted message", "topic", topicName, "message_id", messageID, "seq", removed.Seq, "tombstone_seq", tombstone.Seq)
+	return tombstone, nil
+}
+
+// route republishes a message to every target whose predicate matches
+func (s *service) route(ctx context.Context, topic *Topic, message *Message, visited map[string]bool) {
+	log := logging.WithContext(ctx)
+
+	topic.mu.RLock()
+	routes := make([]*Route, len(topic.Routes))
+	copy(routes, topic.Routes)
+	topic.mu.RUnlock()
+
+	for _, route := range routes {
+		if visited[route.Target] {
+			log.Debugw("Skipped route to already visited topic",
+				"topic", topic.Name, "target", route.Target, "message_id", message.ID)
+			continue
+		}
+		if route.Predicate != nil && !route.Predicate.Matches(message) {
+			continue
+		}
+
+		routed := message.Clone()
+		routed.Origin, routed.OriginSeq = "", 0
+		routed.SchemaVersion = 0 // versions are per topic
+		if err := s.publish(ctx, route.Target, routed, visited); err != nil {
+			log.Warnw("Failed to route message",
+				"topic", topic.Name, "target", route.Target, "message_id", message.ID, "error", err)
+		}
+	}
+}
+
+// AddRoute adds a content-based route from a topic to a target topic
+func (s *service) AddRoute(ctx context.Context, topicName string, route *Route) (*Route, error) {
+	log := logging.WithContext(ctx)
+
+	if route.Target == "" {
+		return nil, fmt.Errorf("invalid route: target is required")
+	}
+	if route.Target == topicName {
+		return nil, fmt.Errorf("invalid route: target must differ from source topic")
+	}
+	if route.Predicate != nil {
+		if err := route.Predicate.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	_, targetExists := s.topics[route.Target]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+	if !targetExists {
+		return nil, fmt.Errorf("invalid route: target topic %s not found", route.Target)
+	}
+
+	created := &Route{
+		ID:        uuid.New().String(),
+		Target:    route.Target,
+		Predicate: route.Predicate,
+		CreatedAt: s.clock.Now(),
+	}
+
+	topic.mu.Lock()
+	topic.Routes = append(topic.Routes, created)
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	log.Info("Added route", "topic", topicName, "target", route.Target, "route_id", created.ID)
+	return created, nil
+}
+
+// ListRoutes returns the routes of a topic
+func (s *service) ListRoutes(ctx context.Context, topicName string) ([]Route, error) {
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.RLock()
+	defer topic.mu.RUnlock()
+
+	routes := make([]Route, 0, len(topic.Routes))
+	for _, route := range topic.Routes {
+		routes = append(routes, *route)
+	}
+
+	return routes, nil
+}
+
+// DeleteRoute removes a route from a topic
+func (s *service) DeleteRoute(ctx context.Context, topicName, routeID string) error {
+	log := logging.WithContext(ctx)
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	for i, route := range topic.Routes {
+		if route.ID == routeID {
+			topic.Routes = append(topic.Routes[:i], topic.Routes[i+1:]...)
+			topic.mu.Unlock()
+			s.recordConfigured(ctx, topic)
+			log.Info("Deleted route", "topic", topicName, "route_id", routeID)
+			return nil
+		}
+	}
+	topic.mu.Unlock()
+
+	return fmt.Errorf("route %s not found", routeID)
 }
 
 // GetStats returns detailed statistics
@@ -319,17 +1498,43 @@ func (s *service) GetStats(ctx context.Context) (*StatsResponse, error) {
 
 	stats := &StatsResponse{
 		Topics: make(map[string]TopicStats),
+		Tasks:  s.tasks.stats(),
 	}
 
 	for name, topic := range s.topics {
 		topic.mu.RLock()
 		subscriberCount := len(topic.Subscribers)
 		messageCount := topic.Messages.Count()
+		head := topic.Messages.LastSeq()
+		cursors := make([]CursorInfo, 0, len(topic.Cursors))
+		for clientID, cursor := range topic.Cursors {
+			cursors = append(cursors, *cursorInfo(clientID, cursor, head))
+		}
+		clients := make([]SubscriberStats, 0, len(topic.Subscribers))
+		for clientID, subscriber := range topic.Subscribers {
+			client := SubscriberStats{
+				ClientID:  clientID,
+				Durable:   subscriber.Durable,
+				Delivered: subscriber.Delivered(),
+				Dropped:   subscriber.Dropped(),
+				Depth:     subscriber.Depth(),
+				Capacity:  cap(subscriber.MessageChan) + cap(subscriber.PriorityChan),
+			}
+			if cursor, tracked := topic.Cursors[clientID]; tracked && subscriber.Durable && head > cursor.Delivered {
+				client.Depth = int(head - cursor.Delivered)
+			}
+			clients = append(clients, client)
+		}
 		topic.mu.RUnlock()
+		slices.SortFunc(clients, func(a, b SubscriberStats) int { return strings.Compare(a.ClientID, b.ClientID) })
 
 		stats.Topics[name] = TopicStats{
 			Messages:    messageCount,
 			Subscribers: subscriberCount,
+			Cursors:     cursors,
+			Payloads:    storePayloadStats(topic.Messages),
+			Dropped:     topic.dropped.Load(),
+			Clients:     clients,
 		}
 	}
 
@@ -349,8 +1554,32 @@ func (s *service) GetHealth(ctx context.Context) (*HealthResponse, error) {
 	}
 
 	return &HealthResponse{
-		UptimeSec:   int64(time.Since(s.startTime).Seconds()),
+		UptimeSec:   int64(s.clock.Now().Sub(s.startTime).Seconds()),
 		Topics:      len(s.topics),
 		Subscribers: totalSubscribers,
 	}, nil
 }
+
+// GetReadiness returns the lifecycle phase and recovery progress
+func (s *service) GetReadiness(ctx context.Context) (*ReadinessResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	readiness := &ReadinessResponse{
+		Ready: s.phase == PhaseReady,
+		Phase: s.phase,
+	}
+	if s.recovery != nil {
+		recovery := *s.recovery
+		readiness.Recovery = &recovery
+	}
+
+	return readiness, nil
+}
+
+// setPhase moves the service to a new lifecycle phase
+func (s *service) setPhase(phase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phase = phase
+}
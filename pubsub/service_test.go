@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+)
+
+// TestInitServiceReset checks InitService keeps its first instance until
+// ResetForTest, so tests using the singleton do not see each other's topics
+func TestInitServiceReset(t *testing.T) {
+	t.Cleanup(ResetForTest)
+	ctx := context.Background()
+
+	first := InitService(&Config{RingBufferSize: 10})
+	if err := first.CreateTopic(ctx, "orders", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if again := InitService(&Config{RingBufferSize: 20}); again != first {
+		t.Fatal("InitService built a second instance without a reset")
+	}
+
+	ResetForTest()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("GetService did not panic after ResetForTest")
+			}
+		}()
+		GetService()
+	}()
+
+	second := InitService(&Config{RingBufferSize: 20})
+	if second == first {
+		t.Fatal("InitService returned the discarded instance")
+	}
+	if GetService() != second {
+		t.Error("GetService does not return the new instance")
+	}
+	if second.config.RingBufferSize != 20 {
+		t.Errorf("new instance has RingBufferSize %d, want 20", second.config.RingBufferSize)
+	}
+	if _, err := second.GetTopic(ctx, "orders"); err == nil {
+		t.Error("new instance sees a topic of the discarded one")
+	}
+	select {
+	case <-first.shutdown:
+	default:
+		t.Error("discarded instance was not shut down")
+	}
+}
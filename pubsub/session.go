@@ -0,0 +1,123 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// SessionSaveInterval is how often changed cursors and read markers are
+// written to Config.DataDir, so a crash loses at most this much progress
+const SessionSaveInterval = time.Second
+
+// persistedSession is the client state of a topic kept in session.json
+// beside its message database, so durable subscribers resume from their last
+// ack after a restart
+type persistedSession struct {
+	Cursors     map[string]uint64 `json:"cursors,omitempty"`      // client_id -> acked seq
+	ReadMarkers map[string]uint64 `json:"read_markers,omitempty"` // client_id -> seq read up to
+	Latest      []*Message        `json:"latest,omitempty"`       // compacted state, oldest first
+}
+
+// saveSessions writes the cursors and read markers of topics that changed
+// every SessionSaveInterval, and once more when the service shuts down
+func (s *service) saveSessions(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(SessionSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			s.saveChangedSessions(ctx)
+			return
+		case <-ticker.C():
+			s.saveChangedSessions(ctx)
+		}
+	}
+}
+
+// saveChangedSessions writes session.json for every topic whose cursors or
+// read markers changed since it was last written
+func (s *service) saveChangedSessions(ctx context.Context) {
+	s.mu.RLock()
+	topics := make([]*Topic, 0, len(s.topics))
+	for _, topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	s.mu.RUnlock()
+
+	for _, topic := range topics {
+		if !topic.unsaved.Swap(false) {
+			continue
+		}
+
+		topic.mu.RLock()
+		session := persistedSession{
+			Cursors:     make(map[string]uint64, len(topic.Cursors)),
+			ReadMarkers: make(map[string]uint64, len(topic.ReadMarkers)),
+			Latest:      savedCompacted(topic),
+		}
+		for clientID, cursor := range topic.Cursors {
+			session.Cursors[clientID] = cursor.Acked
+		}
+		for clientID, seq := range topic.ReadMarkers {
+			session.ReadMarkers[clientID] = seq
+		}
+		topic.mu.RUnlock()
+
+		if err := writeSession(s.config.DataDir, topic.Name, session); err != nil {
+			// A deleted topic's directory is gone; anything else is retried
+			if !errors.Is(err, os.ErrNotExist) {
+				topic.unsaved.Store(true)
+				logging.WithContext(ctx).Warnw("Failed to save topic session", "topic", topic.Name, "error", err)
+			}
+		}
+	}
+}
+
+// writeSession replaces a topic's session.json. It does not create the
+// topic's directory, so a topic deleted meanwhile is not brought back.
+func writeSession(dataDir, topicName string, session persistedSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(topicDir(dataDir, topicName), sessionFile), data)
+}
+
+// restoreSession loads the cursors and read markers saved for a topic.
+// Restored cursors have delivered nothing past their ack, as after a
+// resubscribe.
+func (s *service) restoreSession(topic *Topic) error {
+	data, err := os.ReadFile(filepath.Join(topicDir(s.config.DataDir, topic.Name), sessionFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Payload numbers of the compacted state stay exact
+	var session persistedSession
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&session); err != nil {
+		return err
+	}
+	for clientID, acked := range session.Cursors {
+		topic.Cursors[clientID] = &Cursor{Acked: acked, Delivered: acked}
+	}
+	for clientID, seq := range session.ReadMarkers {
+		topic.ReadMarkers[clientID] = seq
+	}
+	loadCompacted(topic, session.Latest)
+	return nil
+}
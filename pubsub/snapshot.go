@@ -0,0 +1,217 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// snapshot is the state of every topic as written to Config.SnapshotFile
+type snapshot struct {
+	TakenAt    time.Time       `json:"taken_at"`
+	Namespaces []*Namespace    `json:"namespaces,omitempty"`
+	Topics     []topicSnapshot `json:"topics"`
+}
+
+// topicSnapshot is a topic with its buffered messages and client state
+type topicSnapshot struct {
+	persistedTopic
+	LastSeq  uint64           `json:"last_seq"` // newest sequence number stamped, including dropped messages
+	Messages []*Message       `json:"messages"` // oldest first
+	Session  persistedSession `json:"session"`
+}
+
+// storeLoader is implemented by the in-memory stores, which a snapshot
+// fills back with messages keeping their sequence numbers
+type storeLoader interface {
+	load(messages []*Message, lastSeq uint64)
+}
+
+// saveSnapshots writes the snapshot every Config.SnapshotInterval until the
+// service shuts down; Stop writes the last one
+func (s *service) saveSnapshots(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(s.config.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C():
+			if err := s.writeSnapshot(ctx); err != nil {
+				logging.WithContext(ctx).Errorw("Failed to write snapshot", "file", s.config.SnapshotFile, "error", err)
+			}
+		}
+	}
+}
+
+// writeSnapshot replaces Config.SnapshotFile with the current topics,
+// namespaces, messages, cursors and read markers. Each topic is captured
+// under its own lock, so publishes to other topics carry on meanwhile.
+// The write-ahead log segments written before the capture started are
+// deleted once the snapshot is written.
+func (s *service) writeSnapshot(ctx context.Context) error {
+	var checkpoint uint64
+	if s.wal != nil {
+		segment, err := s.wal.rotate()
+		if err != nil {
+			return fmt.Errorf("failed to rotate write-ahead log: %w", err)
+		}
+		checkpoint = segment
+	}
+
+	snap := snapshot{TakenAt: s.clock.Now()}
+
+	s.mu.RLock()
+	topics := make([]*Topic, 0, len(s.topics))
+	for _, topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	for _, namespace := range s.namespaces {
+		snap.Namespaces = append(snap.Namespaces, namespace)
+	}
+	s.mu.RUnlock()
+
+	messages := 0
+	for _, topic := range topics {
+		topic.mu.RLock()
+		saved := topicSnapshot{
+			persistedTopic: persistedTopic{Name: topic.Name, CreatedAt: topic.CreatedAt, Settings: topicSettings(topic)},
+			LastSeq:        topic.Messages.LastSeq(),
+			Messages:       topic.Messages.GetMessages(),
+			Session: persistedSession{
+				Cursors:     make(map[string]uint64, len(topic.Cursors)),
+				ReadMarkers: make(map[string]uint64, len(topic.ReadMarkers)),
+				Latest:      savedCompacted(topic),
+			},
+		}
+		for clientID, cursor := range topic.Cursors {
+			saved.Session.Cursors[clientID] = cursor.Acked
+		}
+		for clientID, seq := range topic.ReadMarkers {
+			saved.Session.ReadMarkers[clientID] = seq
+		}
+		topic.mu.RUnlock()
+
+		messages += len(saved.Messages)
+		snap.Topics = append(snap.Topics, saved)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.config.SnapshotFile), 0o755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.config.SnapshotFile, data); err != nil {
+		return err
+	}
+	if s.wal != nil {
+		if err := s.wal.checkpoint(checkpoint); err != nil {
+			logging.WithContext(ctx).Warnw("Failed to delete write-ahead log segments", "dir", s.config.WALDir, "error", err)
+		}
+	}
+
+	logging.WithContext(ctx).Infow("Wrote snapshot", "file", s.config.SnapshotFile,
+		"topics", len(snap.Topics), "messages", messages, "bytes", len(data))
+	return nil
+}
+
+// restoreSnapshot recreates the topics and namespaces in
+// Config.SnapshotFile, if it exists, and replays the write-ahead log on top
+// of them, reporting progress through GetReadiness. Messages are restored
+// into in-memory stores only; stores from Config.MessageStore keep their
+// own.
+func (s *service) restoreSnapshot(ctx context.Context) error {
+	log := logging.WithContext(ctx)
+
+	snap, err := s.readSnapshot()
+	if err != nil {
+		return err
+	}
+	if s.wal != nil {
+		records, err := s.wal.replay(snap)
+		if err != nil {
+			return err
+		}
+		log.Infow("Replayed write-ahead log", "dir", s.config.WALDir, "records", records)
+	}
+	if snap.TakenAt.IsZero() && len(snap.Topics) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	for _, namespace := range snap.Namespaces {
+		s.namespaces[namespace.Prefix] = namespace
+	}
+	s.recovery = &RecoveryProgress{TopicsTotal: len(snap.Topics)}
+	s.mu.Unlock()
+
+	for i := range snap.Topics {
+		saved := &snap.Topics[i]
+		topic, err := s.restoreTopic(saved.persistedTopic, saved)
+		if err != nil {
+			log.Errorw("Failed to restore topic from snapshot", "topic", saved.Name, "error", err)
+			continue
+		}
+		s.addRecovered(topic)
+	}
+
+	s.mu.RLock()
+	progress := *s.recovery
+	s.mu.RUnlock()
+	log.Infow("Restored snapshot", "file", s.config.SnapshotFile, "taken_at", snap.TakenAt,
+		"topics", progress.TopicsRecovered, "messages", progress.MessagesRecovered)
+
+	return nil
+}
+
+// readSnapshot reads Config.SnapshotFile, returning an empty snapshot if it
+// does not exist
+func (s *service) readSnapshot() (*snapshot, error) {
+	snap := &snapshot{}
+
+	file, err := os.Open(s.config.SnapshotFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer file.Close()
+
+	// Payload numbers stay exact, as publishes keep them by default
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	if err := decoder.Decode(snap); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", s.config.SnapshotFile, err)
+	}
+	return snap, nil
+}
+
+// loadSnapshot fills a restored topic's store with its snapshotted messages
+// and restores its cursors and read markers
+func loadSnapshot(topic *Topic, saved *topicSnapshot) {
+	if loader, ok := topic.Messages.(storeLoader); ok {
+		loader.load(saved.Messages, saved.LastSeq)
+	} else if len(saved.Messages) > 0 {
+		logging.WithContext(context.Background()).Warnw("Message store cannot load a snapshot, messages not restored",
+			"topic", topic.Name, "messages", len(saved.Messages))
+	}
+
+	for clientID, acked := range saved.Session.Cursors {
+		topic.Cursors[clientID] = &Cursor{Acked: acked, Delivered: acked}
+	}
+	for clientID, seq := range saved.Session.ReadMarkers {
+		topic.ReadMarkers[clientID] = seq
+	}
+}
@@ -0,0 +1,102 @@
+package pubsub
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Tag match modes
+const (
+	TagMatchAny = "any" // deliver messages carrying at least one of the tags
+	TagMatchAll = "all" // deliver messages carrying every one of the tags
+)
+
+// TagFilter restricts a subscription to messages by their tags
+type TagFilter struct {
+	Tags  []string `json:"tags"`
+	Match string   `json:"match,omitempty"` // TagMatchAny (default) or TagMatchAll
+
+	set map[string]struct{} // Tags, indexed for the fan-out path
+}
+
+// Validate checks that the tag filter is usable
+func (f *TagFilter) Validate() error {
+	if len(f.Tags) == 0 {
+		return fmt.Errorf("invalid tag filter: at least one tag is required")
+	}
+	for _, tag := range f.Tags {
+		if tag == "" {
+			return fmt.Errorf("invalid tag filter: tags must not be empty")
+		}
+	}
+	if f.Match != "" && f.Match != TagMatchAny && f.Match != TagMatchAll {
+		return fmt.Errorf("invalid tag filter: match must be %q or %q", TagMatchAny, TagMatchAll)
+	}
+	return nil
+}
+
+// indexed returns a copy of the filter with its tag set built
+func (f *TagFilter) indexed() *TagFilter {
+	indexed := &TagFilter{
+		Tags:  slices.Clone(f.Tags),
+		Match: f.Match,
+		set:   make(map[string]struct{}, len(f.Tags)),
+	}
+	if indexed.Match == "" {
+		indexed.Match = TagMatchAny
+	}
+	for _, tag := range f.Tags {
+		indexed.set[tag] = struct{}{}
+	}
+	return indexed
+}
+
+// Matches reports whether the message's tags satisfy the filter. Tombstones
+// always match so every subscriber can purge deleted messages.
+func (f *TagFilter) Matches(msg *Message) bool {
+	if msg.Tombstone != "" {
+		return true
+	}
+
+	if f.Match == TagMatchAll {
+		if len(msg.Tags) < len(f.set) {
+			return false
+		}
+		matched := 0
+		for i, tag := range msg.Tags {
+			if _, ok := f.set[tag]; ok && !slices.Contains(msg.Tags[:i], tag) {
+				matched++
+			}
+		}
+		return matched == len(f.set)
+	}
+
+	for _, tag := range msg.Tags {
+		if _, ok := f.set[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// wants reports whether the subscriber's tag filter and header
+// preferences, if any, admit msg
+func (sub *Subscriber) wants(msg *Message) bool {
+	return (sub.TagFilter == nil || sub.TagFilter.Matches(msg)) && sub.Preferences.Matches(msg) &&
+		(sub.Partition == nil || partitionOf(msg, sub.partitions) == *sub.Partition)
+}
+
+// filterWanted returns the messages the subscriber's tag filter and header
+// preferences admit
+func (sub *Subscriber) filterWanted(messages []*Message) []*Message {
+	if sub.TagFilter == nil && len(sub.Preferences) == 0 && sub.Partition == nil {
+		return messages
+	}
+	filtered := messages[:0:0]
+	for _, msg := range messages {
+		if sub.wants(msg) {
+			filtered = append(filtered, msg)
+		}
+	}
+	return filtered
+}
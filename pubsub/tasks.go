@@ -0,0 +1,252 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Task budget defaults
+const (
+	DefaultMaxGoroutines = 10000
+	DefaultTaskQueueSize = 10000
+)
+
+// TaskKind names the work the engine runs on goroutines of its own
+type TaskKind string
+
+// Task kinds
+const (
+	TaskWriter     TaskKind = "writer"      // a subscriber's writer, delivering published messages
+	TaskReplay     TaskKind = "replay"      // replaying history to a new subscriber
+	TaskDeadLetter TaskKind = "dead_letter" // redirecting dropped messages
+	TaskJanitor    TaskKind = "janitor"     // an expiry or retention sweep
+)
+
+// taskKinds lists every TaskKind, indexing taskPool.kinds
+var taskKinds = []TaskKind{TaskWriter, TaskReplay, TaskDeadLetter, TaskJanitor}
+
+// TaskStats reports the engine's goroutine budget and its use
+type TaskStats struct {
+	Budget    int                        `json:"budget"`     // goroutines tasks may use at once
+	Running   int                        `json:"running"`    // goroutines in use
+	Peak      int                        `json:"peak"`       // most goroutines in use at once
+	Queued    int                        `json:"queued"`     // tasks waiting for a goroutine
+	QueueSize int                        `json:"queue_size"` // tasks that may wait before more are shed
+	Kinds     map[TaskKind]TaskKindStats `json:"kinds"`
+}
+
+// TaskKindStats reports one kind of task
+type TaskKindStats struct {
+	Running int    `json:"running"`
+	Queued  uint64 `json:"queued"` // tasks that had to wait for a goroutine
+	Inline  uint64 `json:"inline"` // tasks run on their caller's goroutine, the budget being spent
+	Shed    uint64 `json:"shed"`   // tasks dropped, the budget and the queue being full
+}
+
+// errOverloaded reports work refused because the goroutine budget and the
+// task queue are full
+func errOverloaded(what string) error {
+	return fmt.Errorf("overloaded: %s, goroutine budget spent", what)
+}
+
+// task is a unit of work waiting for a goroutine
+type task struct {
+	kind int
+	fn   func()
+}
+
+// taskCounters count one kind of task
+type taskCounters struct {
+	running atomic.Int64
+	queued  atomic.Uint64
+	inline  atomic.Uint64
+	shed    atomic.Uint64
+}
+
+// taskPool bounds the goroutines the engine starts for subscriber writers,
+// replay, dead-lettering and janitor sweeps. A task gets a goroutine of
+// its own while the budget lasts; past it, tasks either run on their
+// caller's goroutine, which slows the caller down instead, or wait in a
+// bounded queue that goroutines drain before exiting, and are shed when
+// it is full.
+type taskPool struct {
+	slots   chan struct{} // one token per goroutine in use
+	queue   chan task
+	running atomic.Int64
+	peak    atomic.Int64
+	kinds   []taskCounters
+}
+
+func newTaskPool(budget, queueSize int) *taskPool {
+	if budget <= 0 {
+		budget = DefaultMaxGoroutines
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultTaskQueueSize
+	}
+	return &taskPool{
+		slots: make(chan struct{}, budget),
+		queue: make(chan task, queueSize),
+		kinds: make([]taskCounters, len(taskKinds)),
+	}
+}
+
+// kindIndex returns kind's index in taskKinds
+func kindIndex(kind TaskKind) int {
+	for i, known := range taskKinds {
+		if known == kind {
+			return i
+		}
+	}
+	panic("pubsub: unknown task kind " + string(kind))
+}
+
+// acquire takes a goroutine from the budget, reporting false when it is
+// spent
+func (p *taskPool) acquire() bool {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		return false
+	}
+
+	running := p.running.Add(1)
+	for {
+		peak := p.peak.Load()
+		if running <= peak || p.peak.CompareAndSwap(peak, running) {
+			return true
+		}
+	}
+}
+
+// release gives a goroutine back to the budget
+func (p *taskPool) release() {
+	p.running.Add(-1)
+	<-p.slots
+}
+
+// tryStart runs fn on a goroutine of its own if the budget allows,
+// reporting whether it did. A nil fn starts a goroutine that only drains
+// the queue.
+func (p *taskPool) tryStart(kind int, fn func()) bool {
+	if !p.acquire() {
+		return false
+	}
+	go p.work(task{kind: kind, fn: fn})
+	return true
+}
+
+// goOrRun runs fn on a goroutine of its own, or on the caller's when the
+// budget is spent. For work whose delay would hold up delivery.
+func (p *taskPool) goOrRun(kind TaskKind, fn func()) {
+	index := kindIndex(kind)
+	if p.tryStart(index, fn) {
+		return
+	}
+	p.kinds[index].inline.Add(1)
+	p.run(task{kind: index, fn: fn})
+}
+
+// goOrQueue runs fn on a goroutine of its own, or queues it for the next
+// goroutine to free up when the budget is spent. It reports false, and
+// drops fn, when the queue is full too.
+func (p *taskPool) goOrQueue(kind TaskKind, fn func()) bool {
+	index := kindIndex(kind)
+	if p.tryStart(index, fn) {
+		return true
+	}
+
+	select {
+	case p.queue <- task{kind: index, fn: fn}:
+		p.kinds[index].queued.Add(1)
+	default:
+		p.kinds[index].shed.Add(1)
+		return false
+	}
+
+	// Every goroutine may have exited since tryStart, leaving nothing to
+	// drain the queue
+	p.tryStart(index, nil)
+	return true
+}
+
+// work runs first, if any, then queued tasks until the queue is empty,
+// and gives its slot back
+func (p *taskPool) work(first task) {
+	if first.fn != nil {
+		p.run(first)
+	}
+	for {
+		select {
+		case next := <-p.queue:
+			p.run(next)
+			continue
+		default:
+		}
+
+		p.release()
+		// A task queued after the check above may have found the budget
+		// spent, so take a goroutine back to run it
+		if len(p.queue) == 0 || !p.acquire() {
+			return
+		}
+	}
+}
+
+// run runs one task, counting it as running
+func (p *taskPool) run(t task) {
+	counters := &p.kinds[t.kind]
+	counters.running.Add(1)
+	defer counters.running.Add(-1)
+	t.fn()
+}
+
+// stats reports the pool's budget and use
+func (p *taskPool) stats() TaskStats {
+	stats := TaskStats{
+		Budget:    cap(p.slots),
+		Running:   int(p.running.Load()),
+		Peak:      int(p.peak.Load()),
+		Queued:    len(p.queue),
+		QueueSize: cap(p.queue),
+		Kinds:     make(map[TaskKind]TaskKindStats, len(taskKinds)),
+	}
+	for i, kind := range taskKinds {
+		counters := &p.kinds[i]
+		stats.Kinds[kind] = TaskKindStats{
+			Running: int(counters.running.Load()),
+			Queued:  counters.queued.Load(),
+			Inline:  counters.inline.Load(),
+			Shed:    counters.shed.Load(),
+		}
+	}
+	return stats
+}
+
+// sweep runs a janitor sweep as a task, unless the previous sweep is still
+// running. A sweep shed for lack of budget is skipped until the next tick.
+func (s *service) sweep(ctx context.Context, busy *atomic.Bool, name string, fn func()) {
+	if !busy.CompareAndSwap(false, true) {
+		return
+	}
+
+	s.wg.Add(1)
+	started := s.tasks.goOrQueue(TaskJanitor, func() {
+		defer s.wg.Done()
+		defer busy.Store(false)
+		fn()
+	})
+	if !started {
+		s.wg.Done()
+		busy.Store(false)
+		logging.WithContext(ctx).Warnw("Skipped sweep, goroutine budget spent", "sweep", name)
+	}
+}
+
+// TaskStats reports the engine's goroutine budget and its use
+func (s *service) TaskStats(ctx context.Context) TaskStats {
+	return s.tasks.stats()
+}
@@ -0,0 +1,372 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+const (
+	// MaxTextIndexSize bounds how many recent messages a topic's text index
+	// may cover
+	MaxTextIndexSize = 100000
+	// maxIndexedWords bounds the distinct words indexed per message
+	maxIndexedWords = 1000
+	// maxWordLength is the longest word indexed, in bytes; longer ones,
+	// such as encoded blobs, are skipped
+	maxWordLength = 64
+)
+
+// SearchResult is a message matching a search, with the topic it is on
+type SearchResult struct {
+	Topic   string   `json:"topic"`
+	Message *Message `json:"message"`
+}
+
+// SetTextIndex indexes the words in the payloads of a topic's newest size
+// messages, so operators can search them with SearchMessages, or drops the
+// index with 0. The index is built from the messages already buffered and
+// kept in memory only.
+func (s *service) SetTextIndex(ctx context.Context, topicName string, size int) error {
+	if size < 0 || size > MaxTextIndexSize {
+		return fmt.Errorf("invalid text index: size must be between 0 and %d", MaxTextIndexSize)
+	}
+
+	s.mu.RLock()
+	topic, exists := s.topics[topicName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("topic %s not found", topicName)
+	}
+
+	topic.mu.Lock()
+	setTextIndex(topic, size)
+	topic.mu.Unlock()
+	s.recordConfigured(ctx, topic)
+
+	logging.WithContext(ctx).Infow("Set topic text index", "topic", topicName, "size", size)
+	return nil
+}
+
+// setTextIndex sets the number of messages a topic's text index covers,
+// rebuilding it from the topic's buffer when the size changes. Caller must
+// hold topic.mu or own topic exclusively.
+func setTextIndex(topic *Topic, size int) {
+	if topic.TextIndex == size {
+		return
+	}
+	topic.TextIndex = size
+	if size == 0 {
+		topic.text = nil
+		return
+	}
+
+	text := newTextIndex(size)
+	messages := topic.Messages.GetMessages()
+	for _, msg := range messages[max(len(messages)-size, 0):] {
+		text.add(msg)
+	}
+	topic.text = text
+}
+
+// indexText adds a stored message to the topic's text index, if it has one
+func (t *Topic) indexText(msg *Message) {
+	t.mu.RLock()
+	text := t.text
+	t.mu.RUnlock()
+
+	if text != nil {
+		text.add(msg)
+	}
+}
+
+// unindexText removes a deleted or evicted message from the topic's text
+// index, if it has one
+func (t *Topic) unindexText(msg *Message) {
+	t.mu.RLock()
+	text := t.text
+	t.mu.RUnlock()
+
+	if text != nil {
+		text.remove(msg)
+	}
+}
+
+// evictText drops the messages stamped before cutoff from the topic's text
+// index, including those the topic's buffer already wrapped past
+func (t *Topic) evictText(cutoff time.Time) {
+	t.mu.RLock()
+	text := t.text
+	t.mu.RUnlock()
+
+	if text != nil {
+		text.evictBefore(cutoff)
+	}
+}
+
+// SearchMessages returns up to max of the newest messages whose payloads
+// contain every word of query, across the given topics or, with none,
+// every topic with a text index. Words are matched whole and regardless of
+// case.
+func (s *service) SearchMessages(ctx context.Context, query string, topicNames []string, max int) ([]SearchResult, error) {
+	words := queryWords(query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("invalid search: query must contain a word")
+	}
+	if max <= 0 {
+		return nil, fmt.Errorf("invalid search: max must be positive")
+	}
+
+	type indexed struct {
+		name string
+		text *textIndex
+	}
+	var targets []indexed
+
+	s.mu.RLock()
+	if len(topicNames) == 0 {
+		for name, topic := range s.topics {
+			topic.mu.RLock()
+			if topic.text != nil {
+				targets = append(targets, indexed{name, topic.text})
+			}
+			topic.mu.RUnlock()
+		}
+	}
+	for _, name := range topicNames {
+		topic, exists := s.topics[name]
+		if !exists {
+			s.mu.RUnlock()
+			return nil, fmt.Errorf("topic %s not found", name)
+		}
+		topic.mu.RLock()
+		text := topic.text
+		topic.mu.RUnlock()
+		if text == nil {
+			s.mu.RUnlock()
+			return nil, fmt.Errorf("invalid search: topic %s has no text index", name)
+		}
+		targets = append(targets, indexed{name, text})
+	}
+	s.mu.RUnlock()
+
+	var results []SearchResult
+	for _, target := range targets {
+		for _, msg := range target.text.search(words, max) {
+			results = append(results, SearchResult{Topic: target.name, Message: msg})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Message.Timestamp.After(results[j].Message.Timestamp)
+	})
+	if len(results) > max {
+		results = results[:max]
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	logging.WithContext(ctx).Infow("Searched messages", "query", query, "topics", len(targets), "results", len(results))
+	return results, nil
+}
+
+// textIndex is an inverted index from payload words to a topic's newest
+// messages
+type textIndex struct {
+	mu       sync.RWMutex
+	size     int
+	messages []*Message            // indexed messages, oldest first
+	words    map[*Message][]string // words each indexed message was filed under
+	postings map[string][]*Message // word -> messages containing it, oldest first
+}
+
+func newTextIndex(size int) *textIndex {
+	return &textIndex{
+		size:     size,
+		words:    make(map[*Message][]string),
+		postings: make(map[string][]*Message),
+	}
+}
+
+// add indexes a message, dropping the oldest when full. Tombstones and
+// messages already indexed are skipped.
+func (x *textIndex) add(msg *Message) {
+	if msg.Tombstone != "" {
+		return
+	}
+	words := payloadWords(msg.Payload)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if _, indexed := x.words[msg]; indexed {
+		return
+	}
+	if len(x.messages) == x.size {
+		x.unfile(x.messages[0])
+		x.messages[0] = nil
+		x.messages = x.messages[1:]
+	}
+	x.messages = append(x.messages, msg)
+	x.words[msg] = words
+	for _, word := range words {
+		x.postings[word] = append(x.postings[word], msg)
+	}
+}
+
+// remove drops a message from the index, if it is indexed
+func (x *textIndex) remove(msg *Message) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if _, indexed := x.words[msg]; !indexed {
+		return
+	}
+	x.unfile(msg)
+	x.messages = slices.DeleteFunc(x.messages, func(m *Message) bool { return m == msg })
+}
+
+// evictBefore drops the messages stamped before cutoff
+func (x *textIndex) evictBefore(cutoff time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.messages = slices.DeleteFunc(x.messages, func(msg *Message) bool {
+		if msg.Timestamp.Before(cutoff) {
+			x.unfile(msg)
+			return true
+		}
+		return false
+	})
+}
+
+// unfile removes a message from the postings of its words. Caller must hold
+// x.mu.
+func (x *textIndex) unfile(msg *Message) {
+	for _, word := range x.words[msg] {
+		posting := x.postings[word]
+		if len(posting) > 0 && posting[0] == msg {
+			// The oldest message, as evicted when the index is full
+			posting[0] = nil
+			posting = posting[1:]
+		} else {
+			posting = slices.DeleteFunc(posting, func(m *Message) bool { return m == msg })
+		}
+		if len(posting) == 0 {
+			delete(x.postings, word)
+		} else {
+			x.postings[word] = posting
+		}
+	}
+	delete(x.words, msg)
+}
+
+// search returns up to max of the newest indexed messages containing every
+// one of words, newest first
+func (x *textIndex) search(words []string, max int) []*Message {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// Walk the shortest posting list and check the other words against the
+	// candidates' own words
+	candidates := x.postings[words[0]]
+	for _, word := range words[1:] {
+		if posting := x.postings[word]; len(posting) < len(candidates) {
+			candidates = posting
+		}
+	}
+
+	var messages []*Message
+	for i := len(candidates) - 1; i >= 0 && len(messages) < max; i-- {
+		if containsWords(x.words[candidates[i]], words) {
+			messages = append(messages, candidates[i])
+		}
+	}
+	return messages
+}
+
+// containsWords reports whether the sorted filed words include every one
+// of words
+func containsWords(filed, words []string) bool {
+	for _, word := range words {
+		if _, found := slices.BinarySearch(filed, word); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// payloadWords returns the distinct words in a payload's strings, numbers
+// and booleans, lowercased and sorted. Object keys are not indexed.
+func payloadWords(payload interface{}) []string {
+	seen := make(map[string]bool)
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		if len(seen) >= maxIndexedWords {
+			return
+		}
+		switch v := value.(type) {
+		case string:
+			for _, word := range splitWords(v) {
+				if len(seen) >= maxIndexedWords {
+					return
+				}
+				seen[word] = true
+			}
+		case json.Number:
+			seen[strings.ToLower(v.String())] = true
+		case float64:
+			seen[strconv.FormatFloat(v, 'f', -1, 64)] = true
+		case bool:
+			seen[strconv.FormatBool(v)] = true
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(payload)
+
+	words := make([]string, 0, len(seen))
+	for word := range seen {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	return words
+}
+
+// queryWords returns the distinct words of a search query
+func queryWords(query string) []string {
+	words := splitWords(query)
+	slices.Sort(words)
+	return slices.Compact(words)
+}
+
+// splitWords splits text into lowercase words of letters and digits,
+// skipping words longer than maxWordLength
+func splitWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, field := range fields {
+		if len(field) <= maxWordLength {
+			words = append(words, field)
+		}
+	}
+	return words
+}
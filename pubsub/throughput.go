@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// ThroughputWindow is the span over which recent publish throughput and
+// drops are measured
+const ThroughputWindow = time.Minute
+
+// throughput counts events, such as publishes, in per-second buckets over
+// ThroughputWindow
+type throughput struct {
+	buckets [int(ThroughputWindow / time.Second)]struct {
+		second int64
+		count  int
+	}
+	mu sync.Mutex
+}
+
+// record counts one event at now
+func (t *throughput) record(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	second := now.Unix()
+	bucket := &t.buckets[second%int64(len(t.buckets))]
+	if bucket.second != second {
+		bucket.second = second
+		bucket.count = 0
+	}
+	bucket.count++
+}
+
+// recent returns the number of events within ThroughputWindow of now
+func (t *throughput) recent(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	oldest := now.Unix() - int64(len(t.buckets)) + 1
+	total := 0
+	for _, bucket := range t.buckets {
+		if bucket.second >= oldest {
+			total += bucket.count
+		}
+	}
+	return total
+}
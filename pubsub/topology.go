@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"context"
+	"sort"
+)
+
+// Kinds of Flow between topics
+const (
+	FlowRoute      = "route"       // a route republishing matching messages
+	FlowDeadLetter = "dead_letter" // a subscriber's dropped messages
+)
+
+// Flow is a path messages take from one topic into another
+type Flow struct {
+	Kind      string     `json:"kind"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	ID        string     `json:"id"` // route ID, or the subscriber's client ID
+	Predicate *Predicate `json:"predicate,omitempty"`
+}
+
+// Topology is the graph of topics and the flows between them
+type Topology struct {
+	Topics []string `json:"topics"` // sorted
+	Flows  []Flow   `json:"flows"`  // sorted by source topic
+}
+
+// Topology returns every topic with the routes and dead-letter
+// subscriptions moving messages between them. Flows may point at topics
+// that do not exist, such as a dead-letter topic not created yet.
+func (s *service) Topology(ctx context.Context) (*Topology, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	topology := &Topology{
+		Topics: make([]string, 0, len(s.topics)),
+		Flows:  make([]Flow, 0),
+	}
+	for name, topic := range s.topics {
+		topology.Topics = append(topology.Topics, name)
+
+		topic.mu.RLock()
+		for _, route := range topic.Routes {
+			topology.Flows = append(topology.Flows, Flow{Kind: FlowRoute, From: name, To: route.Target, ID: route.ID, Predicate: route.Predicate})
+		}
+		for clientID, subscriber := range topic.Subscribers {
+			if subscriber.DeadLetter != "" {
+				topology.Flows = append(topology.Flows, Flow{Kind: FlowDeadLetter, From: name, To: subscriber.DeadLetter, ID: clientID})
+			}
+		}
+		topic.mu.RUnlock()
+	}
+
+	sort.Strings(topology.Topics)
+	sort.Slice(topology.Flows, func(i, j int) bool {
+		a, b := topology.Flows[i], topology.Flows[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.ID < b.ID
+	})
+
+	return topology, nil
+}
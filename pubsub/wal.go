@@ -0,0 +1,321 @@
+package pubsub
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// DefaultWALSegmentSize is the size at which a write-ahead log segment is
+// closed and the next one started
+const DefaultWALSegmentSize = 64 << 20
+
+// walSegmentExt ends the name of every segment in Config.WALDir, which is
+// its number padded to sort in order
+const walSegmentExt = ".wal"
+
+// writeAheadLog records every change to in-memory topics since the last
+// snapshot in Config.WALDir, so a crash loses nothing a snapshot has not
+// caught up with yet. It is a series of segments of JSON records; a
+// snapshot starts a new segment before capturing the topics and deletes
+// the older ones once written.
+type writeAheadLog struct {
+	dir         string
+	segmentSize int64
+	sync        bool
+	file        *os.File
+	segment     uint64 // number of the segment being written
+	size        int64  // bytes in the segment being written
+	mu          sync.Mutex
+}
+
+// walRecord is one line of a write-ahead log segment
+type walRecord struct {
+	Created *persistedTopic `json:"created,omitempty"` // a new topic
+	Saved   *persistedTopic `json:"saved,omitempty"`   // a topic's changed settings
+	Deleted string          `json:"deleted,omitempty"` // name of a deleted topic
+	Message *Message        `json:"message,omitempty"` // a published message, with its topic and seq
+	Removed uint64          `json:"removed,omitempty"` // Seq of a message removed from Topic
+	Topic   string          `json:"topic,omitempty"`
+}
+
+// openWAL opens the write-ahead log in dir, starting a segment after the
+// ones a previous run left for replay
+func openWAL(dir string, segmentSize int64, sync bool) (*writeAheadLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create wal directory: %w", err)
+	}
+	if segmentSize <= 0 {
+		segmentSize = DefaultWALSegmentSize
+	}
+
+	w := &writeAheadLog{dir: dir, segmentSize: segmentSize, sync: sync}
+	segments, err := w.segments()
+	if err != nil {
+		return nil, err
+	}
+	next := uint64(1)
+	if len(segments) > 0 {
+		next = segments[len(segments)-1] + 1
+	}
+	if err := w.open(next); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// segments returns the numbers of the segments in the log, oldest first
+func (w *writeAheadLog) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wal directory: %w", err)
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), walSegmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if number, err := strconv.ParseUint(name, 10, 64); err == nil {
+			segments = append(segments, number)
+		}
+	}
+	slices.Sort(segments)
+	return segments, nil
+}
+
+// path returns the file of a segment
+func (w *writeAheadLog) path(segment uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", segment, walSegmentExt))
+}
+
+// open starts writing a new segment. Caller must hold w.mu or own w
+// exclusively.
+func (w *writeAheadLog) open(segment uint64) error {
+	file, err := os.OpenFile(w.path(segment), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			logging.WithContext(context.Background()).Warnw("Failed to close wal segment", "segment", w.segment, "error", err)
+		}
+	}
+	w.file = file
+	w.segment = segment
+	w.size = 0
+	return nil
+}
+
+// append writes a record, syncing it to disk with Config.WALSync, and
+// starts the next segment once this one reaches the segment size
+func (w *writeAheadLog) append(record walRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if w.sync {
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+	}
+	w.size += int64(len(line)) + 1
+
+	if w.size >= w.segmentSize {
+		if err := w.open(w.segment + 1); err != nil {
+			logging.WithContext(context.Background()).Warnw("Failed to rotate wal segment, continuing in the current one",
+				"segment", w.segment, "error", err)
+		}
+	}
+	return nil
+}
+
+// rotate starts a new segment and returns its number; records written from
+// now on are in it or later ones
+func (w *writeAheadLog) rotate() (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.open(w.segment + 1); err != nil {
+		return 0, err
+	}
+	return w.segment, nil
+}
+
+// checkpoint deletes the segments before segment, which a snapshot covers
+func (w *writeAheadLog) checkpoint(segment uint64) error {
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, number := range segments {
+		if number < segment {
+			errs = append(errs, os.Remove(w.path(number)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// close closes the segment being written. The log must not be used
+// afterwards.
+func (w *writeAheadLog) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// replay applies the records of the segments before the one being written
+// to snap, returning how many it read. Messages snap holds already are
+// skipped, as records written while a snapshot was taken are in both. A
+// record left half-written by a crash ends its segment.
+func (w *writeAheadLog) replay(snap *snapshot) (int, error) {
+	segments, err := w.segments()
+	if err != nil {
+		return 0, err
+	}
+
+	topics := make(map[string]*walTopic, len(snap.Topics))
+	for i := range snap.Topics {
+		topics[snap.Topics[i].Name] = newWALTopic(snap.Topics[i])
+	}
+
+	records := 0
+	for _, segment := range segments {
+		if segment >= w.segment {
+			break
+		}
+		read, err := w.replaySegment(segment, topics)
+		if err != nil {
+			return records, fmt.Errorf("failed to replay wal segment %s: %w", w.path(segment), err)
+		}
+		records += read
+	}
+
+	snap.Topics = snap.Topics[:0]
+	for _, topic := range topics {
+		snap.Topics = append(snap.Topics, topic.snapshot())
+	}
+	return records, nil
+}
+
+// replaySegment applies the records of one segment to topics
+func (w *writeAheadLog) replaySegment(segment uint64, topics map[string]*walTopic) (int, error) {
+	file, err := os.Open(w.path(segment))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	records := 0
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return records, nil // a line without its newline was never fully written
+		}
+		if err != nil {
+			return records, err
+		}
+
+		// Payload numbers stay exact, as publishes keep them by default
+		var record walRecord
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&record); err != nil {
+			return records, nil
+		}
+		records++
+
+		switch {
+		case record.Created != nil:
+			// A topic created while the snapshot was taken is in both
+			if topic, ok := topics[record.Created.Name]; ok && topic.saved.CreatedAt.Equal(record.Created.CreatedAt) {
+				continue
+			}
+			topics[record.Created.Name] = newWALTopic(topicSnapshot{persistedTopic: *record.Created})
+		case record.Saved != nil:
+			if topic, ok := topics[record.Saved.Name]; ok {
+				topic.saved.Settings = record.Saved.Settings
+			}
+		case record.Deleted != "":
+			delete(topics, record.Deleted)
+		case record.Message != nil:
+			if topic, ok := topics[record.Message.Topic]; ok {
+				topic.add(record.Message)
+			}
+		case record.Removed != 0:
+			if topic, ok := topics[record.Topic]; ok {
+				delete(topic.messages, record.Removed)
+			}
+		}
+	}
+}
+
+// walTopic is a topic being rebuilt from its snapshot and the log
+type walTopic struct {
+	saved    topicSnapshot
+	messages map[uint64]*Message // seq -> message
+	base     uint64              // LastSeq of the snapshot; the log holds newer messages only
+}
+
+func newWALTopic(saved topicSnapshot) *walTopic {
+	topic := &walTopic{saved: saved, messages: make(map[uint64]*Message, len(saved.Messages)), base: saved.LastSeq}
+	for _, msg := range saved.Messages {
+		topic.messages[msg.Seq] = msg
+	}
+	topic.saved.Messages = nil
+	return topic
+}
+
+// add adds a logged message newer than the snapshot. Messages logged out
+// of order by concurrent publishes are sorted by snapshot; the store keeps
+// the newest it can hold.
+func (t *walTopic) add(msg *Message) {
+	if msg.Seq <= t.base {
+		return
+	}
+	t.messages[msg.Seq] = msg
+	t.saved.LastSeq = max(t.saved.LastSeq, msg.Seq)
+}
+
+// snapshot returns the rebuilt topic, its messages oldest first
+func (t *walTopic) snapshot() topicSnapshot {
+	saved := t.saved
+	for _, msg := range t.messages {
+		saved.Messages = append(saved.Messages, msg)
+	}
+	slices.SortFunc(saved.Messages, func(a, b *Message) int { return cmp.Compare(a.Seq, b.Seq) })
+	return saved
+}
+
+// logWAL writes a record to the write-ahead log, if there is one
+func (s *service) logWAL(record walRecord) error {
+	if s.wal == nil {
+		return nil
+	}
+	return s.wal.append(record)
+}
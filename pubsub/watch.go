@@ -0,0 +1,167 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of StatsDelta
+const (
+	StatsTopicAdded   = "topic_added"
+	StatsTopicRemoved = "topic_removed"
+	StatsSubscribers  = "subscribers" // a topic's subscriber count changed
+	StatsDropped      = "dropped"     // messages were dropped for full subscriber queues
+)
+
+// StatsDelta is one change to the statistics GetStats reports
+type StatsDelta struct {
+	Kind        string    `json:"kind"`
+	Topic       string    `json:"topic"`
+	Subscribers int       `json:"subscribers"`       // the topic's subscriber count after the change
+	Dropped     uint64    `json:"dropped,omitempty"` // for StatsDropped, messages dropped since the previous delta
+	At          time.Time `json:"at"`
+}
+
+// statsKey identifies the deltas a slow watcher can coalesce
+type statsKey struct {
+	kind  string
+	topic string
+}
+
+// statsWatcher queues deltas for one WatchStats caller. Deltas the caller
+// has not received yet are coalesced per kind and topic, so a slow reader
+// never blocks the service or grows without bound.
+type statsWatcher struct {
+	mu      sync.Mutex
+	pending map[statsKey]StatsDelta
+	order   []statsKey
+	wake    chan struct{}
+}
+
+// statsWatchers are the live WatchStats callers
+type statsWatchers struct {
+	mu       sync.Mutex
+	watchers map[*statsWatcher]struct{}
+	count    atomic.Int32 // len(watchers), checked without the lock
+}
+
+// queue adds a delta, merging it into a pending one for the same kind and
+// topic: counts are the latest and drops are summed
+func (w *statsWatcher) queue(delta StatsDelta) {
+	w.mu.Lock()
+	key := statsKey{kind: delta.Kind, topic: delta.Topic}
+	if previous, exists := w.pending[key]; exists {
+		delta.Dropped += previous.Dropped
+	} else {
+		w.order = append(w.order, key)
+	}
+	w.pending[key] = delta
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the pending deltas in the order they were first queued
+func (w *statsWatcher) take() []StatsDelta {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	deltas := make([]StatsDelta, 0, len(w.order))
+	for _, key := range w.order {
+		deltas = append(deltas, w.pending[key])
+	}
+	clear(w.pending)
+	w.order = w.order[:0]
+	return deltas
+}
+
+// WatchStats streams changes to the topics, subscriber counts and drops
+// GetStats reports, so callers can keep its result current without
+// polling. Changes made before the call are not sent, so callers read
+// GetStats once after watching. The channel is closed when ctx is done or
+// the service stops.
+func (s *service) WatchStats(ctx context.Context) <-chan StatsDelta {
+	watcher := &statsWatcher{
+		pending: make(map[statsKey]StatsDelta),
+		wake:    make(chan struct{}, 1),
+	}
+	out := make(chan StatsDelta)
+
+	s.statsWatchers.mu.Lock()
+	if s.statsWatchers.watchers == nil {
+		s.statsWatchers.watchers = make(map[*statsWatcher]struct{})
+	}
+	s.statsWatchers.watchers[watcher] = struct{}{}
+	s.statsWatchers.count.Add(1)
+	s.statsWatchers.mu.Unlock()
+
+	go func() {
+		defer close(out)
+		defer func() {
+			s.statsWatchers.mu.Lock()
+			delete(s.statsWatchers.watchers, watcher)
+			s.statsWatchers.count.Add(-1)
+			s.statsWatchers.mu.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.shutdown:
+				return
+			case <-watcher.wake:
+			}
+
+			for _, delta := range watcher.take() {
+				select {
+				case out <- delta:
+				case <-ctx.Done():
+					return
+				case <-s.shutdown:
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// emitStats queues a delta for every watcher
+func (s *service) emitStats(delta StatsDelta) {
+	if s.statsWatchers.count.Load() == 0 {
+		return
+	}
+	delta.At = s.clock.Now()
+
+	s.statsWatchers.mu.Lock()
+	defer s.statsWatchers.mu.Unlock()
+
+	for watcher := range s.statsWatchers.watchers {
+		watcher.queue(delta)
+	}
+}
+
+// emitDropped reports n messages dropped for a subscriber of a topic
+func (s *service) emitDropped(topic *Topic, n int) {
+	if s.statsWatchers.count.Load() == 0 {
+		return
+	}
+	topic.mu.RLock()
+	subscribers := len(topic.Subscribers)
+	topic.mu.RUnlock()
+
+	s.emitStats(StatsDelta{Kind: StatsDropped, Topic: topic.Name, Subscribers: subscribers, Dropped: uint64(n)})
+}
+
+// emitSubscribers reports a topic's subscriber count. Caller must hold
+// topic.mu.
+func (s *service) emitSubscribers(topic *Topic) {
+	s.emitStats(StatsDelta{Kind: StatsSubscribers, Topic: topic.Name, Subscribers: len(topic.Subscribers)})
+}
@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// pendingDelivery is a message waiting in a subscriber's ordered writer
+type pendingDelivery struct {
+	ctx     context.Context
+	message *Message
+}
+
+// orderedWriter feeds one subscriber's queue from a single goroutine, in
+// the order messages were pushed. Fan-out pushes to it instead of
+// delivering directly, so a subscriber whose backpressure policy blocks
+// delays only its own messages rather than the whole topic, and a publish
+// needs no goroutine per subscriber. The goroutine runs while messages are
+// pending and exits when it runs out.
+type orderedWriter struct {
+	mu      sync.Mutex
+	pending []pendingDelivery // from head on; the array is kept between bursts
+	head    int
+	running bool
+	room    chan struct{} // closed and replaced when a message is taken while pushes wait
+	waiting int           // pushes waiting for room
+}
+
+// writerFor returns a subscriber's ordered writer, creating it on first use
+func (sub *Subscriber) writerFor() *orderedWriter {
+	sub.writerOnce.Do(func() {
+		sub.writer = &orderedWriter{room: make(chan struct{})}
+	})
+	return sub.writer
+}
+
+// pushOrdered queues a message for a subscriber's ordered writer. Once as
+// many messages are pending as the subscriber's queue holds, it waits for
+// the writer to make room, so a stuck subscriber eventually holds up
+// publishes as a direct delivery would. It gives up when the
+// subscription closes or the service stops.
+func (s *service) pushOrdered(ctx context.Context, topic *Topic, sub *Subscriber, message *Message) {
+	writer := sub.writerFor()
+	limit := max(cap(sub.MessageChan), 1)
+
+	writer.mu.Lock()
+	for len(writer.pending)-writer.head >= limit {
+		room := writer.room
+		writer.waiting++
+		writer.mu.Unlock()
+
+		var stopped bool
+		select {
+		case <-room:
+		case <-sub.guard.doneChan():
+			stopped = true
+		case <-s.shutdown:
+			stopped = true
+		}
+
+		writer.mu.Lock()
+		writer.waiting--
+		if stopped {
+			writer.mu.Unlock()
+			return
+		}
+	}
+	if writer.head > 0 && len(writer.pending) == cap(writer.pending) {
+		// Slide what is pending to the front rather than growing the array
+		n := copy(writer.pending, writer.pending[writer.head:])
+		clear(writer.pending[n:])
+		writer.pending = writer.pending[:n]
+		writer.head = 0
+	}
+	writer.pending = append(writer.pending, pendingDelivery{ctx: ctx, message: message})
+	start := !writer.running
+	writer.running = true
+	writer.mu.Unlock()
+
+	if start {
+		// Past the goroutine budget the publisher delivers itself
+		s.tasks.goOrRun(TaskWriter, func() { s.runWriter(topic, sub, writer) })
+	}
+}
+
+// runWriter delivers a subscriber's pending messages one at a time, until
+// none are left
+func (s *service) runWriter(topic *Topic, sub *Subscriber, writer *orderedWriter) {
+	for {
+		writer.mu.Lock()
+		if writer.head == len(writer.pending) {
+			// Reuse the array for the next burst rather than growing a new one
+			writer.running = false
+			writer.pending = writer.pending[:0]
+			writer.head = 0
+			writer.mu.Unlock()
+			return
+		}
+		next := writer.pending[writer.head]
+		writer.pending[writer.head] = pendingDelivery{}
+		writer.head++
+		if writer.waiting > 0 {
+			close(writer.room)
+			writer.room = make(chan struct{})
+		}
+		writer.mu.Unlock()
+
+		// A closed subscription takes nothing, so the rest drains quickly.
+		// Dead-letter on its own goroutine, as the target may be ordered too.
+		if dropped := s.deliver(next.ctx, topic, sub, next.message); len(dropped) > 0 {
+			s.deadLetterLater(next.ctx, topic.Name, sub, dropped)
+		}
+	}
+}
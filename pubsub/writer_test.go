@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+)
+
+// benchmarkOrderedWriter pushes bursts of messages through a subscriber's
+// ordered writer and waits for each to be delivered. Unpooled, the pending
+// array is dropped after every burst, so each burst grows a new one.
+func benchmarkOrderedWriter(b *testing.B, pooled bool) {
+	const burst = 64
+
+	s := newService(nil)
+	ctx := context.Background()
+	if err := s.CreateTopic(ctx, "bench", "", &TopicOptions{ChannelBufferSize: burst}); err != nil {
+		b.Fatal(err)
+	}
+	sub, err := s.Subscribe(ctx, "bench", "bench", &SubscribeOptions{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	topic := s.topics["bench"]
+	message := &Message{ID: "m", Topic: topic.Name}
+	writer := sub.writerFor()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for range burst {
+			s.pushOrdered(ctx, topic, sub, message)
+		}
+		for range burst {
+			<-sub.MessageChan
+		}
+
+		// Wait for the writer to see its queue empty and stop
+		for {
+			writer.mu.Lock()
+			running := writer.running
+			if !running && !pooled {
+				writer.pending = nil
+			}
+			writer.mu.Unlock()
+			if !running {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkOrderedWriter(b *testing.B) {
+	b.Run("pooled", func(b *testing.B) { benchmarkOrderedWriter(b, true) })
+	b.Run("unpooled", func(b *testing.B) { benchmarkOrderedWriter(b, false) })
+}
@@ -0,0 +1,505 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// ActorContextKey is the gin context key holding the authenticated *Actor
+const ActorContextKey = "admin_actor"
+
+// ActorFromContext returns the admin actor set by the admin middleware
+func ActorFromContext(c *gin.Context) *Actor {
+	actor, _ := c.Get(ActorContextKey)
+	a, _ := actor.(*Actor)
+	return a
+}
+
+// RequireScope rejects admin requests whose actor lacks scope. It must run
+// after the admin middleware.
+func RequireScope(scope Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := ActorFromContext(c)
+		if actor == nil || !actor.Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing scope " + string(scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Endpoint interface for admin endpoints
+type Endpoint interface {
+	CreateToken(c *gin.Context)
+	ListTokens(c *gin.Context)
+	RevokeToken(c *gin.Context)
+	GetAuditLog(c *gin.Context)
+	KickSubscriber(c *gin.Context)
+	EnableUser(c *gin.Context)
+	SetUserExempt(c *gin.Context)
+	SetNamespace(c *gin.Context)
+	ListNamespaces(c *gin.Context)
+	DeleteNamespace(c *gin.Context)
+	GetTopology(c *gin.Context)
+	GetHotspots(c *gin.Context)
+	PlaceLegalHold(c *gin.Context)
+	ReleaseLegalHold(c *gin.Context)
+	SetTextIndex(c *gin.Context)
+	SearchMessages(c *gin.Context)
+}
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// CreateToken handles POST /admin/tokens
+func (e *endpoint) CreateToken(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Error binding JSON", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := ActorFromContext(c)
+	response, err := e.service.CreateToken(actor, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid token") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "token "+strings.TrimSpace(req.Name)+" already exists" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error creating admin token", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	log.Infow("Admin token created", "token_id", response.Token.ID, "name", response.Token.Name,
+		"scopes", response.Token.Scopes, "created_by", actor.Name)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListTokens handles GET /admin/tokens
+func (e *endpoint) ListTokens(c *gin.Context) {
+	tokens := e.service.ListTokens()
+	c.JSON(http.StatusOK, ListTokensResponse{Tokens: tokens, Count: len(tokens)})
+}
+
+// RevokeToken handles DELETE /admin/tokens/{id}
+func (e *endpoint) RevokeToken(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenID := c.Param("id")
+	if err := e.service.RevokeToken(tokenID); err != nil {
+		if err.Error() == "token "+tokenID+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+			return
+		}
+		log.Errorw("Error revoking admin token", "error", err.Error(), "token_id", tokenID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	log.Infow("Admin token revoked", "token_id", tokenID, "revoked_by", ActorFromContext(c).Name)
+	c.JSON(http.StatusOK, RevokeTokenResponse{Status: "revoked", ID: tokenID})
+}
+
+// GetAuditLog handles GET /admin/audit?limit=...
+func (e *endpoint) GetAuditLog(c *gin.Context) {
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	entries := e.service.AuditLog(limit)
+	c.JSON(http.StatusOK, AuditLogResponse{Entries: entries, Count: len(entries)})
+}
+
+// KickSubscriber handles DELETE /admin/topics/{name}/subscribers/{client_id}
+func (e *endpoint) KickSubscriber(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topic := c.Param("name")
+	clientID := c.Param("client_id")
+	if err := e.service.KickSubscriber(topic, clientID); err != nil {
+		if err.Error() == "topic "+topic+" not found" || strings.HasSuffix(err.Error(), "not subscribed to topic "+topic) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error kicking subscriber", "error", err.Error(), "topic", topic, "client_id", clientID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove subscriber"})
+		return
+	}
+
+	log.Infow("Subscriber kicked", "topic", topic, "client_id", clientID, "kicked_by", ActorFromContext(c).Name)
+	c.JSON(http.StatusOK, KickSubscriberResponse{Status: "kicked", Topic: topic, ClientID: clientID})
+}
+
+// EnableUser handles POST /admin/users/{id}/enable
+func (e *endpoint) EnableUser(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.Param("id")
+	user, err := e.service.EnableUser(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Errorw("Error enabling user", "error", err.Error(), "user_id", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable user"})
+		return
+	}
+
+	log.Infow("User enabled", "user_id", userID, "enabled_by", ActorFromContext(c).Name)
+	c.JSON(http.StatusOK, UserLifecycleResponse{User: user})
+}
+
+// SetUserExempt handles PUT /admin/users/{id}/exempt
+func (e *endpoint) SetUserExempt(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req SetUserExemptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Error binding JSON", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.Param("id")
+	user, err := e.service.SetUserExempt(userID, *req.Exempt)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Errorw("Error setting user exemption", "error", err.Error(), "user_id", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set user exemption"})
+		return
+	}
+
+	log.Infow("User lifecycle exemption set", "user_id", userID, "exempt", *req.Exempt, "set_by", ActorFromContext(c).Name)
+	c.JSON(http.StatusOK, UserLifecycleResponse{User: user})
+}
+
+// SetNamespace handles PUT /admin/namespaces/{prefix}, with the prefix's
+// separators escaped as %2F
+func (e *endpoint) SetNamespace(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req SetNamespaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Error binding JSON", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix := c.Param("prefix")
+	actor := ActorFromContext(c)
+	namespace, err := e.service.SetNamespace(actor, prefix, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting namespace", "error", err.Error(), "namespace", prefix)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set namespace"})
+		return
+	}
+
+	log.Infow("Namespace set", "namespace", prefix, "set_by", actor.Name)
+	c.JSON(http.StatusOK, NamespaceResponse{Namespace: namespace})
+}
+
+// ListNamespaces handles GET /admin/namespaces?prefix=tenant
+func (e *endpoint) ListNamespaces(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespaces, err := e.service.ListNamespaces(c.Query("prefix"))
+	if err != nil {
+		log.Errorw("Error listing namespaces", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list namespaces"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListNamespacesResponse{Namespaces: namespaces, Count: len(namespaces)})
+}
+
+// DeleteNamespace handles DELETE /admin/namespaces/{prefix}
+func (e *endpoint) DeleteNamespace(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix := c.Param("prefix")
+	actor := ActorFromContext(c)
+	if err := e.service.DeleteNamespace(actor, prefix); err != nil {
+		if err.Error() == "namespace "+prefix+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Namespace not found"})
+			return
+		}
+		log.Errorw("Error deleting namespace", "error", err.Error(), "namespace", prefix)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete namespace"})
+		return
+	}
+
+	log.Infow("Namespace deleted", "namespace", prefix, "deleted_by", actor.Name)
+	c.JSON(http.StatusOK, DeleteNamespaceResponse{Status: "deleted", Namespace: prefix})
+}
+
+// PlaceLegalHold handles PUT /admin/topics/{name}/legal-hold
+func (e *endpoint) PlaceLegalHold(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req PlaceLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Error binding JSON", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	topic := c.Param("name")
+	actor := ActorFromContext(c)
+	hold, err := e.service.PlaceLegalHold(actor, topic, req.Reason)
+	if err != nil {
+		if err.Error() == "topic "+topic+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error placing legal hold", "error", err.Error(), "topic", topic)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place legal hold"})
+		return
+	}
+
+	log.Infow("Legal hold placed", "topic", topic, "placed_by", actor.Name)
+	c.JSON(http.StatusOK, LegalHoldResponse{Topic: topic, LegalHold: hold})
+}
+
+// ReleaseLegalHold handles DELETE /admin/topics/{name}/legal-hold
+func (e *endpoint) ReleaseLegalHold(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topic := c.Param("name")
+	actor := ActorFromContext(c)
+	if err := e.service.ReleaseLegalHold(actor, topic); err != nil {
+		if err.Error() == "topic "+topic+" not found" || err.Error() == "topic "+topic+" has no legal hold" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error releasing legal hold", "error", err.Error(), "topic", topic)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release legal hold"})
+		return
+	}
+
+	log.Infow("Legal hold released", "topic", topic, "released_by", actor.Name)
+	c.JSON(http.StatusOK, ReleaseLegalHoldResponse{Status: "released", Topic: topic})
+}
+
+// GetTopology handles GET /admin/topology?format=json|dot
+func (e *endpoint) GetTopology(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dot" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or dot"})
+		return
+	}
+
+	topology, err := e.service.Topology()
+	if err != nil {
+		log.Errorw("Error building topology", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build topology"})
+		return
+	}
+
+	if format == "dot" {
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(RenderDOT(topology)))
+		return
+	}
+	c.JSON(http.StatusOK, topology)
+}
+
+// GetHotspots handles GET /admin/hotspots?limit=...
+func (e *endpoint) GetHotspots(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := DefaultHotspots
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > MaxHotspots {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(MaxHotspots)})
+			return
+		}
+		limit = parsed
+	}
+
+	hotspots, err := e.service.Hotspots(limit)
+	if err != nil {
+		log.Errorw("Error computing hotspots", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute hotspots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hotspots)
+}
+
+// SetTextIndex handles PUT /admin/topics/{name}/text-index
+func (e *endpoint) SetTextIndex(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req SetTextIndexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Error binding JSON", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	topic := c.Param("name")
+	actor := ActorFromContext(c)
+	if err := e.service.SetTextIndex(actor, topic, req.Size); err != nil {
+		if err.Error() == "topic "+topic+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting text index", "error", err.Error(), "topic", topic)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set text index"})
+		return
+	}
+
+	log.Infow("Text index set", "topic", topic, "size", req.Size, "set_by", actor.Name)
+	c.JSON(http.StatusOK, TextIndexResponse{Topic: topic, Size: req.Size})
+}
+
+// SearchMessages handles GET /admin/search?q=...&topic=...&limit=...
+func (e *endpoint) SearchMessages(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := c.Query("q")
+	limit := DefaultSearchResults
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > MaxSearchResults {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(MaxSearchResults)})
+			return
+		}
+		limit = parsed
+	}
+
+	topics := c.QueryArray("topic")
+	results, err := e.service.SearchMessages(query, topics, limit)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "topic ") && strings.HasSuffix(err.Error(), " not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error searching messages", "error", err.Error(), "query", query)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SearchResponse{Query: query, Results: results, Count: len(results)})
+}
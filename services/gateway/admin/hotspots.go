@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+)
+
+// Hotspots ranks topics by recent throughput, drops, subscriber backlog and
+// buffered bytes, keeping the top limit of each. Topics with nothing to
+// rank by are left out, so an idle deployment returns empty lists.
+func (s *service) Hotspots(limit int) (*HotspotsResponse, error) {
+	loads, err := s.pubsubService.TopicLoads(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &HotspotsResponse{
+		Window:      pubsub.ThroughputWindow.String(),
+		Topics:      len(loads),
+		GeneratedAt: time.Now(),
+	}
+	for _, load := range loads {
+		response.Totals.Subscribers += load.Subscribers
+		response.Totals.Published1m += load.Published1m
+		response.Totals.Dropped1m += load.Dropped1m
+		response.Totals.Backlog += load.Backlog
+		response.Totals.Bytes += load.Bytes
+	}
+
+	response.Throughput = topLoads(loads, limit, func(l pubsub.TopicLoad) int64 { return int64(l.Published1m) })
+	response.Drops = topLoads(loads, limit, func(l pubsub.TopicLoad) int64 { return int64(l.Dropped1m) })
+	response.Backlog = topLoads(loads, limit, func(l pubsub.TopicLoad) int64 { return int64(l.Backlog) })
+	response.Memory = topLoads(loads, limit, func(l pubsub.TopicLoad) int64 { return l.Bytes })
+
+	return response, nil
+}
+
+// topLoads returns up to limit loads with a positive key, highest first.
+// Ties keep loads in topic order.
+func topLoads(loads []pubsub.TopicLoad, limit int, key func(pubsub.TopicLoad) int64) []pubsub.TopicLoad {
+	top := make([]pubsub.TopicLoad, 0, limit)
+	for _, load := range loads {
+		if key(load) > 0 {
+			top = append(top, load)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool { return key(top[i]) > key(top[j]) })
+
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
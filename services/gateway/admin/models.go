@@ -0,0 +1,274 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
+)
+
+// Scope is a permission an admin token can be granted
+type Scope string
+
+const (
+	ScopeTokens Scope = "tokens:manage" // create, list and revoke admin tokens
+	ScopeAudit  Scope = "audit:read"    // read the audit log
+
+	ScopeSubscribers Scope = "subscribers:manage" // disconnect subscribers from topics
+	ScopeUsers       Scope = "users:manage"       // re-enable accounts and exempt them from lifecycle policies
+	ScopeNamespaces  Scope = "namespaces:manage"  // set the defaults topics inherit from their namespaces
+	ScopeTopology    Scope = "topology:read"      // read the message flow graph
+	ScopeHotspots    Scope = "hotspots:read"      // read the busiest topics
+	ScopeLegalHolds  Scope = "legal_holds:manage" // place and release legal holds on topics
+
+	ScopeSearch      Scope = "search:manage"      // index topics' payload text and search it
+	ScopeMaintenance Scope = "maintenance:manage" // schedule and cancel maintenance windows
+	ScopeMetrics     Scope = "metrics:read"       // scrape /metrics
+)
+
+// Scopes lists every scope a token can be granted
+var Scopes = []Scope{ScopeTokens, ScopeAudit, ScopeSubscribers, ScopeUsers, ScopeNamespaces, ScopeTopology, ScopeHotspots, ScopeLegalHolds, ScopeSearch,
+	ScopeMaintenance, ScopeMetrics}
+
+// TokenPrefix marks admin token secrets, so they can share the
+// Authorization header with user JWTs
+const TokenPrefix = "adm_"
+
+// Token lifetimes and audit log size
+const (
+	DefaultTokenTTL = 30 * 24 * time.Hour
+	MaxTokenTTL     = 365 * 24 * time.Hour
+	AuditLogSize    = 1000
+)
+
+// Topics listed per hotspot ranking
+const (
+	DefaultHotspots = 5
+	MaxHotspots     = 100
+)
+
+// Messages returned per search
+const (
+	DefaultSearchResults = 50
+	MaxSearchResults     = 1000
+)
+
+// Actor kinds
+const (
+	ActorToken = "token" // an admin token, identified by its name
+	ActorUser  = "user"  // a user in ADMIN_USERS, identified by user ID
+)
+
+// Actor is the principal performing an admin action
+type Actor struct {
+	Kind   string  `json:"kind"`
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"-"`
+}
+
+// Allows reports whether the actor holds scope. Admin users hold every scope.
+func (a *Actor) Allows(scope Scope) bool {
+	if a.Kind == ActorUser {
+		return true
+	}
+	for _, granted := range a.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Token is an admin API token. The secret is only returned on creation;
+// the service keeps its hash.
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedBy  string     `json:"created_by"` // name of the creating actor
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	hash string
+}
+
+// Active reports whether the token can authenticate at now
+func (t *Token) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// AuditEntry records one admin action and the actor that performed it
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	ActorKind string    `json:"actor_kind"`
+	ActorID   string    `json:"actor_id"`
+	ActorName string    `json:"actor_name"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// REST API Models
+type CreateTokenRequest struct {
+	Name      string  `json:"name" binding:"required"`
+	Scopes    []Scope `json:"scopes" binding:"required"`
+	ExpiresIn string  `json:"expires_in,omitempty"` // Go duration, e.g. "720h"; defaults to DefaultTokenTTL
+}
+
+type CreateTokenResponse struct {
+	Token  Token  `json:"token"`
+	Secret string `json:"secret"` // shown once
+}
+
+type ListTokensResponse struct {
+	Tokens []Token `json:"tokens"`
+	Count  int     `json:"count"`
+}
+
+type RevokeTokenResponse struct {
+	Status string `json:"status"`
+	ID     string `json:"id"`
+}
+
+type KickSubscriberResponse struct {
+	Status   string `json:"status"`
+	Topic    string `json:"topic"`
+	ClientID string `json:"client_id"`
+}
+
+// SetUserExemptRequest exempts an account from being disabled or purged for
+// inactivity, or lifts the exemption
+type SetUserExemptRequest struct {
+	Exempt *bool `json:"exempt" binding:"required"`
+}
+
+type UserLifecycleResponse struct {
+	User *user.User `json:"user"`
+}
+
+// SetNamespaceRequest sets the defaults inherited by topics created under a
+// namespace; zero values leave a setting to parent namespaces
+type SetNamespaceRequest struct {
+	RingBufferSize    int              `json:"ring_buffer_size,omitempty"`
+	ChannelBufferSize int              `json:"channel_buffer_size,omitempty"`
+	Retention         pubsub.Retention `json:"retention"`
+
+	// JSON Schema the payloads of topics created under the namespace must
+	// match
+	JSONSchema map[string]interface{} `json:"json_schema,omitempty"`
+}
+
+type NamespaceResponse struct {
+	Namespace pubsub.Namespace `json:"namespace"`
+}
+
+type ListNamespacesResponse struct {
+	Namespaces []pubsub.Namespace `json:"namespaces"`
+	Count      int                `json:"count"`
+}
+
+type DeleteNamespaceResponse struct {
+	Status    string `json:"status"`
+	Namespace string `json:"namespace"`
+}
+
+// PlaceLegalHoldRequest places a legal hold on a topic, recording why
+type PlaceLegalHoldRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type LegalHoldResponse struct {
+	Topic     string            `json:"topic"`
+	LegalHold *pubsub.LegalHold `json:"legal_hold"`
+}
+
+type ReleaseLegalHoldResponse struct {
+	Status string `json:"status"`
+	Topic  string `json:"topic"`
+}
+
+// SetTextIndexRequest indexes the payload text of a topic's newest size
+// messages for search; 0 drops the index
+type SetTextIndexRequest struct {
+	Size int `json:"size"`
+}
+
+type TextIndexResponse struct {
+	Topic string `json:"topic"`
+	Size  int    `json:"size"`
+}
+
+type SearchResponse struct {
+	Query   string                `json:"query"`
+	Results []pubsub.SearchResult `json:"results"` // newest first
+	Count   int                   `json:"count"`
+}
+
+// Kinds of topology nodes
+const (
+	NodeTopic  = "topic"
+	NodeBridge = "bridge"
+	NodeDigest = "digest"
+)
+
+// Kinds of topology edges besides pubsub.FlowRoute and
+// pubsub.FlowDeadLetter
+const (
+	EdgeBridge = "bridge"
+	EdgeDigest = "digest"
+)
+
+// TopologyNode is a topic, or a sink messages leave the deployment through
+type TopologyNode struct {
+	ID      string `json:"id"` // "topic:<name>", "bridge:<id>" or "digest:<id>"
+	Kind    string `json:"kind"`
+	Label   string `json:"label"`
+	Missing bool   `json:"missing,omitempty"` // a topic flowed into that does not exist
+}
+
+// TopologyEdge is a path messages take from a topic
+type TopologyEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`              // route, subscriber, bridge or digest ID
+	Label string `json:"label,omitempty"` // route predicate
+}
+
+type TopologyResponse struct {
+	Nodes       []TopologyNode `json:"nodes"`
+	Edges       []TopologyEdge `json:"edges"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// HotspotTotals sums the topic loads over every topic
+type HotspotTotals struct {
+	Subscribers int   `json:"subscribers"`
+	Published1m int   `json:"published_1m"`
+	Dropped1m   int   `json:"dropped_1m"`
+	Backlog     int   `json:"backlog"`
+	Bytes       int64 `json:"bytes"`
+}
+
+// HotspotsResponse lists the topics with the most publishes and drops
+// within Window, the largest subscriber backlog and the most bytes held
+// for replay
+type HotspotsResponse struct {
+	Window      string             `json:"window"`
+	Topics      int                `json:"topics"`
+	Totals      HotspotTotals      `json:"totals"`
+	Throughput  []pubsub.TopicLoad `json:"throughput"`
+	Drops       []pubsub.TopicLoad `json:"drops"`
+	Backlog     []pubsub.TopicLoad `json:"backlog"`
+	Memory      []pubsub.TopicLoad `json:"memory"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+type AuditLogResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Count   int          `json:"count"`
+}
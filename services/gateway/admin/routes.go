@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	endpoint     Endpoint
+	authenticate gin.HandlerFunc
+}
+
+// NewRouteRegistrar creates a new route registrar. authenticate is the
+// admin middleware, which accepts admin tokens and admin users' JWTs.
+func NewRouteRegistrar(service Service, authenticate gin.HandlerFunc) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		endpoint:     NewEndpoint(service),
+		authenticate: authenticate,
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	// admin routes authenticate separately, see RegisterUnAuthRoutes
+}
+
+// RegisterUnAuthRoutes registers the /admin routes behind the admin
+// middleware rather than user authentication
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	adminGroup := unAuthGroup.Group("/admin", r.authenticate)
+
+	adminGroup.POST("/tokens", RequireScope(ScopeTokens), r.endpoint.CreateToken)
+	adminGroup.GET("/tokens", RequireScope(ScopeTokens), r.endpoint.ListTokens)
+	adminGroup.DELETE("/tokens/:id", RequireScope(ScopeTokens), r.endpoint.RevokeToken)
+	adminGroup.GET("/audit", RequireScope(ScopeAudit), r.endpoint.GetAuditLog)
+	adminGroup.DELETE("/topics/:name/subscribers/:client_id", RequireScope(ScopeSubscribers), r.endpoint.KickSubscriber)
+	adminGroup.POST("/users/:id/enable", RequireScope(ScopeUsers), r.endpoint.EnableUser)
+	adminGroup.PUT("/users/:id/exempt", RequireScope(ScopeUsers), r.endpoint.SetUserExempt)
+	adminGroup.GET("/namespaces", RequireScope(ScopeNamespaces), r.endpoint.ListNamespaces)
+	adminGroup.PUT("/namespaces/:prefix", RequireScope(ScopeNamespaces), r.endpoint.SetNamespace)
+	adminGroup.DELETE("/namespaces/:prefix", RequireScope(ScopeNamespaces), r.endpoint.DeleteNamespace)
+	adminGroup.GET("/topology", RequireScope(ScopeTopology), r.endpoint.GetTopology)
+	adminGroup.GET("/hotspots", RequireScope(ScopeHotspots), r.endpoint.GetHotspots)
+	adminGroup.PUT("/topics/:name/legal-hold", RequireScope(ScopeLegalHolds), r.endpoint.PlaceLegalHold)
+	adminGroup.DELETE("/topics/:name/legal-hold", RequireScope(ScopeLegalHolds), r.endpoint.ReleaseLegalHold)
+	adminGroup.PUT("/topics/:name/text-index", RequireScope(ScopeSearch), r.endpoint.SetTextIndex)
+	adminGroup.GET("/search", RequireScope(ScopeSearch), r.endpoint.SearchMessages)
+}
@@ -0,0 +1,341 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
+	"github.com/google/uuid"
+)
+
+// Service interface for admin token and audit operations
+type Service interface {
+	CreateToken(actor *Actor, req CreateTokenRequest) (CreateTokenResponse, error)
+	ListTokens() []Token
+	RevokeToken(id string) error
+	// Authenticate resolves an admin token secret to its actor
+	Authenticate(secret string) (*Actor, error)
+	// IsAdminUser reports whether a JWT-authenticated user has the admin role
+	IsAdminUser(userID string) bool
+	Record(entry AuditEntry)
+	AuditLog(limit int) []AuditEntry
+	// KickSubscriber disconnects a client from a topic; the client is sent
+	// a subscription_closed event with reason admin_kick
+	KickSubscriber(topic, clientID string) error
+	// EnableUser re-enables an account disabled for inactivity
+	EnableUser(userID string) (*user.User, error)
+	// SetUserExempt exempts an account from lifecycle policies
+	SetUserExempt(userID string, exempt bool) (*user.User, error)
+	// SetNamespace sets the defaults topics created under prefix inherit
+	SetNamespace(actor *Actor, prefix string, req SetNamespaceRequest) (pubsub.Namespace, error)
+	ListNamespaces(prefix string) ([]pubsub.Namespace, error)
+	DeleteNamespace(actor *Actor, prefix string) error
+	// Topology returns the graph of topics and the flows between them
+	Topology() (*TopologyResponse, error)
+	// Hotspots ranks the busiest topics for incident triage
+	Hotspots(limit int) (*HotspotsResponse, error)
+	// PlaceLegalHold preserves a topic and its messages until released
+	PlaceLegalHold(actor *Actor, topic, reason string) (*pubsub.LegalHold, error)
+	ReleaseLegalHold(actor *Actor, topic string) error
+	// SetTextIndex indexes the payload text of a topic's newest messages
+	SetTextIndex(actor *Actor, topic string, size int) error
+	// SearchMessages finds the newest indexed messages containing every
+	// word of query, on topics or on every indexed topic
+	SearchMessages(query string, topics []string, limit int) ([]pubsub.SearchResult, error)
+}
+type service struct {
+	pubsubService pubsub.Service
+	userService   user.Service
+	bridges       BridgeSource
+	digests       DigestSource
+	adminUsers    map[string]bool
+	tokens        map[string]*Token // id -> token
+	audit         []AuditEntry      // oldest first, at most AuditLogSize
+	mu            sync.RWMutex
+}
+
+// NewService creates a new admin service. adminUsers are the user IDs
+// granted the admin role. bridges and digests, which may be nil, add
+// their sinks to the topology.
+func NewService(adminUsers []string, userService user.Service, bridges BridgeSource, digests DigestSource) Service {
+	s := &service{
+		pubsubService: pubsub.GetService(),
+		userService:   userService,
+		bridges:       bridges,
+		digests:       digests,
+		adminUsers:    make(map[string]bool),
+		tokens:        make(map[string]*Token),
+	}
+	for _, userID := range adminUsers {
+		if userID = strings.TrimSpace(userID); userID != "" {
+			s.adminUsers[userID] = true
+		}
+	}
+	return s
+}
+
+// AdminUsersFromEnv reads the comma-separated ADMIN_USERS list
+func AdminUsersFromEnv() []string {
+	value := os.Getenv("ADMIN_USERS")
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// CreateToken issues a token. A token-authenticated actor can only grant
+// scopes it holds itself.
+func (s *service) CreateToken(actor *Actor, req CreateTokenRequest) (CreateTokenResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return CreateTokenResponse{}, fmt.Errorf("invalid token: name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return CreateTokenResponse{}, fmt.Errorf("invalid token: at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !knownScope(scope) {
+			return CreateTokenResponse{}, fmt.Errorf("invalid token: unknown scope %q", scope)
+		}
+		if !actor.Allows(scope) {
+			return CreateTokenResponse{}, fmt.Errorf("forbidden: cannot grant scope %q", scope)
+		}
+	}
+
+	ttl := DefaultTokenTTL
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 || parsed > MaxTokenTTL {
+			return CreateTokenResponse{}, fmt.Errorf("invalid token: expires_in must be a positive duration up to %s", MaxTokenTTL)
+		}
+		ttl = parsed
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return CreateTokenResponse{}, err
+	}
+
+	now := time.Now()
+	token := &Token{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Scopes:    append([]Scope(nil), req.Scopes...),
+		CreatedBy: actor.Name,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		hash:      hashSecret(secret),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.tokens {
+		if existing.Name == name && existing.Active(now) {
+			return CreateTokenResponse{}, fmt.Errorf("token %s already exists", name)
+		}
+	}
+	s.tokens[token.ID] = token
+
+	return CreateTokenResponse{Token: *token, Secret: secret}, nil
+}
+
+// ListTokens returns all tokens, including revoked and expired ones, oldest
+// first
+func (s *service) ListTokens() []Token {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]Token, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, *token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+
+	return tokens
+}
+
+// RevokeToken stops a token from authenticating. Revoked tokens stay listed
+// so past audit entries remain attributable.
+func (s *service) RevokeToken(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, exists := s.tokens[id]
+	if !exists {
+		return fmt.Errorf("token %s not found", id)
+	}
+	if token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+	}
+
+	return nil
+}
+
+// KickSubscriber removes a client's subscription to a topic
+func (s *service) KickSubscriber(topic, clientID string) error {
+	return s.pubsubService.KickSubscriber(context.Background(), topic, clientID)
+}
+
+// EnableUser re-enables an account disabled for inactivity
+func (s *service) EnableUser(userID string) (*user.User, error) {
+	return s.userService.EnableUser(userID)
+}
+
+// SetUserExempt exempts an account from lifecycle policies, or lifts the
+// exemption
+func (s *service) SetUserExempt(userID string, exempt bool) (*user.User, error) {
+	return s.userService.SetLifecycleExempt(userID, exempt)
+}
+
+// SetNamespace sets the defaults topics created under prefix inherit
+func (s *service) SetNamespace(actor *Actor, prefix string, req SetNamespaceRequest) (pubsub.Namespace, error) {
+	ctx := pubsub.WithActor(context.Background(), actor.Name)
+	namespace := pubsub.Namespace{
+		Prefix:    prefix,
+		Options:   pubsub.TopicOptions{RingBufferSize: req.RingBufferSize, ChannelBufferSize: req.ChannelBufferSize},
+		Retention: req.Retention,
+	}
+	if req.JSONSchema != nil {
+		schema, err := pubsub.NewJSONSchema(req.JSONSchema)
+		if err != nil {
+			return pubsub.Namespace{}, err
+		}
+		namespace.Options.JSONSchema = schema
+	}
+	stored, err := s.pubsubService.SetNamespace(ctx, namespace)
+	if err != nil {
+		return pubsub.Namespace{}, err
+	}
+	return *stored, nil
+}
+
+// ListNamespaces returns the namespaces under prefix, or all when it is
+// empty
+func (s *service) ListNamespaces(prefix string) ([]pubsub.Namespace, error) {
+	return s.pubsubService.ListNamespaces(context.Background(), prefix)
+}
+
+// DeleteNamespace removes a namespace's defaults
+func (s *service) DeleteNamespace(actor *Actor, prefix string) error {
+	return s.pubsubService.DeleteNamespace(pubsub.WithActor(context.Background(), actor.Name), prefix)
+}
+
+// PlaceLegalHold places a legal hold on a topic in the actor's name
+func (s *service) PlaceLegalHold(actor *Actor, topic, reason string) (*pubsub.LegalHold, error) {
+	return s.pubsubService.PlaceLegalHold(pubsub.WithActor(context.Background(), actor.Name), topic, reason)
+}
+
+// ReleaseLegalHold lifts a topic's legal hold in the actor's name
+func (s *service) ReleaseLegalHold(actor *Actor, topic string) error {
+	return s.pubsubService.ReleaseLegalHold(pubsub.WithActor(context.Background(), actor.Name), topic)
+}
+
+// SetTextIndex sets a topic's text index in the actor's name
+func (s *service) SetTextIndex(actor *Actor, topic string, size int) error {
+	return s.pubsubService.SetTextIndex(pubsub.WithActor(context.Background(), actor.Name), topic, size)
+}
+
+// SearchMessages searches the indexed topics' payload text
+func (s *service) SearchMessages(query string, topics []string, limit int) ([]pubsub.SearchResult, error) {
+	return s.pubsubService.SearchMessages(context.Background(), query, topics, limit)
+}
+
+// Authenticate finds the active token matching secret
+func (s *service) Authenticate(secret string) (*Actor, error) {
+	hash := hashSecret(secret)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token.hash), []byte(hash)) != 1 {
+			continue
+		}
+		if !token.Active(now) {
+			return nil, fmt.Errorf("token %s is revoked or expired", token.Name)
+		}
+		token.LastUsedAt = &now
+		return &Actor{
+			Kind:   ActorToken,
+			ID:     token.ID,
+			Name:   token.Name,
+			Scopes: token.Scopes,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown admin token")
+}
+
+// IsAdminUser reports whether the user has the admin role
+func (s *service) IsAdminUser(userID string) bool {
+	return s.adminUsers[userID]
+}
+
+// Record appends an entry to the audit log, dropping the oldest entry once
+// the log holds AuditLogSize entries
+func (s *service) Record(entry AuditEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.audit) >= AuditLogSize {
+		s.audit = s.audit[1:]
+	}
+	s.audit = append(s.audit, entry)
+}
+
+// AuditLog returns up to limit of the most recent entries, newest first
+func (s *service) AuditLog(limit int) []AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if limit <= 0 || limit > len(s.audit) {
+		limit = len(s.audit)
+	}
+
+	entries := make([]AuditEntry, 0, limit)
+	for i := len(s.audit) - 1; i >= len(s.audit)-limit; i-- {
+		entries = append(entries, s.audit[i])
+	}
+
+	return entries
+}
+
+// knownScope reports whether scope can be granted
+func knownScope(scope Scope) bool {
+	for _, known := range Scopes {
+		if known == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// newSecret generates a random token secret
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return TokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// hashSecret returns the stored form of a secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
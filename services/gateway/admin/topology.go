@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/bridge"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/digest"
+)
+
+// BridgeSource lists the chat bridges posting topics to Slack and Discord
+type BridgeSource interface {
+	AllBridges() []*bridge.Bridge
+}
+
+// DigestSource lists the email digests of topics
+type DigestSource interface {
+	AllDigests() []*digest.Digest
+}
+
+// Topology returns the message flow of the deployment: every topic, the
+// routes and dead-letter subscriptions between topics, and the bridges and
+// digests carrying messages out of it
+func (s *service) Topology() (*TopologyResponse, error) {
+	flows, err := s.pubsubService.Topology(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	topology := &TopologyResponse{
+		Nodes:       make([]TopologyNode, 0, len(flows.Topics)),
+		Edges:       make([]TopologyEdge, 0, len(flows.Flows)),
+		GeneratedAt: time.Now(),
+	}
+	topics := make(map[string]bool, len(flows.Topics))
+	for _, name := range flows.Topics {
+		topics[name] = true
+		topology.Nodes = append(topology.Nodes, TopologyNode{ID: topicNodeID(name), Kind: NodeTopic, Label: name})
+	}
+	addTopic := func(name string) {
+		if !topics[name] {
+			topics[name] = true
+			topology.Nodes = append(topology.Nodes, TopologyNode{ID: topicNodeID(name), Kind: NodeTopic, Label: name, Missing: true})
+		}
+	}
+
+	for _, flow := range flows.Flows {
+		addTopic(flow.To)
+		edge := TopologyEdge{From: topicNodeID(flow.From), To: topicNodeID(flow.To), Kind: flow.Kind, ID: flow.ID}
+		if flow.Predicate != nil {
+			edge.Label = fmt.Sprintf("%s == %v", flow.Predicate.Field, flow.Predicate.Equals)
+		}
+		topology.Edges = append(topology.Edges, edge)
+	}
+
+	if s.bridges != nil {
+		bridges := s.bridges.AllBridges()
+		sort.Slice(bridges, func(i, j int) bool { return bridges[i].ID < bridges[j].ID })
+		for _, b := range bridges {
+			addTopic(b.Topic)
+			id := "bridge:" + b.ID
+			topology.Nodes = append(topology.Nodes, TopologyNode{ID: id, Kind: NodeBridge, Label: fmt.Sprintf("%s %s", b.Kind, b.Webhook)})
+			topology.Edges = append(topology.Edges, TopologyEdge{From: topicNodeID(b.Topic), To: id, Kind: EdgeBridge, ID: b.ID})
+		}
+	}
+	if s.digests != nil {
+		digests := s.digests.AllDigests()
+		sort.Slice(digests, func(i, j int) bool { return digests[i].ID < digests[j].ID })
+		for _, d := range digests {
+			addTopic(d.Topic)
+			id := "digest:" + d.ID
+			topology.Nodes = append(topology.Nodes, TopologyNode{ID: id, Kind: NodeDigest, Label: fmt.Sprintf("%s email digest", d.Frequency)})
+			topology.Edges = append(topology.Edges, TopologyEdge{From: topicNodeID(d.Topic), To: id, Kind: EdgeDigest, ID: d.ID})
+		}
+	}
+
+	return topology, nil
+}
+
+// topicNodeID returns the node ID of a topic in the topology
+func topicNodeID(name string) string {
+	return "topic:" + name
+}
+
+// RenderDOT renders a topology as a Graphviz digraph, with topics as
+// ellipses, sinks as boxes and missing topics dashed
+func RenderDOT(topology *TopologyResponse) string {
+	var b strings.Builder
+	b.WriteString("digraph topology {\n\trankdir=LR;\n")
+	for _, node := range topology.Nodes {
+		attrs := []string{"label=" + strconv.Quote(node.Label)}
+		if node.Kind != NodeTopic {
+			attrs = append(attrs, "shape=box")
+		}
+		if node.Missing {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", strconv.Quote(node.ID), strings.Join(attrs, ", "))
+	}
+	for _, edge := range topology.Edges {
+		label := edge.Kind
+		if edge.Label != "" {
+			label += ": " + edge.Label
+		}
+		attrs := []string{"label=" + strconv.Quote(label)}
+		if edge.Kind == pubsub.FlowDeadLetter {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package alerts
+
+import (
+	"net/http"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for alerting endpoints
+type Endpoint interface {
+	GetAlerts(c *gin.Context)
+}
+
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// GetAlerts handles GET /alerts, listing the rules and the alerts firing
+func (e *endpoint) GetAlerts(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, AlertsResponse{
+		Rules:  e.service.Rules(),
+		Firing: e.service.Firing(),
+	})
+}
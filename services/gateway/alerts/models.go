@@ -0,0 +1,77 @@
+package alerts
+
+import "time"
+
+// Metric names a statistic rules are evaluated against
+type Metric string
+
+const (
+	// MetricLag is the messages published but not yet acked, per durable
+	// subscription
+	MetricLag Metric = "lag"
+	// MetricDropRate is the messages per second dropped for full subscriber
+	// queues, per topic, over the last evaluation interval
+	MetricDropRate Metric = "drop_rate"
+	// MetricConnections is the number of open WebSocket connections
+	MetricConnections Metric = "connections"
+)
+
+// Metrics lists every metric rules may use
+var Metrics = []Metric{MetricLag, MetricDropRate, MetricConnections}
+
+// Alerting parameters
+const (
+	// Topic receives every alert that fires or resolves. Clients cannot
+	// publish to topics under $SYS/.
+	Topic = "$SYS/alerts"
+	// DefaultInterval is how often rules are evaluated
+	DefaultInterval = 15 * time.Second
+	// WebhookTimeout bounds one alert POST to the webhook
+	WebhookTimeout = 5 * time.Second
+)
+
+// State is whether an alert is firing or has resolved
+type State string
+
+const (
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Rule fires an alert while a metric exceeds a threshold. Lag and drop rate
+// rules apply to every topic unless Topic is set; connection rules apply to
+// the whole gateway.
+type Rule struct {
+	Name      string  `json:"name"`
+	Metric    Metric  `json:"metric"`
+	Topic     string  `json:"topic,omitempty"`
+	Threshold float64 `json:"threshold"`
+}
+
+// Config configures alerting. No rules disables it.
+type Config struct {
+	Rules        []Rule
+	Interval     time.Duration
+	WebhookURL   string // receives each alert as a JSON POST when set
+	PublishTopic bool   // publish alerts to $SYS/alerts
+}
+
+// Alert reports a rule starting or stopping to fire for one topic or
+// subscription. Alerts are sent once when they fire and once when they
+// resolve, not on every evaluation.
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Metric    Metric    `json:"metric"`
+	Topic     string    `json:"topic,omitempty"`
+	ClientID  string    `json:"client_id,omitempty"` // durable subscription, for lag alerts
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	State     State     `json:"state"`
+	At        time.Time `json:"at"`
+}
+
+// REST API Models
+type AlertsResponse struct {
+	Rules  []Rule  `json:"rules"`
+	Firing []Alert `json:"firing"`
+}
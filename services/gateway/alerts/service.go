@@ -0,0 +1,355 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/google/uuid"
+)
+
+// Service interface for alerting on gateway statistics
+type Service interface {
+	// Rules returns the configured rules
+	Rules() []Rule
+	// Firing returns the alerts currently firing, oldest first
+	Firing() []Alert
+}
+
+// ConnectionCounter reports the open WebSocket connections
+type ConnectionCounter interface {
+	ConnectionCount() int
+}
+
+// observation is one value of a metric, for a topic or subscription
+type observation struct {
+	metric   Metric
+	topic    string
+	clientID string
+	value    float64
+}
+
+type service struct {
+	pubsubService pubsub.Service
+	config        Config
+	connections   ConnectionCounter
+	http          *http.Client
+	firing        map[string]Alert  // rule, topic and client ID -> alert
+	dropped       map[string]uint64 // topic -> drops at the last evaluation, owned by run
+	evaluated     time.Time         // last evaluation, owned by run
+	mu            sync.RWMutex
+}
+
+// LoadConfig reads alerting settings from the environment:
+//
+//	ALERT_RULES          comma-separated name=metric[@topic]>threshold rules,
+//	                     e.g. "orders-lag=lag@orders>1000,drops=drop_rate>5"
+//	ALERT_INTERVAL       how often rules are evaluated, e.g. "15s"
+//	ALERT_WEBHOOK_URL    URL alerts are POSTed to as JSON
+//	ALERT_PUBLISH_TOPIC  "false" stops publishing alerts to $SYS/alerts
+func LoadConfig() (*Config, error) {
+	config := &Config{
+		Interval:     DefaultInterval,
+		WebhookURL:   os.Getenv("ALERT_WEBHOOK_URL"),
+		PublishTopic: os.Getenv("ALERT_PUBLISH_TOPIC") != "false",
+	}
+
+	for _, entry := range strings.Split(os.Getenv("ALERT_RULES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		rule, err := parseRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALERT_RULES entry %q: %w", entry, err)
+		}
+		if slices.ContainsFunc(config.Rules, func(r Rule) bool { return r.Name == rule.Name }) {
+			return nil, fmt.Errorf("invalid ALERT_RULES entry %q: duplicate rule name %s", entry, rule.Name)
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+
+	if value := os.Getenv("ALERT_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid ALERT_INTERVAL %q: expected a positive duration such as 15s", value)
+		}
+		config.Interval = interval
+	}
+
+	if config.WebhookURL != "" {
+		parsed, err := url.Parse(config.WebhookURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid ALERT_WEBHOOK_URL: url must be http(s)")
+		}
+	}
+	return config, nil
+}
+
+// parseRule parses a name=metric[@topic]>threshold rule
+func parseRule(entry string) (Rule, error) {
+	name, spec, found := strings.Cut(entry, "=")
+	if !found || name == "" {
+		return Rule{}, fmt.Errorf("expected name=metric[@topic]>threshold")
+	}
+	subject, thresholdStr, found := strings.Cut(spec, ">")
+	if !found {
+		return Rule{}, fmt.Errorf("expected name=metric[@topic]>threshold")
+	}
+	metric, topic, _ := strings.Cut(subject, "@")
+
+	rule := Rule{Name: name, Metric: Metric(metric), Topic: topic}
+	if !slices.Contains(Metrics, rule.Metric) {
+		return Rule{}, fmt.Errorf("unknown metric %s", metric)
+	}
+	if rule.Metric == MetricConnections && topic != "" {
+		return Rule{}, fmt.Errorf("connections rules cannot name a topic")
+	}
+	threshold, err := strconv.ParseFloat(thresholdStr, 64)
+	if err != nil || threshold < 0 {
+		return Rule{}, fmt.Errorf("threshold must be a non-negative number")
+	}
+	rule.Threshold = threshold
+
+	return rule, nil
+}
+
+// NewService creates a new alerting service and starts evaluating its rules.
+// With no rules it does nothing.
+func NewService(config *Config, connections ConnectionCounter) Service {
+	s := &service{
+		pubsubService: pubsub.GetService(),
+		config:        *config,
+		connections:   connections,
+		http:          &http.Client{Timeout: WebhookTimeout},
+		firing:        make(map[string]Alert),
+		dropped:       make(map[string]uint64),
+	}
+	if len(s.config.Rules) == 0 {
+		return s
+	}
+
+	if s.config.PublishTopic {
+		s.ensureTopic(context.Background())
+	}
+	go s.run()
+
+	return s
+}
+
+// Rules returns the configured rules
+func (s *service) Rules() []Rule {
+	return slices.Clone(s.config.Rules)
+}
+
+// Firing returns the alerts currently firing, oldest first
+func (s *service) Firing() []Alert {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	firing := make([]Alert, 0, len(s.firing))
+	for _, alert := range s.firing {
+		firing = append(firing, alert)
+	}
+	slices.SortFunc(firing, func(a, b Alert) int { return a.At.Compare(b.At) })
+	return firing
+}
+
+// run evaluates the rules every interval
+func (s *service) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx := context.Background()
+		for _, alert := range s.evaluate(ctx) {
+			s.notify(ctx, alert)
+		}
+	}
+}
+
+// evaluate checks every rule against current statistics and returns the
+// alerts that started or stopped firing
+func (s *service) evaluate(ctx context.Context) []Alert {
+	stats, err := s.pubsubService.GetStats(ctx)
+	if err != nil {
+		logging.WithContext(ctx).Warnw("Alert evaluation failed to read stats", "error", err)
+		return nil
+	}
+
+	observations := s.observe(stats, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed []Alert
+	seen := make(map[string]bool)
+	for _, rule := range s.config.Rules {
+		for _, o := range observations {
+			if o.metric != rule.Metric || (rule.Topic != "" && o.topic != rule.Topic) {
+				continue
+			}
+
+			key := rule.Name + "\x00" + o.topic + "\x00" + o.clientID
+			seen[key] = true
+			_, firing := s.firing[key]
+
+			switch {
+			case o.value > rule.Threshold && !firing:
+				alert := Alert{
+					Rule:      rule.Name,
+					Metric:    rule.Metric,
+					Topic:     o.topic,
+					ClientID:  o.clientID,
+					Value:     o.value,
+					Threshold: rule.Threshold,
+					State:     StateFiring,
+					At:        time.Now(),
+				}
+				s.firing[key] = alert
+				changed = append(changed, alert)
+			case o.value <= rule.Threshold && firing:
+				changed = append(changed, s.resolve(key, o.value))
+			}
+		}
+	}
+
+	// Topics and subscriptions that went away no longer fire
+	for key := range s.firing {
+		if !seen[key] {
+			changed = append(changed, s.resolve(key, 0))
+		}
+	}
+
+	return changed
+}
+
+// resolve stops an alert firing. Caller must hold s.mu.
+func (s *service) resolve(key string, value float64) Alert {
+	alert := s.firing[key]
+	delete(s.firing, key)
+
+	alert.Value = value
+	alert.State = StateResolved
+	alert.At = time.Now()
+	return alert
+}
+
+// observe collects the value of every metric from stats. Drop rates need
+// two evaluations, so a topic has none on the first evaluation after it
+// appears.
+func (s *service) observe(stats *pubsub.StatsResponse, now time.Time) []observation {
+	var observations []observation
+	if s.connections != nil {
+		observations = append(observations, observation{
+			metric: MetricConnections,
+			value:  float64(s.connections.ConnectionCount()),
+		})
+	}
+
+	elapsed := now.Sub(s.evaluated).Seconds()
+	dropped := make(map[string]uint64, len(stats.Topics))
+	for name, topic := range stats.Topics {
+		for _, cursor := range topic.Cursors {
+			observations = append(observations, observation{
+				metric:   MetricLag,
+				topic:    name,
+				clientID: cursor.ClientID,
+				value:    float64(cursor.Lag),
+			})
+		}
+
+		if previous, ok := s.dropped[name]; ok && elapsed > 0 && topic.Dropped >= previous {
+			observations = append(observations, observation{
+				metric: MetricDropRate,
+				topic:  name,
+				value:  float64(topic.Dropped-previous) / elapsed,
+			})
+		}
+		dropped[name] = topic.Dropped
+	}
+	s.dropped = dropped
+	s.evaluated = now
+
+	return observations
+}
+
+// notify publishes an alert to $SYS/alerts and POSTs it to the webhook, as
+// configured. Failures are logged; the alert is not retried.
+func (s *service) notify(ctx context.Context, alert Alert) {
+	log := logging.WithContext(ctx)
+	log.Warnw("Alert "+string(alert.State), "rule", alert.Rule, "metric", alert.Metric, "topic", alert.Topic,
+		"client_id", alert.ClientID, "value", alert.Value, "threshold", alert.Threshold)
+
+	if s.config.PublishTopic {
+		if err := s.publish(ctx, alert); err != nil {
+			log.Errorw("Failed to publish alert", "rule", alert.Rule, "error", err)
+		}
+	}
+
+	if s.config.WebhookURL != "" {
+		if err := s.post(alert); err != nil {
+			log.Errorw("Failed to send alert webhook", "rule", alert.Rule, "error", err)
+		}
+	}
+}
+
+// publish publishes an alert to $SYS/alerts, recreating the topic if it was
+// deleted
+func (s *service) publish(ctx context.Context, alert Alert) error {
+	message := pubsub.Message{
+		ID:      uuid.NewString(),
+		Payload: alert,
+		Headers: map[string]string{"rule": alert.Rule, "state": string(alert.State)},
+	}
+
+	err := s.pubsubService.Publish(ctx, Topic, message)
+	if err != nil && err.Error() == fmt.Sprintf("topic %s not found", Topic) {
+		s.ensureTopic(ctx)
+		err = s.pubsubService.Publish(ctx, Topic, message)
+	}
+	return err
+}
+
+// ensureTopic creates $SYS/alerts unless it exists
+func (s *service) ensureTopic(ctx context.Context) {
+	if _, err := s.pubsubService.GetTopic(ctx, Topic); err == nil {
+		return
+	}
+	if err := s.pubsubService.CreateTopic(ctx, Topic, "", nil); err != nil {
+		logging.WithContext(ctx).Warnw("Failed to create alerts topic", "topic", Topic, "error", err)
+	}
+}
+
+// post sends an alert to the webhook
+func (s *service) post(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.http.Post(s.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook responded %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package apikey
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for API key endpoints
+type Endpoint interface {
+	CreateKey(c *gin.Context)
+	ListKeys(c *gin.Context)
+	RevokeKey(c *gin.Context)
+}
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// CreateKey handles POST /users/keys
+func (e *endpoint) CreateKey(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req CreateKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Error binding JSON", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := e.service.CreateKey(userID, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid key") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "key "+strings.TrimSpace(req.Name)+" already exists" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error creating API key", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create key"})
+		return
+	}
+
+	log.Infow("API key created", "key_id", response.Key.ID, "name", response.Key.Name,
+		"direction", response.Key.Direction, "topics", response.Key.Topics, "user_id", userID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListKeys handles GET /users/keys
+func (e *endpoint) ListKeys(c *gin.Context) {
+	keys := e.service.ListKeys(c.GetString("user_id"))
+	c.JSON(http.StatusOK, ListKeysResponse{Keys: keys, Count: len(keys)})
+}
+
+// RevokeKey handles DELETE /users/keys/{id}
+func (e *endpoint) RevokeKey(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	keyID := c.Param("id")
+	if err := e.service.RevokeKey(c.GetString("user_id"), keyID); err != nil {
+		if err.Error() == "key "+keyID+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+			return
+		}
+		log.Errorw("Error revoking API key", "error", err.Error(), "key_id", keyID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke key"})
+		return
+	}
+
+	log.Infow("API key revoked", "key_id", keyID)
+	c.JSON(http.StatusOK, RevokeKeyResponse{Status: "revoked", ID: keyID})
+}
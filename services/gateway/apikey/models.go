@@ -0,0 +1,72 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+)
+
+// KeyPrefix marks API key secrets, so they can share the Authorization
+// header with user JWTs
+const KeyPrefix = "key_"
+
+// Key lifetimes and limits
+const (
+	DefaultKeyTTL = 90 * 24 * time.Hour
+	MaxKeyTTL     = 365 * 24 * time.Hour
+	MaxKeyTopics  = 100
+	MaxUserKeys   = 50 // active keys per user
+)
+
+// Directions a key can be issued for
+var Directions = []pubsub.Action{pubsub.ActionPublish, pubsub.ActionSubscribe}
+
+// Key is an API key acting for its owner in one direction only, on a set
+// of topics. The secret is only returned on creation; the service keeps
+// its hash.
+type Key struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Owner      string        `json:"owner"` // user ID the key acts as
+	Direction  pubsub.Action `json:"direction"`
+	Topics     []string      `json:"topics"` // "*" matches every topic
+	CreatedAt  time.Time     `json:"created_at"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	RevokedAt  *time.Time    `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time    `json:"last_used_at,omitempty"`
+
+	hash string
+}
+
+// Active reports whether the key can authenticate at now
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
+
+// Grant returns the pubsub grant the key's connections are restricted to
+func (k *Key) Grant() *pubsub.Grant {
+	return &pubsub.Grant{Action: k.Direction, Topics: k.Topics}
+}
+
+// REST API Models
+type CreateKeyRequest struct {
+	Name      string        `json:"name" binding:"required"`
+	Direction pubsub.Action `json:"direction" binding:"required"` // "publish" or "subscribe"
+	Topics    []string      `json:"topics" binding:"required"`
+	ExpiresIn string        `json:"expires_in,omitempty"` // Go duration, e.g. "720h"; defaults to DefaultKeyTTL
+}
+
+type CreateKeyResponse struct {
+	Key    Key    `json:"key"`
+	Secret string `json:"secret"` // shown once
+}
+
+type ListKeysResponse struct {
+	Keys  []Key `json:"keys"`
+	Count int   `json:"count"`
+}
+
+type RevokeKeyResponse struct {
+	Status string `json:"status"`
+	ID     string `json:"id"`
+}
@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	endpoint Endpoint
+}
+
+// NewRouteRegistrar creates a new route registrar
+func NewRouteRegistrar(service Service) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		endpoint: NewEndpoint(service),
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes. API keys themselves
+// cannot reach these routes, so a key cannot mint or revoke keys.
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	authGroup.POST("/users/keys", r.endpoint.CreateKey)
+	authGroup.GET("/users/keys", r.endpoint.ListKeys)
+	authGroup.DELETE("/users/keys/:id", r.endpoint.RevokeKey)
+}
+
+// RegisterUnAuthRoutes registers unauthenticated routes
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	// no unauth routes
+}
@@ -0,0 +1,201 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/google/uuid"
+)
+
+// Service interface for publish-only and subscribe-only API keys
+type Service interface {
+	CreateKey(owner string, req CreateKeyRequest) (CreateKeyResponse, error)
+	// ListKeys returns the owner's keys, including revoked and expired ones
+	ListKeys(owner string) []Key
+	RevokeKey(owner, id string) error
+	// Authenticate resolves a key secret to its active key
+	Authenticate(secret string) (*Key, error)
+}
+
+type service struct {
+	keys map[string]*Key // id -> key
+	mu   sync.RWMutex
+}
+
+// NewService creates a new API key service. Keys of users deleted on bus
+// are revoked; bus may be nil.
+func NewService(bus events.Bus) Service {
+	s := &service{
+		keys: make(map[string]*Key),
+	}
+
+	if bus != nil {
+		bus.Subscribe(events.KindUserDeleted, "apikey", func(ctx context.Context, event events.Event) {
+			s.revokeOwner(event.Subject)
+		})
+	}
+
+	return s
+}
+
+// CreateKey issues a key acting for owner
+func (s *service) CreateKey(owner string, req CreateKeyRequest) (CreateKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return CreateKeyResponse{}, fmt.Errorf("invalid key: name is required")
+	}
+	if !slices.Contains(Directions, req.Direction) {
+		return CreateKeyResponse{}, fmt.Errorf("invalid key: direction must be publish or subscribe")
+	}
+	if len(req.Topics) == 0 || len(req.Topics) > MaxKeyTopics {
+		return CreateKeyResponse{}, fmt.Errorf("invalid key: between 1 and %d topics are required", MaxKeyTopics)
+	}
+	for _, topic := range req.Topics {
+		if topic == "" {
+			return CreateKeyResponse{}, fmt.Errorf("invalid key: topics must not be empty")
+		}
+	}
+
+	ttl := DefaultKeyTTL
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 || parsed > MaxKeyTTL {
+			return CreateKeyResponse{}, fmt.Errorf("invalid key: expires_in must be a positive duration up to %s", MaxKeyTTL)
+		}
+		ttl = parsed
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return CreateKeyResponse{}, err
+	}
+
+	now := time.Now()
+	key := &Key{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Owner:     owner,
+		Direction: req.Direction,
+		Topics:    slices.Compact(slices.Sorted(slices.Values(req.Topics))),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		hash:      hashSecret(secret),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := 0
+	for _, existing := range s.keys {
+		if existing.Owner != owner || !existing.Active(now) {
+			continue
+		}
+		if existing.Name == name {
+			return CreateKeyResponse{}, fmt.Errorf("key %s already exists", name)
+		}
+		active++
+	}
+	if active >= MaxUserKeys {
+		return CreateKeyResponse{}, fmt.Errorf("invalid key: at most %d active keys per user", MaxUserKeys)
+	}
+	s.keys[key.ID] = key
+
+	return CreateKeyResponse{Key: *key, Secret: secret}, nil
+}
+
+// ListKeys returns the owner's keys, oldest first
+func (s *service) ListKeys(owner string) []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]Key, 0)
+	for _, key := range s.keys {
+		if key.Owner == owner {
+			keys = append(keys, *key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys
+}
+
+// RevokeKey stops one of the owner's keys from authenticating. Connections
+// already open with the key stay open until they close.
+func (s *service) RevokeKey(owner, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.keys[id]
+	if !exists || key.Owner != owner {
+		return fmt.Errorf("key %s not found", id)
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+	}
+
+	return nil
+}
+
+// Authenticate finds the active key matching secret
+func (s *service) Authenticate(secret string) (*Key, error) {
+	hash := hashSecret(secret)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(key.hash), []byte(hash)) != 1 {
+			continue
+		}
+		if !key.Active(now) {
+			return nil, fmt.Errorf("key %s is revoked or expired", key.Name)
+		}
+		key.LastUsedAt = &now
+		authenticated := *key
+		return &authenticated, nil
+	}
+
+	return nil, fmt.Errorf("unknown api key")
+}
+
+// revokeOwner revokes every key of a user
+func (s *service) revokeOwner(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.Owner == owner && key.RevokedAt == nil {
+			key.RevokedAt = &now
+		}
+	}
+}
+
+// newSecret generates a random key secret
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return KeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// hashSecret returns the stored form of a secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
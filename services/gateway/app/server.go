@@ -0,0 +1,178 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/middlewares"
+)
+
+// Server defaults. IdleTimeout is deliberately longer than the idle timeout
+// of common load balancers (60s), so the balancer rather than the gateway
+// closes idle keep-alive connections and never reuses one the gateway has
+// just closed.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = http.DefaultMaxHeaderBytes
+)
+
+// ServerConfig configures the HTTP server. Timeouts cover the REST API and
+// the WebSocket handshake only: upgraded connections clear their deadlines.
+// A zero timeout disables it.
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	KeepAlive         bool // reuse connections across requests
+	H2C               bool // serve HTTP/2 over cleartext (h2c) alongside HTTP/1.1
+}
+
+// DefaultServerConfig returns the default server configuration
+func DefaultServerConfig() *ServerConfig {
+	return &ServerConfig{
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		ReadTimeout:       DefaultReadTimeout,
+		WriteTimeout:      DefaultWriteTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+		MaxHeaderBytes:    DefaultMaxHeaderBytes,
+		KeepAlive:         true,
+	}
+}
+
+// LoadServerConfig loads the server configuration from environment
+// variables:
+//
+//	HTTP_READ_HEADER_TIMEOUT  time to read request headers, e.g. "10s"
+//	HTTP_READ_TIMEOUT         time to read a whole request
+//	HTTP_WRITE_TIMEOUT        time to write a response
+//	HTTP_IDLE_TIMEOUT         how long an idle keep-alive connection is kept
+//	HTTP_MAX_HEADER_BYTES     maximum size of request headers
+//	HTTP_KEEP_ALIVE           "false" closes connections after each request
+//	HTTP_H2C                  "true" enables cleartext HTTP/2
+func LoadServerConfig() (*ServerConfig, error) {
+	config := DefaultServerConfig()
+
+	durations := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"HTTP_READ_HEADER_TIMEOUT", &config.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", &config.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", &config.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &config.IdleTimeout},
+	}
+	for _, d := range durations {
+		value := os.Getenv(d.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected a non-negative duration such as 30s", d.name, value)
+		}
+		*d.value = parsed
+	}
+
+	if value := os.Getenv("HTTP_MAX_HEADER_BYTES"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid HTTP_MAX_HEADER_BYTES %q: expected a positive integer", value)
+		}
+		config.MaxHeaderBytes = parsed
+	}
+
+	config.KeepAlive = os.Getenv("HTTP_KEEP_ALIVE") != "false"
+	config.H2C = os.Getenv("HTTP_H2C") == "true"
+
+	return config, nil
+}
+
+// LoadBodyLimits loads the request body limits from environment variables:
+//
+//	HTTP_MAX_BODY_BYTES        maximum size of a request body
+//	HTTP_ROUTE_MAX_BODY_BYTES  per-route overrides, e.g.
+//	                           "POST /replication/batch=8388608,POST /users/register=4096"
+//	WS_MAX_FRAME_BYTES         maximum size of a WebSocket frame
+//	JSON_MAX_DEPTH             maximum nesting of JSON objects and arrays
+//	JSON_MAX_ARRAY_LENGTH      maximum elements in one JSON array
+//
+// Zero disables a limit.
+func LoadBodyLimits() (*middlewares.BodyLimits, error) {
+	limits := middlewares.DefaultBodyLimits()
+
+	sizes := []struct {
+		name  string
+		value *int64
+	}{
+		{"HTTP_MAX_BODY_BYTES", &limits.MaxBytes},
+		{"WS_MAX_FRAME_BYTES", &limits.MaxFrameBytes},
+	}
+	for _, s := range sizes {
+		value := os.Getenv(s.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected a non-negative number of bytes", s.name, value)
+		}
+		*s.value = parsed
+	}
+
+	counts := []struct {
+		name  string
+		value *int
+	}{
+		{"JSON_MAX_DEPTH", &limits.MaxDepth},
+		{"JSON_MAX_ARRAY_LENGTH", &limits.MaxArrayLength},
+	}
+	for _, c := range counts {
+		value := os.Getenv(c.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected a non-negative integer", c.name, value)
+		}
+		*c.value = parsed
+	}
+
+	if value := os.Getenv("HTTP_ROUTE_MAX_BODY_BYTES"); value != "" {
+		for _, entry := range strings.Split(value, ",") {
+			route, size, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+			parsed, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+			if !ok || !hasPath || !strings.HasPrefix(path, "/") || err != nil || parsed < 0 {
+				return nil, fmt.Errorf("invalid HTTP_ROUTE_MAX_BODY_BYTES entry %q: expected \"METHOD /route=bytes\"", entry)
+			}
+			limits.RouteMaxBytes[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = parsed
+		}
+	}
+
+	return limits, nil
+}
+
+// newServer builds the HTTP server for handler listening on addr
+func newServer(config *ServerConfig, addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}
+	server.SetKeepAlivesEnabled(config.KeepAlive)
+	return server
+}
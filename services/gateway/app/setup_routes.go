@@ -3,48 +3,90 @@ package app
 import (
 	"context"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/admin"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/alerts"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/apikey"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/bridge"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/demo"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/digest"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/exchange"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/limits"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/lineproto"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/maintenance"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/metrics"
 	"github.com/ammysap/plivo-pub-sub/services/gateway/middlewares"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/notifier"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/playground"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/replication"
 	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
 	"github.com/ammysap/plivo-pub-sub/services/gateway/topic"
 	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
 	"github.com/ammysap/plivo-pub-sub/services/gateway/websocket"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
-func setupRouter() (router *gin.Engine, authGroup, unAuthGroup *gin.RouterGroup) {
+func setupRouter(keys apikey.Service, limiter limits.Service, metricsService metrics.Service, originPolicy *middlewares.OriginPolicy, bodyLimits *middlewares.BodyLimits) (router *gin.Engine, authGroup, unAuthGroup *gin.RouterGroup) {
+	// Decode JSON numbers in untyped fields (e.g. route predicates) exactly,
+	// matching how publish frames are decoded
+	binding.EnableDecoderUseNumber = true
+
 	router = gin.Default()
+	// Match routes against the escaped path, so a namespaced topic such as
+	// tenant%2Fapp%2Fevents fills a single :name segment
+	router.UseRawPath = true
 	numHours := 12
-	allowedOriginsStr, isOrigin := os.LookupEnv("ALLOWED_CORS_ORIGIN")
 	allowedMethodsStr, isMethod := os.LookupEnv("ALLOWED_CORS_METHOD")
 
-	if allowedOriginsStr == "" || !isOrigin {
-		allowedOriginsStr = "*"
-	}
-
 	if allowedMethodsStr == "" || !isMethod {
 		allowedMethodsStr = "*"
 	}
 
-	allowedOrigins := strings.Split(allowedOriginsStr, ",")
 	allowedMethods := strings.Split(allowedMethodsStr, ",")
 
-	router.Use(cors.New(cors.Config{
+	allowedOrigins := originPolicy.AllowedOrigins
+	if originPolicy.DevMode {
+		allowedOrigins = []string{"*"}
+	}
+
+	corsConfig := cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     allowedMethods,
 		AllowHeaders:     []string{"*"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           time.Duration(numHours) * time.Hour,
-	}))
+	}
+	if len(allowedOrigins) == 0 {
+		// No cross-origin requests; same-origin ones never reach the check
+		corsConfig.AllowOriginFunc = func(string) bool { return false }
+	}
+	corsHandler := cors.New(corsConfig)
+	router.Use(func(c *gin.Context) {
+		// WebSocket upgrades are vetted by the origin middleware on /ws
+		if c.Request.URL.Path == "/ws" {
+			c.Next()
+			return
+		}
+		corsHandler(c)
+	})
+
+	router.Use(middlewares.MetricsMiddleware(metricsService))
+	router.Use(middlewares.BodyLimitMiddleware(bodyLimits, metricsService))
 
 	authGroup = router.Group(
 		"/",
-		middlewares.AuthMiddleware(),
+		middlewares.AuthMiddleware(keys),
+		middlewares.RateLimitMiddleware(limiter),
 	)
 
 	unAuthGroup = router.Group("/")
@@ -52,47 +94,287 @@ func setupRouter() (router *gin.Engine, authGroup, unAuthGroup *gin.RouterGroup)
 	return router, authGroup, unAuthGroup
 }
 
+var (
+	closersMu sync.Mutex
+	closers   []func() // stop background work of services built by RegisterRoutes
+)
+
+// onShutdown registers a function for Shutdown to call
+func onShutdown(close func()) {
+	closersMu.Lock()
+	defer closersMu.Unlock()
+	closers = append(closers, close)
+}
+
+// Shutdown stops the background work of the services RegisterRoutes built,
+// latest first. Call it before stopping the pubsub service they use.
+func Shutdown() {
+	closersMu.Lock()
+	defer closersMu.Unlock()
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		closers[i]()
+	}
+	closers = nil
+}
+
 func RegisterRoutes(ctx context.Context,
 	resolver interface{}, // Can be nil for in-memory pub/sub
+	demoMode bool, // seed demo users and topics and generate traffic
 ) error {
 	log := logging.WithContext(ctx)
 
 	log.Info("Registering routes...")
 
-	router, authGroup, unAuthGroup := setupRouter()
+	// HTTP server
+	serverConfig, err := LoadServerConfig()
+	if err != nil {
+		return err
+	}
+	bodyLimits, err := LoadBodyLimits()
+	if err != nil {
+		return err
+	}
+
+	// Events between modules
+	bus := events.NewBus()
+
+	// Rate limits
+	limitsConfig, err := limits.LoadConfig()
+	if err != nil {
+		return err
+	}
+	limitsService := limits.NewService(limitsConfig, bus)
+	limitsRouteRegistrar := limits.NewRouteRegistrar(limitsService)
+
+	// Request and pubsub metrics
+	maxTrackedUsers, _ := strconv.Atoi(os.Getenv("METRICS_MAX_TRACKED_USERS"))
+	metricsService := metrics.NewService(pubsub.GetService(), maxTrackedUsers, bus)
+
+	originPolicy := wsOriginPolicy(ctx)
+	if originPolicy.DevMode {
+		log.Warn("WS_ORIGIN_DEV_MODE is set: any origin is accepted and WebSocket CSRF checks are skipped")
+	}
+
+	// Publish-only and subscribe-only API keys
+	apikeyService := apikey.NewService(bus)
+	apikeyRouteRegistrar := apikey.NewRouteRegistrar(apikeyService)
+
+	// Service tokens exchanged for short-lived, narrowly scoped tokens
+	exchangeRouteRegistrar := exchange.NewRouteRegistrar(exchange.NewService())
+
+	router, authGroup, unAuthGroup := setupRouter(apikeyService, limitsService, metricsService, originPolicy, bodyLimits)
 
 	secureRouter := secure.NewRouter(authGroup, unAuthGroup)
 
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8000"
+	}
+
 	// User service
 	log.Info("Creating User service...")
-	userService := user.NewService()
+	userService := user.NewService(bus)
+	if dataDir := os.Getenv("DATA_DIR"); dataDir != "" {
+		if err := userService.LoadSubscriptions(dataDir); err != nil {
+			return err
+		}
+	}
 	userRouteRegistrar := user.NewRouteRegistrar(userService)
+	activeUser := middlewares.ActiveUserMiddleware(userService)
+	authGroup.Use(activeUser)
+
+	// Replication to peer regions
+	log.Info("Creating Replication service...")
+	replicationConfig, err := replication.LoadConfig()
+	if err != nil {
+		return err
+	}
+	replicationService := replication.NewService(replicationConfig)
+	onShutdown(replicationService.Close)
+	replicationRouteRegistrar := replication.NewRouteRegistrar(replicationService,
+		middlewares.ReplicationAuthMiddleware(replicationConfig.Token))
 
 	// Topic management service
 	log.Info("Creating Topic service...")
-	topicService := topic.NewService()
+	topicService := topic.NewService(userService, limitsService, replicationService, bus)
 	topicRouteRegistrar := topic.NewRouteRegistrar(topicService)
 
+	// Maintenance windows, during which the gateway drains
+	log.Info("Creating Maintenance service...")
+	maintenanceConfig, err := maintenance.LoadConfig()
+	if err != nil {
+		return err
+	}
+	maintenanceService := maintenance.NewService(maintenanceConfig, bus)
+
 	// WebSocket service
 	log.Info("Creating WebSocket service...")
-	websocketService := websocket.NewService()
-	websocketRouteRegistrar := websocket.NewRouteRegistrar(websocketService)
+	writeConfig, err := websocket.LoadWriteConfig()
+	if err != nil {
+		return err
+	}
+	websocketService := websocket.NewService(userService, limitsService, bus, bodyLimits, metricsService, writeConfig)
+	websocketRouteRegistrar := websocket.NewRouteRegistrar(websocketService, wsAuthRequired(),
+		middlewares.WebSocketOriginMiddleware(originPolicy, metricsService),
+		middlewares.OptionalAuthMiddleware(apikeyService), activeUser,
+		middlewares.ConnectionLimitMiddleware(limitsService), middlewares.DrainMiddleware(maintenanceService))
 
-	log.Info("Registering routes...")
-	secureRouter.RegisterRegistrars(
+	// Publish-only TCP listener for producers without WebSocket or HTTP
+	lineConfig, err := lineproto.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if lineConfig.Addr != "" {
+		log.Info("Starting line-protocol listener...")
+		if err := lineproto.NewService(lineConfig, apikeyService, userService, limitsService).Start(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Alerting on lag, drops and connections
+	log.Info("Creating Alerts service...")
+	alertsConfig, err := alerts.LoadConfig()
+	if err != nil {
+		return err
+	}
+	alertsService := alerts.NewService(alertsConfig, websocketService)
+	alertsRouteRegistrar := alerts.NewRouteRegistrar(alertsService)
+
+	// Email digest service
+	log.Info("Creating Digest service...")
+	notifierService := notifier.NewFromEnv()
+	digestService := digest.NewService(notifierService, publicBaseURL(port))
+	digestRouteRegistrar := digest.NewRouteRegistrar(digestService)
+
+	// Slack/Discord bridge service
+	log.Info("Creating Bridge service...")
+	bridgeService := bridge.NewService(bridge.ExtraHostsFromEnv())
+	bridgeRouteRegistrar := bridge.NewRouteRegistrar(bridgeService)
+
+	// Demo users, topics and traffic
+	adminUsers := admin.AdminUsersFromEnv()
+	var seeded *demo.Seeded
+	if demoMode {
+		log.Info("Seeding demo data...")
+		demoService := demo.NewService(userService, topicService)
+		seeded, err = demoService.Seed(ctx)
+		if err != nil {
+			return err
+		}
+		adminUsers = append(adminUsers, seeded.Users[0].ID)
+		go demoService.GenerateTraffic(ctx)
+	}
+
+	// Admin tokens and audit log
+	log.Info("Creating Admin service...")
+	adminService := admin.NewService(adminUsers, userService, bridgeService, digestService)
+
+	// Inactive account cleanup; admins are exempt
+	lifecycleConfig, err := user.LoadLifecycleConfig()
+	if err != nil {
+		return err
+	}
+	lifecycleConfig.ExemptUsers = adminUsers
+	userService.StartLifecycle(ctx, lifecycleConfig, notifierService)
+	adminRouteRegistrar := admin.NewRouteRegistrar(adminService, middlewares.AdminAuthMiddleware(adminService))
+	maintenanceRouteRegistrar := maintenance.NewRouteRegistrar(maintenanceService, middlewares.AdminAuthMiddleware(adminService))
+	metricsRouteRegistrar := metrics.NewRouteRegistrar(metricsService, middlewares.MetricsAuthMiddleware(adminService))
+
+	// Browser page for trying the WebSocket protocol by hand
+	registrars := []secure.RouteRegistrarInterface{
 		userRouteRegistrar,
+		apikeyRouteRegistrar,
+		exchangeRouteRegistrar,
 		topicRouteRegistrar,
 		websocketRouteRegistrar,
-	)
+		digestRouteRegistrar,
+		bridgeRouteRegistrar,
+		limitsRouteRegistrar,
+		metricsRouteRegistrar,
+		alertsRouteRegistrar,
+		adminRouteRegistrar,
+		maintenanceRouteRegistrar,
+		replicationRouteRegistrar,
+	}
+	if playgroundEnabled() {
+		registrars = append(registrars, playground.NewRouteRegistrar())
+	}
+
+	log.Info("Registering routes...")
+	secureRouter.RegisterRegistrars(registrars...)
 
 	log.Info("Registering all routes...")
 	secureRouter.RegisterRoutes()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8000"
+	if seeded != nil {
+		demo.PrintInstructions(os.Stdout, publicBaseURL(port), seeded)
+	}
+
+	router.UseH2C = serverConfig.H2C
+	server := newServer(serverConfig, ":"+port, router.Handler())
+
+	log.Infow("Starting server on port", "port", port, "h2c", serverConfig.H2C, "keep_alive", serverConfig.KeepAlive,
+		"read_timeout", serverConfig.ReadTimeout, "write_timeout", serverConfig.WriteTimeout, "idle_timeout", serverConfig.IdleTimeout)
+	return server.ListenAndServe()
+}
+
+// publicBaseURL returns the externally reachable URL used in links sent to
+// users, taken from PUBLIC_BASE_URL when set
+func publicBaseURL(port string) string {
+	if baseURL := os.Getenv("PUBLIC_BASE_URL"); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return "http://localhost:" + port
+}
+
+// wsAuthRequired reports whether /ws rejects unauthenticated connections.
+// It defaults to true; WS_AUTH_REQUIRED=false allows anonymous clients.
+func wsAuthRequired() bool {
+	return os.Getenv("WS_AUTH_REQUIRED") != "false"
+}
+
+// playgroundEnabled reports whether /playground is served. It defaults to
+// true; PLAYGROUND_ENABLED=false hides it.
+func playgroundEnabled() bool {
+	return os.Getenv("PLAYGROUND_ENABLED") != "false"
+}
+
+// allowedOrigins returns the browser origins allowed by CORS and for
+// WebSocket upgrades, from the comma-separated ALLOWED_CORS_ORIGIN. It
+// defaults to none, so only same-origin browser requests are allowed. "*"
+// allows any origin, but only in dev mode; elsewhere it is dropped with a
+// warning.
+func allowedOrigins(ctx context.Context, devMode bool) []string {
+	log := logging.WithContext(ctx)
+
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("ALLOWED_CORS_ORIGIN"), ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			if !devMode {
+				log.Warn("ALLOWED_CORS_ORIGIN=* is ignored outside WS_ORIGIN_DEV_MODE: list the allowed origins instead")
+				continue
+			}
+			log.Warn("ALLOWED_CORS_ORIGIN=* is set: any origin is allowed")
+		}
+		origins = append(origins, origin)
 	}
+	return origins
+}
 
-	log.Info(ctx, "Starting server on port", "port", port)
-	return router.Run(":" + port)
+// wsOriginPolicy returns the WebSocket origin policy: the CORS origins, plus
+// double-submit CSRF checks when WS_CSRF_PROTECTION=true.
+// WS_ORIGIN_DEV_MODE=true accepts every origin, for CORS too, and skips
+// CSRF checks, for local development.
+func wsOriginPolicy(ctx context.Context) *middlewares.OriginPolicy {
+	devMode := os.Getenv("WS_ORIGIN_DEV_MODE") == "true"
+	return &middlewares.OriginPolicy{
+		AllowedOrigins: allowedOrigins(ctx, devMode),
+		CSRF:           os.Getenv("WS_CSRF_PROTECTION") == "true",
+		DevMode:        devMode,
+	}
 }
@@ -0,0 +1,127 @@
+package bridge
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for chat bridge endpoints
+type Endpoint interface {
+	CreateBridge(c *gin.Context)
+	ListBridges(c *gin.Context)
+	DeleteBridge(c *gin.Context)
+}
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// CreateBridge handles POST /topics/{name}/bridges
+func (e *endpoint) CreateBridge(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	userID := c.GetString("user_id")
+
+	var req CreateBridgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	bridge, err := e.service.CreateBridge(userID, topicName, req)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid bridge") {
+			log.Warnw("Invalid bridge", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error creating bridge", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bridge"})
+		return
+	}
+
+	response := CreateBridgeResponse{
+		Status: "created",
+		Bridge: bridge,
+	}
+
+	log.Infow("Bridge created successfully", "topic", topicName, "bridge_id", bridge.ID, "kind", bridge.Kind)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListBridges handles GET /topics/{name}/bridges
+func (e *endpoint) ListBridges(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	bridges, err := e.service.ListBridges(c.GetString("user_id"), topicName)
+	if err != nil {
+		log.Errorw("Error listing bridges", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list bridges"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListBridgesResponse{
+		Topic:   topicName,
+		Bridges: bridges,
+	})
+}
+
+// DeleteBridge handles DELETE /topics/{name}/bridges/{id}
+func (e *endpoint) DeleteBridge(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	bridgeID := c.Param("id")
+
+	err = e.service.DeleteBridge(c.GetString("user_id"), topicName, bridgeID)
+	if err != nil {
+		if err.Error() == "bridge "+bridgeID+" not found" {
+			log.Warnw("Bridge not found", "topic", topicName, "bridge_id", bridgeID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bridge not found"})
+			return
+		}
+		log.Errorw("Error deleting bridge", "error", err.Error(), "topic", topicName, "bridge_id", bridgeID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bridge"})
+		return
+	}
+
+	log.Infow("Bridge deleted successfully", "topic", topicName, "bridge_id", bridgeID)
+	c.JSON(http.StatusOK, DeleteBridgeResponse{
+		Status:   "deleted",
+		Topic:    topicName,
+		BridgeID: bridgeID,
+	})
+}
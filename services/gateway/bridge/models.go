@@ -0,0 +1,97 @@
+package bridge
+
+import (
+	"time"
+)
+
+// Kind is the chat service a bridge posts to
+type Kind string
+
+const (
+	KindSlack   Kind = "slack"
+	KindDiscord Kind = "discord"
+)
+
+// valid reports whether the kind is supported
+func (k Kind) valid() bool {
+	return k == KindSlack || k == KindDiscord
+}
+
+// minInterval is the spacing between posts that stays within the service's
+// webhook rate limit: about one per second for Slack, five per two seconds
+// for Discord
+func (k Kind) minInterval() time.Duration {
+	if k == KindDiscord {
+		return 400 * time.Millisecond
+	}
+	return time.Second
+}
+
+// maxText is the longest message text the service accepts
+func (k Kind) maxText() int {
+	if k == KindDiscord {
+		return 2000
+	}
+	return 40000
+}
+
+// defaultHosts are the webhook hosts each kind may post to without being
+// listed in BRIDGE_WEBHOOK_HOSTS
+var defaultHosts = map[Kind][]string{
+	KindSlack:   {"hooks.slack.com"},
+	KindDiscord: {"discord.com", "discordapp.com"},
+}
+
+// Batching parameters
+const (
+	// BatchDelay is how long a bridge waits after an idle period before
+	// posting, so bursts are combined into one post
+	BatchDelay = 250 * time.Millisecond
+	// MaxBatchMessages bounds the messages combined into one post
+	MaxBatchMessages = 20
+	// MaxPendingMessages bounds the messages waiting to be posted; older
+	// messages beyond the bound are dropped and counted
+	MaxPendingMessages = 1000
+	// WebhookTimeout bounds one webhook request
+	WebhookTimeout = 10 * time.Second
+)
+
+// Bridge posts messages from a topic to a Slack or Discord channel
+type Bridge struct {
+	ID        string    `json:"id"`
+	Topic     string    `json:"topic"`
+	UserID    string    `json:"user_id"`
+	Kind      Kind      `json:"kind"`
+	Webhook   string    `json:"webhook"` // webhook URL with its secret path elided
+	Template  string    `json:"template,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Posted    uint64    `json:"posted"`  // messages posted
+	Dropped   uint64    `json:"dropped"` // messages dropped while the channel was rate limited
+	Failed    uint64    `json:"failed"`  // messages in posts the webhook rejected
+	LastError string    `json:"last_error,omitempty"`
+
+	webhookURL string
+}
+
+// REST API Models
+type CreateBridgeRequest struct {
+	Kind       Kind   `json:"kind" binding:"required"`
+	WebhookURL string `json:"webhook_url" binding:"required"`
+	Template   string `json:"template,omitempty"` // text/template rendering one message
+}
+
+type CreateBridgeResponse struct {
+	Status string  `json:"status"`
+	Bridge *Bridge `json:"bridge"`
+}
+
+type ListBridgesResponse struct {
+	Topic   string    `json:"topic"`
+	Bridges []*Bridge `json:"bridges"`
+}
+
+type DeleteBridgeResponse struct {
+	Status   string `json:"status"`
+	Topic    string `json:"topic"`
+	BridgeID string `json:"bridge_id"`
+}
@@ -0,0 +1,30 @@
+package bridge
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	endpoint Endpoint
+}
+
+// NewRouteRegistrar creates a new route registrar
+func NewRouteRegistrar(service Service) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		endpoint: NewEndpoint(service),
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	authGroup.POST("/topics/:name/bridges", r.endpoint.CreateBridge)
+	authGroup.GET("/topics/:name/bridges", r.endpoint.ListBridges)
+	authGroup.DELETE("/topics/:name/bridges/:id", r.endpoint.DeleteBridge)
+}
+
+// RegisterUnAuthRoutes registers unauthenticated routes
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	// no unauth routes
+}
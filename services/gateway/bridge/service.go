@@ -0,0 +1,375 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/google/uuid"
+)
+
+const defaultTemplate = `*[{{.Topic}}]* {{json .Payload}}`
+
+// maxFlushRetries bounds the rate-limited attempts to post the remaining
+// messages of a bridge whose topic was deleted
+const maxFlushRetries = 3
+
+// Service interface for chat bridge operations
+type Service interface {
+	CreateBridge(userID, topicName string, req CreateBridgeRequest) (*Bridge, error)
+	ListBridges(userID, topicName string) ([]*Bridge, error)
+	DeleteBridge(userID, topicName, bridgeID string) error
+	// AllBridges returns every user's bridges, for the admin topology
+	AllBridges() []*Bridge
+}
+
+// runner queues messages for one bridge and posts them in rate-limited
+// batches
+type runner struct {
+	bridge     *Bridge
+	template   *template.Template
+	subscriber *pubsub.Subscriber
+	pending    []*pubsub.Message
+	dropped    int // dropped since the last post, reported in the next one
+	nextPost   time.Time
+	lastError  atomic.Value // string
+	stop       chan struct{}
+}
+
+type service struct {
+	pubsubService pubsub.Service
+	webhook       *webhookClient
+	extraHosts    []string
+	runners       map[string]*runner // bridge_id -> runner
+	mu            sync.RWMutex
+}
+
+// NewService creates a new bridge service. extraHosts are webhook hosts
+// allowed in addition to Slack's and Discord's, e.g. a self-hosted
+// Slack-compatible chat server.
+func NewService(extraHosts []string) Service {
+	return &service{
+		pubsubService: pubsub.GetService(),
+		webhook:       &webhookClient{http: &http.Client{Timeout: WebhookTimeout}},
+		extraHosts:    extraHosts,
+		runners:       make(map[string]*runner),
+	}
+}
+
+// ExtraHostsFromEnv reads the comma-separated BRIDGE_WEBHOOK_HOSTS list
+func ExtraHostsFromEnv() []string {
+	var hosts []string
+	for _, host := range strings.Split(os.Getenv("BRIDGE_WEBHOOK_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// CreateBridge starts posting a topic's messages to a chat webhook
+func (s *service) CreateBridge(userID, topicName string, req CreateBridgeRequest) (*Bridge, error) {
+	if !req.Kind.valid() {
+		return nil, fmt.Errorf("invalid bridge: kind must be slack or discord")
+	}
+	if err := validateWebhookURL(req.Kind, req.WebhookURL, s.extraHosts); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := parseTemplate(req.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	bridge := &Bridge{
+		ID:         uuid.New().String(),
+		Topic:      topicName,
+		UserID:     userID,
+		Kind:       req.Kind,
+		Webhook:    maskWebhookURL(req.WebhookURL),
+		Template:   req.Template,
+		CreatedAt:  time.Now(),
+		webhookURL: req.WebhookURL,
+	}
+
+	subscriber, err := s.pubsubService.Subscribe(context.Background(), topicName, clientID(bridge.ID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		bridge:     bridge,
+		template:   tmpl,
+		subscriber: subscriber,
+		stop:       make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.runners[bridge.ID] = r
+	s.mu.Unlock()
+
+	go s.run(r)
+
+	return r.snapshot(), nil
+}
+
+// ListBridges returns the caller's bridges on a topic
+func (s *service) ListBridges(userID, topicName string) ([]*Bridge, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bridges := make([]*Bridge, 0)
+	for _, r := range s.runners {
+		if r.bridge.Topic == topicName && r.bridge.UserID == userID {
+			bridges = append(bridges, r.snapshot())
+		}
+	}
+
+	return bridges, nil
+}
+
+// AllBridges returns every user's bridges on every topic
+func (s *service) AllBridges() []*Bridge {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bridges := make([]*Bridge, 0, len(s.runners))
+	for _, r := range s.runners {
+		bridges = append(bridges, r.snapshot())
+	}
+
+	return bridges
+}
+
+// DeleteBridge removes one of the caller's bridges. Messages not yet posted
+// are discarded.
+func (s *service) DeleteBridge(userID, topicName, bridgeID string) error {
+	s.mu.RLock()
+	r, exists := s.runners[bridgeID]
+	s.mu.RUnlock()
+
+	if !exists || r.bridge.Topic != topicName || r.bridge.UserID != userID {
+		return fmt.Errorf("bridge %s not found", bridgeID)
+	}
+
+	s.stop(r)
+	return nil
+}
+
+// stop removes a runner and its pubsub subscription
+func (s *service) stop(r *runner) {
+	s.mu.Lock()
+	if _, exists := s.runners[r.bridge.ID]; !exists {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.runners, r.bridge.ID)
+	close(r.stop)
+	s.mu.Unlock()
+
+	_ = s.pubsubService.Unsubscribe(context.Background(), r.bridge.Topic, clientID(r.bridge.ID))
+}
+
+// run queues messages and posts them in batches, no more often than the
+// chat service's rate limit allows. When the topic is deleted the queued
+// messages are posted and the bridge is removed.
+func (s *service) run(r *runner) {
+	log := logging.WithContext(context.Background())
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+	scheduled := false
+
+	schedule := func() {
+		if scheduled || len(r.pending) == 0 {
+			return
+		}
+		timer.Reset(max(time.Until(r.nextPost), BatchDelay))
+		scheduled = true
+	}
+
+	add := func(msg *pubsub.Message) {
+		// Chat messages cannot be retracted, so tombstones are not posted
+		if msg.Tombstone != "" {
+			return
+		}
+
+		if len(r.pending) >= MaxPendingMessages {
+			r.pending = r.pending[1:]
+			r.dropped++
+			atomic.AddUint64(&r.bridge.Dropped, 1)
+		}
+		r.pending = append(r.pending, msg)
+		schedule()
+	}
+
+	// Posts are batched, so high priority messages are only queued
+	// alongside the others
+	priority := r.subscriber.PriorityChan
+	for {
+		select {
+		case msg, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			add(msg)
+		case msg, ok := <-r.subscriber.MessageChan:
+			if !ok {
+				select {
+				case <-r.stop:
+				default:
+					log.Infow("Subscription closed, flushing bridge", "bridge_id", r.bridge.ID, "topic", r.bridge.Topic, "reason", r.subscriber.CloseReason())
+					for msg := range r.subscriber.PriorityChan {
+						add(msg)
+					}
+					for retries := 0; len(r.pending) > 0 && retries < maxFlushRetries; {
+						if !s.post(r) {
+							retries++
+						}
+					}
+					s.stop(r)
+				}
+				return
+			}
+			add(msg)
+		case <-timer.C:
+			scheduled = false
+			s.post(r)
+			schedule()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// post sends one batch of pending messages. A rate-limited batch is kept
+// for the next attempt; a rejected one is dropped. It reports whether the
+// batch left the queue.
+func (s *service) post(r *runner) bool {
+	log := logging.WithContext(context.Background())
+
+	if wait := time.Until(r.nextPost); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-r.stop:
+			return false
+		}
+	}
+
+	batch := r.pending[:min(len(r.pending), MaxBatchMessages)]
+	text, err := r.render(batch, r.dropped)
+	if err != nil {
+		log.Errorw("Failed to render bridge message", "error", err, "bridge_id", r.bridge.ID)
+		r.lastError.Store(err.Error())
+		r.pending = r.pending[len(batch):]
+		atomic.AddUint64(&r.bridge.Failed, uint64(len(batch)))
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), WebhookTimeout)
+	err = s.webhook.post(ctx, r.bridge.Kind, r.bridge.webhookURL, text)
+	cancel()
+
+	var limited *rateLimitedError
+	if errors.As(err, &limited) {
+		log.Warnw("Bridge webhook rate limited", "bridge_id", r.bridge.ID, "retry_after", limited.retryAfter)
+		r.nextPost = time.Now().Add(limited.retryAfter)
+		return false
+	}
+
+	r.nextPost = time.Now().Add(r.bridge.Kind.minInterval())
+	r.pending = r.pending[len(batch):]
+	r.dropped = 0
+
+	if err != nil {
+		log.Errorw("Bridge webhook failed", "error", err, "bridge_id", r.bridge.ID, "topic", r.bridge.Topic)
+		r.lastError.Store(err.Error())
+		atomic.AddUint64(&r.bridge.Failed, uint64(len(batch)))
+		return true
+	}
+
+	atomic.AddUint64(&r.bridge.Posted, uint64(len(batch)))
+	log.Infow("Bridge posted", "bridge_id", r.bridge.ID, "topic", r.bridge.Topic, "messages", len(batch))
+	return true
+}
+
+// render formats a batch as one chat message, one line per message,
+// truncated to what the chat service accepts
+func (r *runner) render(batch []*pubsub.Message, dropped int) (string, error) {
+	var text bytes.Buffer
+	if dropped > 0 {
+		fmt.Fprintf(&text, "(%d earlier messages dropped)\n", dropped)
+	}
+	for i, msg := range batch {
+		if i > 0 {
+			text.WriteByte('\n')
+		}
+		if err := r.template.Execute(&text, msg); err != nil {
+			return "", err
+		}
+	}
+
+	rendered := text.String()
+	if limit := r.bridge.Kind.maxText(); len(rendered) > limit {
+		rendered = strings.ToValidUTF8(rendered[:limit-1], "") + "…"
+	}
+	return rendered, nil
+}
+
+// snapshot copies the bridge with its current counters
+func (r *runner) snapshot() *Bridge {
+	lastError, _ := r.lastError.Load().(string)
+	return &Bridge{
+		ID:        r.bridge.ID,
+		Topic:     r.bridge.Topic,
+		UserID:    r.bridge.UserID,
+		Kind:      r.bridge.Kind,
+		Webhook:   r.bridge.Webhook,
+		Template:  r.bridge.Template,
+		CreatedAt: r.bridge.CreatedAt,
+		Posted:    atomic.LoadUint64(&r.bridge.Posted),
+		Dropped:   atomic.LoadUint64(&r.bridge.Dropped),
+		Failed:    atomic.LoadUint64(&r.bridge.Failed),
+		LastError: lastError,
+	}
+}
+
+// parseTemplate parses a message template, falling back to the default
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultTemplate
+	}
+
+	tmpl, err := template.New("message").Funcs(template.FuncMap{
+		"json": func(v interface{}) string {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Sprint(v)
+			}
+			return string(data)
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bridge: template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// clientID returns the pubsub client ID used by a bridge
+func clientID(bridgeID string) string {
+	return "bridge:" + bridgeID
+}
@@ -0,0 +1,115 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimitedError reports a 429 from the webhook and how long to back off
+type rateLimitedError struct {
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("webhook rate limited, retry after %s", e.retryAfter)
+}
+
+// webhookClient posts text to Slack and Discord incoming webhooks
+type webhookClient struct {
+	http *http.Client
+}
+
+// post sends text to the webhook in the payload shape of its kind
+func (w *webhookClient) post(ctx context.Context, kind Kind, webhookURL, text string) error {
+	payload := map[string]string{"text": text}
+	if kind == KindDiscord {
+		payload = map[string]string{"content": text}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &rateLimitedError{retryAfter: retryAfter(resp.Header, respBody, kind)}
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// retryAfter reads the back-off from a 429: the Retry-After header (Slack
+// and Discord), or Discord's retry_after body field, both in seconds
+func retryAfter(header http.Header, body []byte, kind Kind) time.Duration {
+	if seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	var discord struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &discord) == nil && discord.RetryAfter > 0 {
+		return time.Duration(discord.RetryAfter * float64(time.Second))
+	}
+
+	return kind.minInterval()
+}
+
+// validateWebhookURL checks that a webhook URL points at the kind's service,
+// or at one of the extra hosts, so bridges cannot be used to reach arbitrary
+// endpoints from the gateway. Only extra hosts may use plain http.
+func validateWebhookURL(kind Kind, rawURL string, extraHosts []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid bridge: webhook_url is not a valid URL")
+	}
+
+	host := parsed.Hostname()
+	for _, extra := range extraHosts {
+		if host == extra && (parsed.Scheme == "https" || parsed.Scheme == "http") {
+			return nil
+		}
+	}
+	for _, allowed := range defaultHosts[kind] {
+		if host == allowed && parsed.Scheme == "https" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid bridge: webhook_url must be an https URL on %s", strings.Join(defaultHosts[kind], " or "))
+}
+
+// maskWebhookURL hides the secret part of a webhook URL, keeping the scheme,
+// host and first path segment
+func maskWebhookURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.SplitN(strings.TrimPrefix(parsed.Path, "/"), "/", 2)
+	return parsed.Scheme + "://" + parsed.Host + "/" + segments[0] + "/…"
+}
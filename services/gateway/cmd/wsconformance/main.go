@@ -0,0 +1,374 @@
+// Command wsconformance replays a corpus of valid and invalid WebSocket
+// frames, plus fuzz-generated ones, against an in-process WebSocket handler.
+// It checks the response type and error code of every frame, and fails on
+// handler panics and on goroutines left running after all connections close.
+//
+// Usage (from services/gateway):
+//
+//	go run ./cmd/wsconformance [-fuzz 500] [-seed 1] [-corpus file.json]
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/websocket"
+	"github.com/ammysap/plivo-pub-sub/usercontext"
+	gws "github.com/gorilla/websocket"
+)
+
+// conformanceTopic is created before the run; corpus frames may use it
+const conformanceTopic = "conformance"
+
+// strictTopic is created with strict decoding, rejecting unknown fields
+const strictTopic = "conformance-strict"
+
+// responseTimeout bounds the wait for the response to one frame
+const responseTimeout = 2 * time.Second
+
+//go:embed corpus.json
+var defaultCorpus []byte
+
+// Case is one connection's worth of frames, sent in order
+type Case struct {
+	Name   string  `json:"name"`
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is a frame to send, as JSON (Send) or verbatim text (Raw), and the
+// expected outcome
+type Frame struct {
+	Send   json.RawMessage `json:"send,omitempty"`
+	Raw    string          `json:"raw,omitempty"`
+	Expect Expect          `json:"expect"`
+}
+
+// Expect describes the outcome of a frame: a response of Type (with error
+// Code when set), or the server closing the connection
+type Expect struct {
+	Type  websocket.WSResponseType `json:"type,omitempty"`
+	Code  string                   `json:"code,omitempty"`
+	Close bool                     `json:"close,omitempty"`
+}
+
+// harness serves the handler and counts panics raised while serving
+type harness struct {
+	server  *httptest.Server
+	panics  atomic.Int64
+	clients atomic.Int64
+}
+
+func main() {
+	fuzzCount := flag.Int("fuzz", 500, "number of fuzz-generated frames")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "fuzz random seed")
+	corpusPath := flag.String("corpus", "", "corpus file (defaults to the embedded corpus)")
+	flag.Parse()
+
+	corpus := defaultCorpus
+	if *corpusPath != "" {
+		data, err := os.ReadFile(*corpusPath)
+		if err != nil {
+			fatalf("reading corpus: %v", err)
+		}
+		corpus = data
+	}
+
+	var cases []Case
+	if err := json.Unmarshal(corpus, &cases); err != nil {
+		fatalf("parsing corpus: %v", err)
+	}
+
+	ctx := context.Background()
+	pubsubService := pubsub.InitService(pubsub.DefaultConfig())
+	if err := pubsubService.Start(ctx); err != nil {
+		fatalf("starting pubsub: %v", err)
+	}
+	if err := pubsubService.CreateTopic(ctx, conformanceTopic, "", nil); err != nil {
+		fatalf("creating topic: %v", err)
+	}
+	if err := pubsubService.CreateTopic(ctx, strictTopic, "", nil); err != nil {
+		fatalf("creating topic: %v", err)
+	}
+	if err := pubsubService.SetDecoding(ctx, strictTopic, pubsub.Decoding{Strict: true}); err != nil {
+		fatalf("setting decoding: %v", err)
+	}
+
+	h := newHarness(websocket.NewService(nil, nil, nil, nil, nil, websocket.WriteConfig{}))
+	baseline := runtime.NumGoroutine()
+
+	failures := 0
+	for _, c := range cases {
+		if err := h.runCase(c); err != nil {
+			failures++
+			fmt.Printf("FAIL %s: %v\n", c.Name, err)
+			continue
+		}
+		fmt.Printf("ok   %s\n", c.Name)
+	}
+
+	fmt.Printf("fuzz %d frames (seed %d)\n", *fuzzCount, *seed)
+	if err := h.fuzz(rand.New(rand.NewSource(*seed)), *fuzzCount); err != nil {
+		failures++
+		fmt.Printf("FAIL fuzz: %v\n", err)
+	}
+
+	if panics := h.panics.Load(); panics > 0 {
+		failures++
+		fmt.Printf("FAIL handler panicked %d times\n", panics)
+	}
+
+	h.server.Close()
+	if leaked := waitForGoroutines(baseline, 5*time.Second); leaked > 0 {
+		failures++
+		fmt.Printf("FAIL %d goroutines still running after all connections closed\n", leaked)
+	}
+
+	if failures > 0 {
+		fmt.Printf("%d failures\n", failures)
+		os.Exit(1)
+	}
+	fmt.Println("PASS")
+}
+
+// newHarness serves the handler over a test server, authenticating every
+// connection as its own user so cases do not share subscriptions
+func newHarness(service websocket.Service) *harness {
+	h := &harness{}
+	upgrader := gws.Upgrader{}
+
+	h.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				h.panics.Add(1)
+				fmt.Printf("panic: %v\n", recovered)
+			}
+		}()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		userID := fmt.Sprintf("conformance-%d", h.clients.Add(1))
+		ctx := usercontext.CreateSHContextFromUserContext(
+			context.Background(),
+			&usercontext.User{ID: userID},
+			&usercontext.Service{ServiceName: "wsconformance"},
+		)
+		service.HandleWebSocketConnection(conn, ctx)
+	}))
+
+	return h
+}
+
+// dial opens a new connection to the handler
+func (h *harness) dial() (*gws.Conn, error) {
+	url := "ws" + strings.TrimPrefix(h.server.URL, "http")
+	conn, _, err := gws.DefaultDialer.Dial(url, nil)
+	return conn, err
+}
+
+// runCase sends the case's frames on a fresh connection and checks each
+// outcome
+func (h *harness) runCase(c Case) error {
+	conn, err := h.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for i, frame := range c.Frames {
+		data := []byte(frame.Raw)
+		if frame.Send != nil {
+			data = frame.Send
+		}
+
+		if err := conn.WriteMessage(gws.TextMessage, data); err != nil {
+			return fmt.Errorf("frame %d: write: %w", i, err)
+		}
+
+		response, err := readResponse(conn, requestID(data))
+		if frame.Expect.Close {
+			if err == nil {
+				return fmt.Errorf("frame %d: expected close, got %s", i, response.Type)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+
+		if response.Type != frame.Expect.Type {
+			return fmt.Errorf("frame %d: expected %s, got %s", i, frame.Expect.Type, describe(response))
+		}
+		if frame.Expect.Code != "" && (response.Error == nil || response.Error.Code != frame.Expect.Code) {
+			return fmt.Errorf("frame %d: expected code %s, got %s", i, frame.Expect.Code, describe(response))
+		}
+	}
+
+	return nil
+}
+
+// fuzz sends generated frames over a few connections, requiring every frame
+// to be answered with a well-formed response or a close, and reconnecting
+// after a close
+func (h *harness) fuzz(rng *rand.Rand, count int) error {
+	conn, err := h.dial()
+	if err != nil {
+		return err
+	}
+	defer func() { conn.Close() }()
+
+	for i := 0; i < count; i++ {
+		data := fuzzFrame(rng)
+		if err := conn.WriteMessage(gws.TextMessage, data); err != nil {
+			return fmt.Errorf("frame %d %.200q: write: %w", i, data, err)
+		}
+
+		response, err := readResponse(conn, requestID(data))
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return fmt.Errorf("frame %d %.200q: no response within %s", i, data, responseTimeout)
+		}
+		if err != nil {
+			// Undecodable frames close the connection; start a new one
+			conn.Close()
+			if conn, err = h.dial(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch response.Type {
+		case websocket.WSResponseTypeAck, websocket.WSResponseTypePong, websocket.WSResponseTypeTime:
+		case websocket.WSResponseTypeError:
+			if response.Error == nil || response.Error.Code == "" {
+				return fmt.Errorf("frame %d %.200q: error response without code", i, data)
+			}
+			if response.Error.Code == websocket.ErrorCodeInternal {
+				return fmt.Errorf("frame %d %.200q: internal error: %s", i, data, response.Error.Message)
+			}
+		default:
+			return fmt.Errorf("frame %d %.200q: unexpected response %s", i, data, describe(response))
+		}
+	}
+
+	return nil
+}
+
+// readResponse reads frames until the response carrying requestID, skipping
+// events delivered in between
+func readResponse(conn *gws.Conn, requestID string) (*websocket.WSResponse, error) {
+	conn.SetReadDeadline(time.Now().Add(responseTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	for {
+		var response websocket.WSResponse
+		if err := conn.ReadJSON(&response); err != nil {
+			return nil, err
+		}
+		if response.Type == websocket.WSResponseTypeEvent || response.RequestID != requestID {
+			continue
+		}
+		return &response, nil
+	}
+}
+
+// requestID extracts the request_id of a frame, if it decodes
+func requestID(data []byte) string {
+	var frame struct {
+		RequestID string `json:"request_id"`
+	}
+	json.Unmarshal(data, &frame)
+	return frame.RequestID
+}
+
+// fuzzFrame builds a frame from random valid and invalid field values. Most
+// frames are well-formed JSON so they reach the handlers; a few are
+// truncated to exercise the decoder.
+func fuzzFrame(rng *rand.Rand) []byte {
+	pick := func(values ...any) any { return values[rng.Intn(len(values))] }
+
+	frame := map[string]any{
+		"request_id": fmt.Sprintf("fuzz-%d", rng.Int63()),
+	}
+	fields := map[string]func() any{
+		"type": func() any {
+			return pick("subscribe", "unsubscribe", "publish", "ping", "ack", "mark_read", "time", "", "SUBSCRIBE", "x")
+		},
+		"topic": func() any {
+			return pick(conformanceTopic, strictTopic, "", "does-not-exist", strings.Repeat("t", 300), "../")
+		},
+		"last_n":  func() any { return pick(0, 1, 100, -1, 1<<30) },
+		"seq":     func() any { return pick(0, 1, 1<<40) },
+		"durable": func() any { return pick(true, false) },
+		"client_ts": func() any {
+			return pick(time.Now().Format(time.RFC3339Nano), "0001-01-01T00:00:00Z", "yesterday", 0)
+		},
+		"sampling": func() any {
+			return pick(nil, map[string]any{"rate": rng.Float64() * 2}, map[string]any{"every_n": rng.Intn(5) - 1}, map[string]any{})
+		},
+		"tag_filter": func() any {
+			return pick(nil, map[string]any{}, map[string]any{"tags": []any{"a", "b"}, "match": pick("any", "all", "", "x")}, map[string]any{"tags": []any{""}})
+		},
+		"preferences": func() any {
+			return pick(nil, map[string]any{}, map[string]any{"locale": "fr"}, map[string]any{"": "x"}, map[string]any{"locale": ""}, "fr")
+		},
+		"partition": func() any { return pick(nil, 0, 1, -1, 1<<40, "0") },
+		"message": func() any {
+			return pick(nil, map[string]any{}, map[string]any{"id": "m"}, map[string]any{"id": "m", "key": "k", "payload": 1}, map[string]any{"id": fmt.Sprint(rng.Int()), "payload": map[string]any{"n": rng.Int()}},
+				map[string]any{"id": "m", "payload": strings.Repeat("p", rng.Intn(4096))},
+				map[string]any{"id": "m", "payload": 1, "tags": []any{"a", "a", ""}},
+				map[string]any{"id": "m", "payload": json.Number("9007199254740993"), "extra": 1})
+		},
+	}
+	for name, value := range fields {
+		if rng.Intn(3) > 0 {
+			frame[name] = value()
+		}
+	}
+
+	data, _ := json.Marshal(frame)
+	if rng.Intn(20) == 0 {
+		data = data[:rng.Intn(len(data))]
+	}
+	return data
+}
+
+// waitForGoroutines waits for the goroutine count to drop back to baseline
+// and returns how many are left over
+func waitForGoroutines(baseline int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		leaked := runtime.NumGoroutine() - baseline
+		if leaked <= 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// describe formats a response for failure messages
+func describe(response *websocket.WSResponse) string {
+	if response.Error != nil {
+		return fmt.Sprintf("%s (%s: %s)", response.Type, response.Error.Code, response.Error.Message)
+	}
+	return string(response.Type)
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,32 @@
+package demo
+
+import "time"
+
+// Demo parameters
+const (
+	// Password is the password of every demo user
+	Password = "demo-password"
+	// TrafficInterval is how often the traffic generator publishes to each
+	// demo topic
+	TrafficInterval = time.Second
+)
+
+// Users are registered at startup; the first is the owner of every demo
+// topic and an admin
+var Users = []string{"alice", "bob"}
+
+// Topics are created at startup and receive generated traffic
+var Topics = []string{"orders", "notifications", "metrics"}
+
+// SeededUser is a demo user with a ready-to-use token
+type SeededUser struct {
+	ID       string
+	Username string
+	Token    string
+}
+
+// Seeded describes what Seed created
+type Seeded struct {
+	Users  []SeededUser
+	Topics []string
+}
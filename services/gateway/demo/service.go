@@ -0,0 +1,145 @@
+package demo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/topic"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
+	"github.com/google/uuid"
+)
+
+// Service interface for demo mode
+type Service interface {
+	// Seed registers the demo users and creates the demo topics
+	Seed(ctx context.Context) (*Seeded, error)
+	// GenerateTraffic publishes a message to every demo topic each
+	// TrafficInterval until ctx is done
+	GenerateTraffic(ctx context.Context)
+}
+type service struct {
+	pubsubService pubsub.Service
+	userService   user.Service
+	topicService  topic.Service
+}
+
+// NewService creates a new demo service
+func NewService(userService user.Service, topicService topic.Service) Service {
+	return &service{
+		pubsubService: pubsub.GetService(),
+		userService:   userService,
+		topicService:  topicService,
+	}
+}
+
+// EphemeralSecret returns a random HMAC secret for demo mode, so tokens
+// issued by one demo run are useless after it exits
+func EphemeralSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Seed registers the demo users and creates the demo topics
+func (s *service) Seed(ctx context.Context) (*Seeded, error) {
+	log := logging.WithContext(ctx)
+
+	seeded := &Seeded{Topics: Topics}
+	for _, username := range Users {
+		registered, err := s.userService.Register(username, Password, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to register demo user %s: %w", username, err)
+		}
+		token, err := user.GenerateJWTToken(registered)
+		if err != nil {
+			return nil, err
+		}
+		seeded.Users = append(seeded.Users, SeededUser{ID: registered.ID, Username: username, Token: token})
+	}
+
+	owner := seeded.Users[0].ID
+	for _, name := range Topics {
+		if err := s.topicService.CreateTopic(name, owner, time.Time{}, pubsub.Metadata{}, pubsub.TopicOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to create demo topic %s: %w", name, err)
+		}
+	}
+
+	log.Infow("Seeded demo users and topics", "users", Users, "topics", Topics)
+	return seeded, nil
+}
+
+// GenerateTraffic publishes plausible payloads to the demo topics
+func (s *service) GenerateTraffic(ctx context.Context) {
+	log := logging.WithContext(ctx)
+
+	ticker := time.NewTicker(TrafficInterval)
+	defer ticker.Stop()
+
+	statuses := []string{"created", "paid", "shipped", "delivered"}
+	for n := 1; ; n++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		payloads := map[string]interface{}{
+			"orders": map[string]interface{}{
+				"order_id": fmt.Sprintf("ord-%05d", n),
+				"status":   statuses[mathrand.Intn(len(statuses))],
+				"amount":   float64(mathrand.Intn(20000)) / 100,
+			},
+			"notifications": map[string]interface{}{
+				"user": Users[mathrand.Intn(len(Users))],
+				"text": fmt.Sprintf("You have %d new messages", 1+mathrand.Intn(9)),
+			},
+			"metrics": map[string]interface{}{
+				"host":   fmt.Sprintf("web-%d", 1+mathrand.Intn(3)),
+				"cpu":    float64(mathrand.Intn(100)) / 100,
+				"mem_mb": 256 + mathrand.Intn(768),
+			},
+		}
+
+		for _, name := range Topics {
+			message := pubsub.Message{ID: uuid.NewString(), Payload: payloads[name]}
+			if err := s.pubsubService.Publish(ctx, name, message); err != nil {
+				log.Debugw("Demo traffic publish failed", "topic", name, "error", err)
+			}
+		}
+	}
+}
+
+// PrintInstructions writes the demo users' tokens and commands to try
+// against the gateway at baseURL
+func PrintInstructions(w io.Writer, baseURL string, seeded *Seeded) {
+	wsURL := "ws" + strings.TrimPrefix(baseURL, "http")
+
+	fmt.Fprintf(w, "\nPubSub gateway demo mode\n")
+	fmt.Fprintf(w, "State is in memory and the JWT secret is ephemeral: tokens stop working on restart.\n\n")
+
+	fmt.Fprintf(w, "Users (password %q; %s is an admin):\n", Password, seeded.Users[0].Username)
+	for _, u := range seeded.Users {
+		fmt.Fprintf(w, "  %-6s %s\n", u.Username, u.Token)
+	}
+
+	fmt.Fprintf(w, "\nTopics: %s (one message each every %s)\n\n", strings.Join(seeded.Topics, ", "), TrafficInterval)
+
+	fmt.Fprintf(w, "Try:\n")
+	fmt.Fprintf(w, "  export TOKEN=%s\n", seeded.Users[0].Token)
+	fmt.Fprintf(w, "  curl -s %s/topics -H \"Authorization: Bearer $TOKEN\"\n", baseURL)
+	fmt.Fprintf(w, "  curl -s %s/stats -H \"Authorization: Bearer $TOKEN\"\n", baseURL)
+	fmt.Fprintf(w, "  curl -s -X POST %s/users/login -d '{\"username\":\"%s\",\"password\":\"%s\"}'\n",
+		baseURL, seeded.Users[1].Username, Password)
+	fmt.Fprintf(w, "  wscat -c %s/ws -H \"Authorization: Bearer $TOKEN\"\n", wsURL)
+	fmt.Fprintf(w, "    > {\"type\":\"subscribe\",\"topic\":\"%s\",\"last_n\":5,\"request_id\":\"req-1\"}\n\n", seeded.Topics[0])
+}
@@ -0,0 +1,197 @@
+package digest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for digest endpoints
+type Endpoint interface {
+	CreateDigest(c *gin.Context)
+	ListDigests(c *gin.Context)
+	DeleteDigest(c *gin.Context)
+	Confirm(c *gin.Context)
+	Unsubscribe(c *gin.Context)
+}
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// CreateDigest handles POST /topics/{name}/digests
+func (e *endpoint) CreateDigest(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	userID := c.GetString("user_id")
+
+	var req CreateDigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	digest, err := e.service.CreateDigest(userID, topicName, req)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid digest") {
+			log.Warnw("Invalid digest", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error creating digest", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create digest"})
+		return
+	}
+
+	response := CreateDigestResponse{
+		Status: "pending_confirmation",
+		Digest: digest,
+	}
+
+	log.Infow("Digest created, awaiting confirmation", "topic", topicName, "digest_id", digest.ID, "frequency", digest.Frequency)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListDigests handles GET /topics/{name}/digests
+func (e *endpoint) ListDigests(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	digests, err := e.service.ListDigests(c.GetString("user_id"), topicName)
+	if err != nil {
+		log.Errorw("Error listing digests", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list digests"})
+		return
+	}
+
+	response := ListDigestsResponse{
+		Topic:   topicName,
+		Digests: digests,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteDigest handles DELETE /topics/{name}/digests/{id}
+func (e *endpoint) DeleteDigest(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	digestID := c.Param("id")
+
+	err = e.service.DeleteDigest(c.GetString("user_id"), topicName, digestID)
+	if err != nil {
+		if err.Error() == "digest "+digestID+" not found" {
+			log.Warnw("Digest not found", "topic", topicName, "digest_id", digestID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Digest not found"})
+			return
+		}
+		log.Errorw("Error deleting digest", "error", err.Error(), "digest_id", digestID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete digest"})
+		return
+	}
+
+	response := DeleteDigestResponse{
+		Status:   "deleted",
+		Topic:    topicName,
+		DigestID: digestID,
+	}
+
+	log.Infow("Digest deleted successfully", "topic", topicName, "digest_id", digestID)
+	c.JSON(http.StatusOK, response)
+}
+
+// Confirm handles GET /digests/confirm?token=...
+func (e *endpoint) Confirm(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	digest, err := e.service.Confirm(token)
+	if err != nil {
+		log.Warnw("Digest confirmation failed", "error", err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "Digest not found, expired or already confirmed"})
+		return
+	}
+
+	response := ConfirmResponse{
+		Status: "confirmed",
+		Topic:  digest.Topic,
+		Email:  digest.Email,
+	}
+
+	log.Infow("Digest confirmed via link", "topic", digest.Topic, "digest_id", digest.ID)
+	c.JSON(http.StatusOK, response)
+}
+
+// Unsubscribe handles GET /digests/unsubscribe?token=...
+func (e *endpoint) Unsubscribe(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	digest, err := e.service.Unsubscribe(token)
+	if err != nil {
+		log.Warnw("Unsubscribe with unknown token")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Digest not found or already unsubscribed"})
+		return
+	}
+
+	response := UnsubscribeResponse{
+		Status: "unsubscribed",
+		Topic:  digest.Topic,
+		Email:  digest.Email,
+	}
+
+	log.Infow("Digest unsubscribed via link", "topic", digest.Topic, "digest_id", digest.ID)
+	c.JSON(http.StatusOK, response)
+}
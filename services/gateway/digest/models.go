@@ -0,0 +1,88 @@
+package digest
+
+import (
+	"time"
+)
+
+// Frequency controls how often a digest is sent
+type Frequency string
+
+const (
+	FrequencyImmediate Frequency = "immediate"
+	FrequencyHourly    Frequency = "hourly"
+	FrequencyDaily     Frequency = "daily"
+)
+
+// interval returns the flush interval, zero for immediate delivery
+func (f Frequency) interval() time.Duration {
+	switch f {
+	case FrequencyHourly:
+		return time.Hour
+	case FrequencyDaily:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// valid reports whether the frequency is supported
+func (f Frequency) valid() bool {
+	return f == FrequencyImmediate || f == FrequencyHourly || f == FrequencyDaily
+}
+
+// MaxDigestMessages bounds the messages held for one digest between sends;
+// older messages beyond the bound are counted but not included
+const MaxDigestMessages = 100
+
+// ConfirmationTTL is how long the confirmation link of a new digest is
+// valid; digests not confirmed in time are removed
+const ConfirmationTTL = 24 * time.Hour
+
+// Digest represents an email digest attached to a topic
+type Digest struct {
+	ID              string    `json:"id"`
+	Topic           string    `json:"topic"`
+	UserID          string    `json:"user_id"`
+	Email           string    `json:"email"`
+	Frequency       Frequency `json:"frequency"`
+	SubjectTemplate string    `json:"subject_template,omitempty"`
+	BodyTemplate    string    `json:"body_template,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	Confirmed       bool      `json:"confirmed"` // nothing is sent until the recipient follows the confirmation link
+}
+
+// REST API Models
+type CreateDigestRequest struct {
+	Email           string    `json:"email" binding:"required"`
+	Frequency       Frequency `json:"frequency"`
+	SubjectTemplate string    `json:"subject_template,omitempty"`
+	BodyTemplate    string    `json:"body_template,omitempty"`
+}
+
+type CreateDigestResponse struct {
+	Status string  `json:"status"`
+	Digest *Digest `json:"digest"`
+}
+
+type ListDigestsResponse struct {
+	Topic   string    `json:"topic"`
+	Digests []*Digest `json:"digests"`
+}
+
+type DeleteDigestResponse struct {
+	Status   string `json:"status"`
+	Topic    string `json:"topic"`
+	DigestID string `json:"digest_id"`
+}
+
+type ConfirmResponse struct {
+	Status string `json:"status"`
+	Topic  string `json:"topic"`
+	Email  string `json:"email"`
+}
+
+type UnsubscribeResponse struct {
+	Status string `json:"status"`
+	Topic  string `json:"topic"`
+	Email  string `json:"email"`
+}
@@ -0,0 +1,32 @@
+package digest
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	endpoint Endpoint
+}
+
+// NewRouteRegistrar creates a new route registrar
+func NewRouteRegistrar(service Service) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		endpoint: NewEndpoint(service),
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	authGroup.POST("/topics/:name/digests", r.endpoint.CreateDigest)
+	authGroup.GET("/topics/:name/digests", r.endpoint.ListDigests)
+	authGroup.DELETE("/topics/:name/digests/:id", r.endpoint.DeleteDigest)
+}
+
+// RegisterUnAuthRoutes registers unauthenticated routes
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	// Confirmation and unsubscribe links in emails carry their own token
+	unAuthGroup.GET("/digests/confirm", r.endpoint.Confirm)
+	unAuthGroup.GET("/digests/unsubscribe", r.endpoint.Unsubscribe)
+}
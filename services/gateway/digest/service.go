@@ -0,0 +1,445 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/notifier"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSubjectTemplate = `[{{.Topic}}] {{len .Messages}} new message{{if ne (len .Messages) 1}}s{{end}}`
+	defaultBodyTemplate    = `{{range .Messages}}- {{.Timestamp.Format "2006-01-02 15:04:05 MST"}} {{.ID}}: {{json .Payload}}
+{{end}}{{if .Dropped}}...and {{.Dropped}} more
+{{end}}
+To stop receiving these emails, visit {{.UnsubscribeURL}}
+`
+	confirmationSubject = "Confirm your email digest of %s"
+	confirmationBody    = `A %s email digest of topic %s was requested for this address.
+
+To start receiving it, visit %s
+
+The link expires in %s. If you did not ask for this digest, ignore this email and nothing will be sent.
+`
+)
+
+// Service interface for email digest operations
+type Service interface {
+	CreateDigest(userID, topicName string, req CreateDigestRequest) (*Digest, error)
+	ListDigests(userID, topicName string) ([]*Digest, error)
+	DeleteDigest(userID, topicName, digestID string) error
+	// Confirm starts the digest identified by a confirmation link token
+	Confirm(token string) (*Digest, error)
+	Unsubscribe(token string) (*Digest, error)
+	// AllDigests returns every user's digests, for the admin topology
+	AllDigests() []*Digest
+}
+
+// templateData is passed to digest subject and body templates
+type templateData struct {
+	Topic          string
+	Messages       []*pubsub.Message
+	Dropped        int
+	UnsubscribeURL string
+}
+
+// runner collects messages for one digest and sends them on schedule. It
+// has no subscriber until the digest is confirmed.
+type runner struct {
+	digest       *Digest
+	token        string
+	confirmToken string
+	confirmBy    time.Time
+	subject      *template.Template
+	body         *template.Template
+	subscriber   *pubsub.Subscriber
+	pending      []*pubsub.Message
+	dropped      int
+	stop         chan struct{}
+}
+
+type service struct {
+	pubsubService pubsub.Service
+	notifier      notifier.Notifier
+	baseURL       string
+	runners       map[string]*runner // digest_id -> runner
+	tokens        map[string]string  // unsubscribe token -> digest_id
+	confirmations map[string]string  // confirmation token -> digest_id, until confirmed
+	mu            sync.RWMutex
+}
+
+// NewService creates a new digest service. baseURL is used to build the
+// unsubscribe links included in every email.
+func NewService(n notifier.Notifier, baseURL string) Service {
+	return &service{
+		pubsubService: pubsub.GetService(),
+		notifier:      n,
+		baseURL:       baseURL,
+		runners:       make(map[string]*runner),
+		tokens:        make(map[string]string),
+		confirmations: make(map[string]string),
+	}
+}
+
+// CreateDigest attaches an email digest to a topic and emails a
+// confirmation link to its address. Nothing else is sent to the address
+// until the link is followed, so users cannot direct digests at addresses
+// they do not control.
+func (s *service) CreateDigest(userID, topicName string, req CreateDigestRequest) (*Digest, error) {
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return nil, fmt.Errorf("invalid digest: email is not a valid address")
+	}
+	if req.Frequency == "" {
+		req.Frequency = FrequencyDaily
+	}
+	if !req.Frequency.valid() {
+		return nil, fmt.Errorf("invalid digest: frequency must be immediate, hourly or daily")
+	}
+
+	subject, err := parseTemplate("subject", req.SubjectTemplate, defaultSubjectTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := parseTemplate("body", req.BodyTemplate, defaultBodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.pubsubService.GetTopic(context.Background(), topicName); err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate unsubscribe token: %w", err)
+	}
+	confirmToken, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+
+	now := time.Now()
+	digest := &Digest{
+		ID:              uuid.New().String(),
+		Topic:           topicName,
+		UserID:          userID,
+		Email:           req.Email,
+		Frequency:       req.Frequency,
+		SubjectTemplate: req.SubjectTemplate,
+		BodyTemplate:    req.BodyTemplate,
+		CreatedAt:       now,
+	}
+
+	r := &runner{
+		digest:       digest,
+		token:        token,
+		confirmToken: confirmToken,
+		confirmBy:    now.Add(ConfirmationTTL),
+		subject:      subject,
+		body:         body,
+		stop:         make(chan struct{}),
+	}
+
+	err = s.notifier.Notify(context.Background(), &notifier.Notification{
+		To:      []string{digest.Email},
+		Subject: fmt.Sprintf(confirmationSubject, topicName),
+		Body: fmt.Sprintf(confirmationBody, digest.Frequency, topicName,
+			s.baseURL+"/digests/confirm?token="+confirmToken, ConfirmationTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	s.mu.Lock()
+	s.removeUnconfirmed(now)
+	s.runners[digest.ID] = r
+	s.tokens[token] = digest.ID
+	s.confirmations[confirmToken] = digest.ID
+	s.mu.Unlock()
+
+	return digest, nil
+}
+
+// Confirm subscribes the digest identified by a confirmation link token to
+// its topic and starts sending it
+func (s *service) Confirm(token string) (*Digest, error) {
+	s.mu.Lock()
+	digestID, exists := s.confirmations[token]
+	r := s.runners[digestID]
+	if !exists || r == nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("digest not found")
+	}
+	if time.Now().After(r.confirmBy) {
+		s.remove(r)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("digest confirmation expired")
+	}
+	delete(s.confirmations, token)
+	s.mu.Unlock()
+
+	subscriber, err := s.pubsubService.Subscribe(context.Background(), r.digest.Topic, clientID(r.digest.ID), nil)
+	if err != nil {
+		s.mu.Lock()
+		s.remove(r)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	// The digest may have been deleted while subscribing
+	s.mu.Lock()
+	if s.runners[digestID] != r {
+		s.mu.Unlock()
+		_ = s.pubsubService.Unsubscribe(context.Background(), r.digest.Topic, clientID(r.digest.ID))
+		return nil, fmt.Errorf("digest not found")
+	}
+	confirmed := *r.digest
+	confirmed.Confirmed = true
+	r.digest = &confirmed
+	r.subscriber = subscriber
+	s.mu.Unlock()
+
+	go s.run(r)
+
+	return r.digest, nil
+}
+
+// ListDigests returns the caller's digests on a topic
+func (s *service) ListDigests(userID, topicName string) ([]*Digest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	digests := make([]*Digest, 0)
+	for _, r := range s.runners {
+		if r.digest.Topic == topicName && r.digest.UserID == userID {
+			digests = append(digests, r.digest)
+		}
+	}
+
+	return digests, nil
+}
+
+// AllDigests returns every user's digests on every topic
+func (s *service) AllDigests() []*Digest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	digests := make([]*Digest, 0, len(s.runners))
+	for _, r := range s.runners {
+		digests = append(digests, r.digest)
+	}
+
+	return digests
+}
+
+// DeleteDigest removes one of the caller's digests
+func (s *service) DeleteDigest(userID, topicName, digestID string) error {
+	s.mu.RLock()
+	r, exists := s.runners[digestID]
+	s.mu.RUnlock()
+
+	if !exists || r.digest.Topic != topicName || r.digest.UserID != userID {
+		return fmt.Errorf("digest %s not found", digestID)
+	}
+
+	s.stop(r)
+	return nil
+}
+
+// Unsubscribe removes the digest identified by an unsubscribe link token
+func (s *service) Unsubscribe(token string) (*Digest, error) {
+	s.mu.RLock()
+	digestID, exists := s.tokens[token]
+	r := s.runners[digestID]
+	s.mu.RUnlock()
+
+	if !exists || r == nil {
+		return nil, fmt.Errorf("digest not found")
+	}
+
+	s.stop(r)
+	return r.digest, nil
+}
+
+// stop removes a runner and its pubsub subscription without a final send
+func (s *service) stop(r *runner) {
+	s.mu.Lock()
+	removed := s.remove(r)
+	subscribed := r.subscriber != nil
+	s.mu.Unlock()
+
+	if removed && subscribed {
+		_ = s.pubsubService.Unsubscribe(context.Background(), r.digest.Topic, clientID(r.digest.ID))
+	}
+}
+
+// remove forgets a runner and its tokens and stops its goroutine, if any,
+// reporting whether it was still registered. The caller holds s.mu.
+func (s *service) remove(r *runner) bool {
+	if s.runners[r.digest.ID] != r {
+		return false
+	}
+	delete(s.runners, r.digest.ID)
+	delete(s.tokens, r.token)
+	delete(s.confirmations, r.confirmToken)
+	close(r.stop)
+	return true
+}
+
+// removeUnconfirmed removes the digests whose confirmation link expired
+// before now. The caller holds s.mu.
+func (s *service) removeUnconfirmed(now time.Time) {
+	for _, r := range s.runners {
+		if r.subscriber == nil && now.After(r.confirmBy) {
+			s.remove(r)
+		}
+	}
+}
+
+// run collects messages and sends the digest on its schedule. When the topic
+// is deleted the remaining messages are sent and the digest is removed.
+func (s *service) run(r *runner) {
+	log := logging.WithContext(context.Background())
+
+	var tick <-chan time.Time
+	if interval := r.digest.Frequency.interval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	add := func(msg *pubsub.Message) {
+		if len(r.pending) >= MaxDigestMessages {
+			r.pending = r.pending[1:]
+			r.dropped++
+		}
+		r.pending = append(r.pending, msg)
+	}
+
+	// Digests are batched, so high priority messages are only collected
+	// alongside the others
+	priority := r.subscriber.PriorityChan
+	for {
+		select {
+		case msg, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			add(msg)
+			if r.digest.Frequency == FrequencyImmediate {
+				s.send(r)
+			}
+		case msg, ok := <-r.subscriber.MessageChan:
+			if !ok {
+				select {
+				case <-r.stop:
+				default:
+					log.Infow("Subscription closed, sending final digest", "digest_id", r.digest.ID, "topic", r.digest.Topic, "reason", r.subscriber.CloseReason())
+					for msg := range r.subscriber.PriorityChan {
+						add(msg)
+					}
+					s.send(r)
+					s.stop(r)
+				}
+				return
+			}
+
+			add(msg)
+			if r.digest.Frequency == FrequencyImmediate {
+				s.send(r)
+			}
+		case <-tick:
+			s.send(r)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// send renders and delivers the pending messages, if any
+func (s *service) send(r *runner) {
+	log := logging.WithContext(context.Background())
+
+	if len(r.pending) == 0 {
+		return
+	}
+
+	data := templateData{
+		Topic:          r.digest.Topic,
+		Messages:       r.pending,
+		Dropped:        r.dropped,
+		UnsubscribeURL: s.baseURL + "/digests/unsubscribe?token=" + r.token,
+	}
+	r.pending = nil
+	r.dropped = 0
+
+	var subject, body bytes.Buffer
+	if err := r.subject.Execute(&subject, data); err != nil {
+		log.Errorw("Failed to render digest subject", "error", err, "digest_id", r.digest.ID)
+		return
+	}
+	if err := r.body.Execute(&body, data); err != nil {
+		log.Errorw("Failed to render digest body", "error", err, "digest_id", r.digest.ID)
+		return
+	}
+
+	err := s.notifier.Notify(context.Background(), &notifier.Notification{
+		To:      []string{r.digest.Email},
+		Subject: subject.String(),
+		Body:    body.String(),
+	})
+	if err != nil {
+		log.Errorw("Failed to send digest", "error", err, "digest_id", r.digest.ID, "topic", r.digest.Topic)
+		return
+	}
+
+	log.Infow("Digest sent", "digest_id", r.digest.ID, "topic", r.digest.Topic, "messages", len(data.Messages))
+}
+
+// parseTemplate parses a user template, falling back to the default
+func parseTemplate(name, text, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"json": func(v interface{}) string {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Sprint(v)
+			}
+			return string(data)
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest: %s template: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+// clientID returns the pubsub client ID used by a digest
+func clientID(digestID string) string {
+	return "digest:" + digestID
+}
+
+// generateToken generates a random unsubscribe token
+func generateToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
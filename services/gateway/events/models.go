@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// Kind identifies what happened
+type Kind string
+
+// Event kinds. The subject of user events is the user ID; the subject of
+// topic events is the topic name; the subject of maintenance events is the
+// window ID.
+const (
+	KindUserRegistered Kind = "user.registered"
+	KindUserDeleted    Kind = "user.deleted"
+	KindUserDisabled   Kind = "user.disabled"
+	KindTopicCreated   Kind = "topic.created"
+	KindTopicDeleted   Kind = "topic.deleted"
+
+	// KindMaintenanceStarted puts the gateway in drain mode for a
+	// maintenance window, and KindMaintenanceEnded takes it out again
+	KindMaintenanceStarted Kind = "maintenance.started"
+	KindMaintenanceEnded   Kind = "maintenance.ended"
+)
+
+// Kinds lists every event kind, for subscribers interested in all of them
+var Kinds = []Kind{KindUserRegistered, KindUserDeleted, KindUserDisabled, KindTopicCreated, KindTopicDeleted,
+	KindMaintenanceStarted, KindMaintenanceEnded}
+
+// Event is something one gateway module did that others may react to
+type Event struct {
+	Kind    Kind      `json:"kind"`
+	Subject string    `json:"subject"`
+	At      time.Time `json:"at"`
+}
+
+// Handler reacts to an event. Handlers run on the publisher's goroutine, so
+// they must be quick and must not publish events of the kind they handle.
+type Handler func(ctx context.Context, event Event)
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Bus delivers events between gateway modules, so a module can react to
+// another's changes without importing it
+type Bus interface {
+	// Publish delivers the event to every handler subscribed to its kind,
+	// in subscription order, and returns once they have all run. A zero At
+	// is set to the current time.
+	Publish(ctx context.Context, event Event)
+	// Subscribe registers a handler for one kind of event. name identifies
+	// the subscriber in logs.
+	Subscribe(kind Kind, name string, handler Handler)
+}
+
+type subscription struct {
+	name    string
+	handler Handler
+}
+
+type bus struct {
+	subscriptions map[Kind][]subscription
+	mu            sync.RWMutex
+}
+
+// NewBus creates an empty event bus
+func NewBus() Bus {
+	return &bus{
+		subscriptions: make(map[Kind][]subscription),
+	}
+}
+
+// Publish runs the event's handlers. A handler that panics is logged and
+// does not stop the others.
+func (b *bus) Publish(ctx context.Context, event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.RLock()
+	subscriptions := b.subscriptions[event.Kind]
+	b.mu.RUnlock()
+
+	log := logging.WithContext(ctx)
+	log.Debugw("Publishing event", "kind", event.Kind, "subject", event.Subject, "handlers", len(subscriptions))
+
+	for _, sub := range subscriptions {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorw("Event handler panicked", "kind", event.Kind, "subject", event.Subject,
+						"subscriber", sub.name, "panic", r)
+				}
+			}()
+			sub.handler(ctx, event)
+		}()
+	}
+}
+
+// Subscribe appends a handler for the kind
+func (b *bus) Subscribe(kind Kind, name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Copy on write, so Publish can iterate a snapshot without the lock
+	subscriptions := make([]subscription, len(b.subscriptions[kind]), len(b.subscriptions[kind])+1)
+	copy(subscriptions, b.subscriptions[kind])
+	b.subscriptions[kind] = append(subscriptions, subscription{name: name, handler: handler})
+}
@@ -0,0 +1,60 @@
+package exchange
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for token exchange endpoints
+type Endpoint interface {
+	Exchange(c *gin.Context)
+}
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// Exchange handles POST /auth/token
+func (e *endpoint) Exchange(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Tokens must never be cached, per RFC 6749
+	c.Header("Cache-Control", "no-store")
+
+	var req ExchangeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Errorw("Error binding request", "error", err.Error())
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: ErrInvalidRequest, ErrorDescription: err.Error()})
+		return
+	}
+
+	response, err := e.service.Exchange(req)
+	if err != nil {
+		code, description, found := strings.Cut(err.Error(), ": ")
+		if found && isOAuthError(code) {
+			log.Warnw("Token exchange rejected", "error", err.Error(), "audience", req.Audience, "scope", req.Scope)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: code, ErrorDescription: description})
+			return
+		}
+		log.Errorw("Error exchanging token", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "server_error", ErrorDescription: "Failed to issue token"})
+		return
+	}
+
+	log.Infow("Token exchanged", "audience", req.Audience, "scope", response.Scope, "expires_in", response.ExpiresIn)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,64 @@
+package exchange
+
+import (
+	"time"
+)
+
+// GrantTypeTokenExchange is the RFC 8693 grant type of an exchange request
+const GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Token types a subject token may be presented as and an issued token is
+// reported as
+const (
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// RoleService is the role a token must hold to be exchanged
+const RoleService = "service"
+
+// AudiencePrefix marks the audience of exchanged tokens, which names the
+// topic ("pubsub:topic:<name>") or tenant ("pubsub:tenant:<tenant>") they
+// are scoped to
+const AudiencePrefix = "pubsub:"
+
+// Targets an exchanged token can be scoped to, as given in audience
+const (
+	TargetTopic  = "topic:"
+	TargetTenant = "tenant:"
+)
+
+// Exchanged token lifetimes
+const (
+	DefaultTokenTTL = 5 * time.Minute
+	MaxTokenTTL     = time.Hour
+)
+
+// REST API Models
+
+// ExchangeRequest is an RFC 8693 token exchange request, sent as a form or
+// as JSON
+type ExchangeRequest struct {
+	GrantType          string `form:"grant_type" json:"grant_type"`
+	SubjectToken       string `form:"subject_token" json:"subject_token"`
+	SubjectTokenType   string `form:"subject_token_type" json:"subject_token_type"`
+	Audience           string `form:"audience" json:"audience"`                         // "topic:<name>" or "tenant:<tenant>"
+	Scope              string `form:"scope" json:"scope"`                               // "publish" or "subscribe"
+	RequestedTokenType string `form:"requested_token_type" json:"requested_token_type"` // optional
+	ExpiresIn          int    `form:"expires_in" json:"expires_in"`                     // seconds; defaults to DefaultTokenTTL
+}
+
+// ExchangeResponse carries the issued token
+type ExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"` // seconds
+	Scope           string `json:"scope"`
+}
+
+// ErrorResponse is an RFC 6749 error, with one of the OAuth error codes
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
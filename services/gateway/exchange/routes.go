@@ -0,0 +1,29 @@
+package exchange
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	endpoint Endpoint
+}
+
+// NewRouteRegistrar creates a new route registrar
+func NewRouteRegistrar(service Service) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		endpoint: NewEndpoint(service),
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	// no auth routes
+}
+
+// RegisterUnAuthRoutes registers unauthenticated routes. The exchange
+// authenticates the subject token in its body, not the request.
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	unAuthGroup.POST("/auth/token", r.endpoint.Exchange)
+}
@@ -0,0 +1,192 @@
+package exchange
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/libraries/auth"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// OAuth error codes an exchange is rejected with, per RFC 6749 and 8693
+const (
+	ErrInvalidRequest       = "invalid_request"
+	ErrInvalidGrant         = "invalid_grant"
+	ErrInvalidScope         = "invalid_scope"
+	ErrInvalidTarget        = "invalid_target"
+	ErrUnsupportedGrantType = "unsupported_grant_type"
+)
+
+var oauthErrors = []string{ErrInvalidRequest, ErrInvalidGrant, ErrInvalidScope, ErrInvalidTarget, ErrUnsupportedGrantType}
+
+// isOAuthError reports whether code is an error an exchange is rejected with
+func isOAuthError(code string) bool {
+	return slices.Contains(oauthErrors, code)
+}
+
+// tokenTypes are the token types a subject token may be presented as and
+// a client may request
+var tokenTypes = []string{TokenTypeJWT, TokenTypeAccessToken}
+
+// Service interface for exchanging service tokens
+type Service interface {
+	// Exchange trades a service token for a short-lived token scoped to one
+	// action on a topic or tenant
+	Exchange(req ExchangeRequest) (ExchangeResponse, error)
+}
+
+type service struct {
+	now func() time.Time
+}
+
+// NewService creates a new token exchange service
+func NewService() Service {
+	return &service{
+		now: auth.Now,
+	}
+}
+
+// Exchange verifies the subject token and issues a token restricted to the
+// requested action on the requested topic or tenant. The token expires
+// with the subject token, if that is sooner than requested.
+func (s *service) Exchange(req ExchangeRequest) (ExchangeResponse, error) {
+	if req.GrantType != GrantTypeTokenExchange {
+		return ExchangeResponse{}, fmt.Errorf("%s: grant_type must be %s", ErrUnsupportedGrantType, GrantTypeTokenExchange)
+	}
+	if req.SubjectToken == "" {
+		return ExchangeResponse{}, fmt.Errorf("%s: subject_token is required", ErrInvalidRequest)
+	}
+	if !slices.Contains(tokenTypes, req.SubjectTokenType) {
+		return ExchangeResponse{}, fmt.Errorf("%s: subject_token_type must be %s", ErrInvalidRequest, strings.Join(tokenTypes, " or "))
+	}
+	if req.RequestedTokenType != "" && !slices.Contains(tokenTypes, req.RequestedTokenType) {
+		return ExchangeResponse{}, fmt.Errorf("%s: requested_token_type must be %s", ErrInvalidRequest, strings.Join(tokenTypes, " or "))
+	}
+	if req.ExpiresIn < 0 || time.Duration(req.ExpiresIn)*time.Second > MaxTokenTTL {
+		return ExchangeResponse{}, fmt.Errorf("%s: expires_in must be between 1 and %d seconds", ErrInvalidRequest, int(MaxTokenTTL.Seconds()))
+	}
+
+	action := pubsub.Action(req.Scope)
+	if action != pubsub.ActionPublish && action != pubsub.ActionSubscribe {
+		return ExchangeResponse{}, fmt.Errorf("%s: scope must be publish or subscribe", ErrInvalidScope)
+	}
+
+	topic, tenant, err := parseTarget(req.Audience)
+	if err != nil {
+		return ExchangeResponse{}, err
+	}
+
+	subject, err := auth.Verify(req.SubjectToken)
+	if err != nil {
+		return ExchangeResponse{}, fmt.Errorf("%s: subject_token is invalid or expired", ErrInvalidGrant)
+	}
+	if IsExchanged(subject) {
+		return ExchangeResponse{}, fmt.Errorf("%s: an exchanged token cannot be exchanged again", ErrInvalidGrant)
+	}
+	if !subject.HasRole(RoleService) {
+		return ExchangeResponse{}, fmt.Errorf("%s: subject_token must hold the %s role", ErrInvalidGrant, RoleService)
+	}
+
+	// A service bound to a tenant can only delegate within it
+	target := topic + tenant
+	if subject.Tenant != "" && !pubsub.InNamespace(target, subject.Tenant) {
+		return ExchangeResponse{}, fmt.Errorf("%s: %s is outside tenant %s", ErrInvalidTarget, target, subject.Tenant)
+	}
+	if tenant == "" {
+		tenant = subject.Tenant
+	}
+
+	now := s.now()
+	ttl := DefaultTokenTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if subject.ExpiresAt != nil {
+		ttl = min(ttl, subject.ExpiresAt.Sub(now).Truncate(time.Second))
+	}
+	if ttl <= 0 {
+		return ExchangeResponse{}, fmt.Errorf("%s: subject_token is invalid or expired", ErrInvalidGrant)
+	}
+
+	audience := AudiencePrefix + req.Audience
+	token, err := auth.GenerateJWTWithClaims(&auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   subject.Subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Tenant: tenant,
+		Scopes: []string{string(action)},
+	})
+	if err != nil {
+		return ExchangeResponse{}, err
+	}
+
+	issuedType := req.RequestedTokenType
+	if issuedType == "" {
+		issuedType = TokenTypeAccessToken
+	}
+
+	return ExchangeResponse{
+		AccessToken:     token,
+		IssuedTokenType: issuedType,
+		TokenType:       "Bearer",
+		ExpiresIn:       int(ttl.Seconds()),
+		Scope:           string(action),
+	}, nil
+}
+
+// parseTarget splits an audience into the topic or the tenant it names
+func parseTarget(audience string) (topic, tenant string, err error) {
+	name, isTopic := strings.CutPrefix(audience, TargetTopic)
+	if !isTopic {
+		var isTenant bool
+		if name, isTenant = strings.CutPrefix(audience, TargetTenant); !isTenant {
+			return "", "", fmt.Errorf("%s: audience must be %s<name> or %s<tenant>", ErrInvalidTarget, TargetTopic, TargetTenant)
+		}
+	}
+
+	if err := pubsub.ValidateTopicName(name); err != nil {
+		return "", "", fmt.Errorf("%s: %s", ErrInvalidTarget, strings.TrimPrefix(err.Error(), "invalid topic name: "))
+	}
+	if isTopic {
+		return name, "", nil
+	}
+	return "", name, nil
+}
+
+// IsExchanged reports whether claims are those of an exchanged token
+func IsExchanged(claims *auth.Claims) bool {
+	return slices.ContainsFunc(claims.Audience, func(audience string) bool {
+		return strings.HasPrefix(audience, AudiencePrefix)
+	})
+}
+
+// GrantFromClaims returns the pubsub grant an exchanged token is restricted
+// to, or nil for any other token. A malformed exchanged token is granted
+// nothing.
+func GrantFromClaims(claims *auth.Claims) *pubsub.Grant {
+	if !IsExchanged(claims) {
+		return nil
+	}
+
+	grant := &pubsub.Grant{}
+	if len(claims.Scopes) == 1 {
+		grant.Action = pubsub.Action(claims.Scopes[0])
+	}
+	for _, audience := range claims.Audience {
+		target := strings.TrimPrefix(audience, AudiencePrefix)
+		if topic, found := strings.CutPrefix(target, TargetTopic); found {
+			grant.Topics = append(grant.Topics, topic)
+		} else if tenant, found := strings.CutPrefix(target, TargetTenant); found {
+			grant.Namespace = tenant
+		}
+	}
+	return grant
+}
@@ -0,0 +1,46 @@
+package limits
+
+import (
+	"net/http"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for rate limit endpoints
+type Endpoint interface {
+	GetEffectiveLimits(c *gin.Context)
+}
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// GetEffectiveLimits handles GET /limits/effective?topic=...
+func (e *endpoint) GetEffectiveLimits(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limits := []EffectiveLimit{
+		e.service.Effective(ScopeUser, c.GetString("user_id")),
+	}
+	if apiKey := c.GetString("api_key_id"); apiKey != "" {
+		limits = append(limits, e.service.Effective(ScopeAPIKey, apiKey))
+	}
+	if topicName := c.Query("topic"); topicName != "" {
+		limits = append(limits, e.service.Effective(ScopeTopic, topicName))
+	}
+
+	connections := e.service.Connections(c.GetString("user_id"))
+	c.JSON(http.StatusOK, EffectiveLimitsResponse{Limits: limits, Connections: &connections})
+}
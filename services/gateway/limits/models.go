@@ -0,0 +1,92 @@
+package limits
+
+// Scope identifies the kind of principal a limit applies to
+type Scope string
+
+const (
+	ScopeUser   Scope = "user"
+	ScopeAPIKey Scope = "api_key"
+	ScopeTopic  Scope = "topic"
+	// ScopeConnection limits the frames read from each WebSocket connection,
+	// keyed by the connection's user ID for overrides
+	ScopeConnection Scope = "connection"
+)
+
+// Default limits, applied when no environment configuration is present
+const (
+	DefaultUserRate    = 50.0
+	DefaultUserBurst   = 100
+	DefaultAPIKeyRate  = 50.0
+	DefaultAPIKeyBurst = 100
+	DefaultTopicRate   = 1000.0
+	DefaultTopicBurst  = 2000
+
+	DefaultConnectionRate  = 100.0
+	DefaultConnectionBurst = 200
+
+	// DefaultMaxConnections caps simultaneous WebSocket connections per user
+	DefaultMaxConnections = 5
+)
+
+// Limit is a token bucket: Rate tokens per second sustained, up to Burst
+// tokens at once. A zero Rate disables limiting for the scope.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Unlimited reports whether the limit disables limiting
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// Config holds the default limit per scope and per-principal overrides
+type Config struct {
+	Defaults  map[Scope]Limit
+	Overrides map[Scope]map[string]Limit // scope -> principal key -> limit
+
+	MaxConnections      int            // simultaneous WebSocket connections per user, 0 disables
+	ConnectionOverrides map[string]int // user ID -> max connections
+}
+
+// DefaultConfig returns default configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Defaults: map[Scope]Limit{
+			ScopeUser:       {Rate: DefaultUserRate, Burst: DefaultUserBurst},
+			ScopeAPIKey:     {Rate: DefaultAPIKeyRate, Burst: DefaultAPIKeyBurst},
+			ScopeTopic:      {Rate: DefaultTopicRate, Burst: DefaultTopicBurst},
+			ScopeConnection: {Rate: DefaultConnectionRate, Burst: DefaultConnectionBurst},
+		},
+		Overrides: make(map[Scope]map[string]Limit),
+
+		MaxConnections:      DefaultMaxConnections,
+		ConnectionOverrides: make(map[string]int),
+	}
+}
+
+// EffectiveLimit is the limit currently applied to one principal
+type EffectiveLimit struct {
+	Scope     Scope   `json:"scope"`
+	Key       string  `json:"key"`
+	Rate      float64 `json:"rate"`
+	Burst     int     `json:"burst"`
+	Available float64 `json:"available"` // tokens currently in the bucket
+	Override  bool    `json:"override"`  // true when a per-principal override applies
+	Unlimited bool    `json:"unlimited"`
+}
+
+// ConnectionLimit is the connection cap applied to one user
+type ConnectionLimit struct {
+	UserID    string `json:"user_id"`
+	Max       int    `json:"max"`
+	Active    int    `json:"active"`   // connections currently open
+	Override  bool   `json:"override"` // true when a per-user override applies
+	Unlimited bool   `json:"unlimited"`
+}
+
+// REST API Models
+type EffectiveLimitsResponse struct {
+	Limits      []EffectiveLimit `json:"limits"`
+	Connections *ConnectionLimit `json:"connections,omitempty"`
+}
@@ -0,0 +1,306 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"golang.org/x/time/rate"
+)
+
+// Service interface for rate limit operations
+type Service interface {
+	// Allow consumes one token for the principal and reports whether the
+	// request may proceed; when it may not, retryAfter estimates the wait
+	Allow(scope Scope, key string) (allowed bool, retryAfter time.Duration)
+	Effective(scope Scope, key string) EffectiveLimit
+	// NewLimiter returns a bucket of its own with the principal's limit, for
+	// limits applied per connection rather than per principal. It returns
+	// nil when the limit is disabled.
+	NewLimiter(scope Scope, key string) *rate.Limiter
+	// AcquireConnection counts a new WebSocket connection for the user if
+	// it stays within the user's cap, and reports the cap either way. Every
+	// acquired connection must be released with ReleaseConnection.
+	AcquireConnection(userID string) (ConnectionLimit, bool)
+	ReleaseConnection(userID string)
+	Connections(userID string) ConnectionLimit
+}
+type service struct {
+	config      *Config
+	limiters    map[Scope]map[string]*rate.Limiter // scope -> principal key -> bucket
+	connections map[string]int                     // user ID -> open WebSocket connections
+	mu          sync.Mutex
+}
+
+// NewService creates a new rate limit service. Buckets of users and topics
+// deleted on bus are dropped, so a recreated principal starts afresh; bus
+// may be nil.
+func NewService(config *Config, bus events.Bus) Service {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	s := &service{
+		config:      config,
+		limiters:    make(map[Scope]map[string]*rate.Limiter),
+		connections: make(map[string]int),
+	}
+
+	if bus != nil {
+		bus.Subscribe(events.KindUserDeleted, "limits", func(_ context.Context, event events.Event) {
+			s.forget(ScopeUser, event.Subject)
+		})
+		bus.Subscribe(events.KindTopicDeleted, "limits", func(_ context.Context, event events.Event) {
+			s.forget(ScopeTopic, event.Subject)
+		})
+	}
+
+	return s
+}
+
+// Allow consumes one token from the principal's bucket
+func (s *service) Allow(scope Scope, key string) (bool, time.Duration) {
+	limit, _ := s.limitFor(scope, key)
+	if limit.Unlimited() {
+		return true, 0
+	}
+
+	limiter := s.limiter(scope, key, limit)
+	if limiter.Allow() {
+		return true, 0
+	}
+
+	missing := 1 - limiter.Tokens()
+	return false, time.Duration(missing / limit.Rate * float64(time.Second))
+}
+
+// Effective returns the limit and remaining tokens for a principal
+func (s *service) Effective(scope Scope, key string) EffectiveLimit {
+	limit, override := s.limitFor(scope, key)
+
+	effective := EffectiveLimit{
+		Scope:     scope,
+		Key:       key,
+		Rate:      limit.Rate,
+		Burst:     limit.Burst,
+		Override:  override,
+		Unlimited: limit.Unlimited(),
+	}
+	if !limit.Unlimited() {
+		effective.Available = s.limiter(scope, key, limit).Tokens()
+	}
+
+	return effective
+}
+
+// NewLimiter creates an unshared bucket with the principal's limit
+func (s *service) NewLimiter(scope Scope, key string) *rate.Limiter {
+	limit, _ := s.limitFor(scope, key)
+	if limit.Unlimited() {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+}
+
+// AcquireConnection counts a connection unless the user is at their cap
+func (s *service) AcquireConnection(userID string) (ConnectionLimit, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := s.connectionLimit(userID)
+	if !limit.Unlimited && limit.Active >= limit.Max {
+		return limit, false
+	}
+
+	s.connections[userID]++
+	limit.Active++
+	return limit, true
+}
+
+// ReleaseConnection uncounts a connection counted by AcquireConnection
+func (s *service) ReleaseConnection(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.connections[userID] <= 1 {
+		delete(s.connections, userID)
+		return
+	}
+	s.connections[userID]--
+}
+
+// Connections returns the user's connection cap and open connections
+func (s *service) Connections(userID string) ConnectionLimit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connectionLimit(userID)
+}
+
+// connectionLimit resolves a user's connection cap. Caller must hold s.mu.
+func (s *service) connectionLimit(userID string) ConnectionLimit {
+	max, override := s.config.ConnectionOverrides[userID]
+	if !override {
+		max = s.config.MaxConnections
+	}
+
+	return ConnectionLimit{
+		UserID:    userID,
+		Max:       max,
+		Active:    s.connections[userID],
+		Override:  override,
+		Unlimited: max <= 0,
+	}
+}
+
+// limitFor resolves the override or default limit of a principal
+func (s *service) limitFor(scope Scope, key string) (Limit, bool) {
+	if limit, exists := s.config.Overrides[scope][key]; exists {
+		return limit, true
+	}
+	return s.config.Defaults[scope], false
+}
+
+// limiter returns the principal's bucket, creating it full on first use
+func (s *service) limiter(scope Scope, key string, limit Limit) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byKey, exists := s.limiters[scope]
+	if !exists {
+		byKey = make(map[string]*rate.Limiter)
+		s.limiters[scope] = byKey
+	}
+
+	limiter, exists := byKey[key]
+	if !exists {
+		limiter = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		byKey[key] = limiter
+	}
+
+	return limiter
+}
+
+// forget drops the principal's bucket
+func (s *service) forget(scope Scope, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.limiters[scope], key)
+}
+
+// LoadConfig loads limits from environment variables:
+//
+//	RATE_LIMIT_{USER,API_KEY,TOPIC,CONNECTION}_RATE   sustained tokens per second (0 disables)
+//	RATE_LIMIT_{USER,API_KEY,TOPIC,CONNECTION}_BURST  bucket size
+//	RATE_LIMIT_OVERRIDES                              comma-separated scope:key=rate/burst,
+//	                                                  e.g. "user:abc=10/20,topic:orders=500/1000"
+//	MAX_CONNECTIONS_PER_USER                          simultaneous WebSocket connections (0 disables)
+//	MAX_CONNECTIONS_OVERRIDES                         comma-separated user=max, e.g. "abc=50,def=0"
+func LoadConfig() (*Config, error) {
+	config := DefaultConfig()
+
+	for scope, prefix := range map[Scope]string{
+		ScopeUser:       "RATE_LIMIT_USER",
+		ScopeAPIKey:     "RATE_LIMIT_API_KEY",
+		ScopeTopic:      "RATE_LIMIT_TOPIC",
+		ScopeConnection: "RATE_LIMIT_CONNECTION",
+	} {
+		limit := config.Defaults[scope]
+
+		if value, ok := os.LookupEnv(prefix + "_RATE"); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s_RATE: %w", prefix, err)
+			}
+			limit.Rate = parsed
+		}
+		if value, ok := os.LookupEnv(prefix + "_BURST"); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s_BURST: %w", prefix, err)
+			}
+			limit.Burst = parsed
+		}
+
+		config.Defaults[scope] = limit
+	}
+
+	overrides := os.Getenv("RATE_LIMIT_OVERRIDES")
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		scope, key, limit, err := parseOverride(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		if config.Overrides[scope] == nil {
+			config.Overrides[scope] = make(map[string]Limit)
+		}
+		config.Overrides[scope][key] = limit
+	}
+
+	if value, ok := os.LookupEnv("MAX_CONNECTIONS_PER_USER"); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_CONNECTIONS_PER_USER: %w", err)
+		}
+		config.MaxConnections = parsed
+	}
+
+	for _, entry := range strings.Split(os.Getenv("MAX_CONNECTIONS_OVERRIDES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		userID, value, found := strings.Cut(entry, "=")
+		if !found || userID == "" {
+			return nil, fmt.Errorf("invalid connection override %q: expected user=max", entry)
+		}
+		max, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connection override %q: %w", entry, err)
+		}
+		config.ConnectionOverrides[userID] = max
+	}
+
+	return config, nil
+}
+
+// parseOverride parses one "scope:key=rate/burst" override
+func parseOverride(entry string) (Scope, string, Limit, error) {
+	target, value, found := strings.Cut(entry, "=")
+	if !found {
+		return "", "", Limit{}, fmt.Errorf("invalid rate limit override %q: missing '='", entry)
+	}
+
+	scopeStr, key, found := strings.Cut(target, ":")
+	scope := Scope(scopeStr)
+	if !found || key == "" || (scope != ScopeUser && scope != ScopeAPIKey && scope != ScopeTopic && scope != ScopeConnection) {
+		return "", "", Limit{}, fmt.Errorf("invalid rate limit override %q: expected user:, api_key:, topic: or connection: prefix", entry)
+	}
+
+	rateStr, burstStr, found := strings.Cut(value, "/")
+	if !found {
+		return "", "", Limit{}, fmt.Errorf("invalid rate limit override %q: expected rate/burst", entry)
+	}
+
+	limitRate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil {
+		return "", "", Limit{}, fmt.Errorf("invalid rate limit override %q: %w", entry, err)
+	}
+	burst, err := strconv.Atoi(burstStr)
+	if err != nil {
+		return "", "", Limit{}, fmt.Errorf("invalid rate limit override %q: %w", entry, err)
+	}
+
+	return scope, key, Limit{Rate: limitRate, Burst: burst}, nil
+}
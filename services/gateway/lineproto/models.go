@@ -0,0 +1,68 @@
+package lineproto
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+)
+
+// Listener defaults
+const (
+	// DefaultMaxLineBytes bounds one line, the token included
+	DefaultMaxLineBytes = 64 << 10
+	// DefaultIdleTimeout closes connections that send nothing for this long
+	DefaultIdleTimeout = 5 * time.Minute
+	// AuthTimeout bounds the wait for the token line after connecting
+	AuthTimeout = 10 * time.Second
+	// writeTimeout bounds writing one reply
+	writeTimeout = 5 * time.Second
+)
+
+// Replies sent for every line, followed by a newline. Errors are
+// "ERR <code> <message>", with the codes of WebSocket error frames.
+const (
+	replyOK  = "OK"
+	replyErr = "ERR"
+)
+
+// Config configures the TCP line-protocol listener
+type Config struct {
+	Addr         string // address to listen on, e.g. ":7000"; empty disables the listener
+	MaxLineBytes int
+	IdleTimeout  time.Duration // zero never closes idle connections
+}
+
+// DefaultConfig returns the default configuration, with the listener
+// disabled
+func DefaultConfig() *Config {
+	return &Config{
+		MaxLineBytes: DefaultMaxLineBytes,
+		IdleTimeout:  DefaultIdleTimeout,
+	}
+}
+
+// PublishLine is a JSON line, the publish frame of the WebSocket protocol
+// without its type. A message without an ID is given one.
+type PublishLine struct {
+	Topic   string          `json:"topic"`
+	Message *pubsub.Message `json:"message"`
+}
+
+// textPayload returns the payload of a text line: the value itself when
+// it is JSON, such as a number, otherwise the text as a string
+func textPayload(text string) interface{} {
+	if !json.Valid([]byte(text)) {
+		return text
+	}
+
+	// Numbers stay exact, as in publish frames
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return text
+	}
+	return value
+}
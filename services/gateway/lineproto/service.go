@@ -0,0 +1,318 @@
+package lineproto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/libraries/auth"
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/apikey"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/exchange"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/limits"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/websocket"
+	"github.com/ammysap/plivo-pub-sub/usercontext"
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+// serviceName identifies this listener in the request context
+const serviceName = "gateway-tcp"
+
+// Service accepts publish-only TCP connections from producers that cannot
+// speak WebSocket or HTTP comfortably, such as legacy appliances. A
+// connection sends its token (a user JWT or an API key) on the first line,
+// then one message per line: a JSON PublishLine, or "<topic> <payload>".
+// Every line is answered with "OK" or "ERR <code> <message>".
+type Service interface {
+	// Start listens on Config.Addr and serves connections in the background
+	// until ctx is done
+	Start(ctx context.Context) error
+}
+
+type service struct {
+	config        Config
+	pubsubService pubsub.Service
+	keys          apikey.Service
+	users         user.Service
+	limiter       limits.Service
+	wg            sync.WaitGroup
+}
+
+// LoadConfig reads listener settings from the environment:
+//
+//	TCP_LISTEN_ADDR     address of the line-protocol listener, e.g. ":7000";
+//	                    unset disables it
+//	TCP_MAX_LINE_BYTES  maximum size of one line; defaults to 65536
+//	TCP_IDLE_TIMEOUT    how long a silent connection is kept, e.g. "5m";
+//	                    0 keeps it forever
+func LoadConfig() (*Config, error) {
+	config := DefaultConfig()
+	config.Addr = os.Getenv("TCP_LISTEN_ADDR")
+
+	if value := os.Getenv("TCP_MAX_LINE_BYTES"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid TCP_MAX_LINE_BYTES %q: expected a positive integer", value)
+		}
+		config.MaxLineBytes = parsed
+	}
+
+	if value := os.Getenv("TCP_IDLE_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid TCP_IDLE_TIMEOUT %q: expected a non-negative duration such as 5m", value)
+		}
+		config.IdleTimeout = parsed
+	}
+
+	return config, nil
+}
+
+// NewService creates a new line-protocol listener. Tokens are checked
+// against keys and users like on /ws; limiter may be nil.
+func NewService(config *Config, keys apikey.Service, users user.Service, limiter limits.Service) Service {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &service{
+		config:        *config,
+		pubsubService: pubsub.GetService(),
+		keys:          keys,
+		users:         users,
+		limiter:       limiter,
+	}
+}
+
+// Start opens the listener, so a bad address fails startup
+func (s *service) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on TCP_LISTEN_ADDR %s: %w", s.config.Addr, err)
+	}
+
+	logging.WithContext(ctx).Infow("Line-protocol listener started", "addr", listener.Addr().String())
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	go s.serve(ctx, listener)
+	return nil
+}
+
+// serve accepts connections until the listener is closed
+func (s *service) serve(ctx context.Context, listener net.Listener) {
+	log := logging.WithContext(ctx)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				s.wg.Wait()
+				return
+			}
+			log.Warnw("Failed to accept line-protocol connection", "error", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+// handle authenticates a connection and publishes its lines until it
+// closes, goes idle or sends a line that is too long
+func (s *service) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	log := logging.WithContext(ctx)
+
+	// Closing the connection ends the read it may be blocked in
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, min(4096, s.config.MaxLineBytes)), s.config.MaxLineBytes)
+	writer := bufio.NewWriter(conn)
+
+	conn.SetReadDeadline(time.Now().Add(AuthTimeout))
+	if !scanner.Scan() {
+		return
+	}
+	connCtx, userID, err := s.authenticate(ctx, strings.TrimSpace(scanner.Text()))
+	if err != nil {
+		log.Warnw("Line-protocol connection rejected", "remote_addr", conn.RemoteAddr().String(), "error", err)
+		s.reply(conn, writer, fmt.Sprintf("%s %s %s", replyErr, websocket.ErrorCodeUnauthorized, err.Error()))
+		return
+	}
+	if !s.reply(conn, writer, replyOK) {
+		return
+	}
+
+	log.Infow("Line-protocol connection opened", "user_id", userID, "remote_addr", conn.RemoteAddr().String())
+	defer log.Infow("Line-protocol connection closed", "user_id", userID, "remote_addr", conn.RemoteAddr().String())
+
+	var lines *rate.Limiter
+	if s.limiter != nil {
+		lines = s.limiter.NewLimiter(limits.ScopeConnection, userID)
+	}
+
+	for {
+		if s.config.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.config.IdleTimeout))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+		if !scanner.Scan() {
+			if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+				s.reply(conn, writer, fmt.Sprintf("%s %s line exceeds %d bytes", replyErr, websocket.ErrorCodeBadRequest, s.config.MaxLineBytes))
+			}
+			return
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		reply := replyOK
+		if lines != nil && !lines.Allow() {
+			reply = fmt.Sprintf("%s %s connection rate limit exceeded", replyErr, websocket.ErrorCodeRateLimited)
+		} else if code, err := s.publish(connCtx, line); err != nil {
+			reply = fmt.Sprintf("%s %s %s", replyErr, code, err.Error())
+		}
+		if !s.reply(conn, writer, reply) {
+			return
+		}
+	}
+}
+
+// authenticate verifies a user JWT or an API key, returning the context
+// publishes run in. API keys carry their grant, so a subscribe-only key
+// cannot publish.
+func (s *service) authenticate(ctx context.Context, token string) (context.Context, string, error) {
+	if token == "" {
+		return nil, "", fmt.Errorf("expected a token on the first line")
+	}
+
+	var userID string
+	if strings.HasPrefix(token, apikey.KeyPrefix) {
+		key, err := s.keys.Authenticate(token)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid api key")
+		}
+		userID = key.Owner
+		ctx = pubsub.WithGrant(ctx, key.Grant())
+	} else {
+		claims, err := auth.Verify(token)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid token")
+		}
+		userID = claims.Subject
+		ctx = auth.WithClaims(ctx, claims)
+		if grant := exchange.GrantFromClaims(claims); grant != nil {
+			ctx = pubsub.WithGrant(ctx, grant)
+		}
+	}
+
+	if s.users != nil {
+		if err := s.users.RecordActivity(userID); err != nil {
+			return nil, "", fmt.Errorf("account disabled")
+		}
+	}
+
+	ctx = usercontext.CreateSHContextFromUserContext(
+		ctx,
+		&usercontext.User{ID: userID},
+		&usercontext.Service{ServiceName: serviceName},
+	)
+	return ctx, userID, nil
+}
+
+// publish publishes one line, returning the error code and error of a
+// rejected one
+func (s *service) publish(ctx context.Context, line []byte) (string, error) {
+	var topicName string
+	var message pubsub.Message
+
+	if line[0] == '{' {
+		// Numbers stay exact, as in publish frames
+		var parsed PublishLine
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&parsed); err != nil {
+			return websocket.ErrorCodeBadRequest, fmt.Errorf("invalid line: %v", err)
+		}
+		if parsed.Topic == "" || parsed.Message == nil {
+			return websocket.ErrorCodeBadRequest, fmt.Errorf("topic and message are required")
+		}
+		topicName, message = parsed.Topic, *parsed.Message
+	} else {
+		name, payload, _ := strings.Cut(string(line), " ")
+		topicName = name
+		message.Payload = textPayload(strings.TrimSpace(payload))
+	}
+	if message.ID == "" {
+		message.ID = uuid.NewString()
+	}
+
+	// Topics under $SYS/ carry messages from the gateway itself
+	if strings.HasPrefix(topicName, websocket.SystemTopicPrefix) {
+		return websocket.ErrorCodeBadRequest, fmt.Errorf("topic %s is reserved for the gateway", topicName)
+	}
+
+	if s.limiter != nil {
+		if allowed, _ := s.limiter.Allow(limits.ScopeTopic, topicName); !allowed {
+			return websocket.ErrorCodeRateLimited, fmt.Errorf("%s rate limit exceeded", limits.ScopeTopic)
+		}
+	}
+
+	err := s.pubsubService.Publish(ctx, topicName, message)
+	switch {
+	case err == nil:
+		return "", nil
+	case err.Error() == fmt.Sprintf("topic %s not found", topicName):
+		return websocket.ErrorCodeTopicNotFound, err
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		return websocket.ErrorCodeForbidden, err
+	case strings.HasPrefix(err.Error(), "busy"):
+		return websocket.ErrorCodeBusy, err
+	case strings.HasPrefix(err.Error(), "invalid payload") ||
+		strings.HasPrefix(err.Error(), "invalid schema_version") ||
+		strings.HasPrefix(err.Error(), "invalid priority"):
+		return websocket.ErrorCodeBadRequest, err
+	default:
+		return websocket.ErrorCodeInternal, err
+	}
+}
+
+// reply writes one reply line, reporting whether the connection is still
+// usable
+func (s *service) reply(conn net.Conn, writer *bufio.Writer, reply string) bool {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	writer.WriteString(strings.ReplaceAll(reply, "\n", " "))
+	writer.WriteByte('\n')
+	return writer.Flush() == nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -12,9 +14,17 @@ import (
 	"github.com/ammysap/plivo-pub-sub/logging"
 	"github.com/ammysap/plivo-pub-sub/pubsub"
 	"github.com/ammysap/plivo-pub-sub/services/gateway/app"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/demo"
 )
 
+// closeDrainDelay is how long to wait after stopping pubsub so WebSocket
+// clients receive their subscription_closed events before the process exits
+const closeDrainDelay = 500 * time.Millisecond
+
 func main() {
+	demoMode := flag.Bool("demo", false, "start with an ephemeral secret, demo users and topics, and generated traffic")
+	flag.Parse()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -22,12 +32,80 @@ func main() {
 
 	logger.Info("Starting PubSub Gateway Service...")
 
+	// Demo mode signs tokens with a secret of its own, so it needs no
+	// configuration and its tokens are worthless once it exits
+	if *demoMode {
+		secret, err := demo.EphemeralSecret()
+		if err != nil {
+			log.Fatalf("cannot start demo mode: %v", err)
+		}
+		os.Setenv("JWT_SECRET_KEY", secret)
+	}
+
 	// Initialize auth
-	auth.InitAuth(auth.AuthTypeHMAC)
+	auth.InitAuth(auth.AuthTypeFromEnv())
 
 	// Initialize PubSub service (singleton)
 	logger.Info("Initializing PubSub service...")
-	pubsubService := pubsub.InitService(pubsub.DefaultConfig())
+	pubsubConfig := pubsub.DefaultConfig()
+	if value := os.Getenv("MAX_REPLAY_RATE"); value != "" {
+		replayRate, err := strconv.ParseFloat(value, 64)
+		if err != nil || replayRate < 0 {
+			log.Fatalf("invalid MAX_REPLAY_RATE %q", value)
+		}
+		pubsubConfig.MaxReplayRate = replayRate
+	}
+	if value := os.Getenv("MAX_GOROUTINES"); value != "" {
+		maxGoroutines, err := strconv.Atoi(value)
+		if err != nil || maxGoroutines < 0 {
+			log.Fatalf("invalid MAX_GOROUTINES %q", value)
+		}
+		pubsubConfig.MaxGoroutines = maxGoroutines
+	}
+	if value := os.Getenv("TASK_QUEUE_SIZE"); value != "" {
+		queueSize, err := strconv.Atoi(value)
+		if err != nil || queueSize < 0 {
+			log.Fatalf("invalid TASK_QUEUE_SIZE %q", value)
+		}
+		pubsubConfig.TaskQueueSize = queueSize
+	}
+	if value := os.Getenv("MAX_TOPICS"); value != "" {
+		maxTopics, err := strconv.Atoi(value)
+		if err != nil || maxTopics < 0 {
+			log.Fatalf("invalid MAX_TOPICS %q", value)
+		}
+		pubsubConfig.MaxTopics = maxTopics
+	}
+	if value := os.Getenv("MAX_TOPICS_PER_USER"); value != "" {
+		maxTopics, err := strconv.Atoi(value)
+		if err != nil || maxTopics < 0 {
+			log.Fatalf("invalid MAX_TOPICS_PER_USER %q", value)
+		}
+		pubsubConfig.MaxTopicsPerOwner = maxTopics
+	}
+	pubsubConfig.LockFreeBuffer = os.Getenv("RING_BUFFER_LOCK_FREE") == "true"
+	pubsubConfig.Region = os.Getenv("REGION")
+	pubsubConfig.DataDir = os.Getenv("DATA_DIR")
+	pubsubConfig.SnapshotFile = os.Getenv("SNAPSHOT_FILE")
+	if value := os.Getenv("SNAPSHOT_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			log.Fatalf("invalid SNAPSHOT_INTERVAL %q", value)
+		}
+		pubsubConfig.SnapshotInterval = interval
+	}
+	pubsubConfig.WALDir = os.Getenv("WAL_DIR")
+	if value := os.Getenv("WAL_SEGMENT_SIZE"); value != "" {
+		segmentSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || segmentSize < 0 {
+			log.Fatalf("invalid WAL_SEGMENT_SIZE %q", value)
+		}
+		pubsubConfig.WALSegmentSize = segmentSize
+	}
+	pubsubConfig.WALSync = os.Getenv("WAL_SYNC") == "true"
+	pubsubConfig.Backend = os.Getenv("PUBSUB_BACKEND")
+	pubsubConfig.RedisURL = os.Getenv("REDIS_URL")
+	pubsubService := pubsub.InitService(pubsubConfig)
 
 	// Start the service
 	logger.Info("Starting PubSub service...")
@@ -45,7 +123,7 @@ func main() {
 	serverDone := make(chan error, 1)
 	go func() {
 		logger.Info("Starting HTTP server...")
-		err := app.RegisterRoutes(ctx, nil)
+		err := app.RegisterRoutes(ctx, nil, *demoMode)
 		serverDone <- err
 	}()
 
@@ -67,6 +145,10 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
+	// Stop the gateway services pushing to and reading from pubsub first
+	logger.Info("Stopping gateway services...")
+	app.Shutdown()
+
 	// Stop PubSub service
 	logger.Info("Stopping PubSub service...")
 	if err := pubsubService.Stop(shutdownCtx); err != nil {
@@ -75,5 +157,10 @@ func main() {
 		logger.Info("PubSub service stopped successfully")
 	}
 
+	select {
+	case <-time.After(closeDrainDelay):
+	case <-shutdownCtx.Done():
+	}
+
 	logger.Info("Graceful shutdown completed")
 }
@@ -0,0 +1,100 @@
+package maintenance
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/admin"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for maintenance endpoints
+type Endpoint interface {
+	ScheduleWindow(c *gin.Context)
+	ListWindows(c *gin.Context)
+	CancelWindow(c *gin.Context)
+}
+
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// ScheduleWindow handles POST /admin/maintenance
+func (e *endpoint) ScheduleWindow(c *gin.Context) {
+	ctx, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Error binding JSON", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window, err := e.service.Schedule(ctx, admin.ActorFromContext(c).Name, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "maintenance window overlaps") {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error scheduling maintenance window", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule maintenance window"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, WindowResponse{Window: window})
+}
+
+// ListWindows handles GET /admin/maintenance
+func (e *endpoint) ListWindows(c *gin.Context) {
+	windows := e.service.Windows()
+	c.JSON(http.StatusOK, ListWindowsResponse{
+		Windows:  windows,
+		Count:    len(windows),
+		Draining: e.service.Active() != nil,
+	})
+}
+
+// CancelWindow handles DELETE /admin/maintenance/{id}
+func (e *endpoint) CancelWindow(c *gin.Context) {
+	ctx, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	window, err := e.service.Cancel(ctx, admin.ActorFromContext(c).Name, id)
+	if err != nil {
+		if err.Error() == "maintenance window "+id+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error cancelling maintenance window", "error", err.Error(), "window_id", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel maintenance window"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WindowResponse{Window: window})
+}
@@ -0,0 +1,93 @@
+package maintenance
+
+import "time"
+
+// Maintenance parameters
+const (
+	// Topic receives a notice when a window is scheduled, ahead of its start,
+	// and when it starts, ends or is cancelled. Clients cannot publish to
+	// topics under $SYS/.
+	Topic = "$SYS/info"
+	// MaxDuration is the longest a window may last
+	MaxDuration = 24 * time.Hour
+	// HistorySize is how many ended and cancelled windows are kept
+	HistorySize = 100
+	// tick is how often windows are checked for notices, starts and ends
+	tick = time.Second
+	// noticeGrace is how long clients have to receive the notice that a
+	// window started before their connections are closed
+	noticeGrace = time.Second
+)
+
+// DefaultNotices are how long before a window starts clients are reminded
+// of it
+var DefaultNotices = []time.Duration{15 * time.Minute, 5 * time.Minute, time.Minute}
+
+// State is where a window is in its lifecycle
+type State string
+
+const (
+	StateScheduled State = "scheduled"
+	StateActive    State = "active" // the gateway is draining
+	StateCompleted State = "completed"
+	StateCancelled State = "cancelled"
+)
+
+// Window is a period during which the gateway drains: it refuses new
+// WebSocket connections, closes open ones and reports itself not ready
+type Window struct {
+	ID          string     `json:"id"`
+	Start       time.Time  `json:"start"`
+	End         time.Time  `json:"end"`
+	Reason      string     `json:"reason,omitempty"`
+	State       State      `json:"state"`
+	CreatedBy   string     `json:"created_by"` // name of the scheduling actor
+	CreatedAt   time.Time  `json:"created_at"`
+	CancelledBy string     `json:"cancelled_by,omitempty"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"` // when draining stopped, early if cancelled while active
+
+	noticed int // Config.Notices already sent, from the longest
+}
+
+// Config configures maintenance notices
+type Config struct {
+	// Notices are how long before a window starts clients are reminded of
+	// it, longest first
+	Notices []time.Duration
+}
+
+// Kinds of notices published to Topic
+const (
+	NoticeScheduled = "maintenance_scheduled"
+	NoticeUpcoming  = "maintenance_upcoming"
+	NoticeStarted   = "maintenance_started"
+	NoticeEnded     = "maintenance_ended"
+	NoticeCancelled = "maintenance_cancelled"
+)
+
+// Notice tells clients about a maintenance window
+type Notice struct {
+	Kind     string `json:"kind"`
+	Window   Window `json:"window"`
+	StartsIn string `json:"starts_in,omitempty"` // for upcoming notices, e.g. "5m0s"
+	Message  string `json:"message"`
+}
+
+// REST API Models
+
+// ScheduleRequest schedules a window. A zero Start starts it immediately.
+type ScheduleRequest struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end" binding:"required"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+type WindowResponse struct {
+	Window Window `json:"window"`
+}
+
+type ListWindowsResponse struct {
+	Windows  []Window `json:"windows"` // by start
+	Count    int      `json:"count"`
+	Draining bool     `json:"draining"`
+}
@@ -0,0 +1,37 @@
+package maintenance
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/admin"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	endpoint     Endpoint
+	authenticate gin.HandlerFunc
+}
+
+// NewRouteRegistrar creates a new route registrar. authenticate is the
+// admin middleware; the routes require the maintenance:manage scope.
+func NewRouteRegistrar(service Service, authenticate gin.HandlerFunc) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		endpoint:     NewEndpoint(service),
+		authenticate: authenticate,
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	// admin routes authenticate separately, see RegisterUnAuthRoutes
+}
+
+// RegisterUnAuthRoutes registers the /admin/maintenance routes behind the
+// admin middleware rather than user authentication
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	maintenanceGroup := unAuthGroup.Group("/admin/maintenance", r.authenticate, admin.RequireScope(admin.ScopeMaintenance))
+
+	maintenanceGroup.POST("", r.endpoint.ScheduleWindow)
+	maintenanceGroup.GET("", r.endpoint.ListWindows)
+	maintenanceGroup.DELETE("/:id", r.endpoint.CancelWindow)
+}
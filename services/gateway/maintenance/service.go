@@ -0,0 +1,369 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/google/uuid"
+)
+
+// Service interface for scheduling maintenance windows
+type Service interface {
+	// Schedule adds a window; the gateway drains automatically while it
+	// lasts and notifies clients on $SYS/info beforehand
+	Schedule(ctx context.Context, actor string, req ScheduleRequest) (Window, error)
+	// Windows returns the scheduled and active windows and the most recent
+	// ended ones, by start
+	Windows() []Window
+	// Cancel cancels a scheduled window, or ends an active one early
+	Cancel(ctx context.Context, actor, id string) (Window, error)
+	// Active returns the window the gateway is draining for, or nil
+	Active() *Window
+}
+
+// notice is a change to announce once the lock is released
+type notice struct {
+	Notice
+	event events.Kind // published on the bus as well, or ""
+}
+
+type service struct {
+	pubsubService pubsub.Service
+	bus           events.Bus
+	config        Config
+	windows       []*Window // by start
+	mu            sync.RWMutex
+}
+
+// LoadConfig reads maintenance settings from the environment:
+//
+//	MAINTENANCE_NOTICES  comma-separated durations before a window starts at
+//	                     which clients are reminded of it, e.g. "1h,10m";
+//	                     "none" sends no reminders. Defaults to 15m,5m,1m.
+func LoadConfig() (*Config, error) {
+	config := &Config{Notices: slices.Clone(DefaultNotices)}
+
+	value := strings.TrimSpace(os.Getenv("MAINTENANCE_NOTICES"))
+	switch value {
+	case "":
+		return config, nil
+	case "none":
+		config.Notices = nil
+		return config, nil
+	}
+
+	config.Notices = nil
+	for _, entry := range strings.Split(value, ",") {
+		lead, err := time.ParseDuration(strings.TrimSpace(entry))
+		if err != nil || lead <= 0 {
+			return nil, fmt.Errorf("invalid MAINTENANCE_NOTICES entry %q: expected a positive duration such as 15m", entry)
+		}
+		config.Notices = append(config.Notices, lead)
+	}
+	slices.Sort(config.Notices)
+	slices.Reverse(config.Notices)
+	config.Notices = slices.Compact(config.Notices)
+	return config, nil
+}
+
+// NewService creates a maintenance scheduler and starts watching its
+// windows. Drain mode is announced on bus, for the modules that refuse and
+// close connections while it lasts.
+func NewService(config *Config, bus events.Bus) Service {
+	s := &service{
+		pubsubService: pubsub.GetService(),
+		bus:           bus,
+		config:        *config,
+	}
+	go s.run()
+
+	return s
+}
+
+// Schedule validates and adds a window. Windows may not overlap.
+func (s *service) Schedule(ctx context.Context, actor string, req ScheduleRequest) (Window, error) {
+	now := time.Now()
+	start := req.Start
+	if start.IsZero() {
+		start = now
+	}
+	if start.Before(now.Add(-time.Minute)) {
+		return Window{}, fmt.Errorf("invalid maintenance window: start is in the past")
+	}
+	if start.Before(now) {
+		start = now
+	}
+	if !req.End.After(start) {
+		return Window{}, fmt.Errorf("invalid maintenance window: end must be after start")
+	}
+	if req.End.Sub(start) > MaxDuration {
+		return Window{}, fmt.Errorf("invalid maintenance window: a window may last at most %s", MaxDuration)
+	}
+
+	window := &Window{
+		ID:        uuid.NewString(),
+		Start:     start,
+		End:       req.End,
+		Reason:    strings.TrimSpace(req.Reason),
+		State:     StateScheduled,
+		CreatedBy: actor,
+		CreatedAt: now,
+	}
+	// Reminders whose time has passed are covered by the scheduled notice
+	for window.noticed < len(s.config.Notices) && !now.Before(start.Add(-s.config.Notices[window.noticed])) {
+		window.noticed++
+	}
+
+	s.mu.Lock()
+	for _, other := range s.windows {
+		if other.State != StateScheduled && other.State != StateActive {
+			continue
+		}
+		if window.Start.Before(other.End) && other.Start.Before(window.End) {
+			s.mu.Unlock()
+			return Window{}, fmt.Errorf("maintenance window overlaps window %s", other.ID)
+		}
+	}
+	s.windows = append(s.windows, window)
+	slices.SortStableFunc(s.windows, func(a, b *Window) int { return a.Start.Compare(b.Start) })
+	scheduled := *window
+	s.mu.Unlock()
+
+	logging.WithContext(ctx).Infow("Maintenance window scheduled", "window_id", scheduled.ID,
+		"start", scheduled.Start, "end", scheduled.End, "reason", scheduled.Reason, "actor", actor)
+
+	if start.After(now) {
+		s.announce(ctx, notice{Notice: Notice{
+			Kind:   NoticeScheduled,
+			Window: scheduled,
+			Message: fmt.Sprintf("Gateway maintenance is scheduled from %s to %s; connections will be closed and refused meanwhile",
+				stamp(scheduled.Start), stamp(scheduled.End)),
+		}})
+	} else {
+		// A window starting now drains without waiting for the next tick;
+		// its started notice is the only one sent. New connections are
+		// refused at once and open ones closed before returning.
+		s.step(ctx, now)
+
+		s.mu.RLock()
+		scheduled = *window
+		s.mu.RUnlock()
+	}
+	return scheduled, nil
+}
+
+// Windows returns the windows, by start
+func (s *service) Windows() []Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	windows := make([]Window, 0, len(s.windows))
+	for _, window := range s.windows {
+		windows = append(windows, *window)
+	}
+	return windows
+}
+
+// Cancel cancels a scheduled window, or ends an active one now, resuming
+// connections
+func (s *service) Cancel(ctx context.Context, actor, id string) (Window, error) {
+	s.mu.Lock()
+	index := slices.IndexFunc(s.windows, func(w *Window) bool { return w.ID == id })
+	if index < 0 {
+		s.mu.Unlock()
+		return Window{}, fmt.Errorf("maintenance window %s not found", id)
+	}
+	window := s.windows[index]
+	if window.State != StateScheduled && window.State != StateActive {
+		s.mu.Unlock()
+		return Window{}, fmt.Errorf("invalid maintenance window: window %s already %s", id, window.State)
+	}
+
+	wasActive := window.State == StateActive
+	now := time.Now()
+	window.State = StateCancelled
+	window.CancelledBy = actor
+	if wasActive {
+		window.EndedAt = &now
+	}
+	cancelled := *window
+	s.trim()
+	s.mu.Unlock()
+
+	logging.WithContext(ctx).Infow("Maintenance window cancelled", "window_id", id, "active", wasActive, "actor", actor)
+	if wasActive {
+		s.announce(ctx, ended(cancelled))
+	} else {
+		s.announce(ctx, notice{Notice: Notice{
+			Kind:    NoticeCancelled,
+			Window:  cancelled,
+			Message: fmt.Sprintf("Gateway maintenance scheduled for %s is cancelled", stamp(cancelled.Start)),
+		}})
+	}
+	return cancelled, nil
+}
+
+// Active returns the window the gateway is draining for, or nil
+func (s *service) Active() *Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, window := range s.windows {
+		if window.State == StateActive {
+			active := *window
+			return &active
+		}
+	}
+	return nil
+}
+
+// run checks the windows every tick
+func (s *service) run() {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.step(context.Background(), now)
+	}
+}
+
+// step sends the reminders that are due and starts and ends the windows
+// whose time has come
+func (s *service) step(ctx context.Context, now time.Time) {
+	var notices []notice
+
+	s.mu.Lock()
+	for _, window := range s.windows {
+		switch window.State {
+		case StateScheduled:
+			// Only the latest of several reminders due at once is sent
+			due := -1
+			for window.noticed < len(s.config.Notices) && !now.Before(window.Start.Add(-s.config.Notices[window.noticed])) {
+				due = window.noticed
+				window.noticed++
+			}
+			if now.Before(window.Start) {
+				if due >= 0 {
+					lead := s.config.Notices[due]
+					notices = append(notices, notice{Notice: Notice{
+						Kind:     NoticeUpcoming,
+						Window:   *window,
+						StartsIn: lead.String(),
+						Message: fmt.Sprintf("Gateway maintenance starts in %s; connections will be closed and refused until %s",
+							lead, stamp(window.End)),
+					}})
+				}
+				continue
+			}
+
+			window.State = StateActive
+			notices = append(notices, notice{
+				Notice: Notice{
+					Kind:    NoticeStarted,
+					Window:  *window,
+					Message: fmt.Sprintf("Gateway maintenance has started; connections are refused until %s", stamp(window.End)),
+				},
+				event: events.KindMaintenanceStarted,
+			})
+		case StateActive:
+			if now.Before(window.End) {
+				continue
+			}
+			window.State = StateCompleted
+			endedAt := now
+			window.EndedAt = &endedAt
+			notices = append(notices, ended(*window))
+		}
+	}
+	if len(notices) > 0 {
+		s.trim()
+	}
+	s.mu.Unlock()
+
+	for _, n := range notices {
+		s.announce(ctx, n)
+	}
+}
+
+// ended returns the notice that draining for a window has stopped
+func ended(window Window) notice {
+	return notice{
+		Notice: Notice{
+			Kind:    NoticeEnded,
+			Window:  window,
+			Message: "Gateway maintenance has ended; connections are accepted again",
+		},
+		event: events.KindMaintenanceEnded,
+	}
+}
+
+// trim drops the oldest ended and cancelled windows beyond HistorySize.
+// Caller must hold s.mu.
+func (s *service) trim() {
+	finished := 0
+	for _, window := range s.windows {
+		if window.State == StateCompleted || window.State == StateCancelled {
+			finished++
+		}
+	}
+	s.windows = slices.DeleteFunc(s.windows, func(w *Window) bool {
+		if finished > HistorySize && (w.State == StateCompleted || w.State == StateCancelled) {
+			finished--
+			return true
+		}
+		return false
+	})
+}
+
+// announce publishes a notice to $SYS/info and, for starts and ends, on the
+// bus. Clients are told a window started a moment before their connections
+// are closed, and the gateway resumes before they are told it ended.
+func (s *service) announce(ctx context.Context, n notice) {
+	log := logging.WithContext(ctx)
+	log.Infow("Maintenance "+n.Kind, "window_id", n.Window.ID, "start", n.Window.Start, "end", n.Window.End)
+
+	if n.event == events.KindMaintenanceEnded && s.bus != nil {
+		s.bus.Publish(ctx, events.Event{Kind: n.event, Subject: n.Window.ID})
+	}
+
+	if err := s.publish(ctx, n.Notice); err != nil {
+		log.Errorw("Failed to publish maintenance notice", "window_id", n.Window.ID, "kind", n.Kind, "error", err)
+	}
+
+	if n.event == events.KindMaintenanceStarted && s.bus != nil {
+		time.Sleep(noticeGrace)
+		s.bus.Publish(ctx, events.Event{Kind: n.event, Subject: n.Window.ID})
+	}
+}
+
+// publish publishes a notice to $SYS/info, creating the topic if it does
+// not exist
+func (s *service) publish(ctx context.Context, n Notice) error {
+	message := pubsub.Message{
+		ID:      uuid.NewString(),
+		Payload: n,
+		Headers: map[string]string{"kind": n.Kind, "window": n.Window.ID},
+	}
+
+	err := s.pubsubService.Publish(ctx, Topic, message)
+	if err != nil && err.Error() == fmt.Sprintf("topic %s not found", Topic) {
+		if err := s.pubsubService.CreateTopic(ctx, Topic, "", nil); err != nil {
+			logging.WithContext(ctx).Warnw("Failed to create maintenance topic", "topic", Topic, "error", err)
+		}
+		err = s.pubsubService.Publish(ctx, Topic, message)
+	}
+	return err
+}
+
+// stamp formats a time for notices
+func stamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
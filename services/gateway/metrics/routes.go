@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	service      Service
+	authenticate gin.HandlerFunc
+}
+
+// NewRouteRegistrar creates a new route registrar. authenticate guards
+// /metrics, whose user labels name individual users.
+func NewRouteRegistrar(service Service, authenticate gin.HandlerFunc) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		service:      service,
+		authenticate: authenticate,
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	// no auth routes
+}
+
+// RegisterUnAuthRoutes registers unauthenticated routes
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	// OpenMetrics is required for exemplars; Prometheus negotiates it
+	handler := promhttp.HandlerFor(r.service.Registry(), promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+	unAuthGroup.GET("/metrics", r.authenticate, gin.WrapH(handler))
+}
@@ -0,0 +1,273 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultMaxTrackedUsers bounds the user label's cardinality
+const DefaultMaxTrackedUsers = 100
+
+// Label values standing in for users that are not tracked individually
+const (
+	UserAnonymous = "anonymous"
+	UserOther     = "other"
+)
+
+// MaxTrackedOrigins bounds the origin label's cardinality
+const MaxTrackedOrigins = 50
+
+// Label values standing in for origins that are not tracked individually
+const (
+	OriginNone  = "none" // no Origin header, i.e. a non-browser client
+	OriginOther = "other"
+)
+
+// Outcomes of a WebSocket upgrade request
+const (
+	UpgradeAllowed        = "allowed"
+	UpgradeRejectedOrigin = "rejected_origin"
+	UpgradeRejectedCSRF   = "rejected_csrf"
+)
+
+// Transports a rejected request body or frame arrived on
+const (
+	TransportHTTP = "http"
+	TransportWS   = "ws"
+)
+
+// Reasons a request body or frame is rejected before parsing
+const (
+	RejectedBodyTooLarge     = "body_too_large"
+	RejectedJSONTooDeep      = "json_too_deep"
+	RejectedJSONArrayTooLong = "json_array_too_long"
+	RejectedEncoding         = "unsupported_encoding"
+)
+
+// Service interface for request and pubsub metrics
+type Service interface {
+	// ObserveRequest records one HTTP request. traceID, when set, is
+	// attached to the latency sample as an exemplar.
+	ObserveRequest(route, method string, status int, userID, traceID string, duration time.Duration)
+	// ObserveUpgrade records the outcome of one WebSocket upgrade request
+	// by its Origin header
+	ObserveUpgrade(origin, result string)
+	// ObserveRejection records one request body or WebSocket frame
+	// rejected by the body limits
+	ObserveRejection(transport, reason string)
+	Registry() *prometheus.Registry
+}
+type service struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	latency         *prometheus.HistogramVec
+	maxTrackedUsers int
+	trackedUsers    map[string]bool
+	mu              sync.Mutex
+
+	upgrades       *prometheus.CounterVec
+	trackedOrigins map[string]bool
+
+	events *prometheus.CounterVec
+
+	rejections *prometheus.CounterVec
+}
+
+// NewService creates a metrics service that exports HTTP request metrics and
+// the pubsub service's topic metrics, and counts the events published on
+// bus. Only the first maxTrackedUsers users get their own user label value;
+// later users are reported as "other".
+func NewService(pubsubService pubsub.Service, maxTrackedUsers int, bus events.Bus) Service {
+	if maxTrackedUsers <= 0 {
+		maxTrackedUsers = DefaultMaxTrackedUsers
+	}
+
+	s := &service{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_http_requests_total",
+			Help: "HTTP requests by route, method, status and user.",
+		}, []string{"route", "method", "status", "user"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gateway_http_request_duration_seconds",
+			Help:    "HTTP request latency by route, method, status and user.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status", "user"}),
+		maxTrackedUsers: maxTrackedUsers,
+		trackedUsers:    make(map[string]bool),
+		upgrades: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_ws_upgrades_total",
+			Help: "WebSocket upgrade requests by origin and result.",
+		}, []string{"origin", "result"}),
+		trackedOrigins: make(map[string]bool),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_events_total",
+			Help: "Internal gateway events by kind.",
+		}, []string{"kind"}),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_rejected_payloads_total",
+			Help: "Request bodies and WebSocket frames rejected by the body limits, by transport and reason.",
+		}, []string{"transport", "reason"}),
+	}
+
+	s.registry.MustRegister(s.requests, s.latency, s.upgrades, s.events, s.rejections, newPubsubCollector(pubsubService))
+
+	for _, kind := range events.Kinds {
+		bus.Subscribe(kind, "metrics", func(_ context.Context, event events.Event) {
+			s.events.WithLabelValues(string(event.Kind)).Inc()
+		})
+	}
+
+	return s
+}
+
+// ObserveRequest records one HTTP request
+func (s *service) ObserveRequest(route, method string, status int, userID, traceID string, duration time.Duration) {
+	labels := prometheus.Labels{
+		"route":  route,
+		"method": method,
+		"status": strconv.Itoa(status),
+		"user":   s.userLabel(userID),
+	}
+
+	s.requests.With(labels).Inc()
+
+	observer := s.latency.With(labels)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && traceID != "" {
+		exemplarObserver.ObserveWithExemplar(duration.Seconds(), prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	observer.Observe(duration.Seconds())
+}
+
+// ObserveUpgrade records one WebSocket upgrade request
+func (s *service) ObserveUpgrade(origin, result string) {
+	s.upgrades.With(prometheus.Labels{
+		"origin": s.originLabel(origin),
+		"result": result,
+	}).Inc()
+}
+
+// ObserveRejection records one rejected request body or frame
+func (s *service) ObserveRejection(transport, reason string) {
+	s.rejections.WithLabelValues(transport, reason).Inc()
+}
+
+// Registry returns the registry holding all gateway metrics
+func (s *service) Registry() *prometheus.Registry {
+	return s.registry
+}
+
+// userLabel maps a user ID to its label value, keeping at most
+// maxTrackedUsers distinct users
+func (s *service) userLabel(userID string) string {
+	if userID == "" {
+		return UserAnonymous
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.trackedUsers[userID] {
+		return userID
+	}
+	if len(s.trackedUsers) < s.maxTrackedUsers {
+		s.trackedUsers[userID] = true
+		return userID
+	}
+	return UserOther
+}
+
+// originLabel maps an Origin header to its label value, keeping at most
+// MaxTrackedOrigins distinct origins
+func (s *service) originLabel(origin string) string {
+	if origin == "" {
+		return OriginNone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.trackedOrigins[origin] {
+		return origin
+	}
+	if len(s.trackedOrigins) < MaxTrackedOrigins {
+		s.trackedOrigins[origin] = true
+		return origin
+	}
+	return OriginOther
+}
+
+// pubsubCollector exports per-topic pubsub metrics at scrape time
+type pubsubCollector struct {
+	pubsubService pubsub.Service
+	subscribers   *prometheus.Desc
+	messages      *prometheus.Desc
+	published     *prometheus.Desc
+	topics        *prometheus.Desc
+
+	taskBudget  *prometheus.Desc
+	taskRunning *prometheus.Desc
+	taskQueued  *prometheus.Desc
+	taskInline  *prometheus.Desc
+	taskShed    *prometheus.Desc
+}
+
+func newPubsubCollector(pubsubService pubsub.Service) *pubsubCollector {
+	return &pubsubCollector{
+		pubsubService: pubsubService,
+		topics:        prometheus.NewDesc("pubsub_topics", "Number of topics.", nil, nil),
+		subscribers:   prometheus.NewDesc("pubsub_topic_subscribers", "Subscribers per topic.", []string{"topic"}, nil),
+		messages:      prometheus.NewDesc("pubsub_topic_buffered_messages", "Messages held for replay per topic.", []string{"topic"}, nil),
+		published:     prometheus.NewDesc("pubsub_topic_published_last_minute", "Messages published per topic in the last minute.", []string{"topic"}, nil),
+
+		taskBudget:  prometheus.NewDesc("pubsub_goroutine_budget", "Goroutines the engine may use at once for its tasks.", nil, nil),
+		taskRunning: prometheus.NewDesc("pubsub_tasks_running", "Engine tasks running, by kind.", []string{"kind"}, nil),
+		taskQueued:  prometheus.NewDesc("pubsub_tasks_waiting", "Engine tasks waiting for a goroutine.", nil, nil),
+		taskInline:  prometheus.NewDesc("pubsub_tasks_inline_total", "Engine tasks run on their caller's goroutine because the budget was spent, by kind.", []string{"kind"}, nil),
+		taskShed:    prometheus.NewDesc("pubsub_tasks_shed_total", "Engine tasks dropped because the budget and the task queue were full, by kind.", []string{"kind"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *pubsubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.topics
+	ch <- c.subscribers
+	ch <- c.messages
+	ch <- c.published
+	ch <- c.taskBudget
+	ch <- c.taskRunning
+	ch <- c.taskQueued
+	ch <- c.taskInline
+	ch <- c.taskShed
+}
+
+// Collect implements prometheus.Collector
+func (c *pubsubCollector) Collect(ch chan<- prometheus.Metric) {
+	topics, err := c.pubsubService.ListTopics(context.Background(), "")
+	if err != nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.topics, prometheus.GaugeValue, float64(len(topics)))
+	for _, topic := range topics {
+		ch <- prometheus.MustNewConstMetric(c.subscribers, prometheus.GaugeValue, float64(topic.Subscribers), topic.Name)
+		ch <- prometheus.MustNewConstMetric(c.messages, prometheus.GaugeValue, float64(topic.Messages), topic.Name)
+		ch <- prometheus.MustNewConstMetric(c.published, prometheus.GaugeValue, float64(topic.Published1m), topic.Name)
+	}
+
+	tasks := c.pubsubService.TaskStats(context.Background())
+	ch <- prometheus.MustNewConstMetric(c.taskBudget, prometheus.GaugeValue, float64(tasks.Budget))
+	ch <- prometheus.MustNewConstMetric(c.taskQueued, prometheus.GaugeValue, float64(tasks.Queued))
+	for kind, stats := range tasks.Kinds {
+		ch <- prometheus.MustNewConstMetric(c.taskRunning, prometheus.GaugeValue, float64(stats.Running), string(kind))
+		ch <- prometheus.MustNewConstMetric(c.taskInline, prometheus.CounterValue, float64(stats.Inline), string(kind))
+		ch <- prometheus.MustNewConstMetric(c.taskShed, prometheus.CounterValue, float64(stats.Shed), string(kind))
+	}
+}
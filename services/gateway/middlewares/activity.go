@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
+	"github.com/gin-gonic/gin"
+)
+
+// ActiveUserMiddleware records each authenticated request as activity of
+// its user and rejects requests from accounts disabled for inactivity. It
+// must run after authentication; anonymous requests pass through.
+func ActiveUserMiddleware(users user.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.Next()
+			return
+		}
+
+		if err := users.RecordActivity(userID); err != nil {
+			logging.WithContext(c.Request.Context()).Warnw("Request from disabled account rejected", "user_id", userID)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -6,40 +6,156 @@ import (
 
 	"github.com/ammysap/plivo-pub-sub/libraries/auth"
 	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/apikey"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/exchange"
+	"github.com/ammysap/plivo-pub-sub/usercontext"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := c.Request.Context()
-		log := logging.WithContext(ctx)
+// BearerSubprotocol is the WebSocket subprotocol marker for browser clients,
+// which cannot set headers on the upgrade request. They offer the
+// subprotocols ["bearer", "<jwt>"] and the server selects "bearer".
+const BearerSubprotocol = "bearer"
+
+// serviceName identifies this service in the request context
+const serviceName = "gateway"
+
+// webSocketPath is the only route API keys and exchanged tokens can
+// authenticate
+const webSocketPath = "/ws"
 
-		authHeader := c.Request.Header["Authorization"]
-		if authHeader == nil {
+// AuthMiddleware authenticates the request with a user JWT or an API key
+// ("Bearer key_...")
+func AuthMiddleware(keys apikey.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := tokenFromRequest(c.Request)
+		if token == "" {
 			// no token present
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		authValue := authHeader[0]
-		if !strings.HasPrefix(authValue, "Bearer ") {
-			c.AbortWithStatus(http.StatusUnauthorized)
+		if !authenticate(c, keys, token) {
 			return
 		}
 
-		token := strings.TrimPrefix(authValue, "Bearer ")
+		c.Next()
+	}
+}
 
-		claims, err := auth.Verify(token)
-		if err != nil {
-			log.Errorw("Token verification failed", "error", err.Error())
-			c.AbortWithStatus(http.StatusUnauthorized)
+// OptionalAuthMiddleware authenticates the request when it carries a token
+// and lets anonymous requests through. An invalid token is still rejected.
+func OptionalAuthMiddleware(keys apikey.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := tokenFromRequest(c.Request)
+		if token == "" {
+			c.Next()
 			return
 		}
 
-		// Store the claims in context for later use
-		c.Set("claims", claims)
-		c.Set("user_id", claims.Subject)
+		if !authenticate(c, keys, token) {
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// authenticate verifies a user JWT or an API key and attaches the caller to
+// the request, aborting it when the token is rejected. API keys act as
+// their owner but may only open WebSocket connections, and carry their
+// grant in the request context so pubsub restricts the connection to the
+// key's direction and topics. Tokens from the token exchange are held to
+// the same route and carry the grant of their scope and audience.
+func authenticate(c *gin.Context, keys apikey.Service, token string) bool {
+	log := logging.WithContext(c.Request.Context())
+
+	if strings.HasPrefix(token, apikey.KeyPrefix) {
+		key, err := keys.Authenticate(token)
+		if err != nil {
+			log.Warnw("API key rejected", "error", err.Error())
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return false
+		}
+		if c.FullPath() != webSocketPath {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api keys can only open WebSocket connections"})
+			return false
+		}
+
+		setUser(c, key.Owner)
+		c.Request = c.Request.WithContext(pubsub.WithGrant(c.Request.Context(), key.Grant()))
+		c.Set("api_key_id", key.ID)
+		return true
+	}
+
+	claims, err := auth.Verify(token)
+	if err != nil {
+		log.Errorw("Token verification failed", "error", err.Error())
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return false
+	}
+
+	if grant := exchange.GrantFromClaims(claims); grant != nil {
+		if c.FullPath() != webSocketPath {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "exchanged tokens can only open WebSocket connections"})
+			return false
+		}
+		c.Request = c.Request.WithContext(pubsub.WithGrant(c.Request.Context(), grant))
+	}
+
+	setClaims(c, claims)
+	return true
+}
+
+// setClaims stores the verified claims on the gin and request contexts and
+// attaches the authenticated user to the request context. WebSocket
+// connections keep the request context, so their handlers read the claims
+// from it instead of verifying the token again.
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Request = c.Request.WithContext(auth.WithClaims(c.Request.Context(), claims))
+	setUser(c, claims.Subject)
+
+	// Store the claims in context for later use
+	c.Set("claims", claims)
+}
+
+// setUser attaches the authenticated user to the gin and request contexts
+func setUser(c *gin.Context, userID string) {
+	shctx := usercontext.CreateSHContextFromUserContext(
+		c.Request.Context(),
+		&usercontext.User{ID: userID},
+		&usercontext.Service{ServiceName: serviceName},
+	)
+	c.Request = c.Request.WithContext(shctx)
+
+	c.Set("shcontext", shctx)
+	c.Set("user_id", userID)
+}
+
+// tokenFromRequest extracts the bearer token from the Authorization header.
+// WebSocket upgrades may instead carry it as a subprotocol, or as the
+// legacy token query parameter.
+func tokenFromRequest(r *http.Request) string {
+	if authValue := r.Header.Get("Authorization"); authValue != "" {
+		token, found := strings.CutPrefix(authValue, "Bearer ")
+		if !found {
+			return ""
+		}
+		return token
+	}
+
+	if !websocket.IsWebSocketUpgrade(r) {
+		return ""
+	}
+
+	protocols := websocket.Subprotocols(r)
+	for i, protocol := range protocols {
+		if protocol == BearerSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+
+	return r.URL.Query().Get("token")
+}
@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Body limit defaults
+const (
+	DefaultMaxBodyBytes       = 1 << 20
+	DefaultMaxFrameBytes      = 1 << 20
+	DefaultMaxJSONDepth       = 32
+	DefaultMaxJSONArrayLength = 10000
+)
+
+// BodyLimits bounds what the gateway reads from a request before parsing
+// it, so a small request cannot expand into a huge allocation. A zero limit
+// disables it.
+type BodyLimits struct {
+	MaxBytes       int64            // request body size
+	RouteMaxBytes  map[string]int64 // "METHOD /route/:pattern" -> body size, overriding MaxBytes
+	MaxFrameBytes  int64            // WebSocket frame size
+	MaxDepth       int              // nesting of JSON objects and arrays
+	MaxArrayLength int              // elements in one JSON array
+}
+
+// DefaultBodyLimits returns the default limits
+func DefaultBodyLimits() *BodyLimits {
+	return &BodyLimits{
+		MaxBytes:       DefaultMaxBodyBytes,
+		RouteMaxBytes:  make(map[string]int64),
+		MaxFrameBytes:  DefaultMaxFrameBytes,
+		MaxDepth:       DefaultMaxJSONDepth,
+		MaxArrayLength: DefaultMaxJSONArrayLength,
+	}
+}
+
+// MaxBodyBytes returns the body size limit of a route
+func (l *BodyLimits) MaxBodyBytes(method, route string) int64 {
+	if limit, ok := l.RouteMaxBytes[method+" "+route]; ok {
+		return limit
+	}
+	return l.MaxBytes
+}
+
+// CheckJSON scans a JSON document against the depth and array length
+// limits, returning the metrics rejection reason with the error. Malformed
+// JSON passes, for the decoder to report.
+func (l *BodyLimits) CheckJSON(data []byte) (string, error) {
+	if l.MaxDepth <= 0 && l.MaxArrayLength <= 0 {
+		return "", nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	// lengths holds the element count of each open array, -1 for objects
+	var lengths []int
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", nil
+		}
+
+		if n := len(lengths); n > 0 && lengths[n-1] >= 0 && token != json.Delim(']') {
+			lengths[n-1]++
+			if l.MaxArrayLength > 0 && lengths[n-1] > l.MaxArrayLength {
+				return metrics.RejectedJSONArrayTooLong,
+					fmt.Errorf("invalid JSON: arrays are limited to %d elements", l.MaxArrayLength)
+			}
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			if l.MaxDepth > 0 && len(lengths) >= l.MaxDepth {
+				return metrics.RejectedJSONTooDeep,
+					fmt.Errorf("invalid JSON: nesting is limited to %d levels", l.MaxDepth)
+			}
+			if token == json.Delim('[') {
+				lengths = append(lengths, 0)
+			} else {
+				lengths = append(lengths, -1)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			lengths = lengths[:len(lengths)-1]
+		}
+	}
+}
+
+// BodyLimitMiddleware reads request bodies up to the route's size limit,
+// rejecting larger ones with 413, and JSON bodies over the depth or array
+// length limits with 400. Compressed bodies are rejected with 415, as the
+// gateway never decompresses requests. Every rejection is counted.
+func BodyLimitMiddleware(limits *BodyLimits, metricsService metrics.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody || c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+
+		reject := func(status int, reason, message string) {
+			logging.WithContext(c.Request.Context()).Warnw("Rejected request body",
+				"route", c.FullPath(), "reason", reason, "content_length", c.Request.ContentLength)
+			metricsService.ObserveRejection(metrics.TransportHTTP, reason)
+			c.AbortWithStatusJSON(status, gin.H{"error": message})
+		}
+
+		if encoding := c.GetHeader("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+			reject(http.StatusUnsupportedMediaType, metrics.RejectedEncoding,
+				fmt.Sprintf("unsupported content encoding %s: request bodies must not be compressed", encoding))
+			return
+		}
+
+		limit := limits.MaxBodyBytes(c.Request.Method, c.FullPath())
+		tooLarge := fmt.Sprintf("request body too large: at most %d bytes", limit)
+		if limit > 0 && c.Request.ContentLength > limit {
+			reject(http.StatusRequestEntityTooLarge, metrics.RejectedBodyTooLarge, tooLarge)
+			return
+		}
+
+		// Bodies without a Content-Length are read one byte past the limit
+		// to tell whether they exceed it
+		reader := io.Reader(c.Request.Body)
+		if limit > 0 {
+			reader = io.LimitReader(reader, limit+1)
+		}
+		data, err := io.ReadAll(reader)
+		c.Request.Body.Close()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		if limit > 0 && int64(len(data)) > limit {
+			reject(http.StatusRequestEntityTooLarge, metrics.RejectedBodyTooLarge, tooLarge)
+			return
+		}
+
+		// Handlers bind JSON whatever the Content-Type, so every body is
+		// scanned; anything else fails on its first token
+		if reason, err := limits.CheckJSON(data); err != nil {
+			reject(http.StatusBadRequest, reason, err.Error())
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/limits"
+	"github.com/gin-gonic/gin"
+)
+
+// ConnectionLimitMiddleware caps the simultaneous WebSocket connections of
+// each user. A connection counts from the upgrade request until its handler
+// returns, so it must wrap the WebSocket handler and run after
+// authentication. Anonymous connections are not counted.
+func ConnectionLimitMiddleware(limiter limits.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.Next()
+			return
+		}
+
+		limit, acquired := limiter.AcquireConnection(userID)
+		if !acquired {
+			logging.WithContext(c.Request.Context()).Warnw("Connection limit reached",
+				"user_id", userID, "max", limit.Max, "active", limit.Active)
+
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":  "too many connections",
+				"max":    limit.Max,
+				"active": limit.Active,
+			})
+			return
+		}
+		defer limiter.ReleaseConnection(userID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/maintenance"
+	"github.com/gin-gonic/gin"
+)
+
+// DrainMiddleware refuses WebSocket connections with 503 while the gateway
+// drains for a maintenance window, with Retry-After set to the window's
+// end. It must wrap the WebSocket handler.
+func DrainMiddleware(windows maintenance.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window := windows.Active()
+		if window == nil {
+			c.Next()
+			return
+		}
+
+		retryAfter := math.Ceil(time.Until(window.End).Seconds())
+		c.Header("Retry-After", strconv.Itoa(max(int(retryAfter), 1)))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":  "gateway is draining for maintenance",
+			"reason": window.Reason,
+			"until":  window.End,
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID used as the metrics exemplar when
+// the request has no trace context
+const RequestIDHeader = "X-Request-ID"
+
+// maxTraceIDLength bounds client-supplied trace and request IDs; Prometheus
+// refuses exemplar labels over 128 runes
+const maxTraceIDLength = 64
+
+// MetricsMiddleware records latency and status per route and user. The
+// route is the registered path pattern, so path parameters do not add
+// label values. Each latency sample carries the request's trace ID (from a
+// W3C traceparent header, or the request ID) as an exemplar.
+func MetricsMiddleware(metricsService metrics.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		traceID := traceIDFromRequest(c)
+		if traceID == "" {
+			traceID = uuid.NewString()
+			c.Request.Header.Set(RequestIDHeader, traceID)
+		}
+		c.Header(RequestIDHeader, traceID)
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metricsService.ObserveRequest(route, c.Request.Method, c.Writer.Status(),
+			c.GetString("user_id"), traceID, time.Since(start))
+	}
+}
+
+// traceIDFromRequest returns the trace ID from a W3C traceparent header
+// ("version-traceid-parentid-flags"), falling back to the request ID. IDs
+// that are not hex or UUIDs of at most maxTraceIDLength characters are
+// ignored, so one is generated instead.
+func traceIDFromRequest(c *gin.Context) string {
+	if parts := strings.Split(c.GetHeader("traceparent"), "-"); len(parts) == 4 && len(parts[1]) == 32 && validTraceID(parts[1]) {
+		return parts[1]
+	}
+	if requestID := c.GetHeader(RequestIDHeader); validTraceID(requestID) {
+		return requestID
+	}
+	return ""
+}
+
+// validTraceID reports whether id is hex digits and dashes, as trace IDs
+// and UUIDs are, and at most maxTraceIDLength long
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/limits"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware enforces the per-user and per-API-key token buckets
+// on authenticated routes. It must run after AuthMiddleware.
+func RateLimitMiddleware(limiter limits.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principals := []struct {
+			scope limits.Scope
+			key   string
+		}{
+			{limits.ScopeUser, c.GetString("user_id")},
+			{limits.ScopeAPIKey, c.GetString("api_key_id")},
+		}
+
+		for _, principal := range principals {
+			if principal.key == "" {
+				continue
+			}
+
+			allowed, retryAfter := limiter.Allow(principal.scope, principal.key)
+			if allowed {
+				continue
+			}
+
+			logging.WithContext(c.Request.Context()).Warnw("Rate limit exceeded",
+				"scope", principal.scope, "key", principal.key, "path", c.FullPath())
+
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":          "rate limit exceeded",
+				"scope":          principal.scope,
+				"retry_after_ms": retryAfter.Milliseconds(),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplicationAuthMiddleware authenticates pushes from peer regions, which
+// present the shared replication token as a bearer token. Every request is
+// rejected when no token is configured.
+func ReplicationAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := tokenFromRequest(c.Request)
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+)
+
+// Notification represents an outbound notification to one or more recipients
+type Notification struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications through an external channel
+type Notifier interface {
+	Notify(ctx context.Context, notification *Notification) error
+}
+
+// NewFromEnv returns an SMTP notifier when SMTP_HOST is set, otherwise a
+// notifier that only logs, which keeps local development dependency free
+func NewFromEnv() Notifier {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return NewLogNotifier()
+	}
+
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil || port == 0 {
+		port = DefaultSMTPPort
+	}
+
+	return NewSMTPNotifier(&SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	})
+}
+
+// logNotifier writes notifications to the log instead of sending them
+type logNotifier struct{}
+
+// NewLogNotifier creates a notifier that logs notifications
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+// Notify logs the notification
+func (n *logNotifier) Notify(ctx context.Context, notification *Notification) error {
+	log := logging.WithContext(ctx)
+	log.Infow("Notification (log only, SMTP not configured)",
+		"to", notification.To, "subject", notification.Subject, "body", notification.Body)
+	return nil
+}
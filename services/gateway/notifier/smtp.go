@@ -0,0 +1,72 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+)
+
+// DefaultSMTPPort is the submission port used when SMTP_PORT is unset
+const DefaultSMTPPort = 587
+
+// SMTPConfig holds SMTP server settings
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// smtpNotifier sends notifications as plain-text email
+type smtpNotifier struct {
+	config *SMTPConfig
+}
+
+// NewSMTPNotifier creates a notifier that sends email through an SMTP server
+func NewSMTPNotifier(config *SMTPConfig) Notifier {
+	return &smtpNotifier{
+		config: config,
+	}
+}
+
+// Notify sends the notification as an email
+func (n *smtpNotifier) Notify(ctx context.Context, notification *Notification) error {
+	if len(notification.To) == 0 {
+		return fmt.Errorf("notification has no recipients")
+	}
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	// Subjects are rendered from user templates and message payloads, so
+	// line breaks are removed to keep them from adding headers
+	msg := strings.Join([]string{
+		"From: " + headerValue(n.config.From),
+		"To: " + headerValue(strings.Join(notification.To, ", ")),
+		"Subject: " + mime.QEncoding.Encode("UTF-8", headerValue(notification.Subject)),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		notification.Body,
+	}, "\r\n")
+
+	addr := fmt.Sprintf("%s:%d", n.config.Host, n.config.Port)
+	if err := smtp.SendMail(addr, auth, n.config.From, notification.To, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// headerLineBreaks replaces the line breaks a header value must not contain
+var headerLineBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// headerValue returns value on a single line, safe to use as a header
+func headerValue(value string) string {
+	return headerLineBreaks.Replace(value)
+}
@@ -0,0 +1,42 @@
+package playground
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// page is the playground: a self-contained HTML page whose script connects
+// to /ws from the browser
+//
+//go:embed playground.html
+var page []byte
+
+// contentSecurityPolicy confines the page to its inline script and style
+// and, with the host it was served from, to connections back to the
+// gateway; 'self' does not cover WebSocket URLs in every browser
+const contentSecurityPolicy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; " +
+	"connect-src 'self' ws://%[1]s wss://%[1]s; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// Endpoint serves the protocol playground
+type Endpoint interface {
+	ServePage(c *gin.Context)
+}
+
+type endpoint struct{}
+
+// NewEndpoint creates a new endpoint
+func NewEndpoint() Endpoint {
+	return &endpoint{}
+}
+
+// ServePage handles GET /playground
+func (e *endpoint) ServePage(c *gin.Context) {
+	c.Header("Content-Security-Policy", fmt.Sprintf(contentSecurityPolicy, c.Request.Host))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
@@ -0,0 +1,51 @@
+package replication
+
+import (
+	"net/http"
+
+	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint interface for replication endpoints
+type Endpoint interface {
+	ApplyBatch(c *gin.Context)
+}
+
+type endpoint struct {
+	service Service
+}
+
+// NewEndpoint creates a new replication endpoint
+func NewEndpoint(service Service) Endpoint {
+	return &endpoint{
+		service: service,
+	}
+}
+
+// ApplyBatch handles POST /replication/batch, pushed by peer regions
+func (e *endpoint) ApplyBatch(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var batch Batch
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	response, err := e.service.Apply(batch)
+	if err != nil {
+		log.Warnw("Replication batch rejected", "region", batch.Region, "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Debugw("Applied replication batch", "region", batch.Region, "topics", response.Topics,
+		"applied", response.Applied, "duplicates", response.Duplicates, "skipped", response.Skipped)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,97 @@
+package replication
+
+import (
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+)
+
+// Replication parameters
+const (
+	// SyncInterval is how often topics are checked for changes and new
+	// messages are collected for each peer
+	SyncInterval = 250 * time.Millisecond
+	// HeartbeatInterval is the longest a peer goes without a push, so topic
+	// records converge and peer status stays current on idle links
+	HeartbeatInterval = 10 * time.Second
+	// MaxBatchMessages bounds the messages pushed to a peer at once
+	MaxBatchMessages = 500
+	// PushTimeout bounds one push to a peer
+	PushTimeout = 10 * time.Second
+	// MaxRetryBackoff bounds the wait between failed pushes, which starts at
+	// one second and doubles
+	MaxRetryBackoff = 30 * time.Second
+)
+
+// Config configures replication between regions. Batches from peers are
+// accepted when Region and Token are set; pushing also needs Peers.
+type Config struct {
+	Region string // this region's name, stamped on messages published here
+	Peers  []Peer
+	Token  string // shared secret peers present when pushing batches
+}
+
+// Peer is the gateway of another region
+type Peer struct {
+	Name string `json:"name"`
+	URL  string `json:"url"` // base URL, e.g. https://eu.pubsub.example.com
+}
+
+// TopicRecord is the last-writer-wins state of a topic: whether it exists,
+// as of the latest change any region made to it
+type TopicRecord struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner,omitempty"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Region    string    `json:"region"` // region that made the change
+}
+
+// newerThan orders records by UpdatedAt, breaking ties by region name so
+// every region picks the same winner
+func (r TopicRecord) newerThan(other TopicRecord) bool {
+	if !r.UpdatedAt.Equal(other.UpdatedAt) {
+		return r.UpdatedAt.After(other.UpdatedAt)
+	}
+	return r.Region > other.Region
+}
+
+// ReplicatedMessage is a message published to Topic in the sending region
+type ReplicatedMessage struct {
+	Topic   string         `json:"topic"`
+	Message pubsub.Message `json:"message"`
+}
+
+// Batch is pushed by one region to another: every topic record the sender
+// knows, and the messages published in the sender's region since its last
+// successful push
+type Batch struct {
+	Region   string              `json:"region"`
+	Topics   []TopicRecord       `json:"topics"`
+	Messages []ReplicatedMessage `json:"messages"`
+}
+
+type BatchResponse struct {
+	Topics     int `json:"topics"`     // topic records that changed local state
+	Applied    int `json:"applied"`    // messages published
+	Duplicates int `json:"duplicates"` // messages already replicated
+	Skipped    int `json:"skipped"`    // messages for topics that no longer exist
+}
+
+// PeerStatus reports replication to one peer
+type PeerStatus struct {
+	Name        string     `json:"name"`
+	URL         string     `json:"url"`
+	Pending     int        `json:"pending"`     // messages not yet pushed or skipped
+	LagSeconds  float64    `json:"lag_seconds"` // age of the oldest message waiting to be pushed
+	Sent        uint64     `json:"sent"`        // messages pushed
+	Failed      uint64     `json:"failed"`      // failed pushes
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// Status reports replication from this region, included in /stats
+type Status struct {
+	Region string       `json:"region"`
+	Peers  []PeerStatus `json:"peers"`
+}
@@ -0,0 +1,34 @@
+package replication
+
+import (
+	"github.com/ammysap/plivo-pub-sub/services/gateway/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRegistrar implements the secure.RouteRegistrarInterface
+type RouteRegistrar struct {
+	endpoint     Endpoint
+	authenticate gin.HandlerFunc
+}
+
+// NewRouteRegistrar creates a new route registrar. authenticate checks the
+// shared replication token peers push with.
+func NewRouteRegistrar(service Service, authenticate gin.HandlerFunc) secure.RouteRegistrarInterface {
+	return &RouteRegistrar{
+		endpoint:     NewEndpoint(service),
+		authenticate: authenticate,
+	}
+}
+
+// RegisterAuthRoutes registers authenticated routes
+func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
+	// replication routes authenticate separately, see RegisterUnAuthRoutes
+}
+
+// RegisterUnAuthRoutes registers the /replication routes behind the
+// replication token rather than user authentication
+func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
+	replicationGroup := unAuthGroup.Group("/replication", r.authenticate)
+
+	replicationGroup.POST("/batch", r.endpoint.ApplyBatch)
+}
@@ -0,0 +1,469 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+)
+
+// Service interface for replication between regions
+type Service interface {
+	// Apply merges a batch pushed by a peer region
+	Apply(batch Batch) (BatchResponse, error)
+	// Status reports replication lag per peer, or nil when replication is
+	// disabled
+	Status() *Status
+	// Close stops pushing to peers and waits for a push in flight to end
+	Close()
+}
+
+// peerRunner pushes this region's topics and messages to one peer. Messages
+// are pulled through a durable subscription per topic and acked once the
+// peer accepts them, so a failed push is retried with the same batch.
+type peerRunner struct {
+	peer       Peer
+	clientID   string
+	subscribed map[string]bool // topics with a durable subscription, owned by run
+	batch      *Batch          // collected and not yet accepted, owned by run
+	acks       map[string]uint64
+	pushed     uint64    // topic records version last accepted
+	lastPush   time.Time // last accepted push
+
+	status PeerStatus
+	oldest time.Time // timestamp of the oldest message in batch
+	mu     sync.Mutex
+}
+
+type service struct {
+	pubsubService pubsub.Service
+	config        Config
+	http          *http.Client
+	topics        map[string]TopicRecord // name -> latest record
+	version       uint64                 // bumped on every record change
+	runners       []*peerRunner
+	mu            sync.RWMutex
+
+	shutdown chan struct{}
+	wg       sync.WaitGroup
+}
+
+// LoadConfig reads replication settings from the environment:
+//
+//	REGION             this region's name
+//	REPLICATION_PEERS  comma-separated name=url pairs,
+//	                   e.g. "eu=https://eu.example.com,ap=https://ap.example.com"
+//	REPLICATION_TOKEN  shared secret every region uses to push batches
+func LoadConfig() (*Config, error) {
+	config := &Config{
+		Region: os.Getenv("REGION"),
+		Token:  os.Getenv("REPLICATION_TOKEN"),
+	}
+
+	for _, entry := range strings.Split(os.Getenv("REPLICATION_PEERS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rawURL, found := strings.Cut(entry, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid REPLICATION_PEERS entry %q: expected name=url", entry)
+		}
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid REPLICATION_PEERS entry %q: url must be http(s)", entry)
+		}
+		config.Peers = append(config.Peers, Peer{Name: name, URL: strings.TrimSuffix(rawURL, "/")})
+	}
+
+	if len(config.Peers) > 0 && (config.Region == "" || config.Token == "") {
+		return nil, fmt.Errorf("REPLICATION_PEERS requires REGION and REPLICATION_TOKEN")
+	}
+	return config, nil
+}
+
+// NewService creates a new replication service and starts pushing to each
+// peer. With no peers it still applies batches pushed by others.
+func NewService(config *Config) Service {
+	s := &service{
+		pubsubService: pubsub.GetService(),
+		config:        *config,
+		http:          &http.Client{Timeout: PushTimeout},
+		topics:        make(map[string]TopicRecord),
+		shutdown:      make(chan struct{}),
+	}
+	if !s.enabled() {
+		return s
+	}
+
+	for _, peer := range config.Peers {
+		r := &peerRunner{
+			peer:       peer,
+			clientID:   "replication:" + peer.Name,
+			subscribed: make(map[string]bool),
+			status:     PeerStatus{Name: peer.Name, URL: peer.URL},
+		}
+		s.runners = append(s.runners, r)
+		s.wg.Add(1)
+		go s.run(r)
+	}
+	s.wg.Add(1)
+	go s.watch()
+
+	return s
+}
+
+// Close stops the peer runners and the topic watcher. A batch not yet
+// accepted stays unacked, so it is pushed again after a restart.
+func (s *service) Close() {
+	select {
+	case <-s.shutdown:
+		return
+	default:
+		close(s.shutdown)
+	}
+	s.wg.Wait()
+}
+
+// enabled reports whether this region takes part in replication
+func (s *service) enabled() bool {
+	return s.config.Region != "" && s.config.Token != ""
+}
+
+// Apply merges a peer's topic records, last writer wins, then publishes its
+// messages. Messages already replicated are skipped, so a retried batch is
+// harmless.
+func (s *service) Apply(batch Batch) (BatchResponse, error) {
+	ctx := context.Background()
+	log := logging.WithContext(ctx)
+
+	if !s.enabled() {
+		return BatchResponse{}, fmt.Errorf("replication is disabled")
+	}
+	if batch.Region == "" || batch.Region == s.config.Region {
+		return BatchResponse{}, fmt.Errorf("invalid batch: region must name another region")
+	}
+
+	var response BatchResponse
+	for _, record := range batch.Topics {
+		if record.Name == "" || !s.merge(record) {
+			continue
+		}
+		response.Topics++
+
+		// Attribute the change in topic history to the region that made it
+		recordCtx := pubsub.WithActor(ctx, "replication:"+record.Region)
+		if record.Deleted {
+			if err := s.pubsubService.DeleteTopic(recordCtx, record.Name); err == nil {
+				log.Infow("Deleted replicated topic", "topic", record.Name, "region", record.Region)
+			}
+		} else if err := s.pubsubService.CreateTopic(recordCtx, record.Name, record.Owner, nil); err == nil {
+			log.Infow("Created replicated topic", "topic", record.Name, "region", record.Region)
+		}
+	}
+
+	for _, replicated := range batch.Messages {
+		applied, err := s.pubsubService.Replicate(ctx, replicated.Topic, replicated.Message)
+		switch {
+		case err != nil:
+			response.Skipped++
+			log.Debugw("Skipped replicated message", "topic", replicated.Topic, "message_id", replicated.Message.ID, "error", err)
+		case applied:
+			response.Applied++
+		default:
+			response.Duplicates++
+		}
+	}
+
+	return response, nil
+}
+
+// merge stores a record if it is newer than the one known for its topic
+func (s *service) merge(record TopicRecord) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if known, exists := s.topics[record.Name]; exists && !record.newerThan(known) {
+		return false
+	}
+	s.topics[record.Name] = record
+	s.version++
+	return true
+}
+
+// watch records topics created and deleted in this region, and deletes
+// local copies of topics another region deleted later than they were
+// created here
+func (s *service) watch() {
+	defer s.wg.Done()
+
+	ctx := context.Background()
+	ticker := time.NewTicker(SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		topics, err := s.pubsubService.ListTopics(ctx, "")
+		if err != nil {
+			continue
+		}
+
+		var stale []TopicRecord
+		seen := make(map[string]bool, len(topics))
+
+		s.mu.Lock()
+		for _, topic := range topics {
+			seen[topic.Name] = true
+			known, exists := s.topics[topic.Name]
+			switch {
+			case !exists || (known.Deleted && topic.CreatedAt.After(known.UpdatedAt)):
+				s.topics[topic.Name] = TopicRecord{Name: topic.Name, Owner: topic.Owner, UpdatedAt: topic.CreatedAt, Region: s.config.Region}
+				s.version++
+			case known.Deleted:
+				stale = append(stale, known)
+			}
+		}
+		for name, known := range s.topics {
+			if !known.Deleted && !seen[name] {
+				s.topics[name] = TopicRecord{Name: name, Owner: known.Owner, Deleted: true, UpdatedAt: time.Now(), Region: s.config.Region}
+				s.version++
+			}
+		}
+		s.mu.Unlock()
+
+		for _, record := range stale {
+			s.pubsubService.DeleteTopic(pubsub.WithActor(ctx, "replication:"+record.Region), record.Name)
+		}
+	}
+}
+
+// records returns every known topic record and the records version
+func (s *service) records() ([]TopicRecord, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]TopicRecord, 0, len(s.topics))
+	for _, record := range s.topics {
+		records = append(records, record)
+	}
+	return records, s.version
+}
+
+// run collects and pushes batches to one peer until the service is closed
+func (s *service) run(r *peerRunner) {
+	defer s.wg.Done()
+
+	log := logging.WithContext(context.Background())
+	backoff := time.Second
+	ticker := time.NewTicker(SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		// A failed batch is held and retried as is; an empty one is refilled
+		if r.batch == nil || len(r.batch.Messages) == 0 {
+			s.collect(r)
+		}
+
+		records, version := s.records()
+		if len(r.batch.Messages) == 0 && version == r.pushed && time.Since(r.lastPush) < HeartbeatInterval {
+			continue
+		}
+		r.batch.Topics = records
+
+		if err := s.push(r.peer, r.batch); err != nil {
+			r.mu.Lock()
+			r.status.Failed++
+			r.status.LastError = err.Error()
+			r.mu.Unlock()
+
+			log.Warnw("Replication push failed", "peer", r.peer.Name, "error", err, "retry_in", backoff)
+			select {
+			case <-s.shutdown:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, MaxRetryBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		for topic, seq := range r.acks {
+			s.pubsubService.Ack(context.Background(), topic, r.clientID, seq)
+		}
+
+		now := time.Now()
+		r.mu.Lock()
+		r.status.Sent += uint64(len(r.batch.Messages))
+		r.status.LastSuccess = &now
+		r.status.LastError = ""
+		r.mu.Unlock()
+
+		r.batch, r.acks = nil, nil
+		r.pushed, r.lastPush = version, now
+		s.updatePending(r)
+	}
+}
+
+// collect subscribes to new topics and fetches the next messages that
+// originated in this region. Messages replicated in from other regions are
+// fetched and acked but not pushed; their origin pushes them.
+func (s *service) collect(r *peerRunner) {
+	ctx := context.Background()
+
+	r.batch = &Batch{Region: s.config.Region}
+	r.acks = make(map[string]uint64)
+
+	topics, err := s.pubsubService.ListTopics(ctx, "")
+	if err != nil {
+		return
+	}
+
+	current := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		current[topic.Name] = true
+		if r.subscribed[topic.Name] {
+			continue
+		}
+		// Start from the oldest buffered message so nothing published
+		// before the topic was noticed is missed
+		_, err := s.pubsubService.Subscribe(ctx, topic.Name, r.clientID, &pubsub.SubscribeOptions{LastN: math.MaxInt32, Durable: true})
+		if err == nil {
+			r.subscribed[topic.Name] = true
+		}
+	}
+	for name := range r.subscribed {
+		if !current[name] {
+			delete(r.subscribed, name)
+		}
+	}
+
+	var oldest time.Time
+	for name := range r.subscribed {
+		remaining := MaxBatchMessages - len(r.batch.Messages)
+		if remaining <= 0 {
+			break
+		}
+
+		messages, err := s.pubsubService.Fetch(ctx, name, r.clientID, remaining)
+		if err != nil {
+			// The topic was deleted, and maybe recreated, since subscribing
+			delete(r.subscribed, name)
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		r.acks[name] = messages[len(messages)-1].Seq
+
+		for _, message := range messages {
+			if message.Origin != s.config.Region {
+				continue
+			}
+			r.batch.Messages = append(r.batch.Messages, ReplicatedMessage{Topic: name, Message: *message})
+			if oldest.IsZero() || message.Timestamp.Before(oldest) {
+				oldest = message.Timestamp
+			}
+		}
+	}
+
+	// Nothing to push: release messages that only came from other regions
+	if len(r.batch.Messages) == 0 {
+		for name, seq := range r.acks {
+			s.pubsubService.Ack(ctx, name, r.clientID, seq)
+		}
+		r.acks = make(map[string]uint64)
+	}
+
+	r.mu.Lock()
+	r.oldest = oldest
+	r.mu.Unlock()
+	s.updatePending(r)
+}
+
+// updatePending recomputes how many messages wait to be pushed to a peer
+func (s *service) updatePending(r *peerRunner) {
+	ctx := context.Background()
+
+	pending := 0
+	for name := range r.subscribed {
+		if cursor, err := s.pubsubService.GetCursor(ctx, name, r.clientID); err == nil {
+			pending += int(cursor.Lag)
+		}
+	}
+
+	r.mu.Lock()
+	r.status.Pending = pending
+	if r.batch == nil {
+		r.oldest = time.Time{}
+	}
+	r.mu.Unlock()
+}
+
+// push sends a batch to a peer
+func (s *service) push(peer Peer, batch *Batch) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, peer.URL+"/replication/batch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.config.Token)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("peer responded %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Status reports each peer's pending messages and lag
+func (s *service) Status() *Status {
+	if !s.enabled() {
+		return nil
+	}
+
+	status := &Status{Region: s.config.Region, Peers: make([]PeerStatus, 0, len(s.runners))}
+	for _, r := range s.runners {
+		r.mu.Lock()
+		peer := r.status
+		if !r.oldest.IsZero() {
+			peer.LagSeconds = time.Since(r.oldest).Seconds()
+		}
+		r.mu.Unlock()
+		status.Peers = append(status.Peers, peer)
+	}
+	return status
+}
@@ -2,7 +2,11 @@ package topic
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ammysap/plivo-pub-sub/pubsub"
 	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
 	"github.com/gin-gonic/gin"
 )
@@ -10,10 +14,40 @@ import (
 // endpoint implements the Endpoint interface
 type Endpoint interface {
 	CreateTopic(c *gin.Context)
+	CloneTopic(c *gin.Context)
 	DeleteTopic(c *gin.Context)
 	ListTopics(c *gin.Context)
+	GetTopic(c *gin.Context)
+	GetTopicHistory(c *gin.Context)
+	ListUserTopics(c *gin.Context)
 	GetHealth(c *gin.Context)
+	GetReadiness(c *gin.Context)
 	GetStats(c *gin.Context)
+	CreateRoute(c *gin.Context)
+	ListRoutes(c *gin.Context)
+	GetReadMarker(c *gin.Context)
+	SetReplayRate(c *gin.Context)
+	SetExpiry(c *gin.Context)
+	SetRetention(c *gin.Context)
+	SetDecoding(c *gin.Context)
+	SetOrdering(c *gin.Context)
+	SetDelivery(c *gin.Context)
+	SetMetadata(c *gin.Context)
+	SetPartitions(c *gin.Context)
+	SetCompaction(c *gin.Context)
+	SetIngress(c *gin.Context)
+	GetPartitions(c *gin.Context)
+	SetBackpressure(c *gin.Context)
+	SetDedup(c *gin.Context)
+	RegisterSchema(c *gin.Context)
+	ListSchemas(c *gin.Context)
+	SetIndexes(c *gin.Context)
+	FindMessages(c *gin.Context)
+	DeleteRoute(c *gin.Context)
+	DeleteMessage(c *gin.Context)
+	CreateSchedule(c *gin.Context)
+	ListSchedules(c *gin.Context)
+	DeleteSchedule(c *gin.Context)
 }
 type endpoint struct {
 	service Service
@@ -48,27 +82,114 @@ func (e *endpoint) CreateTopic(c *gin.Context) {
 		return
 	}
 
-	err = e.service.CreateTopic(req.Name)
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	metadata := pubsub.Metadata{Description: req.Description, Labels: req.Labels}
+	opts := pubsub.TopicOptions{RingBufferSize: req.RingBufferSize, ChannelBufferSize: req.ChannelBufferSize}
+	if req.JSONSchema != nil {
+		if opts.JSONSchema, err = pubsub.NewJSONSchema(req.JSONSchema); err != nil {
+			log.Warnw("Invalid topic JSON schema", "error", err.Error(), "topic", req.Name)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	err = e.service.CreateTopic(req.Name, c.GetString("user_id"), expiresAt, metadata, opts)
 	if err != nil {
 		if err.Error() == "topic "+req.Name+" already exists" {
 			log.Errorw("Topic already exists", "topic", req.Name)
 			c.JSON(http.StatusConflict, gin.H{"error": "Topic already exists"})
 			return
 		}
+		if strings.HasPrefix(err.Error(), "topic quota exceeded") {
+			log.Warnw("Topic quota exceeded", "error", err.Error(), "topic", req.Name)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid expiry") {
+			log.Warnw("Invalid topic expiry", "error", err.Error(), "topic", req.Name)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid topic") || strings.HasPrefix(err.Error(), "invalid metadata") {
+			log.Warnw("Invalid topic options", "error", err.Error(), "topic", req.Name)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Errorw("Error creating topic", "error", err.Error(), "topic", req.Name)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create topic"})
 		return
 	}
 
 	response := CreateTopicResponse{
-		Status: "created",
-		Topic:  req.Name,
+		Status:    "created",
+		Topic:     req.Name,
+		ExpiresAt: req.ExpiresAt,
 	}
 
 	log.Infow("Topic created successfully", "topic", req.Name)
 	c.JSON(http.StatusCreated, response)
 }
 
+// CloneTopic handles POST /topics/{name}/clone?target=...&with_history=true
+func (e *endpoint) CloneTopic(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	source := c.Param("name")
+	target := c.Query("target")
+	if target == "" {
+		log.Errorw("Clone target is required", "topic", source)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target is required"})
+		return
+	}
+
+	withHistory := false
+	if value := c.Query("with_history"); value != "" {
+		withHistory, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "with_history must be true or false"})
+			return
+		}
+	}
+
+	response, err := e.service.CloneTopic(source, target, c.GetString("user_id"), withHistory)
+	if err != nil {
+		if err.Error() == "topic "+source+" not found" {
+			log.Warnw("Topic not found", "topic", source)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if err.Error() == "topic "+target+" already exists" {
+			log.Errorw("Topic already exists", "topic", target)
+			c.JSON(http.StatusConflict, gin.H{"error": "Topic already exists"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "topic quota exceeded") {
+			log.Warnw("Topic quota exceeded", "error", err.Error(), "target", target)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid topic name") {
+			log.Warnw("Invalid clone target", "error", err.Error(), "target", target)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error cloning topic", "error", err.Error(), "topic", source, "target", target)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone topic"})
+		return
+	}
+
+	log.Infow("Topic cloned successfully", "topic", source, "target", target, "messages", response.Messages)
+	c.JSON(http.StatusCreated, response)
+}
+
 // DeleteTopic handles DELETE /topics/{name}
 func (e *endpoint) DeleteTopic(c *gin.Context) {
 	_, log, err := logger.GetLoggerFromGinContext(c)
@@ -85,13 +206,18 @@ func (e *endpoint) DeleteTopic(c *gin.Context) {
 		return
 	}
 
-	err = e.service.DeleteTopic(topicName)
+	err = e.service.DeleteTopic(topicName, c.GetString("user_id"))
 	if err != nil {
 		if err.Error() == "topic "+topicName+" not found" {
 			log.Warnw("Topic not found", "topic", topicName)
 			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
 			return
 		}
+		if err.Error() == "topic "+topicName+" is under legal hold" {
+			log.Warnw("Topic deletion refused under legal hold", "topic", topicName)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		log.Errorw("Error deleting topic", "error", err.Error(), "topic", topicName)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete topic"})
 		return
@@ -106,7 +232,7 @@ func (e *endpoint) DeleteTopic(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// ListTopics handles GET /topics
+// ListTopics handles GET /topics?prefix=tenant/app
 func (e *endpoint) ListTopics(c *gin.Context) {
 	_, log, err := logger.GetLoggerFromGinContext(c)
 	if err != nil {
@@ -115,7 +241,7 @@ func (e *endpoint) ListTopics(c *gin.Context) {
 		return
 	}
 
-	topics, err := e.service.ListTopics()
+	topics, err := e.service.ListTopics(c.Query("prefix"), c.QueryArray("label"))
 	if err != nil {
 		log.Errorw("Error listing topics", "error", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list topics"})
@@ -130,6 +256,75 @@ func (e *endpoint) ListTopics(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetTopic handles GET /topics/{name}
+func (e *endpoint) GetTopic(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	topic, err := e.service.GetTopic(topicName)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error getting topic", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get topic"})
+		return
+	}
+
+	c.JSON(http.StatusOK, topic)
+}
+
+// GetTopicHistory handles GET /topics/{name}/history
+func (e *endpoint) GetTopicHistory(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	response, err := e.service.TopicHistory(topicName)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error getting topic history", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get topic history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListUserTopics handles GET /users/topics
+func (e *endpoint) ListUserTopics(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	topics, err := e.service.ListUserTopics(userID)
+	if err != nil {
+		log.Errorw("Error listing user topics", "error", err.Error(), "user_id", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list user topics"})
+		return
+	}
+
+	log.Infow("User topics listed successfully", "user_id", userID, "count", len(topics))
+	c.JSON(http.StatusOK, UserTopicsResponse{Topics: topics})
+}
+
 // GetHealth handles GET /health
 func (e *endpoint) GetHealth(c *gin.Context) {
 	_, log, err := logger.GetLoggerFromGinContext(c)
@@ -150,6 +345,32 @@ func (e *endpoint) GetHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, health)
 }
 
+// GetReadiness handles GET /ready, answering 503 until the node has
+// recovered its state so load balancers hold traffic back
+func (e *endpoint) GetReadiness(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	readiness, err := e.service.GetReadiness()
+	if err != nil {
+		log.Errorw("Error getting readiness status", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get readiness status"})
+		return
+	}
+
+	if !readiness.Ready {
+		log.Debugw("Readiness check failed", "phase", readiness.Phase)
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	c.JSON(http.StatusOK, readiness)
+}
+
 // GetStats handles GET /stats
 func (e *endpoint) GetStats(c *gin.Context) {
 	_, log, err := logger.GetLoggerFromGinContext(c)
@@ -169,3 +390,1002 @@ func (e *endpoint) GetStats(c *gin.Context) {
 	log.Debugw("Stats requested", "topics_count", len(stats.Topics))
 	c.JSON(http.StatusOK, stats)
 }
+
+// CreateRoute handles POST /topics/{name}/routes
+func (e *endpoint) CreateRoute(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req CreateRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	route, err := e.service.AddRoute(topicName, req, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid route") {
+			log.Warnw("Invalid route", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error creating route", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create route"})
+		return
+	}
+
+	response := CreateRouteResponse{
+		Status: "created",
+		Topic:  topicName,
+		Route:  route,
+	}
+
+	log.Infow("Route created successfully", "topic", topicName, "target", route.Target, "route_id", route.ID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListRoutes handles GET /topics/{name}/routes
+func (e *endpoint) ListRoutes(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	routes, err := e.service.ListRoutes(topicName)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error listing routes", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list routes"})
+		return
+	}
+
+	response := ListRoutesResponse{
+		Topic:  topicName,
+		Routes: routes,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetReadMarker handles GET /topics/{name}/read-marker
+func (e *endpoint) GetReadMarker(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	marker, err := e.service.GetReadMarker(topicName, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error getting read marker", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get read marker"})
+		return
+	}
+
+	c.JSON(http.StatusOK, marker)
+}
+
+// SetReplayRate handles PUT /topics/{name}/replay-rate
+func (e *endpoint) SetReplayRate(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetReplayRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetReplayRate(topicName, *req.Rate, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid replay rate") {
+			log.Warnw("Invalid replay rate", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting replay rate", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set replay rate"})
+		return
+	}
+
+	log.Infow("Replay rate set", "topic", topicName, "rate", *req.Rate)
+	c.JSON(http.StatusOK, ReplayRateResponse{Topic: topicName, Rate: *req.Rate})
+}
+
+// SetExpiry handles PUT /topics/{name}/expiry
+func (e *endpoint) SetExpiry(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	err = e.service.SetExpiry(topicName, expiresAt, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid expiry") {
+			log.Warnw("Invalid topic expiry", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting topic expiry", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set topic expiry"})
+		return
+	}
+
+	log.Infow("Topic expiry set", "topic", topicName, "expires_at", expiresAt)
+	c.JSON(http.StatusOK, ExpiryResponse{Topic: topicName, ExpiresAt: req.ExpiresAt})
+}
+
+// SetRetention handles PUT /topics/{name}/retention
+func (e *endpoint) SetRetention(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetRetention(topicName, Retention(req), c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid retention") {
+			log.Warnw("Invalid topic retention", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting topic retention", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set topic retention"})
+		return
+	}
+
+	log.Infow("Topic retention set", "topic", topicName, "max_age_sec", req.MaxAgeSec, "max_count", req.MaxCount)
+	c.JSON(http.StatusOK, RetentionResponse{Topic: topicName, MaxAgeSec: req.MaxAgeSec, MaxCount: req.MaxCount})
+}
+
+// SetDecoding handles PUT /topics/{name}/decoding
+func (e *endpoint) SetDecoding(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetDecodingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetDecoding(topicName, Decoding(req), c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid decoding") {
+			log.Warnw("Invalid decoding", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting decoding", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set decoding"})
+		return
+	}
+
+	log.Infow("Decoding set", "topic", topicName, "numbers", req.Numbers, "strict", req.Strict)
+	c.JSON(http.StatusOK, DecodingResponse{Topic: topicName, Numbers: req.Numbers, Strict: req.Strict})
+}
+
+// SetOrdering handles PUT /topics/{name}/ordering
+func (e *endpoint) SetOrdering(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetOrderingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetOrdering(topicName, req.Mode, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ordering") {
+			log.Warnw("Invalid ordering", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting ordering", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set ordering"})
+		return
+	}
+
+	log.Infow("Ordering set", "topic", topicName, "mode", req.Mode)
+	c.JSON(http.StatusOK, OrderingResponse{Topic: topicName, Mode: req.Mode})
+}
+
+// SetDelivery handles PUT /topics/{name}/delivery
+func (e *endpoint) SetDelivery(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetDelivery(topicName, req.Mode, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid delivery") {
+			log.Warnw("Invalid delivery", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting delivery", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set delivery"})
+		return
+	}
+
+	log.Infow("Delivery set", "topic", topicName, "mode", req.Mode)
+	c.JSON(http.StatusOK, DeliveryResponse{Topic: topicName, Mode: req.Mode})
+}
+
+// SetMetadata handles PUT /topics/{name}/metadata
+func (e *endpoint) SetMetadata(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	metadata := pubsub.Metadata{Description: req.Description, Labels: req.Labels}
+	err = e.service.SetMetadata(topicName, metadata, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid metadata") {
+			log.Warnw("Invalid metadata", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting metadata", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set metadata"})
+		return
+	}
+
+	log.Infow("Metadata set", "topic", topicName, "labels", len(req.Labels))
+	c.JSON(http.StatusOK, MetadataResponse{Topic: topicName, Description: req.Description, Labels: req.Labels})
+}
+
+// SetCompaction handles PUT /topics/{name}/compaction
+func (e *endpoint) SetCompaction(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetCompactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	compaction := pubsub.Compaction{Enabled: req.Enabled, Header: req.Header}
+	err = e.service.SetCompaction(topicName, compaction, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid compaction") {
+			log.Warnw("Invalid compaction", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting compaction", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set compaction"})
+		return
+	}
+
+	log.Infow("Compaction set", "topic", topicName, "enabled", req.Enabled, "header", req.Header)
+	c.JSON(http.StatusOK, CompactionResponse{Topic: topicName, Enabled: req.Enabled, Header: req.Header})
+}
+
+// SetIngress handles PUT /topics/{name}/ingress
+func (e *endpoint) SetIngress(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetIngressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetIngress(topicName, pubsub.Ingress{Size: req.Size, Rate: req.Rate}, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ingress") {
+			log.Warnw("Invalid ingress", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting ingress", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set ingress"})
+		return
+	}
+
+	log.Infow("Ingress set", "topic", topicName, "size", req.Size, "rate", req.Rate)
+	c.JSON(http.StatusOK, IngressResponse{Topic: topicName, Size: req.Size, Rate: req.Rate})
+}
+
+// SetPartitions handles PUT /topics/{name}/partitions
+func (e *endpoint) SetPartitions(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetPartitionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetPartitions(topicName, req.Count, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid partitions") {
+			log.Warnw("Invalid partitions", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting partitions", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set partitions"})
+		return
+	}
+
+	partitions, err := e.service.GetPartitions(topicName)
+	if err != nil {
+		log.Errorw("Error getting partitions", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get partitions"})
+		return
+	}
+
+	log.Infow("Partitions set", "topic", topicName, "count", req.Count)
+	c.JSON(http.StatusOK, PartitionsResponse{Topic: topicName, Count: len(partitions), Partitions: partitions})
+}
+
+// GetPartitions handles GET /topics/{name}/partitions
+func (e *endpoint) GetPartitions(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	partitions, err := e.service.GetPartitions(topicName)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error getting partitions", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get partitions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PartitionsResponse{Topic: topicName, Count: len(partitions), Partitions: partitions})
+}
+
+// SetBackpressure handles PUT /topics/{name}/backpressure
+func (e *endpoint) SetBackpressure(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetBackpressureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	backpressure := pubsub.Backpressure{Policy: pubsub.BackpressurePolicy(req.Policy), TimeoutMs: req.TimeoutMs}
+	err = e.service.SetBackpressure(topicName, backpressure, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid backpressure") {
+			log.Warnw("Invalid backpressure", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting backpressure", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set backpressure"})
+		return
+	}
+
+	log.Infow("Backpressure set", "topic", topicName, "policy", req.Policy, "timeout_ms", req.TimeoutMs)
+	c.JSON(http.StatusOK, BackpressureResponse{Topic: topicName, Policy: req.Policy, TimeoutMs: req.TimeoutMs})
+}
+
+// RegisterSchema handles PUT /topics/{name}/schema
+func (e *endpoint) RegisterSchema(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req RegisterSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	schema, err := e.service.RegisterSchema(topicName, req, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid schema") {
+			log.Warnw("Invalid schema", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error registering schema", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register schema"})
+		return
+	}
+
+	log.Infow("Schema registered", "topic", topicName, "version", schema.Version)
+	c.JSON(http.StatusOK, SchemaResponse{Topic: topicName, Schema: schema})
+}
+
+// ListSchemas handles GET /topics/{name}/schemas
+func (e *endpoint) ListSchemas(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	schemas, err := e.service.ListSchemas(topicName)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error listing schemas", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list schemas"})
+		return
+	}
+
+	versions := schemas.Versions
+	if versions == nil {
+		versions = []pubsub.Schema{}
+	}
+	c.JSON(http.StatusOK, ListSchemasResponse{Topic: topicName, Compatibility: schemas.Compatibility, Versions: versions})
+}
+
+// SetDedup handles PUT /topics/{name}/dedup
+func (e *endpoint) SetDedup(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetDedupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetDedup(topicName, pubsub.Dedup{Size: req.Size, WindowSec: req.WindowSec}, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid dedup") {
+			log.Warnw("Invalid dedup", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting dedup", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set dedup"})
+		return
+	}
+
+	log.Infow("Dedup set", "topic", topicName, "size", req.Size, "window_sec", req.WindowSec)
+	c.JSON(http.StatusOK, DedupResponse{Topic: topicName, Size: req.Size, WindowSec: req.WindowSec})
+}
+
+// SetIndexes handles PUT /topics/{name}/indexes
+func (e *endpoint) SetIndexes(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req SetIndexesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SetHeaderIndexes(topicName, req.Keys, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid header index") {
+			log.Warnw("Invalid header index", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error setting header indexes", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set header indexes"})
+		return
+	}
+
+	keys := req.Keys
+	if keys == nil {
+		keys = []string{}
+	}
+
+	log.Infow("Header indexes set", "topic", topicName, "keys", keys)
+	c.JSON(http.StatusOK, IndexesResponse{Topic: topicName, Keys: keys})
+}
+
+// FindMessages handles GET /topics/{name}/messages?header.<key>=<value>&limit=...
+func (e *endpoint) FindMessages(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	headers := make(map[string]string)
+	for param, values := range c.Request.URL.Query() {
+		if key, ok := strings.CutPrefix(param, "header."); ok && len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	if len(headers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one header.<key>=<value> query parameter is required"})
+		return
+	}
+
+	limit := DefaultFindLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(parsed, MaxFindLimit)
+	}
+
+	// Tagged before the lookup, so a publish racing it makes the tag stale
+	// rather than the cached body
+	etag, err := e.service.MessagesETag(topicName, headers, limit)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error tagging messages", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find messages"})
+		return
+	}
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		setCacheHeaders(c, etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	messages, err := e.service.FindMessages(topicName, headers, limit)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid header lookup") {
+			log.Warnw("Invalid header lookup", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error finding messages", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find messages"})
+		return
+	}
+
+	setCacheHeaders(c, etag)
+	c.JSON(http.StatusOK, FindMessagesResponse{
+		Topic:    topicName,
+		Headers:  headers,
+		Messages: messages,
+		Count:    len(messages),
+	})
+}
+
+// DeleteMessage handles DELETE /topics/{name}/messages/{id}
+func (e *endpoint) DeleteMessage(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	messageID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	response, err := e.service.DeleteMessage(topicName, messageID, userID)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if err.Error() == "message "+messageID+" not found in topic "+topicName {
+			log.Warnw("Message not found", "topic", topicName, "message_id", messageID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			log.Warnw("Message deletion forbidden", "topic", topicName, "message_id", messageID, "user_id", userID)
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "topic "+topicName+" is under legal hold" {
+			log.Warnw("Message deletion refused under legal hold", "topic", topicName, "message_id", messageID)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error deleting message", "error", err.Error(), "topic", topicName, "message_id", messageID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
+		return
+	}
+
+	log.Infow("Message deleted successfully", "topic", topicName, "message_id", messageID, "user_id", userID)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteRoute handles DELETE /topics/{name}/routes/{id}
+func (e *endpoint) DeleteRoute(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	routeID := c.Param("id")
+
+	err = e.service.DeleteRoute(topicName, routeID, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if err.Error() == "route "+routeID+" not found" {
+			log.Warnw("Route not found", "topic", topicName, "route_id", routeID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			return
+		}
+		log.Errorw("Error deleting route", "error", err.Error(), "topic", topicName, "route_id", routeID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete route"})
+		return
+	}
+
+	response := DeleteRouteResponse{
+		Status:  "deleted",
+		Topic:   topicName,
+		RouteID: routeID,
+	}
+
+	log.Infow("Route deleted successfully", "topic", topicName, "route_id", routeID)
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSchedule handles POST /topics/{name}/schedules
+func (e *endpoint) CreateSchedule(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	var req CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	schedule, err := e.service.AddSchedule(topicName, req, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid schedule") {
+			log.Warnw("Invalid schedule", "error", err.Error(), "topic", topicName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Errorw("Error creating schedule", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+
+	response := CreateScheduleResponse{
+		Status:   "created",
+		Topic:    topicName,
+		Schedule: schedule,
+	}
+
+	log.Infow("Schedule created successfully", "topic", topicName, "schedule_id", schedule.ID, "cron", schedule.Cron)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListSchedules handles GET /topics/{name}/schedules
+func (e *endpoint) ListSchedules(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+
+	schedules, err := e.service.ListSchedules(topicName)
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		log.Errorw("Error listing schedules", "error", err.Error(), "topic", topicName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list schedules"})
+		return
+	}
+
+	response := ListSchedulesResponse{
+		Topic:     topicName,
+		Schedules: schedules,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteSchedule handles DELETE /topics/{name}/schedules/{id}
+func (e *endpoint) DeleteSchedule(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	topicName := c.Param("name")
+	scheduleID := c.Param("id")
+
+	err = e.service.DeleteSchedule(topicName, scheduleID, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "topic "+topicName+" not found" {
+			log.Warnw("Topic not found", "topic", topicName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if err.Error() == "schedule "+scheduleID+" not found" {
+			log.Warnw("Schedule not found", "topic", topicName, "schedule_id", scheduleID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return
+		}
+		log.Errorw("Error deleting schedule", "error", err.Error(), "topic", topicName, "schedule_id", scheduleID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+
+	response := DeleteScheduleResponse{
+		Status:     "deleted",
+		Topic:      topicName,
+		ScheduleID: scheduleID,
+	}
+
+	log.Infow("Schedule deleted successfully", "topic", topicName, "schedule_id", scheduleID)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,53 @@
+package topic
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MessagesCacheControl lets browsers and CDNs store message lookups but
+// revalidate them on every use, so the gateway still authenticates each
+// request and answers an unchanged topic with 304 Not Modified
+const MessagesCacheControl = "public, no-cache"
+
+// messagesETag tags a lookup of a topic's buffered messages by the
+// sequence number of the newest one ever published and how many are held,
+// which together change on every publish, deletion and eviction, and by
+// the lookup itself and the header keys the topic indexes
+func messagesETag(lastSeq uint64, count int, indexes []string, headers map[string]string, limit int) string {
+	lookup := fnv.New32a()
+	fmt.Fprintf(lookup, "%q %d", indexes, limit)
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(lookup, " %q=%q", key, headers[key])
+	}
+	return fmt.Sprintf(`"seq-%d-%d-%08x"`, lastSeq, count, lookup.Sum32())
+}
+
+// etagMatches reports whether an If-None-Match header names etag. Weak
+// validators match their strong form, as RFC 9110 requires for GET.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setCacheHeaders marks a response as the version etag of a cacheable
+// resource. Caches keep one copy per Authorization header.
+func setCacheHeaders(c *gin.Context, etag string) {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", MessagesCacheControl)
+	c.Header("Vary", "Authorization")
+}
@@ -1,13 +1,34 @@
 package topic
 
+import (
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/replication"
+)
+
 // REST API Models
 type CreateTopicRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name      string     `json:"name" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // delete the topic automatically at this time
+
+	// Description and labels for browsing topics; see SetMetadataRequest
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+
+	// Buffer sizes of the topic, fixed at creation; 0 uses the server's
+	RingBufferSize    int `json:"ring_buffer_size,omitempty"`    // messages held for replay
+	ChannelBufferSize int `json:"channel_buffer_size,omitempty"` // messages queued per subscriber
+
+	// JSON Schema payloads published to the topic must match, fixed at
+	// creation
+	JSONSchema map[string]interface{} `json:"json_schema,omitempty"`
 }
 
 type CreateTopicResponse struct {
-	Status string `json:"status"`
-	Topic  string `json:"topic"`
+	Status    string     `json:"status"`
+	Topic     string     `json:"topic"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type DeleteTopicResponse struct {
@@ -16,25 +37,465 @@ type DeleteTopicResponse struct {
 }
 
 type TopicInfo struct {
-	Name        string `json:"name"`
-	Subscribers int    `json:"subscribers"`
+	Name        string            `json:"name"`
+	Subscribers int               `json:"subscribers"`
+	Owner       string            `json:"owner,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+
+	JSONSchema *pubsub.JSONSchema `json:"json_schema,omitempty"` // payloads must match it
 }
 
 type ListTopicsResponse struct {
 	Topics []TopicInfo `json:"topics"`
 }
 
+// TopicHistoryResponse lists a topic's lifecycle events, oldest first
+type TopicHistoryResponse struct {
+	Topic  string                `json:"topic"`
+	Exists bool                  `json:"exists"` // false once the topic is deleted or expired
+	Events []pubsub.HistoryEvent `json:"events"`
+	Count  int                   `json:"count"`
+}
+
+// Relations between the calling user and a topic in UserTopic
+const (
+	RelationOwner      = "owner"      // the user created the topic
+	RelationSubscriber = "subscriber" // the user has a live or durable subscription
+	RelationSaved      = "saved"      // the topic is in the user's saved subscriptions
+)
+
+// UserTopic describes a topic from the point of view of one user
+type UserTopic struct {
+	Name        string            `json:"name"`
+	Relations   []string          `json:"relations"`
+	Owner       string            `json:"owner,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Config      TopicConfig       `json:"config"`
+	Quota       TopicQuota        `json:"quota"`
+	Throughput  TopicThroughput   `json:"throughput"`
+}
+
+type TopicConfig struct {
+	Routes     int      `json:"routes"`
+	Schedules  int      `json:"schedules"`
+	ReplayRate float64  `json:"replay_rate"`
+	Decoding   Decoding `json:"decoding"`
+	Ordering   string   `json:"ordering,omitempty"` // "strict" for single-writer FIFO ordering
+	Delivery   string   `json:"delivery,omitempty"` // "once_per_user" to alert one connection per user
+	Indexes    []string `json:"indexes,omitempty"`  // indexed header keys
+
+	RingBufferSize    int `json:"ring_buffer_size"`    // messages held for replay
+	ChannelBufferSize int `json:"channel_buffer_size"` // messages queued per subscriber
+
+	JSONSchema *pubsub.JSONSchema `json:"json_schema,omitempty"` // payloads must match it
+
+	Retention *Retention `json:"retention,omitempty"` // message eviction by age and count
+
+	Backpressure *pubsub.Backpressure `json:"backpressure,omitempty"` // full queue policy of subscriptions without their own
+
+	Dedup *pubsub.Dedup `json:"dedup,omitempty"` // window of message IDs published at most once
+
+	LegalHold *pubsub.LegalHold `json:"legal_hold,omitempty"` // set while the topic is preserved for an investigation
+
+	Partitions int `json:"partitions,omitempty"` // sequencers publishes are split across by key
+
+	TextIndex int `json:"text_index,omitempty"` // newest messages indexed for operator search
+
+	Compaction *pubsub.Compaction `json:"compaction,omitempty"` // latest message kept per key
+
+	Ingress *pubsub.Ingress `json:"ingress,omitempty"` // queue smoothing publish bursts
+}
+
+// SetReplayRateRequest sets the maximum messages per second replayed to each
+// subscriber; 0 restores the server default
+type SetReplayRateRequest struct {
+	Rate *float64 `json:"rate" binding:"required"`
+}
+
+type ReplayRateResponse struct {
+	Topic string  `json:"topic"`
+	Rate  float64 `json:"rate"`
+}
+
+// SetExpiryRequest schedules the topic's automatic deletion; a null or
+// missing expires_at cancels it
+type SetExpiryRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ExpiryResponse struct {
+	Topic     string     `json:"topic"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Decoding is how messages published to a topic are decoded
+type Decoding struct {
+	Numbers string `json:"numbers,omitempty"` // "exact" (default) keeps numbers as written, "float" decodes them as float64
+	Strict  bool   `json:"strict,omitempty"`  // reject publish frames with unknown fields
+}
+
+// SetDecodingRequest sets how messages published to the topic are decoded
+type SetDecodingRequest struct {
+	Numbers string `json:"numbers,omitempty"`
+	Strict  bool   `json:"strict,omitempty"`
+}
+
+type DecodingResponse struct {
+	Topic   string `json:"topic"`
+	Numbers string `json:"numbers,omitempty"`
+	Strict  bool   `json:"strict"`
+}
+
+// Retention evicts a topic's messages before its buffer wraps; a zero limit
+// is off
+type Retention struct {
+	MaxAgeSec int64 `json:"max_age_sec,omitempty"` // messages older than this many seconds are evicted
+	MaxCount  int   `json:"max_count,omitempty"`   // only the newest this many messages are kept
+}
+
+// SetRetentionRequest sets how long and how many messages the topic keeps;
+// zero or missing limits keep messages until the buffer wraps
+type SetRetentionRequest struct {
+	MaxAgeSec int64 `json:"max_age_sec"`
+	MaxCount  int   `json:"max_count"`
+}
+
+type RetentionResponse struct {
+	Topic     string `json:"topic"`
+	MaxAgeSec int64  `json:"max_age_sec"`
+	MaxCount  int    `json:"max_count"`
+}
+
+// SetOrderingRequest sets the topic's ordering mode: "strict" sequences every
+// publish through a single writer, an empty mode restores the default
+type SetOrderingRequest struct {
+	Mode string `json:"mode"`
+}
+
+type OrderingResponse struct {
+	Topic string `json:"topic"`
+	Mode  string `json:"mode"`
+}
+
+// SetDeliveryRequest sets the topic's delivery mode: "once_per_user" offers
+// each message to one connection per user, an empty mode to every connection
+type SetDeliveryRequest struct {
+	Mode string `json:"mode"`
+}
+
+type DeliveryResponse struct {
+	Topic string `json:"topic"`
+	Mode  string `json:"mode"`
+}
+
+// SetMetadataRequest replaces the topic's description and labels, such as
+// {"env": "prod", "team": "payments"}, which GET /topics?label=env:prod
+// filters on
+type SetMetadataRequest struct {
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+}
+
+type MetadataResponse struct {
+	Topic       string            `json:"topic"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// SetCompactionRequest makes the topic keep the latest message of each key,
+// taken from the message key or, without one, from the header, and replay
+// them to new subscribers before live messages. A message with a null
+// payload removes its key.
+type SetCompactionRequest struct {
+	Enabled bool   `json:"enabled"`
+	Header  string `json:"header"`
+}
+
+type CompactionResponse struct {
+	Topic   string `json:"topic"`
+	Enabled bool   `json:"enabled"`
+	Header  string `json:"header,omitempty"`
+}
+
+// SetIngressRequest queues up to size publishes to the topic, fanned out
+// at most rate per second (0 as fast as possible), so bursts are absorbed
+// rather than refused; publishes are refused as busy only while the queue
+// is full. A size of 0 publishes directly.
+type SetIngressRequest struct {
+	Size int     `json:"size"`
+	Rate float64 `json:"rate"`
+}
+
+type IngressResponse struct {
+	Topic string  `json:"topic"`
+	Size  int     `json:"size"`
+	Rate  float64 `json:"rate"`
+}
+
+// SetPartitionsRequest splits the topic into count partitions keyed by
+// message key, each delivered in order; 0 merges them back
+type SetPartitionsRequest struct {
+	Count int `json:"count"`
+}
+
+type PartitionsResponse struct {
+	Topic      string                 `json:"topic"`
+	Count      int                    `json:"count"`
+	Partitions []pubsub.PartitionInfo `json:"partitions"`
+}
+
+// SetBackpressureRequest sets what happens when a subscriber's queue is
+// full, for subscriptions without a policy of their own: drop_newest (the
+// default), drop_oldest, block_with_timeout or disconnect. An empty policy
+// restores the default.
+type SetBackpressureRequest struct {
+	Policy    string `json:"policy"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // block_with_timeout wait; 0 uses 100ms
+}
+
+type BackpressureResponse struct {
+	Topic     string `json:"topic"`
+	Policy    string `json:"policy"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+// SetDedupRequest sets the window in which a republished message ID is
+// acknowledged without being delivered again: the last size IDs, forgotten
+// after window_sec seconds. Zero or missing limits disable it.
+type SetDedupRequest struct {
+	Size      int   `json:"size"`
+	WindowSec int64 `json:"window_sec"`
+}
+
+type DedupResponse struct {
+	Topic     string `json:"topic"`
+	Size      int    `json:"size"`
+	WindowSec int64  `json:"window_sec"`
+}
+
+// RegisterSchemaRequest adds a schema version to a topic. Compatibility,
+// when set, replaces the topic's mode (backward by default) before the new
+// version is checked against the latest.
+type RegisterSchemaRequest struct {
+	Fields        []pubsub.SchemaField `json:"fields" binding:"required"`
+	Compatibility string               `json:"compatibility,omitempty"`
+}
+
+type SchemaResponse struct {
+	Topic  string        `json:"topic"`
+	Schema pubsub.Schema `json:"schema"`
+}
+
+type ListSchemasResponse struct {
+	Topic         string          `json:"topic"`
+	Compatibility string          `json:"compatibility"`
+	Versions      []pubsub.Schema `json:"versions"`
+}
+
+// Header lookup result limits
+const (
+	DefaultFindLimit = 100
+	MaxFindLimit     = 1000
+)
+
+// SetIndexesRequest sets the header keys indexed for message lookups; an
+// empty list drops the index
+type SetIndexesRequest struct {
+	Keys []string `json:"keys"`
+}
+
+type IndexesResponse struct {
+	Topic string   `json:"topic"`
+	Keys  []string `json:"keys"`
+}
+
+// FindMessagesResponse lists buffered messages matching a header lookup
+type FindMessagesResponse struct {
+	Topic    string            `json:"topic"`
+	Headers  map[string]string `json:"headers"`
+	Messages []*pubsub.Message `json:"messages"`
+	Count    int               `json:"count"`
+}
+
+// TopicQuota is the topic's publish rate limit
+type TopicQuota struct {
+	Rate      float64 `json:"rate"`
+	Burst     int     `json:"burst"`
+	Unlimited bool    `json:"unlimited"`
+}
+
+// TopicThroughput reports recent activity on a topic
+type TopicThroughput struct {
+	Subscribers int     `json:"subscribers"`
+	Messages    int     `json:"messages"`     // messages held for replay
+	Published1m int     `json:"published_1m"` // publishes in the last minute
+	PublishRate float64 `json:"publish_rate"` // average publishes per second over the last minute
+	Evicted     uint64  `json:"evicted"`      // messages evicted by retention
+	Duplicates  uint64  `json:"duplicates"`   // publishes skipped by the dedup window
+
+	CompactedKeys int `json:"compacted_keys,omitempty"` // keys whose latest message is kept
+
+	IngressQueued int    `json:"ingress_queued,omitempty"` // publishes waiting in the ingress queue
+	Busy          uint64 `json:"busy,omitempty"`           // publishes refused while it was full
+}
+
+type UserTopicsResponse struct {
+	Topics []UserTopic `json:"topics"`
+}
+
 type HealthResponse struct {
 	UptimeSec   int64 `json:"uptime_sec"`
 	Topics      int   `json:"topics"`
 	Subscribers int   `json:"subscribers"`
 }
 
+// PhaseDraining is the readiness phase of a node draining for a maintenance
+// window, besides the pubsub phases
+const PhaseDraining = "draining"
+
+type ReadinessResponse struct {
+	Ready    bool              `json:"ready"`
+	Phase    string            `json:"phase"`
+	Recovery *RecoveryProgress `json:"recovery,omitempty"`
+}
+
+type RecoveryProgress struct {
+	TopicsTotal       int   `json:"topics_total"`
+	TopicsRecovered   int   `json:"topics_recovered"`
+	MessagesRecovered int64 `json:"messages_recovered"`
+}
+
 type TopicStats struct {
-	Messages    int `json:"messages"`
-	Subscribers int `json:"subscribers"`
+	Messages    int           `json:"messages"`
+	Subscribers int           `json:"subscribers"`
+	Cursors     []CursorInfo  `json:"cursors,omitempty"`
+	Payloads    *PayloadStats `json:"payloads,omitempty"`
+
+	Dropped uint64            `json:"dropped"`           // messages dropped for full subscriber queues
+	Clients []SubscriberStats `json:"clients,omitempty"` // per subscriber, sorted by client ID
+}
+
+// SubscriberStats shows how far one subscriber is behind
+type SubscriberStats struct {
+	ClientID  string `json:"client_id"`
+	Durable   bool   `json:"durable"`
+	Delivered uint64 `json:"delivered"` // messages enqueued, or fetched if durable
+	Dropped   uint64 `json:"dropped"`   // live messages dropped because the queue was full
+	Depth     int    `json:"depth"`     // messages queued, or not yet fetched if durable
+	Capacity  int    `json:"capacity"`  // messages the queue holds; 0 if durable
+}
+
+type PayloadStats struct {
+	TotalBytes int64          `json:"total_bytes"`
+	MaxBytes   int            `json:"max_bytes"`
+	Sizes      []SizeBucket   `json:"sizes"`
+	Types      map[string]int `json:"types"`
+}
+
+type SizeBucket struct {
+	LE    string `json:"le"`
+	Count int    `json:"count"`
+}
+
+type CursorInfo struct {
+	ClientID  string `json:"client_id"`
+	Acked     uint64 `json:"acked"`
+	Delivered uint64 `json:"delivered"`
+	Head      uint64 `json:"head"`
+	Lag       uint64 `json:"lag"`
+}
+
+type ReadMarker struct {
+	Topic  string `json:"topic"`
+	Read   uint64 `json:"read"`
+	Head   uint64 `json:"head"`
+	Unread uint64 `json:"unread"`
 }
 
 type StatsResponse struct {
-	Topics map[string]TopicStats `json:"topics"`
+	Topics      map[string]TopicStats `json:"topics"`
+	Replication *replication.Status   `json:"replication,omitempty"` // set when replicating to peer regions
+	Tasks       pubsub.TaskStats      `json:"tasks"`                 // the engine's goroutine budget and its use
+}
+
+type CreateRouteRequest struct {
+	Target    string            `json:"target" binding:"required"`
+	Predicate *pubsub.Predicate `json:"predicate,omitempty"`
+}
+
+type CloneTopicResponse struct {
+	Status   string `json:"status"`
+	Source   string `json:"source"`
+	Topic    string `json:"topic"`
+	Messages int    `json:"messages"` // buffered messages copied
+}
+
+// DeleteMessageResponse reports a deleted message and the tombstone
+// published in its place
+type DeleteMessageResponse struct {
+	Status       string `json:"status"`
+	Topic        string `json:"topic"`
+	MessageID    string `json:"message_id"`
+	TombstoneSeq uint64 `json:"tombstone_seq"`
+}
+
+type RouteInfo struct {
+	ID        string            `json:"id"`
+	Target    string            `json:"target"`
+	Predicate *pubsub.Predicate `json:"predicate,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type CreateRouteResponse struct {
+	Status string    `json:"status"`
+	Topic  string    `json:"topic"`
+	Route  RouteInfo `json:"route"`
+}
+
+type ListRoutesResponse struct {
+	Topic  string      `json:"topic"`
+	Routes []RouteInfo `json:"routes"`
+}
+
+type DeleteRouteResponse struct {
+	Status  string `json:"status"`
+	Topic   string `json:"topic"`
+	RouteID string `json:"route_id"`
+}
+
+type CreateScheduleRequest struct {
+	Cron            string `json:"cron" binding:"required"`
+	PayloadTemplate string `json:"payload_template" binding:"required"`
+}
+
+type ScheduleInfo struct {
+	ID              string    `json:"id"`
+	Cron            string    `json:"cron"`
+	PayloadTemplate string    `json:"payload_template"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastRun         time.Time `json:"last_run,omitempty"`
+	Runs            uint64    `json:"runs"`
+}
+
+type CreateScheduleResponse struct {
+	Status   string       `json:"status"`
+	Topic    string       `json:"topic"`
+	Schedule ScheduleInfo `json:"schedule"`
+}
+
+type ListSchedulesResponse struct {
+	Topic     string         `json:"topic"`
+	Schedules []ScheduleInfo `json:"schedules"`
+}
+
+type DeleteScheduleResponse struct {
+	Status     string `json:"status"`
+	Topic      string `json:"topic"`
+	ScheduleID string `json:"schedule_id"`
 }
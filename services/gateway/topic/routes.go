@@ -22,10 +22,40 @@ func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
 	authGroup.POST("/topics", r.endpoint.CreateTopic)
 	authGroup.DELETE("/topics/:name", r.endpoint.DeleteTopic)
 	authGroup.GET("/topics", r.endpoint.ListTopics)
+	authGroup.GET("/topics/:name", r.endpoint.GetTopic)
+	authGroup.GET("/topics/:name/history", r.endpoint.GetTopicHistory)
+	authGroup.GET("/users/topics", r.endpoint.ListUserTopics)
+	authGroup.POST("/topics/:name/routes", r.endpoint.CreateRoute)
+	authGroup.GET("/topics/:name/routes", r.endpoint.ListRoutes)
+	authGroup.DELETE("/topics/:name/routes/:id", r.endpoint.DeleteRoute)
+	authGroup.GET("/topics/:name/read-marker", r.endpoint.GetReadMarker)
+	authGroup.DELETE("/topics/:name/messages/:id", r.endpoint.DeleteMessage)
+	authGroup.POST("/topics/:name/clone", r.endpoint.CloneTopic)
+	authGroup.PUT("/topics/:name/replay-rate", r.endpoint.SetReplayRate)
+	authGroup.PUT("/topics/:name/expiry", r.endpoint.SetExpiry)
+	authGroup.PUT("/topics/:name/retention", r.endpoint.SetRetention)
+	authGroup.PUT("/topics/:name/decoding", r.endpoint.SetDecoding)
+	authGroup.PUT("/topics/:name/ordering", r.endpoint.SetOrdering)
+	authGroup.PUT("/topics/:name/delivery", r.endpoint.SetDelivery)
+	authGroup.PUT("/topics/:name/metadata", r.endpoint.SetMetadata)
+	authGroup.PUT("/topics/:name/partitions", r.endpoint.SetPartitions)
+	authGroup.GET("/topics/:name/partitions", r.endpoint.GetPartitions)
+	authGroup.PUT("/topics/:name/compaction", r.endpoint.SetCompaction)
+	authGroup.PUT("/topics/:name/ingress", r.endpoint.SetIngress)
+	authGroup.PUT("/topics/:name/backpressure", r.endpoint.SetBackpressure)
+	authGroup.PUT("/topics/:name/dedup", r.endpoint.SetDedup)
+	authGroup.PUT("/topics/:name/schema", r.endpoint.RegisterSchema)
+	authGroup.GET("/topics/:name/schemas", r.endpoint.ListSchemas)
+	authGroup.PUT("/topics/:name/indexes", r.endpoint.SetIndexes)
+	authGroup.GET("/topics/:name/messages", r.endpoint.FindMessages)
+	authGroup.POST("/topics/:name/schedules", r.endpoint.CreateSchedule)
+	authGroup.GET("/topics/:name/schedules", r.endpoint.ListSchedules)
+	authGroup.DELETE("/topics/:name/schedules/:id", r.endpoint.DeleteSchedule)
 }
 
 // RegisterUnAuthRoutes registers unauthenticated routes
 func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
 	unAuthGroup.GET("/health", r.endpoint.GetHealth)
+	unAuthGroup.GET("/ready", r.endpoint.GetReadiness)
 	unAuthGroup.GET("/stats", r.endpoint.GetStats)
 }
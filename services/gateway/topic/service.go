@@ -2,61 +2,328 @@ package topic
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/limits"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/replication"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
 )
 
 // service implements the Service interface
 type Service interface {
-	CreateTopic(name string) error
-	DeleteTopic(name string) error
-	ListTopics() ([]TopicInfo, error)
+	CreateTopic(name, owner string, expiresAt time.Time, metadata pubsub.Metadata, opts pubsub.TopicOptions) error
+	CloneTopic(source, target, owner string, withHistory bool) (CloneTopicResponse, error)
+	DeleteTopic(name, userID string) error
+	ListTopics(prefix string, labels []string) ([]TopicInfo, error)
+	GetTopic(name string) (TopicInfo, error)
+	TopicHistory(name string) (TopicHistoryResponse, error)
+	ListUserTopics(userID string) ([]UserTopic, error)
 	GetHealth() (HealthResponse, error)
+	GetReadiness() (ReadinessResponse, error)
 	GetStats() (StatsResponse, error)
+	AddRoute(name string, req CreateRouteRequest, userID string) (RouteInfo, error)
+	ListRoutes(name string) ([]RouteInfo, error)
+	DeleteRoute(name, routeID, userID string) error
+	DeleteMessage(name, messageID, userID string) (DeleteMessageResponse, error)
+	GetReadMarker(name, userID string) (ReadMarker, error)
+	SetReplayRate(name string, rate float64, userID string) error
+	SetExpiry(name string, expiresAt time.Time, userID string) error
+	SetRetention(name string, retention Retention, userID string) error
+	SetDecoding(name string, decoding Decoding, userID string) error
+	SetOrdering(name, mode, userID string) error
+	SetDelivery(name, mode, userID string) error
+	SetMetadata(name string, metadata pubsub.Metadata, userID string) error
+	SetPartitions(name string, count int, userID string) error
+	SetCompaction(name string, compaction pubsub.Compaction, userID string) error
+	SetIngress(name string, ingress pubsub.Ingress, userID string) error
+	GetPartitions(name string) ([]pubsub.PartitionInfo, error)
+	SetBackpressure(name string, backpressure pubsub.Backpressure, userID string) error
+	SetDedup(name string, dedup pubsub.Dedup, userID string) error
+	RegisterSchema(name string, req RegisterSchemaRequest, userID string) (pubsub.Schema, error)
+	ListSchemas(name string) (*pubsub.Schemas, error)
+	SetHeaderIndexes(name string, keys []string, userID string) error
+	FindMessages(name string, headers map[string]string, limit int) ([]*pubsub.Message, error)
+	MessagesETag(name string, headers map[string]string, limit int) (string, error)
+	AddSchedule(name string, req CreateScheduleRequest, userID string) (ScheduleInfo, error)
+	ListSchedules(name string) ([]ScheduleInfo, error)
+	DeleteSchedule(name, scheduleID, userID string) error
 }
 type service struct {
-	pubsubService pubsub.Service
+	pubsubService     pubsub.Service
+	subscriptionStore SubscriptionStore
+	limiter           limits.Service
+	replication       ReplicationStatus
+	events            events.Bus
+	draining          atomic.Bool // during a maintenance window
 }
 
-// NewService creates a new topic service
-func NewService() Service {
-	return &service{
-		pubsubService: pubsub.GetService(),
+// SubscriptionStore provides a user's saved subscriptions
+type SubscriptionStore interface {
+	GetSavedSubscriptions(userID string) ([]user.SavedSubscription, error)
+}
+
+// ReplicationStatus reports replication lag to peer regions
+type ReplicationStatus interface {
+	Status() *replication.Status
+}
+
+// NewService creates a new topic service that announces created and deleted
+// topics on bus, and reports the node not ready while bus says it drains for
+// maintenance. replicationStatus may be nil when the gateway does not
+// replicate.
+func NewService(subscriptionStore SubscriptionStore, limiter limits.Service, replicationStatus ReplicationStatus, bus events.Bus) Service {
+	s := &service{
+		pubsubService:     pubsub.GetService(),
+		subscriptionStore: subscriptionStore,
+		limiter:           limiter,
+		replication:       replicationStatus,
+		events:            bus,
 	}
+
+	bus.Subscribe(events.KindMaintenanceStarted, "topic", func(context.Context, events.Event) {
+		s.draining.Store(true)
+	})
+	bus.Subscribe(events.KindMaintenanceEnded, "topic", func(context.Context, events.Event) {
+		s.draining.Store(false)
+	})
+
+	return s
 }
 
-// CreateTopic creates a new topic
-func (s *service) CreateTopic(name string) error {
-	ctx := context.Background()
-	return s.pubsubService.CreateTopic(ctx, name)
+// CreateTopic creates a new topic owned by the given user with buffer
+// sizes from opts, deleted automatically at expiresAt unless it is zero
+func (s *service) CreateTopic(name, owner string, expiresAt time.Time, metadata pubsub.Metadata, opts pubsub.TopicOptions) error {
+	ctx := pubsub.WithActor(context.Background(), owner)
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return fmt.Errorf("invalid expiry: expires_at %s is not in the future", expiresAt.Format(time.RFC3339))
+	}
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.pubsubService.CreateTopic(ctx, name, owner, &opts); err != nil {
+		return err
+	}
+	if !expiresAt.IsZero() {
+		if err := s.pubsubService.SetExpiry(ctx, name, expiresAt); err != nil {
+			return err
+		}
+	}
+	if metadata.Description != "" || len(metadata.Labels) > 0 {
+		if err := s.pubsubService.SetMetadata(ctx, name, metadata); err != nil {
+			return err
+		}
+	}
+
+	s.events.Publish(ctx, events.Event{Kind: events.KindTopicCreated, Subject: name})
+	return nil
+}
+
+// CloneTopic creates target from source's configuration and, optionally,
+// its buffered messages
+func (s *service) CloneTopic(source, target, owner string, withHistory bool) (CloneTopicResponse, error) {
+	ctx := pubsub.WithActor(context.Background(), owner)
+	copied, err := s.pubsubService.CloneTopic(ctx, source, target, owner, withHistory)
+	if err != nil {
+		return CloneTopicResponse{}, err
+	}
+
+	s.events.Publish(ctx, events.Event{Kind: events.KindTopicCreated, Subject: target})
+
+	return CloneTopicResponse{
+		Status:   "cloned",
+		Source:   source,
+		Topic:    target,
+		Messages: copied,
+	}, nil
 }
 
 // DeleteTopic deletes a topic
-func (s *service) DeleteTopic(name string) error {
+func (s *service) DeleteTopic(name, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	if err := s.pubsubService.DeleteTopic(ctx, name); err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, events.Event{Kind: events.KindTopicDeleted, Subject: name})
+	return nil
+}
+
+// TopicHistory returns the lifecycle events of a topic, which may have been
+// deleted
+func (s *service) TopicHistory(name string) (TopicHistoryResponse, error) {
 	ctx := context.Background()
-	return s.pubsubService.DeleteTopic(ctx, name)
+	history, err := s.pubsubService.TopicHistory(ctx, name)
+	if err != nil {
+		return TopicHistoryResponse{}, err
+	}
+
+	return TopicHistoryResponse{
+		Topic:  name,
+		Exists: history[len(history)-1].Action != pubsub.HistoryDeleted && history[len(history)-1].Action != pubsub.HistoryExpired,
+		Events: history,
+		Count:  len(history),
+	}, nil
 }
 
-// ListTopics returns all topics
-func (s *service) ListTopics() ([]TopicInfo, error) {
+// ListTopics returns the topics in the namespace prefix, or all topics when
+// it is empty
+func (s *service) ListTopics(prefix string, labels []string) ([]TopicInfo, error) {
 	ctx := context.Background()
-	pubsubTopics, err := s.pubsubService.ListTopics(ctx)
+	pubsubTopics, err := s.pubsubService.ListTopics(ctx, prefix)
 	if err != nil {
 		return nil, err
 	}
 
 	// Convert pubsub.TopicInfo to local TopicInfo
-	topics := make([]TopicInfo, len(pubsubTopics))
-	for i, topic := range pubsubTopics {
-		topics[i] = TopicInfo{
-			Name:        topic.Name,
-			Subscribers: topic.Subscribers,
+	topics := make([]TopicInfo, 0, len(pubsubTopics))
+	for _, topic := range pubsubTopics {
+		if pubsub.MatchLabels(topic.Labels, labels) {
+			topics = append(topics, topicInfo(topic))
 		}
 	}
 
 	return topics, nil
 }
 
+// GetTopic returns one topic's information
+func (s *service) GetTopic(name string) (TopicInfo, error) {
+	pubsubTopics, err := s.pubsubService.ListTopics(context.Background(), name)
+	if err != nil {
+		return TopicInfo{}, err
+	}
+	for _, topic := range pubsubTopics {
+		if topic.Name == name {
+			return topicInfo(topic), nil
+		}
+	}
+	return TopicInfo{}, fmt.Errorf("topic %s not found", name)
+}
+
+// topicInfo converts pubsub.TopicInfo to local TopicInfo
+func topicInfo(topic pubsub.TopicInfo) TopicInfo {
+	return TopicInfo{
+		Name:        topic.Name,
+		Subscribers: topic.Subscribers,
+		Owner:       topic.Owner,
+		CreatedAt:   topic.CreatedAt,
+		ExpiresAt:   topic.ExpiresAt,
+		Description: topic.Description,
+		Labels:      topic.Labels,
+		JSONSchema:  topic.JSONSchema,
+	}
+}
+
+// ListUserTopics returns the topics the user owns, is subscribed to or has
+// saved, with their configuration, publish quota and recent throughput
+func (s *service) ListUserTopics(userID string) ([]UserTopic, error) {
+	ctx := context.Background()
+	pubsubTopics, err := s.pubsubService.ListTopics(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	relations := make(map[string][]string)
+	subscribed, err := s.pubsubService.ListClientTopics(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range subscribed {
+		relations[name] = append(relations[name], RelationSubscriber)
+	}
+	if s.subscriptionStore != nil {
+		saved, err := s.subscriptionStore.GetSavedSubscriptions(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range saved {
+			relations[sub.Topic] = append(relations[sub.Topic], RelationSaved)
+		}
+	}
+
+	topics := make([]UserTopic, 0)
+	for _, topic := range pubsubTopics {
+		topicRelations := relations[topic.Name]
+		if topic.Owner != "" && topic.Owner == userID {
+			topicRelations = append([]string{RelationOwner}, topicRelations...)
+		}
+		if len(topicRelations) == 0 {
+			continue
+		}
+
+		userTopic := UserTopic{
+			Name:      topic.Name,
+			Relations: topicRelations,
+			Owner:     topic.Owner,
+			CreatedAt: topic.CreatedAt,
+			ExpiresAt: topic.ExpiresAt,
+
+			Description: topic.Description,
+			Labels:      topic.Labels,
+
+			Config: TopicConfig{
+				Routes:     topic.Routes,
+				Schedules:  topic.Schedules,
+				ReplayRate: topic.ReplayRate,
+				Decoding:   Decoding(topic.Decoding),
+				Ordering:   topic.Ordering,
+				Delivery:   topic.Delivery,
+				Indexes:    topic.HeaderIndexes,
+
+				RingBufferSize:    topic.RingBufferSize,
+				ChannelBufferSize: topic.ChannelBufferSize,
+
+				JSONSchema: topic.JSONSchema,
+
+				Retention: (*Retention)(topic.Retention),
+
+				Backpressure: topic.Backpressure,
+
+				Dedup: topic.Dedup,
+
+				LegalHold: topic.LegalHold,
+
+				Partitions: topic.Partitions,
+
+				TextIndex: topic.TextIndex,
+
+				Compaction: topic.Compaction,
+
+				Ingress: topic.Ingress,
+			},
+			Throughput: TopicThroughput{
+				Subscribers: topic.Subscribers,
+				Messages:    topic.Messages,
+				Published1m: topic.Published1m,
+				PublishRate: float64(topic.Published1m) / pubsub.ThroughputWindow.Seconds(),
+				Evicted:     topic.Evicted,
+				Duplicates:  topic.Duplicates,
+
+				CompactedKeys: topic.CompactedKeys,
+
+				IngressQueued: topic.IngressQueued,
+				Busy:          topic.Busy,
+			},
+		}
+		if s.limiter != nil {
+			quota := s.limiter.Effective(limits.ScopeTopic, topic.Name)
+			userTopic.Quota = TopicQuota{
+				Rate:      quota.Rate,
+				Burst:     quota.Burst,
+				Unlimited: quota.Unlimited,
+			}
+		}
+
+		topics = append(topics, userTopic)
+	}
+
+	return topics, nil
+}
+
 // GetHealth returns service health
 func (s *service) GetHealth() (HealthResponse, error) {
 	ctx := context.Background()
@@ -72,6 +339,34 @@ func (s *service) GetHealth() (HealthResponse, error) {
 	}, nil
 }
 
+// GetReadiness returns whether the node has finished starting up and is not
+// draining for maintenance
+func (s *service) GetReadiness() (ReadinessResponse, error) {
+	ctx := context.Background()
+	pubsubReadiness, err := s.pubsubService.GetReadiness(ctx)
+	if err != nil {
+		return ReadinessResponse{}, err
+	}
+
+	readiness := ReadinessResponse{
+		Ready: pubsubReadiness.Ready,
+		Phase: pubsubReadiness.Phase,
+	}
+	if readiness.Ready && s.draining.Load() {
+		readiness.Ready = false
+		readiness.Phase = PhaseDraining
+	}
+	if pubsubReadiness.Recovery != nil {
+		readiness.Recovery = &RecoveryProgress{
+			TopicsTotal:       pubsubReadiness.Recovery.TopicsTotal,
+			TopicsRecovered:   pubsubReadiness.Recovery.TopicsRecovered,
+			MessagesRecovered: pubsubReadiness.Recovery.MessagesRecovered,
+		}
+	}
+
+	return readiness, nil
+}
+
 // GetStats returns service statistics
 func (s *service) GetStats() (StatsResponse, error) {
 	ctx := context.Background()
@@ -83,14 +378,312 @@ func (s *service) GetStats() (StatsResponse, error) {
 	// Convert pubsub.StatsResponse to local StatsResponse
 	stats := StatsResponse{
 		Topics: make(map[string]TopicStats),
+		Tasks:  pubsubStats.Tasks,
 	}
 
 	for name, topicStats := range pubsubStats.Topics {
+		var cursors []CursorInfo
+		for _, cursor := range topicStats.Cursors {
+			cursors = append(cursors, CursorInfo{
+				ClientID:  cursor.ClientID,
+				Acked:     cursor.Acked,
+				Delivered: cursor.Delivered,
+				Head:      cursor.Head,
+				Lag:       cursor.Lag,
+			})
+		}
+
+		var payloads *PayloadStats
+		if topicStats.Payloads != nil {
+			payloads = &PayloadStats{
+				TotalBytes: topicStats.Payloads.TotalBytes,
+				MaxBytes:   topicStats.Payloads.MaxBytes,
+				Types:      topicStats.Payloads.Types,
+			}
+			for _, bucket := range topicStats.Payloads.Sizes {
+				payloads.Sizes = append(payloads.Sizes, SizeBucket{LE: bucket.LE, Count: bucket.Count})
+			}
+		}
+
+		var clients []SubscriberStats
+		for _, client := range topicStats.Clients {
+			clients = append(clients, SubscriberStats{
+				ClientID:  client.ClientID,
+				Durable:   client.Durable,
+				Delivered: client.Delivered,
+				Dropped:   client.Dropped,
+				Depth:     client.Depth,
+				Capacity:  client.Capacity,
+			})
+		}
+
 		stats.Topics[name] = TopicStats{
 			Messages:    topicStats.Messages,
 			Subscribers: topicStats.Subscribers,
+			Cursors:     cursors,
+			Payloads:    payloads,
+			Dropped:     topicStats.Dropped,
+			Clients:     clients,
 		}
 	}
 
+	if s.replication != nil {
+		stats.Replication = s.replication.Status()
+	}
+
 	return stats, nil
 }
+
+// AddRoute adds a content-based route from a topic to another topic
+func (s *service) AddRoute(name string, req CreateRouteRequest, userID string) (RouteInfo, error) {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	route, err := s.pubsubService.AddRoute(ctx, name, &pubsub.Route{
+		Target:    req.Target,
+		Predicate: req.Predicate,
+	})
+	if err != nil {
+		return RouteInfo{}, err
+	}
+
+	return toRouteInfo(*route), nil
+}
+
+// ListRoutes returns the routes of a topic
+func (s *service) ListRoutes(name string) ([]RouteInfo, error) {
+	ctx := context.Background()
+	pubsubRoutes, err := s.pubsubService.ListRoutes(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]RouteInfo, len(pubsubRoutes))
+	for i, route := range pubsubRoutes {
+		routes[i] = toRouteInfo(route)
+	}
+
+	return routes, nil
+}
+
+// GetReadMarker returns how far the user has read the topic
+func (s *service) GetReadMarker(name, userID string) (ReadMarker, error) {
+	ctx := context.Background()
+	marker, err := s.pubsubService.GetReadMarker(ctx, name, userID)
+	if err != nil {
+		return ReadMarker{}, err
+	}
+
+	return ReadMarker{
+		Topic:  name,
+		Read:   marker.Read,
+		Head:   marker.Head,
+		Unread: marker.Unread,
+	}, nil
+}
+
+// SetReplayRate sets the topic's maximum replay rate
+func (s *service) SetReplayRate(name string, rate float64, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetReplayRate(ctx, name, rate)
+}
+
+// SetExpiry schedules the topic's automatic deletion; a zero time cancels it
+func (s *service) SetExpiry(name string, expiresAt time.Time, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetExpiry(ctx, name, expiresAt)
+}
+
+// SetRetention sets the topic's message eviction by age and count
+func (s *service) SetRetention(name string, retention Retention, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetRetention(ctx, name, pubsub.Retention(retention))
+}
+
+// SetDecoding sets the topic's payload number mode and strictness
+func (s *service) SetDecoding(name string, decoding Decoding, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetDecoding(ctx, name, pubsub.Decoding(decoding))
+}
+
+// SetOrdering switches the topic between default and strict FIFO ordering
+func (s *service) SetOrdering(name, mode, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetOrdering(ctx, name, mode)
+}
+
+// SetDelivery switches the topic between delivering every message to every
+// connection and to one connection per user
+func (s *service) SetDelivery(name, mode, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetDelivery(ctx, name, mode)
+}
+
+// SetMetadata replaces the topic's description and labels
+func (s *service) SetMetadata(name string, metadata pubsub.Metadata, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetMetadata(ctx, name, metadata)
+}
+
+// SetPartitions splits the topic into count partitions keyed by message
+// key, or merges it back with 0
+func (s *service) SetPartitions(name string, count int, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetPartitions(ctx, name, count)
+}
+
+// SetCompaction makes the topic keep the latest message of each key, or not
+func (s *service) SetCompaction(name string, compaction pubsub.Compaction, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetCompaction(ctx, name, compaction)
+}
+
+// SetIngress sets the queue smoothing bursts of publishes to the topic
+func (s *service) SetIngress(name string, ingress pubsub.Ingress, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetIngress(ctx, name, ingress)
+}
+
+// GetPartitions reports the topic's partitions
+func (s *service) GetPartitions(name string) ([]pubsub.PartitionInfo, error) {
+	return s.pubsubService.GetPartitions(context.Background(), name)
+}
+
+// SetBackpressure sets the full queue policy of the topic's subscriptions
+// without their own
+func (s *service) SetBackpressure(name string, backpressure pubsub.Backpressure, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetBackpressure(ctx, name, backpressure)
+}
+
+// RegisterSchema adds a schema version to the topic, returning it, or the
+// latest version when its fields are unchanged
+func (s *service) RegisterSchema(name string, req RegisterSchemaRequest, userID string) (pubsub.Schema, error) {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	schema, err := s.pubsubService.RegisterSchema(ctx, name, req.Fields, req.Compatibility)
+	if err != nil {
+		return pubsub.Schema{}, err
+	}
+	return *schema, nil
+}
+
+// ListSchemas returns the topic's schema versions, oldest first
+func (s *service) ListSchemas(name string) (*pubsub.Schemas, error) {
+	return s.pubsubService.ListSchemas(context.Background(), name)
+}
+
+// SetDedup sets the topic's window of message IDs published at most once
+func (s *service) SetDedup(name string, dedup pubsub.Dedup, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetDedup(ctx, name, dedup)
+}
+
+// SetHeaderIndexes sets the header keys the topic indexes for lookups
+func (s *service) SetHeaderIndexes(name string, keys []string, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.SetHeaderIndexes(ctx, name, keys)
+}
+
+// FindMessages returns up to limit of the topic's newest buffered messages
+// carrying every one of the headers
+func (s *service) FindMessages(name string, headers map[string]string, limit int) ([]*pubsub.Message, error) {
+	ctx := context.Background()
+	return s.pubsubService.FindMessages(ctx, name, headers, limit)
+}
+
+// MessagesETag returns the entity tag of a FindMessages lookup, which
+// changes whenever its result may
+func (s *service) MessagesETag(name string, headers map[string]string, limit int) (string, error) {
+	topic, err := s.pubsubService.GetTopic(context.Background(), name)
+	if err != nil {
+		return "", err
+	}
+	return messagesETag(topic.Messages.LastSeq(), topic.Messages.Count(), topic.Messages.Indexes(), headers, limit), nil
+}
+
+// DeleteMessage removes a message from a topic and publishes a tombstone.
+// Only the topic's owner may delete messages.
+func (s *service) DeleteMessage(name, messageID, userID string) (DeleteMessageResponse, error) {
+	ctx := context.Background()
+	topic, err := s.pubsubService.GetTopic(ctx, name)
+	if err != nil {
+		return DeleteMessageResponse{}, err
+	}
+	if topic.Owner == "" || topic.Owner != userID {
+		return DeleteMessageResponse{}, fmt.Errorf("forbidden: only the owner of topic %s may delete messages", name)
+	}
+
+	tombstone, err := s.pubsubService.DeleteMessage(ctx, name, messageID)
+	if err != nil {
+		return DeleteMessageResponse{}, err
+	}
+
+	return DeleteMessageResponse{
+		Status:       "deleted",
+		Topic:        name,
+		MessageID:    messageID,
+		TombstoneSeq: tombstone.Seq,
+	}, nil
+}
+
+// DeleteRoute removes a route from a topic
+func (s *service) DeleteRoute(name, routeID, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.DeleteRoute(ctx, name, routeID)
+}
+
+// toRouteInfo converts pubsub.Route to local RouteInfo
+func toRouteInfo(route pubsub.Route) RouteInfo {
+	return RouteInfo{
+		ID:        route.ID,
+		Target:    route.Target,
+		Predicate: route.Predicate,
+		CreatedAt: route.CreatedAt,
+	}
+}
+
+// AddSchedule adds a cron-driven publisher to a topic
+func (s *service) AddSchedule(name string, req CreateScheduleRequest, userID string) (ScheduleInfo, error) {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	schedule, err := s.pubsubService.AddSchedule(ctx, name, &pubsub.Schedule{
+		Cron:            req.Cron,
+		PayloadTemplate: req.PayloadTemplate,
+	})
+	if err != nil {
+		return ScheduleInfo{}, err
+	}
+
+	return toScheduleInfo(*schedule), nil
+}
+
+// ListSchedules returns the schedules of a topic
+func (s *service) ListSchedules(name string) ([]ScheduleInfo, error) {
+	ctx := context.Background()
+	pubsubSchedules, err := s.pubsubService.ListSchedules(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]ScheduleInfo, len(pubsubSchedules))
+	for i, schedule := range pubsubSchedules {
+		schedules[i] = toScheduleInfo(schedule)
+	}
+
+	return schedules, nil
+}
+
+// DeleteSchedule removes a schedule from a topic
+func (s *service) DeleteSchedule(name, scheduleID, userID string) error {
+	ctx := pubsub.WithActor(context.Background(), userID)
+	return s.pubsubService.DeleteSchedule(ctx, name, scheduleID)
+}
+
+// toScheduleInfo converts pubsub.Schedule to local ScheduleInfo
+func toScheduleInfo(schedule pubsub.Schedule) ScheduleInfo {
+	return ScheduleInfo{
+		ID:              schedule.ID,
+		Cron:            schedule.Cron,
+		PayloadTemplate: schedule.PayloadTemplate,
+		CreatedAt:       schedule.CreatedAt,
+		LastRun:         schedule.LastRun,
+		Runs:            schedule.Runs,
+	}
+}
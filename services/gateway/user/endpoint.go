@@ -2,6 +2,7 @@ package user
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/ammysap/plivo-pub-sub/services/gateway/logger"
 	"github.com/gin-gonic/gin"
@@ -12,6 +13,10 @@ type Endpoint interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
 	GetProfile(c *gin.Context)
+	DeleteProfile(c *gin.Context)
+	GetSavedSubscriptions(c *gin.Context)
+	SaveSubscriptions(c *gin.Context)
+	GetSubscriptionStats(c *gin.Context)
 }
 type endpoint struct {
 	service Service
@@ -41,8 +46,13 @@ func (e *endpoint) Register(c *gin.Context) {
 	}
 
 	// Register user
-	user, err := e.service.Register(req.Username, req.Password)
+	user, err := e.service.Register(req.Username, req.Password, req.Email)
 	if err != nil {
+		if err.Error() == "invalid email" {
+			log.Warnw("Invalid email", "username", req.Username)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+			return
+		}
 		if err.Error() == "username already exists" {
 			log.Warnw("Username already exists", "username", req.Username)
 			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
@@ -95,6 +105,11 @@ func (e *endpoint) Login(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 			return
 		}
+		if err.Error() == "account disabled" {
+			log.Warnw("Login to disabled account", "username", req.Username)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+			return
+		}
 		log.Errorw("Error logging in user", "error", err.Error(), "username", req.Username)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login user"})
 		return
@@ -162,3 +177,140 @@ func (e *endpoint) GetProfile(c *gin.Context) {
 	log.Infow("User profile retrieved successfully", "user_id", user.ID, "username", user.Username)
 	c.JSON(http.StatusOK, response)
 }
+
+// DeleteProfile handles DELETE /users/profile, deleting the caller's account
+func (e *endpoint) DeleteProfile(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		log.Errorw("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := e.service.DeleteUser(userID); err != nil {
+		if err.Error() == "user not found" {
+			log.Warnw("User not found", "user_id", userID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Errorw("Error deleting user", "error", err.Error(), "user_id", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	log.Infow("User deleted", "user_id", userID)
+	c.JSON(http.StatusOK, DeleteProfileResponse{Status: "deleted", UserID: userID})
+}
+
+// GetSavedSubscriptions handles GET /users/subscriptions
+func (e *endpoint) GetSavedSubscriptions(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		log.Errorw("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	subscriptions, err := e.service.GetSavedSubscriptions(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			log.Warnw("User not found", "user_id", userID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Errorw("Error getting saved subscriptions", "error", err.Error(), "user_id", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get saved subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SavedSubscriptionsResponse{Subscriptions: subscriptions})
+}
+
+// GetSubscriptionStats handles GET /users/subscriptions/stats
+func (e *endpoint) GetSubscriptionStats(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		log.Errorw("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	stats, err := e.service.GetSubscriptionStats(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			log.Warnw("User not found", "user_id", userID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Errorw("Error getting subscription stats", "error", err.Error(), "user_id", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscription stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SubscriptionStatsResponse{Subscriptions: stats})
+}
+
+// SaveSubscriptions handles PUT /users/subscriptions
+func (e *endpoint) SaveSubscriptions(c *gin.Context) {
+	_, log, err := logger.GetLoggerFromGinContext(c)
+	if err != nil {
+		log.Errorw("Error getting logger from gin context", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		log.Errorw("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SaveSubscriptionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = e.service.SaveSubscriptions(userID, req.Subscriptions)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid subscription") {
+			log.Warnw("Invalid saved subscriptions", "error", err.Error(), "user_id", userID)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "user not found" {
+			log.Warnw("User not found", "user_id", userID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Errorw("Error saving subscriptions", "error", err.Error(), "user_id", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subscriptions"})
+		return
+	}
+
+	log.Infow("Saved subscriptions updated", "user_id", userID, "count", len(req.Subscriptions))
+	c.JSON(http.StatusOK, SavedSubscriptionsResponse{Subscriptions: req.Subscriptions})
+}
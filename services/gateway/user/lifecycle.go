@@ -0,0 +1,269 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/notifier"
+)
+
+// Account lifecycle defaults
+const (
+	DefaultLifecycleInterval = time.Hour
+	// ActivityResolution is how stale LastActiveAt gets before a request
+	// updates it, so busy users do not contend on the user lock
+	ActivityResolution = time.Minute
+)
+
+// LifecycleConfig controls how inactive accounts are disabled and purged
+type LifecycleConfig struct {
+	DisableAfter time.Duration // inactivity before an account is disabled; 0 never disables
+	PurgeAfter   time.Duration // time disabled before an account is deleted; 0 never purges
+	Interval     time.Duration // how often accounts are checked
+	ExemptUsers  []string      // user IDs never disabled or purged, such as admins
+}
+
+// Enabled reports whether any policy is configured
+func (c *LifecycleConfig) Enabled() bool {
+	return c.DisableAfter > 0 || c.PurgeAfter > 0
+}
+
+// LoadLifecycleConfig reads USER_DISABLE_AFTER_DAYS, USER_PURGE_AFTER_DAYS
+// and USER_LIFECYCLE_INTERVAL. Both policies are off by default.
+func LoadLifecycleConfig() (*LifecycleConfig, error) {
+	config := &LifecycleConfig{Interval: DefaultLifecycleInterval}
+
+	readDays := func(name string) (time.Duration, error) {
+		value := os.Getenv(name)
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid %s %q: expected a non-negative number of days", name, value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	var err error
+	if config.DisableAfter, err = readDays("USER_DISABLE_AFTER_DAYS"); err != nil {
+		return nil, err
+	}
+	if config.PurgeAfter, err = readDays("USER_PURGE_AFTER_DAYS"); err != nil {
+		return nil, err
+	}
+	if config.PurgeAfter > 0 && config.DisableAfter == 0 {
+		return nil, fmt.Errorf("invalid USER_PURGE_AFTER_DAYS: purging needs USER_DISABLE_AFTER_DAYS, as only disabled accounts are purged")
+	}
+
+	if value := os.Getenv("USER_LIFECYCLE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid USER_LIFECYCLE_INTERVAL %q: expected a positive duration such as 1h", value)
+		}
+		config.Interval = interval
+	}
+	return config, nil
+}
+
+// RecordActivity marks a user active now. It fails with "account disabled"
+// for disabled users; unknown users are ignored, as their tokens stay valid
+// after deletion.
+func (s *service) RecordActivity(userID string) error {
+	now := time.Now()
+
+	s.mu.RLock()
+	user, exists := s.usersByID[userID]
+	stale := exists && now.Sub(user.LastActiveAt) >= ActivityResolution
+	disabled := exists && user.DisabledAt != nil
+	s.mu.RUnlock()
+
+	if disabled {
+		return fmt.Errorf("account disabled")
+	}
+	if stale {
+		s.mu.Lock()
+		user.LastActiveAt = now
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// EnableUser re-enables a disabled account, counting as activity so it is
+// not disabled again on the next check
+func (s *service) EnableUser(userID string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+	user.DisabledAt = nil
+	user.LastActiveAt = time.Now()
+	user.UpdatedAt = user.LastActiveAt
+
+	return user, nil
+}
+
+// SetLifecycleExempt exempts an account from being disabled or purged, or
+// lifts the exemption
+func (s *service) SetLifecycleExempt(userID string, exempt bool) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+	user.LifecycleExempt = exempt
+	user.UpdatedAt = time.Now()
+
+	return user, nil
+}
+
+// StartLifecycle checks accounts against config every config.Interval until
+// ctx is done, notifying users through n as their accounts are disabled and
+// purged. It does nothing unless a policy is configured.
+func (s *service) StartLifecycle(ctx context.Context, config *LifecycleConfig, n notifier.Notifier) {
+	if !config.Enabled() {
+		return
+	}
+
+	logging.WithContext(ctx).Infow("Starting account lifecycle checks",
+		"disable_after", config.DisableAfter, "purge_after", config.PurgeAfter, "interval", config.Interval)
+
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.applyLifecycle(ctx, config, n, now)
+			}
+		}
+	}()
+}
+
+// applyLifecycle disables accounts inactive for config.DisableAfter and
+// deletes accounts disabled for config.PurgeAfter. Users with live
+// subscriptions count as active.
+func (s *service) applyLifecycle(ctx context.Context, config *LifecycleConfig, n notifier.Notifier, now time.Time) {
+	log := logging.WithContext(ctx)
+
+	exempt := make(map[string]bool, len(config.ExemptUsers))
+	for _, userID := range config.ExemptUsers {
+		exempt[strings.TrimSpace(userID)] = true
+	}
+
+	var inactive, expired []string
+	s.mu.RLock()
+	for userID, user := range s.usersByID {
+		if user.LifecycleExempt || exempt[userID] {
+			continue
+		}
+		switch {
+		case user.DisabledAt == nil:
+			if config.DisableAfter > 0 && now.Sub(user.LastActiveAt) >= config.DisableAfter {
+				inactive = append(inactive, userID)
+			}
+		case config.PurgeAfter > 0 && now.Sub(*user.DisabledAt) >= config.PurgeAfter:
+			expired = append(expired, userID)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, userID := range inactive {
+		stats, err := s.pubsubService.ListSubscriptionStats(ctx, userID)
+		if err == nil && len(stats) > 0 {
+			s.RecordActivity(userID)
+			continue
+		}
+
+		user, disabled := s.disable(userID, config.DisableAfter, now)
+		if !disabled {
+			continue
+		}
+		s.events.Publish(ctx, events.Event{Kind: events.KindUserDisabled, Subject: userID})
+		log.Infow("Disabled inactive account", "user_id", userID, "last_active_at", user.LastActiveAt)
+
+		body := fmt.Sprintf("Your account %s has been disabled after %d days without activity.", user.Username, days(config.DisableAfter))
+		if config.PurgeAfter > 0 {
+			body += fmt.Sprintf(" It will be deleted in %d days unless an administrator re-enables it.", days(config.PurgeAfter))
+		}
+		s.notify(ctx, n, user, "Your account has been disabled", body)
+	}
+
+	for _, userID := range expired {
+		user, purged := s.purge(userID, config.PurgeAfter, now)
+		if !purged {
+			continue
+		}
+		s.events.Publish(ctx, events.Event{Kind: events.KindUserDeleted, Subject: userID})
+		log.Infow("Purged disabled account", "user_id", userID, "disabled_at", user.DisabledAt)
+
+		body := fmt.Sprintf("Your account %s has been deleted after being disabled for %d days.", user.Username, days(config.PurgeAfter))
+		s.notify(ctx, n, user, "Your account has been deleted", body)
+	}
+}
+
+// disable disables an account still inactive for inactivity at now,
+// returning a copy of it
+func (s *service) disable(userID string, inactivity time.Duration, now time.Time) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[userID]
+	if !exists || user.DisabledAt != nil || user.LifecycleExempt || now.Sub(user.LastActiveAt) < inactivity {
+		return User{}, false
+	}
+	user.DisabledAt = &now
+	user.UpdatedAt = now
+
+	return *user, true
+}
+
+// purge deletes an account still disabled for purgeAfter at now, returning
+// a copy of it
+func (s *service) purge(userID string, purgeAfter time.Duration, now time.Time) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[userID]
+	if !exists || user.DisabledAt == nil || user.LifecycleExempt || now.Sub(*user.DisabledAt) < purgeAfter {
+		return User{}, false
+	}
+	delete(s.users, user.Username)
+	delete(s.usersByID, userID)
+	delete(s.subscriptions, userID)
+	if err := s.writeSubscriptions(); err != nil {
+		logging.WithContext(context.Background()).Warnw("Failed to persist saved subscriptions", "user_id", userID, "error", err)
+	}
+
+	return *user, true
+}
+
+// notify sends a lifecycle notification to users with an email address
+func (s *service) notify(ctx context.Context, n notifier.Notifier, user User, subject, body string) {
+	if n == nil || user.Email == "" {
+		return
+	}
+	err := n.Notify(ctx, &notifier.Notification{To: []string{user.Email}, Subject: subject, Body: body})
+	if err != nil {
+		logging.WithContext(ctx).Warnw("Failed to send account notification", "user_id", user.ID, "error", err)
+	}
+}
+
+// days returns d in whole days
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
@@ -2,6 +2,8 @@ package user
 
 import (
 	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
 )
 
 // User represents a user in the system
@@ -12,6 +14,15 @@ type User struct {
 	HashedPassword string    `json:"-"` // Don't include in JSON responses
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+
+	// LastActiveAt is the last login or authenticated request, to within
+	// ActivityResolution
+	LastActiveAt time.Time `json:"last_active_at"`
+	// DisabledAt is when the account was disabled for inactivity; disabled
+	// accounts cannot log in or make requests
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// LifecycleExempt accounts are never disabled or purged
+	LifecycleExempt bool `json:"lifecycle_exempt,omitempty"`
 }
 
 // RegisterRequest represents a user registration request
@@ -46,6 +57,47 @@ type ProfileResponse struct {
 	User *User `json:"user"`
 }
 
+// DeleteProfileResponse represents a user deletion response
+type DeleteProfileResponse struct {
+	Status string `json:"status"`
+	UserID string `json:"user_id"`
+}
+
+// SavedSubscription represents a subscription a user wants re-established
+// automatically when reconnecting with auto_resume
+type SavedSubscription struct {
+	Topic     string            `json:"topic"`
+	LastN     int               `json:"last_n,omitempty"`
+	Sampling  *pubsub.Sampling  `json:"sampling,omitempty"`
+	TagFilter *pubsub.TagFilter `json:"tag_filter,omitempty"`
+	// DeadLetter names a topic receiving messages dropped for the subscription
+	DeadLetter string `json:"dead_letter,omitempty"`
+	// Durable resumes a pull-based subscription from its last ack
+	Durable bool `json:"durable,omitempty"`
+	// MaxDeliveries allows nacks, redelivering a message up to this many times
+	MaxDeliveries int `json:"max_deliveries,omitempty"`
+	// Backpressure is the subscription's full queue policy, overriding the topic's
+	Backpressure *pubsub.Backpressure `json:"backpressure,omitempty"`
+	// Preferences picks the variants delivered by their headers, such as locale
+	Preferences pubsub.HeaderPreferences `json:"preferences,omitempty"`
+}
+
+// SaveSubscriptionsRequest represents a request replacing a user's saved subscriptions
+type SaveSubscriptionsRequest struct {
+	Subscriptions []SavedSubscription `json:"subscriptions"`
+}
+
+// SavedSubscriptionsResponse represents a user's saved subscriptions
+type SavedSubscriptionsResponse struct {
+	Subscriptions []SavedSubscription `json:"subscriptions"`
+}
+
+// SubscriptionStatsResponse represents the delivery statistics of a user's
+// live subscriptions
+type SubscriptionStatsResponse struct {
+	Subscriptions []pubsub.SubscriptionStats `json:"subscriptions"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -21,6 +21,12 @@ func NewRouteRegistrar(service Service) secure.RouteRegistrarInterface {
 func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
 	// User profile endpoint (requires authentication)
 	authGroup.GET("/users/profile", r.endpoint.GetProfile)
+	authGroup.DELETE("/users/profile", r.endpoint.DeleteProfile)
+
+	// Saved subscriptions used by WebSocket auto_resume
+	authGroup.GET("/users/subscriptions", r.endpoint.GetSavedSubscriptions)
+	authGroup.PUT("/users/subscriptions", r.endpoint.SaveSubscriptions)
+	authGroup.GET("/users/subscriptions/stats", r.endpoint.GetSubscriptionStats)
 }
 
 // RegisterUnAuthRoutes registers unauthenticated routes
@@ -1,39 +1,80 @@
 package user
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net/mail"
 	"sync"
 	"time"
 
 	"github.com/ammysap/plivo-pub-sub/libraries/auth"
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/notifier"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // Service interface for user operations
 type Service interface {
-	Register(username, password string) (*User, error)
+	Register(username, password, email string) (*User, error)
 	Login(username, password string) (*User, error)
 	GetUserByID(userID string) (*User, error)
 	GetUserByUsername(username string) (*User, error)
+	DeleteUser(userID string) error
+	SaveSubscriptions(userID string, subscriptions []SavedSubscription) error
+	GetSavedSubscriptions(userID string) ([]SavedSubscription, error)
+	LoadSubscriptions(dataDir string) error
+	GetSubscriptionStats(userID string) ([]pubsub.SubscriptionStats, error)
+	RecordActivity(userID string) error
+	EnableUser(userID string) (*User, error)
+	SetLifecycleExempt(userID string, exempt bool) (*User, error)
+	StartLifecycle(ctx context.Context, config *LifecycleConfig, n notifier.Notifier)
 }
 type service struct {
-	users     map[string]*User // username -> user
-	usersByID map[string]*User // user_id -> user
-	mu        sync.RWMutex
+	users         map[string]*User               // username -> user
+	usersByID     map[string]*User               // user_id -> user
+	subscriptions map[string][]SavedSubscription // user_id -> saved subscriptions
+	events        events.Bus
+	pubsubService pubsub.Service
+	mu            sync.RWMutex
+
+	subscriptionsPath string // file saved subscriptions persist to; empty keeps them in memory
 }
 
-// NewService creates a new user service
-func NewService() Service {
+// NewService creates a new user service that announces registered and
+// deleted users on bus
+func NewService(bus events.Bus) Service {
 	return &service{
-		users:     make(map[string]*User),
-		usersByID: make(map[string]*User),
+		users:         make(map[string]*User),
+		usersByID:     make(map[string]*User),
+		subscriptions: make(map[string][]SavedSubscription),
+		events:        bus,
+		pubsubService: pubsub.GetService(),
 	}
 }
 
-// Register creates a new user
-func (s *service) Register(username, password string) (*User, error) {
+// Register creates a new user. email is optional; account notifications
+// are sent to it.
+func (s *service) Register(username, password, email string) (*User, error) {
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return nil, fmt.Errorf("invalid email")
+		}
+	}
+
+	user, err := s.register(username, password, email)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(context.Background(), events.Event{Kind: events.KindUserRegistered, Subject: user.ID})
+	return user, nil
+}
+
+func (s *service) register(username, password, email string) (*User, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -55,12 +96,15 @@ func (s *service) Register(username, password string) (*User, error) {
 	}
 
 	// Create user
+	now := time.Now()
 	user := &User{
 		ID:             userID,
 		Username:       username,
+		Email:          email,
 		HashedPassword: string(hashedPassword),
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		LastActiveAt:   now,
 	}
 
 	// Store user with hashed password
@@ -70,10 +114,11 @@ func (s *service) Register(username, password string) (*User, error) {
 	return user, nil
 }
 
-// Login authenticates a user
+// Login authenticates a user, failing with "account disabled" for accounts
+// disabled for inactivity
 func (s *service) Login(username, password string) (*User, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	// Check if user exists
 	user, exists := s.users[username]
@@ -86,6 +131,10 @@ func (s *service) Login(username, password string) (*User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid username or password")
 	}
+	if user.DisabledAt != nil {
+		return nil, fmt.Errorf("account disabled")
+	}
+	user.LastActiveAt = time.Now()
 
 	return user, nil
 }
@@ -103,6 +152,27 @@ func (s *service) GetUserByID(userID string) (*User, error) {
 	return user, nil
 }
 
+// DeleteUser removes a user and their saved subscriptions. Tokens already
+// issued to the user stay valid until they expire.
+func (s *service) DeleteUser(userID string) error {
+	s.mu.Lock()
+	user, exists := s.usersByID[userID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("user not found")
+	}
+	delete(s.users, user.Username)
+	delete(s.usersByID, userID)
+	delete(s.subscriptions, userID)
+	if err := s.writeSubscriptions(); err != nil {
+		logging.WithContext(context.Background()).Warnw("Failed to persist saved subscriptions", "user_id", userID, "error", err)
+	}
+	s.mu.Unlock()
+
+	s.events.Publish(context.Background(), events.Event{Kind: events.KindUserDeleted, Subject: userID})
+	return nil
+}
+
 // GetUserByUsername retrieves a user by username
 func (s *service) GetUserByUsername(username string) (*User, error) {
 	s.mu.RLock()
@@ -116,6 +186,99 @@ func (s *service) GetUserByUsername(username string) (*User, error) {
 	return user, nil
 }
 
+// SaveSubscriptions replaces the saved subscriptions of a user
+func (s *service) SaveSubscriptions(userID string, subscriptions []SavedSubscription) error {
+	seen := make(map[string]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		if sub.Topic == "" {
+			return fmt.Errorf("invalid subscription: topic is required")
+		}
+		if seen[sub.Topic] {
+			return fmt.Errorf("invalid subscription: duplicate topic %s", sub.Topic)
+		}
+		seen[sub.Topic] = true
+
+		if sub.LastN < 0 {
+			return fmt.Errorf("invalid subscription: last_n must not be negative")
+		}
+		if sub.Sampling != nil {
+			if err := sub.Sampling.Validate(); err != nil {
+				return fmt.Errorf("invalid subscription: %w", err)
+			}
+		}
+		if sub.TagFilter != nil {
+			if err := sub.TagFilter.Validate(); err != nil {
+				return fmt.Errorf("invalid subscription: %w", err)
+			}
+		}
+		if sub.DeadLetter == sub.Topic {
+			return fmt.Errorf("invalid subscription: dead_letter must differ from topic")
+		}
+		if sub.MaxDeliveries < 0 {
+			return fmt.Errorf("invalid subscription: max_deliveries must not be negative")
+		}
+		if sub.Backpressure != nil {
+			if err := sub.Backpressure.Validate(); err != nil {
+				return fmt.Errorf("invalid subscription: %w", err)
+			}
+		}
+		if err := sub.Preferences.Validate(); err != nil {
+			return fmt.Errorf("invalid subscription: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.knownUser(userID) {
+		return fmt.Errorf("user not found")
+	}
+
+	previous, hadSaved := s.subscriptions[userID]
+	saved := make([]SavedSubscription, len(subscriptions))
+	copy(saved, subscriptions)
+	s.subscriptions[userID] = saved
+
+	if err := s.writeSubscriptions(); err != nil {
+		if hadSaved {
+			s.subscriptions[userID] = previous
+		} else {
+			delete(s.subscriptions, userID)
+		}
+		return fmt.Errorf("failed to persist saved subscriptions: %w", err)
+	}
+	return nil
+}
+
+// GetSavedSubscriptions returns the saved subscriptions of a user
+func (s *service) GetSavedSubscriptions(userID string) ([]SavedSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.knownUser(userID) {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	saved := make([]SavedSubscription, len(s.subscriptions[userID]))
+	copy(saved, s.subscriptions[userID])
+
+	return saved, nil
+}
+
+// GetSubscriptionStats returns delivery statistics for the live
+// subscriptions of a user
+func (s *service) GetSubscriptionStats(userID string) ([]pubsub.SubscriptionStats, error) {
+	s.mu.RLock()
+	_, exists := s.usersByID[userID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return s.pubsubService.ListSubscriptionStats(context.Background(), userID)
+}
+
 // generateUserID generates a random user ID
 func generateUserID() (string, error) {
 	bytes := make([]byte, 16)
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Layout of saved subscriptions under DATA_DIR, beside the pubsub topics
+const (
+	usersDir          = "users"
+	subscriptionsFile = "subscriptions.json"
+)
+
+// LoadSubscriptions restores the saved subscriptions persisted under
+// dataDir and keeps every later change there, so clients reconnecting with
+// auto_resume after a restart get their subscriptions back. Users are not
+// persisted: a user known only from saved subscriptions can still read and
+// replace them with a token issued before the restart.
+func (s *service) LoadSubscriptions(dataDir string) error {
+	path := filepath.Join(dataDir, usersDir, subscriptionsFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	saved := make(map[string][]SavedSubscription)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read saved subscriptions: %w", err)
+	default:
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return fmt.Errorf("failed to read saved subscriptions: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, subscriptions := range saved {
+		if _, exists := s.subscriptions[userID]; !exists {
+			s.subscriptions[userID] = subscriptions
+		}
+	}
+	s.subscriptionsPath = path
+	return nil
+}
+
+// writeSubscriptions persists the saved subscriptions, if LoadSubscriptions
+// enabled persistence. Caller must hold s.mu.
+func (s *service) writeSubscriptions() error {
+	if s.subscriptionsPath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.subscriptions)
+	if err != nil {
+		return err
+	}
+	tmp := s.subscriptionsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+	return os.Rename(tmp, s.subscriptionsPath)
+}
+
+// knownUser reports whether a user is registered or has persisted saved
+// subscriptions. Caller must hold s.mu.
+func (s *service) knownUser(userID string) bool {
+	if _, exists := s.usersByID[userID]; exists {
+		return true
+	}
+	_, saved := s.subscriptions[userID]
+	return saved
+}
@@ -0,0 +1,135 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+)
+
+// mirrorBatch is the most mirrored messages sent per mirror per pass of
+// messageSender, so a busy mirror drains faster than one event per pass
+// without starving the connection's subscriptions
+const mirrorBatch = 64
+
+// handleMirror handles subscribe requests with mirror set, which need a
+// token holding RoleMirror
+func (h *WebSocketHandler) handleMirror(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
+	log := logging.WithContext(ctx)
+
+	if client.Claims == nil || !client.Claims.HasRole(RoleMirror) {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeForbidden,
+			Message: fmt.Sprintf("forbidden: mirrors require a token with the %s role", RoleMirror),
+		}
+		return
+	}
+
+	// Mirrors copy live messages only, so subscription options do not apply
+	if req.LastN > 0 || req.Since != "" || req.AfterSeq != nil || req.Durable || req.DeadLetter != "" ||
+		req.MaxDeliveries > 0 || req.Backpressure != nil || len(req.Preferences) > 0 || req.Partition != nil || req.TTL != "" {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: "invalid mirror: only sampling, tag_filter and buffer_size apply to mirrors",
+		}
+		return
+	}
+
+	mirror, err := h.pubsubService.Mirror(ctx, client.ID, &pubsub.MirrorOptions{
+		Namespace:  req.Topic,
+		Sampling:   req.Sampling,
+		TagFilter:  req.TagFilter,
+		BufferSize: req.BufferSize,
+	})
+	if err != nil {
+		response.Type = WSResponseTypeError
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			response.Error = &WSError{
+				Code:    ErrorCodeForbidden,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "invalid mirror") ||
+			strings.HasPrefix(err.Error(), "invalid sampling") ||
+			strings.HasPrefix(err.Error(), "invalid tag filter") ||
+			strings.Contains(err.Error(), "already mirrors namespace") {
+			response.Error = &WSError{
+				Code:    ErrorCodeBadRequest,
+				Message: err.Error(),
+			}
+		} else {
+			response.Error = &WSError{
+				Code:    ErrorCodeInternal,
+				Message: err.Error(),
+			}
+		}
+		return
+	}
+
+	client.mu.Lock()
+	client.mirrors[req.Topic] = mirror
+	client.mu.Unlock()
+
+	response.Type = WSResponseTypeAck
+	response.Topic = req.Topic
+	response.Status = "ok"
+	response.Mirror = true
+
+	log.Infow("Client mirroring namespace", "client_id", client.ID, "tenant", client.tenant(), "namespace", req.Topic)
+}
+
+// handleUnmirror handles unsubscribe requests with mirror set
+func (h *WebSocketHandler) handleUnmirror(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
+	if err := h.pubsubService.Unmirror(ctx, client.ID, req.Topic); err != nil {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	client.mu.Lock()
+	delete(client.mirrors, req.Topic)
+	client.mu.Unlock()
+
+	response.Type = WSResponseTypeAck
+	response.Topic = req.Topic
+	response.Status = "ok"
+	response.Mirror = true
+}
+
+// sendMirrored queues a mirror's drop report and waiting messages for the
+// client. It reports whether anything was sent and whether the connection
+// is still open.
+func (h *WebSocketHandler) sendMirrored(client *Client, mirror *pubsub.Mirror) (bool, bool) {
+	if count, total := mirror.TakeDrops(); count > 0 {
+		response := &WSResponse{
+			Type:      WSResponseTypeDropped,
+			Topic:     mirror.Namespace,
+			Mirror:    true,
+			Dropped:   &DroppedInfo{Count: count, Total: total, Policy: pubsub.BackpressureDropNewest},
+			Timestamp: time.Now(),
+		}
+		if !h.send(client, response) {
+			return false, false
+		}
+	}
+
+	sent := false
+	for range mirrorBatch {
+		message, ok := mirror.TryReceive()
+		if !ok {
+			break
+		}
+		if !h.send(client, mirrorResponse(message)) {
+			return false, false
+		}
+		sent = true
+	}
+	return sent, true
+}
@@ -14,6 +14,10 @@ const (
 	WSMessageTypeUnsubscribe WSMessageType = "unsubscribe"
 	WSMessageTypePublish     WSMessageType = "publish"
 	WSMessageTypePing        WSMessageType = "ping"
+	WSMessageTypeAck         WSMessageType = "ack"
+	WSMessageTypeNack        WSMessageType = "nack"
+	WSMessageTypeMarkRead    WSMessageType = "mark_read"
+	WSMessageTypeTime        WSMessageType = "time"
 )
 
 type WSResponseType string
@@ -24,28 +28,103 @@ const (
 	WSResponseTypeError WSResponseType = "error"
 	WSResponseTypePong  WSResponseType = "pong"
 	WSResponseTypeInfo  WSResponseType = "info"
+	WSResponseTypeTime  WSResponseType = "time"
+
+	// WSResponseTypeSubscriptionClosed is the last frame sent for a
+	// subscription the server ended, with the reason in Reason
+	WSResponseTypeSubscriptionClosed WSResponseType = "subscription_closed"
+
+	// WSResponseTypeDropped reports messages dropped for a subscription
+	// whose queue was full, before its next event
+	WSResponseTypeDropped WSResponseType = "dropped"
 )
 
 // WebSocket Request Message
 type WSRequest struct {
-	Type      WSMessageType   `json:"type"`
-	Topic     string          `json:"topic,omitempty"`
-	Message   *pubsub.Message `json:"message,omitempty"`
-	ClientID  string          `json:"client_id,omitempty"`
-	LastN     int             `json:"last_n,omitempty"`
-	RequestID string          `json:"request_id,omitempty"`
+	Type             WSMessageType     `json:"type"`
+	Topic            string            `json:"topic,omitempty"`
+	Message          *pubsub.Message   `json:"message,omitempty"`
+	ClientID         string            `json:"client_id,omitempty"`
+	LastN            int               `json:"last_n,omitempty"`
+	Since            string            `json:"since,omitempty"`     // subscribe: replay from an RFC 3339 time, or for a duration back such as "5m"
+	AfterSeq         *uint64           `json:"after_seq,omitempty"` // subscribe: replay every buffered message after this seq
+	Sampling         *pubsub.Sampling  `json:"sampling,omitempty"`
+	TagFilter        *pubsub.TagFilter `json:"tag_filter,omitempty"`
+	Durable          bool              `json:"durable,omitempty"`
+	DeadLetter       string            `json:"dead_letter,omitempty"` // subscribe: topic receiving messages dropped for this subscription
+	Seq              uint64            `json:"seq,omitempty"`
+	ValidateOrdering bool              `json:"validate_ordering,omitempty"`
+	ClientTime       *time.Time        `json:"client_ts,omitempty"` // time request: the client's clock when sending
+	RequestID        string            `json:"request_id,omitempty"`
+	raw              []byte            // frame as received, for re-decoding per topic
+
+	MaxDeliveries int   `json:"max_deliveries,omitempty"` // subscribe: allow nacks, redelivering a message up to this many times
+	Requeue       *bool `json:"requeue,omitempty"`        // nack: redeliver rather than dead-letter; defaults to true
+
+	Backpressure *pubsub.Backpressure `json:"backpressure,omitempty"` // subscribe: full queue policy, overriding the topic's
+
+	Preferences pubsub.HeaderPreferences `json:"preferences,omitempty"` // subscribe: header values picking the variants delivered
+
+	Partition *int `json:"partition,omitempty"` // subscribe: the one partition of a partitioned topic delivered
+
+	TTL string `json:"ttl,omitempty"` // subscribe: a duration such as "10m" after which the server unsubscribes
+
+	// Mirror subscribes to, or unsubscribes from, a copy of every message
+	// published under the namespace in Topic, or to every topic without one
+	Mirror     bool `json:"mirror,omitempty"`
+	BufferSize int  `json:"buffer_size,omitempty"` // mirror: messages queued before they are dropped
 }
 
 // WebSocket Response Message
 type WSResponse struct {
-	Type      WSResponseType  `json:"type"`
-	RequestID string          `json:"request_id,omitempty"`
-	Topic     string          `json:"topic,omitempty"`
-	Message   *pubsub.Message `json:"message,omitempty"`
-	Error     *WSError        `json:"error,omitempty"`
-	Status    string          `json:"status,omitempty"`
-	Msg       string          `json:"msg,omitempty"`
-	Timestamp time.Time       `json:"ts"`
+	Type       WSResponseType     `json:"type"`
+	RequestID  string             `json:"request_id,omitempty"`
+	Topic      string             `json:"topic,omitempty"`
+	Message    *pubsub.Message    `json:"message,omitempty"`
+	Error      *WSError           `json:"error,omitempty"`
+	Status     string             `json:"status,omitempty"`
+	Msg        string             `json:"msg,omitempty"`
+	Cursor     *pubsub.CursorInfo `json:"cursor,omitempty"`
+	ReadMarker *pubsub.ReadMarker `json:"read_marker,omitempty"`
+	Ordering   *OrderingInfo      `json:"ordering,omitempty"`
+	Reason     string             `json:"reason,omitempty"`
+	Time       *TimeInfo          `json:"time,omitempty"`
+	Timestamp  time.Time          `json:"ts"`
+
+	Nack    *pubsub.NackResult `json:"nack,omitempty"`
+	Dropped *DroppedInfo       `json:"dropped,omitempty"`
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // subscribe ack: when the subscription's ttl ends it
+
+	Mirror bool `json:"mirror,omitempty"` // the frame is for a mirror rather than a subscription
+}
+
+// DroppedInfo counts messages dropped for a subscription under its
+// backpressure policy
+type DroppedInfo struct {
+	Count  uint64                    `json:"count"` // since the previous dropped frame
+	Total  uint64                    `json:"total"` // since the subscription was made
+	Policy pubsub.BackpressurePolicy `json:"policy"`
+}
+
+// TimeInfo answers a time request. Clients estimate their clock offset as
+// ServerTime minus the midpoint between sending the request and receiving
+// the response; ClientTime is echoed so the round trip can be computed
+// without tracking requests.
+type TimeInfo struct {
+	ClientTime *time.Time `json:"client_ts,omitempty"`
+	ServerTime time.Time  `json:"server_ts"`
+	RTTMillis  *float64   `json:"rtt_ms,omitempty"` // last round trip the server measured with a ping control frame
+}
+
+// OrderingInfo is attached to events on subscriptions made with
+// validate_ordering, so clients can tell events lost between server and
+// client (a DeliverySeq gap) from messages the server never sent them
+// (a gap between PrevSeq and the message seq), and detect reordering
+type OrderingInfo struct {
+	DeliverySeq uint64 `json:"delivery_seq"` // 1-based count of events sent on this subscription
+	PrevSeq     uint64 `json:"prev_seq"`     // topic seq of the previous event sent on this subscription
+	seq         uint64 // topic seq of this event
 }
 
 // WebSocket Error
@@ -61,4 +140,22 @@ const (
 	ErrorCodeSlowConsumer  = "SLOW_CONSUMER"
 	ErrorCodeUnauthorized  = "UNAUTHORIZED"
 	ErrorCodeInternal      = "INTERNAL"
+	ErrorCodeRateLimited   = "RATE_LIMITED"
+	ErrorCodeForbidden     = "FORBIDDEN" // the connection's API key does not permit the operation
+
+	ErrorCodeOverloaded = "OVERLOADED" // the engine's goroutine budget is spent; retry later
+	ErrorCodeBusy       = "BUSY"       // the topic's ingress queue is full; retry later
 )
+
+// RoleMirror is the token role a connection needs to mirror namespaces
+const RoleMirror = "mirror"
+
+// SystemTopicPrefix marks topics only the gateway publishes to, such as
+// $SYS/alerts
+const SystemTopicPrefix = "$SYS/"
+
+// CSRFTokenResponse carries the double-submit token set as the ws_csrf
+// cookie; browsers pass it back as the csrf_token parameter of /ws
+type CSRFTokenResponse struct {
+	CSRFToken string `json:"csrf_token"`
+}
@@ -7,23 +7,44 @@ import (
 
 // RouteRegistrar implements the secure.RouteRegistrarInterface
 type RouteRegistrar struct {
-	endpoint Endpoint
+	endpoint        Endpoint
+	authRequired    bool
+	originCheck     gin.HandlerFunc
+	optionalAuth    gin.HandlerFunc
+	activeUser      gin.HandlerFunc
+	connectionLimit gin.HandlerFunc
+	drain           gin.HandlerFunc
 }
 
-// NewRouteRegistrar creates a new route registrar
-func NewRouteRegistrar(service Service) secure.RouteRegistrarInterface {
+// NewRouteRegistrar creates a new route registrar. When authRequired is
+// false, /ws also accepts anonymous connections, authenticating those that
+// carry a token with optionalAuth and rejecting disabled accounts among
+// them with activeUser. originCheck vets each upgrade's origin and CSRF
+// token, connectionLimit wraps each connection to cap how many a user
+// holds at once, and drain refuses connections during maintenance.
+func NewRouteRegistrar(service Service, authRequired bool, originCheck, optionalAuth, activeUser, connectionLimit, drain gin.HandlerFunc) secure.RouteRegistrarInterface {
 	return &RouteRegistrar{
-		endpoint: NewEndpoint(service),
+		endpoint:        NewEndpoint(service),
+		authRequired:    authRequired,
+		originCheck:     originCheck,
+		optionalAuth:    optionalAuth,
+		activeUser:      activeUser,
+		connectionLimit: connectionLimit,
+		drain:           drain,
 	}
 }
 
 // RegisterAuthRoutes registers authenticated routes
 func (r *RouteRegistrar) RegisterAuthRoutes(authGroup *gin.RouterGroup) {
-	// no auth routes
+	if r.authRequired {
+		authGroup.GET("/ws", r.drain, r.originCheck, r.connectionLimit, r.endpoint.HandleWebSocket)
+	}
 }
 
 // RegisterUnAuthRoutes registers unauthenticated routes
 func (r *RouteRegistrar) RegisterUnAuthRoutes(unAuthGroup *gin.RouterGroup) {
-	// WebSocket endpoint (unauthenticated for now)
-	unAuthGroup.GET("/ws", r.endpoint.HandleWebSocket)
+	unAuthGroup.GET("/ws/csrf", r.endpoint.IssueCSRFToken)
+	if !r.authRequired {
+		unAuthGroup.GET("/ws", r.drain, r.originCheck, r.optionalAuth, r.activeUser, r.connectionLimit, r.endpoint.HandleWebSocket)
+	}
 }
@@ -2,35 +2,117 @@ package websocket
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ammysap/plivo-pub-sub/libraries/auth"
 	"github.com/ammysap/plivo-pub-sub/logging"
 	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/events"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/limits"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/metrics"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/middlewares"
+	"github.com/ammysap/plivo-pub-sub/services/gateway/user"
+	"github.com/ammysap/plivo-pub-sub/usercontext"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
 )
 
+// durableFetchBatch is the maximum number of messages pulled per durable
+// subscription on each sender pass
+const durableFetchBatch = 50
+
+// readLimitStrikes is the number of consecutive frames a connection may have
+// rejected by its read limit before it is disconnected
+const readLimitStrikes = 50
+
 // Service interface for WebSocket operations
 type Service interface {
 	HandleWebSocketConnection(conn *websocket.Conn, ctx context.Context)
+	// ConnectionCount returns the number of open WebSocket connections
+	ConnectionCount() int
+}
+
+// SubscriptionStore provides the saved subscriptions replayed on auto_resume
+type SubscriptionStore interface {
+	GetSavedSubscriptions(userID string) ([]user.SavedSubscription, error)
 }
 
 // WebSocketHandler handles WebSocket connections for pub/sub
 type WebSocketHandler struct {
-	pubsubService pubsub.Service
-	clients       map[string]*Client // client_id -> client
-	clientsMu     sync.RWMutex
-	shutdown      chan struct{}
+	pubsubService     pubsub.Service
+	subscriptionStore SubscriptionStore
+	limiter           limits.Service
+	clients           map[string]*Client // client_id -> client
+	clientsMu         sync.RWMutex
+	connections       atomic.Int64 // open connections, including several per user
+	shutdown          chan struct{}
+
+	bodyLimits *middlewares.BodyLimits // frame size and JSON limits; nil disables them
+	metrics    metrics.Service         // counts frames rejected by bodyLimits; may be nil
+
+	writes WriteConfig // bounds on each connection's outgoing frames
 }
 
 // Client represents a WebSocket client connection
 type Client struct {
 	ID            string
+	UserID        string // authenticated user, shared by its connections; "" when anonymous
 	Conn          *websocket.Conn
 	Subscriptions map[string]*pubsub.Subscriber // topic -> subscriber
+	mirrors       map[string]*pubsub.Mirror     // namespace -> mirror
+	ordering      map[string]*OrderingInfo      // topic -> last ordering info sent, for validate_ordering subscriptions
+	reads         *rate.Limiter                 // frames read per second; nil when unlimited
+	readStrikes   int                           // consecutive frames rejected by reads, owned by the read loop
+	rtt           atomic.Int64                  // last ping round trip in nanoseconds, 0 until measured
 	mu            sync.RWMutex
 	done          chan struct{}
+
+	outbox   chan *WSResponse // frames waiting for writeLoop
+	closing  sync.Once        // closes the connection once when it cannot be written to
+	finished sync.Once        // closes done once
+
+	// Claims are the verified claims of the token the connection was opened
+	// with, for per-operation decisions; nil for anonymous and API key
+	// connections
+	Claims *auth.Claims
+}
+
+// tenant returns the tenant claimed by the client's token, or ""
+func (c *Client) tenant() string {
+	if c.Claims == nil {
+		return ""
+	}
+	return c.Claims.Tenant
+}
+
+// user returns the client's user ID, or its connection ID when anonymous
+func (c *Client) user() string {
+	if c.UserID == "" {
+		return c.ID
+	}
+	return c.UserID
+}
+
+// touch records activity on every subscription of the client, so topics
+// delivering once per user prefer this connection over the user's others
+func (c *Client) touch() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, subscriber := range c.Subscriptions {
+		subscriber.Touch()
+	}
+}
+
+// finish closes done, stopping the connection's goroutines
+func (c *Client) finish() {
+	c.finished.Do(func() { close(c.done) })
 }
 
 // service implements the Service interface
@@ -38,12 +120,33 @@ type service struct {
 	handler *WebSocketHandler
 }
 
-// NewService creates a new WebSocket service
-func NewService() Service {
+// NewService creates a new WebSocket service. Connections of users deleted
+// on bus are closed; bus may be nil. Frames are held to bodyLimits, with
+// rejections counted in metricsService; either may be nil. Outgoing frames
+// are bounded by writes.
+func NewService(subscriptionStore SubscriptionStore, limiter limits.Service, bus events.Bus,
+	bodyLimits *middlewares.BodyLimits, metricsService metrics.Service, writes WriteConfig) Service {
 	handler := &WebSocketHandler{
-		pubsubService: pubsub.GetService(),
-		clients:       make(map[string]*Client),
-		shutdown:      make(chan struct{}),
+		pubsubService:     pubsub.GetService(),
+		subscriptionStore: subscriptionStore,
+		limiter:           limiter,
+		bodyLimits:        bodyLimits,
+		metrics:           metricsService,
+		writes:            writes.withDefaults(),
+		clients:           make(map[string]*Client),
+		shutdown:          make(chan struct{}),
+	}
+
+	if bus != nil {
+		bus.Subscribe(events.KindUserDeleted, "websocket", func(ctx context.Context, event events.Event) {
+			handler.disconnect(ctx, event.Subject, "user deleted")
+		})
+		bus.Subscribe(events.KindUserDisabled, "websocket", func(ctx context.Context, event events.Event) {
+			handler.disconnect(ctx, event.Subject, "user disabled")
+		})
+		bus.Subscribe(events.KindMaintenanceStarted, "websocket", func(ctx context.Context, event events.Event) {
+			handler.disconnectAll(ctx, websocket.CloseServiceRestart, "maintenance")
+		})
 	}
 
 	return &service{
@@ -56,34 +159,56 @@ func (s *service) HandleWebSocketConnection(conn *websocket.Conn, ctx context.Co
 	s.handler.HandleWebSocketConnection(conn, ctx)
 }
 
+// ConnectionCount returns the number of open WebSocket connections
+func (s *service) ConnectionCount() int {
+	return int(s.handler.connections.Load())
+}
+
 // HandleWebSocketConnection handles WebSocket connections
 func (h *WebSocketHandler) HandleWebSocketConnection(conn *websocket.Conn, ctx context.Context) {
 	defer conn.Close()
 
-	// Get authenticated user ID from context
-	userID, ok := ctx.Value(ctxKeyUserID).(string)
-	if !ok || userID == "" {
-		logging.WithContext(ctx).Errorw("No authenticated user ID in context")
-		return
-	}
-
-	// Use user ID as client ID for authenticated connections
+	// Use user ID as client ID for authenticated connections; anonymous
+	// connections get a generated one
+	userID := userIDFromContext(ctx)
 	clientID := userID
+	if clientID == "" {
+		clientID = "anonymous-" + uuid.NewString()
+	}
 
+	// Register client. A user's further connections, such as from another
+	// device, get client IDs of their own.
+	h.clientsMu.Lock()
+	if _, taken := h.clients[clientID]; taken {
+		clientID = userID + "#" + uuid.NewString()[:8]
+	}
 	client := &Client{
 		ID:            clientID,
+		UserID:        userID,
 		Conn:          conn,
 		Subscriptions: make(map[string]*pubsub.Subscriber),
+		mirrors:       make(map[string]*pubsub.Mirror),
+		ordering:      make(map[string]*OrderingInfo),
 		done:          make(chan struct{}),
+		outbox:        make(chan *WSResponse, h.writes.Buffer),
+		Claims:        auth.ClaimsFromContext(ctx),
 	}
-
-	// Register client
-	h.clientsMu.Lock()
 	h.clients[clientID] = client
 	h.clientsMu.Unlock()
+	h.connections.Add(1)
+
+	if h.limiter != nil {
+		client.reads = h.limiter.NewLimiter(limits.ScopeConnection, clientID)
+	}
+	conn.SetPongHandler(client.handlePong)
+	if h.bodyLimits != nil && h.bodyLimits.MaxFrameBytes > 0 {
+		// Larger frames fail the read, closing the connection with 1009
+		conn.SetReadLimit(h.bodyLimits.MaxFrameBytes)
+	}
 
 	// Cleanup on disconnect
 	defer func() {
+		h.connections.Add(-1)
 		h.clientsMu.Lock()
 		delete(h.clients, clientID)
 		h.clientsMu.Unlock()
@@ -93,14 +218,23 @@ func (h *WebSocketHandler) HandleWebSocketConnection(conn *websocket.Conn, ctx c
 		for topicName := range client.Subscriptions {
 			h.pubsubService.Unsubscribe(ctx, topicName, clientID)
 		}
+		for namespace := range client.mirrors {
+			h.pubsubService.Unmirror(ctx, clientID, namespace)
+		}
 		client.mu.RUnlock()
 
-		close(client.done)
+		client.finish()
 	}()
 
-	// Start message sender goroutine
+	// Start the writer and message sender goroutines
+	go h.writeLoop(client)
 	go h.messageSender(client)
 
+	// Re-establish saved subscriptions if requested at connect
+	if autoResume, _ := ctx.Value(ctxKeyAutoResume).(bool); autoResume {
+		h.resumeSubscriptions(ctx, client)
+	}
+
 	// Handle incoming messages
 	for {
 		select {
@@ -109,24 +243,143 @@ func (h *WebSocketHandler) HandleWebSocketConnection(conn *websocket.Conn, ctx c
 		case <-client.done:
 			return
 		default:
-			var req WSRequest
-			err := conn.ReadJSON(&req)
+			_, data, err := conn.ReadMessage()
 			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				if errors.Is(err, websocket.ErrReadLimit) {
+					logging.WithContext(ctx).Warnw("Closing connection sending an oversized frame", "client_id", clientID)
+					h.observeRejection(metrics.RejectedBodyTooLarge)
+				} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					logging.WithContext(ctx).Errorw("WebSocket read error", "error", err, "client_id", clientID)
 				}
 				return
 			}
 
-			h.handleMessage(ctx, client, &req)
+			if !h.allowFrame(ctx, client, data) {
+				continue
+			}
+
+			req, err := decodeRequest(data)
+			if err != nil {
+				return
+			}
+			client.touch()
+
+			if !h.allowRead(ctx, client, req) {
+				if client.readStrikes >= readLimitStrikes {
+					logging.WithContext(ctx).Warnw("Closing connection exceeding read rate", "client_id", clientID)
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "read rate exceeded"),
+						time.Now().Add(time.Second))
+					return
+				}
+				continue
+			}
+
+			h.handleMessage(ctx, client, req)
 		}
 	}
 }
 
-// handleMessage processes incoming WebSocket messages
-func (h *WebSocketHandler) handleMessage(ctx context.Context, client *Client, req *WSRequest) {
+// decodeRequest decodes a frame. Payload numbers are decoded exactly;
+// handlePublish re-decodes with the topic's settings when they differ.
+func decodeRequest(data []byte) (*WSRequest, error) {
+	req := &WSRequest{raw: data}
+	if err := (pubsub.Decoding{}).Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// allowFrame checks a frame against the JSON depth and array length limits
+// before it is decoded. A frame over them is answered with BAD_REQUEST and
+// not processed.
+func (h *WebSocketHandler) allowFrame(ctx context.Context, client *Client, data []byte) bool {
+	if h.bodyLimits == nil {
+		return true
+	}
+	reason, err := h.bodyLimits.CheckJSON(data)
+	if err == nil {
+		return true
+	}
+
+	h.observeRejection(reason)
+	response := &WSResponse{
+		Type:      WSResponseTypeError,
+		Error:     &WSError{Code: ErrorCodeBadRequest, Message: err.Error()},
+		Timestamp: time.Now(),
+	}
+	h.send(client, response)
+	return false
+}
+
+// observeRejection counts a frame rejected by the body limits
+func (h *WebSocketHandler) observeRejection(reason string) {
+	if h.metrics != nil {
+		h.metrics.ObserveRejection(metrics.TransportWS, reason)
+	}
+}
+
+// allowRead consumes a token from the connection's read limit. A frame over
+// the limit is answered with RATE_LIMITED and not processed, so a client
+// flooding frames cannot monopolise the pubsub service.
+func (h *WebSocketHandler) allowRead(ctx context.Context, client *Client, req *WSRequest) bool {
+	if client.reads == nil || client.reads.Allow() {
+		client.readStrikes = 0
+		return true
+	}
+
+	client.readStrikes++
+	response := &WSResponse{
+		Type:      WSResponseTypeError,
+		RequestID: req.RequestID,
+		Error: &WSError{
+			Code:    ErrorCodeRateLimited,
+			Message: fmt.Sprintf("read rate exceeded: at most %g frames per second", float64(client.reads.Limit())),
+		},
+		Timestamp: time.Now(),
+	}
+	h.send(client, response)
+
+	return false
+}
+
+// resumeSubscriptions subscribes the client to its saved subscriptions,
+// sending the usual ack or error response for each one
+func (h *WebSocketHandler) resumeSubscriptions(ctx context.Context, client *Client) {
 	log := logging.WithContext(ctx)
 
+	if h.subscriptionStore == nil {
+		return
+	}
+
+	saved, err := h.subscriptionStore.GetSavedSubscriptions(client.user())
+	if err != nil {
+		log.Warnw("Failed to load saved subscriptions", "error", err, "client_id", client.ID)
+		return
+	}
+
+	for _, sub := range saved {
+		h.handleMessage(ctx, client, &WSRequest{
+			Type:       WSMessageTypeSubscribe,
+			Topic:      sub.Topic,
+			LastN:      sub.LastN,
+			Sampling:   sub.Sampling,
+			TagFilter:  sub.TagFilter,
+			DeadLetter: sub.DeadLetter,
+			Durable:    sub.Durable,
+			RequestID:  "auto_resume",
+
+			MaxDeliveries: sub.MaxDeliveries,
+			Backpressure:  sub.Backpressure,
+			Preferences:   sub.Preferences,
+		})
+	}
+
+	log.Infow("Resumed saved subscriptions", "client_id", client.ID, "count", len(saved))
+}
+
+// handleMessage processes incoming WebSocket messages
+func (h *WebSocketHandler) handleMessage(ctx context.Context, client *Client, req *WSRequest) {
 	response := &WSResponse{
 		RequestID: req.RequestID,
 		Timestamp: time.Now(),
@@ -141,6 +394,14 @@ func (h *WebSocketHandler) handleMessage(ctx context.Context, client *Client, re
 		h.handlePublish(ctx, client, req, response)
 	case WSMessageTypePing:
 		h.handlePing(ctx, client, req, response)
+	case WSMessageTypeAck:
+		h.handleAck(ctx, client, req, response)
+	case WSMessageTypeNack:
+		h.handleNack(ctx, client, req, response)
+	case WSMessageTypeMarkRead:
+		h.handleMarkRead(ctx, client, req, response)
+	case WSMessageTypeTime:
+		h.handleTime(ctx, client, req, response)
 	default:
 		response.Type = WSResponseTypeError
 		response.Error = &WSError{
@@ -150,15 +411,18 @@ func (h *WebSocketHandler) handleMessage(ctx context.Context, client *Client, re
 	}
 
 	// Send response
-	if err := client.Conn.WriteJSON(response); err != nil {
-		log.Errorw("Failed to send WebSocket response", "error", err, "client_id", client.ID)
-	}
+	h.send(client, response)
 }
 
 // handleSubscribe handles subscribe requests
 func (h *WebSocketHandler) handleSubscribe(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
 	log := logging.WithContext(ctx)
 
+	if req.Mirror {
+		h.handleMirror(ctx, client, req, response)
+		return
+	}
+
 	if req.Topic == "" {
 		response.Type = WSResponseTypeError
 		response.Error = &WSError{
@@ -168,10 +432,50 @@ func (h *WebSocketHandler) handleSubscribe(ctx context.Context, client *Client,
 		return
 	}
 
-	// Use authenticated user ID as client ID
+	since, err := parseSince(req.Since, time.Now())
+	if err != nil {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	ttl, err := parseTTL(req.TTL)
+	if err != nil {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	// The connection's client ID keys its subscriptions
 	clientID := client.ID
 
-	subscriber, err := h.pubsubService.Subscribe(ctx, req.Topic, clientID, req.LastN)
+	var backpressure pubsub.Backpressure
+	if req.Backpressure != nil {
+		backpressure = *req.Backpressure
+	}
+
+	subscriber, err := h.pubsubService.Subscribe(ctx, req.Topic, clientID, &pubsub.SubscribeOptions{
+		LastN:      req.LastN,
+		Since:      since,
+		AfterSeq:   req.AfterSeq,
+		Sampling:   req.Sampling,
+		TagFilter:  req.TagFilter,
+		Durable:    req.Durable,
+		DeadLetter: req.DeadLetter,
+
+		MaxDeliveries: req.MaxDeliveries,
+		Backpressure:  backpressure,
+		Preferences:   req.Preferences,
+		Partition:     req.Partition,
+		UserID:        client.UserID,
+		TTL:           ttl,
+	})
 	if err != nil {
 		response.Type = WSResponseTypeError
 		if err.Error() == fmt.Sprintf("topic %s not found", req.Topic) {
@@ -179,6 +483,29 @@ func (h *WebSocketHandler) handleSubscribe(ctx context.Context, client *Client,
 				Code:    ErrorCodeTopicNotFound,
 				Message: err.Error(),
 			}
+		} else if strings.HasPrefix(err.Error(), "forbidden") {
+			response.Error = &WSError{
+				Code:    ErrorCodeForbidden,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "invalid sampling") ||
+			strings.HasPrefix(err.Error(), "invalid tag filter") ||
+			strings.HasPrefix(err.Error(), "invalid dead_letter") ||
+			strings.HasPrefix(err.Error(), "invalid after_seq") ||
+			strings.HasPrefix(err.Error(), "invalid max_deliveries") ||
+			strings.HasPrefix(err.Error(), "invalid backpressure") ||
+			strings.HasPrefix(err.Error(), "invalid header preferences") ||
+			strings.HasPrefix(err.Error(), "invalid partition") ||
+			strings.HasSuffix(err.Error(), "already subscribed to topic "+req.Topic) {
+			response.Error = &WSError{
+				Code:    ErrorCodeBadRequest,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "overloaded") {
+			response.Error = &WSError{
+				Code:    ErrorCodeOverloaded,
+				Message: err.Error(),
+			}
 		} else {
 			response.Error = &WSError{
 				Code:    ErrorCodeInternal,
@@ -191,19 +518,60 @@ func (h *WebSocketHandler) handleSubscribe(ctx context.Context, client *Client,
 	// Store subscription
 	client.mu.Lock()
 	client.Subscriptions[req.Topic] = subscriber
+	if req.ValidateOrdering {
+		client.ordering[req.Topic] = &OrderingInfo{}
+	}
 	client.mu.Unlock()
 
 	response.Type = WSResponseTypeAck
 	response.Topic = req.Topic
 	response.Status = "ok"
+	response.ExpiresAt = subscriber.ExpiresAt
 
-	log.Info("Client subscribed to topic", "client_id", clientID, "topic", req.Topic, "last_n", req.LastN)
+	log.Info("Client subscribed to topic", "client_id", clientID, "tenant", client.tenant(), "topic", req.Topic, "last_n", req.LastN, "since", req.Since)
+}
+
+// parseSince parses a subscribe request's since: an RFC 3339 time, or a
+// duration counted back from now. Empty returns the zero time.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid since: duration must be positive")
+		}
+		return now.Add(-d), nil
+	}
+	since, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since: expected an RFC 3339 time or a duration such as 5m")
+	}
+	return since, nil
+}
+
+// parseTTL parses a subscribe request's ttl, a positive duration. Empty
+// returns 0, for a subscription without one.
+func parseTTL(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl: expected a positive duration such as 10m")
+	}
+	return ttl, nil
 }
 
 // handleUnsubscribe handles unsubscribe requests
 func (h *WebSocketHandler) handleUnsubscribe(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
 	log := logging.WithContext(ctx)
 
+	if req.Mirror {
+		h.handleUnmirror(ctx, client, req, response)
+		return
+	}
+
 	if req.Topic == "" {
 		response.Type = WSResponseTypeError
 		response.Error = &WSError{
@@ -213,7 +581,7 @@ func (h *WebSocketHandler) handleUnsubscribe(ctx context.Context, client *Client
 		return
 	}
 
-	// Use authenticated user ID as client ID
+	// The connection's client ID keys its subscriptions
 	clientID := client.ID
 
 	err := h.pubsubService.Unsubscribe(ctx, req.Topic, clientID)
@@ -224,6 +592,11 @@ func (h *WebSocketHandler) handleUnsubscribe(ctx context.Context, client *Client
 				Code:    ErrorCodeTopicNotFound,
 				Message: err.Error(),
 			}
+		} else if strings.HasSuffix(err.Error(), "not subscribed to topic "+req.Topic) {
+			response.Error = &WSError{
+				Code:    ErrorCodeBadRequest,
+				Message: err.Error(),
+			}
 		} else {
 			response.Error = &WSError{
 				Code:    ErrorCodeInternal,
@@ -236,6 +609,7 @@ func (h *WebSocketHandler) handleUnsubscribe(ctx context.Context, client *Client
 	// Remove subscription
 	client.mu.Lock()
 	delete(client.Subscriptions, req.Topic)
+	delete(client.ordering, req.Topic)
 	client.mu.Unlock()
 
 	response.Type = WSResponseTypeAck
@@ -245,6 +619,24 @@ func (h *WebSocketHandler) handleUnsubscribe(ctx context.Context, client *Client
 	log.Info("Client unsubscribed from topic", "client_id", clientID, "topic", req.Topic)
 }
 
+// rateLimited consumes a token from the publishing user's and the topic's
+// buckets, returning the first scope that is exhausted
+func (h *WebSocketHandler) rateLimited(client *Client, topicName string) (limits.Scope, bool) {
+	if h.limiter == nil {
+		return "", false
+	}
+
+	// Anonymous clients are limited per connection
+	if allowed, _ := h.limiter.Allow(limits.ScopeUser, client.user()); !allowed {
+		return limits.ScopeUser, true
+	}
+	if allowed, _ := h.limiter.Allow(limits.ScopeTopic, topicName); !allowed {
+		return limits.ScopeTopic, true
+	}
+
+	return "", false
+}
+
 // handlePublish handles publish requests
 func (h *WebSocketHandler) handlePublish(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
 	log := logging.WithContext(ctx)
@@ -268,7 +660,96 @@ func (h *WebSocketHandler) handlePublish(ctx context.Context, client *Client, re
 		return
 	}
 
-	err := h.pubsubService.Publish(ctx, req.Topic, req.Message)
+	// Topics under $SYS/ carry messages from the gateway itself
+	if strings.HasPrefix(req.Topic, SystemTopicPrefix) {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: fmt.Sprintf("topic %s is reserved for the gateway", req.Topic),
+		}
+		return
+	}
+
+	// Frames are read with exact numbers and unknown fields allowed; topics
+	// configured otherwise decode the frame again
+	if req.raw != nil {
+		decoding, err := h.pubsubService.GetDecoding(ctx, req.Topic)
+		if err == nil && (decoding.Strict || decoding.Numbers == pubsub.NumbersFloat) {
+			decoded := &WSRequest{}
+			if err := decoding.Unmarshal(req.raw, decoded); err != nil {
+				response.Type = WSResponseTypeError
+				response.Error = &WSError{
+					Code:    ErrorCodeBadRequest,
+					Message: "invalid publish frame: " + err.Error(),
+				}
+				return
+			}
+			req.Message = decoded.Message
+		}
+	}
+
+	if scope, limited := h.rateLimited(client, req.Topic); limited {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeRateLimited,
+			Message: fmt.Sprintf("%s rate limit exceeded", scope),
+		}
+		return
+	}
+
+	err := h.pubsubService.Publish(ctx, req.Topic, *req.Message)
+	if err != nil {
+		response.Type = WSResponseTypeError
+		if err.Error() == fmt.Sprintf("topic %s not found", req.Topic) {
+			response.Error = &WSError{
+				Code:    ErrorCodeTopicNotFound,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "forbidden") {
+			response.Error = &WSError{
+				Code:    ErrorCodeForbidden,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "invalid payload") ||
+			strings.HasPrefix(err.Error(), "invalid schema_version") ||
+			strings.HasPrefix(err.Error(), "invalid priority") {
+			response.Error = &WSError{
+				Code:    ErrorCodeBadRequest,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "busy") {
+			response.Error = &WSError{
+				Code:    ErrorCodeBusy,
+				Message: err.Error(),
+			}
+		} else {
+			response.Error = &WSError{
+				Code:    ErrorCodeInternal,
+				Message: err.Error(),
+			}
+		}
+		return
+	}
+
+	response.Type = WSResponseTypeAck
+	response.Topic = req.Topic
+	response.Status = "ok"
+
+	log.Info("Message published", "client_id", client.ID, "tenant", client.tenant(), "topic", req.Topic, "message_id", req.Message.ID)
+}
+
+// handleAck advances the cursor of a durable subscription
+func (h *WebSocketHandler) handleAck(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
+	if req.Topic == "" || req.Seq == 0 {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: "topic and seq are required for ack",
+		}
+		return
+	}
+
+	cursor, err := h.pubsubService.Ack(ctx, req.Topic, client.ID, req.Seq)
 	if err != nil {
 		response.Type = WSResponseTypeError
 		if err.Error() == fmt.Sprintf("topic %s not found", req.Topic) {
@@ -276,6 +757,98 @@ func (h *WebSocketHandler) handlePublish(ctx context.Context, client *Client, re
 				Code:    ErrorCodeTopicNotFound,
 				Message: err.Error(),
 			}
+		} else if strings.HasPrefix(err.Error(), "forbidden") {
+			response.Error = &WSError{
+				Code:    ErrorCodeForbidden,
+				Message: err.Error(),
+			}
+		} else {
+			response.Error = &WSError{
+				Code:    ErrorCodeBadRequest,
+				Message: err.Error(),
+			}
+		}
+		return
+	}
+
+	response.Type = WSResponseTypeAck
+	response.Topic = req.Topic
+	response.Status = "ok"
+	response.Cursor = cursor
+}
+
+// handleNack rejects a message delivered on a subscription made with
+// max_deliveries, redelivering or dead-lettering it
+func (h *WebSocketHandler) handleNack(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
+	if req.Topic == "" || req.Seq == 0 {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: "topic and seq are required for nack",
+		}
+		return
+	}
+
+	requeue := req.Requeue == nil || *req.Requeue
+	result, err := h.pubsubService.Nack(ctx, req.Topic, client.ID, req.Seq, requeue)
+	if err != nil {
+		response.Type = WSResponseTypeError
+		if err.Error() == fmt.Sprintf("topic %s not found", req.Topic) {
+			response.Error = &WSError{
+				Code:    ErrorCodeTopicNotFound,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "forbidden") {
+			response.Error = &WSError{
+				Code:    ErrorCodeForbidden,
+				Message: err.Error(),
+			}
+		} else {
+			response.Error = &WSError{
+				Code:    ErrorCodeBadRequest,
+				Message: err.Error(),
+			}
+		}
+		return
+	}
+
+	response.Type = WSResponseTypeAck
+	response.Topic = req.Topic
+	response.Status = "ok"
+	response.Nack = result
+}
+
+// handleMarkRead handles mark_read requests, moving the client's read
+// marker on a topic forward to req.Seq
+func (h *WebSocketHandler) handleMarkRead(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
+	if req.Topic == "" || req.Seq == 0 {
+		response.Type = WSResponseTypeError
+		response.Error = &WSError{
+			Code:    ErrorCodeBadRequest,
+			Message: "topic and seq are required for mark_read",
+		}
+		return
+	}
+
+	// Read markers are the user's, whichever device read up to seq
+	marker, err := h.pubsubService.MarkRead(ctx, req.Topic, client.user(), req.Seq)
+	if err != nil {
+		response.Type = WSResponseTypeError
+		if err.Error() == fmt.Sprintf("topic %s not found", req.Topic) {
+			response.Error = &WSError{
+				Code:    ErrorCodeTopicNotFound,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "forbidden") {
+			response.Error = &WSError{
+				Code:    ErrorCodeForbidden,
+				Message: err.Error(),
+			}
+		} else if strings.HasPrefix(err.Error(), "invalid read marker") {
+			response.Error = &WSError{
+				Code:    ErrorCodeBadRequest,
+				Message: err.Error(),
+			}
 		} else {
 			response.Error = &WSError{
 				Code:    ErrorCodeInternal,
@@ -288,8 +861,38 @@ func (h *WebSocketHandler) handlePublish(ctx context.Context, client *Client, re
 	response.Type = WSResponseTypeAck
 	response.Topic = req.Topic
 	response.Status = "ok"
+	response.ReadMarker = marker
+}
+
+// handleTime answers a time request with the server clock, and sends a
+// ping control frame so the next answer can include the measured round trip
+func (h *WebSocketHandler) handleTime(ctx context.Context, client *Client, req *WSRequest, response *WSResponse) {
+	response.Type = WSResponseTypeTime
+	response.Time = &TimeInfo{
+		ClientTime: req.ClientTime,
+		ServerTime: time.Now(),
+	}
+	if rtt := client.rtt.Load(); rtt > 0 {
+		millis := float64(rtt) / float64(time.Millisecond)
+		response.Time.RTTMillis = &millis
+	}
+
+	sentAt := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := client.Conn.WriteControl(websocket.PingMessage, []byte(sentAt), time.Now().Add(time.Second)); err != nil {
+		logging.WithContext(ctx).Debugw("Failed to send latency probe", "error", err, "client_id", client.ID)
+	}
+}
 
-	log.Info("Message published", "topic", req.Topic, "message_id", req.Message.ID)
+// handlePong records the round trip of a ping sent by handleTime
+func (c *Client) handlePong(data string) error {
+	sentAt, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return nil // not one of our probes
+	}
+	if rtt := time.Now().UnixNano() - sentAt; rtt > 0 {
+		c.rtt.Store(rtt)
+	}
+	return nil
 }
 
 // handlePing handles ping requests
@@ -300,6 +903,10 @@ func (h *WebSocketHandler) handlePing(ctx context.Context, client *Client, _ *WS
 
 // messageSender sends messages from subscriber channels to WebSocket
 func (h *WebSocketHandler) messageSender(client *Client) {
+	// Reused across passes, so polling an idle connection allocates nothing
+	var subscriptions []*pubsub.Subscriber
+	var mirrors []*pubsub.Mirror
+
 	for {
 		select {
 		case <-h.shutdown:
@@ -309,33 +916,60 @@ func (h *WebSocketHandler) messageSender(client *Client) {
 		default:
 			// Check all subscriptions for new messages
 			client.mu.RLock()
-			subscriptions := make([]*pubsub.Subscriber, 0, len(client.Subscriptions))
+			clear(subscriptions)
+			subscriptions = subscriptions[:0]
 			for _, subscriber := range client.Subscriptions {
 				subscriptions = append(subscriptions, subscriber)
 			}
+			clear(mirrors)
+			mirrors = mirrors[:0]
+			for _, mirror := range client.mirrors {
+				mirrors = append(mirrors, mirror)
+			}
 			client.mu.RUnlock()
 
 			// Use select with default to avoid blocking
 			messageSent := false
 			for _, subscriber := range subscriptions {
-				select {
-				case message := <-subscriber.MessageChan: // non blocking
-					response := &WSResponse{
-						Type:      WSResponseTypeEvent,
-						Topic:     message.Topic,
-						Message:   message,
-						Timestamp: time.Now(),
+				if subscriber.Durable {
+					sent, ok := h.sendDurable(client, subscriber)
+					if !ok {
+						return
 					}
+					messageSent = messageSent || sent
+					continue
+				}
 
-					if err := client.Conn.WriteJSON(response); err != nil {
-						logging.WithContext(context.Background()).Errorw("Failed to send event message",
-							"error", err, "client_id", client.ID, "topic", message.Topic)
+				if !h.reportDrops(client, subscriber) {
+					return
+				}
+
+				// Non blocking, high priority messages first
+				message, ok, closed := subscriber.TryReceive()
+				if closed {
+					if !h.closeSubscription(client, subscriber) {
 						return
 					}
-					messageSent = true
-				default:
-					// No message available, continue
+					continue
+				}
+				if !ok {
+					continue
 				}
+
+				if !h.send(client, eventResponse(client, message)) {
+					return
+				}
+				messageSent = true
+			}
+
+			// Mirrors have queues of their own, so they are served after
+			// the subscriptions and never hold them back
+			for _, mirror := range mirrors {
+				sent, ok := h.sendMirrored(client, mirror)
+				if !ok {
+					return
+				}
+				messageSent = messageSent || sent
 			}
 
 			// If no messages were sent, sleep briefly to avoid busy waiting
@@ -346,6 +980,141 @@ func (h *WebSocketHandler) messageSender(client *Client) {
 	}
 }
 
+// reportDrops tells the client about messages dropped for a subscription
+// since the last report, reporting whether the connection is still open
+func (h *WebSocketHandler) reportDrops(client *Client, subscriber *pubsub.Subscriber) bool {
+	count, total, policy := subscriber.TakeDrops()
+	if count == 0 {
+		return true
+	}
+
+	response := &WSResponse{
+		Type:      WSResponseTypeDropped,
+		Topic:     subscriber.TopicName,
+		Dropped:   &DroppedInfo{Count: count, Total: total, Policy: policy},
+		Timestamp: time.Now(),
+	}
+	return h.send(client, response)
+}
+
+// sendDurable pulls the next batch for a durable subscription and queues it
+// for the client. It reports whether anything was sent and whether the
+// connection is still open.
+func (h *WebSocketHandler) sendDurable(client *Client, subscriber *pubsub.Subscriber) (bool, bool) {
+	messages, err := h.pubsubService.Fetch(context.Background(), subscriber.TopicName, client.ID, durableFetchBatch)
+	if err != nil {
+		// Subscription went away between snapshot and fetch
+		if subscriber.CloseReason() != "" {
+			return false, h.closeSubscription(client, subscriber)
+		}
+		return false, true
+	}
+
+	for _, message := range messages {
+		if !h.send(client, eventResponse(client, message)) {
+			return false, false
+		}
+	}
+
+	return len(messages) > 0, true
+}
+
+// closeSubscription drops a subscription the pubsub service has closed and,
+// unless the client unsubscribed itself, tells the client why with a
+// subscription_closed frame. It reports whether the connection is still
+// open.
+func (h *WebSocketHandler) closeSubscription(client *Client, subscriber *pubsub.Subscriber) bool {
+	topic := subscriber.TopicName
+
+	client.mu.Lock()
+	current := client.Subscriptions[topic] == subscriber
+	if current {
+		delete(client.Subscriptions, topic)
+		delete(client.ordering, topic)
+	}
+	client.mu.Unlock()
+
+	reason := subscriber.CloseReason()
+	if !current || reason == pubsub.CloseReasonUnsubscribed {
+		return true
+	}
+
+	response := &WSResponse{
+		Type:      WSResponseTypeSubscriptionClosed,
+		Topic:     topic,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	}
+	if !h.send(client, response) {
+		return false
+	}
+
+	logging.WithContext(context.Background()).Infow("Subscription closed by server",
+		"client_id", client.ID, "topic", topic, "reason", reason)
+	return true
+}
+
+// nextOrdering returns the ordering info to attach to an event on a
+// validate_ordering subscription, or nil for other subscriptions. It must
+// be called once per event written, in write order.
+func (c *Client) nextOrdering(message *pubsub.Message) *OrderingInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last, exists := c.ordering[message.Topic]
+	if !exists {
+		return nil
+	}
+
+	next := &OrderingInfo{
+		DeliverySeq: last.DeliverySeq + 1,
+		PrevSeq:     last.seq,
+		seq:         message.Seq,
+	}
+	c.ordering[message.Topic] = next
+	return next
+}
+
+// disconnect closes every connection of a user with a close frame carrying
+// reason. The connections' read loops then clean them up.
+func (h *WebSocketHandler) disconnect(ctx context.Context, userID, reason string) {
+	h.clientsMu.RLock()
+	var clients []*Client
+	for _, client := range h.clients {
+		if client.user() == userID {
+			clients = append(clients, client)
+		}
+	}
+	h.clientsMu.RUnlock()
+
+	for _, client := range clients {
+		logging.WithContext(ctx).Infow("Disconnecting client", "client_id", client.ID, "user_id", userID, "reason", reason)
+		client.Conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+			time.Now().Add(time.Second))
+		client.Conn.Close()
+	}
+}
+
+// disconnectAll closes every connection with a close frame carrying code and
+// reason, so clients reconnect once the gateway accepts connections again
+func (h *WebSocketHandler) disconnectAll(ctx context.Context, code int, reason string) {
+	h.clientsMu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for _, client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.clientsMu.RUnlock()
+
+	logging.WithContext(ctx).Infow("Disconnecting all clients", "count", len(clients), "reason", reason)
+	for _, client := range clients {
+		client.Conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(code, reason),
+			time.Now().Add(time.Second))
+		client.Conn.Close()
+	}
+}
+
 // Shutdown gracefully shuts down the WebSocket handler
 func (h *WebSocketHandler) Shutdown() {
 	close(h.shutdown)
@@ -354,7 +1123,17 @@ func (h *WebSocketHandler) Shutdown() {
 	h.clientsMu.RLock()
 	for _, client := range h.clients {
 		client.Conn.Close()
-		close(client.done)
+		client.finish()
 	}
 	h.clientsMu.RUnlock()
 }
+
+// userIDFromContext returns the authenticated user's ID, or "" for an
+// anonymous connection
+func userIDFromContext(ctx context.Context) string {
+	user, err := usercontext.GetUserFromContext(ctx)
+	if err != nil {
+		return ""
+	}
+	return user.ID
+}
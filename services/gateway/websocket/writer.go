@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/logging"
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+	"github.com/gorilla/websocket"
+)
+
+// CloseWriteTimeout is the close code of connections whose frames could not
+// be written in time, such as clients on a stalled network
+const CloseWriteTimeout = 4008
+
+const (
+	// DefaultWriteBuffer is the number of frames queued per connection
+	DefaultWriteBuffer = 256
+	// DefaultWriteTimeout is how long a frame may take to be written
+	DefaultWriteTimeout = 10 * time.Second
+)
+
+// WriteConfig bounds how frames wait to be written to a connection. Zero
+// values take the defaults.
+type WriteConfig struct {
+	// Buffer is the number of frames queued for a connection while earlier
+	// ones are written
+	Buffer int
+	// Timeout is how long a frame may take to be written, and how long a
+	// frame waits for room in a full buffer, before the connection is
+	// closed with CloseWriteTimeout
+	Timeout time.Duration
+}
+
+// LoadWriteConfig reads WS_WRITE_BUFFER and WS_WRITE_TIMEOUT
+func LoadWriteConfig() (WriteConfig, error) {
+	config := WriteConfig{Buffer: DefaultWriteBuffer, Timeout: DefaultWriteTimeout}
+
+	if value := os.Getenv("WS_WRITE_BUFFER"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return config, fmt.Errorf("invalid WS_WRITE_BUFFER %q: expected a positive number of frames", value)
+		}
+		config.Buffer = parsed
+	}
+	if value := os.Getenv("WS_WRITE_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return config, fmt.Errorf("invalid WS_WRITE_TIMEOUT %q: expected a positive duration such as 10s", value)
+		}
+		config.Timeout = parsed
+	}
+	return config, nil
+}
+
+// withDefaults fills in the zero values
+func (c WriteConfig) withDefaults() WriteConfig {
+	if c.Buffer <= 0 {
+		c.Buffer = DefaultWriteBuffer
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultWriteTimeout
+	}
+	return c
+}
+
+// responses recycles the frames written by writeLoop. Events are the bulk of
+// the frames on a busy connection, and building each one afresh made them
+// a large share of the gateway's garbage.
+var responses = sync.Pool{
+	New: func() interface{} { return new(WSResponse) },
+}
+
+// eventResponse returns an event frame for a message, from the pool
+func eventResponse(client *Client, message *pubsub.Message) *WSResponse {
+	response := responses.Get().(*WSResponse)
+	response.Type = WSResponseTypeEvent
+	response.Topic = message.Topic
+	response.Message = message
+	response.Ordering = client.nextOrdering(message)
+	response.Timestamp = time.Now()
+	return response
+}
+
+// mirrorResponse returns an event frame for a mirrored message, from the
+// pool. Mirrored events carry no ordering info.
+func mirrorResponse(message *pubsub.Message) *WSResponse {
+	response := responses.Get().(*WSResponse)
+	response.Type = WSResponseTypeEvent
+	response.Topic = message.Topic
+	response.Message = message
+	response.Mirror = true
+	response.Timestamp = time.Now()
+	return response
+}
+
+// releaseResponse returns a written frame to the pool. The frame must not
+// be used afterwards; the message it carried is shared and is not reset.
+func releaseResponse(response *WSResponse) {
+	*response = WSResponse{}
+	responses.Put(response)
+}
+
+// send queues a frame for the connection's writer. A full buffer means the
+// network is not keeping up, so the frame waits for room rather than being
+// dropped, holding back the caller; if none frees up within the write
+// timeout the connection is closed. It reports whether the frame was queued;
+// either way the frame belongs to the writer afterwards and must not be
+// touched by the caller.
+func (h *WebSocketHandler) send(client *Client, response *WSResponse) bool {
+	select {
+	case client.outbox <- response:
+		return true
+	case <-client.done:
+		return false
+	default:
+	}
+
+	timer := time.NewTimer(h.writes.Timeout)
+	defer timer.Stop()
+
+	select {
+	case client.outbox <- response:
+		return true
+	case <-client.done:
+		return false
+	case <-timer.C:
+		h.closeUnwritable(client, "write buffer full")
+		return false
+	}
+}
+
+// writeLoop writes a connection's queued frames in order until it closes.
+// It is the only writer of data frames, so the read loop and the message
+// sender never write at once. A frame that fails to be written breaks the
+// stream for every later frame, so the connection is closed; the events it
+// never got are replayed to subscriptions that resume after a reconnect.
+func (h *WebSocketHandler) writeLoop(client *Client) {
+	for {
+		select {
+		case <-client.done:
+			return
+		case response := <-client.outbox:
+			client.Conn.SetWriteDeadline(time.Now().Add(h.writes.Timeout))
+			if err := client.Conn.WriteJSON(response); err != nil {
+				logging.WithContext(context.Background()).Warnw("Failed to write WebSocket frame",
+					"error", err, "client_id", client.ID, "type", response.Type, "topic", response.Topic)
+				h.closeUnwritable(client, "write failed")
+				return
+			}
+			releaseResponse(response)
+		}
+	}
+}
+
+// closeUnwritable closes a connection that cannot be written to, with a
+// CloseWriteTimeout frame if the network still takes one. The read loop
+// then cleans it up.
+func (h *WebSocketHandler) closeUnwritable(client *Client, reason string) {
+	client.closing.Do(func() {
+		logging.WithContext(context.Background()).Warnw("Closing connection that cannot be written to",
+			"client_id", client.ID, "reason", reason, "queued", len(client.outbox))
+		client.Conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(CloseWriteTimeout, reason),
+			time.Now().Add(time.Second))
+		client.Conn.Close()
+	})
+}
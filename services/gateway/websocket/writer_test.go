@@ -0,0 +1,39 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ammysap/plivo-pub-sub/pubsub"
+)
+
+// BenchmarkEventFrames builds event frames and passes them through a
+// connection's outbox to the writer, which releases them once written
+func BenchmarkEventFrames(b *testing.B) {
+	client := &Client{}
+	message := &pubsub.Message{ID: "m", Topic: "orders", Payload: "payload"}
+
+	b.Run("pooled", func(b *testing.B) {
+		outbox := make(chan *WSResponse, 1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			outbox <- eventResponse(client, message)
+			releaseResponse(<-outbox)
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		outbox := make(chan *WSResponse, 1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			outbox <- &WSResponse{
+				Type:      WSResponseTypeEvent,
+				Topic:     message.Topic,
+				Message:   message,
+				Ordering:  client.nextOrdering(message),
+				Timestamp: time.Now(),
+			}
+			<-outbox
+		}
+	})
+}
@@ -219,7 +219,7 @@ fi
 echo -e "\n${BLUE}📝 Test: WebSocket with Valid Token${NC}"
 # Use the login token if available, otherwise use the register token
 WS_TOKEN=${LOGIN_TOKEN:-$TOKEN}
-WS_VALID_TOKEN_RESPONSE=$(curl -s -w "%{http_code}" -o /dev/null "$BASE_URL/ws?token=$WS_TOKEN")
+WS_VALID_TOKEN_RESPONSE=$(curl -s -w "%{http_code}" -o /dev/null -H "Authorization: Bearer $WS_TOKEN" "$BASE_URL/ws")
 if [ "$WS_VALID_TOKEN_RESPONSE" = "400" ]; then
     echo -e "${GREEN}✅ WebSocket with valid token responds correctly (400 - expects WebSocket upgrade)${NC}"
     ((TESTS_PASSED++))
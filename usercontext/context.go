@@ -16,6 +16,7 @@ var (
 )
 
 type User struct {
+	ID    string `json:"id"`
 	Phone string `json:"phone"`
 	Email string `json:"email"`
 }